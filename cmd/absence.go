@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var absenceRequestFlags = struct {
+	typeID       int
+	startDate    flagtype.Date
+	endDate      flagtype.Date
+	halfDayStart bool
+	halfDayEnd   bool
+	comment      string
+}{}
+
+var absenceCmd = &cobra.Command{
+	Use:   "absence",
+	Short: "Group of commands for interacting with absences",
+}
+
+var absenceRequestCmd = &cobra.Command{
+	Use:   "request",
+	Short: "Requests an absence, such as vacation",
+	Long: `Requests an absence, such as vacation, for the given dates.
+
+The --type flag is the ID of the absence type in Personio.
+Without --end, the absence is a single day.`,
+	Example: `  rootless-personio absence request --type 12345 --start 2023-07-03 --end 2023-07-14`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if absenceRequestFlags.startDate.IsZero() {
+			return errors.New("missing start date, use the --start flag")
+		}
+		end := absenceRequestFlags.endDate
+		if end.IsZero() {
+			end = absenceRequestFlags.startDate
+		}
+		absence := personio.AbsenceRequest{
+			TimeOffTypeID: absenceRequestFlags.typeID,
+			StartDate:     absenceRequestFlags.startDate.Time(),
+			EndDate:       end.Time(),
+			HalfDayStart:  absenceRequestFlags.halfDayStart,
+			HalfDayEnd:    absenceRequestFlags.halfDayEnd,
+			Comment:       absenceRequestFlags.comment,
+		}
+
+		client, err := newLoggedInClient()
+		if err != nil {
+			return err
+		}
+		if err := actOnBehalfOf(client, "edit"); err != nil {
+			return err
+		}
+		if err := client.RequestAbsence(absence); err != nil {
+			return err
+		}
+		log.Info().
+			Str("start", absence.StartDate.Format(time.DateOnly)).
+			Str("end", absence.EndDate.Format(time.DateOnly)).
+			Msg("Successfully requested absence.")
+		return printOutputJSONOrYAML(map[string]any{
+			"typeId":       absence.TimeOffTypeID,
+			"start":        absence.StartDate.Format(time.DateOnly),
+			"end":          absence.EndDate.Format(time.DateOnly),
+			"halfDayStart": absence.HalfDayStart,
+			"halfDayEnd":   absence.HalfDayEnd,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(absenceCmd)
+	absenceCmd.AddCommand(absenceRequestCmd)
+	markExperimental(absenceRequestCmd, "absenceWrite", func() bool {
+		return cfg.Experimental.AbsenceWrite
+	})
+
+	absenceRequestCmd.Flags().IntVar(&absenceRequestFlags.typeID, "type", 0, "ID of the absence type in Personio")
+	absenceRequestCmd.MarkFlagRequired("type")
+	absenceRequestCmd.Flags().VarP(&absenceRequestFlags.startDate, "start", "s", "First day of the absence")
+	absenceRequestCmd.Flags().VarP(&absenceRequestFlags.endDate, "end", "e", "Last day of the absence (default same as --start)")
+	absenceRequestCmd.Flags().BoolVar(&absenceRequestFlags.halfDayStart, "half-day-start", false, "Only take the second half of the first day off")
+	absenceRequestCmd.Flags().BoolVar(&absenceRequestFlags.halfDayEnd, "half-day-end", false, "Only take the first half of the last day off")
+	absenceRequestCmd.Flags().StringVarP(&absenceRequestFlags.comment, "comment", "c", "", "Comment on the absence request")
+	addEmployeeFlags(absenceRequestCmd.Flags(), true)
+}
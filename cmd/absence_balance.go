@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var absenceBalanceFlags = struct {
+	year int
+}{
+	year: time.Now().Year(),
+}
+
+var absenceBalanceCmd = &cobra.Command{
+	Use:   "balance",
+	Short: "Count the vacation days taken, planned, and left in a year",
+	Long: `Count the vacation days taken, planned, and left in a year.
+
+Personio's own vacation balance is not available via the endpoints used by
+this program, so the balance is instead calculated from your absences and
+the yearly entitlement in the report.vacation.days config. Absences whose
+names contain any of the report.vacation.keywords are counted, on the
+workdays in your report.workingHours, excluding public holidays.
+
+The year is the leave year that starts on report.vacation.leaveYearStart
+in the given calendar year, and defaults to the current leave year.
+Unused days of the previous leave year are carried over, up to
+report.vacation.carryover.maxDays, and expire after the
+report.vacation.carryover.expires day. The entitlement is pro-rated for
+years that are only partially covered by report.vacation.employmentStart
+and report.vacation.employmentEnd.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, err := newCalendarSource()
+		if err != nil {
+			return err
+		}
+		defer closeCalendarSource(source)
+		now := time.Now()
+		year := absenceBalanceFlags.year
+		if !cmd.Flags().Changed("year") {
+			year, err = currentLeaveYear(now)
+			if err != nil {
+				return err
+			}
+		}
+		balance, err := vacationBalance(source, year, now)
+		if err != nil {
+			return err
+		}
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintVacationBalance(balance)
+			return nil
+		}
+		return printOutputJSONOrYAML(balance)
+	},
+}
+
+func init() {
+	absenceCmd.AddCommand(absenceBalanceCmd)
+
+	addMirrorFlag(absenceBalanceCmd.Flags())
+
+	absenceBalanceCmd.Flags().IntVarP(&absenceBalanceFlags.year, "year", "y", absenceBalanceFlags.year, "Year that the leave year to count vacation days in starts in")
+}
+
+// vacationBalance counts the vacation days in the leave year that starts
+// in the given year, including the days carried over from the previous
+// leave year.
+func vacationBalance(source calendarSource, year int, now time.Time) (report.VacationBalance, error) {
+	rules, err := vacationRules(year)
+	if err != nil {
+		return report.VacationBalance{}, err
+	}
+	if maxDays := cfg.Report.Vacation.Carryover.MaxDays; maxDays > 0 {
+		prevRules, err := vacationRules(year - 1)
+		if err != nil {
+			return report.VacationBalance{}, err
+		}
+		prevDays, err := fetchReportDays(source, prevRules.LeaveYear)
+		if err != nil {
+			return report.VacationBalance{}, fmt.Errorf("previous leave year: %w", err)
+		}
+		prev := report.CalculateVacation(prevDays, reportSchedule(), prevRules, now)
+		rules.CarryoverDays = math.Min(math.Max(prev.RemainingDays, 0), maxDays)
+	}
+	days, err := fetchReportDays(source, rules.LeaveYear)
+	if err != nil {
+		return report.VacationBalance{}, err
+	}
+	return report.CalculateVacation(days, reportSchedule(), rules, now), nil
+}
+
+// vacationRules returns the vacation rules from the config for the leave
+// year that starts in the given year, without any carryover days.
+func vacationRules(year int) (report.VacationRules, error) {
+	conf := cfg.Report.Vacation
+	leaveYear, err := leaveYearRange(year)
+	if err != nil {
+		return report.VacationRules{}, err
+	}
+	rules := report.VacationRules{
+		Days:      conf.Days,
+		Keywords:  conf.Keywords,
+		LeaveYear: leaveYear,
+		ProRate:   report.ProRate(conf.ProRate),
+	}
+	if conf.EmploymentStart != "" {
+		if rules.Employment.Start, err = time.Parse(time.DateOnly, conf.EmploymentStart); err != nil {
+			return report.VacationRules{}, fmt.Errorf("parse report.vacation.employmentStart: %w", err)
+		}
+	}
+	if conf.EmploymentEnd != "" {
+		if rules.Employment.End, err = time.Parse(time.DateOnly, conf.EmploymentEnd); err != nil {
+			return report.VacationRules{}, fmt.Errorf("parse report.vacation.employmentEnd: %w", err)
+		}
+	}
+	if conf.Carryover.Expires != "" {
+		expires, err := parseMonthDay(conf.Carryover.Expires, leaveYear.Start)
+		if err != nil {
+			return report.VacationRules{}, fmt.Errorf("parse report.vacation.carryover.expires: %w", err)
+		}
+		rules.CarryoverExpires = expires
+	}
+	return rules, nil
+}
+
+// leaveYearRange returns the leave year that starts in the given year.
+func leaveYearRange(year int) (datespec.Range, error) {
+	if cfg.Report.Vacation.LeaveYearStart == "" {
+		return yearRange(year), nil
+	}
+	start, err := parseMonthDay(cfg.Report.Vacation.LeaveYearStart, time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return datespec.Range{}, fmt.Errorf("parse report.vacation.leaveYearStart: %w", err)
+	}
+	return datespec.Range{Start: start, End: start.AddDate(1, 0, -1)}, nil
+}
+
+// currentLeaveYear returns the year that the leave year of now starts in.
+func currentLeaveYear(now time.Time) (int, error) {
+	leaveYear, err := leaveYearRange(now.Year())
+	if err != nil {
+		return 0, err
+	}
+	if now.Format(time.DateOnly) < leaveYear.Start.Format(time.DateOnly) {
+		return now.Year() - 1, nil
+	}
+	return now.Year(), nil
+}
+
+// parseMonthDay parses a day in the format "01-02" (month-day), as the
+// first such day on or after the given date.
+func parseMonthDay(value string, onOrAfter time.Time) (time.Time, error) {
+	t, err := time.Parse("01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	date := time.Date(onOrAfter.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if date.Before(onOrAfter) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, nil
+}
@@ -18,9 +18,19 @@
 package cmd
 
 import (
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/comment"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
+var attendanceFlags = struct {
+	offline bool
+	verify  bool
+}{}
+
 var attendanceCmd = &cobra.Command{
 	Use:   "attendance",
 	Short: "Group of commands for interacting with attendance",
@@ -28,4 +38,51 @@ var attendanceCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(attendanceCmd)
+
+	attendanceCmd.PersistentFlags().BoolVar(&attendanceFlags.offline, "offline", false, `Queue changes locally instead of sending them, to be sent later via "flush"`)
+	attendanceCmd.PersistentFlags().BoolVar(&attendanceFlags.verify, "verify", false, "Fetch changed days again after sending them, and fail if Personio stored something else")
+}
+
+// verifyAttendance fetches the day again when the --verify flag is set, and
+// returns an error if the stored periods differ from the submitted ones,
+// such as when Personio silently drops seconds or comments.
+func verifyAttendance(client *personio.Client, date time.Time, submitted []personio.Period) error {
+	if !attendanceFlags.verify {
+		return nil
+	}
+	day := date.Format(time.DateOnly)
+	mismatches, err := client.VerifyAttendance(date, submitted)
+	for _, m := range mismatches {
+		log.Warn().
+			Str("day", day).
+			Stringer("period", m.PeriodID).
+			Str("field", m.Field).
+			Str("submitted", m.Submitted).
+			Str("stored", m.Stored).
+			Msg("Stored period differs from the submitted period.")
+	}
+	if err != nil {
+		return err
+	}
+	log.Info().Str("day", day).Msg("Verified the stored attendance.")
+	return nil
 }
+
+// newCommentRenderer returns a renderer for comment templates, using the
+// variables from the --var flags.
+func newCommentRenderer(vars []string) (*comment.Renderer, error) {
+	parsed, err := comment.ParseVars(vars)
+	if err != nil {
+		return nil, err
+	}
+	return &comment.Renderer{Vars: parsed}, nil
+}
+
+const commentTemplateHelp = `Comments are Go templates, such as "ticket {{.Ticket}}", with these variables:
+
+  {{.GitBranch}}  the current git branch
+  {{.Ticket}}     the first ticket ID in the git branch, e.g "ABC-123"
+
+Variables can also be set via --var key=value, which takes precedence.
+Keys can be used with an upper case first letter, e.g --var ticket=X
+can be used as {{.Ticket}}.`
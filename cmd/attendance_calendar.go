@@ -18,10 +18,12 @@
 package cmd
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/applejag/rootless-personio/pkg/config"
 	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/datespec"
 	"github.com/applejag/rootless-personio/pkg/flagtype"
 	"github.com/applejag/rootless-personio/pkg/personio"
 	"github.com/applejag/rootless-personio/pkg/util"
@@ -38,16 +40,11 @@ var attendanceCalendarCmd = &cobra.Command{
 	Use:   "calendar",
 	Short: "Show the calendar of your attendance",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		startDate := attendanceCalendarFlags.startDate.Time()
-		endDate := attendanceCalendarFlags.endDate.Time()
-
-		monthStart, monthEnd := util.TimeFullMonth(time.Now())
-		if !cmd.Flag("start").Changed {
-			startDate = monthStart
-		}
-		if !cmd.Flag("end").Changed {
-			endDate = monthEnd
+		r, err := resolveRange(cmd, attendanceCalendarFlags.startDate, attendanceCalendarFlags.endDate)
+		if err != nil {
+			return err
 		}
+		startDate, endDate := r.Start, r.End
 
 		log.Debug().
 			Time("start", startDate).
@@ -57,13 +54,30 @@ var attendanceCalendarCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cal, err := client.GetMyAttendanceCalendar(startDate, endDate)
-		if err != nil {
+		if err := actOnBehalfOf(client, "view"); err != nil {
 			return err
 		}
-
 		if cfg.Output == config.OutFormatPretty {
-			return prettyPrintCalendar(cal, startDate, endDate)
+			weekStart, err := datespec.ParseWeekStart(cfg.DateRange.WeekStart)
+			if err != nil {
+				return fmt.Errorf("dateRange.weekStart: %w", err)
+			}
+			// Print each month as soon as it's fetched
+			return client.GetAttendanceCalendarRange(client.TargetEmployeeID(), startDate, endDate, func(cal *personio.AttendanceCalendar, start, _ time.Time) error {
+				month, _ := util.TimeFullMonth(start)
+				console.PrintCalendarMonth(month, cal, weekStart)
+				return nil
+			})
+		}
+		if cfg.Output == config.OutFormatJSONL {
+			// Print each month's calendar on its own line as soon as it's fetched
+			return client.GetAttendanceCalendarRange(client.TargetEmployeeID(), startDate, endDate, func(cal *personio.AttendanceCalendar, _, _ time.Time) error {
+				return printJSONL(cal)
+			})
+		}
+		cal, err := client.GetAttendanceCalendarMerged(client.TargetEmployeeID(), startDate, endDate)
+		if err != nil {
+			return err
 		}
 		return printOutputJSONOrYAML(cal)
 	},
@@ -72,16 +86,7 @@ var attendanceCalendarCmd = &cobra.Command{
 func init() {
 	attendanceCmd.AddCommand(attendanceCalendarCmd)
 
-	attendanceCalendarCmd.Flags().VarP(&attendanceCalendarFlags.startDate, "start", "s", "Start date to show (default first day this month)")
-	attendanceCalendarCmd.Flags().VarP(&attendanceCalendarFlags.endDate, "end", "e", "End date to show (default first day this month)")
-}
-
-func prettyPrintCalendar(cal *personio.AttendanceCalendar, startDate, endDate time.Time) error {
-	year, month, _ := startDate.Date()
-	date := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
-	for date.Before(endDate) {
-		console.PrintCalendarMonth(date, cal)
-		date = date.AddDate(0, 1, 0)
-	}
-	return nil
+	attendanceCalendarCmd.Flags().VarP(&attendanceCalendarFlags.startDate, "start", "s", "Start date to show (default start of dateRange config, this month)")
+	attendanceCalendarCmd.Flags().VarP(&attendanceCalendarFlags.endDate, "end", "e", "End date to show (default start of dateRange config, this month)")
+	addEmployeeFlags(attendanceCalendarCmd.Flags(), false)
 }
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/clock"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var attendanceStartFlags = struct {
+	comment   string
+	projectID int
+	pomodoro  string
+	vars      []string
+}{}
+
+var attendanceStartCmd = &cobra.Command{
+	Use:     "start",
+	Aliases: []string{"in"},
+	Short:   "Start the punch clock",
+	Long: `Start the punch clock, which remembers when you started working.
+
+Nothing is sent to Personio until you run "attendance stop", which
+then adds the period from start to stop to that day's attendance.
+
+With --pomodoro, such as "--pomodoro 50/10", the time is split into
+focus blocks and breaks of the given minutes, and the breaks are added as
+break periods on "attendance stop". Breaks shorter than the
+clock.minBreak config are merged into the surrounding work.
+
+` + commentTemplateHelp + `
+
+The comment is rendered when starting, so e.g {{.GitBranch}} is the
+branch you're on when running "attendance start".`,
+	Example: `  rootless-personio attendance start --comment "Fixing bugs"
+  rootless-personio attendance start --comment "ticket {{.Ticket}}"
+  rootless-personio attendance start --pomodoro 25/5`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := clockStatePath()
+		if err != nil {
+			return err
+		}
+		renderer, err := newCommentRenderer(attendanceStartFlags.vars)
+		if err != nil {
+			return err
+		}
+		comment, err := renderer.Render(attendanceStartFlags.comment, nil)
+		if err != nil {
+			return err
+		}
+		opts := clock.Options{Comment: comment}
+		if cmd.Flags().Changed("project") {
+			opts.ProjectID = &attendanceStartFlags.projectID
+		}
+		if attendanceStartFlags.pomodoro != "" {
+			pomodoro, err := clock.ParsePomodoro(attendanceStartFlags.pomodoro)
+			if err != nil {
+				return err
+			}
+			opts.Pomodoro = &pomodoro
+		}
+		state, err := clock.In(path, time.Now(), opts)
+		if err != nil {
+			return err
+		}
+		ev := log.Info().Time("start", *state.ClockedInAt)
+		if state.Pomodoro != nil {
+			ev = ev.Stringer("pomodoro", state.Pomodoro)
+		}
+		ev.Msg("Clocked in.")
+		return printOutputJSONOrYAML(state)
+	},
+}
+
+var attendanceStopCmd = &cobra.Command{
+	Use:     "stop",
+	Aliases: []string{"out"},
+	Short:   "Stop the punch clock and submit the period",
+	Long: `Stop the punch clock, and add the period since "attendance start"
+to that day's attendance, keeping the day's existing periods.
+
+In pomodoro mode, the focus blocks are added as work periods and the
+breaks between them as break periods.
+
+If submitting fails, the punch clock keeps running, so you can retry.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := clockStatePath()
+		if err != nil {
+			return err
+		}
+		state, err := clock.Load(path)
+		if err != nil {
+			return err
+		}
+		if !state.IsClockedIn() {
+			return clock.ErrNotClockedIn
+		}
+		client, err := newLoggedInClient()
+		if err != nil {
+			logOfflineHint(err)
+			return err
+		}
+		periods, err := clockOut(client)
+		if err != nil {
+			return err
+		}
+		return printOutputJSONOrYAML(periods)
+	},
+}
+
+// clockOut stops the punch clock and adds its periods to Personio.
+func clockOut(client *personio.Client) ([]personio.Period, error) {
+	path, err := clockStatePath()
+	if err != nil {
+		return nil, err
+	}
+	periods, err := clock.Out(path, time.Now(), cfg.Clock.MinBreak, func(periods []personio.Period) error {
+		return client.AddAttendancePeriods(periods[0].Start, periods)
+	})
+	if err != nil {
+		return periods, err
+	}
+	log.Info().
+		Time("start", periods[0].Start).
+		Time("end", periods[len(periods)-1].End).
+		Int("periods", len(periods)).
+		Msg("Clocked out.")
+	return periods, nil
+}
+
+func clockStatePath() (string, error) {
+	if cfg.Clock.Path != "" {
+		return cfg.Clock.Path, nil
+	}
+	return clock.DefaultPath()
+}
+
+func init() {
+	attendanceCmd.AddCommand(attendanceStartCmd)
+	attendanceCmd.AddCommand(attendanceStopCmd)
+
+	attendanceStartCmd.Flags().StringVarP(&attendanceStartFlags.comment, "comment", "c", "", "Comment of the period")
+	attendanceStartCmd.Flags().IntVar(&attendanceStartFlags.projectID, "project", 0, "Project ID of the period")
+	attendanceStartCmd.Flags().StringArrayVar(&attendanceStartFlags.vars, "var", nil, `Comment template variable, as "key=value"`)
+	attendanceStartCmd.Flags().StringVar(&attendanceStartFlags.pomodoro, "pomodoro", "", `Pomodoro mode, as "focus/break" minutes, e.g "50/10"`)
+}
@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/hook"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/applejag/rootless-personio/pkg/timesheet"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var attendanceCopyFlags = struct {
+	to    flagtype.Date
+	force bool
+}{}
+
+var attendanceCopyCmd = &cobra.Command{
+	Use:   "copy <YYYY-MM-DD>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Copy a day's attendance to another day",
+	Long: `Copy the attendance periods of a day to another day, keeping the
+same times of day, comments, and projects.
+
+Provide the date in format YYYY-MM-DD, e.g 2023-01-25 for Jan 25, 2023.
+
+The target day must not have any attendance already, unless --force is
+given, which replaces the target day's periods.`,
+	Example: `  rootless-personio attendance copy 2024-05-02 --to 2024-05-03`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		date, err := time.Parse(time.DateOnly, args[0])
+		if err != nil {
+			return err
+		}
+		from := datespec.Range{Start: date, End: date}
+		to := attendanceCopyFlags.to.Time()
+		return copyAttendance("copy", from, to, false)
+	},
+}
+
+var attendanceCopyWeekCmd = &cobra.Command{
+	Use:   "copy-week <YYYY-MM-DD>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Copy a week's attendance to another week",
+	Long: `Copy the attendance periods of the week containing the given date to
+the week containing the --to date, keeping the same weekdays and times of
+day. Weeks start on the dateRange.weekStart config.
+
+Days without attendance in the source week are left as-is in the target
+week, and target days with holidays or absences are skipped.
+
+The target days must not have any attendance already, unless --force is
+given, which replaces the target days' periods.`,
+	Example: `  rootless-personio attendance copy-week 2024-04-29 --to 2024-05-06`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		date, err := time.Parse(time.DateOnly, args[0])
+		if err != nil {
+			return err
+		}
+		weekStart, err := datespec.ParseWeekStart(cfg.DateRange.WeekStart)
+		if err != nil {
+			return fmt.Errorf("dateRange.weekStart: %w", err)
+		}
+		from := datespec.ThisWeek(date, weekStart)
+		to := datespec.ThisWeek(attendanceCopyFlags.to.Time(), weekStart).Start
+		return copyAttendance("copy-week", from, to, true)
+	},
+}
+
+func init() {
+	attendanceCmd.AddCommand(attendanceCopyCmd)
+	attendanceCmd.AddCommand(attendanceCopyWeekCmd)
+
+	for _, c := range []*cobra.Command{attendanceCopyCmd, attendanceCopyWeekCmd} {
+		c.Flags().Var(&attendanceCopyFlags.to, "to", "Date to copy the attendance to")
+		c.MarkFlagRequired("to")
+		c.Flags().BoolVar(&attendanceCopyFlags.force, "force", false, "Replace the attendance of target days that already have attendance")
+		addEmployeeFlags(c.Flags(), true)
+	}
+}
+
+// copyAttendance copies the periods of each day in the range to the days
+// starting at the "to" date. When skipAbsent is set, target days with
+// holidays or absences are skipped.
+func copyAttendance(action string, from datespec.Range, to time.Time, skipAbsent bool) error {
+	if attendanceFlags.offline {
+		return errors.New("copying needs to read the attendance from Personio, and cannot be used with --offline")
+	}
+	offset := int(datespec.Date(to).Sub(from.Start).Hours() / 24)
+	if offset == 0 {
+		return errors.New("the --to date must differ from the source date")
+	}
+	target := datespec.Range{Start: from.Start.AddDate(0, 0, offset), End: from.End.AddDate(0, 0, offset)}
+
+	client, err := newLoggedInClient()
+	if err != nil {
+		return err
+	}
+	if err := actOnBehalfOf(client, "edit"); err != nil {
+		return err
+	}
+
+	srcCal, err := client.GetMyAttendanceCalendar(from.Start, from.End)
+	if err != nil {
+		return fmt.Errorf("get source attendance: %w", err)
+	}
+	srcPeriods, err := calendarPeriodsByDay(srcCal)
+	if err != nil {
+		return err
+	}
+	dstCal, err := client.GetMyAttendanceCalendar(target.Start, target.End)
+	if err != nil {
+		return fmt.Errorf("get target attendance: %w", err)
+	}
+	dstDays, err := report.Days(dstCal, target)
+	if err != nil {
+		return err
+	}
+
+	var hookDays []submitHookDay
+	for _, dst := range dstDays {
+		srcDay := dst.Date.AddDate(0, 0, -offset).Format(time.DateOnly)
+		dstDay := dst.Date.Format(time.DateOnly)
+		periods := srcPeriods[srcDay]
+		if len(periods) == 0 {
+			log.Debug().Str("day", srcDay).Msg("Skipping day without attendance.")
+			continue
+		}
+		if skipAbsent && (dst.Absence != nil || (dst.Holiday != nil && !dst.Holiday.HalfDay)) {
+			log.Warn().Str("day", dstDay).Msg("Skipping target day because of a holiday or absence.")
+			continue
+		}
+		if len(dst.Periods) > 0 && !attendanceCopyFlags.force {
+			return fmt.Errorf("target day %s already has attendance, use --force to replace it", dstDay)
+		}
+		shifted := make([]personio.Period, len(periods))
+		for i, p := range periods {
+			shifted[i] = p.ShiftDays(offset)
+		}
+		hookDays = append(hookDays, submitHookDay{Day: dstDay, Periods: shifted})
+	}
+	if len(hookDays) == 0 {
+		return errors.New("found no attendance to copy")
+	}
+
+	if err := runSubmitHook(hook.PreSubmit, cfg.Hooks.PreSubmit, action, client.TargetEmployeeID(), hookDays); err != nil {
+		return err
+	}
+	for _, day := range hookDays {
+		err := client.SetAttendance(day.Periods[0].Start, day.Periods)
+		recordAudit("attendance "+action, client, queue.NewOperation(queue.ActionSet, day.Day, day.Periods, time.Now()), err, nil)
+		if err != nil {
+			return err
+		}
+		log.Info().
+			Str("day", day.Day).
+			Int("periods", len(day.Periods)).
+			Msg("Successfully copied attendance to day.")
+		date, err := time.Parse(time.DateOnly, day.Day)
+		if err != nil {
+			return err
+		}
+		if err := verifyAttendance(client, date, day.Periods); err != nil {
+			return err
+		}
+	}
+	if err := runSubmitHook(hook.PostSubmit, cfg.Hooks.PostSubmit, action, client.TargetEmployeeID(), hookDays); err != nil {
+		return err
+	}
+	return printOutputJSONOrYAML(map[string]any{
+		"days": hookDays,
+	})
+}
+
+// calendarPeriodsByDay groups the calendar's attendance periods by the date
+// of the attendance day they belong to, e.g "2023-01-20".
+func calendarPeriodsByDay(cal *personio.AttendanceCalendar) (map[string][]personio.Period, error) {
+	entries, err := timesheet.FromCalendar(cal)
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[string][]personio.Period)
+	for day, dayEntries := range timesheet.ByDate(entries) {
+		byDay[day] = timesheet.Periods(dayEntries)
+	}
+	return byDay, nil
+}
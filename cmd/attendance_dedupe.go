@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/mirror"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/timesheet"
+	"github.com/google/uuid"
+)
+
+// importSource is where a period read by "attendance set" comes from, as
+// given by the optional "source" and "source_id" fields of its JSON object.
+type importSource struct {
+	Source   timesheet.Source `json:"source"`
+	SourceID string           `json:"source_id"`
+}
+
+// importDedupe keeps track of which entries from external time trackers
+// have been sent to Personio before, using the local mirror, so importing
+// the same entries again is skipped.
+type importDedupe struct {
+	mirror *mirror.Mirror
+	// unchanged are the days where all periods were imported before,
+	// without any changes since
+	unchanged map[string]bool
+	// pending are the entries to record per day, once the day is sent
+	pending map[string][]mirror.ImportedEntry
+	// writtenAt is when the periods that were imported before were sent
+	writtenAt map[uuid.UUID]time.Time
+}
+
+// newImportDedupe looks up the periods' source IDs in the mirror. Periods
+// that were imported before get the same ID as last time, so they are
+// updated in place instead of being replaced by new periods.
+//
+// Returns nil if none of the periods have source IDs.
+func newImportDedupe(periods []personio.Period, sources []importSource) (*importDedupe, error) {
+	hasSourceIDs := false
+	for _, src := range sources {
+		if src.SourceID != "" {
+			hasSourceIDs = true
+			break
+		}
+	}
+	if !hasSourceIDs {
+		return nil, nil
+	}
+	m, err := openMirror()
+	if err != nil {
+		return nil, err
+	}
+	d := &importDedupe{
+		mirror:    m,
+		unchanged: make(map[string]bool),
+		pending:   make(map[string][]mirror.ImportedEntry),
+		writtenAt: make(map[uuid.UUID]time.Time),
+	}
+	imported := make(map[timesheet.Source]map[string]mirror.ImportedEntry)
+	for i := range periods {
+		day := periods[i].Start.Format(time.DateOnly)
+		src := sources[i]
+		if src.SourceID == "" {
+			d.unchanged[day] = false
+			continue
+		}
+		if src.Source == "" {
+			src.Source = timesheet.SourceImport
+		}
+		if imported[src.Source] == nil {
+			imported[src.Source], err = m.ImportedEntries(src.Source)
+			if err != nil {
+				m.Close()
+				return nil, err
+			}
+		}
+
+		entry := timesheet.FromPeriod(periods[i], src.Source)
+		entry.Date = day
+		entry.SourceID = src.SourceID
+		hash := entry.Hash()
+		prev, ok := imported[src.Source][src.SourceID]
+		if ok {
+			periods[i].ID = prev.PeriodID
+			d.writtenAt[prev.PeriodID] = prev.ImportedAt
+		} else if periods[i].ID == uuid.Nil {
+			periods[i].ID = uuid.New()
+		}
+		if _, seen := d.unchanged[day]; !seen {
+			d.unchanged[day] = true
+		}
+		if !ok || prev.Hash != hash {
+			d.unchanged[day] = false
+		}
+		d.pending[day] = append(d.pending[day], mirror.ImportedEntry{
+			Source:   src.Source,
+			SourceID: src.SourceID,
+			PeriodID: periods[i].ID,
+			Date:     day,
+			Hash:     hash,
+		})
+	}
+	return d, nil
+}
+
+// Skip returns true if all periods of the day were imported before.
+func (d *importDedupe) Skip(day string) bool {
+	return d != nil && d.unchanged[day]
+}
+
+// WrittenAt returns when the periods that were imported before were sent
+// to Personio, keyed by period ID.
+func (d *importDedupe) WrittenAt() map[uuid.UUID]time.Time {
+	if d == nil {
+		return nil
+	}
+	return d.writtenAt
+}
+
+// Done records that the day's entries were sent to Personio.
+func (d *importDedupe) Done(day string, now time.Time) error {
+	if d == nil {
+		return nil
+	}
+	entries := d.pending[day]
+	for i := range entries {
+		entries[i].ImportedAt = now
+	}
+	return d.mirror.RecordImports(entries...)
+}
+
+func (d *importDedupe) Close() error {
+	if d == nil {
+		return nil
+	}
+	return d.mirror.Close()
+}
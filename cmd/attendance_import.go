@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/sheet"
+	"github.com/applejag/rootless-personio/pkg/xlsx"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var attendanceImportFlags = struct {
+	preview     bool
+	previewRows int
+	format      string
+	sheet       string
+}{
+	previewRows: 10,
+}
+
+var attendanceImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Imports attendance periods from a CSV or Excel spreadsheet",
+	Long: `Imports attendance periods from a CSV or Excel (XLSX) spreadsheet,
+with one period per row, and sets them the same way as "attendance set".
+
+The first row must be a header. Which columns contain the date, start and
+end times, period type, and comment is configured via import.csv.mapping,
+as everyone's spreadsheet layout differs. Columns are referenced by header
+name or by 1-based column number, and the date and time formats are Go
+time layouts, such as "02.01.2006" and "15:04".
+
+Excel files use the import.xlsx.mapping config, or else the same mapping as
+CSV files. Their first sheet is read, unless another is selected via --sheet
+or the import.xlsx.sheet config. The format defaults to XLSX when the file
+ends with ".xlsx", and otherwise CSV.
+
+Use --preview to only show how the first rows were parsed, without sending
+anything to Personio, when setting up the mapping.
+
+All cells of a row can be used in comment templates, by their header name.`,
+	Example: `  rootless-personio attendance import timesheet.csv --preview
+  rootless-personio attendance import timesheet.csv
+  rootless-personio attendance import timesheet.xlsx --sheet January`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rows, err := readSheetRows(args[0])
+		if err != nil {
+			return err
+		}
+
+		if attendanceImportFlags.preview {
+			if len(rows) > attendanceImportFlags.previewRows && attendanceImportFlags.previewRows >= 0 {
+				rows = rows[:attendanceImportFlags.previewRows]
+			}
+			if cfg.Output == config.OutFormatPretty {
+				console.PrintSheetPreview(rows)
+				return nil
+			}
+			return printOutputJSONOrYAML(rows)
+		}
+
+		var buf bytes.Buffer
+		for _, row := range rows {
+			if row.Err != nil {
+				return fmt.Errorf("row %d: %w (use --preview to check the import mapping config)", row.Line, row.Err)
+			}
+			obj, err := row.Object()
+			if err != nil {
+				return fmt.Errorf("row %d: %w", row.Line, err)
+			}
+			buf.Write(obj)
+			buf.WriteByte('\n')
+		}
+		if len(rows) == 0 {
+			return errors.New("missing attendance periods, the spreadsheet has no rows after the header")
+		}
+		log.Debug().Int("rows", len(rows)).Msg("Parsed spreadsheet rows.")
+		return setAttendanceFromJSON(cmd, io.NopCloser(&buf))
+	},
+}
+
+func init() {
+	attendanceCmd.AddCommand(attendanceImportCmd)
+
+	attendanceImportCmd.Flags().BoolVar(&attendanceImportFlags.preview, "preview", false, "Only show how the first rows were parsed, without sending them")
+	attendanceImportCmd.Flags().IntVar(&attendanceImportFlags.previewRows, "preview-rows", attendanceImportFlags.previewRows, "Number of rows to show with --preview, or -1 for all")
+	attendanceImportCmd.Flags().StringVar(&attendanceImportFlags.format, "format", "", `Spreadsheet format, "csv" or "xlsx" (default from file extension)`)
+	attendanceImportCmd.Flags().StringVar(&attendanceImportFlags.sheet, "sheet", "", "Name or 1-based number of the Excel sheet to read (default import.xlsx.sheet config, or the first sheet)")
+	addAttendanceSetFlags(attendanceImportCmd)
+}
+
+// readSheetRows reads and parses the rows of a spreadsheet file, where "-"
+// means STDIN, using the import config.
+func readSheetRows(path string) ([]sheet.Row, error) {
+	format := attendanceImportFlags.format
+	if format == "" {
+		format = "csv"
+		if strings.EqualFold(filepath.Ext(path), ".xlsx") {
+			format = "xlsx"
+		}
+	}
+	switch format {
+	case "csv":
+		return readCSVRows(path)
+	case "xlsx":
+		return readXLSXRows(path)
+	default:
+		return nil, fmt.Errorf("unknown format %q, must be \"csv\" or \"xlsx\"", format)
+	}
+}
+
+func readCSVRows(path string) ([]sheet.Row, error) {
+	mapping, err := sheetMapping("import.csv.mapping", cfg.Import.CSV.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	var delimiter rune
+	if d := cfg.Import.CSV.Delimiter; d != "" {
+		if utf8.RuneCountInString(d) != 1 {
+			return nil, fmt.Errorf("import.csv.delimiter: must be a single character, got %q", d)
+		}
+		delimiter, _ = utf8.DecodeRuneInString(d)
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	records, err := sheet.ReadCSV(r, delimiter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := sheet.Parse(records, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("import.csv.mapping: %w", err)
+	}
+	return rows, nil
+}
+
+func readXLSXRows(path string) ([]sheet.Row, error) {
+	configKey, conf := "import.xlsx.mapping", cfg.Import.XLSX.Mapping
+	if conf.Start == "" {
+		configKey, conf = "import.csv.mapping", cfg.Import.CSV.Mapping
+	}
+	mapping, err := sheetMapping(configKey, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	wb, err := xlsx.Open(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	sheetName := attendanceImportFlags.sheet
+	if sheetName == "" {
+		sheetName = cfg.Import.XLSX.Sheet
+	}
+	// Date and time cells are converted to text that the mapping can parse
+	opts := xlsx.ReadOptions{
+		DateFormat: mapping.DateFormat,
+		TimeFormat: mapping.TimeFormat,
+	}
+	if mapping.Date == "" {
+		opts.DateTimeFormat = mapping.TimeFormat
+		if opts.DateTimeFormat == "" {
+			opts.DateTimeFormat = time.RFC3339
+		}
+	}
+	records, err := wb.Rows(sheetName, opts)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Str("sheet", sheetName).Strs("sheets", wb.Sheets).Msg("Read Excel sheet.")
+	rows, err := sheet.Parse(records, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configKey, err)
+	}
+	return rows, nil
+}
+
+func sheetMapping(configKey string, conf config.ImportMapping) (sheet.Mapping, error) {
+	mapping := sheet.Mapping{
+		Date:       conf.Date,
+		Start:      conf.Start,
+		End:        conf.End,
+		Duration:   conf.Duration,
+		Type:       conf.Type,
+		Comment:    conf.Comment,
+		Source:     conf.Source,
+		SourceID:   conf.SourceID,
+		DateFormat: conf.DateFormat,
+		TimeFormat: conf.TimeFormat,
+	}
+	if conf.TimeZone != "" {
+		loc, err := time.LoadLocation(conf.TimeZone)
+		if err != nil {
+			return sheet.Mapping{}, fmt.Errorf("%s.timeZone: %w", configKey, err)
+		}
+		mapping.Location = loc
+	}
+	return mapping, nil
+}
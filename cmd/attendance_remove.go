@@ -20,6 +20,8 @@ package cmd
 import (
 	"time"
 
+	"github.com/applejag/rootless-personio/pkg/hook"
+	"github.com/applejag/rootless-personio/pkg/queue"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -38,19 +40,52 @@ Provide the date in format YYYY-MM-DD, e.g 2023-01-25 for Jan 25, 2023.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		date, err := time.Parse(time.DateOnly, args[0])
+		if err != nil {
+			return err
+		}
+		hookDays := []submitHookDay{{Day: date.Format(time.DateOnly)}}
+
+		if err := checkEmployeeFlagOffline(); err != nil {
+			return err
+		}
+		if attendanceFlags.offline {
+			// Queued changes are always for yourself, whose employee ID is
+			// only known once logged in
+			if err := runSubmitHook(hook.PreSubmit, cfg.Hooks.PreSubmit, "remove", 0, hookDays); err != nil {
+				return err
+			}
+			return queueOperations([]queue.Operation{
+				queue.NewOperation(queue.ActionRemove, date.Format(time.DateOnly), nil, time.Now()),
+			})
+		}
 
 		client, err := newLoggedInClient()
 		if err != nil {
+			logOfflineHint(err)
+			return err
+		}
+		if err := actOnBehalfOf(client, "delete"); err != nil {
+			return err
+		}
+		if err := runSubmitHook(hook.PreSubmit, cfg.Hooks.PreSubmit, "remove", client.TargetEmployeeID(), hookDays); err != nil {
 			return err
 		}
 
 		err = client.DeleteAttendance(date)
+		recordAudit("attendance remove", client, queue.NewOperation(queue.ActionRemove, date.Format(time.DateOnly), nil, time.Now()), err, nil)
 		if err != nil {
 			return err
 		}
 		log.Info().
 			Str("day", date.Format(time.DateOnly)).
 			Msg("Successfully deleted attendance periods for day.")
+		if err := verifyAttendance(client, date, nil); err != nil {
+			return err
+		}
+
+		if err := runSubmitHook(hook.PostSubmit, cfg.Hooks.PostSubmit, "remove", client.TargetEmployeeID(), hookDays); err != nil {
+			return err
+		}
 
 		return printOutputJSONOrYAML(map[string]any{
 			"date": date.Format(time.DateOnly),
@@ -60,4 +95,6 @@ Provide the date in format YYYY-MM-DD, e.g 2023-01-25 for Jan 25, 2023.
 
 func init() {
 	attendanceCmd.AddCommand(attendanceRemoveCmd)
+
+	addEmployeeFlags(attendanceRemoveCmd.Flags(), true)
 }
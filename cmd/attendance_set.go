@@ -23,16 +23,29 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/applejag/rootless-personio/pkg/comment"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/hook"
 	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/applejag/rootless-personio/pkg/tidy"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"gopkg.in/typ.v4/slices"
 )
 
 var attendanceSetFlags = struct {
-	file string
+	file     string
+	plugin   string
+	comment  string
+	vars     []string
+	tidy     bool
+	noDedupe bool
+	force    bool
 }{}
 
 var attendanceSetCmd = &cobra.Command{
@@ -45,6 +58,8 @@ updated by this command.
 
 The input is provided by JSON objects in a file as specified with the --file flag,
 (or when set to "--file -", piping in JSON through STDIN).
+The input can also come from an importer plugin via the --plugin flag,
+e.g "--plugin jira -- --week 3" (see "plugin --help").
 The input should be a stream of Personio attendance periods. Example:
 
     {
@@ -70,10 +85,40 @@ It is incorrect to provide a JSON array with the elements.
 If you have a JSON array, you can convert it to a stream via jq like so:
 
     jq '.[]' my-file.json
+
+Periods imported from other time trackers can set the optional "source"
+and "source_id" fields, such as {"source": "timetrap", "source_id": "123"}.
+Imported entries are then remembered in the local mirror, so running the
+same import again skips days where nothing changed, and updates changed
+entries in place instead of replacing them with new periods.
+
+Days where Personio reports that a period was last changed by someone else,
+such as an HR correction, or was changed after it was imported, are not
+overwritten unless the --force flag is given.
+
+` + commentTemplateHelp + `
+
+In addition, all fields of the period's JSON object can be used, e.g
+{"ticket": "ABC-123", ...} can be used as {{.Ticket}}. The --comment
+flag sets the comment template of periods without a comment.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 && attendanceSetFlags.plugin == "" {
+			return errors.New("arguments are only allowed together with the --plugin flag")
+		}
 		var file io.ReadCloser = os.Stdin
-		if attendanceSetFlags.file != "-" {
+		switch {
+		case attendanceSetFlags.plugin != "" && attendanceSetFlags.file != "":
+			return errors.New("the --file and --plugin flags cannot be used together")
+		case attendanceSetFlags.plugin != "":
+			var err error
+			file, err = openPluginImport(attendanceSetFlags.plugin, args)
+			if err != nil {
+				return err
+			}
+		case attendanceSetFlags.file == "":
+			return errors.New("missing attendance periods, please provide the --file or --plugin flag")
+		case attendanceSetFlags.file != "-":
 			var err error
 			file, err = os.Open(attendanceSetFlags.file)
 			if err != nil {
@@ -82,83 +127,288 @@ If you have a JSON array, you can convert it to a stream via jq like so:
 		}
 		defer file.Close()
 
-		var periods []personio.Period
-		dec := json.NewDecoder(file)
-		for {
-			var p personio.Period
-			err := dec.Decode(&p)
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			if err != nil {
-				return fmt.Errorf("read periods: %w", err)
-			}
-			dur := p.End.Sub(p.Start)
+		return setAttendanceFromJSON(cmd, file)
+	},
+}
 
-			log.Debug().
-				Str("type", string(p.PeriodType)).
-				Time("start", p.Start).
-				Time("end", p.End).
-				Str("dur", dur.Truncate(time.Second).String()).
-				Str("comment", p.GetComment()).
-				Msg("Read attendance period.")
-
-			if dur < cfg.MinimumPeriodDuration {
-				log.Warn().
-					Str("type", string(p.PeriodType)).
-					Time("start", p.Start).
-					Time("end", p.End).
-					Str("dur", dur.Truncate(time.Second).String()).
-					Str("comment", p.GetComment()).
-					Str("minimumDuration", cfg.MinimumPeriodDuration.String()).
-					Msg("Skipping period because it has a too short duration.")
-				continue
-			}
+// setAttendanceFromJSON reads a stream of JSON attendance periods and sets
+// them in Personio, as described by "attendance set --help".
+func setAttendanceFromJSON(cmd *cobra.Command, file io.ReadCloser) error {
+	renderer, err := newCommentRenderer(attendanceSetFlags.vars)
+	if err != nil {
+		return err
+	}
+
+	periods, sources, err := readPeriodsJSON(file, renderer)
+	if err != nil {
+		return err
+	}
+	if len(periods) == 0 {
+		return errors.New("missing attendance periods, please provide JSON objects via STDIN, --file, or --plugin")
+	}
 
-			periods = append(periods, p)
+	var dedupe *importDedupe
+	if !attendanceFlags.offline && !attendanceSetFlags.noDedupe {
+		dedupe, err = newImportDedupe(periods, sources)
+		if err != nil {
+			return fmt.Errorf("look up imported entries: %w", err)
 		}
+		defer dedupe.Close()
+	}
 
+	script, err := loadTransformScript()
+	if err != nil {
+		return fmt.Errorf("load transform script: %w", err)
+	}
+	if script != nil {
+		periods, err = script.Apply(periods)
+		if err != nil {
+			return fmt.Errorf("transform periods: %w", err)
+		}
+		log.Debug().Int("periods", len(periods)).Msg("Transformed periods using script.")
 		if len(periods) == 0 {
-			return errors.New("missing attendance periods, please provide JSON objects via STDIN or --file")
+			return errors.New("transform script removed all attendance periods")
 		}
+	}
 
-		periodsPerDay := slices.GroupBy(periods, func(p personio.Period) string {
-			return p.Start.Format("2006-01-02")
-		})
-		slices.SortFunc(periodsPerDay, func(a, b slices.Grouping[string, personio.Period]) bool {
-			return a.Key < b.Key
+	periodsPerDay := slices.GroupBy(periods, func(p personio.Period) string {
+		return p.Start.Format("2006-01-02")
+	})
+	periodsPerDay = slices.Filter(periodsPerDay, func(group slices.Grouping[string, personio.Period]) bool {
+		if dedupe.Skip(group.Key) {
+			log.Info().Str("day", group.Key).Msg("Skipping day, as all its periods were imported before without changes.")
+			return false
+		}
+		return true
+	})
+	if len(periodsPerDay) == 0 {
+		log.Info().Msg("Nothing new to import.")
+		return printOutputJSONOrYAML(map[string]any{
+			"groups": []any{},
 		})
+	}
+	if attendanceSetFlags.tidy || (cfg.Tidy.Enabled && !cmd.Flags().Changed("tidy")) {
+		for i, group := range periodsPerDay {
+			periodsPerDay[i].Values = tidy.Periods(group.Values, cfg.Tidy.MaxGap)
+		}
+	}
+	slices.SortFunc(periodsPerDay, func(a, b slices.Grouping[string, personio.Period]) bool {
+		return a.Key < b.Key
+	})
+
+	hookDays := make([]submitHookDay, len(periodsPerDay))
+	for i, group := range periodsPerDay {
+		hookDays[i] = submitHookDay{Day: group.Key, Periods: group.Values}
+	}
+
+	if err := checkEmployeeFlagOffline(); err != nil {
+		return err
+	}
+	if attendanceFlags.offline {
+		// Queued changes are always for yourself, whose employee ID is
+		// only known once logged in
+		if err := runSubmitHook(hook.PreSubmit, cfg.Hooks.PreSubmit, "set", 0, hookDays); err != nil {
+			return err
+		}
+		ops := make([]queue.Operation, len(periodsPerDay))
+		for i, group := range periodsPerDay {
+			ops[i] = queue.NewOperation(queue.ActionSet, group.Key, group.Values, time.Now())
+		}
+		return queueOperations(ops)
+	}
+
+	client, err := newLoggedInClient()
+	if err != nil {
+		logOfflineHint(err)
+		return err
+	}
+	if err := actOnBehalfOf(client, "edit"); err != nil {
+		return err
+	}
+	if err := runSubmitHook(hook.PreSubmit, cfg.Hooks.PreSubmit, "set", client.TargetEmployeeID(), hookDays); err != nil {
+		return err
+	}
+	if !attendanceSetFlags.force {
+		days := make([]string, len(periodsPerDay))
+		for i, group := range periodsPerDay {
+			days[i] = group.Key
+		}
+		if err := checkForeignChanges(client, days, dedupe.WrittenAt()); err != nil {
+			return err
+		}
+	}
 
-		client, err := newLoggedInClient()
+	type PerDay struct {
+		Day     string            `json:"day"`
+		Periods []personio.Period `json:"periods"`
+	}
+	var printableGroups []PerDay
+
+	for _, group := range periodsPerDay {
+		err = client.SetAttendance(group.Values[0].Start, group.Values)
+		recordAudit("attendance set", client, queue.NewOperation(queue.ActionSet, group.Key, group.Values, time.Now()), err, nil)
 		if err != nil {
 			return err
 		}
+		log.Info().
+			Str("day", group.Key).
+			Int("periods", len(group.Values)).
+			Msg("Successfully updated attendance for day.")
+		date, err := time.Parse(time.DateOnly, group.Key)
+		if err != nil {
+			return err
+		}
+		if err := verifyAttendance(client, date, group.Values); err != nil {
+			return err
+		}
+		if err := dedupe.Done(group.Key, time.Now()); err != nil {
+			log.Warn().Err(err).Str("day", group.Key).Msg("Failed recording imported entries, they will be sent again on the next import.")
+		}
+		printableGroups = append(printableGroups, PerDay{
+			Day:     group.Key,
+			Periods: group.Values,
+		})
+	}
 
-		type PerDay struct {
-			Day     string            `json:"day"`
-			Periods []personio.Period `json:"periods"`
+	if err := runSubmitHook(hook.PostSubmit, cfg.Hooks.PostSubmit, "set", client.TargetEmployeeID(), hookDays); err != nil {
+		return err
+	}
+
+	return printOutputJSONOrYAML(map[string]any{
+		"groups": printableGroups,
+	})
+}
+
+// readPeriodsJSON reads a stream of JSON attendance periods, rendering
+// their comment templates and skipping periods that are too short.
+func readPeriodsJSON(file io.ReadCloser, renderer *comment.Renderer) ([]personio.Period, []importSource, error) {
+	var periods []personio.Period
+	var sources []importSource
+	dec := json.NewDecoder(file)
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read periods: %w", err)
+		}
+		var p personio.Period
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, nil, fmt.Errorf("read periods: %w", err)
+		}
+		if err := renderPeriodComment(renderer, &p, raw); err != nil {
+			return nil, nil, err
 		}
-		var printableGroups []PerDay
+		var src importSource
+		if err := json.Unmarshal(raw, &src); err != nil {
+			return nil, nil, fmt.Errorf("read periods: %w", err)
+		}
+		dur := p.End.Sub(p.Start)
 
-		for _, group := range periodsPerDay {
-			err = client.SetAttendance(group.Values[0].Start, group.Values)
-			if err != nil {
-				return err
+		log.Debug().
+			Str("type", string(p.PeriodType)).
+			Time("start", p.Start).
+			Time("end", p.End).
+			Str("dur", dur.Truncate(time.Second).String()).
+			Str("comment", p.GetComment()).
+			Msg("Read attendance period.")
+
+		if dur < cfg.MinimumPeriodDuration {
+			log.Warn().
+				Str("type", string(p.PeriodType)).
+				Time("start", p.Start).
+				Time("end", p.End).
+				Str("dur", dur.Truncate(time.Second).String()).
+				Str("comment", p.GetComment()).
+				Str("minimumDuration", cfg.MinimumPeriodDuration.String()).
+				Msg("Skipping period because it has a too short duration.")
+			continue
+		}
+
+		periods = append(periods, p)
+		sources = append(sources, src)
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, nil, fmt.Errorf("read periods: %w", err)
+	}
+	return periods, sources, nil
+}
+
+// checkForeignChanges returns an error if any of the days, which must be
+// sorted, have periods that someone else changed, so they are not undone
+// by overwriting them.
+func checkForeignChanges(client *personio.Client, days []string, writtenAt map[uuid.UUID]time.Time) error {
+	if len(days) == 0 {
+		return nil
+	}
+	start, err := time.Parse(time.DateOnly, days[0])
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(time.DateOnly, days[len(days)-1])
+	if err != nil {
+		return err
+	}
+	r := datespec.Range{Start: start, End: end}
+	overwritten := make(map[string]bool, len(days))
+	for _, day := range days {
+		overwritten[day] = true
+	}
+	var changedDays []string
+	err = forEachCalendarMonth(client, r, func(cal *personio.AttendanceCalendar, _ datespec.Range) error {
+		for _, change := range personio.FindForeignChanges(cal, client.EmployeeID, writtenAt) {
+			if !overwritten[change.Day] {
+				continue
+			}
+			log.Warn().
+				Str("day", change.Day).
+				Stringer("period", change.PeriodID).
+				Time("updatedAt", change.UpdatedAt).
+				Int("updatedBy", change.UpdatedBy).
+				Msg("Period was changed by someone else.")
+			if len(changedDays) == 0 || changedDays[len(changedDays)-1] != change.Day {
+				changedDays = append(changedDays, change.Day)
 			}
-			log.Info().
-				Str("day", group.Key).
-				Int("periods", len(group.Values)).
-				Msg("Successfully updated attendance for day.")
-			printableGroups = append(printableGroups, PerDay{
-				Day:     group.Key,
-				Periods: group.Values,
-			})
 		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("check for changes by others: %w", err)
+	}
+	if len(changedDays) > 0 {
+		return fmt.Errorf("refusing to overwrite changes by others, such as HR corrections, on %s; use --force to overwrite anyway",
+			strings.Join(changedDays, ", "))
+	}
+	return nil
+}
 
-		return printOutputJSONOrYAML(map[string]any{
-			"groups": printableGroups,
-		})
-	},
+// renderPeriodComment renders the period's comment template, or the
+// --comment template if the period has no comment, using the fields of
+// the period's JSON object as variables.
+func renderPeriodComment(renderer *comment.Renderer, p *personio.Period, raw json.RawMessage) error {
+	text := p.GetComment()
+	if text == "" {
+		text = attendanceSetFlags.comment
+	}
+	if !comment.IsTemplate(text) {
+		if text != "" {
+			p.Comment = &text
+		}
+		return nil
+	}
+	var entry comment.Vars
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return fmt.Errorf("read period fields: %w", err)
+	}
+	rendered, err := renderer.Render(text, entry)
+	if err != nil {
+		return fmt.Errorf("period starting at %s: %w", p.Start.Format(time.RFC3339), err)
+	}
+	p.Comment = &rendered
+	return nil
 }
 
 func init() {
@@ -166,5 +416,16 @@ func init() {
 
 	attendanceSetCmd.Flags().StringVarP(&attendanceSetFlags.file, "file", "f", "", `Attendance periods JSON file, "-" means STDIN`)
 	attendanceSetCmd.MarkFlagFilename("file", "json")
-	attendanceSetCmd.MarkFlagRequired("file")
+	attendanceSetCmd.Flags().StringVar(&attendanceSetFlags.plugin, "plugin", "", `Read the periods from an importer plugin, with any arguments after "--" (see "plugin --help")`)
+	addAttendanceSetFlags(attendanceSetCmd)
+}
+
+// addAttendanceSetFlags adds the flags used by [setAttendanceFromJSON].
+func addAttendanceSetFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&attendanceSetFlags.comment, "comment", "c", "", "Comment template of periods without a comment")
+	cmd.Flags().StringArrayVar(&attendanceSetFlags.vars, "var", nil, `Comment template variable, as "key=value"`)
+	cmd.Flags().BoolVar(&attendanceSetFlags.noDedupe, "no-dedupe", false, `Send periods with a "source_id" even if they were imported before`)
+	cmd.Flags().BoolVar(&attendanceSetFlags.force, "force", false, "Overwrite days with periods that were changed by someone else, such as HR corrections")
+	cmd.Flags().BoolVar(&attendanceSetFlags.tidy, "tidy", false, "Merge adjacent and duplicate periods, as configured by the tidy config (default tidy.enabled config)")
+	addEmployeeFlags(cmd.Flags(), true)
 }
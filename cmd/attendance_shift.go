@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/hook"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var attendanceShiftFlags = struct {
+	by        time.Duration
+	extendEnd time.Duration
+	dryRun    bool
+}{}
+
+var attendanceShiftCmd = &cobra.Command{
+	Use:   "shift <YYYY-MM-DD>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Shift or extend the attendance periods of a day",
+	Long: `Adjust the existing attendance periods of a day in place, without
+having to provide the whole day again.
+
+The --by flag moves all periods of the day, and --extend-end moves the end
+of the day's last period. Negative durations move the times earlier, such
+as "--by=-30m". The adjusted periods are checked for overlaps before they
+are sent.
+
+Provide the date in format YYYY-MM-DD, e.g 2023-01-25 for Jan 25, 2023.`,
+	Example: `  rootless-personio attendance shift 2024-05-02 --by 30m
+  rootless-personio attendance shift 2024-05-02 --extend-end 1h
+  rootless-personio attendance shift 2024-05-02 --by=-15m --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		date, err := time.Parse(time.DateOnly, args[0])
+		if err != nil {
+			return err
+		}
+		if attendanceShiftFlags.by == 0 && attendanceShiftFlags.extendEnd == 0 {
+			return errors.New("missing adjustment, please provide --by or --extend-end")
+		}
+		return adjustDay("shift", date, attendanceShiftFlags.dryRun, func(periods []personio.Period) []personio.Period {
+			return shiftPeriods(periods, attendanceShiftFlags.by, attendanceShiftFlags.extendEnd)
+		})
+	},
+}
+
+func init() {
+	attendanceCmd.AddCommand(attendanceShiftCmd)
+
+	attendanceShiftCmd.Flags().DurationVar(&attendanceShiftFlags.by, "by", 0, "Move all periods of the day by this duration")
+	attendanceShiftCmd.Flags().DurationVar(&attendanceShiftFlags.extendEnd, "extend-end", 0, "Move the end of the day's last period by this duration")
+	attendanceShiftCmd.Flags().BoolVar(&attendanceShiftFlags.dryRun, "dry-run", false, "Print the adjusted periods instead of sending them")
+	addEmployeeFlags(attendanceShiftCmd.Flags(), true)
+}
+
+// shiftPeriods moves all periods by the "by" duration, and then the end of
+// the last period by the "extendEnd" duration. The periods keep their IDs.
+func shiftPeriods(periods []personio.Period, by, extendEnd time.Duration) []personio.Period {
+	shifted := make([]personio.Period, len(periods))
+	for i, p := range periods {
+		p.Start = p.Start.Add(by)
+		p.End = p.End.Add(by)
+		shifted[i] = p
+	}
+	sort.Slice(shifted, func(i, j int) bool {
+		return shifted[i].Start.Before(shifted[j].Start)
+	})
+	if extendEnd != 0 {
+		shifted[len(shifted)-1].End = shifted[len(shifted)-1].End.Add(extendEnd)
+	}
+	return shifted
+}
+
+// adjustDay fetches the day's attendance periods, adjusts them, and sends
+// them back, unless dryRun is set, in which case they are only printed.
+func adjustDay(action string, date time.Time, dryRun bool, adjust func(periods []personio.Period) []personio.Period) error {
+	if attendanceFlags.offline {
+		return fmt.Errorf("%s needs to read the attendance from Personio, and cannot be used with --offline", action)
+	}
+
+	client, err := newLoggedInClient()
+	if err != nil {
+		return err
+	}
+	if err := actOnBehalfOf(client, "edit"); err != nil {
+		return err
+	}
+	cal, err := client.GetMyAttendanceCalendar(date, date)
+	if err != nil {
+		return err
+	}
+	byDay, err := calendarPeriodsByDay(cal)
+	if err != nil {
+		return err
+	}
+	dayStr := date.Format(time.DateOnly)
+	periods := byDay[dayStr]
+	if len(periods) == 0 {
+		return fmt.Errorf("found no attendance on %s to %s", dayStr, action)
+	}
+
+	periods = adjust(periods)
+	if err := personio.ValidatePeriods(periods); err != nil {
+		return fmt.Errorf("invalid periods after %s: %w", action, err)
+	}
+	if dryRun {
+		return printOutputJSONOrYAML(periods)
+	}
+
+	hookDays := []submitHookDay{{Day: dayStr, Periods: periods}}
+	if err := runSubmitHook(hook.PreSubmit, cfg.Hooks.PreSubmit, action, client.TargetEmployeeID(), hookDays); err != nil {
+		return err
+	}
+	err = client.SetAttendance(date, periods)
+	recordAudit("attendance "+action, client, queue.NewOperation(queue.ActionSet, dayStr, periods, time.Now()), err, nil)
+	if err != nil {
+		return err
+	}
+	log.Info().
+		Str("action", action).
+		Str("day", dayStr).
+		Int("periods", len(periods)).
+		Msg("Successfully adjusted attendance for day.")
+	if err := verifyAttendance(client, date, periods); err != nil {
+		return err
+	}
+	if err := runSubmitHook(hook.PostSubmit, cfg.Hooks.PostSubmit, action, client.TargetEmployeeID(), hookDays); err != nil {
+		return err
+	}
+	return printOutputJSONOrYAML(periods)
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var attendanceShowCmd = &cobra.Command{
+	Use:   "show <YYYY-MM-DD>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Show a single day in detail",
+	Long: `Show a single day in detail, including each attendance period with its
+times, type, comment, and project, as well as the day's totals, status,
+alerts, and the day's ID in Personio.
+
+Provide the date in format YYYY-MM-DD, e.g 2023-01-25 for Jan 25, 2023.
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		date, err := time.Parse(time.DateOnly, args[0])
+		if err != nil {
+			return err
+		}
+
+		client, err := newLoggedInClient()
+		if err != nil {
+			return err
+		}
+		if err := actOnBehalfOf(client, "view"); err != nil {
+			return err
+		}
+		cal, err := client.GetMyAttendanceCalendar(date, date)
+		if err != nil {
+			return err
+		}
+		detail, err := report.NewDayDetail(cal, date)
+		if err != nil {
+			return err
+		}
+
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintDayDetail(detail)
+			return nil
+		}
+		return printOutputJSONOrYAML(detail)
+	},
+}
+
+func init() {
+	attendanceCmd.AddCommand(attendanceShowCmd)
+
+	addEmployeeFlags(attendanceShowCmd.Flags(), false)
+}
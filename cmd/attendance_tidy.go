@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/tidy"
+	"github.com/spf13/cobra"
+)
+
+var attendanceTidyFlags = struct {
+	maxGap time.Duration
+	dryRun bool
+}{}
+
+var attendanceTidyCmd = &cobra.Command{
+	Use:   "tidy <YYYY-MM-DD>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Merge adjacent and duplicate attendance periods of a day",
+	Long: `Merge the attendance periods of a day that are of the same type and
+project, and that overlap or are separated by a gap of at most the
+tidy.maxGap config. Duplicate periods are collapsed into one.
+
+Merged periods get the distinct comments of all merged periods.
+
+The same tidying can be applied to "attendance set" via its --tidy flag.
+
+Provide the date in format YYYY-MM-DD, e.g 2023-01-25 for Jan 25, 2023.`,
+	Example: `  rootless-personio attendance tidy 2024-05-02
+  rootless-personio attendance tidy 2024-05-02 --max-gap 15m --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		date, err := time.Parse(time.DateOnly, args[0])
+		if err != nil {
+			return err
+		}
+		maxGap := cfg.Tidy.MaxGap
+		if cmd.Flags().Changed("max-gap") {
+			maxGap = attendanceTidyFlags.maxGap
+		}
+		return adjustDay("tidy", date, attendanceTidyFlags.dryRun, func(periods []personio.Period) []personio.Period {
+			return tidy.Periods(periods, maxGap)
+		})
+	},
+}
+
+func init() {
+	attendanceCmd.AddCommand(attendanceTidyCmd)
+
+	attendanceTidyCmd.Flags().DurationVar(&attendanceTidyFlags.maxGap, "max-gap", 0, "Longest gap between periods that are merged (default tidy.maxGap config)")
+	attendanceTidyCmd.Flags().BoolVar(&attendanceTidyFlags.dryRun, "dry-run", false, "Print the tidied periods instead of sending them")
+	addEmployeeFlags(attendanceTidyCmd.Flags(), true)
+}
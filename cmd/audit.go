@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/audit"
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/hook"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/applejag/rootless-personio/pkg/storage"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var auditListFlags = struct {
+	failed bool
+}{}
+
+var auditReplayFlags = struct {
+	force bool
+}{}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect and replay attendance changes sent to Personio",
+	Long: `Inspect and replay attendance changes sent to Personio.
+
+All attendance changes made by "attendance set", "attendance remove",
+"flush", and the "set_attendance" MCP tool are recorded in a local journal,
+including the ones Personio rejected. Disable it via the "audit.enabled"
+config.`,
+}
+
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists attendance changes from the audit journal",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := loadAuditEntries()
+		if err != nil {
+			return err
+		}
+		if auditListFlags.failed {
+			var failed []audit.Entry
+			for _, e := range entries {
+				if e.Failed() && !audit.IsReplayed(entries, e.ID) {
+					failed = append(failed, e)
+				}
+			}
+			entries = failed
+		}
+		if cfg.Output != config.OutFormatPretty {
+			return printOutputJSONOrYAML(entries)
+		}
+		for _, e := range entries {
+			status := "ok"
+			if e.Failed() {
+				status = "failed: " + e.Error
+				if audit.IsReplayed(entries, e.ID) {
+					status = "replayed, " + status
+				}
+			}
+			fmt.Printf("%s  %s  %-6s %s  %s (%s)\n",
+				e.ID.String()[:8], e.Time.Local().Format("2006-01-02 15:04"),
+				e.Action, e.Day, status, e.Command)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No entries found.")
+		}
+		return nil
+	},
+}
+
+var auditReplayCmd = &cobra.Command{
+	Use:   "replay <entry-id>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Sends a failed attendance change from the audit journal again",
+	Long: `Sends a failed attendance change from the audit journal again,
+e.g after logging in again when the change failed due to an expired session.
+
+The entry ID is shown by "audit list --failed". A unique prefix of the ID
+is enough. Changes that already succeeded are only sent again with --force.
+
+The post-submit hook is run when the change is sent, while the pre-submit
+hook is not, as it already ran for the original change.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := loadAuditEntries()
+		if err != nil {
+			return err
+		}
+		entry, err := audit.Find(entries, args[0])
+		if err != nil {
+			return err
+		}
+		if !auditReplayFlags.force {
+			if !entry.Failed() {
+				return fmt.Errorf("audit entry %s succeeded, use --force to send it again", entry.ID)
+			}
+			if audit.IsReplayed(entries, entry.ID) {
+				return fmt.Errorf("audit entry %s was already replayed, use --force to send it again", entry.ID)
+			}
+		}
+
+		client, err := newLoggedInClient()
+		if err != nil {
+			return err
+		}
+		if entry.EmployeeID != 0 && entry.EmployeeID != client.EmployeeID {
+			employeeFlags.employee = strconv.Itoa(entry.EmployeeID)
+			action := "edit"
+			if entry.Action == queue.ActionRemove {
+				action = "delete"
+			}
+			if err := actOnBehalfOf(client, action); err != nil {
+				return err
+			}
+		}
+
+		op := entry.Operation()
+		err = sendOperation(client, op)
+		recordAudit("audit replay", client, op, err, &entry.ID)
+		if err != nil {
+			return fmt.Errorf("replay %s of %s: %w", op.Action, op.Day, err)
+		}
+		log.Info().
+			Str("action", string(op.Action)).
+			Str("day", op.Day).
+			Msg("Successfully replayed audit entry.")
+
+		hookDays := []submitHookDay{{Day: op.Day, Periods: op.Periods}}
+		if err := runSubmitHook(hook.PostSubmit, cfg.Hooks.PostSubmit, string(op.Action), client.TargetEmployeeID(), hookDays); err != nil {
+			return err
+		}
+		return printOutputJSONOrYAML(map[string]any{
+			"replayed": entry,
+		})
+	},
+}
+
+// sendOperation sends a single attendance mutation to Personio.
+func sendOperation(client *personio.Client, op queue.Operation) error {
+	date, err := time.Parse(time.DateOnly, op.Day)
+	if err != nil {
+		return err
+	}
+	switch op.Action {
+	case queue.ActionSet:
+		if len(op.Periods) == 0 {
+			return fmt.Errorf("no periods to set")
+		}
+		return client.SetAttendance(op.Periods[0].Start, op.Periods)
+	case queue.ActionRemove:
+		return client.DeleteAttendance(date)
+	default:
+		return fmt.Errorf("unknown action: %q", op.Action)
+	}
+}
+
+// recordAudit adds the attendance mutation to the audit journal, where a
+// non-nil err marks it as failed. Failing to write the journal only logs a
+// warning, as the mutation itself has already been sent.
+func recordAudit(command string, client *personio.Client, op queue.Operation, err error, replayOf *uuid.UUID) {
+	if !cfg.Audit.Enabled {
+		return
+	}
+	store, key, storeErr := auditStorage()
+	if storeErr != nil {
+		log.Warn().Err(storeErr).Msg("Failed opening audit journal.")
+		return
+	}
+	entry := audit.NewEntry(command, client.TargetEmployeeID(), op, err, time.Now())
+	entry.ReplayOf = replayOf
+	if err := audit.Append(store, key, entry); err != nil {
+		log.Warn().Err(err).Msg("Failed writing audit journal.")
+		return
+	}
+	if entry.Failed() {
+		log.Info().
+			Str("entry", entry.ID.String()[:8]).
+			Msg(`Recorded failed change, send it again later via "audit replay".`)
+	}
+}
+
+func loadAuditEntries() ([]audit.Entry, error) {
+	store, key, err := auditStorage()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := audit.Load(store, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 && !cfg.Audit.Enabled {
+		return nil, errors.New(`the audit journal is disabled, enable it via the "audit.enabled" config`)
+	}
+	return entries, nil
+}
+
+// auditStorage returns where the audit journal is stored, which is the
+// audit.path file when set, or else in the configured storage.
+func auditStorage() (storage.Backend, string, error) {
+	if cfg.Audit.Path != "" {
+		return storage.NewFS(filepath.Dir(cfg.Audit.Path)), filepath.Base(cfg.Audit.Path), nil
+	}
+	store, err := openStorage()
+	return store, audit.DefaultKey, err
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditListCmd)
+	auditCmd.AddCommand(auditReplayCmd)
+
+	auditListCmd.Flags().BoolVar(&auditListFlags.failed, "failed", false, "Only list failed changes that have not been replayed")
+	auditReplayCmd.Flags().BoolVar(&auditReplayFlags.force, "force", false, "Send the change even if it already succeeded")
+	auditReplayCmd.Flags().BoolVarP(&employeeFlags.yes, "yes", "y", false, "Skip confirmation when changing another employee's attendance")
+}
@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/applejag/rootless-personio/pkg/auth"
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage logging in to Personio",
+}
+
+var authConfirmDeviceCmd = &cobra.Command{
+	Use:   "confirm-device <code-or-link>",
+	Short: "Confirms this device using the token from Personio's email",
+	Long: `Confirms this device using the login token or link from Personio's
+"[Personio] Confirm login in your account" email, and then logs in.
+
+Personio sends this email when it doesn't recognize the device you log in
+from. The confirmation is tied to the login attempt that triggered the
+email, which is remembered between runs when auth.rememberDevice is
+enabled (the default).`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cfg.Auth.Email == "" {
+			log.Error().Msg("Missing email! Must set auth.email config or PERSONIO_AUTH_EMAIL env var.")
+			return errors.New("missing credentials")
+		}
+		baseURL, err := resolveBaseURL()
+		if err != nil {
+			return err
+		}
+		client, err := personio.New(baseURL)
+		if err != nil {
+			return err
+		}
+		client.SetTimeouts(personio.Timeouts(cfg.HTTP.Timeouts))
+		if err := preflight(client.BaseURL); err != nil {
+			return err
+		}
+		if cfg.Auth.RememberDevice {
+			if err := useSessionJar(client, cfg.Auth.Email); err != nil {
+				log.Warn().Err(err).Msg("Failed loading remembered cookies, continuing without them.")
+			}
+		}
+
+		token := personio.ParseUnlockToken(args[0])
+		if token == "" {
+			return errors.New("missing login token")
+		}
+		if err := client.UnlockWithToken(token); err != nil {
+			log.Error().Msg("Failed to confirm device. The token may have expired, or belong to a different login attempt.\n" +
+				"\tRun any command that logs in to get a new email, and try again with the new token.")
+			return fmt.Errorf("confirm device: %w", err)
+		}
+		log.Info().Msg("Device confirmed.")
+
+		if err := login(client); err != nil {
+			return err
+		}
+		log.Info().Int("employeeId", client.EmployeeID).
+			Msg("Successfully logged in.")
+		return nil
+	},
+}
+
+var authBootstrapFlags = struct {
+	cookiesFile string
+}{}
+
+var authBootstrapCmd = &cobra.Command{
+	Use:   "bootstrap --cookies-file <file>",
+	Short: "Remembers the session of an already confirmed device, without prompting",
+	Long: `Remembers the cookies of an already logged in and confirmed device, such
+as exported from your web browser, so that later runs log in without
+Personio asking to confirm the device. This never prompts, which makes it
+suitable for setting up containers and other non-interactive deployments.
+
+The cookies file may be in the Netscape "cookies.txt" format (as exported
+by curl and browser extensions), JSON (as exported by browser extensions,
+or a Playwright storage state), or the value of a "Cookie" HTTP header.
+Use "-" to read it from STDIN.
+
+The cookies are stored the same as by auth.rememberDevice, for the account
+in auth.email, and are then verified by logging in. Run it once per
+storage, such as in an init container that shares the storage volume.`,
+	Example: `  rootless-personio auth bootstrap --cookies-file cookies.txt
+  docker run -i -e PERSONIO_AUTH_EMAIL=me@example.com ... \
+    rootless-personio auth bootstrap --cookies-file - < cookies.txt`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if authBootstrapFlags.cookiesFile == "" {
+			return errors.New("missing --cookies-file flag")
+		}
+		if cfg.Auth.Email == "" {
+			log.Error().Msg("Missing email! Must set auth.email config or PERSONIO_AUTH_EMAIL env var.")
+			return errors.New("missing email, which the cookies are stored for")
+		}
+		if !cfg.Auth.RememberDevice {
+			return errors.New("auth.rememberDevice is disabled, so later runs would not use the cookies")
+		}
+		var data []byte
+		var err error
+		if authBootstrapFlags.cookiesFile == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(authBootstrapFlags.cookiesFile)
+		}
+		if err != nil {
+			return fmt.Errorf("read cookies file: %w", err)
+		}
+		baseURL, err := resolveBaseURL()
+		if err != nil {
+			return err
+		}
+		client, err := newClient(baseURL)
+		if err != nil {
+			return err
+		}
+		u, err := url.Parse(client.BaseURL)
+		if err != nil {
+			return fmt.Errorf("parse base URL: %w", err)
+		}
+		cookies, err := auth.ParseCookiesFile(data, u.Hostname(), time.Now())
+		if err != nil {
+			return err
+		}
+		if err := preflight(client.BaseURL); err != nil {
+			return err
+		}
+		if err := useSessionJar(client, cfg.Auth.Email); err != nil {
+			return err
+		}
+		// Saves the persistent cookies to the session storage
+		if err := client.SetCookies(cookies); err != nil {
+			return err
+		}
+		log.Info().Int("cookies", len(cookies)).Msg("Stored cookies.")
+
+		if err := client.ResumeSession(); err != nil {
+			log.Info().Err(err).Msg("The cookies have no logged in session, logging in to verify them.")
+			if err := login(client); err != nil {
+				return fmt.Errorf("verify cookies: %w", err)
+			}
+		}
+		log.Info().Int("employeeId", client.EmployeeID).
+			Msg("Successfully logged in.")
+		return nil
+	},
+}
+
+func init() {
+	authCmd.AddCommand(authConfirmDeviceCmd)
+	authCmd.AddCommand(authBootstrapCmd)
+	rootCmd.AddCommand(authCmd)
+
+	authBootstrapCmd.Flags().StringVar(&authBootstrapFlags.cookiesFile, "cookies-file", "", `File of exported cookies, or "-" for STDIN`)
+}
+
+// newAuthProvider returns the auth provider selected by the auth.provider
+// config.
+func newAuthProvider(creds config.Auth) (auth.Provider, error) {
+	switch creds.Provider {
+	case "", "password":
+		return auth.PasswordProvider{
+			Email:    creds.Email,
+			Password: creds.Password,
+		}, nil
+	case "cookie":
+		if creds.Cookie == "" {
+			log.Error().Msg("Missing cookie! Must set auth.cookie config or PERSONIO_AUTH_COOKIE env var.")
+			return nil, errors.New("missing credentials")
+		}
+		return auth.CookieProvider{Cookie: creds.Cookie}, nil
+	case "sso":
+		if !isInteractive() {
+			return nil, fmt.Errorf("the sso auth provider must be run from a terminal: %w", errNoPrompt("the session's cookies"))
+		}
+		return auth.SSOProvider{
+			OpenURL:      openBrowser,
+			PromptCookie: promptCookie,
+		}, nil
+	case "headlessBrowser":
+		return auth.HeadlessBrowserProvider{
+			Command:  creds.BrowserCommand,
+			Email:    creds.Email,
+			Password: creds.Password,
+		}, nil
+	default:
+		return nil, fmt.Errorf("auth.provider: unknown provider %q, must be one of: password, cookie, sso, headlessBrowser", creds.Provider)
+	}
+}
+
+func promptCookie() (string, error) {
+	log.Warn().Msg("Please log in to Personio in your web browser.\n" +
+		"\tThen open the developer tools, find a request to Personio in the network tab,\n" +
+		"\tand copy the value of its \"Cookie\" request header here:")
+	var cookie string
+	prompt := &survey.Password{
+		Message: "Cookie:",
+	}
+	if err := survey.AskOne(prompt, &cookie, survey.WithValidator(survey.Required)); err != nil {
+		return "", err
+	}
+	return cookie, nil
+}
+
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,192 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/bot"
+	"github.com/applejag/rootless-personio/pkg/clock"
+	"github.com/applejag/rootless-personio/pkg/hook"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var botCmd = &cobra.Command{
+	Use:   "bot",
+	Short: "Run a chat bot that logs attendance from chat messages",
+	Long: `Run a chat bot, until interrupted, that accepts commands from your own
+chat account, such as:
+
+  log 9-17:30 today
+  log 8-12 13-17 yesterday
+  how many vacation days left?
+  today
+
+The bot only replies to the user configured in the bot config, and
+ignores everyone else. Logged periods go through the same hooks, audit
+journal, and --verify checks as the "attendance" commands.`,
+}
+
+var botTelegramCmd = &cobra.Command{
+	Use:   "telegram",
+	Short: "Run the chat bot on Telegram",
+	Long: `Run the chat bot on Telegram, using a bot created via @BotFather.
+
+Send any message to the bot and look for your user ID in the debug logs,
+then set it as bot.telegram.userId.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf := cfg.Bot.Telegram
+		if conf.Token == "" {
+			return errors.New("missing bot token, please set the bot.telegram.token config")
+		}
+		if conf.UserID == 0 {
+			return errors.New("missing your user ID, please set the bot.telegram.userId config")
+		}
+		userID := strconv.FormatInt(conf.UserID, 10)
+		return runBot(&bot.Telegram{Token: conf.Token}, func(m bot.Message) bool {
+			return m.Sender == userID
+		})
+	},
+}
+
+var botMatrixCmd = &cobra.Command{
+	Use:   "matrix",
+	Short: "Run the chat bot on Matrix",
+	Long: `Run the chat bot on Matrix, using a separate account for the bot.
+
+Invite the bot's account to a room, join the room with the bot, and set
+the room's ID as bot.matrix.roomId. Encrypted rooms are not supported.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf := cfg.Bot.Matrix
+		switch {
+		case conf.Homeserver == "":
+			return errors.New("missing homeserver, please set the bot.matrix.homeserver config")
+		case conf.AccessToken == "":
+			return errors.New("missing access token, please set the bot.matrix.accessToken config")
+		case conf.RoomID == "":
+			return errors.New("missing room, please set the bot.matrix.roomId config")
+		case conf.UserID == "":
+			return errors.New("missing your user ID, please set the bot.matrix.userId config")
+		}
+		return runBot(&bot.Matrix{
+			Homeserver:  conf.Homeserver,
+			AccessToken: conf.AccessToken,
+			RoomID:      conf.RoomID,
+		}, func(m bot.Message) bool {
+			return m.Sender == conf.UserID
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(botCmd)
+	botCmd.AddCommand(botTelegramCmd)
+	botCmd.AddCommand(botMatrixCmd)
+}
+
+func runBot(transport bot.Transport, allowed func(m bot.Message) bool) error {
+	client, err := newLoggedInClient()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Info().Msg("Bot is running.")
+	return bot.Run(ctx, transport, &bot.Dispatcher{
+		Backend: &botBackend{client: client},
+	}, func(m bot.Message) bool {
+		log.Debug().Str("sender", m.Sender).Str("chat", m.Chat).Msg("Received message.")
+		return allowed(m)
+	})
+}
+
+// botBackend implements [bot.Backend] using a logged in client.
+type botBackend struct {
+	client *personio.Client
+}
+
+func (b *botBackend) Log(date time.Time, periods []personio.Period) ([]personio.Period, error) {
+	existing, err := b.client.GetAttendancePeriods(date)
+	if err != nil {
+		return nil, fmt.Errorf("get existing periods: %w", err)
+	}
+	dayPeriods := append(existing, periods...)
+	if err := personio.ValidatePeriods(dayPeriods); err != nil {
+		return nil, err
+	}
+	const action = "log"
+	dayStr := date.Format(time.DateOnly)
+	hookDays := []submitHookDay{{Day: dayStr, Periods: dayPeriods}}
+	if err := runSubmitHook(hook.PreSubmit, cfg.Hooks.PreSubmit, action, b.client.TargetEmployeeID(), hookDays); err != nil {
+		return nil, err
+	}
+	err = b.client.SetAttendance(date, dayPeriods)
+	recordAudit("bot log", b.client, queue.NewOperation(queue.ActionSet, dayStr, dayPeriods, time.Now()), err, nil)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().
+		Str("day", dayStr).
+		Int("periods", len(periods)).
+		Msg("Logged attendance from chat.")
+	if err := verifyAttendance(b.client, date, dayPeriods); err != nil {
+		return nil, err
+	}
+	if err := runSubmitHook(hook.PostSubmit, cfg.Hooks.PostSubmit, action, b.client.TargetEmployeeID(), hookDays); err != nil {
+		return nil, err
+	}
+	return dayPeriods, nil
+}
+
+func (b *botBackend) Worked(date time.Time) (time.Duration, error) {
+	periods, err := b.client.GetAttendancePeriods(date)
+	if err != nil {
+		return 0, err
+	}
+	worked := workedDuration(periods)
+	path, err := clockStatePath()
+	if err != nil {
+		return 0, err
+	}
+	state, err := clock.Load(path)
+	if err != nil {
+		return 0, err
+	}
+	return worked + state.Worked(time.Now(), cfg.Clock.MinBreak), nil
+}
+
+func (b *botBackend) Vacation(now time.Time) (report.VacationBalance, error) {
+	year, err := currentLeaveYear(now)
+	if err != nil {
+		return report.VacationBalance{}, err
+	}
+	return vacationBalance(b.client, year, now)
+}
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/canary"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var canaryFlags = struct {
+	pushgateway string
+	job         string
+	instance    string
+	pushTimeout time.Duration
+}{
+	job:         "rootless_personio_canary",
+	pushTimeout: 10 * time.Second,
+}
+
+var canaryCmd = &cobra.Command{
+	Use:   "canary",
+	Short: "Checks that logging in and reading from Personio still works, for monitoring",
+	Long: `Checks that logging in and reading from Personio still works, meant to
+be run from cron or a monitoring system, so you're alerted when a change on
+Personio's side breaks this program.
+
+It connects to Personio, logs in, and reads today's attendance, and then
+prints a single line with the result. The daemon and HTTP cache are never
+used, so the check always talks to Personio. Remembered cookies are used
+when auth.rememberDevice is enabled, to not trigger new device emails.
+
+With --pushgateway, the result is also pushed as metrics to a Prometheus
+Pushgateway, such as to alert on:
+
+  rootless_personio_canary_success == 0
+
+Exit codes:
+  0  All steps succeeded
+  1  A step failed, or pushing the metrics failed`,
+	Example: `  rootless-personio canary
+  rootless-personio canary --pushgateway http://pushgateway:9091 --instance laptop`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var client *personio.Client
+		result := canary.Check(time.Now(), map[canary.Step]func() error{
+			canary.StepConnect: func() error {
+				baseURL, err := resolveBaseURL()
+				if err != nil {
+					return err
+				}
+				client, err = newClient(baseURL)
+				if err != nil {
+					return err
+				}
+				return preflight(client.BaseURL)
+			},
+			canary.StepLogin: func() error {
+				if cfg.Auth.RememberDevice && cfg.Auth.Email != "" {
+					if err := useSessionJar(client, cfg.Auth.Email); err != nil {
+						log.Warn().Err(err).Msg("Failed loading remembered cookies, continuing without them.")
+					}
+				}
+				return login(client)
+			},
+			canary.StepRead: func() error {
+				today := time.Now()
+				_, err := client.GetMyAttendanceCalendar(today, today)
+				return err
+			},
+		})
+		fmt.Println(result)
+
+		if canaryFlags.pushgateway != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), canaryFlags.pushTimeout)
+			defer cancel()
+			push := canary.Pushgateway{
+				URL:      canaryFlags.pushgateway,
+				Job:      canaryFlags.job,
+				Instance: canaryFlags.instance,
+			}
+			if err := push.Push(ctx, result); err != nil {
+				return err
+			}
+			log.Debug().Str("url", canaryFlags.pushgateway).Msg("Pushed canary metrics.")
+		}
+		if !result.OK() {
+			return &exitCodeError{code: 1, msg: result.String()}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(canaryCmd)
+
+	canaryCmd.Flags().StringVar(&canaryFlags.pushgateway, "pushgateway", "", "Push the result to this Prometheus Pushgateway URL")
+	canaryCmd.Flags().StringVar(&canaryFlags.job, "job", canaryFlags.job, "Job label of the pushed metrics")
+	canaryCmd.Flags().StringVar(&canaryFlags.instance, "instance", "", "Instance label of the pushed metrics")
+	canaryCmd.Flags().DurationVar(&canaryFlags.pushTimeout, "push-timeout", canaryFlags.pushTimeout, "How long to wait for the Pushgateway")
+}
@@ -20,6 +20,8 @@ package cmd
 import (
 	"os"
 
+	"github.com/applejag/rootless-personio/pkg/anonymize"
+	"github.com/applejag/rootless-personio/pkg/config"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -36,12 +38,28 @@ var configCmd = &cobra.Command{
 		enc.SetIndent(2)
 		defer enc.Close()
 		if !configFlags.showPassword {
-			cfg.Auth.Password = "/redacted/"
+			return enc.Encode(redactedConfig())
 		}
 		return enc.Encode(cfg)
 	},
 }
 
+// redactedConfig returns a copy of the config with all secrets redacted.
+func redactedConfig() config.Config {
+	redacted := cfg
+	for _, secret := range []*string{
+		&redacted.Auth.Password,
+		&redacted.Auth.Cookie,
+		&redacted.Auth.CSRFToken,
+		&redacted.Auth.EmailToken,
+	} {
+		if *secret != "" {
+			*secret = anonymize.Redacted
+		}
+	}
+	return redacted
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 
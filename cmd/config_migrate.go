@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/filelock"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var configMigrateFlags = struct {
+	dryRun bool
+}{}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [file]...",
+	Short: "Upgrades old config files to the current config layout",
+	Long: `Upgrades config files that use an old config layout, such as keys
+that have since been moved or renamed, to the current config layout.
+
+Old config layouts are still upgraded in-memory when loading the config,
+so this only needs to be done to get rid of the deprecation warnings.
+A backup of each changed file is written next to it before it's updated.
+
+Without arguments, the config files that are loaded on startup are
+migrated.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files := configFilesLoaded
+		if len(args) > 0 {
+			files = args
+		}
+		if len(files) == 0 {
+			return errors.New("no config files found to migrate")
+		}
+		for _, file := range files {
+			if err := migrateConfigFile(file, configMigrateFlags.dryRun); err != nil {
+				return fmt.Errorf("%s: %w", util.PrettyPath(file), err)
+			}
+		}
+		return nil
+	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Intentionally overrides the config issue check from root.go,
+		// as invalid config files is what this command is meant to fix
+		return nil
+	},
+}
+
+func init() {
+	configMigrateCmd.Flags().BoolVar(&configMigrateFlags.dryRun, "dry-run", false, "Print the migrated config files instead of writing them")
+
+	configCmd.AddCommand(configMigrateCmd)
+}
+
+func migrateConfigFile(file string, dryRun bool) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	migrated, applied, err := config.Migrate(data)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 || bytes.Equal(data, migrated) {
+		log.Info().Str("file", util.PrettyPath(file)).Msg("Config file is already up to date.")
+		return nil
+	}
+	for _, desc := range applied {
+		log.Info().Str("file", util.PrettyPath(file)).Msgf("Migration: %s", desc)
+	}
+	if dryRun {
+		fmt.Printf("# %s\n", util.PrettyPath(file))
+		os.Stdout.Write(migrated)
+		return nil
+	}
+
+	backup := fmt.Sprintf("%s.%s.bak", file, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(backup, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("write backup: %w", err)
+	}
+	// Replace atomically, so a failed write doesn't leave the config file
+	// half-written
+	if err := filelock.WriteFile(file, migrated, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("write migrated config: %w", err)
+	}
+	log.Info().
+		Str("file", util.PrettyPath(file)).
+		Str("backup", util.PrettyPath(backup)).
+		Msg("Migrated config file.")
+	return nil
+}
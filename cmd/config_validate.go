@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]...",
+	Short: "Validates the config files against the JSON schema",
+	Long: `Validates the config files against the config's JSON schema, and
+reports unknown keys, type mismatches, and deprecated fields, with line
+numbers.
+
+Without arguments, the config files that are loaded on startup are
+validated. This is also done before running any other command, which
+then fails on any errors.
+
+Keys are matched case-insensitively, same as when loading the config.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		issues := configIssues
+		if len(args) > 0 {
+			issues = validateConfigFiles(args)
+		}
+		if cfg.Output == config.OutFormatPretty {
+			for _, issue := range issues {
+				fmt.Printf("%s: %s\n", issue.Severity, issue)
+			}
+			if len(issues) == 0 {
+				fmt.Println("No issues found.")
+			}
+		} else if err := printOutputJSONOrYAML(issues); err != nil {
+			return err
+		}
+		if config.HasErrors(issues) {
+			return errors.New("invalid config")
+		}
+		return nil
+	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Intentionally overrides the config issue check from root.go
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
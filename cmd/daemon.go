@@ -0,0 +1,877 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/caldav"
+	"github.com/applejag/rootless-personio/pkg/clock"
+	"github.com/applejag/rootless-personio/pkg/daemon"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/filelock"
+	"github.com/applejag/rootless-personio/pkg/health"
+	"github.com/applejag/rootless-personio/pkg/ical"
+	"github.com/applejag/rootless-personio/pkg/mirror"
+	"github.com/applejag/rootless-personio/pkg/netwatch"
+	"github.com/applejag/rootless-personio/pkg/oidc"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Group of commands for the long-running daemon",
+	Long: `Group of commands for the long-running daemon.
+
+The daemon logs in once and exposes a JSON-RPC API over a unix socket.
+While it is running, all other commands send their requests through the
+daemon, sharing its logged in session and HTTP cache instead of logging
+in separately. Use the --no-daemon flag to opt out of this.`,
+}
+
+var daemonRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the daemon in the foreground",
+	Long: `Run the daemon in the foreground, until interrupted.
+
+The daemon exposes the JSON-RPC 1.0 methods "Daemon.Status",
+"Daemon.Sync", "Daemon.ClockIn", "Daemon.ClockOut", and "Daemon.Proxy"
+over its unix socket, and periodically syncs the current month into the
+local mirror, as configured via daemon.syncInterval.
+
+When the network changes, such as when the laptop sleeps, switches Wi-Fi,
+or leaves the VPN, the daemon pauses its requests until Personio is
+reachable again, and then syncs right away.
+
+All requests sent by the daemon, including the ones from other commands,
+share the budget in daemon.requestBudget, so the daemon never sends more
+requests per hour than that. Requests beyond the budget wait their turn,
+and as the daemon handles one call at a time, other calls such as
+"daemon status" wait with them.
+
+When slack.syncAt is set, the daemon sets your Slack status each day at
+that time, as done by "slack sync-status".
+
+When digest.at is set, the daemon sends the weekly digest email each
+digest.weekday at that time, as done by "digest --send".
+
+When daemon.caldav.listen is set, the daemon also serves a read-only
+CalDAV calendar of your attendance, absences, and holidays, read from the
+local mirror, which calendar clients can subscribe to.
+
+When daemon.healthListen is set, the daemon serves the "/healthz" and
+"/readyz" probes, where "/readyz" fails until the daemon has logged in.
+When interrupted or sent SIGTERM, the daemon stops taking new requests and
+sends the changes queued while offline, within daemon.shutdownTimeout.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The daemon must not try to send its requests to itself
+		rootFlags.noDaemon = true
+
+		socketPath, err := daemonSocketPath()
+		if err != nil {
+			return err
+		}
+		// Probes are served while logging in, so the daemon isn't considered
+		// dead while waiting for Personio's maintenance to end
+		var loggedIn atomic.Bool
+		probes := &health.Handler{
+			Ready: func() error {
+				if !loggedIn.Load() {
+					return errors.New("not logged in yet")
+				}
+				return nil
+			},
+		}
+		if cfg.Daemon.HealthListen != "" {
+			srv, err := serveHealth(cfg.Daemon.HealthListen, probes)
+			if err != nil {
+				return err
+			}
+			defer srv.Close()
+		}
+		client, err := newLoggedInClient()
+		for errors.Is(err, personio.ErrMaintenance) {
+			wait := maintenanceBackoff(err, time.Now())
+			log.Warn().Err(err).Str("wait", wait.String()).Msg("Waiting for maintenance to end before logging in.")
+			time.Sleep(wait)
+			client, err = newLoggedInClient()
+		}
+		if err != nil {
+			return err
+		}
+		budget := cfg.Daemon.RequestBudget
+		client.SetRequestBudget(budget.PerHour, time.Hour, budget.Burst)
+		backend := &daemonBackend{
+			client:    client,
+			startedAt: time.Now(),
+			networkUp: make(chan struct{}, 1),
+		}
+
+		ln, err := daemon.Listen(socketPath)
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+		log.Info().Str("socket", socketPath).Msg("Daemon is listening.")
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		done := make(chan struct{})
+		defer close(done)
+
+		var calDAV *http.Server
+		if cfg.Daemon.CalDAV.Listen != "" {
+			calDAV, err = serveCalDAV(backend)
+			if err != nil {
+				return err
+			}
+			defer calDAV.Close()
+		}
+		if cfg.Slack.SyncAt != "" {
+			at, err := time.Parse("15:04", cfg.Slack.SyncAt)
+			if err != nil {
+				return fmt.Errorf("parse slack.syncAt: %w", err)
+			}
+			go backend.syncSlackDaily(at, done)
+		}
+		if cfg.Digest.At != "" {
+			weekday, err := datespec.ParseWeekday(cfg.Digest.Weekday)
+			if err != nil {
+				return fmt.Errorf("parse digest.weekday: %w", err)
+			}
+			at, err := time.Parse("15:04", cfg.Digest.At)
+			if err != nil {
+				return fmt.Errorf("parse digest.at: %w", err)
+			}
+			go backend.sendDigestWeekly(weekday, at, done)
+		}
+		if cfg.Daemon.SyncInterval > 0 {
+			go backend.syncPeriodically(cfg.Daemon.SyncInterval, done)
+		}
+		if cfg.Daemon.NetworkWatchInterval > 0 {
+			go backend.watchNetwork(cfg.Daemon.NetworkWatchInterval, done)
+		}
+		if interval := daemon.WatchdogInterval(); interval > 0 {
+			go backend.notifyWatchdog(interval/2, done)
+		}
+		notifySystemd("READY=1")
+		defer notifySystemd("STOPPING=1")
+		loggedIn.Store(true)
+
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- daemon.Serve(ln, backend)
+		}()
+		select {
+		case sig := <-stop:
+			log.Info().Stringer("signal", sig).Msg("Shutting down daemon.")
+		case err := <-serveErr:
+			return err
+		}
+
+		probes.ShuttingDown()
+		ln.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Daemon.ShutdownTimeout)
+		defer cancel()
+		if calDAV != nil {
+			if err := calDAV.Shutdown(ctx); err != nil {
+				log.Warn().Err(err).Msg("Failed waiting for CalDAV requests to finish.")
+			}
+		}
+		if err := backend.flushQueue(ctx); err != nil {
+			log.Error().Err(err).Msg(`Failed sending queued changes, run "flush" to send them later.`)
+		}
+		return nil
+	},
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the running daemon",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dc, err := dialDaemon()
+		if err != nil {
+			return err
+		}
+		defer dc.Close()
+		status, err := dc.Status()
+		if err != nil {
+			return err
+		}
+		return printOutputJSONOrYAML(status)
+	},
+}
+
+var daemonSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Make the running daemon sync the current month into the mirror",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dc, err := dialDaemon()
+		if err != nil {
+			return err
+		}
+		defer dc.Close()
+		r := datespec.ThisMonth(time.Now())
+		reply, err := dc.Sync(daemon.SyncArgs{Start: r.Start, End: r.End})
+		if err != nil {
+			return err
+		}
+		return printOutputJSONOrYAML(reply)
+	},
+}
+
+// daemonBackend implements [daemon.Backend] using a logged in client.
+//
+// All calls are serialized, as the client is not safe for concurrent use.
+// This includes calls waiting for the client's request budget, so a call
+// that runs out of budget also holds up [daemonBackend.Status].
+type daemonBackend struct {
+	mu        sync.Mutex
+	client    *personio.Client
+	startedAt time.Time
+	lastSync  *time.Time
+	// maintenanceUntil is when Personio's maintenance is expected to end,
+	// during which requests are not sent
+	maintenanceUntil time.Time
+	// networkDownSince is when Personio became unreachable, such as when
+	// the laptop went to sleep or left the VPN, or zero while reachable
+	networkDownSince time.Time
+	// networkUp is signaled when Personio becomes reachable again after a
+	// network change, to sync right away instead of on the next tick
+	networkUp chan struct{}
+}
+
+func (b *daemonBackend) Status() (daemon.Status, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clockPath, err := clockStatePath()
+	if err != nil {
+		return daemon.Status{}, err
+	}
+	state, err := clock.Load(clockPath)
+	if err != nil {
+		return daemon.Status{}, err
+	}
+	status := daemon.Status{
+		BaseURL:    b.client.BaseURL,
+		EmployeeID: b.client.EmployeeID,
+		StartedAt:  b.startedAt,
+		LastSync:   b.lastSync,
+		Clock:      state,
+	}
+	if b.maintenanceUntil.After(time.Now()) {
+		until := b.maintenanceUntil
+		status.MaintenanceUntil = &until
+	}
+	if !b.networkDownSince.IsZero() {
+		since := b.networkDownSince
+		status.NetworkDownSince = &since
+	}
+	if available, perHour := b.client.RequestBudget(); perHour > 0 {
+		status.RequestBudget = &daemon.RequestBudget{PerHour: perHour, Available: available}
+	}
+	for _, capability := range b.client.CapabilityStatuses() {
+		if capability.Degraded {
+			status.Degraded = append(status.Degraded, capability)
+		}
+	}
+	return status, nil
+}
+
+func (b *daemonBackend) Sync(args daemon.SyncArgs) (daemon.SyncReply, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkPaused(); err != nil {
+		return daemon.SyncReply{}, err
+	}
+	r := datespec.Range{Start: args.Start, End: args.End}
+	r.Start, _ = util.TimeFullMonth(r.Start)
+	_, r.End = util.TimeFullMonth(r.End)
+
+	m, err := openMirror()
+	if err != nil {
+		return daemon.SyncReply{}, err
+	}
+	defer m.Close()
+	var reply daemon.SyncReply
+	err = forEachCalendarMonth(b.client, r, func(cal *personio.AttendanceCalendar, month datespec.Range) error {
+		reply.Months++
+		return m.Sync(cal, month, time.Now())
+	})
+	b.noteFailure(err)
+	if err != nil {
+		return reply, err
+	}
+	now := time.Now()
+	b.lastSync = &now
+	log.Info().Int("months", reply.Months).Msg("Synced mirror.")
+	return reply, nil
+}
+
+func (b *daemonBackend) ClockIn(args daemon.ClockInArgs) (clock.State, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	path, err := clockStatePath()
+	if err != nil {
+		return clock.State{}, err
+	}
+	return clock.In(path, time.Now(), clock.Options{
+		Comment:   args.Comment,
+		ProjectID: args.ProjectID,
+		Pomodoro:  args.Pomodoro,
+	})
+}
+
+func (b *daemonBackend) ClockOut() ([]personio.Period, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return clockOut(b.client)
+}
+
+func (b *daemonBackend) Proxy(req daemon.ProxyRequest) (daemon.ProxyResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkMaintenance(); err != nil {
+		return maintenanceProxyResponse(b.maintenanceUntil, time.Now()), nil
+	}
+	resp, err := b.proxy(req)
+	b.noteFailure(err)
+	if resp != nil && (resp.StatusCode == http.StatusUnauthorized || redirectedToLogin(req, resp)) {
+		log.Info().Msg("Session expired, logging in again.")
+		loginErr := guardLogin(cfg.Auth.Email, func() error {
+			provider, err := newAuthProvider(cfg.Auth)
+			if err != nil {
+				return err
+			}
+			return provider.Login(b.client)
+		})
+		if loginErr != nil {
+			return daemon.ProxyResponse{}, fmt.Errorf("log in again: %w", loginErr)
+		}
+		resp, err = b.proxy(req)
+	}
+	if resp == nil {
+		return daemon.ProxyResponse{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return daemon.ProxyResponse{}, fmt.Errorf("read body: %w", err)
+	}
+	header := resp.Header.Clone()
+	// The session stays in the daemon
+	header.Del("Set-Cookie")
+	return daemon.ProxyResponse{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     header,
+		Body:       body,
+	}, nil
+}
+
+// checkMaintenance returns an error while Personio is expected to be under
+// maintenance, so the daemon backs off instead of retrying.
+func (b *daemonBackend) checkMaintenance() error {
+	if time.Now().Before(b.maintenanceUntil) {
+		return &personio.MaintenanceError{Until: b.maintenanceUntil}
+	}
+	return nil
+}
+
+// checkPaused returns an error while requests are paused, due to Personio's
+// maintenance or Personio being unreachable.
+func (b *daemonBackend) checkPaused() error {
+	if err := b.checkMaintenance(); err != nil {
+		return err
+	}
+	if !b.networkDownSince.IsZero() {
+		return errNetworkDown
+	}
+	return nil
+}
+
+// noteFailure pauses requests if the error is due to Personio's maintenance
+// or the network.
+func (b *daemonBackend) noteFailure(err error) {
+	b.noteMaintenance(err)
+	if isNetworkError(err) && b.networkDownSince.IsZero() {
+		b.networkDownSince = time.Now()
+		log.Warn().Err(err).Msg("Personio is unreachable, pausing requests until the network changes.")
+	}
+}
+
+// noteMaintenance starts backing off if the error is Personio's
+// maintenance page.
+func (b *daemonBackend) noteMaintenance(err error) {
+	if !errors.Is(err, personio.ErrMaintenance) {
+		return
+	}
+	now := time.Now()
+	b.maintenanceUntil = now.Add(maintenanceBackoff(err, now))
+	log.Warn().Err(err).
+		Time("until", b.maintenanceUntil).
+		Msg("Personio is under maintenance, pausing requests.")
+}
+
+// maintenanceBackoff returns how long to wait for the maintenance to end,
+// which is until the announced end if any, or else daemon.maintenanceBackoff.
+func maintenanceBackoff(err error, now time.Time) time.Duration {
+	var maintErr *personio.MaintenanceError
+	if errors.As(err, &maintErr) && maintErr.Until.After(now) {
+		return maintErr.Until.Sub(now)
+	}
+	if cfg.Daemon.MaintenanceBackoff <= 0 {
+		return time.Minute
+	}
+	return cfg.Daemon.MaintenanceBackoff
+}
+
+// maintenanceProxyResponse is sent instead of proxying requests during
+// maintenance, which clients detect as the maintenance page.
+func maintenanceProxyResponse(until, now time.Time) daemon.ProxyResponse {
+	header := http.Header{}
+	header.Set("Retry-After", strconv.Itoa(int(until.Sub(now).Seconds())+1))
+	return daemon.ProxyResponse{
+		StatusCode: http.StatusServiceUnavailable,
+		Status:     fmt.Sprintf("%d %s", http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)),
+		Header:     header,
+	}
+}
+
+// redirectedToLogin returns true if Personio redirected the request to the
+// login page, which it does instead of HTTP 401 for some endpoints.
+func redirectedToLogin(req daemon.ProxyRequest, resp *http.Response) bool {
+	if strings.HasPrefix(req.Path, "/login") || resp.Request == nil {
+		return false
+	}
+	return strings.HasPrefix(resp.Request.URL.Path, "/login")
+}
+
+func (b *daemonBackend) proxy(req daemon.ProxyRequest) (*http.Response, error) {
+	httpReq, err := http.NewRequest(req.Method, req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range req.Header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Cookie", "X-Csrf-Token":
+			continue
+		}
+		httpReq.Header[key] = values
+	}
+	return b.client.Raw(httpReq)
+}
+
+func (b *daemonBackend) syncPeriodically(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Network changes are not always seen, such as when a VPN drops
+		// without its interface going down, so retry on each tick too
+		b.probeNetwork()
+		r := datespec.ThisMonth(time.Now())
+		if _, err := b.Sync(daemon.SyncArgs{Start: r.Start, End: r.End}); err != nil && !isNetworkError(err) {
+			log.Warn().Err(err).Msg("Failed syncing mirror.")
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		case <-b.networkUp:
+		}
+	}
+}
+
+// watchNetwork checks whether Personio is reachable each time the network
+// changes, to pause requests while offline and resume them once it is back.
+func (b *daemonBackend) watchNetwork(interval time.Duration, done <-chan struct{}) {
+	netwatch.Watch(interval, done, func(state netwatch.State) {
+		log.Debug().Bool("online", state.Online).Msg("Network changed.")
+		if !state.Online {
+			b.mu.Lock()
+			b.noteFailure(errNetworkDown)
+			b.mu.Unlock()
+			return
+		}
+		if b.probeNetwork() {
+			select {
+			case b.networkUp <- struct{}{}:
+			default:
+			}
+		}
+	})
+}
+
+// probeNetwork checks if Personio is reachable again while the network is
+// down, and returns true if requests were resumed.
+func (b *daemonBackend) probeNetwork() bool {
+	b.mu.Lock()
+	down := !b.networkDownSince.IsZero()
+	b.mu.Unlock()
+	if !down {
+		return false
+	}
+	p := personio.Preflight{Timeout: cfg.HTTP.PreflightTimeout}
+	if err := p.Check(context.Background(), b.client.BaseURL); err != nil {
+		log.Debug().Err(err).Msg("Personio is still unreachable.")
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	log.Info().Str("downFor", time.Since(b.networkDownSince).Round(time.Second).String()).
+		Msg("Personio is reachable again, resuming requests.")
+	b.networkDownSince = time.Time{}
+	return true
+}
+
+var errNetworkDown = errors.New("network is down")
+
+// isNetworkError returns true if the request failed before reaching
+// Personio, such as DNS lookups, refused connections, and timeouts.
+func isNetworkError(err error) bool {
+	if errors.Is(err, errNetworkDown) || errors.Is(err, personio.ErrUnreachable) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// serveHealth serves the health probes in the background, until the
+// returned server is closed.
+func serveHealth(addr string, probes *health.Handler) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen for health probes: %w", err)
+	}
+	mux := http.NewServeMux()
+	probes.Register(mux)
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("Failed serving health probes.")
+		}
+	}()
+	log.Info().Str("address", "http://"+ln.Addr().String()+"/healthz").Msg("Serving health probes.")
+	return srv, nil
+}
+
+// flushQueue sends the changes queued while offline, such as before the
+// daemon stops, so they aren't left behind when the daemon runs as a
+// short-lived service.
+func (b *daemonBackend) flushQueue(ctx context.Context) error {
+	path, err := queuePath()
+	if err != nil {
+		return err
+	}
+	timeout := time.Duration(0)
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	lock, err := filelock.Acquire(path+".flush", timeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	ops, err := queue.Load(path)
+	if err != nil || len(ops) == 0 {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkPaused(); err != nil {
+		return err
+	}
+	flushed, dropped, err := flushQueue(ctx, b.client, path, ops)
+	log.Info().
+		Int("flushed", len(flushed)).
+		Int("dropped", len(dropped)).
+		Int("queued", len(ops)).
+		Msg("Sent queued changes.")
+	return err
+}
+
+// serveCalDAV serves the read-only CalDAV calendar in the background, until
+// the returned server is closed.
+func serveCalDAV(b *daemonBackend) (*http.Server, error) {
+	conf := cfg.Daemon.CalDAV
+	var verifier *oidc.Verifier
+	if conf.OIDC.Issuer != "" {
+		var err error
+		verifier, err = newOIDCVerifier(conf.OIDC)
+		if err != nil {
+			return nil, fmt.Errorf("daemon.caldav.oidc: %w", err)
+		}
+	}
+	ln, err := net.Listen("tcp", conf.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("listen for CalDAV: %w", err)
+	}
+	handler := &caldav.Handler{
+		Name:     "Personio",
+		Events:   b.calendarEvents,
+		Stamp:    b.startedAt,
+		Username: conf.Username,
+		Password: conf.Password,
+	}
+	if verifier != nil {
+		handler.BearerAuth = func(r *http.Request, token string) error {
+			_, err := verifier.Verify(r.Context(), token)
+			return err
+		}
+	}
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("Failed serving CalDAV calendar.")
+		}
+	}()
+	log.Info().Str("address", "http://"+ln.Addr().String()+"/").Msg("Serving CalDAV calendar.")
+	return srv, nil
+}
+
+// calendarEvents returns the events of the CalDAV calendar, read from the
+// mirror, which is synced first if needed.
+func (b *daemonBackend) calendarEvents() ([]ical.Event, error) {
+	r := datespec.ThisMonth(time.Now())
+	if months := cfg.Daemon.CalDAV.Months; months > 1 {
+		r.Start = r.Start.AddDate(0, -(months - 1), 0)
+	}
+	m, err := openMirror()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+	cal, err := m.GetMyAttendanceCalendar(r.Start, r.End)
+	if errors.Is(err, mirror.ErrNotSynced) {
+		if _, err := b.Sync(daemon.SyncArgs{Start: r.Start, End: r.End}); err != nil {
+			return nil, err
+		}
+		cal, err = m.GetMyAttendanceCalendar(r.Start, r.End)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ical.CalendarEvents(cal, ical.EventOptions{
+		Periods:  true,
+		Absences: true,
+		Holidays: cfg.Daemon.CalDAV.Holidays,
+	})
+}
+
+// syncSlackDaily syncs the Slack status each day at the time of day, and
+// right away if the daemon starts later than that.
+func (b *daemonBackend) syncSlackDaily(at time.Time, done <-chan struct{}) {
+	now := time.Now()
+	next := nextTimeOfDay(now, at)
+	if next.Day() != now.Day() {
+		// Already past today's time
+		b.syncSlack()
+	}
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		b.syncSlack()
+		next = nextTimeOfDay(time.Now(), at)
+	}
+}
+
+func (b *daemonBackend) syncSlack() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkPaused(); err != nil {
+		log.Warn().Err(err).Msg("Skipping Slack status sync.")
+		return
+	}
+	_, err := syncSlackStatus(b.client, time.Now(), false)
+	b.noteFailure(err)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed syncing Slack status.")
+	}
+}
+
+// sendDigestWeekly sends the digest email each week on the weekday at the
+// time of day. Unlike the Slack status, a missed digest is not sent when
+// the daemon starts, to not send duplicates when restarting the daemon.
+func (b *daemonBackend) sendDigestWeekly(weekday time.Weekday, at time.Time, done <-chan struct{}) {
+	for {
+		next := nextTimeOfDay(time.Now(), at)
+		for next.Weekday() != weekday {
+			next = next.AddDate(0, 0, 1)
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		b.sendDigest()
+	}
+}
+
+func (b *daemonBackend) sendDigest() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkPaused(); err != nil {
+		log.Warn().Err(err).Msg("Skipping digest.")
+		return
+	}
+	now := time.Now()
+	digest, err := buildDigest(b.client, now.AddDate(0, 0, -1), now)
+	b.noteFailure(err)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed building digest.")
+		return
+	}
+	if err := sendDigest(digest, now); err != nil {
+		log.Warn().Err(err).Msg("Failed sending digest.")
+	}
+}
+
+// nextTimeOfDay returns the next time after now at the time of day.
+func nextTimeOfDay(now, at time.Time) time.Time {
+	year, month, day := now.Date()
+	next := time.Date(year, month, day, at.Hour(), at.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func notifySystemd(state string) {
+	sent, err := daemon.Notify(state)
+	if err != nil {
+		log.Warn().Err(err).Str("state", state).Msg("Failed notifying systemd.")
+		return
+	}
+	if sent {
+		log.Debug().Str("state", state).Msg("Notified systemd.")
+	}
+}
+
+// notifyWatchdog periodically tells systemd that the daemon is alive.
+// A backend call that hangs will block the notifications, making
+// systemd restart the daemon.
+func (b *daemonBackend) notifyWatchdog(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			b.mu.Lock()
+			b.mu.Unlock()
+			notifySystemd("WATCHDOG=1")
+		}
+	}
+}
+
+func daemonSocketPath() (string, error) {
+	if cfg.Daemon.Socket != "" {
+		return cfg.Daemon.Socket, nil
+	}
+	return daemon.DefaultSocketPath()
+}
+
+func dialDaemon() (*daemon.Client, error) {
+	socketPath, err := daemonSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	return daemon.Dial(socketPath)
+}
+
+// newDaemonClient returns a client that sends all its requests through the
+// running daemon, or nil if no daemon is running.
+func newDaemonClient(baseURL string) *personio.Client {
+	dc, err := dialDaemon()
+	if errors.Is(err, daemon.ErrNotRunning) {
+		return nil
+	}
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed connecting to daemon.")
+		return nil
+	}
+	status, err := dc.Status()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed getting daemon status, continuing without it.")
+		dc.Close()
+		return nil
+	}
+	if status.BaseURL != baseURL {
+		log.Warn().
+			Str("daemonBaseUrl", status.BaseURL).
+			Str("baseUrl", baseURL).
+			Msg("Daemon uses a different base URL, continuing without it.")
+		dc.Close()
+		return nil
+	}
+	normalized, err := personio.NormalizeBaseURL(baseURL)
+	if err != nil {
+		dc.Close()
+		return nil
+	}
+	// The daemon forwards the paths as-is, so the endpoint overrides and
+	// other options must still be applied here
+	opts := append(clientOptions(), personio.WithTransport(dc.Transport(normalized)))
+	client, err := personio.New(baseURL, opts...)
+	if err != nil {
+		dc.Close()
+		return nil
+	}
+	client.EmployeeID = status.EmployeeID
+	log.Debug().Int("employeeId", client.EmployeeID).Msg("Using session of running daemon.")
+	return client
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonRunCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonSyncCmd)
+}
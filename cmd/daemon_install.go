@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/daemon"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var daemonInstallUnitFlags = struct {
+	print        bool
+	syncInterval time.Duration
+	watchdog     time.Duration
+}{
+	syncInterval: time.Hour,
+	watchdog:     time.Minute,
+}
+
+var daemonInstallUnitCmd = &cobra.Command{
+	Use:   "install-unit",
+	Short: "Generate service files to run the daemon in the background",
+	Long: `Generate service files to run the daemon in the background.
+
+On Linux, this writes systemd user units to ~/.config/systemd/user:
+
+- rootless-personio.service runs the daemon, with readiness notification
+  and watchdog support.
+- rootless-personio-sync.timer periodically runs "mirror sync", for when
+  you don't want the daemon always running.
+
+On macOS, this writes a launchd agent to ~/Library/LaunchAgents.
+
+The files are not enabled automatically. The command prints how to
+enable them afterwards.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("find executable: %w", err)
+		}
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		opts := daemon.UnitOptions{
+			Executable:   exe,
+			SyncInterval: daemonInstallUnitFlags.syncInterval,
+			Watchdog:     daemonInstallUnitFlags.watchdog,
+		}
+
+		var dir string
+		var files []daemon.UnitFile
+		var hint string
+		switch runtime.GOOS {
+		case "linux":
+			files, err = daemon.SystemdUnits(opts)
+			if err != nil {
+				return err
+			}
+			configDir, err := os.UserConfigDir()
+			if err != nil {
+				return err
+			}
+			dir = filepath.Join(configDir, "systemd", "user")
+			hint = fmt.Sprintf(`Enable the daemon with:
+
+  systemctl --user daemon-reload
+  systemctl --user enable --now %[1]s.service
+
+Or only the periodic sync with:
+
+  systemctl --user daemon-reload
+  systemctl --user enable --now %[1]s-sync.timer
+`, daemon.UnitName)
+		case "darwin":
+			plist, err := daemon.LaunchdPlist(opts)
+			if err != nil {
+				return err
+			}
+			files = []daemon.UnitFile{plist}
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return err
+			}
+			dir = filepath.Join(homeDir, "Library", "LaunchAgents")
+			hint = fmt.Sprintf(`Enable the daemon with:
+
+  launchctl load -w %s
+`, filepath.Join(dir, plist.Name))
+		default:
+			return errors.New("install-unit is only supported on Linux (systemd) and macOS (launchd)")
+		}
+
+		if daemonInstallUnitFlags.print {
+			for _, f := range files {
+				fmt.Printf("# %s\n%s\n", filepath.Join(dir, f.Name), f.Content)
+			}
+			return nil
+		}
+
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		for _, f := range files {
+			path := filepath.Join(dir, f.Name)
+			if err := os.WriteFile(path, []byte(f.Content), 0o644); err != nil {
+				return err
+			}
+			log.Info().Str("path", path).Msg("Wrote service file.")
+		}
+		fmt.Print(hint)
+		return nil
+	},
+}
+
+func init() {
+	daemonCmd.AddCommand(daemonInstallUnitCmd)
+
+	daemonInstallUnitCmd.Flags().BoolVar(&daemonInstallUnitFlags.print, "print", false, "Print the files instead of writing them")
+	daemonInstallUnitCmd.Flags().DurationVar(&daemonInstallUnitFlags.syncInterval, "sync-interval", daemonInstallUnitFlags.syncInterval, "How often the timer syncs the mirror")
+	daemonInstallUnitCmd.Flags().DurationVar(&daemonInstallUnitFlags.watchdog, "watchdog", daemonInstallUnitFlags.watchdog, "How long systemd waits for the daemon's watchdog notifications before restarting it (0 disables)")
+}
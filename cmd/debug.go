@@ -0,0 +1,322 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/anonymize"
+	"github.com/applejag/rootless-personio/pkg/daemon"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Commands for troubleshooting and bug reports",
+}
+
+var debugDumpFlags = struct {
+	file       string
+	anonymize  bool
+	noRequests bool
+}{}
+
+var debugDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Writes a zip file with data to attach to bug reports",
+	Long: `Writes a zip file with data to attach to bug reports, containing:
+
+- the program's version, and your OS and architecture
+- your config, with secrets such as passwords and cookies removed
+- the problems found when validating your config
+- the logs of this command, at debug level
+- the daemon's logs from the last 24h, if installed as a systemd service
+- the responses from fetching this month's attendance calendar and your
+  employee data from Personio
+
+Secrets are always removed. With --anonymize, also personal data is
+replaced by hashes, such as names, emails, comments, and IDs. The hashes
+are salted with a random value, so they can't be traced back to you, while
+the same value is still replaced the same way throughout the dump.
+
+Please still look through the zip file before attaching it anywhere.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Intentionally overrides the config issue check from root.go,
+		// as a dump is especially useful when the config is invalid
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file := debugDumpFlags.file
+		if file == "" {
+			file = "personio-debug-" + time.Now().Format("20060102-150405") + ".zip"
+		}
+
+		anon := anonymize.New()
+		anon.AddSecrets(cfg.Auth.Password, cfg.Auth.Cookie, cfg.Auth.CSRFToken, cfg.Auth.EmailToken)
+		if debugDumpFlags.anonymize {
+			anon.AddPersonal(cfg.Auth.Email, cfg.Tenant.Slug, cfg.Tenant.Host, cfg.BaseURL)
+			for _, e := range cfg.Employees {
+				anon.AddPersonal(e.Email, strconv.Itoa(e.ID))
+			}
+		}
+
+		var logs bytes.Buffer
+		restoreLogger := captureLogs(&logs)
+		var responses []recordedResponse
+		if !debugDumpFlags.noRequests {
+			responses = recordSampleResponses(anon)
+		}
+		restoreLogger()
+
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		dump := func(name string, data []byte) error {
+			w, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		}
+
+		version := fmt.Sprintf("version: %s\ngo: %s\nos: %s\narch: %s\ncreated: %s\nanonymized: %t\n",
+			buildVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH,
+			time.Now().Format(time.RFC3339), debugDumpFlags.anonymize)
+		if err := dump("version.txt", []byte(version)); err != nil {
+			return err
+		}
+
+		var configYAML bytes.Buffer
+		enc := yaml.NewEncoder(&configYAML)
+		enc.SetIndent(2)
+		if err := enc.Encode(redactedConfig()); err != nil {
+			return fmt.Errorf("encode config: %w", err)
+		}
+		if err := dump("config.yaml", []byte(anon.Text(configYAML.String()))); err != nil {
+			return err
+		}
+
+		var issues strings.Builder
+		for _, issue := range configIssues {
+			fmt.Fprintf(&issues, "%s: %s\n", issue.Severity, issue)
+		}
+		if err := dump("config-issues.txt", []byte(anon.Text(issues.String()))); err != nil {
+			return err
+		}
+
+		if err := dump("log.jsonl", []byte(anon.Text(logs.String()))); err != nil {
+			return err
+		}
+
+		if journal := daemonJournal(); journal != "" {
+			if err := dump("daemon-journal.txt", []byte(anon.Text(journal))); err != nil {
+				return err
+			}
+		}
+
+		var index strings.Builder
+		for i, resp := range responses {
+			name := fmt.Sprintf("responses/%02d-%s.json", i+1, resp.name)
+			fmt.Fprintf(&index, "%s\t%s %s\t%d\n", name, resp.method, resp.path, resp.status)
+			if err := dump(name, resp.body); err != nil {
+				return err
+			}
+		}
+		if len(responses) > 0 {
+			if err := dump("responses/index.txt", []byte(index.String())); err != nil {
+				return err
+			}
+		}
+
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, buf.Bytes(), 0o600); err != nil {
+			return err
+		}
+		log.Info().
+			Str("file", util.PrettyPath(file)).
+			Bool("anonymized", debugDumpFlags.anonymize).
+			Int("responses", len(responses)).
+			Msg("Wrote debug dump. Please look through it before attaching it to a bug report.")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+	debugCmd.AddCommand(debugDumpCmd)
+
+	debugDumpCmd.Flags().StringVarP(&debugDumpFlags.file, "file", "f", "", `Zip file to write (default "personio-debug-<time>.zip")`)
+	debugDumpCmd.Flags().BoolVar(&debugDumpFlags.anonymize, "anonymize", false, "Replace personal data, such as names, emails, comments, and IDs, with hashes")
+	debugDumpCmd.Flags().BoolVar(&debugDumpFlags.noRequests, "no-requests", false, "Skip logging in and fetching sample responses from Personio")
+}
+
+// captureLogs writes all logs at debug level or higher to the writer, in
+// addition to the normal logging, and returns a function that restores the
+// previous logger.
+func captureLogs(w io.Writer) func() {
+	old := log.Logger
+	stderr := levelFilterWriter{w: newLogWriter(), min: old.GetLevel()}
+	log.Logger = zerolog.New(zerolog.MultiLevelWriter(stderr, anonymize.NewWriter(w))).
+		With().Timestamp().Logger().
+		Level(zerolog.DebugLevel)
+	return func() { log.Logger = old }
+}
+
+// levelFilterWriter only writes log lines of the minimum level or higher.
+type levelFilterWriter struct {
+	w   io.Writer
+	min zerolog.Level
+}
+
+func (w levelFilterWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+func (w levelFilterWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < w.min {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
+
+type recordedResponse struct {
+	name   string
+	method string
+	path   string
+	status int
+	body   []byte
+}
+
+// recordingTransport records the JSON responses.
+type recordingTransport struct {
+	inner     http.RoundTripper
+	responses []recordedResponse
+	anon      *anonymize.Anonymizer
+	anonymize bool
+}
+
+var unsafeFileNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded := body
+	if t.anonymize {
+		if anonymized, err := t.anon.JSON(body); err == nil {
+			recorded = anonymized
+		} else {
+			recorded = []byte(t.anon.Text(string(body)))
+		}
+	} else {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "", "  "); err == nil {
+			recorded = pretty.Bytes()
+		}
+		recorded = []byte(t.anon.Text(string(recorded)))
+	}
+	path := t.anon.Text(req.URL.Path)
+	t.responses = append(t.responses, recordedResponse{
+		name:   strings.Trim(unsafeFileNameRegex.ReplaceAllString(req.Method+path, "-"), "-"),
+		method: req.Method,
+		path:   path,
+		status: resp.StatusCode,
+		body:   recorded,
+	})
+	return resp, nil
+}
+
+// recordSampleResponses logs in and fetches some data, and returns the
+// responses. Errors are logged, as they are part of the dump.
+func recordSampleResponses(anon *anonymize.Anonymizer) []recordedResponse {
+	// Talk to Personio directly, so the responses can be recorded
+	rootFlags.noDaemon = true
+	rootFlags.noCache = true
+	client, err := newLoggedInClient()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to log in.")
+		return nil
+	}
+	if debugDumpFlags.anonymize {
+		anon.AddPersonal(strconv.Itoa(client.EmployeeID))
+	}
+	rec := &recordingTransport{
+		inner:     personio.NewTransport(),
+		anon:      anon,
+		anonymize: debugDumpFlags.anonymize,
+	}
+	client.SetTransport(rec)
+
+	start, end := util.TimeFullMonth(time.Now())
+	if _, err := client.GetMyAttendanceCalendar(start, end); err != nil {
+		log.Error().Err(err).Msg("Failed to fetch attendance calendar.")
+	}
+	if _, err := client.GetMyEmployeeData(); err != nil {
+		log.Error().Err(err).Msg("Failed to fetch employee data.")
+	}
+	return rec.responses
+}
+
+// daemonJournal returns the daemon's logs from the last 24h when installed
+// as a systemd user service, or an empty string.
+func daemonJournal() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		return ""
+	}
+	out, err := exec.Command("journalctl", "--user",
+		"--unit", daemon.UnitName+".service",
+		"--since", "-24h", "--no-pager", "--output", "short-iso").Output()
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to read daemon logs from journalctl.")
+		return ""
+	}
+	if strings.HasPrefix(string(out), "-- No entries --") {
+		return ""
+	}
+	return string(out)
+}
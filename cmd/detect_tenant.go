@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var detectTenantCmd = &cobra.Command{
+	Use:   "detect-tenant <company>",
+	Short: "Find which domain your company's Personio is hosted on",
+	Long: `Find which domain your company's Personio is hosted on,
+by probing the login page on all known Personio domains.
+
+The <company> is your company's subdomain, e.g "mycompany" in
+https://mycompany.personio.de.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		candidates, err := personio.DetectTenant(args[0])
+		if err != nil && !errors.Is(err, personio.ErrTenantNotFound) {
+			return err
+		}
+		if cfg.Output != config.OutFormatPretty {
+			if printErr := printOutputJSONOrYAML(candidates); printErr != nil {
+				return printErr
+			}
+			return err
+		}
+		for _, c := range candidates {
+			if c.Found {
+				color.Green("✔ %s (%s)", c.BaseURL, c.Status)
+			} else {
+				color.HiBlack("✘ %s (%s)", c.BaseURL, c.Status)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		for _, c := range candidates {
+			if c.Found {
+				fmt.Printf("\nAdd this to your config file:\n\ntenant:\n  slug: %s\n  domain: %s\n", args[0], c.Domain)
+				break
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(detectTenantCmd)
+}
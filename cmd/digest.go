@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/mail"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var digestFlags = struct {
+	week flagtype.Date
+	send bool
+}{}
+
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Summarize a week of attendance, optionally sent by email",
+	Long: `Summarize a week of attendance, with the hours worked versus your
+report.workingHours, gaps and other lint warnings, days waiting for
+approval, and your absences in the upcoming digest.upcomingWeeks.
+
+Defaults to the week of yesterday, so a digest made on a Monday morning
+covers the week before, and one made on a Friday covers the current week.
+
+Use --send to send the digest by email, as configured in the digest config.
+The daemon sends it each week when digest.at is set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, err := newCalendarSource()
+		if err != nil {
+			return err
+		}
+		defer closeCalendarSource(source)
+		now := time.Now()
+		week := digestFlags.week.Time()
+		if digestFlags.week.IsZero() {
+			week = now.AddDate(0, 0, -1)
+		}
+		digest, err := buildDigest(source, week, now)
+		if err != nil {
+			return err
+		}
+		if digestFlags.send {
+			if err := sendDigest(digest, now); err != nil {
+				return err
+			}
+		}
+		if cfg.Output == config.OutFormatPretty {
+			return digest.WriteText(os.Stdout)
+		}
+		return printOutputJSONOrYAML(digest)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(digestCmd)
+
+	addMirrorFlag(digestCmd.Flags())
+
+	digestCmd.Flags().VarP(&digestFlags.week, "week", "w", "Any date in the week to summarize (default yesterday)")
+	digestCmd.Flags().BoolVar(&digestFlags.send, "send", false, "Send the digest by email")
+}
+
+// buildDigest summarizes the week of the given date.
+func buildDigest(source calendarSource, date, now time.Time) (report.Digest, error) {
+	weekStart, err := datespec.ParseWeekStart(cfg.DateRange.WeekStart)
+	if err != nil {
+		return report.Digest{}, err
+	}
+	week := datespec.ThisWeek(date, weekStart)
+	r := week
+	if cfg.Digest.UpcomingWeeks > 0 {
+		r.End = week.End.AddDate(0, 0, 7*cfg.Digest.UpcomingWeeks)
+	}
+	days, err := fetchReportDays(source, r)
+	if err != nil {
+		return report.Digest{}, err
+	}
+	weekDays := days
+	if n := week.Days(); n < len(days) {
+		weekDays = days[:n]
+	}
+	return report.NewDigest(weekDays, days[len(weekDays):], reportSchedule(), now), nil
+}
+
+// sendDigest sends the digest by email.
+func sendDigest(digest report.Digest, now time.Time) error {
+	var body bytes.Buffer
+	if err := digest.WriteText(&body); err != nil {
+		return err
+	}
+	conf := cfg.Digest
+	err := mail.Send(mail.Server{
+		Host:     conf.SMTP.Host,
+		Port:     conf.SMTP.Port,
+		Username: conf.SMTP.Username,
+		Password: conf.SMTP.Password,
+	}, mail.Message{
+		From:    conf.From,
+		To:      conf.To,
+		Subject: fmt.Sprintf("Attendance digest %s to %s", digest.Start, digest.End),
+		Body:    body.String(),
+		Date:    now,
+	})
+	if err != nil {
+		return fmt.Errorf("send digest: %w", err)
+	}
+	log.Info().Strs("to", conf.To).Str("start", digest.Start).Msg("Sent digest.")
+	return nil
+}
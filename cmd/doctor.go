@@ -0,0 +1,306 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"text/tabwriter"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/daemon"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var doctorFlags = struct {
+	api bool
+}{}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks your setup, and which features work against your Personio",
+	Long: `Checks your setup, and which features work against your Personio.
+
+It validates the config, connects to and logs in to Personio, and then
+tries each capability that can be checked without changing any data, such
+as reading your attendance. Capabilities are groups of features that
+depend on the same Personio endpoints. When Personio changes one of them,
+only that capability is degraded, while the rest keep working.
+
+When the daemon is running, the capabilities that it found degraded are
+also shown.
+
+With --api, it instead prints what's known about each of Personio's
+endpoints used by this program, such as their paths, methods, required
+headers, and payloads, and when they were last verified, without
+contacting Personio.
+
+Exits with code 1 if any check failed.`,
+	Example: `  rootless-personio doctor
+  rootless-personio doctor --api
+  rootless-personio doctor --api -o yaml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if doctorFlags.api {
+			return printDoctorAPI()
+		}
+		checks := runDoctorChecks()
+		if cfg.Output != config.OutFormatPretty {
+			if err := printOutputJSONOrYAML(checks); err != nil {
+				return err
+			}
+		} else {
+			for _, c := range checks {
+				c.print()
+			}
+		}
+		for _, c := range checks {
+			if c.Result == doctorFail {
+				return &exitCodeError{code: 1, msg: "some checks failed"}
+			}
+		}
+		return nil
+	},
+}
+
+type doctorResult string
+
+const (
+	doctorOK   doctorResult = "ok"
+	doctorWarn doctorResult = "warn"
+	doctorFail doctorResult = "fail"
+	doctorSkip doctorResult = "skip"
+)
+
+type doctorCheck struct {
+	Name   string       `json:"name"`
+	Result doctorResult `json:"result"`
+	Detail string       `json:"detail,omitempty"`
+}
+
+func (c doctorCheck) print() {
+	switch c.Result {
+	case doctorOK:
+		color.Green("✔ %s: %s", c.Name, c.Detail)
+	case doctorWarn:
+		color.Yellow("! %s: %s", c.Name, c.Detail)
+	case doctorFail:
+		color.Red("✘ %s: %s", c.Name, c.Detail)
+	default:
+		color.HiBlack("- %s: %s", c.Name, c.Detail)
+	}
+}
+
+func runDoctorChecks() []doctorCheck {
+	checks := []doctorCheck{doctorConfigCheck()}
+
+	client, err := doctorConnect()
+	if err != nil {
+		checks = append(checks, doctorCheck{Name: "connect", Result: doctorFail, Detail: err.Error()})
+		return append(checks, doctorDaemonChecks()...)
+	}
+	checks = append(checks, doctorCheck{Name: "connect", Result: doctorOK, Detail: client.BaseURL})
+
+	if err := doctorLogin(client); err != nil {
+		checks = append(checks, doctorCheck{Name: "capability " + string(personio.CapabilityLogin), Result: doctorFail, Detail: err.Error()})
+		return append(checks, doctorDaemonChecks()...)
+	}
+
+	// Only capabilities that can be checked without changing any data
+	today := time.Now()
+	probes := map[personio.Capability]func() error{
+		personio.CapabilityLogin: func() error { return nil },
+		personio.CapabilityEmployee: func() error {
+			_, err := client.GetEmployeeData(client.EmployeeID)
+			return err
+		},
+		personio.CapabilityAttendanceRead: func() error {
+			_, err := client.GetMyAttendanceCalendar(today, today)
+			return err
+		},
+	}
+	probeErrs := make(map[personio.Capability]error)
+	for capability, probe := range probes {
+		probeErrs[capability] = probe()
+	}
+	for _, status := range client.CapabilityStatuses() {
+		check := doctorCheck{Name: "capability " + string(status.Capability)}
+		probeErr, probed := probeErrs[status.Capability]
+		switch {
+		case status.Degraded:
+			check.Result = doctorFail
+			check.Detail = fmt.Sprintf("degraded, as the %s endpoint seems to have changed: %s", status.Endpoint, status.Error)
+		case probeErr != nil:
+			check.Result = doctorFail
+			check.Detail = probeErr.Error()
+		case probed:
+			check.Result = doctorOK
+			check.Detail = "works"
+		default:
+			check.Result = doctorSkip
+			check.Detail = "not checked, as it would change data"
+		}
+		checks = append(checks, check)
+	}
+	return append(checks, doctorDaemonChecks()...)
+}
+
+func doctorConfigCheck() doctorCheck {
+	check := doctorCheck{Name: "config", Result: doctorOK, Detail: "no issues"}
+	if len(configIssues) == 0 {
+		return check
+	}
+	check.Result = doctorWarn
+	if config.HasErrors(configIssues) {
+		check.Result = doctorFail
+	}
+	check.Detail = fmt.Sprintf(`%d issues, see "config validate"`, len(configIssues))
+	return check
+}
+
+// doctorConnect returns a client that talks to Personio directly, without
+// the daemon or HTTP cache.
+func doctorConnect() (*personio.Client, error) {
+	baseURL, err := resolveBaseURL()
+	if err != nil {
+		return nil, err
+	}
+	client, err := newClient(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client, preflight(client.BaseURL)
+}
+
+func doctorLogin(client *personio.Client) error {
+	if cfg.Auth.RememberDevice && cfg.Auth.Email != "" {
+		if err := useSessionJar(client, cfg.Auth.Email); err != nil {
+			log.Warn().Err(err).Msg("Failed loading remembered cookies, continuing without them.")
+		}
+	}
+	return login(client)
+}
+
+// doctorDaemonChecks returns the capabilities that the running daemon found
+// degraded, if any.
+func doctorDaemonChecks() []doctorCheck {
+	dc, err := dialDaemon()
+	if errors.Is(err, daemon.ErrNotRunning) {
+		return []doctorCheck{{Name: "daemon", Result: doctorSkip, Detail: "not running"}}
+	}
+	if err != nil {
+		return []doctorCheck{{Name: "daemon", Result: doctorWarn, Detail: err.Error()}}
+	}
+	defer dc.Close()
+	status, err := dc.Status()
+	if err != nil {
+		return []doctorCheck{{Name: "daemon", Result: doctorWarn, Detail: err.Error()}}
+	}
+	if len(status.Degraded) == 0 {
+		return []doctorCheck{{Name: "daemon", Result: doctorOK, Detail: "running, all capabilities work"}}
+	}
+	checks := []doctorCheck{{Name: "daemon", Result: doctorWarn, Detail: fmt.Sprintf("running, %d capabilities degraded", len(status.Degraded))}}
+	for _, d := range status.Degraded {
+		checks = append(checks, doctorCheck{
+			Name:   "daemon capability " + string(d.Capability),
+			Result: doctorWarn,
+			Detail: fmt.Sprintf("degraded since %s, as the %s endpoint failed: %s", d.Since.Local().Format(time.RFC3339), d.Endpoint, d.Error),
+		})
+	}
+	return checks
+}
+
+// doctorAPIEndpoint is an endpoint's spec from the manifest, together with
+// its path as configured via the endpoints config.
+type doctorAPIEndpoint struct {
+	personio.EndpointSpec `yaml:",inline"`
+	ConfiguredPath        string `json:"configuredPath" yaml:"configuredPath"`
+}
+
+func printDoctorAPI() error {
+	specs, err := personio.Manifest()
+	if err != nil {
+		return err
+	}
+	configured := reflect.ValueOf(personio.Endpoints(cfg.Endpoints).WithDefaults())
+	endpoints := make([]doctorAPIEndpoint, len(specs))
+	for i, spec := range specs {
+		endpoints[i] = doctorAPIEndpoint{EndpointSpec: spec, ConfiguredPath: spec.Path}
+		if field := configured.FieldByName(spec.Name); field.IsValid() {
+			endpoints[i].ConfiguredPath = field.String()
+		}
+	}
+	if cfg.Output != config.OutFormatPretty {
+		return printOutputJSONOrYAML(endpoints)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, e := range endpoints {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		lastVerified := e.LastVerified
+		if lastVerified == "" {
+			lastVerified = "unknown"
+		}
+		color.New(color.Bold).Fprintf(w, "%s", e.Name)
+		fmt.Fprintf(w, " (%s, last verified: %s)\n", e.Status, lastVerified)
+		fmt.Fprintf(w, "  %s %s\n", e.Method, e.Path)
+		if e.ConfiguredPath != e.Path {
+			color.New(color.FgYellow).Fprintf(w, "  Overridden via the endpoints config: %s\n", e.ConfiguredPath)
+		}
+		for _, section := range []struct {
+			title  string
+			fields []personio.FieldSpec
+		}{
+			{"Query", e.Query},
+			{"Request", e.Request},
+			{"Response", e.Response},
+		} {
+			if len(section.fields) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "  %s:\n", section.title)
+			for _, f := range section.fields {
+				fmt.Fprintf(w, "    %s\t%s\t%s\n", f.Name, f.Type, f.Description)
+			}
+		}
+		if len(e.Headers) > 0 {
+			fmt.Fprintln(w, "  Headers:")
+			for _, h := range e.Headers {
+				fmt.Fprintf(w, "    %s\t%s\n", h.Name, h.Value)
+			}
+		}
+		if e.Notes != "" {
+			fmt.Fprintf(w, "  Notes: %s\n", e.Notes)
+		}
+	}
+	return w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().BoolVar(&doctorFlags.api, "api", false, "Print what's known about Personio's endpoints, instead of running checks")
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
+)
+
+var employeeFlags = struct {
+	employee string
+	yes      bool
+}{}
+
+// addEmployeeFlags adds the --employee flag for acting on behalf of other
+// employees, and for commands that write also the --yes flag.
+func addEmployeeFlags(flags *pflag.FlagSet, write bool) {
+	flags.StringVar(&employeeFlags.employee, "employee", "", `Act on behalf of another employee, by ID or by email from the "employees" config (requires admin rights)`)
+	if write {
+		flags.BoolVarP(&employeeFlags.yes, "yes", "y", false, "Skip confirmation when changing another employee's attendance")
+	}
+}
+
+// resolveEmployeeID parses an employee ID, or looks up the ID of an email
+// from the "employees" config.
+func resolveEmployeeID(s string) (int, error) {
+	if id, err := strconv.Atoi(s); err == nil {
+		if id <= 0 {
+			return 0, fmt.Errorf("invalid employee ID: %d", id)
+		}
+		return id, nil
+	}
+	for _, e := range cfg.Employees {
+		if strings.EqualFold(e.Email, s) {
+			return e.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown employee %q, must be an employee ID or an email from the \"employees\" config", s)
+}
+
+// checkEmployeeFlagOffline returns an error if the --employee flag is
+// combined with --offline, as queued changes are always for yourself.
+func checkEmployeeFlagOffline() error {
+	if employeeFlags.employee != "" && attendanceFlags.offline {
+		return errors.New("the --employee flag cannot be combined with --offline")
+	}
+	return nil
+}
+
+// actOnBehalfOf makes the client act on the employee from the --employee
+// flag, if set. It first checks that the account has permission to do the
+// action ("view", "edit", or "delete"), and asks for confirmation before
+// changing another employee's attendance.
+func actOnBehalfOf(client *personio.Client, action string) error {
+	if employeeFlags.employee == "" {
+		return nil
+	}
+	id, err := resolveEmployeeID(employeeFlags.employee)
+	if err != nil {
+		return err
+	}
+	if id == client.EmployeeID {
+		return nil
+	}
+
+	perms, err := client.GetPermissions(id)
+	if err != nil {
+		if errors.Is(err, personio.ErrNon2xxStatusCode) {
+			return fmt.Errorf("%w: your account cannot view attendance for other employees (employee %d): %s",
+				personio.ErrPermissionDenied, id, err)
+		}
+		return fmt.Errorf("get permissions for employee %d: %w", id, err)
+	}
+	var check func(ownEmployeeID int) error
+	switch action {
+	case "edit":
+		check = perms.CheckEdit
+	case "delete":
+		check = perms.CheckDelete
+	default:
+		check = perms.CheckView
+	}
+	if err := check(client.EmployeeID); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("employee %d", id)
+	if employee, err := client.GetEmployeeData(id); err == nil && employee.FirstName != "" {
+		name = fmt.Sprintf("%s %s (employee %d)", employee.FirstName, employee.LastName, id)
+	}
+	if action != "view" && !employeeFlags.yes {
+		if !isInteractive() {
+			return fmt.Errorf("changing the attendance of %s requires confirmation, use --yes to skip it: %w", name, errNoPrompt("confirmation"))
+		}
+		var confirmed bool
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Really %s the attendance of %s?", action, name),
+		}
+		if err := survey.AskOne(prompt, &confirmed); err != nil {
+			return err
+		}
+		if !confirmed {
+			return errors.New("aborted")
+		}
+	}
+
+	client.ActOnBehalfOf(id)
+	log.Info().Int("employeeId", id).Msgf("Acting on behalf of %s.", name)
+	return nil
+}
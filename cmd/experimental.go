@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var errExperimentalDisabled = errors.New("experimental feature is disabled")
+
+// markExperimental gates the command behind a flag in the "experimental"
+// config, so default installations never send requests to endpoints that
+// have not yet been verified.
+func markExperimental(cmd *cobra.Command, key string, enabled func() bool) {
+	if cmd.Long == "" {
+		cmd.Long = cmd.Short
+	}
+	cmd.Short += " (experimental)"
+	cmd.Long += fmt.Sprintf(`
+
+This command is experimental, as it uses newly reverse-engineered endpoints
+that are not yet verified against many tenants. Enable it via the
+"experimental.%s" config.`, key)
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if !enabled() {
+			return fmt.Errorf(`%w: enable it via the "experimental.%s" config`, errExperimentalDisabled, key)
+		}
+		log.Warn().
+			Str("feature", key).
+			Msg("Using experimental feature, please report any issues.")
+		return nil
+	}
+}
@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/export"
+	"github.com/applejag/rootless-personio/pkg/filelock"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var exportFlags = struct {
+	startDate flagtype.Date
+	endDate   flagtype.Date
+	file      string
+	state     string
+}{
+	file: "-",
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export your attendance periods as a JSON stream",
+	Long: `Export your attendance periods as a JSON stream, with one JSON
+object per line, e.g for backups into external systems.
+
+The objects use the same fields as the input of "attendance set",
+together with an "action" field that is either "upsert" or "delete".
+
+When using --state, the exported periods are remembered in the given
+state file, and repeated exports only write the periods that were added
+or changed since the previous export, together with deletions of periods
+that no longer exist. This allows cheap incremental backups.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := resolveRange(cmd, exportFlags.startDate, exportFlags.endDate)
+		if err != nil {
+			return err
+		}
+		if exportFlags.state == "" {
+			return runExport(r, nil)
+		}
+		// Hold the lock from load to save, so concurrent exports don't
+		// compute their deltas from the same state and lose changes.
+		return filelock.WithLock(exportFlags.state, func() error {
+			state, err := export.LoadState(exportFlags.state)
+			if err != nil {
+				return err
+			}
+			log.Debug().
+				Time("watermark", state.Watermark).
+				Int("periods", len(state.Periods)).
+				Msg("Loaded export state.")
+			return runExport(r, state)
+		})
+	},
+}
+
+// runExport exports the records of the date range, or only the changes
+// since the previous export if the state is not nil, and saves the state.
+func runExport(r datespec.Range, state *export.State) error {
+	client, err := newLoggedInClient()
+	if err != nil {
+		return err
+	}
+	w, closeFile, err := openExportFile(exportFlags.file)
+	if err != nil {
+		return err
+	}
+	defer closeFile()
+	enc := json.NewEncoder(w)
+
+	// Each month is written as soon as it's fetched, so exporting
+	// many years doesn't keep them all in memory.
+	var changed, total int
+	now := time.Now()
+	err = forEachCalendarMonth(client, r, func(cal *personio.AttendanceCalendar, month datespec.Range) error {
+		records, err := export.Records(cal)
+		if err != nil {
+			return err
+		}
+		total += len(records)
+		if state != nil {
+			records, err = state.Delta(records, month, now)
+			if err != nil {
+				return err
+			}
+		}
+		changed += len(records)
+		return writeExportRecords(enc, records)
+	})
+	if err != nil {
+		return err
+	}
+	if state != nil {
+		log.Info().
+			Int("changed", changed).
+			Int("total", total).
+			Msg("Calculated changes since previous export.")
+	}
+
+	// Only save after the records were written, so a failed export
+	// gets retried in full on the next run.
+	if state != nil {
+		if err := state.Save(exportFlags.state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openExportFile opens the file to export to, where "-" means STDOUT.
+func openExportFile(path string) (io.Writer, func(), error) {
+	if path == "-" {
+		return os.Stdout, func() {}, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create export file: %w", err)
+	}
+	return file, func() { file.Close() }, nil
+}
+
+func writeExportRecords(enc *json.Encoder, records []export.Record) error {
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return fmt.Errorf("write export: %w", err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().VarP(&exportFlags.startDate, "start", "s", "Start date to export (default start of dateRange config, this month)")
+	exportCmd.Flags().VarP(&exportFlags.endDate, "end", "e", "End date to export (default end of dateRange config, this month)")
+	exportCmd.Flags().StringVarP(&exportFlags.file, "file", "f", exportFlags.file, `File to write to, "-" means STDOUT`)
+	exportCmd.Flags().StringVar(&exportFlags.state, "state", "", "State file used to only export changes since the previous export")
+}
@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/filelock"
+	"github.com/applejag/rootless-personio/pkg/hook"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var flushFlags = struct {
+	dryRun bool
+}{}
+
+var flushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Send attendance changes that were queued while offline",
+	Long: `Send attendance changes that were queued via the --offline flag
+on the "attendance set" and "attendance remove" commands.
+
+The changes are sent in the order they were queued. If a change fails,
+then it and all changes after it are kept in the queue, so you can
+run "flush" again later. Changes to months that Personio has locked after
+a payroll run can never be sent, so those are dropped from the queue.
+
+The pre-submit hook is run when queueing, while the post-submit hook
+is run when the change is sent.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := queuePath()
+		if err != nil {
+			return err
+		}
+		// Only one run may flush at a time, as concurrent runs would
+		// otherwise send the same operations twice
+		lock, err := filelock.Acquire(path+".flush", 0)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+		ops, err := queue.Load(path)
+		if err != nil {
+			return err
+		}
+		if len(ops) == 0 || flushFlags.dryRun {
+			log.Info().Int("operations", len(ops)).Msg("Queued operations.")
+			return printOutputJSONOrYAML(map[string]any{
+				"queued": ops,
+			})
+		}
+
+		client, err := newLoggedInClient()
+		if err != nil {
+			return err
+		}
+		flushed, dropped, err := flushQueue(context.Background(), client, path, ops)
+		if err != nil {
+			return err
+		}
+		return printOutputJSONOrYAML(map[string]any{
+			"flushed": flushed,
+			"dropped": dropped,
+		})
+	},
+}
+
+// flushQueue sends the queued operations in order, until one fails or the
+// context is done, and then removes the sent operations from the queue,
+// along with the ones that can never be sent as their month is locked.
+// The caller must hold the queue's flush lock.
+func flushQueue(ctx context.Context, client *personio.Client, path string, ops []queue.Operation) (flushed, dropped []queue.Operation, err error) {
+	for _, op := range ops {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("stopped with %d operations left in the queue: %w",
+				len(ops)-len(flushed)-len(dropped), ctx.Err())
+			break
+		}
+		opErr := flushOperation(client, op)
+		if errors.Is(opErr, personio.ErrMonthLocked) {
+			log.Error().Err(opErr).
+				Str("action", string(op.Action)).
+				Str("day", op.Day).
+				Msg("Dropped queued operation, as its month is locked.")
+			dropped = append(dropped, op)
+			continue
+		}
+		if opErr != nil {
+			err = fmt.Errorf("flush %s of %s (queued %s): %w",
+				op.Action, op.Day, op.QueuedAt.Format(time.RFC3339), opErr)
+			break
+		}
+		flushed = append(flushed, op)
+	}
+	if saveErr := removeFromQueue(path, flushed, dropped); saveErr != nil {
+		if err == nil {
+			return flushed, dropped, saveErr
+		}
+		log.Error().Err(saveErr).Msg("Failed saving remaining queue.")
+	}
+	return flushed, dropped, err
+}
+
+// removeFromQueue removes the handled operations from the queue, keeping
+// operations queued by other runs while flushing.
+func removeFromQueue(path string, handled ...[]queue.Operation) error {
+	var ids []uuid.UUID
+	for _, ops := range handled {
+		for _, op := range ops {
+			ids = append(ids, op.ID)
+		}
+	}
+	return queue.Remove(path, ids...)
+}
+
+func flushOperation(client *personio.Client, op queue.Operation) error {
+	err := sendOperation(client, op)
+	recordAudit("flush", client, op, err, nil)
+	if err != nil {
+		return err
+	}
+	log.Info().
+		Str("action", string(op.Action)).
+		Str("day", op.Day).
+		Msg("Successfully flushed queued operation.")
+
+	hookDays := []submitHookDay{{Day: op.Day, Periods: op.Periods}}
+	if err := runSubmitHook(hook.PostSubmit, cfg.Hooks.PostSubmit, string(op.Action), client.TargetEmployeeID(), hookDays); err != nil {
+		// The operation was already sent, so it must not be kept in the queue
+		log.Warn().Err(err).Str("day", op.Day).Msg("Failed running post-submit hook.")
+	}
+	return nil
+}
+
+// logOfflineHint suggests the --offline flag when Personio is unreachable.
+func logOfflineHint(err error) {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		log.Warn().Msg(`Personio seems unreachable. Use --offline to queue the changes and send them later via "flush".`)
+	}
+}
+
+// queueOperations appends the operations to the offline queue,
+// instead of sending them to Personio.
+func queueOperations(ops []queue.Operation) error {
+	path, err := queuePath()
+	if err != nil {
+		return err
+	}
+	if err := queue.Append(path, ops...); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		log.Info().
+			Str("action", string(op.Action)).
+			Str("day", op.Day).
+			Msg(`Queued operation, send it later via "flush".`)
+	}
+	return printOutputJSONOrYAML(map[string]any{
+		"queued": ops,
+	})
+}
+
+func queuePath() (string, error) {
+	if cfg.Queue.Path != "" {
+		return cfg.Queue.Path, nil
+	}
+	return queue.DefaultPath()
+}
+
+func init() {
+	rootCmd.AddCommand(flushCmd)
+
+	flushCmd.Flags().BoolVar(&flushFlags.dryRun, "dry-run", false, "Only list the queued changes, without sending them")
+}
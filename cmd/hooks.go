@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"github.com/applejag/rootless-personio/pkg/hook"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+)
+
+type submitHookPayload struct {
+	Hook       hook.Name       `json:"hook"`
+	Action     string          `json:"action"` // "set" or "remove"
+	EmployeeID int             `json:"employeeId,omitempty"`
+	Days       []submitHookDay `json:"days"`
+}
+
+type submitHookDay struct {
+	Day     string            `json:"day"`
+	Periods []personio.Period `json:"periods,omitempty"`
+}
+
+type loginFailureHookPayload struct {
+	Hook    hook.Name `json:"hook"`
+	BaseURL string    `json:"baseUrl"`
+	Email   string    `json:"email"`
+	Error   string    `json:"error"`
+}
+
+func runSubmitHook(name hook.Name, command []string, action string, employeeID int, days []submitHookDay) error {
+	return hook.Run(name, command, submitHookPayload{
+		Hook:       name,
+		Action:     action,
+		EmployeeID: employeeID,
+		Days:       days,
+	})
+}
+
+func runLoginFailureHook(baseURL string, loginErr error) {
+	err := hook.Run(hook.OnLoginFailure, cfg.Hooks.OnLoginFailure, loginFailureHookPayload{
+		Hook:    hook.OnLoginFailure,
+		BaseURL: baseURL,
+		Email:   cfg.Auth.Email,
+		Error:   loginErr.Error(),
+	})
+	if err != nil {
+		// Don't hide the login error behind the hook's error
+		log.Warn().Err(err).Msg("Failed running login failure hook.")
+	}
+}
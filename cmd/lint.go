@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/mirror"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var errLintFindings = errors.New("lint findings")
+
+var lintFlags = struct {
+	month  flagtype.Month
+	failOn string
+}{
+	failOn: string(report.SeverityError),
+}
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Flag suspicious attendance entries",
+	Long: `Flag suspicious attendance entries in a month, such as:
+
+- past workdays without any work logged
+- duplicate or overlapping periods
+- days with more work than lint.maxDay
+- work on public holidays or during booked absences
+- days whose start, end, or amount of work differs wildly from your
+  usual days, as seen in the lint.baselineMonths before the month
+
+Exits with a non-zero exit code when there are findings of the
+--fail-on severity or higher, which makes it usable in CI-like checks
+together with --output json.
+
+Use --output github inside GitHub Actions to get the findings as
+workflow annotations and as a Markdown table in the step summary.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failOn, err := report.ParseSeverity(lintFlags.failOn)
+		if err != nil {
+			return fmt.Errorf("flag --fail-on: %w", err)
+		}
+		month := lintFlags.month.Time()
+		if lintFlags.month.IsZero() {
+			month = time.Now()
+		}
+		r := datespec.ThisMonth(month)
+
+		source, err := newCalendarSource()
+		if err != nil {
+			return err
+		}
+		defer closeCalendarSource(source)
+		days, err := fetchReportDays(source, r)
+		if err != nil {
+			return err
+		}
+		var baseline []report.Day
+		if cfg.Lint.BaselineMonths > 0 {
+			baseline, err = fetchReportDays(source, datespec.Range{
+				Start: r.Start.AddDate(0, -cfg.Lint.BaselineMonths, 0),
+				End:   r.Start.AddDate(0, 0, -1),
+			})
+			if errors.Is(err, mirror.ErrNotSynced) {
+				log.Warn().Err(err).Msg("Skipping baseline, as it is not in the mirror.")
+			} else if err != nil {
+				return err
+			}
+		}
+		rules := report.DefaultLintRules
+		rules.MaxDay = cfg.Lint.MaxDay
+		findings := report.Lint(days, baseline, rules, time.Now())
+
+		switch cfg.Output {
+		case config.OutFormatPretty:
+			console.PrintLintFindings(findings)
+		case config.OutFormatGitHub:
+			console.PrintLintFindingsGitHub(findings)
+			if err := writeLintStepSummary(r.Start.Format("2006-01"), findings); err != nil {
+				return err
+			}
+		default:
+			if err := printOutputJSONOrYAML(findings); err != nil {
+				return err
+			}
+		}
+
+		var failed int
+		for _, f := range findings {
+			if f.Severity.AtLeast(failOn) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d finding(s) with severity %s or higher", errLintFindings, failed, failOn)
+		}
+		return nil
+	},
+}
+
+// writeLintStepSummary appends the findings as Markdown to the GitHub Actions
+// step summary, if running inside GitHub Actions.
+func writeLintStepSummary(month string, findings []report.Finding) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		log.Debug().Msg("Not writing step summary, as $GITHUB_STEP_SUMMARY is unset.")
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open step summary: %w", err)
+	}
+	defer file.Close()
+	if err := console.WriteLintStepSummaryGitHub(file, month, findings); err != nil {
+		return fmt.Errorf("write step summary: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	addMirrorFlag(lintCmd.Flags())
+
+	lintCmd.Flags().VarP(&lintFlags.month, "month", "m", "Month to lint, e.g 2023-01 (default this month)")
+	lintCmd.Flags().StringVar(&lintFlags.failOn, "fail-on", lintFlags.failOn, "Lowest severity that fails the command (info, warning, error)")
+}
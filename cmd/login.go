@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/applejag/rootless-personio/pkg/keyring"
+	"github.com/applejag/rootless-personio/pkg/lockout"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+)
+
+// maxPasswordAttempts is how many times the user may enter a wrong password
+// when prompted, before giving up.
+const maxPasswordAttempts = 3
+
+// login logs in using the configured auth provider. For the default
+// "password" provider, it uses the configured password, or the password
+// stored in the OS keyring, or else prompts for the password.
+func login(client *personio.Client) error {
+	auth := cfg.Auth
+	if err := checkLockout(auth.Email); err != nil {
+		return err
+	}
+	if auth.Provider != "" && auth.Provider != "password" {
+		provider, err := newAuthProvider(auth)
+		if err != nil {
+			return err
+		}
+		return guardLogin(auth.Email, func() error {
+			return provider.Login(client)
+		})
+	}
+	if auth.Email == "" {
+		log.Error().Msg("Missing email! Must set auth.email config or PERSONIO_AUTH_EMAIL env var.")
+		return errors.New("missing credentials")
+	}
+	if auth.Password != "" {
+		return loginWithPassword(client, auth)
+	}
+
+	if auth.Keyring {
+		password, err := keyring.Get(auth.Email)
+		switch {
+		case err == nil:
+			log.Debug().Msg("Using password from the OS keyring.")
+			auth.Password = password
+			err := loginWithPassword(client, auth)
+			if err == nil {
+				cfg.Auth.Password = password
+				return nil
+			}
+			if !errors.Is(err, personio.ErrInvalidCredentials) || !isInteractive() {
+				return err
+			}
+			log.Warn().Err(err).Msg("The password stored in the OS keyring was not accepted.")
+		case errors.Is(err, keyring.ErrNotFound):
+			log.Debug().Msg("No password found in the OS keyring.")
+		default:
+			log.Debug().Err(err).Msg("Failed reading password from the OS keyring.")
+		}
+	}
+
+	if !isInteractive() {
+		log.Error().Msg("Missing password! Must set auth.password config or PERSONIO_AUTH_PASSWORD env var.")
+		return fmt.Errorf("missing credentials: %w", errNoPrompt("the password of "+auth.Email))
+	}
+
+	for attempt := 1; ; attempt++ {
+		password, err := promptPassword(auth.Email)
+		if err != nil {
+			return err
+		}
+		auth.Password = password
+		err = loginWithPassword(client, auth)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, personio.ErrInvalidCredentials) {
+			return err
+		}
+		if attempt >= maxPasswordAttempts {
+			log.Error().Err(err).Msgf("Failed to log in after %d attempts.", maxPasswordAttempts)
+			return err
+		}
+		log.Warn().Err(err).Msgf("Wrong email or password. Please try again (%d attempts left).", maxPasswordAttempts-attempt)
+	}
+	// Keep it for later logins, such as when the daemon's session expires
+	cfg.Auth.Password = auth.Password
+
+	if auth.Keyring {
+		offerStorePassword(auth.Email, auth.Password)
+	}
+	return nil
+}
+
+func promptPassword(email string) (string, error) {
+	var password string
+	prompt := &survey.Password{
+		Message: "Password for " + email + ":",
+	}
+	if err := survey.AskOne(prompt, &password, survey.WithValidator(survey.Required)); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+func offerStorePassword(email, password string) {
+	var store bool
+	prompt := &survey.Confirm{
+		Message: "Store the password in the OS keyring?",
+		Default: true,
+	}
+	if err := survey.AskOne(prompt, &store); err != nil || !store {
+		return
+	}
+	if err := keyring.Set(email, password); err != nil {
+		log.Warn().Err(err).Msg("Failed to store password in the OS keyring.")
+		return
+	}
+	log.Info().Msg("Stored password in the OS keyring.")
+}
+
+// isInteractive returns true if the user can be prompted, i.e if both
+// stdin and stdout are terminals.
+func isInteractive() bool {
+	if cfg.NonInteractive {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// errNoPrompt returns an error for when prompting for the input is needed,
+// but not possible, saying why.
+func errNoPrompt(input string) error {
+	if cfg.NonInteractive {
+		return fmt.Errorf("refusing to prompt for %s, as prompts are disabled by --non-interactive", input)
+	}
+	return fmt.Errorf("cannot prompt for %s, as not running in a terminal", input)
+}
+
+// guardLogin refuses to log in while Personio has locked out logins due to
+// too many login attempts, and remembers any new lockout from the login.
+func guardLogin(email string, login func() error) error {
+	if err := checkLockout(email); err != nil {
+		return err
+	}
+	err := login()
+	path, pathErr := lockout.DefaultPath()
+	if pathErr != nil {
+		return err
+	}
+	var lockoutErr *personio.LockoutError
+	switch {
+	case err == nil:
+		if err := lockout.Clear(path); err != nil {
+			log.Warn().Err(err).Msg("Failed to clear login lockout state.")
+		}
+	case errors.As(err, &lockoutErr):
+		cooldown := lockoutErr.RetryAfter
+		if cooldown <= 0 {
+			cooldown = cfg.Auth.LockoutCooldown
+		}
+		state := lockout.State{
+			Email:   email,
+			Until:   time.Now().Add(cooldown),
+			Message: lockoutErr.Message,
+		}
+		if err := lockout.Save(path, state); err != nil {
+			log.Warn().Err(err).Msg("Failed to save login lockout state.")
+		}
+		log.Error().
+			Time("until", state.Until).
+			Msg("Personio refuses logging in due to too many attempts. Will not try again until the cooldown has passed.")
+	}
+	return err
+}
+
+func checkLockout(email string) error {
+	path, err := lockout.DefaultPath()
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to find login lockout state file, skipping lockout check.")
+		return nil
+	}
+	return lockout.Check(path, email, time.Now())
+}
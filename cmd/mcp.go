@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/hook"
+	"github.com/applejag/rootless-personio/pkg/mcp"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve tools to AI assistants via the Model Context Protocol",
+	Long: `Serve tools to AI assistants via the Model Context Protocol (MCP),
+using the stdio transport, i.e JSON-RPC on STDIN and STDOUT.
+
+Tools:
+
+- get_attendance: the attendance periods within a date range
+- list_absences: the absences and public holidays within a date range
+- set_attendance: replaces the attendance periods on a day
+
+The set_attendance tool is hidden when mcp.readOnly is enabled (default),
+and only reports what it would have done when mcp.dryRun is enabled.
+Logging is written to STDERR, so it doesn't interfere with the protocol.`,
+	Example: `  # Example config for MCP clients:
+  {
+    "mcpServers": {
+      "personio": {
+        "command": "rootless-personio",
+        "args": ["mcp", "--read-only=false", "--dry-run"]
+      }
+    }
+  }`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		srv := mcp.Server{
+			Name:    "rootless-personio",
+			Version: buildVersion(),
+			Tools:   mcpTools(cfg.MCP.ReadOnly, cfg.MCP.DryRun),
+		}
+		return srv.Serve(os.Stdin, os.Stdout)
+	},
+}
+
+// mcpClient logs in lazily on the first tool call, so the assistant
+// can list the tools without waiting for the login.
+var mcpClient = struct {
+	once   sync.Once
+	client *personio.Client
+	err    error
+}{}
+
+func getMCPClient() (*personio.Client, error) {
+	mcpClient.once.Do(func() {
+		mcpClient.client, mcpClient.err = newLoggedInClient()
+	})
+	return mcpClient.client, mcpClient.err
+}
+
+type mcpDateRangeArgs struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+func (a mcpDateRangeArgs) parse() (time.Time, time.Time, error) {
+	start, err := time.Parse(time.DateOnly, a.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date: %w", err)
+	}
+	end, err := time.Parse(time.DateOnly, a.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date: %w", err)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, errors.New("end date must not be before start date")
+	}
+	return start, end, nil
+}
+
+var mcpDateRangeSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"start": map[string]any{"type": "string", "format": "date", "description": "First date, in the format YYYY-MM-DD"},
+		"end":   map[string]any{"type": "string", "format": "date", "description": "Last date (inclusive), in the format YYYY-MM-DD"},
+	},
+	"required": []string{"start", "end"},
+}
+
+type mcpSetAttendanceArgs struct {
+	Date    string            `json:"date"`
+	Periods []personio.Period `json:"periods"`
+}
+
+func mcpTools(readOnly, dryRun bool) []mcp.Tool {
+	tools := []mcp.Tool{
+		{
+			Name:        "get_attendance",
+			Description: "Get the user's attendance periods (work and breaks) per day within a date range.",
+			InputSchema: mcpDateRangeSchema,
+			Handler: func(raw json.RawMessage) (any, error) {
+				var args mcpDateRangeArgs
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				start, end, err := args.parse()
+				if err != nil {
+					return nil, err
+				}
+				client, err := getMCPClient()
+				if err != nil {
+					return nil, err
+				}
+				var days []submitHookDay
+				for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+					periods, err := client.GetAttendancePeriods(date)
+					if err != nil {
+						return nil, err
+					}
+					if len(periods) > 0 {
+						days = append(days, submitHookDay{Day: date.Format(time.DateOnly), Periods: periods})
+					}
+				}
+				return days, nil
+			},
+		},
+		{
+			Name:        "list_absences",
+			Description: "List the user's absences (e.g vacation, sick leave) and public holidays within a date range.",
+			InputSchema: mcpDateRangeSchema,
+			Handler: func(raw json.RawMessage) (any, error) {
+				var args mcpDateRangeArgs
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				start, end, err := args.parse()
+				if err != nil {
+					return nil, err
+				}
+				client, err := getMCPClient()
+				if err != nil {
+					return nil, err
+				}
+				cal, err := client.GetAttendanceCalendarMerged(client.TargetEmployeeID(), start, end)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{
+					"absences": cal.GetAbsencePeriods(),
+					"holidays": cal.GetHolidays(),
+				}, nil
+			},
+		},
+	}
+	if readOnly {
+		return tools
+	}
+	return append(tools, mcp.Tool{
+		Name: "set_attendance",
+		Description: "Replace all of the user's attendance periods on a single day. " +
+			"Periods not included are deleted. Times are RFC 3339 timestamps.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"date": map[string]any{"type": "string", "format": "date", "description": "The day, in the format YYYY-MM-DD"},
+				"periods": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"start":       map[string]any{"type": "string", "format": "date-time"},
+							"end":         map[string]any{"type": "string", "format": "date-time"},
+							"period_type": map[string]any{"type": "string", "enum": []string{"work", "break"}},
+							"comment":     map[string]any{"type": "string"},
+							"project_id":  map[string]any{"type": "integer"},
+						},
+						"required": []string{"start", "end", "period_type"},
+					},
+				},
+			},
+			"required": []string{"date", "periods"},
+		},
+		Handler: func(raw json.RawMessage) (any, error) {
+			var args mcpSetAttendanceArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, err
+			}
+			date, err := time.Parse(time.DateOnly, args.Date)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date: %w", err)
+			}
+			if len(args.Periods) == 0 {
+				return nil, errors.New("missing periods, use at least one period")
+			}
+			for _, p := range args.Periods {
+				if p.Start.Format(time.DateOnly) != args.Date {
+					return nil, fmt.Errorf("period starting at %s is not on %s", p.Start.Format(time.RFC3339), args.Date)
+				}
+				if !p.End.After(p.Start) {
+					return nil, fmt.Errorf("period starting at %s must end after it starts", p.Start.Format(time.RFC3339))
+				}
+			}
+			periods := args.Periods
+			script, err := loadTransformScript()
+			if err != nil {
+				return nil, fmt.Errorf("load transform script: %w", err)
+			}
+			if script != nil {
+				if periods, err = script.Apply(periods); err != nil {
+					return nil, fmt.Errorf("transform periods: %w", err)
+				}
+			}
+			if dryRun {
+				return map[string]any{
+					"dryRun":  true,
+					"message": "Dry run, nothing was sent to Personio.",
+					"day":     args.Date,
+					"periods": periods,
+				}, nil
+			}
+
+			client, err := getMCPClient()
+			if err != nil {
+				return nil, err
+			}
+			hookDays := []submitHookDay{{Day: args.Date, Periods: periods}}
+			if err := runSubmitHook(hook.PreSubmit, cfg.Hooks.PreSubmit, "set", client.TargetEmployeeID(), hookDays); err != nil {
+				return nil, err
+			}
+			err = client.SetAttendance(date, periods)
+			recordAudit("mcp set_attendance", client, queue.NewOperation(queue.ActionSet, args.Date, periods, time.Now()), err, nil)
+			if err != nil {
+				return nil, err
+			}
+			if err := runSubmitHook(hook.PostSubmit, cfg.Hooks.PostSubmit, "set", client.TargetEmployeeID(), hookDays); err != nil {
+				return nil, err
+			}
+			return map[string]any{
+				"day":     args.Date,
+				"periods": periods,
+			}, nil
+		},
+	})
+}
+
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+
+	mcpCmd.Flags().BoolVar(&cfg.MCP.ReadOnly, "read-only", cfg.MCP.ReadOnly, "Hide tools that modify data")
+	mcpCmd.Flags().BoolVar(&cfg.MCP.DryRun, "dry-run", cfg.MCP.DryRun, "Only report what tools that modify data would do")
+}
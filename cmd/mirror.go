@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/mirror"
+	"github.com/applejag/rootless-personio/pkg/pacer"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror",
+	Short: "Group of commands for the local SQLite mirror of your attendance",
+	Long: `Group of commands for the local SQLite mirror of your attendance.
+
+The mirror contains your attendance days, periods, absences, and holidays,
+and is updated via "mirror sync". Use the --mirror flag on the "stats",
+"lint", and "report" commands to read from the mirror instead of Personio,
+for instant and offline analysis.`,
+}
+
+var mirrorSyncFlags = struct {
+	startDate   flagtype.Date
+	endDate     flagtype.Date
+	maxDuration time.Duration
+}{}
+
+var mirrorSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Update the local mirror with data from Personio",
+	Long: `Update the local mirror with data from Personio.
+
+Data is always synced in full months, where the dates are expanded to the
+start and end of their months.
+
+Requests are paced using the rate limit headers from Personio's responses.
+For big backfills, use --max-duration to spread the requests evenly across
+a bounded amount of time. Syncing stops when the time is up, and can be
+continued by running the command again with --start set to the first month
+that was not synced.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		now := time.Now()
+		r := datespec.Resolve(
+			mirrorSyncFlags.startDate.Time(),
+			mirrorSyncFlags.endDate.Time(),
+			datespec.Range{
+				Start: time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC),
+				End:   datespec.ThisMonth(now).End,
+			})
+		r.Start, _ = util.TimeFullMonth(r.Start)
+		_, r.End = util.TimeFullMonth(r.End)
+
+		m, err := openMirror()
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		client, err := newLoggedInClient()
+		if err != nil {
+			return err
+		}
+		source := &pacedCalendarSource{
+			ctx:    cmd.Context(),
+			client: client,
+			pacer:  pacer.New(countMonths(r), mirrorSyncFlags.maxDuration, time.Now()),
+		}
+		var months int
+		err = forEachCalendarMonth(source, r, func(cal *personio.AttendanceCalendar, month datespec.Range) error {
+			if err := m.Sync(cal, month, time.Now()); err != nil {
+				return err
+			}
+			months++
+			log.Info().
+				Str("month", month.Start.Format("2006-01")).
+				Int("periods", len(cal.AttendancePeriods.Data)).
+				Msg("Synced month.")
+			return nil
+		})
+		if errors.Is(err, pacer.ErrMaxDurationReached) {
+			next, _ := util.TimeFullMonth(r.Start.AddDate(0, months, 0))
+			return fmt.Errorf("synced %d months before reaching --max-duration, continue with --start %s: %w",
+				months, next.Format(time.DateOnly), err)
+		}
+		if err != nil {
+			return err
+		}
+		log.Info().Int("months", months).Msg("Mirror is up to date.")
+		return nil
+	},
+}
+
+var mirrorQueryCmd = &cobra.Command{
+	Use:   "query <sql>",
+	Short: "Run an SQL query against the local mirror",
+	Long: `Run an SQL query against the local mirror.
+
+The mirror has the tables "days", "periods", "absences", "holidays",
+and "synced_months". Dates are stored as text in the format "2006-01-02",
+and period start and end times as RFC 3339 text in UTC.`,
+	Example: `  rootless-personio mirror query "SELECT date, SUM(duration_min) FROM days GROUP BY strftime('%Y-%m', date)"`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := openMirror()
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		if cfg.Output == config.OutFormatPretty {
+			result, err := m.Query(args[0])
+			if err != nil {
+				return fmt.Errorf("query mirror: %w", err)
+			}
+			console.PrintQueryResult(result)
+			return nil
+		}
+
+		var stream outputStream
+		err = m.QueryEach(args[0], func(columns []string, values []any) error {
+			obj := make(map[string]any, len(values))
+			for i, col := range columns {
+				obj[col] = values[i]
+			}
+			return stream.Print(obj)
+		})
+		if err != nil {
+			return fmt.Errorf("query mirror: %w", err)
+		}
+		return stream.Close()
+	},
+}
+
+// pacedCalendarSource waits before each request to Personio, to spread the
+// requests according to the pacer and Personio's rate limit.
+type pacedCalendarSource struct {
+	ctx    context.Context
+	client *personio.Client
+	pacer  *pacer.Pacer
+}
+
+func (s *pacedCalendarSource) GetMyAttendanceCalendar(startDate, endDate time.Time) (*personio.AttendanceCalendar, error) {
+	rl, ok := s.client.RateLimit()
+	if ok {
+		log.Debug().
+			Int("remaining", rl.Remaining).
+			Time("reset", rl.Reset).
+			Msg("Pacing requests by rate limit.")
+	}
+	if err := s.pacer.Wait(s.ctx, rl, ok); err != nil {
+		return nil, err
+	}
+	return s.client.GetMyAttendanceCalendar(startDate, endDate)
+}
+
+// countMonths returns the number of calendar months touched by the range.
+func countMonths(r datespec.Range) int {
+	return (r.End.Year()-r.Start.Year())*12 + int(r.End.Month()-r.Start.Month()) + 1
+}
+
+func openMirror() (*mirror.Mirror, error) {
+	path := cfg.Mirror.Path
+	if path == "" {
+		defaultPath, err := mirror.DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+		path = defaultPath
+	}
+	log.Debug().Str("path", path).Msg("Opening mirror.")
+	return mirror.Open(path)
+}
+
+func init() {
+	rootCmd.AddCommand(mirrorCmd)
+	mirrorCmd.AddCommand(mirrorSyncCmd)
+	mirrorCmd.AddCommand(mirrorQueryCmd)
+
+	mirrorSyncCmd.Flags().VarP(&mirrorSyncFlags.startDate, "start", "s", "Start date to sync (default first day this year)")
+	mirrorSyncCmd.Flags().VarP(&mirrorSyncFlags.endDate, "end", "e", "End date to sync (default last day this month)")
+	mirrorSyncCmd.Flags().DurationVar(&mirrorSyncFlags.maxDuration, "max-duration", 0, `Spread the requests across this long, and stop when it's up, e.g "2h"`)
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+)
+
+// printJSONL prints the model as JSON Lines, where each element of a slice
+// is printed on its own line, and other models on a single line.
+func printJSONL(model any) error {
+	enc := json.NewEncoder(os.Stdout)
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Slice {
+		return enc.Encode(model)
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := enc.Encode(v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputStream prints the items of a list as they are produced. With the
+// "jsonl" output format each item is printed right away, while the other
+// formats collect the items and print them as a single list on Close.
+type outputStream struct {
+	items []any
+}
+
+// Print prints or collects the item.
+func (s *outputStream) Print(item any) error {
+	if cfg.Output == config.OutFormatJSONL {
+		return json.NewEncoder(os.Stdout).Encode(item)
+	}
+	s.items = append(s.items, item)
+	return nil
+}
+
+// Close prints the collected items, if any output format other than
+// "jsonl" is used.
+func (s *outputStream) Close() error {
+	if cfg.Output == config.OutFormatJSONL {
+		return nil
+	}
+	if s.items == nil {
+		s.items = []any{}
+	}
+	return printOutputJSONOrYAML(s.items)
+}
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/plugin"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Group of commands for plugins",
+	Long: `Group of commands for plugins.
+
+Plugins are executables on your PATH named "personio-plugin-<name>",
+similar to kubectl plugins. A plugin adds a subcommand, so that
+"rootless-personio foo bar" runs "personio-plugin-foo bar".
+
+A plugin can also be an importer, which is used via
+"attendance set --plugin foo -- [args...]". This runs
+"personio-plugin-foo import [args...]", which must write attendance
+periods to STDOUT in the same JSON format as read by "attendance set".
+
+Plugins get the PERSONIO_PLUGIN_BIN environment variable set to the path
+of this program, so they can call back into it, e.g to read the config via
+"$PERSONIO_PLUGIN_BIN config -o json".`,
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the plugins found on your PATH",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plugins, err := plugin.List()
+		if err != nil {
+			return err
+		}
+		for _, p := range plugins {
+			if isBuiltinCommand(p.Name) {
+				log.Warn().
+					Str("plugin", p.Name).
+					Str("path", p.Path).
+					Msg("Plugin has the same name as a built-in command, and can only be used as an importer.")
+			}
+		}
+		if cfg.Output == config.OutFormatPretty {
+			if len(plugins) == 0 {
+				fmt.Printf("No plugins found. Add executables named %q to your PATH.\n", plugin.Prefix+"<name>")
+			}
+			for _, p := range plugins {
+				fmt.Printf("%s\t%s\n", p.Name, p.Path)
+			}
+			return nil
+		}
+		return printOutputJSONOrYAML(plugins)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pluginCmd)
+	pluginCmd.AddCommand(pluginListCmd)
+}
+
+// reservedCommands are added by cobra when executing, and can therefore
+// not be found before then.
+var reservedCommands = []string{"help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd}
+
+func isBuiltinCommand(name string) bool {
+	for _, reserved := range reservedCommands {
+		if name == reserved {
+			return true
+		}
+	}
+	c, _, err := rootCmd.Find([]string{name})
+	return err == nil && c != rootCmd
+}
+
+// runPluginCommand runs the plugin named by the first argument, unless it's
+// a built-in command, and exits with the plugin's exit code. Returns
+// without doing anything if there is no such plugin.
+func runPluginCommand(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") || isBuiltinCommand(args[0]) {
+		return
+	}
+	p, err := plugin.Find(args[0])
+	if err != nil {
+		return
+	}
+	log.Debug().Str("plugin", p.Name).Str("path", p.Path).Msg("Running plugin.")
+	err = p.Run(args[1:]...)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		log.Error().Msgf("Failed running plugin %s: %s", p.Name, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// pluginImport is the output of an importer plugin, which reports the
+// plugin's failure when closed.
+type pluginImport struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	name   string
+	closed bool
+	err    error
+}
+
+// openPluginImport starts the importer plugin, and returns its STDOUT.
+func openPluginImport(name string, args []string) (io.ReadCloser, error) {
+	p, err := plugin.Find(name)
+	if err != nil {
+		return nil, err
+	}
+	c := p.Command(append([]string{"import"}, args...)...)
+	c.Stdin = os.Stdin
+	c.Stderr = os.Stderr
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Str("plugin", p.Name).Str("path", p.Path).Msg("Running importer plugin.")
+	if err := c.Start(); err != nil {
+		return nil, fmt.Errorf("start plugin %s: %w", name, err)
+	}
+	return &pluginImport{ReadCloser: stdout, cmd: c, name: name}, nil
+}
+
+func (p *pluginImport) Close() error {
+	if p.closed {
+		return p.err
+	}
+	p.closed = true
+	// Drain the output, so the plugin doesn't block on writing
+	io.Copy(io.Discard, p.ReadCloser)
+	if err := p.cmd.Wait(); err != nil {
+		p.err = fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+	return p.err
+}
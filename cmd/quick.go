@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/clock"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/spf13/cobra"
+)
+
+var quickCmd = &cobra.Command{
+	Use:   "quick",
+	Short: "Group of terse commands for launchers and status bars",
+	Long: `Group of terse commands meant for launcher integrations such as
+Raycast or Alfred, and for status bars such as waybar or polybar.
+
+Each command writes a single line to STDOUT, ignoring the --output flag:
+
+  quick today   "in 7:32" or "out 6:30"
+  quick in      "in 09:02"
+  quick out     "out 09:02-12:30 3:28"
+
+Durations are formatted as h:mm, and times as HH:MM in local time.
+Combine with --quiet to also silence any logging on STDERR.`,
+}
+
+var quickTodayCmd = &cobra.Command{
+	Use:   "today",
+	Short: "Print whether you're clocked in, and today's worked time",
+	Long: `Print whether you're clocked in or out, followed by today's
+worked time, including the time since "attendance start" when clocked in,
+excluding any pomodoro breaks.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := clockStatePath()
+		if err != nil {
+			return err
+		}
+		state, err := clock.Load(path)
+		if err != nil {
+			return err
+		}
+		client, err := newLoggedInClient()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		periods, err := client.GetAttendancePeriods(now)
+		if err != nil {
+			return err
+		}
+		worked := workedDuration(periods) + state.Worked(now, cfg.Clock.MinBreak)
+		fmt.Println(quickState(state), console.FormatDuration(worked))
+		return nil
+	},
+}
+
+var quickInCmd = &cobra.Command{
+	Use:   "in",
+	Short: "Start the punch clock",
+	Long:  `Start the punch clock, same as "attendance start", and print the start time.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := clockStatePath()
+		if err != nil {
+			return err
+		}
+		state, err := clock.In(path, time.Now(), clock.Options{})
+		if err != nil {
+			return err
+		}
+		fmt.Println("in", state.ClockedInAt.Local().Format("15:04"))
+		return nil
+	},
+}
+
+var quickOutCmd = &cobra.Command{
+	Use:   "out",
+	Short: "Stop the punch clock and submit the period",
+	Long: `Stop the punch clock and submit the period, same as "attendance stop",
+and print the submitted period and the worked time, excluding breaks.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := clockStatePath()
+		if err != nil {
+			return err
+		}
+		state, err := clock.Load(path)
+		if err != nil {
+			return err
+		}
+		if !state.IsClockedIn() {
+			return clock.ErrNotClockedIn
+		}
+		client, err := newLoggedInClient()
+		if err != nil {
+			logOfflineHint(err)
+			return err
+		}
+		periods, err := clockOut(client)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("out %s-%s %s\n",
+			periods[0].Start.Local().Format("15:04"),
+			periods[len(periods)-1].End.Local().Format("15:04"),
+			console.FormatDuration(workedDuration(periods)))
+		return nil
+	},
+}
+
+func quickState(state clock.State) string {
+	if state.IsClockedIn() {
+		return "in"
+	}
+	return "out"
+}
+
+// workedDuration sums up the work periods, excluding breaks.
+func workedDuration(periods []personio.Period) time.Duration {
+	var total time.Duration
+	for _, p := range periods {
+		if p.PeriodType == personio.PeriodTypeBreak {
+			continue
+		}
+		total += p.End.Sub(p.Start)
+	}
+	return total
+}
+
+func init() {
+	rootCmd.AddCommand(quickCmd)
+	quickCmd.AddCommand(quickTodayCmd)
+	quickCmd.AddCommand(quickInCmd)
+	quickCmd.AddCommand(quickOutCmd)
+}
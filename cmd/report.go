@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Group of commands for summarizing attendance and absences",
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	addMirrorFlag(reportCmd.PersistentFlags())
+	addOriginFlag(reportCmd.PersistentFlags())
+}
+
+// calendarSource is where attendance calendars are read from, which is
+// either Personio itself or the local mirror.
+type calendarSource interface {
+	GetMyAttendanceCalendar(startDate, endDate time.Time) (*personio.AttendanceCalendar, error)
+}
+
+var useMirror bool
+
+func addMirrorFlag(flags *pflag.FlagSet) {
+	flags.BoolVar(&useMirror, "mirror", false, `Read attendance from the local mirror instead of Personio (see "mirror sync")`)
+}
+
+var reportOrigins []string
+
+func addOriginFlag(flags *pflag.FlagSet) {
+	flags.StringSliceVar(&reportOrigins, "origin", nil, `Only include attendance periods created via these origins, e.g "web", "api", "import", or "unknown" (default all)`)
+}
+
+// originFilter returns the origins from the --origin flag, where "unknown"
+// is the empty origin of periods where Personio didn't report it.
+func originFilter() []personio.PeriodOrigin {
+	origins := make([]personio.PeriodOrigin, len(reportOrigins))
+	for i, o := range reportOrigins {
+		if o != "unknown" {
+			origins[i] = personio.PeriodOrigin(o)
+		}
+	}
+	return origins
+}
+
+// newCalendarSource returns the local mirror if the --mirror flag is set,
+// and otherwise a logged in client.
+func newCalendarSource() (calendarSource, error) {
+	if useMirror {
+		return openMirror()
+	}
+	return newLoggedInClient()
+}
+
+// closeCalendarSource closes the calendar source, if it needs closing.
+func closeCalendarSource(source calendarSource) {
+	if closer, ok := source.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed closing calendar source.")
+		}
+	}
+}
+
+// fetchReportDays fetches the attendance calendar one month at a time,
+// to not overload the API when reporting on long date ranges. The days only
+// contain periods of the origins from the --origin flag, if set.
+func fetchReportDays(source calendarSource, r datespec.Range) ([]report.Day, error) {
+	var days []report.Day
+	err := forEachCalendarMonth(source, r, func(cal *personio.AttendanceCalendar, month datespec.Range) error {
+		monthDays, err := report.Days(cal, month)
+		if err != nil {
+			return err
+		}
+		days = append(days, monthDays...)
+		return nil
+	})
+	if err == nil && len(reportOrigins) > 0 {
+		days = report.FilterOrigins(days, originFilter())
+	}
+	return days, err
+}
+
+// forEachCalendarMonth fetches the attendance calendar one month at a time,
+// and calls the function with each month's calendar and date range.
+func forEachCalendarMonth(source calendarSource, r datespec.Range, f func(cal *personio.AttendanceCalendar, month datespec.Range) error) error {
+	log.Debug().
+		Time("start", r.Start).
+		Time("end", r.End).
+		Msg("Date range.")
+	warned := make(map[personio.CalendarSection]bool)
+	return util.ForEachMonth(r.Start, r.End, func(start, end time.Time) error {
+		cal, err := source.GetMyAttendanceCalendar(start, end)
+		if err != nil {
+			return err
+		}
+		for _, section := range cal.MissingSections() {
+			if !warned[section] {
+				warned[section] = true
+				warnMissingSection(section)
+			}
+		}
+		return f(cal, datespec.Range{Start: start, End: end})
+	})
+}
+
+// warnMissingSection tells which features are incomplete when Personio
+// omitted an optional section of the attendance calendar.
+func warnMissingSection(section personio.CalendarSection) {
+	log.Warn().
+		Str("section", string(section)).
+		Msg("Personio omitted this section of the attendance calendar, so results depending on it are incomplete.")
+}
+
+// defaultRange returns the range that the command uses when no --start and
+// --end flags are given, as configured via the "dateRange" config.
+func defaultRange(cmd *cobra.Command) (datespec.Range, error) {
+	name := strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" ")
+	preset := datespec.PresetThisMonth
+	if p, ok := cfg.DateRange.Commands[name]; ok && p != "" {
+		preset = datespec.Preset(p)
+	} else if cfg.DateRange.Default != "" {
+		preset = datespec.Preset(cfg.DateRange.Default)
+	}
+	weekStart, err := datespec.ParseWeekStart(cfg.DateRange.WeekStart)
+	if err != nil {
+		return datespec.Range{}, fmt.Errorf("dateRange.weekStart: %w", err)
+	}
+	r, err := preset.Range(time.Now(), weekStart)
+	if err != nil {
+		return datespec.Range{}, fmt.Errorf("dateRange: %w", err)
+	}
+	return r, nil
+}
+
+// resolveRange returns the range from the --start and --end flags, where
+// unset flags fall back to the command's default range.
+func resolveRange(cmd *cobra.Command, start, end flagtype.Date) (datespec.Range, error) {
+	def, err := defaultRange(cmd)
+	if err != nil {
+		return datespec.Range{}, err
+	}
+	return datespec.Resolve(start.Time(), end.Time(), def), nil
+}
+
+func yearRange(year int) datespec.Range {
+	return datespec.Range{
+		Start: time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC),
+	}
+}
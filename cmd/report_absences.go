@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var reportAbsencesFlags = struct {
+	year          int
+	byType        bool
+	employees     []string
+	team          bool
+	includeOthers bool
+	concurrency   int
+}{
+	year:        time.Now().Year(),
+	concurrency: 4,
+}
+
+var reportAbsencesCmd = &cobra.Command{
+	Use:   "absences",
+	Short: "Count absence days in a year, such as sick days",
+	Long: `Count the absence days in a year, in total or per absence type
+with --by-type, such as your number of sick days.
+
+Only workdays in your report.workingHours are counted, where half-day
+absences count as half a day, and public holidays are not counted.
+
+Absence types such as sick leave are health data. To not accidentally
+generate health reports on colleagues, summarizing other employees via
+--employee or --team requires the --include-others flag.`,
+	Example: `  rootless-personio report absences --by-type --year 2023
+  rootless-personio report absences --by-type --team --include-others`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		others := reportAbsencesFlags.team || len(reportAbsencesFlags.employees) > 0
+		if !others {
+			source, err := newCalendarSource()
+			if err != nil {
+				return err
+			}
+			defer closeCalendarSource(source)
+			days, err := fetchReportDays(source, yearRange(reportAbsencesFlags.year))
+			if err != nil {
+				return err
+			}
+			summary := report.SummarizeAbsences(days, reportSchedule())
+			return printAbsenceSummaries([]report.AbsenceSummary{summary})
+		}
+
+		if useMirror {
+			return errors.New("the --mirror flag cannot be combined with --employee or --team, as the mirror only contains your own attendance")
+		}
+		var only []string
+		if !reportAbsencesFlags.team {
+			only = reportAbsencesFlags.employees
+		}
+		members, err := teamMembers(only)
+		if err != nil {
+			return err
+		}
+		client, err := newLoggedInClient()
+		if err != nil {
+			return err
+		}
+		if err := checkIncludeOthers(client.EmployeeID, members); err != nil {
+			return err
+		}
+		memberDays, failed := fetchTeamDays(client, members, yearRange(reportAbsencesFlags.year), reportAbsencesFlags.concurrency)
+		summaries := make([]report.AbsenceSummary, len(memberDays))
+		for i, m := range memberDays {
+			summaries[i] = report.SummarizeAbsences(m.Days, reportSchedule())
+			summaries[i].EmployeeID = m.Member.ID
+			summaries[i].Name = m.Member.Name
+		}
+		if err := printAbsenceSummaries(summaries); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed fetching the absences of %d of %d employees", failed, len(members))
+		}
+		return nil
+	},
+}
+
+func init() {
+	reportCmd.AddCommand(reportAbsencesCmd)
+
+	reportAbsencesCmd.Flags().IntVarP(&reportAbsencesFlags.year, "year", "y", reportAbsencesFlags.year, "Year to count absence days in")
+	reportAbsencesCmd.Flags().BoolVar(&reportAbsencesFlags.byType, "by-type", false, "Count the days per absence type")
+	reportAbsencesCmd.Flags().StringArrayVar(&reportAbsencesFlags.employees, "employee", nil, `Count the absences of this employee, by ID or by email from the "employees" config (repeatable, requires --include-others)`)
+	reportAbsencesCmd.Flags().BoolVar(&reportAbsencesFlags.team, "team", false, `Count the absences of all employees in the "employees" config (requires --include-others)`)
+	reportAbsencesCmd.Flags().BoolVar(&reportAbsencesFlags.includeOthers, "include-others", false, "Allow counting the absences of other employees than yourself")
+	reportAbsencesCmd.Flags().IntVar(&reportAbsencesFlags.concurrency, "concurrency", reportAbsencesFlags.concurrency, "How many employees' calendars to fetch at the same time")
+}
+
+// checkIncludeOthers returns an error if any of the members are other
+// employees than yourself, unless the --include-others flag is set.
+func checkIncludeOthers(ownEmployeeID int, members []report.TeamMember) error {
+	if reportAbsencesFlags.includeOthers {
+		return nil
+	}
+	for _, m := range members {
+		if m.ID != ownEmployeeID {
+			return fmt.Errorf("counting the absences of other employees (employee %d) requires the --include-others flag, as absence types such as sick leave are health data", m.ID)
+		}
+	}
+	return nil
+}
+
+func printAbsenceSummaries(summaries []report.AbsenceSummary) error {
+	if !reportAbsencesFlags.byType {
+		for i := range summaries {
+			summaries[i].Types = nil
+		}
+	}
+	if cfg.Output == config.OutFormatPretty {
+		console.PrintAbsenceSummaries(summaries, reportAbsencesFlags.byType)
+		return nil
+	}
+	if len(summaries) == 1 && summaries[0].EmployeeID == 0 {
+		return printOutputJSONOrYAML(summaries[0])
+	}
+	return printOutputJSONOrYAML(summaries)
+}
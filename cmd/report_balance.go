@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var reportBalanceFlags = struct {
+	startDate flagtype.Date
+	endDate   flagtype.Date
+}{}
+
+var reportBalanceCmd = &cobra.Command{
+	Use:   "balance",
+	Short: "Show target versus logged hours over time",
+	Long: `Show your target hours versus your logged hours over time,
+as a chart of the cumulative hours, i.e how your flexitime balance evolved.
+
+The target hours are taken from the report.workingHours config,
+where holidays and absences are subtracted automatically.
+
+Days in the future are not included.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := resolveRange(cmd, reportBalanceFlags.startDate, reportBalanceFlags.endDate)
+		if err != nil {
+			return err
+		}
+
+		source, err := newCalendarSource()
+		if err != nil {
+			return err
+		}
+		defer closeCalendarSource(source)
+		days, err := fetchReportDays(source, r)
+		if err != nil {
+			return err
+		}
+		balance := report.CalculateBalance(days, reportSchedule(), time.Now())
+
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintBalance(balance)
+			return nil
+		}
+		return printOutputJSONOrYAML(balance)
+	},
+}
+
+func init() {
+	reportCmd.AddCommand(reportBalanceCmd)
+
+	reportBalanceCmd.Flags().VarP(&reportBalanceFlags.startDate, "start", "s", "Start date of report (default start of dateRange config, this month)")
+	reportBalanceCmd.Flags().VarP(&reportBalanceFlags.endDate, "end", "e", "End date of report (default end of dateRange config, this month)")
+}
+
+func reportSchedule() report.Schedule {
+	h := cfg.Report.WorkingHours
+	return report.Schedule{
+		time.Sunday:    h.Sunday,
+		time.Monday:    h.Monday,
+		time.Tuesday:   h.Tuesday,
+		time.Wednesday: h.Wednesday,
+		time.Thursday:  h.Thursday,
+		time.Friday:    h.Friday,
+		time.Saturday:  h.Saturday,
+	}
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var reportHomeOfficeFlags = struct {
+	year int
+}{
+	year: time.Now().Year(),
+}
+
+var reportHomeOfficeCmd = &cobra.Command{
+	Use:     "homeoffice-days",
+	Aliases: []string{"homeoffice"},
+	Short:   "Count office versus home office days",
+	Long: `Count the days you worked at the office versus from home,
+as needed for e.g German tax declarations (Homeoffice-Pauschale and
+Pendlerpauschale).
+
+Personio doesn't track where you work, so the location is instead
+taken from keywords in your attendance period comments, as configured
+via report.homeOffice.remoteKeywords and report.homeOffice.officeKeywords.
+When a day has periods from both locations, the location with the most
+work that day is used.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, err := newCalendarSource()
+		if err != nil {
+			return err
+		}
+		defer closeCalendarSource(source)
+		days, err := fetchReportDays(source, yearRange(reportHomeOfficeFlags.year))
+		if err != nil {
+			return err
+		}
+		summary := report.SummarizeLocations(days, report.LocationRules{
+			RemoteKeywords: cfg.Report.HomeOffice.RemoteKeywords,
+			OfficeKeywords: cfg.Report.HomeOffice.OfficeKeywords,
+			Default:        report.Location(cfg.Report.HomeOffice.Default),
+		})
+
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintLocationSummary(summary)
+			return nil
+		}
+		return printOutputJSONOrYAML(summary)
+	},
+}
+
+func init() {
+	reportCmd.AddCommand(reportHomeOfficeCmd)
+
+	reportHomeOfficeCmd.Flags().IntVarP(&reportHomeOfficeFlags.year, "year", "y", reportHomeOfficeFlags.year, "Year to count days in")
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var reportSurchargeFlags = struct {
+	year int
+}{
+	year: time.Now().Year(),
+}
+
+var reportSurchargeCmd = &cobra.Command{
+	Use:     "surcharge",
+	Aliases: []string{"surcharges", "oncall"},
+	Short:   "Total surcharge-eligible work per category and month",
+	Long: `Total the work eligible for on-call, night, weekend, and holiday
+surcharges per month, for cross-checking against your payroll.
+
+The categories overlap, so work on a Sunday night counts towards both
+the night and the weekend total.
+
+- On-call: whole periods with any of the report.surcharge.onCallKeywords
+  in their comment.
+- Night: work between report.surcharge.nightStart and nightEnd.
+- Weekend: work on Saturdays and Sundays.
+- Holiday: work on public holidays from your holiday calendar.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules, err := reportSurchargeRules()
+		if err != nil {
+			return err
+		}
+		source, err := newCalendarSource()
+		if err != nil {
+			return err
+		}
+		defer closeCalendarSource(source)
+		days, err := fetchReportDays(source, yearRange(reportSurchargeFlags.year))
+		if err != nil {
+			return err
+		}
+		summary := report.SummarizeSurcharges(days, rules)
+
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintSurchargeSummary(summary)
+			return nil
+		}
+		return printOutputJSONOrYAML(summary)
+	},
+}
+
+func reportSurchargeRules() (report.SurchargeRules, error) {
+	nightStart, err := report.ParseTimeOfDay(cfg.Report.Surcharge.NightStart)
+	if err != nil {
+		return report.SurchargeRules{}, fmt.Errorf("config report.surcharge.nightStart: %w", err)
+	}
+	nightEnd, err := report.ParseTimeOfDay(cfg.Report.Surcharge.NightEnd)
+	if err != nil {
+		return report.SurchargeRules{}, fmt.Errorf("config report.surcharge.nightEnd: %w", err)
+	}
+	return report.SurchargeRules{
+		NightStart:     nightStart,
+		NightEnd:       nightEnd,
+		OnCallKeywords: cfg.Report.Surcharge.OnCallKeywords,
+	}, nil
+}
+
+func init() {
+	reportCmd.AddCommand(reportSurchargeCmd)
+
+	reportSurchargeCmd.Flags().IntVarP(&reportSurchargeFlags.year, "year", "y", reportSurchargeFlags.year, "Year to total surcharges in")
+}
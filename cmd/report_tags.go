@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var reportTagsFlags = struct {
+	startDate flagtype.Date
+	endDate   flagtype.Date
+}{}
+
+var reportTagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Sum up work per local-only tag",
+	Long: `Sum up your work per local-only tag, as added via "tag add".
+
+Periods with multiple tags count towards each of them. As tags are only
+stored in the local mirror, this always reads from the mirror.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := resolveRange(cmd, reportTagsFlags.startDate, reportTagsFlags.endDate)
+		if err != nil {
+			return err
+		}
+		days, err := fetchTaggedDays(r)
+		if err != nil {
+			return err
+		}
+		summary := report.SummarizeTags(days)
+
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintTagSummary(summary)
+			return nil
+		}
+		return printOutputJSONOrYAML(summary)
+	},
+}
+
+func init() {
+	reportCmd.AddCommand(reportTagsCmd)
+
+	reportTagsCmd.Flags().VarP(&reportTagsFlags.startDate, "start", "s", "Start date of report (default start of dateRange config, this month)")
+	reportTagsCmd.Flags().VarP(&reportTagsFlags.endDate, "end", "e", "End date of report (default end of dateRange config, this month)")
+}
@@ -23,35 +23,67 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/applejag/rootless-personio/pkg/anonymize"
+	"github.com/applejag/rootless-personio/pkg/auth"
+	"github.com/applejag/rootless-personio/pkg/chaos"
+	"github.com/applejag/rootless-personio/pkg/clock"
 	"github.com/applejag/rootless-personio/pkg/config"
 	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/daemon"
+	"github.com/applejag/rootless-personio/pkg/httpcache"
+	"github.com/applejag/rootless-personio/pkg/mirror"
 	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/applejag/rootless-personio/pkg/session"
+	"github.com/applejag/rootless-personio/pkg/storage"
+	"github.com/applejag/rootless-personio/pkg/telemetry"
 	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/fatih/color"
+	"github.com/mattn/go-colorable"
 	"github.com/mitchellh/mapstructure"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/typ.v4/slices"
 	"gopkg.in/yaml.v3"
 )
 
 var cfg config.Config
 var cfgFileFlag string
 
+// configIssues are the problems found when validating the config files,
+// reported before running any command.
+var configIssues []config.Issue
+
+// configFilesLoaded are the config files that were found and loaded.
+var configFilesLoaded []string
+
 var rootFlags = struct {
 	config   string
+	profile  string
+	chaos    string
 	showHelp bool
 	verbose  int
 	quiet    bool
 	noLogin  bool
+	noCache  bool
+	noDaemon bool
 }{}
 
 var rootCmd = &cobra.Command{
@@ -61,6 +93,9 @@ var rootCmd = &cobra.Command{
 instead of obtaining admin/root API credentials.`,
 	SilenceErrors: true,
 	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkConfigIssues()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -75,15 +110,35 @@ func Execute(defaultConfig config.Config) {
 	rootCmd.PersistentFlags().VarP(&cfg.Output, "output", "o", "Sets the output format")
 	rootCmd.PersistentFlags().Var(&cfg.Log.Level, "log.level", "Sets the logging level")
 	rootCmd.PersistentFlags().Var(&cfg.Log.Format, "log.format", "Sets the logging format")
+	rootCmd.PersistentFlags().Bool("non-interactive", false, "Fail instead of prompting for input")
 	viper.BindPFlags(rootCmd.PersistentFlags())
+	viper.BindPFlag("nonInteractive", rootCmd.PersistentFlags().Lookup("non-interactive"))
 
+	runPluginCommand(os.Args[1:])
 	err := rootCmd.Execute()
+	sendTelemetry()
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		log.Debug().Int("code", exitErr.code).Msgf("Exiting: %s", exitErr.msg)
+		os.Exit(exitErr.code)
+	}
 	if err != nil {
 		log.Error().Msgf("Failed: %s", err)
 		os.Exit(1)
 	}
 }
 
+// exitCodeError makes the program exit with the code, without logging it
+// as a failure, such as to tell "changes pending" apart from errors.
+type exitCodeError struct {
+	code int
+	msg  string
+}
+
+func (e *exitCodeError) Error() string {
+	return e.msg
+}
+
 func init() {
 	rootCmd.SetUsageTemplate(console.UsageTemplate())
 	cobra.OnInitialize(initConfig)
@@ -93,6 +148,11 @@ func init() {
 	rootCmd.PersistentFlags().CountVarP(&rootFlags.verbose, "verbose", "v", `Shows verbose logging (-v=info, -vv=debug, -vvv=trace)`)
 	rootCmd.PersistentFlags().BoolVarP(&rootFlags.quiet, "quiet", "q", false, `Disables logging (same as "--log.level disabled")`)
 	rootCmd.PersistentFlags().BoolVar(&rootFlags.noLogin, "no-login", false, `Skip logging in before the request`)
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.noCache, "no-cache", false, `Skip the HTTP response cache`)
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.noDaemon, "no-daemon", false, `Skip sending requests through the running daemon`)
+	rootCmd.PersistentFlags().StringVar(&rootFlags.profile, "profile", "", `Config profile, such as "sandbox", which also reads personio.<profile>.yaml files and keeps separate local state (env: PERSONIO_PROFILE)`)
+	rootCmd.PersistentFlags().StringVar(&rootFlags.chaos, "chaos", "", `Inject failures into requests, for development, e.g "latency=200ms,jitter=100ms,429=0.1,expire=0.05,seed=1" (env: PERSONIO_CHAOS)`)
+	rootCmd.PersistentFlags().MarkHidden("chaos")
 }
 
 func initConfig() {
@@ -103,12 +163,13 @@ func initConfig() {
 	viper.SetConfigName("personio")
 	viper.SetConfigType("yaml")
 
-	if err := registerConfigsInViper(cfg); err != nil {
+	defaults := cfg
+	if err := registerConfigsInViper(defaults); err != nil {
 		log.Error().Msgf("Failed set config defaults: %s", err)
 		os.Exit(1)
 	}
 
-	files := []string{"/etc/rootless-personio/personio.yaml"}
+	files := []string{systemConfigFile()}
 
 	if homePath, err := os.UserHomeDir(); err == nil {
 		files = append(files, filepath.Join(homePath, ".personio.yaml"))
@@ -123,24 +184,232 @@ func initConfig() {
 		files = append(files, cfgFileFlag)
 	}
 
+	profile := activeProfile()
+	if profile != "" {
+		if !profileNameRegex.MatchString(profile) {
+			log.Error().Msgf("Invalid profile name %q, must only contain letters, digits, dashes, and underscores.", profile)
+			os.Exit(1)
+		}
+		files = append(files, profileConfigFiles(files, profile)...)
+	}
+
+	configIssues = validateConfigFiles(files)
+
 	filesLoaded, err := mergeInConfigFiles(files)
+	configFilesLoaded = filesLoaded
 	if err != nil {
+		logConfigIssues(configIssues)
 		log.Error().Msgf("Failed decoding config file:\n%s", err)
 		os.Exit(1)
 	}
 
+	if cfg.Team.Source != "" {
+		if err := mergeInTeamConfig(defaults, files); err != nil {
+			log.Error().Msgf("Failed loading team config:\n%s", err)
+			os.Exit(1)
+		}
+	}
+
+	if profile != "" {
+		if err := applyProfilePaths(profile); err != nil {
+			log.Error().Msgf("Failed setting paths of profile %q: %s", profile, err)
+			os.Exit(1)
+		}
+	}
+
 	// Set up logger last time, now that we've read in the new config
 	initLogger()
 
+	if err := initLocale(); err != nil {
+		log.Error().Msgf("Failed parsing locale config: %s", err)
+		os.Exit(1)
+	}
+
+	if cfg.Team.Source != "" {
+		log.Debug().
+			Str("source", cfg.Team.Source).
+			Msg("Loaded team configuration.")
+	}
 	for _, file := range filesLoaded {
 		log.Debug().
 			Str("file", util.PrettyPath(file)).
 			Msg("Loaded configuration.")
 	}
+	if cfg.Sandbox.Enabled {
+		printSandboxBanner(profile)
+	}
+}
+
+var profileNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// activeProfile returns the profile from the --profile flag or the
+// PERSONIO_PROFILE env var, or an empty string for none.
+func activeProfile() string {
+	if rootFlags.profile != "" {
+		return rootFlags.profile
+	}
+	return os.Getenv("PERSONIO_PROFILE")
+}
+
+// profileConfigFiles returns the profile's variant of each config file,
+// e.g "~/.personio.sandbox.yaml" for "~/.personio.yaml", which are read
+// after all other config files.
+func profileConfigFiles(files []string, profile string) []string {
+	profileFiles := make([]string, len(files))
+	for i, file := range files {
+		profileFiles[i] = profileFileName(file, profile)
+	}
+	return profileFiles
+}
+
+// profileFileName inserts the profile before the file extension, e.g
+// "queue.sandbox.json" for "queue.json".
+func profileFileName(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// applyProfilePaths keeps the local state of the profile apart from the
+// default profile, such as remembered cookies and queued changes, so a
+// sandbox tenant never mixes with the production one. Only paths that are
+// not set in the config are changed.
+func applyProfilePaths(profile string) error {
+	defaults := []struct {
+		path        *string
+		defaultPath func() (string, error)
+	}{
+		{&cfg.Queue.Path, queue.DefaultPath},
+		{&cfg.Clock.Path, clock.DefaultPath},
+		{&cfg.Mirror.Path, mirror.DefaultPath},
+		{&cfg.Daemon.Socket, daemon.DefaultSocketPath},
+		{&cfg.Cache.Dir, httpcache.DefaultDir},
+	}
+	for _, d := range defaults {
+		if *d.path != "" {
+			continue
+		}
+		path, err := d.defaultPath()
+		if err != nil {
+			return err
+		}
+		*d.path = profileFileName(path, profile)
+	}
+	if cfg.Storage.Path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg.Storage.Path = filepath.Join(dir, "rootless-personio", "profiles", profile)
+		if storage.Kind(cfg.Storage.Backend) == storage.KindSQLite {
+			cfg.Storage.Path = filepath.Join(cfg.Storage.Path, "storage.db")
+		}
+	}
+	return nil
+}
+
+// printSandboxBanner warns loudly on STDERR that a test tenant is used, so
+// it's never mistaken for the production HR record.
+func printSandboxBanner(profile string) {
+	name := cfg.Sandbox.Name
+	if name == "" {
+		name = profile
+	}
+	if name == "" {
+		name = "sandbox"
+	}
+	msg := fmt.Sprintf(" SANDBOX %q ", name)
+	if cfg.BaseURL != "" || cfg.Tenant.Host != "" || cfg.Tenant.Slug != "" {
+		if baseURL, err := resolveBaseURL(); err == nil {
+			msg += "· " + baseURL + " "
+		}
+	}
+	msg += "· not the production HR record "
+	color.New(color.BgYellow, color.FgBlack, color.Bold).Fprintln(os.Stderr, msg)
+}
+
+// mergeInTeamConfig reloads the config, but with the team config merged in
+// between the default values and the user's own config files.
+func mergeInTeamConfig(defaults config.Config, files []string) error {
+	data, err := config.LoadTeamConfig(cfg.Team)
+	if err != nil {
+		return err
+	}
+	if err := registerConfigsInViper(defaults); err != nil {
+		return err
+	}
+	if err := viper.MergeConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("merge team config: %w", err)
+	}
+	_, err = mergeInConfigFiles(files)
+	return err
+}
+
+// validateConfigFiles validates the config files that exist against the
+// config's JSON schema.
+func validateConfigFiles(files []string) []config.Issue {
+	var issues []config.Issue
+	validated := make(map[string]bool, len(files))
+	for _, file := range files {
+		if abs, err := filepath.Abs(file); err == nil {
+			if validated[abs] {
+				continue
+			}
+			validated[abs] = true
+		}
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("file", util.PrettyPath(file)).Msg("Failed reading config file for validation.")
+			continue
+		}
+		fileIssues, err := config.Validate(util.PrettyPath(file), data)
+		if err != nil {
+			log.Warn().Err(err).Str("file", util.PrettyPath(file)).Msg("Failed validating config file.")
+			continue
+		}
+		issues = append(issues, fileIssues...)
+	}
+	return issues
+}
+
+func logConfigIssues(issues []config.Issue) {
+	for _, issue := range issues {
+		if issue.Severity == config.IssueError {
+			log.Error().Msgf("Invalid config: %s", issue)
+		} else {
+			log.Warn().Msgf("Config warning: %s", issue)
+		}
+	}
+}
+
+// checkConfigIssues logs the config issues, and fails if any of them
+// are errors, so typos in the config aren't silently ignored.
+func checkConfigIssues() error {
+	logConfigIssues(configIssues)
+	if config.HasErrors(configIssues) {
+		return errors.New(`invalid config, see the errors above or run "config validate"`)
+	}
+	return nil
+}
+
+func initLocale() error {
+	locale, err := console.NewLocale(
+		cfg.Locale.Language,
+		cfg.Locale.DateFormat,
+		cfg.Locale.TimeFormat,
+		cfg.Locale.DurationFormat,
+	)
+	if err != nil {
+		return err
+	}
+	console.SetLocale(locale)
+	return nil
 }
 
 func registerConfigsInViper(defaults config.Config) error {
-	b, err := yaml.Marshal(cfg)
+	b, err := yaml.Marshal(defaults)
 	if err != nil {
 		return err
 	}
@@ -152,18 +421,28 @@ func mergeInConfigFiles(files []string) ([]string, error) {
 	var filesLoaded []string
 
 	for _, file := range files {
-		viper.SetConfigFile(file)
-		if err := viper.MergeInConfig(); err != nil {
+		data, err := os.ReadFile(file)
+		if err != nil {
 			if os.IsNotExist(err) {
 				continue
 			}
 			return nil, err
 		}
+		// Old config layouts are upgraded in-memory, so they keep working
+		// until the user runs "config migrate".
+		data, _, err = config.Migrate(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", util.PrettyPath(file), err)
+		}
+		if err := viper.MergeConfig(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("%s: %w", util.PrettyPath(file), err)
+		}
 		filesLoaded = append(filesLoaded, file)
 	}
 
 	if err := viper.Unmarshal(&cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
 		mapstructure.TextUnmarshallerHookFunc(),
+		config.StringToStructuredHookFunc(),
 		mapstructure.StringToTimeDurationHookFunc(), // default hook
 		mapstructure.StringToSliceHookFunc(","),     // default hook
 	))); err != nil {
@@ -176,15 +455,31 @@ func mergeInConfigFiles(files []string) ([]string, error) {
 func initLogger() {
 	overrideLoggerSettings()
 
+	log.Logger = zerolog.New(newLogWriter()).With().Timestamp().Logger()
+	log.Logger = log.Level(zerolog.Level(cfg.Log.Level))
+}
+
+// systemConfigFile returns the path of the config file shared by all
+// users, which is in %ProgramData% on Windows.
+func systemConfigFile() string {
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("ProgramData"); dir != "" {
+			return filepath.Join(dir, "rootless-personio", "personio.yaml")
+		}
+	}
+	return "/etc/rootless-personio/personio.yaml"
+}
+
+// newLogWriter returns the writer of log lines in the configured format.
+func newLogWriter() io.Writer {
 	if cfg.Log.Format == config.LogFormatJSON {
-		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
-	} else {
-		log.Logger = log.Output(zerolog.ConsoleWriter{
-			Out:        os.Stderr,
-			TimeFormat: "Jan-02 15:04",
-		})
+		return anonymize.NewWriter(os.Stderr)
 	}
-	log.Logger = log.Level(zerolog.Level(cfg.Log.Level))
+	return anonymize.NewWriter(zerolog.ConsoleWriter{
+		// Translates the colors on older Windows consoles without ANSI support
+		Out:        colorable.NewColorableStderr(),
+		TimeFormat: "Jan-02 15:04",
+	})
 }
 
 func overrideLoggerSettings() {
@@ -204,38 +499,78 @@ func overrideLoggerSettings() {
 	}
 }
 
+func resolveBaseURL() (string, error) {
+	if cfg.BaseURL != "" {
+		return cfg.BaseURL, nil
+	}
+	host := cfg.Tenant.Host
+	if host == "" {
+		if cfg.Tenant.Slug == "" {
+			log.Error().Msg("Missing base URL! Must set baseUrl or tenant.slug config, or PERSONIO_BASEURL env var.")
+			return "", errors.New("missing base URL")
+		}
+		if err := personio.ValidateTenantSlug(cfg.Tenant.Slug); err != nil {
+			return "", fmt.Errorf("tenant.slug: %w", err)
+		}
+		if !slices.Contains(personio.TenantDomains, cfg.Tenant.Domain) {
+			return "", fmt.Errorf("tenant.domain: unknown domain %q, must be one of: %s",
+				cfg.Tenant.Domain, strings.Join(personio.TenantDomains, ", "))
+		}
+		host = cfg.Tenant.Slug + "." + cfg.Tenant.Domain
+	}
+	baseURL, err := personio.TenantBaseURL(host, cfg.Tenant.PathPrefix)
+	if err != nil {
+		return "", fmt.Errorf("tenant: %w", err)
+	}
+	return baseURL, nil
+}
+
 func newLoggedInClient() (*personio.Client, error) {
-	if cfg.BaseURL == "" {
-		log.Error().Msg("Missing base URL! Must set baseUrl config or PERSONIO_BASEURL env var.")
-		return nil, errors.New("missing base URL")
+	baseURL, err := resolveBaseURL()
+	if err != nil {
+		return nil, err
 	}
 
-	client, err := personio.New(cfg.BaseURL)
+	client, err := newClient(baseURL)
 	if err != nil {
 		return nil, err
 	}
-	log.Debug().Str("baseUrl", client.BaseURL).Msg("Created valid client.")
+	if cfg.Storage.DayIDs {
+		if err := useDayIDStore(client); err != nil {
+			log.Warn().Err(err).Msg("Failed opening storage of day IDs, continuing without it.")
+		}
+	}
+
+	if !rootFlags.noDaemon && !rootFlags.noLogin {
+		if daemonClient := newDaemonClient(client.BaseURL); daemonClient != nil {
+			return daemonClient, nil
+		}
+	}
+
+	if cfg.Cache.Enabled && !rootFlags.noCache {
+		transport, err := newCacheTransport()
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed setting up HTTP cache, continuing without it.")
+		} else {
+			client.SetTransport(transport)
+		}
+	}
 
 	if rootFlags.noLogin {
 		return client, nil
 	}
 
-	var missingCredentials bool
-	if cfg.Auth.Email == "" {
-		missingCredentials = true
-		log.Error().Msg("Missing email! Must set auth.email config or PERSONIO_AUTH_EMAIL env var.")
-	}
-	if cfg.Auth.Password == "" {
-		missingCredentials = true
-		log.Error().Msg("Missing password! Must set auth.password config or PERSONIO_AUTH_PASSWORD env var.")
+	if cfg.Auth.RememberDevice && cfg.Auth.Email != "" {
+		if err := useSessionJar(client, cfg.Auth.Email); err != nil {
+			log.Warn().Err(err).Msg("Failed loading remembered cookies, continuing without them.")
+		}
 	}
-	if missingCredentials {
-		return nil, errors.New("missing credentials")
+
+	if err := preflight(client.BaseURL); err != nil {
+		return nil, err
 	}
-	if err := client.Login(cfg.Auth.Email, cfg.Auth.Password); err != nil {
-		if err := handleLoginError(client, err, cfg.Auth); err != nil {
-			return nil, err
-		}
+	if err := login(client); err != nil {
+		runLoginFailureHook(client.BaseURL, err)
 		return nil, err
 	}
 	log.Info().Int("employeeId", client.EmployeeID).
@@ -243,13 +578,224 @@ func newLoggedInClient() (*personio.Client, error) {
 	return client, nil
 }
 
+// newClient returns a client that is not yet logged in, configured via
+// the http and endpoints configs.
+func newClient(baseURL string) (*personio.Client, error) {
+	opts := clientOptions()
+	if chaosTransport, err := newChaosTransport(personio.NewTransport()); err != nil {
+		return nil, err
+	} else if chaosTransport != nil {
+		opts = append(opts, personio.WithTransport(chaosTransport))
+	}
+	client, err := personio.New(baseURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Str("baseUrl", client.BaseURL).Msg("Created valid client.")
+	return client, nil
+}
+
+// clientOptions returns the client options from the config, shared by all
+// clients of the CLI, whether they talk to Personio directly or via the
+// daemon.
+func clientOptions() []personio.Option {
+	opts := []personio.Option{
+		personio.WithMaxResponseSize(int64(cfg.HTTP.MaxResponseSizeMiB) << 20),
+		personio.WithEndpoints(personio.Endpoints(cfg.Endpoints)),
+		personio.WithTimeouts(personio.Timeouts(cfg.HTTP.Timeouts)),
+	}
+	if collector := telemetryCollector(); collector != nil {
+		opts = append(opts, personio.WithFailureObserver(collector.Observe))
+	}
+	return opts
+}
+
+// newChaosTransport wraps the transport to inject failures as configured
+// via the --chaos flag, or returns nil when not set.
+func newChaosTransport(base http.RoundTripper) (*chaos.Transport, error) {
+	spec := rootFlags.chaos
+	if spec == "" {
+		spec = os.Getenv("PERSONIO_CHAOS")
+	}
+	if spec == "" {
+		return nil, nil
+	}
+	chaosCfg, err := chaos.ParseConfig(spec)
+	if err != nil {
+		return nil, fmt.Errorf("parse --chaos flag: %w", err)
+	}
+	log.Warn().Str("chaos", spec).Msg("Injecting failures into requests to Personio, for development.")
+	endpoints := personio.Endpoints(cfg.Endpoints).WithDefaults()
+	return chaos.NewTransport(base, chaosCfg, endpoints.Login, endpoints.TokenAuth), nil
+}
+
+var (
+	telemetryCollectorOnce  sync.Once
+	telemetryCollectorValue *telemetry.Collector
+)
+
+// telemetryCollector returns the collector of failed requests, or nil
+// unless telemetry is enabled.
+func telemetryCollector() *telemetry.Collector {
+	telemetryCollectorOnce.Do(func() {
+		if !cfg.Telemetry.Enabled {
+			return
+		}
+		if cfg.Telemetry.Endpoint == "" {
+			log.Warn().Msg("Telemetry is enabled, but telemetry.endpoint is not set. Not sending any telemetry.")
+			return
+		}
+		command := rootCmd.Name()
+		if cmd, _, err := rootCmd.Find(os.Args[1:]); err == nil {
+			command = cmd.CommandPath()
+		}
+		telemetryCollectorValue = &telemetry.Collector{
+			Endpoint: cfg.Telemetry.Endpoint,
+			Command:  strings.TrimPrefix(command, rootCmd.Name()+" "),
+		}
+	})
+	return telemetryCollectorValue
+}
+
+// sendTelemetry sends the failed requests, if telemetry is enabled. It
+// never fails the command, as telemetry is only a courtesy.
+func sendTelemetry() {
+	if telemetryCollectorValue == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Telemetry.Timeout)
+	defer cancel()
+	if err := telemetryCollectorValue.Send(ctx); err != nil {
+		log.Debug().Err(err).Msg("Failed to send telemetry.")
+	}
+}
+
+// preflight fails fast when the base URL is unreachable, instead of letting
+// the login hang until its timeout.
+func preflight(baseURL string) error {
+	if cfg.HTTP.PreflightTimeout <= 0 {
+		return nil
+	}
+	p := personio.Preflight{Timeout: cfg.HTTP.PreflightTimeout}
+	if err := p.Check(context.Background(), baseURL); err != nil {
+		return err
+	}
+	log.Debug().Str("baseUrl", baseURL).Msg("Preflight check passed.")
+	return nil
+}
+
+// useSessionJar makes the client remember Personio's long-lived cookies
+// between runs.
+func useSessionJar(client *personio.Client, email string) error {
+	u, err := url.Parse(client.BaseURL)
+	if err != nil {
+		return fmt.Errorf("parse base URL: %w", err)
+	}
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	key := session.Key(u.Hostname(), email)
+	jar, err := session.LoadFrom(store, key)
+	if err != nil {
+		return err
+	}
+	client.SetCookieJar(jar)
+	log.Debug().Str("key", key).Msg("Using remembered cookies.")
+	return nil
+}
+
+// useDayIDStore makes the client remember day IDs between runs.
+func useDayIDStore(client *personio.Client) error {
+	u, err := url.Parse(client.BaseURL)
+	if err != nil {
+		return fmt.Errorf("parse base URL: %w", err)
+	}
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	client.SetDayIDStore(personio.NewStoredDayIDs(store, "dayids/"+util.SafeFileName(u.Hostname())))
+	return nil
+}
+
+var (
+	storageBackend    storage.Backend
+	storageBackendErr error
+	storageOnce       sync.Once
+)
+
+// openStorage returns the storage of the local state, as configured via
+// the storage config. It is opened once and shared for the rest of the run.
+func openStorage() (storage.Backend, error) {
+	storageOnce.Do(func() {
+		kind := storage.Kind(cfg.Storage.Backend)
+		path := cfg.Storage.Path
+		if path == "" {
+			dir, err := os.UserConfigDir()
+			if err != nil {
+				storageBackendErr = err
+				return
+			}
+			path = filepath.Join(dir, "rootless-personio")
+			if kind == storage.KindSQLite {
+				path = filepath.Join(path, "storage.db")
+			}
+		}
+		log.Debug().Str("backend", string(kind)).Str("path", util.PrettyPath(path)).Msg("Opening storage.")
+		storageBackend, storageBackendErr = storage.Open(kind, path)
+	})
+	return storageBackend, storageBackendErr
+}
+
+func newCacheTransport() (*httpcache.Transport, error) {
+	dir := cfg.Cache.Dir
+	if dir == "" {
+		defaultDir, err := httpcache.DefaultDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = defaultDir
+	}
+	log.Debug().Str("dir", dir).Msg("Using HTTP cache.")
+	var base http.RoundTripper = personio.NewTransport()
+	if chaosTransport, err := newChaosTransport(base); err != nil {
+		return nil, err
+	} else if chaosTransport != nil {
+		base = chaosTransport
+	}
+	return &httpcache.Transport{
+		Base:    base,
+		Dir:     dir,
+		MaxSize: int64(cfg.Cache.MaxSizeMiB) << 20,
+	}, nil
+}
+
+func loginWithPassword(client *personio.Client, creds config.Auth) error {
+	return guardLogin(creds.Email, func() error {
+		provider := auth.PasswordProvider{Email: creds.Email, Password: creds.Password}
+		if err := provider.Login(client); err != nil {
+			return handleLoginError(client, err, creds)
+		}
+		return nil
+	})
+}
+
 func handleLoginError(client *personio.Client, err error, auth config.Auth) error {
 	if !errors.Is(err, personio.ErrUnlockRequired) {
 		return err
 	}
+	if !isInteractive() {
+		log.Error().Msg("Login confirmation required, as Personio doesn't recognize this device.\n" +
+			"\tPlease open your inbox and find the email named \"[Personio] Confirm login in your account\"\n" +
+			"\tThen copy the login token or link from the email, and run:\n" +
+			"\t  rootless-personio auth confirm-device <code-or-link>\n" +
+			"\tOr copy the cookies of an already confirmed device via \"auth bootstrap\".")
+		return fmt.Errorf("%w: %w", err, errNoPrompt("the login token from the email"))
+	}
 	log.Warn().Msg("Login confirmation required.\n" +
 		"\tPlease open your inbox and find the email named \"[Personio] Confirm login in your account\"\n" +
-		"\tCopy the login token from the email and enter it here:")
+		"\tCopy the login token or link from the email and enter it here:")
 
 	var resp struct {
 		Token string
@@ -265,7 +811,7 @@ func handleLoginError(client *personio.Client, err error, auth config.Auth) erro
 		log.Warn().Err(err).Msg("Failed to ask for token. Please try again, and make sure to run rootless-personio from a tty (don't pipe the output).")
 		return err
 	}
-	if err := client.UnlockAndLogin(auth.Email, auth.Password, resp.Token); err != nil {
+	if err := client.UnlockAndLogin(auth.Email, auth.Password, personio.ParseUnlockToken(resp.Token)); err != nil {
 		return err
 	}
 	log.Info().Msg("Successfully unlocked and logged into account.")
@@ -275,6 +821,8 @@ func handleLoginError(client *personio.Client, err error, auth config.Auth) erro
 
 func printOutputJSONOrYAML(model any) error {
 	switch cfg.Output {
+	case config.OutFormatJSONL:
+		return printJSONL(model)
 	case config.OutFormatYAML:
 		// Encode to JSON first, so we reuse the `json:"field_name"` tags
 		jsonBytes, err := json.Marshal(model)
@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/health"
+	"github.com/applejag/rootless-personio/pkg/keyring"
+	"github.com/applejag/rootless-personio/pkg/oidc"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/serve"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a REST API shared by multiple employees",
+	Long: `Serve a REST API, until interrupted, that multiple employees can use
+to read and log their attendance, each with their own API key and
+Personio credentials from the serve.users config.
+
+Each user's session is logged in on their first request, and logged in
+again when it expires. Requests authenticate with the header:
+
+  Authorization: Bearer <apiKey>
+
+The "/healthz" and "/readyz" probes are served without authentication.
+When interrupted or sent SIGTERM, "/readyz" starts failing and ongoing
+requests may finish within serve.shutdownTimeout.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		baseURL, err := resolveBaseURL()
+		if err != nil {
+			return err
+		}
+		users := make([]serve.User, 0, len(cfg.Serve.Users))
+		for _, u := range cfg.Serve.Users {
+			creds := u.Auth
+			users = append(users, serve.User{
+				Name:     u.Name,
+				APIKey:   u.APIKey,
+				Identity: u.Auth.Email,
+				Login: func() (*personio.Client, error) {
+					return newServeClient(baseURL, creds)
+				},
+			})
+		}
+		var oidcAuth *serve.OIDC
+		if cfg.Serve.OIDC.Issuer != "" {
+			verifier, err := newOIDCVerifier(cfg.Serve.OIDC)
+			if err != nil {
+				return fmt.Errorf("serve.oidc: %w", err)
+			}
+			oidcAuth = &serve.OIDC{
+				Verifier:      verifier,
+				ManagerGroups: cfg.Serve.ManagerGroups,
+			}
+			log.Info().Str("issuer", cfg.Serve.OIDC.Issuer).Msg("Authenticating with OIDC.")
+		}
+		handler, err := serve.New(users, oidcAuth)
+		if err != nil {
+			return fmt.Errorf("serve.users: %w", err)
+		}
+
+		ln, err := net.Listen("tcp", cfg.Serve.Listen)
+		if err != nil {
+			return fmt.Errorf("listen for REST API: %w", err)
+		}
+		probes := &health.Handler{}
+		mux := http.NewServeMux()
+		probes.Register(mux)
+		mux.Handle("/", handler)
+		srv := &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		shutdownDone := make(chan struct{})
+		go func() {
+			defer close(shutdownDone)
+			<-ctx.Done()
+			log.Info().Msg("Stopping REST API.")
+			probes.ShuttingDown()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Serve.ShutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				log.Warn().Err(err).Msg("Failed waiting for ongoing requests to finish.")
+			}
+		}()
+		log.Info().
+			Str("address", "http://"+ln.Addr().String()+"/api/v1/").
+			Int("users", len(users)).
+			Msg("Serving REST API.")
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		// Serve returns as soon as shutdown starts, so wait for ongoing
+		// requests before exiting
+		<-shutdownDone
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&cfg.Serve.Listen, "listen", cfg.Serve.Listen, "TCP address to serve the REST API on")
+}
+
+// newOIDCVerifier returns a verifier of OpenID Connect bearer tokens.
+func newOIDCVerifier(conf config.OIDC) (*oidc.Verifier, error) {
+	if conf.Audience == "" {
+		return nil, errors.New("audience is required, usually the client ID, so tokens issued to other clients of the issuer are rejected")
+	}
+	return &oidc.Verifier{
+		Issuer:        conf.Issuer,
+		Audience:      conf.Audience,
+		UsernameClaim: conf.UsernameClaim,
+		GroupsClaim:   conf.GroupsClaim,
+		AllowedGroups: conf.AllowedGroups,
+	}, nil
+}
+
+// newServeClient returns a new client logged in as one of the serve.users.
+// Unlike [login], it never prompts, and it doesn't use the HTTP cache, as
+// the cache is shared between all users.
+func newServeClient(baseURL string, creds config.Auth) (*personio.Client, error) {
+	if creds.Provider == "sso" {
+		return nil, errors.New("the sso auth provider is not supported by serve")
+	}
+	if err := checkLockout(creds.Email); err != nil {
+		return nil, err
+	}
+	client, err := newClient(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if creds.RememberDevice && creds.Email != "" {
+		if err := useSessionJar(client, creds.Email); err != nil {
+			log.Warn().Err(err).Str("email", creds.Email).Msg("Failed loading remembered cookies, continuing without them.")
+		}
+	}
+	if (creds.Provider == "" || creds.Provider == "password") && creds.Password == "" && creds.Keyring {
+		password, err := keyring.Get(creds.Email)
+		if err != nil {
+			return nil, fmt.Errorf("read password from the OS keyring: %w", err)
+		}
+		creds.Password = password
+	}
+	provider, err := newAuthProvider(creds)
+	if err != nil {
+		return nil, err
+	}
+	if err := guardLogin(creds.Email, func() error {
+		return provider.Login(client)
+	}); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/clock"
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/applejag/rootless-personio/pkg/slack"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var slackFlags = struct {
+	dryRun bool
+}{}
+
+var slackCmd = &cobra.Command{
+	Use:   "slack",
+	Short: "Group of commands for the Slack integration",
+}
+
+var slackSyncStatusCmd = &cobra.Command{
+	Use:   "sync-status",
+	Short: "Set your Slack status from today's absences and work location",
+	Long: `Set your Slack status from today's absences and work location.
+
+When you are absent today, your status is set to the slack.vacation or
+slack.sick status, depending on the slack.sickKeywords, and expires after
+the absence's last day. When working remotely, as told by the
+report.homeOffice.remoteKeywords in today's attendance comments or in the
+comment of the running punch clock, your status is set to slack.remote and
+expires at the end of the day.
+
+Otherwise, a status previously set by this command is cleared. Statuses
+that you set yourself are only replaced, never cleared.
+
+The daemon runs this each morning when slack.syncAt is set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, err := newCalendarSource()
+		if err != nil {
+			return err
+		}
+		defer closeCalendarSource(source)
+		result, err := syncSlackStatus(source, time.Now(), slackFlags.dryRun)
+		if err != nil {
+			return err
+		}
+		return printOutputJSONOrYAML(result)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(slackCmd)
+	slackCmd.AddCommand(slackSyncStatusCmd)
+
+	slackSyncStatusCmd.Flags().BoolVar(&slackFlags.dryRun, "dry-run", false, "Only print the status, without setting it in Slack")
+	addMirrorFlag(slackSyncStatusCmd.Flags())
+}
+
+// slackSyncResult is the outcome of [syncSlackStatus].
+type slackSyncResult struct {
+	Location report.Location `json:"location,omitempty"`
+	Previous *slack.Status   `json:"previous,omitempty"`
+	Status   slack.Status    `json:"status"`
+	// Action is one of "set", "clear", or "none"
+	Action string `json:"action"`
+	DryRun bool   `json:"dryRun,omitempty"`
+}
+
+// syncSlackStatus sets the Slack status from the absences and work location
+// of the day of the given time.
+func syncSlackStatus(source calendarSource, now time.Time, dryRun bool) (slackSyncResult, error) {
+	if cfg.Slack.Token == "" && !dryRun {
+		return slackSyncResult{}, errors.New("missing Slack token, please set the slack.token config or the PERSONIO_SLACK_TOKEN env var")
+	}
+	year, month, date := now.Date()
+	today := time.Date(year, month, date, 0, 0, 0, 0, now.Location())
+	days, err := fetchReportDays(source, datespec.Range{Start: today, End: today})
+	if err != nil {
+		return slackSyncResult{}, err
+	}
+	if len(days) != 1 {
+		return slackSyncResult{}, fmt.Errorf("expected 1 day, got %d", len(days))
+	}
+	day := days[0]
+
+	location, err := todayLocation(day)
+	if err != nil {
+		return slackSyncResult{}, err
+	}
+	rules := slackRules(cfg.Slack)
+	status := rules.StatusFor(day, location)
+	result := slackSyncResult{
+		Location: location,
+		Status:   status,
+		Action:   "set",
+		DryRun:   dryRun,
+	}
+	if dryRun {
+		if status.IsZero() {
+			result.Action = "none"
+		}
+		return result, nil
+	}
+
+	client := &slack.Client{Token: cfg.Slack.Token}
+	if status.IsZero() {
+		current, err := client.GetStatus()
+		if err != nil {
+			return result, err
+		}
+		result.Previous = &current
+		if !rules.IsOwn(current) {
+			result.Action = "none"
+			return result, nil
+		}
+		result.Action = "clear"
+	}
+	if err := client.SetStatus(status); err != nil {
+		return result, err
+	}
+	log.Info().
+		Str("action", result.Action).
+		Str("text", status.Text).
+		Str("emoji", status.Emoji).
+		Msg("Synced Slack status.")
+	return result, nil
+}
+
+// todayLocation returns where most of today's work took place, or where the
+// running punch clock says you work when nothing is submitted yet today.
+func todayLocation(day report.Day) (report.Location, error) {
+	rules := report.LocationRules{
+		RemoteKeywords: cfg.Report.HomeOffice.RemoteKeywords,
+		OfficeKeywords: cfg.Report.HomeOffice.OfficeKeywords,
+		Default:        report.Location(cfg.Report.HomeOffice.Default),
+	}
+	if location := rules.DayLocation(day); location != "" {
+		return location, nil
+	}
+	path, err := clockStatePath()
+	if err != nil {
+		return "", err
+	}
+	state, err := clock.Load(path)
+	if err != nil {
+		return "", err
+	}
+	if state.ClockedInAt == nil {
+		return "", nil
+	}
+	return rules.PeriodLocation(report.Period{Comment: state.Comment}), nil
+}
+
+func slackRules(conf config.Slack) slack.Rules {
+	return slack.Rules{
+		Vacation:     slack.Status{Text: conf.Vacation.Text, Emoji: conf.Vacation.Emoji},
+		Sick:         slack.Status{Text: conf.Sick.Text, Emoji: conf.Sick.Emoji},
+		Remote:       slack.Status{Text: conf.Remote.Text, Emoji: conf.Remote.Emoji},
+		SickKeywords: conf.SickKeywords,
+	}
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var statsFlags = struct {
+	startDate flagtype.Date
+	endDate   flagtype.Date
+}{}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show statistics about your attendance",
+	Long: `Show statistics about your attendance, such as your average start
+and end times, your longest day, a distribution of your daily hours,
+and your longest streak of workdays without missing entries.
+
+Weekends, holidays, and absences do not break a streak.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := resolveRange(cmd, statsFlags.startDate, statsFlags.endDate)
+		if err != nil {
+			return err
+		}
+
+		source, err := newCalendarSource()
+		if err != nil {
+			return err
+		}
+		defer closeCalendarSource(source)
+		days, err := fetchReportDays(source, r)
+		if err != nil {
+			return err
+		}
+		stats := report.CalculateStats(days, r, time.Now())
+
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintStats(stats)
+			return nil
+		}
+		return printOutputJSONOrYAML(stats)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	addMirrorFlag(statsCmd.Flags())
+	addOriginFlag(statsCmd.Flags())
+
+	statsCmd.Flags().VarP(&statsFlags.startDate, "start", "s", "Start date of statistics (default start of dateRange config, this month)")
+	statsCmd.Flags().VarP(&statsFlags.endDate, "end", "e", "End date of statistics (default end of dateRange config, this month)")
+}
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/clock"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/daemon"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/mirror"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var statusFlags = struct {
+	format string
+}{
+	format: string(console.StatusBarText),
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print your clocked-in state and today's hours for status bars",
+	Long: `Print whether you're clocked in, the hours worked today, and the
+remaining hours to today's target, as a single line meant for status bars.
+
+This never contacts Personio, so it's safe to run every few seconds.
+The punch clock state is read from the running daemon, or from the local
+punch clock file when no daemon is running. Today's submitted hours are
+read from the local mirror, which the daemon keeps synced. Without a synced
+mirror, only the time since "attendance start" is counted.
+
+The target hours are taken from the report.workingHours config.
+
+Formats:
+  text      plain text, e.g "in 4:15 (3:45 left)"
+  waybar    JSON for a waybar "custom" module with "return-type": "json"
+  i3blocks  JSON for an i3blocks block with "format=json"
+  polybar   text with polybar color tags`,
+	Example: `  # waybar config:
+  "custom/personio": {
+    "exec": "rootless-personio status --format waybar --quiet",
+    "return-type": "json",
+    "interval": 30
+  }`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := console.ParseStatusBarFormat(statusFlags.format)
+		if err != nil {
+			return err
+		}
+		state, err := loadStatusClockState()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		day, synced, err := loadStatusDay(today)
+		if err != nil {
+			return err
+		}
+		return console.WriteStatusBar(os.Stdout, format, console.StatusBar{
+			ClockedInAt: state.ClockedInAt,
+			Worked:      day.Work + state.Worked(now, cfg.Clock.MinBreak),
+			Target:      reportSchedule().Target(day),
+			Synced:      synced,
+		})
+	},
+}
+
+// loadStatusClockState reads the punch clock state from the running daemon,
+// falling back to the local punch clock file.
+func loadStatusClockState() (clock.State, error) {
+	if !rootFlags.noDaemon {
+		dc, err := dialDaemon()
+		if err == nil {
+			defer dc.Close()
+			status, err := dc.Status()
+			if err == nil {
+				return status.Clock, nil
+			}
+			log.Debug().Err(err).Msg("Failed getting daemon status, reading local punch clock.")
+		} else if !errors.Is(err, daemon.ErrNotRunning) {
+			log.Debug().Err(err).Msg("Failed connecting to daemon, reading local punch clock.")
+		}
+	}
+	path, err := clockStatePath()
+	if err != nil {
+		return clock.State{}, err
+	}
+	return clock.Load(path)
+}
+
+// loadStatusDay reads the given day from the local mirror. Returns false if
+// the mirror has not been synced for that day.
+func loadStatusDay(date time.Time) (report.Day, bool, error) {
+	empty := report.Day{Date: date}
+	m, err := openMirror()
+	if err != nil {
+		return empty, false, err
+	}
+	defer m.Close()
+	cal, err := m.GetMyAttendanceCalendar(date, date)
+	if errors.Is(err, mirror.ErrNotSynced) {
+		log.Debug().Err(err).Msg("Mirror not synced, only counting the punch clock.")
+		return empty, false, nil
+	}
+	if err != nil {
+		return empty, false, err
+	}
+	days, err := report.Days(cal, datespec.Range{Start: date, End: date})
+	if err != nil {
+		return empty, false, err
+	}
+	if len(days) == 0 {
+		return empty, true, nil
+	}
+	return days[0], true, nil
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVarP(&statusFlags.format, "format", "f", statusFlags.format,
+		fmt.Sprintf("Status bar format, one of: %s", strings.Join(console.StatusBarFormatNames(), ", ")))
+	statusCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return console.StatusBarFormatNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+}
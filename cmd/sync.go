@@ -0,0 +1,389 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/hook"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/plan"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/applejag/rootless-personio/pkg/storage"
+	"github.com/applejag/rootless-personio/pkg/tidy"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/typ.v4/slices"
+)
+
+var syncFlags = struct {
+	file        string
+	planOnly    bool
+	autoApprove bool
+	force       bool
+}{}
+
+// syncExitChangesPending is the exit code of "sync --plan" when Personio
+// doesn't match the file, same as "terraform plan -detailed-exitcode".
+const syncExitChangesPending = 2
+
+var syncCmd = &cobra.Command{
+	Use:   "sync --file <periods.json>",
+	Short: "Makes Personio match a file of attendance periods, after showing a plan",
+	Long: `Makes Personio match a file of attendance periods, such as a timesheet
+kept in git, by first showing a plan of the changes, similar to
+"terraform plan", and then applying it once approved.
+
+The file uses the same format as "attendance set", and all days mentioned in
+the file are made to match it. The plan addresses each period by its day,
+type, and start time, e.g:
+
+  attendance["2024-05-02"]: + create 1, ~ update 1, - delete 1
+    + attendance["2024-05-02"].work["08:00"]: 08:00-12:00 "Work before lunch"
+    ~ attendance["2024-05-02"].work["13:00"]: 13:00-17:00 -> 13:00-17:30
+    - attendance["2024-05-02"].break["12:00"]: 12:00-12:30
+
+  Plan: 1 to create, 1 to update, 1 to delete.
+
+The plan is applied after confirming it, or right away with --auto-approve.
+With --plan, only the plan is shown. The transformation script and tidying
+apply the same as for "attendance set".
+
+The periods of each applied day are remembered in a state file in the
+storage, so the plan can tell new changes in the file apart from changes
+made in Personio since the last sync, such as edits in the web UI. Days
+changed in Personio are marked in the plan, and days changed both in the
+file and in Personio are refused unless --force is used. Run "sync refresh"
+to accept the periods in Personio as the new state.
+
+Days in months locked by payroll are marked in the plan, and the plan is
+refused as a whole if it changes any of them, even with --force, as
+Personio would reject those changes halfway through applying the plan.
+
+Exit codes:
+  0  No changes, or the changes were applied
+  1  Failed, or the plan was not approved
+  2  Changes are pending, when using --plan`,
+	Example: `  rootless-personio sync --file timesheet.json --plan
+  rootless-personio sync --file timesheet.json --auto-approve`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var file io.ReadCloser = os.Stdin
+		switch syncFlags.file {
+		case "":
+			return errors.New("missing attendance periods, please provide the --file flag")
+		case "-":
+		default:
+			var err error
+			file, err = os.Open(syncFlags.file)
+			if err != nil {
+				return err
+			}
+		}
+		defer file.Close()
+		desired, err := readDesiredPeriods(file)
+		if err != nil {
+			return err
+		}
+
+		client, err := newLoggedInClient()
+		if err != nil {
+			return err
+		}
+		store, key, err := syncStateStorage(client)
+		if err != nil {
+			return err
+		}
+		state, err := plan.LoadState(store, key)
+		if err != nil {
+			return err
+		}
+		p, err := planSync(client, desired, state)
+		if err != nil {
+			return err
+		}
+		if cfg.Output == config.OutFormatPretty {
+			if err := p.Write(os.Stdout); err != nil {
+				return err
+			}
+		} else if err := printOutputJSONOrYAML(map[string]any{
+			"changes": p.Changes,
+			"days":    p.Days,
+			"summary": p.Summary(),
+		}); err != nil {
+			return err
+		}
+		if syncFlags.planOnly {
+			if p.Empty() {
+				return nil
+			}
+			return &exitCodeError{code: syncExitChangesPending, msg: "changes pending"}
+		}
+		if p.Empty() {
+			return plan.SaveDays(store, key, desired, time.Now())
+		}
+
+		if days := p.LockedDays(); len(days) > 0 {
+			return fmt.Errorf("%w: the plan changes days in locked months: %v, "+
+				"remove them from the file to apply the rest", personio.ErrMonthLocked, days)
+		}
+		if !syncFlags.force {
+			if days := conflictDays(p); len(days) > 0 {
+				return fmt.Errorf("days changed both in the file and in Personio since the last sync: %v, "+
+					"use \"sync refresh\" to accept the periods in Personio, or --force to overwrite them", days)
+			}
+			if err := checkForeignChanges(client, p.Days, nil); err != nil {
+				return err
+			}
+		}
+		if !syncFlags.autoApprove {
+			if !isInteractive() {
+				return fmt.Errorf("use --auto-approve to apply without approval: %w", errNoPrompt("approval of the plan"))
+			}
+			var approved bool
+			prompt := &survey.Confirm{
+				Message: fmt.Sprintf("Apply the plan (%s)?", p.Summary()),
+			}
+			if err := survey.AskOne(prompt, &approved); err != nil {
+				return err
+			}
+			if !approved {
+				return errors.New("apply cancelled")
+			}
+		}
+		if err := applySync(client, p, desired); err != nil {
+			return err
+		}
+		return plan.SaveDays(store, key, desired, time.Now())
+	},
+}
+
+var syncRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Accepts the periods in Personio as the last synced state",
+	Long: `Accepts the periods in Personio as the last synced state, for all days
+that have been synced before, similar to "terraform refresh".
+
+Use this after changes made in Personio on purpose, such as corrections by
+HR, so the next "sync" doesn't report them as conflicts or revert them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newLoggedInClient()
+		if err != nil {
+			return err
+		}
+		store, key, err := syncStateStorage(client)
+		if err != nil {
+			return err
+		}
+		state, err := plan.LoadState(store, key)
+		if err != nil {
+			return err
+		}
+		days := state.SortedDays()
+		if len(days) == 0 {
+			log.Info().Msg("No synced days to refresh.")
+			return nil
+		}
+		current, err := currentPeriods(client, days)
+		if err != nil {
+			return err
+		}
+		refreshed := make(map[string][]personio.Period, len(days))
+		var changed int
+		for _, day := range days {
+			refreshed[day] = current[day]
+			if !plan.EqualDays(current[day], state.Days[day].Periods) {
+				log.Info().Str("day", day).Msg("Day was changed in Personio since the last sync.")
+				changed++
+			}
+		}
+		if err := plan.SaveDays(store, key, refreshed, time.Now()); err != nil {
+			return err
+		}
+		log.Info().Int("days", len(days)).Int("changed", changed).Msg("Refreshed sync state.")
+		return nil
+	},
+}
+
+// syncStateStorage returns where the sync state of the logged in employee
+// is stored.
+func syncStateStorage(client *personio.Client) (storage.Backend, string, error) {
+	u, err := url.Parse(client.BaseURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("parse base URL: %w", err)
+	}
+	store, err := openStorage()
+	if err != nil {
+		return nil, "", err
+	}
+	return store, plan.StateKey(u.Hostname(), client.EmployeeID), nil
+}
+
+// conflictDays returns the days of the plan that were changed both in the
+// file and in Personio since the last sync.
+func conflictDays(p *plan.Plan) []string {
+	var days []string
+	for _, c := range p.Changes {
+		if c.Cause == plan.CauseConflict && (len(days) == 0 || days[len(days)-1] != c.Day) {
+			days = append(days, c.Day)
+		}
+	}
+	return days
+}
+
+// readDesiredPeriods reads the JSON attendance periods, applies the
+// transformation script and tidying, and groups them by day.
+func readDesiredPeriods(file io.ReadCloser) (map[string][]personio.Period, error) {
+	renderer, err := newCommentRenderer(nil)
+	if err != nil {
+		return nil, err
+	}
+	periods, _, err := readPeriodsJSON(file, renderer)
+	if err != nil {
+		return nil, err
+	}
+	if len(periods) == 0 {
+		return nil, errors.New("missing attendance periods, please provide JSON objects via STDIN or --file")
+	}
+	script, err := loadTransformScript()
+	if err != nil {
+		return nil, fmt.Errorf("load transform script: %w", err)
+	}
+	if script != nil {
+		periods, err = script.Apply(periods)
+		if err != nil {
+			return nil, fmt.Errorf("transform periods: %w", err)
+		}
+	}
+	desired := make(map[string][]personio.Period)
+	for _, group := range slices.GroupBy(periods, func(p personio.Period) string {
+		return p.Start.Format(time.DateOnly)
+	}) {
+		values := group.Values
+		if cfg.Tidy.Enabled {
+			values = tidy.Periods(values, cfg.Tidy.MaxGap)
+		}
+		desired[group.Key] = values
+	}
+	return desired, nil
+}
+
+// planSync compares the desired periods with the ones in Personio and the
+// last synced state.
+func planSync(client *personio.Client, desired map[string][]personio.Period, state *plan.State) (*plan.Plan, error) {
+	days := make([]string, 0, len(desired))
+	for day := range desired {
+		days = append(days, day)
+	}
+	slices.Sort(days)
+	current, err := currentPeriods(client, days)
+	if err != nil {
+		return nil, err
+	}
+	// Fetching the current periods caches which months are locked
+	p := &plan.Plan{
+		State: state,
+		IsLocked: func(day string) bool {
+			date, err := time.ParseInLocation(time.DateOnly, day, time.Local)
+			return err == nil && client.IsMonthLocked(date)
+		},
+	}
+	for _, day := range days {
+		p.Add(day, current[day], desired[day])
+	}
+	return p, nil
+}
+
+// currentPeriods returns the periods in Personio, grouped by day, of the
+// range of the sorted days.
+func currentPeriods(client *personio.Client, days []string) (map[string][]personio.Period, error) {
+	start, err := time.ParseInLocation(time.DateOnly, days[0], time.Local)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.ParseInLocation(time.DateOnly, days[len(days)-1], time.Local)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string][]personio.Period)
+	err = forEachCalendarMonth(client, datespec.Range{Start: start, End: end}, func(cal *personio.AttendanceCalendar, _ datespec.Range) error {
+		byDay, err := cal.PeriodsByDay()
+		if err != nil {
+			return err
+		}
+		for day, periods := range byDay {
+			current[day] = periods
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get current periods: %w", err)
+	}
+	return current, nil
+}
+
+// applySync sets the desired periods of the days that have changes.
+func applySync(client *personio.Client, p *plan.Plan, desired map[string][]personio.Period) error {
+	hookDays := make([]submitHookDay, len(p.Days))
+	for i, day := range p.Days {
+		hookDays[i] = submitHookDay{Day: day, Periods: desired[day]}
+	}
+	if err := runSubmitHook(hook.PreSubmit, cfg.Hooks.PreSubmit, "set", client.TargetEmployeeID(), hookDays); err != nil {
+		return err
+	}
+	for _, day := range p.Days {
+		date, err := time.ParseInLocation(time.DateOnly, day, time.Local)
+		if err != nil {
+			return err
+		}
+		periods := desired[day]
+		err = client.SetAttendance(date, periods)
+		recordAudit("sync", client, queue.NewOperation(queue.ActionSet, day, periods, time.Now()), err, nil)
+		if err != nil {
+			return fmt.Errorf("apply %s: %w", day, err)
+		}
+		if err := verifyAttendance(client, date, periods); err != nil {
+			return err
+		}
+		log.Info().Str("day", day).Int("periods", len(periods)).Msg("Applied changes for day.")
+	}
+	if err := runSubmitHook(hook.PostSubmit, cfg.Hooks.PostSubmit, "set", client.TargetEmployeeID(), hookDays); err != nil {
+		return err
+	}
+	log.Info().Msgf("Applied plan: %s.", p.Summary())
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.AddCommand(syncRefreshCmd)
+
+	syncCmd.Flags().StringVarP(&syncFlags.file, "file", "f", "", `Attendance periods JSON file, "-" means STDIN`)
+	syncCmd.Flags().BoolVar(&syncFlags.planOnly, "plan", false, "Only show the plan, exiting with code 2 if there are changes")
+	syncCmd.Flags().BoolVar(&syncFlags.autoApprove, "auto-approve", false, "Apply the plan without asking for approval")
+	syncCmd.Flags().BoolVar(&syncFlags.force, "force", false, "Overwrite periods that someone else changed, such as HR corrections, and days in conflict with the sync state")
+}
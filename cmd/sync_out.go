@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/caldav"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/ical"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var syncOutFlags = struct {
+	startDate flagtype.Date
+	endDate   flagtype.Date
+	holidays  bool
+	file      string
+}{}
+
+var syncOutCmd = &cobra.Command{
+	Use:   "sync-out",
+	Short: "Copy your absences and holidays into external calendars",
+	Long: `Copy your absences and public holidays from Personio into an external
+calendar, to keep a private calendar in step with your HR-approved vacations.
+
+Events that were copied before, but are no longer in Personio within the
+date range, such as cancelled absences, are removed from CalDAV calendars.
+Other events in the calendar are left as-is.`,
+}
+
+var syncOutICSCmd = &cobra.Command{
+	Use:     "ics",
+	Short:   "Write your absences and holidays to an iCalendar file",
+	Example: `  rootless-personio sync-out ics --start 2024-01-01 --end 2024-12-31 -f vacation.ics`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, events, err := syncOutEvents(cmd)
+		if err != nil {
+			return err
+		}
+		var w io.Writer = os.Stdout
+		if syncOutFlags.file != "-" {
+			f, err := os.Create(syncOutFlags.file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		cal := ical.Calendar{Name: "Personio", Events: events}
+		if err := cal.Write(w, time.Now()); err != nil {
+			return fmt.Errorf("write calendar: %w", err)
+		}
+		log.Info().
+			Int("events", len(events)).
+			Time("start", r.Start).
+			Time("end", r.End).
+			Msg("Wrote calendar.")
+		return nil
+	},
+}
+
+var syncOutCalDAVCmd = &cobra.Command{
+	Use:   "caldav",
+	Short: "Write your absences and holidays to a CalDAV calendar",
+	Long: `Write your absences and holidays to a calendar on a CalDAV server, such
+as Nextcloud, Fastmail, or iCloud, as configured by the syncOut.caldav config.
+
+Use a dedicated calendar, or at least one where you don't mind this program
+adding events.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf := cfg.SyncOut.CalDAV
+		if conf.URL == "" {
+			return errors.New("missing CalDAV calendar, please set the syncOut.caldav.url config")
+		}
+		client := &caldav.Client{
+			URL: conf.URL,
+			Authorize: func(req *http.Request) error {
+				if conf.Username != "" || conf.Password != "" {
+					req.SetBasicAuth(conf.Username, conf.Password)
+				}
+				return nil
+			},
+		}
+		return syncOutCalDAV(cmd, client)
+	},
+}
+
+var syncOutGCalCmd = &cobra.Command{
+	Use:   "gcal",
+	Short: "Write your absences and holidays to a Google calendar",
+	Long: `Write your absences and holidays to a Google calendar via Google's
+CalDAV API, as configured by the syncOut.gcal config.
+
+The syncOut.gcal.tokenCommand must print an OAuth 2.0 access token with the
+"https://www.googleapis.com/auth/calendar" scope, such as:
+
+    gcloud auth print-access-token`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf := cfg.SyncOut.GCal
+		if conf.CalendarID == "" {
+			return errors.New("missing Google calendar, please set the syncOut.gcal.calendarId config")
+		}
+		if len(conf.TokenCommand) == 0 {
+			return errors.New("missing access token command, please set the syncOut.gcal.tokenCommand config")
+		}
+		token, err := runTokenCommand(conf.TokenCommand)
+		if err != nil {
+			return err
+		}
+		client := &caldav.Client{
+			URL: "https://apidata.googleusercontent.com/caldav/v2/" + url.PathEscape(conf.CalendarID) + "/events/",
+			Authorize: func(req *http.Request) error {
+				req.Header.Set("Authorization", "Bearer "+token)
+				return nil
+			},
+		}
+		return syncOutCalDAV(cmd, client)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncOutCmd)
+	syncOutCmd.AddCommand(syncOutICSCmd)
+	syncOutCmd.AddCommand(syncOutCalDAVCmd)
+	syncOutCmd.AddCommand(syncOutGCalCmd)
+
+	syncOutCmd.PersistentFlags().VarP(&syncOutFlags.startDate, "start", "s", "Start date of absences to copy (default start of dateRange config, this month)")
+	syncOutCmd.PersistentFlags().VarP(&syncOutFlags.endDate, "end", "e", "End date of absences to copy (default end of dateRange config, this month)")
+	syncOutCmd.PersistentFlags().BoolVar(&syncOutFlags.holidays, "holidays", false, "Include public holidays (default syncOut.holidays config)")
+	syncOutICSCmd.Flags().StringVarP(&syncOutFlags.file, "file", "f", "-", `Output file, "-" means STDOUT`)
+	syncOutICSCmd.MarkFlagFilename("file", "ics")
+}
+
+func syncOutCalDAV(cmd *cobra.Command, client *caldav.Client) error {
+	r, events, err := syncOutEvents(cmd)
+	if err != nil {
+		return err
+	}
+	result, err := client.Sync(events, r.Start, r.End, time.Now())
+	if err != nil {
+		return err
+	}
+	log.Info().
+		Int("put", result.Put).
+		Int("deleted", result.Deleted).
+		Msg("Synced calendar.")
+	return printOutputJSONOrYAML(result)
+}
+
+// syncOutEvents fetches the absences and holidays within the date range,
+// as calendar events sorted by their start.
+func syncOutEvents(cmd *cobra.Command) (datespec.Range, []ical.Event, error) {
+	r, err := resolveRange(cmd, syncOutFlags.startDate, syncOutFlags.endDate)
+	if err != nil {
+		return r, nil, err
+	}
+	holidays := cfg.SyncOut.Holidays
+	if cmd.Flags().Changed("holidays") {
+		holidays = syncOutFlags.holidays
+	}
+
+	client, err := newLoggedInClient()
+	if err != nil {
+		return r, nil, err
+	}
+	byUID := make(map[string]ical.Event)
+	err = forEachCalendarMonth(client, r, func(cal *personio.AttendanceCalendar, month datespec.Range) error {
+		events, err := ical.CalendarEvents(cal, ical.EventOptions{
+			Absences: true,
+			Holidays: holidays,
+		})
+		if err != nil {
+			return err
+		}
+		// Absences spanning multiple months are included in each month
+		for _, e := range events {
+			byUID[e.UID] = e
+		}
+		return nil
+	})
+	if err != nil {
+		return r, nil, err
+	}
+
+	events := make([]ical.Event, 0, len(byUID))
+	for _, e := range byUID {
+		events = append(events, e)
+	}
+	ical.SortEvents(events)
+	return r, events, nil
+}
+
+// runTokenCommand runs the command and returns its trimmed output.
+func runTokenCommand(command []string) (string, error) {
+	var stdout bytes.Buffer
+	c := exec.Command(command[0], command[1:]...)
+	c.Stdout = &stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("run token command: %w", err)
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", errors.New("token command printed no access token")
+	}
+	return token, nil
+}
@@ -0,0 +1,230 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/mirror"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Group of commands for local-only period tags and notes",
+	Long: `Group of commands for attaching tags and notes to attendance periods,
+which are only stored in the local mirror and never sent to Personio.
+This lets you slice reports by e.g client or project, without polluting
+the period comments that your employer sees.
+
+Tags and notes are keyed by the period ID, so they are kept when syncing
+the mirror again. Use "tag list" to find the period IDs, and
+"report tags" to summarize your work per tag.
+
+The periods must have been synced to the mirror first, via "mirror sync".`,
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:     "add <period-id> <tag>...",
+	Short:   "Add tags to a period",
+	Example: `  rootless-personio tag add 46365bc8-482a-41b2-8d36-68491140edd9 client-a billable`,
+	Args:    cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		periodID, err := parsePeriodID(args[0])
+		if err != nil {
+			return err
+		}
+		m, err := openMirror()
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		if err := m.AddTags(periodID, args[1:]...); err != nil {
+			return err
+		}
+		log.Info().Stringer("period", periodID).Strs("tags", args[1:]).Msg("Added tags.")
+		return nil
+	},
+}
+
+var tagRemoveCmd = &cobra.Command{
+	Use:     "remove <period-id> <tag>...",
+	Aliases: []string{"rm"},
+	Short:   "Remove tags from a period",
+	Args:    cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		periodID, err := parsePeriodID(args[0])
+		if err != nil {
+			return err
+		}
+		m, err := openMirror()
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		if err := m.RemoveTags(periodID, args[1:]...); err != nil {
+			return err
+		}
+		log.Info().Stringer("period", periodID).Strs("tags", args[1:]).Msg("Removed tags.")
+		return nil
+	},
+}
+
+var tagNoteCmd = &cobra.Command{
+	Use:   "note <period-id> [note]",
+	Short: "Set or remove the note of a period",
+	Long:  `Set the local-only note of a period. Leave out the note to remove it.`,
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		periodID, err := parsePeriodID(args[0])
+		if err != nil {
+			return err
+		}
+		var note string
+		if len(args) == 2 {
+			note = strings.TrimSpace(args[1])
+		}
+		m, err := openMirror()
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		if err := m.SetNote(periodID, note); err != nil {
+			return err
+		}
+		if note == "" {
+			log.Info().Stringer("period", periodID).Msg("Removed note.")
+		} else {
+			log.Info().Stringer("period", periodID).Msg("Set note.")
+		}
+		return nil
+	},
+}
+
+var tagListFlags = struct {
+	startDate flagtype.Date
+	endDate   flagtype.Date
+}{}
+
+var tagListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List periods with their IDs, tags, and notes",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := resolveRange(cmd, tagListFlags.startDate, tagListFlags.endDate)
+		if err != nil {
+			return err
+		}
+		days, err := fetchTaggedDays(r)
+		if err != nil {
+			return err
+		}
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintPeriodTags(days)
+			return nil
+		}
+		var periods []tagListPeriod
+		for _, day := range days {
+			for _, p := range day.Periods {
+				periods = append(periods, tagListPeriod{
+					ID:         p.ID,
+					Date:       day.Date.Format(time.DateOnly),
+					PeriodType: p.Type,
+					Start:      p.Start,
+					End:        p.End,
+					Comment:    p.Comment,
+					Tags:       p.Tags,
+					Note:       p.Note,
+				})
+			}
+		}
+		return printOutputJSONOrYAML(periods)
+	},
+}
+
+type tagListPeriod struct {
+	ID         uuid.UUID           `json:"id"`
+	Date       string              `json:"date"`
+	PeriodType personio.PeriodType `json:"period_type"`
+	Start      time.Time           `json:"start"`
+	End        time.Time           `json:"end"`
+	Comment    string              `json:"comment,omitempty"`
+	Tags       []string            `json:"tags,omitempty"`
+	Note       string              `json:"note,omitempty"`
+}
+
+// fetchTaggedDays reads the days from the local mirror, together with the
+// periods' local-only tags and notes.
+func fetchTaggedDays(r datespec.Range) ([]report.Day, error) {
+	m, err := openMirror()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+	days, err := fetchReportDays(m, r)
+	if err != nil {
+		return nil, err
+	}
+	metas, err := m.PeriodMeta(r.Start, r.End)
+	if err != nil {
+		return nil, err
+	}
+	applyPeriodMeta(days, metas)
+	return days, nil
+}
+
+func applyPeriodMeta(days []report.Day, metas map[uuid.UUID]mirror.PeriodMeta) {
+	for i := range days {
+		for j := range days[i].Periods {
+			p := &days[i].Periods[j]
+			meta := metas[p.ID]
+			p.Tags = meta.Tags
+			p.Note = meta.Note
+		}
+	}
+}
+
+func parsePeriodID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid period ID %q: %w", s, err)
+	}
+	return id, nil
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+	tagCmd.AddCommand(tagNoteCmd)
+	tagCmd.AddCommand(tagListCmd)
+
+	tagListCmd.Flags().VarP(&tagListFlags.startDate, "start", "s", "Start date to list (default start of dateRange config, this month)")
+	tagListCmd.Flags().VarP(&tagListFlags.endDate, "end", "e", "End date to list (default end of dateRange config, this month)")
+}
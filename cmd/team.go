@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/flagtype"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/applejag/rootless-personio/pkg/xlsx"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var teamCmd = &cobra.Command{
+	Use:   "team",
+	Short: "Commands for managers, about the employees in your team",
+}
+
+var teamExportFlags = struct {
+	from        flagtype.Date
+	to          flagtype.Date
+	file        string
+	format      string
+	employees   []string
+	concurrency int
+}{
+	file:        "-",
+	concurrency: 4,
+}
+
+var teamExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the attendance of your team as CSV or XLSX",
+	Long: `Export the attendance of all employees in your team into a single
+CSV or XLSX (Excel) report, with one row per employee and day.
+
+The team is the employees listed in the "employees" config, as Personio
+doesn't expose your direct reports to this program. Use --employee to only
+export some of them. Their calendars are fetched concurrently, and
+employees whose attendance your account cannot view are skipped.
+
+The format defaults to XLSX when the --file ends with ".xlsx",
+and otherwise CSV.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := resolveRange(cmd, teamExportFlags.from, teamExportFlags.to)
+		if err != nil {
+			return err
+		}
+
+		format := teamExportFlags.format
+		if format == "" {
+			format = "csv"
+			if strings.EqualFold(filepath.Ext(teamExportFlags.file), ".xlsx") {
+				format = "xlsx"
+			}
+		}
+		if format != "csv" && format != "xlsx" {
+			return fmt.Errorf("unknown format %q, must be one of: csv, xlsx", format)
+		}
+
+		members, err := teamMembers(teamExportFlags.employees)
+		if err != nil {
+			return err
+		}
+
+		client, err := newLoggedInClient()
+		if err != nil {
+			return err
+		}
+		results, failed := fetchTeamDays(client, members, r, teamExportFlags.concurrency)
+		rows := report.TeamTable(results)
+
+		var w io.Writer = os.Stdout
+		if teamExportFlags.file != "-" {
+			f, err := os.Create(teamExportFlags.file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		switch format {
+		case "xlsx":
+			err = writeTeamXLSX(w, rows)
+		default:
+			err = writeTeamCSV(w, rows)
+		}
+		if err != nil {
+			return err
+		}
+		log.Info().
+			Int("employees", len(results)).
+			Int("rows", len(rows)).
+			Msg("Exported team attendance.")
+		if failed > 0 {
+			return fmt.Errorf("failed fetching attendance of %d of %d employees", failed, len(members))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(teamCmd)
+	teamCmd.AddCommand(teamExportCmd)
+
+	teamExportCmd.Flags().Var(&teamExportFlags.from, "from", "First date to export (default start of dateRange config, this month)")
+	teamExportCmd.Flags().Var(&teamExportFlags.to, "to", "Last date to export (default end of dateRange config, this month)")
+	teamExportCmd.Flags().StringVarP(&teamExportFlags.file, "file", "f", teamExportFlags.file, `File to write to, "-" means STDOUT`)
+	teamExportCmd.Flags().StringVar(&teamExportFlags.format, "format", "", `Report format, "csv" or "xlsx" (default from --file extension)`)
+	teamExportCmd.Flags().StringArrayVar(&teamExportFlags.employees, "employee", nil, `Only export this employee, by ID or by email from the "employees" config (repeatable)`)
+	teamExportCmd.Flags().IntVar(&teamExportFlags.concurrency, "concurrency", teamExportFlags.concurrency, "How many employees' calendars to fetch at the same time")
+}
+
+// teamMembers returns the employees from the "employees" config, or only
+// the given employees if any.
+func teamMembers(only []string) ([]report.TeamMember, error) {
+	if len(only) > 0 {
+		members := make([]report.TeamMember, 0, len(only))
+		for _, s := range only {
+			id, err := resolveEmployeeID(s)
+			if err != nil {
+				return nil, err
+			}
+			member := report.TeamMember{ID: id}
+			for _, e := range cfg.Employees {
+				if e.ID == id {
+					member.Email = e.Email
+				}
+			}
+			members = append(members, member)
+		}
+		return members, nil
+	}
+	if len(cfg.Employees) == 0 {
+		return nil, errors.New(`no employees in your team, add them to the "employees" config`)
+	}
+	members := make([]report.TeamMember, len(cfg.Employees))
+	for i, e := range cfg.Employees {
+		members[i] = report.TeamMember{ID: e.ID, Email: e.Email}
+	}
+	return members, nil
+}
+
+// fetchTeamDays fetches the names and attendance of the team members
+// concurrently. Employees that fail are logged and skipped, and counted in
+// the returned number of failures.
+func fetchTeamDays(client *personio.Client, members []report.TeamMember, r datespec.Range, concurrency int) ([]report.TeamMemberDays, int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]*report.TeamMemberDays, len(members))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, member := range members {
+		wg.Add(1)
+		go func(i int, member report.TeamMember) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if employee, err := client.GetEmployeeData(member.ID); err == nil {
+				member.Name = strings.TrimSpace(employee.FirstName + " " + employee.LastName)
+			} else {
+				log.Debug().Err(err).Int("employeeId", member.ID).Msg("Failed fetching employee name.")
+			}
+			days, err := fetchReportDays(employeeCalendarSource{client, member.ID}, r)
+			if err != nil {
+				log.Error().Err(err).Int("employeeId", member.ID).Str("email", member.Email).
+					Msg("Failed fetching attendance of employee, skipping.")
+				return
+			}
+			results[i] = &report.TeamMemberDays{Member: member, Days: days}
+		}(i, member)
+	}
+	wg.Wait()
+
+	var fetched []report.TeamMemberDays
+	for _, result := range results {
+		if result != nil {
+			fetched = append(fetched, *result)
+		}
+	}
+	return fetched, len(members) - len(fetched)
+}
+
+// employeeCalendarSource reads the attendance calendar of another employee.
+type employeeCalendarSource struct {
+	client     *personio.Client
+	employeeID int
+}
+
+func (s employeeCalendarSource) GetMyAttendanceCalendar(startDate, endDate time.Time) (*personio.AttendanceCalendar, error) {
+	return s.client.GetAttendanceCalendar(s.employeeID, startDate, endDate)
+}
+
+func writeTeamCSV(w io.Writer, rows [][]any) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(report.TeamTableHeader); err != nil {
+		return err
+	}
+	record := make([]string, len(report.TeamTableHeader))
+	for _, row := range rows {
+		for i, cell := range row {
+			record[i] = csvCell(cell)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Duration:
+		return strconv.FormatFloat(v.Hours(), 'f', 2, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func writeTeamXLSX(w io.Writer, rows [][]any) error {
+	header := make([]any, len(report.TeamTableHeader))
+	for i, name := range report.TeamTableHeader {
+		header[i] = name
+	}
+	return xlsx.Write(w, xlsx.Sheet{
+		Name: "Team attendance",
+		Rows: append([][]any{header}, rows...),
+	})
+}
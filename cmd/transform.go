@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/applejag/rootless-personio/pkg/transform"
+)
+
+func loadTransformScript() (*transform.Script, error) {
+	if cfg.Transform.File != "" {
+		src, err := os.ReadFile(cfg.Transform.File)
+		if err != nil {
+			return nil, err
+		}
+		return transform.Compile(cfg.Transform.File, src)
+	}
+	if cfg.Transform.Script != "" {
+		return transform.Compile("transform.script", cfg.Transform.Script)
+	}
+	return nil, nil
+}
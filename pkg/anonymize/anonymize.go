@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package anonymize removes secrets and personal data from logs, configs,
+// and API responses, so they can be attached to bug reports.
+//
+// Personal values are replaced by salted hashes, so the same value is
+// always replaced the same way within a dump, keeping the data consistent
+// enough to reproduce bugs with, while not revealing the original values.
+package anonymize
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Redacted replaces secrets, such as passwords.
+const Redacted = "/redacted/"
+
+// personalKeys are JSON object keys, in lower case, whose string values
+// are personal data.
+var personalKeys = map[string]bool{
+	"name":                  true,
+	"first_name":            true,
+	"last_name":             true,
+	"firstname":             true,
+	"lastname":              true,
+	"full_name":             true,
+	"display_name":          true,
+	"preferred_name":        true,
+	"email":                 true,
+	"comment":               true,
+	"note":                  true,
+	"position":              true,
+	"department":            true,
+	"office":                true,
+	"team":                  true,
+	"holiday_calendar_name": true,
+	"small":                 true, // profile image URLs
+	"medium":                true,
+	"large":                 true,
+	"original":              true,
+}
+
+// Anonymizer replaces secrets and personal data.
+type Anonymizer struct {
+	salt     []byte
+	secrets  []string
+	personal []string
+}
+
+// New returns an anonymizer with a random salt, so the hashes cannot be
+// compared between dumps, or brute forced from known names.
+func New() *Anonymizer {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		panic(fmt.Sprintf("read random salt: %s", err))
+	}
+	return &Anonymizer{salt: salt}
+}
+
+// AddSecrets adds values that are replaced with [Redacted] by [Anonymizer.Text].
+// Empty values are ignored.
+func (a *Anonymizer) AddSecrets(values ...string) {
+	a.secrets = appendNonEmpty(a.secrets, values)
+}
+
+// AddPersonal adds values that are replaced with their hash by
+// [Anonymizer.Text]. Empty values are ignored.
+func (a *Anonymizer) AddPersonal(values ...string) {
+	a.personal = appendNonEmpty(a.personal, values)
+}
+
+func appendNonEmpty(dst, values []string) []string {
+	for _, v := range values {
+		if v != "" {
+			dst = append(dst, v)
+		}
+	}
+	// Replace longer values first, in case one value contains another
+	sort.SliceStable(dst, func(i, j int) bool { return len(dst[i]) > len(dst[j]) })
+	return dst
+}
+
+func (a *Anonymizer) sum(s string) []byte {
+	h := sha256.New()
+	h.Write(a.salt)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// String returns the hash of a personal value, e.g "anon-1a2b3c4d".
+func (a *Anonymizer) String(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "anon-" + hex.EncodeToString(a.sum(s)[:4])
+}
+
+// Int returns the hash of a personal ID as a positive 7-digit number,
+// which looks like a Personio ID.
+func (a *Anonymizer) Int(n int64) int64 {
+	if n == 0 {
+		return 0
+	}
+	return 1_000_000 + int64(binary.BigEndian.Uint64(a.sum(strconv.FormatInt(n, 10)))%9_000_000)
+}
+
+// UUID returns the hash of a UUID as a new valid UUID.
+func (a *Anonymizer) UUID(id uuid.UUID) uuid.UUID {
+	if id == uuid.Nil {
+		return id
+	}
+	return uuid.NewSHA1(uuid.NameSpaceOID, a.sum(id.String()))
+}
+
+// Text replaces all secrets and personal values added to the anonymizer,
+// as well as any credentials found by [Redact].
+func (a *Anonymizer) Text(s string) string {
+	for _, secret := range a.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	s = Redact(s)
+	for _, value := range a.personal {
+		s = strings.ReplaceAll(s, value, a.String(value))
+	}
+	return s
+}
+
+// JSON anonymizes a JSON document, by hashing the values of personal
+// fields such as names and comments, and of all IDs, while keeping their
+// types. All other strings are passed through [Anonymizer.Text].
+func (a *Anonymizer) JSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(a.value("", doc), "", "  ")
+}
+
+func (a *Anonymizer) value(key string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = a.value(k, child)
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = a.value(key, child)
+		}
+		return v
+	case string:
+		switch {
+		case isIDKey(key):
+			if id, err := uuid.Parse(v); err == nil {
+				return a.UUID(id).String()
+			}
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return strconv.FormatInt(a.Int(n), 10)
+			}
+			return a.String(v)
+		case personalKeys[strings.ToLower(key)]:
+			return a.String(v)
+		default:
+			return a.Text(v)
+		}
+	case json.Number:
+		if isIDKey(key) {
+			if n, err := v.Int64(); err == nil {
+				return json.Number(strconv.FormatInt(a.Int(n), 10))
+			}
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isIDKey(key string) bool {
+	return key == "id" || strings.HasSuffix(key, "_id") || strings.HasSuffix(key, "Id") || strings.HasSuffix(key, "ID")
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package anonymize
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	a := New()
+	a.AddPersonal("jane.doe@example.com")
+	data := []byte(`{
+		"id": 1234567,
+		"first_name": "Jane",
+		"attributes": {
+			"attendance_day_id": "81954d73-0b0d-4053-a5dc-937bdd62f9f7",
+			"comment": "Meeting with Bob",
+			"start": "2023-01-18T13:00:00Z",
+			"duration_min": 240,
+			"message": "Sent to jane.doe@example.com"
+		}
+	}`)
+	out, err := a.JSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{"1234567", "Jane", "81954d73", "Bob", "jane.doe"} {
+		if strings.Contains(string(out), leaked) {
+			t.Errorf("output contains %q:\n%s", leaked, out)
+		}
+	}
+
+	var got struct {
+		ID         int    `json:"id"`
+		FirstName  string `json:"first_name"`
+		Attributes struct {
+			AttendanceDayID string `json:"attendance_day_id"`
+			Start           string `json:"start"`
+			DurationMin     int    `json:"duration_min"`
+		} `json:"attributes"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("want same types after anonymizing, got error: %s", err)
+	}
+	if got.ID < 1_000_000 || got.FirstName != a.String("Jane") {
+		t.Errorf("wrong anonymized employee: %+v", got)
+	}
+	if got.Attributes.Start != "2023-01-18T13:00:00Z" || got.Attributes.DurationMin != 240 {
+		t.Errorf("want non-personal fields kept, got: %+v", got.Attributes)
+	}
+
+	again, err := a.JSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(out) {
+		t.Error("want same output when anonymizing the same data twice")
+	}
+}
+
+func TestText(t *testing.T) {
+	a := New()
+	a.AddSecrets("hunter2")
+	a.AddPersonal("jane.doe@example.com", "")
+	got := a.Text("Login as jane.doe@example.com with hunter2")
+	want := "Login as " + a.String("jane.doe@example.com") + " with " + Redacted
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
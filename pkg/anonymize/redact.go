@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package anonymize
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// sensitiveHeaders are the canonical names of HTTP headers whose values
+// are credentials.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Csrf-Token":        true,
+	"X-Xsrf-Token":        true,
+}
+
+// IsSensitiveHeader returns true if the HTTP header's value is a
+// credential, such as cookies and CSRF tokens.
+func IsSensitiveHeader(name string) bool {
+	return sensitiveHeaders[http.CanonicalHeaderKey(name)]
+}
+
+// Header returns the HTTP header's value, or [Redacted] if the header is
+// sensitive.
+func Header(name, value string) string {
+	if IsSensitiveHeader(name) {
+		return Redacted
+	}
+	return value
+}
+
+var redactPatterns = []*regexp.Regexp{
+	// Headers, as in "Cookie: a=b" or "X-CSRF-Token: abc"
+	regexp.MustCompile(`(?i)(\b(?:proxy-)?authorization|\bset-cookie|\bcookie|\bx-[cx]srf-token)(\s*:\s*)([^"\r\n\\]+)`),
+	// Keys in JSON, YAML, logfmt, and URL queries, as in `"password":"abc"`
+	// or "email_token=abc"
+	regexp.MustCompile(`(?i)(\b[a-z_-]*(?:password|passwd|token|secret|api_?key|cookie)["']?)(\s*[:=]\s*["']?)([^"'&\s\\,;}]+)`),
+	// Bearer tokens outside of headers
+	regexp.MustCompile(`(?i)(\bbearer)(\s+)([a-z0-9\-._~+/]+=*)`),
+}
+
+// Redact replaces the values of credentials in the text with [Redacted],
+// such as headers with cookies and CSRF tokens, bearer tokens, and fields
+// named like passwords or tokens. It is applied to all logs and debug dumps,
+// so secrets are never written at any log level.
+func Redact(s string) string {
+	for _, pattern := range redactPatterns {
+		s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+			groups := pattern.FindStringSubmatch(match)
+			if strings.TrimSpace(groups[3]) == "" || groups[3] == Redacted {
+				return match
+			}
+			return groups[1] + groups[2] + Redacted
+		})
+	}
+	return s
+}
+
+// NewWriter returns a writer that passes each write through [Redact]
+// before writing it to w. Each write must be complete, such as a single log
+// line from zerolog, as secrets split between writes are not redacted.
+func NewWriter(w io.Writer) io.Writer {
+	return redactWriter{w: w}
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
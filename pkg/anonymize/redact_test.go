@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package anonymize
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+const secret = "s3cr3t-value"
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "cookie header",
+			text: "\t> Cookie: personio_session=" + secret + "; XSRF-TOKEN=" + secret,
+			want: "\t> Cookie: " + Redacted,
+		},
+		{
+			name: "escaped header in JSON",
+			text: `{"message":"Request:\n\t> X-CSRF-Token: ` + secret + `\n\t> Host: example"}`,
+			want: `{"message":"Request:\n\t> X-CSRF-Token: ` + Redacted + `\n\t> Host: example"}`,
+		},
+		{
+			name: "authorization header",
+			text: "Authorization: Bearer " + secret,
+			want: "Authorization: " + Redacted,
+		},
+		{
+			name: "bearer token",
+			text: "using bearer " + secret,
+			want: "using bearer " + Redacted,
+		},
+		{
+			name: "JSON field",
+			text: `{"password":"` + secret + `","email":"me@example.com"}`,
+			want: `{"password":"` + Redacted + `","email":"me@example.com"}`,
+		},
+		{
+			name: "form body",
+			text: "email=me%40example.com&password=" + secret,
+			want: "email=me%40example.com&password=" + Redacted,
+		},
+		{
+			name: "logfmt field",
+			text: "INF Logged in. emailToken=" + secret + " employeeId=123",
+			want: "INF Logged in. emailToken=" + Redacted + " employeeId=123",
+		},
+		{
+			name: "no secrets",
+			text: "INF Logged in. employeeId=123",
+			want: "INF Logged in. employeeId=123",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Redact(tc.text); got != tc.want {
+				t.Errorf("\nwant: %q\ngot:  %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewWriter_allLevels(t *testing.T) {
+	for _, level := range []zerolog.Level{zerolog.TraceLevel, zerolog.DebugLevel, zerolog.InfoLevel} {
+		for _, console := range []bool{false, true} {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			if console {
+				w = NewWriter(zerolog.ConsoleWriter{Out: &buf, NoColor: true})
+			}
+			logger := zerolog.New(w).Level(level)
+			logger.Trace().Msg("Request:\n\t> Cookie: " + secret)
+			logger.Debug().Str("password", secret).Msg("Logging in.")
+			logger.Info().Str("csrfToken", secret).Msg("Logged in.")
+			logger.Warn().Msg("Got Authorization: Bearer " + secret)
+			if strings.Contains(buf.String(), secret) {
+				t.Errorf("level %s, console %t: secret in logs:\n%s", level, console, buf.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package audit contains a local journal of attendance mutations sent to
+// Personio, including the failed ones, so they can be inspected and
+// replayed later.
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/applejag/rootless-personio/pkg/storage"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned by Find when no entry matches the ID.
+var ErrNotFound = errors.New("audit entry not found")
+
+// Entry is a single attendance mutation that was sent to Personio.
+type Entry struct {
+	ID         uuid.UUID         `json:"id"`
+	Time       time.Time         `json:"time"`
+	Command    string            `json:"command"`
+	EmployeeID int               `json:"employeeId,omitempty"`
+	Action     queue.Action      `json:"action"`
+	Day        string            `json:"day"` // ex: "2023-01-18"
+	Periods    []personio.Period `json:"periods,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	ReplayOf   *uuid.UUID        `json:"replayOf,omitempty"`
+}
+
+// NewEntry creates a new entry with a random ID for the operation.
+// A non-nil err marks the entry as failed.
+func NewEntry(command string, employeeID int, op queue.Operation, err error, now time.Time) Entry {
+	e := Entry{
+		ID:         uuid.New(),
+		Time:       now,
+		Command:    command,
+		EmployeeID: employeeID,
+		Action:     op.Action,
+		Day:        op.Day,
+		Periods:    op.Periods,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return e
+}
+
+// Failed returns true if the mutation was not accepted by Personio.
+func (e Entry) Failed() bool {
+	return e.Error != ""
+}
+
+// Operation returns the mutation of the entry, so it can be sent again.
+func (e Entry) Operation() queue.Operation {
+	return queue.Operation{
+		ID:       e.ID,
+		QueuedAt: e.Time,
+		Action:   e.Action,
+		Day:      e.Day,
+		Periods:  e.Periods,
+	}
+}
+
+// DefaultKey is the storage key of the audit journal, which in the
+// filesystem storage is inside the user's config directory, e.g
+// ~/.config/rootless-personio/audit.jsonl
+const DefaultKey = "audit.jsonl"
+
+// Append adds entries to the end of the journal at the storage key.
+func Append(store storage.Backend, key string, entries ...Entry) error {
+	records := make([][]byte, 0, len(entries))
+	for _, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("write audit journal: %w", err)
+		}
+		records = append(records, b)
+	}
+	if err := store.Append(key, records...); err != nil {
+		return fmt.Errorf("write audit journal: %w", err)
+	}
+	return nil
+}
+
+// Load reads all entries, in the order they were recorded.
+// A missing journal results in no entries.
+func Load(store storage.Backend, key string) ([]Entry, error) {
+	records, err := store.Records(key)
+	if err != nil {
+		return nil, fmt.Errorf("read audit journal: %w", err)
+	}
+	entries := make([]Entry, 0, len(records))
+	for i, r := range records {
+		var e Entry
+		if err := json.Unmarshal(r, &e); err != nil {
+			return nil, fmt.Errorf("parse audit journal entry %d: %w", i+1, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Find returns the entry whose ID starts with the given prefix, so the
+// short IDs printed by "audit list" can be used. The prefix must only
+// match a single entry.
+func Find(entries []Entry, idPrefix string) (Entry, error) {
+	idPrefix = strings.ToLower(strings.TrimSpace(idPrefix))
+	if idPrefix == "" {
+		return Entry{}, fmt.Errorf("%w: empty ID", ErrNotFound)
+	}
+	var found []Entry
+	for _, e := range entries {
+		if strings.HasPrefix(e.ID.String(), idPrefix) {
+			found = append(found, e)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Entry{}, fmt.Errorf("%w: %q", ErrNotFound, idPrefix)
+	case 1:
+		return found[0], nil
+	default:
+		return Entry{}, fmt.Errorf("ambiguous audit entry ID %q matches %d entries, use a longer prefix", idPrefix, len(found))
+	}
+}
+
+// IsReplayed returns true if a later successful entry replays the entry.
+func IsReplayed(entries []Entry, id uuid.UUID) bool {
+	for _, e := range entries {
+		if e.ReplayOf != nil && *e.ReplayOf == id && !e.Failed() {
+			return true
+		}
+	}
+	return false
+}
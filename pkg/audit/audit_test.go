@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package audit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/queue"
+	"github.com/applejag/rootless-personio/pkg/storage"
+)
+
+func TestAppendLoadFind(t *testing.T) {
+	store := storage.NewFS(t.TempDir())
+	entries, err := Load(store, DefaultKey)
+	if err != nil {
+		t.Fatalf("load missing journal: %s", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("want empty journal, got %d entries", len(entries))
+	}
+
+	op := queue.NewOperation(queue.ActionRemove, "2023-01-18", nil, time.Now())
+	failed := NewEntry("attendance remove", 123, op, errors.New("session expired"), time.Now())
+	if err := Append(store, DefaultKey, failed); err != nil {
+		t.Fatalf("append failed entry: %s", err)
+	}
+	replay := NewEntry("audit replay", 123, failed.Operation(), nil, time.Now())
+	replay.ReplayOf = &failed.ID
+	if err := Append(store, DefaultKey, replay); err != nil {
+		t.Fatalf("append replay entry: %s", err)
+	}
+
+	entries, err = Load(store, DefaultKey)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if len(entries) != 2 || entries[0].ID != failed.ID || entries[1].ID != replay.ID {
+		t.Fatalf("want entries in recorded order, got %+v", entries)
+	}
+	if !entries[0].Failed() || entries[1].Failed() {
+		t.Errorf("want only first entry failed, got %q and %q", entries[0].Error, entries[1].Error)
+	}
+	if !IsReplayed(entries, failed.ID) {
+		t.Error("want failed entry marked as replayed")
+	}
+
+	found, err := Find(entries, failed.ID.String()[:8])
+	if err != nil {
+		t.Fatalf("find by prefix: %s", err)
+	}
+	if found.ID != failed.ID {
+		t.Errorf("want %s, got %s", failed.ID, found.ID)
+	}
+	if _, err := Find(entries, "zzz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("want ErrNotFound, got %v", err)
+	}
+}
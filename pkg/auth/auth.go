@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package auth contains the different ways of logging in to Personio, so
+// company-specific login flows can be added without changing the client.
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrNoCookies = errors.New("no cookies")
+
+// Provider logs in a Personio client.
+type Provider interface {
+	// Login logs in the client, so it's ready to send requests.
+	Login(client *personio.Client) error
+}
+
+// PasswordProvider logs in using email and password, same as when using
+// Personio's login form.
+type PasswordProvider struct {
+	Email    string
+	Password string
+}
+
+// Login implements [Provider].
+func (p PasswordProvider) Login(client *personio.Client) error {
+	return client.Login(p.Email, p.Password)
+}
+
+// CookieProvider logs in by reusing a logged in session from a web browser.
+type CookieProvider struct {
+	// Cookie is the value of the "Cookie" HTTP header, as copied from the
+	// web browser's developer tools, e.g "personio_session=abc; XSRF-TOKEN=def"
+	Cookie string
+}
+
+// Login implements [Provider].
+func (p CookieProvider) Login(client *personio.Client) error {
+	cookies := ParseCookieHeader(p.Cookie)
+	if len(cookies) == 0 {
+		return ErrNoCookies
+	}
+	if err := client.SetCookies(cookies); err != nil {
+		return err
+	}
+	return client.ResumeSession()
+}
+
+// SSOProvider logs in via a company's single sign-on (SSO), by opening
+// Personio's login page in the web browser, and then asking the user for
+// the logged in session's cookies.
+type SSOProvider struct {
+	// OpenURL opens the URL in the user's web browser.
+	OpenURL func(url string) error
+	// PromptCookie asks the user for the "Cookie" HTTP header value from
+	// the web browser, after having logged in.
+	PromptCookie func() (string, error)
+}
+
+// Login implements [Provider].
+func (p SSOProvider) Login(client *personio.Client) error {
+	if err := p.OpenURL(client.BaseURL); err != nil {
+		log.Warn().Err(err).Str("url", client.BaseURL).
+			Msg("Failed to open web browser. Please open the URL manually.")
+	}
+	cookie, err := p.PromptCookie()
+	if err != nil {
+		return err
+	}
+	return CookieProvider{Cookie: cookie}.Login(client)
+}
+
+// HeadlessBrowserProvider logs in by running an external command, such as a
+// headless browser script, which logs in and then prints the session's
+// cookies to STDOUT as a "Cookie" HTTP header value.
+//
+// The command gets Personio's base URL, and the email and password, via the
+// PERSONIO_BASEURL, PERSONIO_AUTH_EMAIL, and PERSONIO_AUTH_PASSWORD
+// environment variables.
+type HeadlessBrowserProvider struct {
+	Command  []string
+	Email    string
+	Password string
+}
+
+// Login implements [Provider].
+func (p HeadlessBrowserProvider) Login(client *personio.Client) error {
+	if len(p.Command) == 0 {
+		return errors.New("missing headless browser command")
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"PERSONIO_BASEURL="+client.BaseURL,
+		"PERSONIO_AUTH_EMAIL="+p.Email,
+		"PERSONIO_AUTH_PASSWORD="+p.Password,
+	)
+	log.Debug().
+		Str("command", strings.Join(p.Command, " ")).
+		Msg("Running headless browser command.")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run headless browser command: %w", err)
+	}
+	return CookieProvider{Cookie: stdout.String()}.Login(client)
+}
+
+// ParseCookieHeader parses the value of a "Cookie" HTTP header. A leading
+// "Cookie:" is ignored, to allow pasting the whole header line.
+func ParseCookieHeader(header string) []*http.Cookie {
+	header = strings.TrimSpace(header)
+	if name, value, ok := strings.Cut(header, ":"); ok && strings.EqualFold(name, "cookie") {
+		header = strings.TrimSpace(value)
+	}
+	req := http.Request{Header: http.Header{"Cookie": []string{header}}}
+	return req.Cookies()
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCookieHeader(t *testing.T) {
+	cookies := ParseCookieHeader("Cookie: personio_session=abc; XSRF-TOKEN=def%3D\n")
+	want := map[string]string{"personio_session": "abc", "XSRF-TOKEN": "def%3D"}
+	if len(cookies) != len(want) {
+		t.Fatalf("want %d cookies, got %d: %v", len(want), len(cookies), cookies)
+	}
+	for _, c := range cookies {
+		if want[c.Name] != c.Value {
+			t.Errorf("cookie %q: want %q, got %q", c.Name, want[c.Name], c.Value)
+		}
+	}
+}
+
+func TestParseCookiesFile(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		data string
+		want map[string]string
+	}{
+		{
+			name: "netscape",
+			data: "# Netscape HTTP Cookie File\n" +
+				"#HttpOnly_.example.personio.de\tTRUE\t/\tTRUE\t1893456000\tpersonio_session\tabc\n" +
+				"example.personio.de\tFALSE\t/\tTRUE\t1893456000\tremembered_device\tdef\n" +
+				"example.personio.de\tFALSE\t/\tTRUE\t1000000000\texpired\tx\n" +
+				".google.com\tTRUE\t/\tTRUE\t1893456000\tother_site\tx\n",
+			want: map[string]string{"personio_session": "abc", "remembered_device": "def"},
+		},
+		{
+			name: "browser extension json",
+			data: `[{"name": "personio_session", "value": "abc", "domain": ".personio.de", "session": true},
+				{"name": "remembered_device", "value": "def", "domain": "example.personio.de", "expirationDate": 1893456000.5}]`,
+			want: map[string]string{"personio_session": "abc", "remembered_device": "def"},
+		},
+		{
+			name: "playwright storage state",
+			data: `{"cookies": [{"name": "personio_session", "value": "abc", "domain": "example.personio.de", "expires": -1}], "origins": []}`,
+			want: map[string]string{"personio_session": "abc"},
+		},
+		{
+			name: "cookie header",
+			data: "personio_session=abc; remembered_device=def",
+			want: map[string]string{"personio_session": "abc", "remembered_device": "def"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cookies, err := ParseCookiesFile([]byte(tc.data), "example.personio.de", now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string, len(cookies))
+			for _, c := range cookies {
+				got[c.Name] = c.Value
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want cookies %v, got %v", tc.want, got)
+			}
+			for name, value := range tc.want {
+				if got[name] != value {
+					t.Errorf("cookie %q: want %q, got %q", name, value, got[name])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseCookiesFile parses exported cookies, and returns the unexpired ones
+// that are sent to the host. Supported formats are:
+//
+//   - Netscape "cookies.txt", as exported by curl and browser extensions
+//   - JSON, either a list of cookies as exported by browser extensions,
+//     or a Playwright/Puppeteer storage state with a "cookies" field
+//   - The value of a "Cookie" HTTP header
+func ParseCookiesFile(data []byte, host string, now time.Time) ([]*http.Cookie, error) {
+	data = bytes.TrimSpace(data)
+	var cookies []*http.Cookie
+	var err error
+	switch {
+	case len(data) == 0:
+		return nil, ErrNoCookies
+	case data[0] == '[' || data[0] == '{':
+		cookies, err = parseJSONCookies(data)
+	case isNetscapeCookies(data):
+		cookies, err = parseNetscapeCookies(data)
+	default:
+		cookies = ParseCookieHeader(string(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var kept []*http.Cookie
+	for _, c := range cookies {
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			continue
+		}
+		if c.Domain != "" && !domainMatch(host, c.Domain) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoCookies, host)
+	}
+	return kept, nil
+}
+
+func isNetscapeCookies(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("# Netscape HTTP Cookie File")) ||
+		bytes.HasPrefix(data, []byte("# HTTP Cookie File")) {
+		return true
+	}
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	return bytes.Count(firstLine, []byte("\t")) == 6
+}
+
+func parseNetscapeCookies(data []byte) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(line, "#HttpOnly_"); ok {
+			line = rest
+			httpOnly = true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("cookies file line %d: want 7 tab-separated fields, got %d", lineNum, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cookies file line %d: expires: %w", lineNum, err)
+		}
+		c := &http.Cookie{
+			Domain:   strings.TrimPrefix(fields[0], "."),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+		if expires > 0 {
+			c.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, scanner.Err()
+}
+
+// jsonCookie is a cookie as exported by the different browser extensions
+// and automation tools, which disagree on the name and type of the expiry.
+type jsonCookie struct {
+	Name           string          `json:"name"`
+	Value          string          `json:"value"`
+	Domain         string          `json:"domain"`
+	Path           string          `json:"path"`
+	Secure         bool            `json:"secure"`
+	HTTPOnly       bool            `json:"httpOnly"`
+	Expires        json.RawMessage `json:"expires"`
+	ExpirationDate float64         `json:"expirationDate"`
+	Session        bool            `json:"session"`
+}
+
+func parseJSONCookies(data []byte) ([]*http.Cookie, error) {
+	var list []jsonCookie
+	if data[0] == '{' {
+		var state struct {
+			Cookies []jsonCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("parse cookies file: %w", err)
+		}
+		list = state.Cookies
+	} else if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse cookies file: %w", err)
+	}
+	cookies := make([]*http.Cookie, 0, len(list))
+	for i, jc := range list {
+		if jc.Name == "" {
+			return nil, fmt.Errorf("parse cookies file: cookie #%d: missing name", i+1)
+		}
+		c := &http.Cookie{
+			Name:     jc.Name,
+			Value:    jc.Value,
+			Domain:   strings.TrimPrefix(jc.Domain, "."),
+			Path:     jc.Path,
+			Secure:   jc.Secure,
+			HttpOnly: jc.HTTPOnly,
+		}
+		expires, err := jc.expires()
+		if err != nil {
+			return nil, fmt.Errorf("parse cookies file: cookie %q: %w", jc.Name, err)
+		}
+		c.Expires = expires
+		cookies = append(cookies, c)
+	}
+	return cookies, nil
+}
+
+// expires returns the cookie's expiry, or zero for session cookies.
+func (c jsonCookie) expires() (time.Time, error) {
+	if c.Session {
+		return time.Time{}, nil
+	}
+	if c.ExpirationDate > 0 {
+		return unixFloat(c.ExpirationDate), nil
+	}
+	if len(c.Expires) == 0 || string(c.Expires) == "null" {
+		return time.Time{}, nil
+	}
+	var seconds float64
+	if err := json.Unmarshal(c.Expires, &seconds); err == nil {
+		// Playwright uses -1 for session cookies
+		if seconds <= 0 {
+			return time.Time{}, nil
+		}
+		return unixFloat(seconds), nil
+	}
+	var t time.Time
+	if err := json.Unmarshal(c.Expires, &t); err != nil {
+		return time.Time{}, fmt.Errorf("expires: %w", err)
+	}
+	return t, nil
+}
+
+func unixFloat(seconds float64) time.Time {
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9))
+}
+
+// domainMatch returns true if cookies of the domain are sent to the host.
+func domainMatch(host, domain string) bool {
+	host = strings.ToLower(host)
+	domain = strings.ToLower(domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
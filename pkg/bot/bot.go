@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bot
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Message is a chat message received by the bot.
+type Message struct {
+	// Chat is where to send the reply, such as a room or chat ID.
+	Chat   string
+	Sender string
+	Text   string
+}
+
+// Transport receives and sends chat messages.
+type Transport interface {
+	// Receive waits for new messages, typically by long polling.
+	Receive(ctx context.Context) ([]Message, error)
+	Send(ctx context.Context, chat, text string) error
+}
+
+// retryDelay is how long to wait after failing to receive messages.
+const retryDelay = 5 * time.Second
+
+// Run replies to the messages from the allowed sender, until the context
+// is cancelled. Messages from anyone else are ignored.
+func Run(ctx context.Context, t Transport, d *Dispatcher, allowed func(m Message) bool) error {
+	for {
+		messages, err := t.Receive(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed receiving messages, retrying.")
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+		for _, m := range messages {
+			if !allowed(m) {
+				log.Debug().Str("sender", m.Sender).Msg("Ignoring message from unknown sender.")
+				continue
+			}
+			reply := d.Handle(m.Text, time.Now())
+			if err := t.Send(ctx, m.Chat, reply); err != nil {
+				log.Warn().Err(err).Str("chat", m.Chat).Msg("Failed sending reply.")
+			}
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package bot contains the chat bot frontend, which accepts commands such
+// as "log 9-17:30 today" via Matrix or Telegram, and dispatches them to a
+// [Backend] implemented by the CLI's command layer.
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/google/uuid"
+)
+
+// Backend carries out the bot's commands.
+type Backend interface {
+	// Log adds the periods to the day, and returns all periods of that day.
+	Log(date time.Time, periods []personio.Period) ([]personio.Period, error)
+	// Worked returns the time worked on the day, excluding breaks.
+	Worked(date time.Time) (time.Duration, error)
+	// Vacation returns the vacation balance of the year of the given time.
+	Vacation(now time.Time) (report.VacationBalance, error)
+}
+
+// Help is the reply to "help" and to messages the bot doesn't understand.
+const Help = `Commands:
+  log 9-17:30 today        add work, also "log 8-12 13-17 yesterday"
+  today                    show today's worked time
+  vacation                 show how many vacation days are left
+  help                     show this help`
+
+// Dispatcher parses messages into commands, and carries them out using
+// the backend.
+type Dispatcher struct {
+	Backend Backend
+}
+
+// Handle carries out the command in the message, and returns the reply.
+func (d *Dispatcher) Handle(text string, now time.Time) string {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) == 0 {
+		return Help
+	}
+	// Telegram commands look like "/log" or "/log@mybot"
+	command, _, _ := strings.Cut(strings.TrimPrefix(words[0], "/"), "@")
+	args := words[1:]
+
+	switch {
+	case command == "log":
+		return d.log(args, now)
+	case command == "help" || command == "start":
+		return Help
+	case containsAny(words, "vacation", "urlaub"):
+		return d.vacation(now)
+	case command == "today" || command == "status" || containsAny(words, "today"):
+		return d.today(now)
+	default:
+		return "Sorry, I didn't understand that.\n\n" + Help
+	}
+}
+
+func (d *Dispatcher) log(args []string, now time.Time) string {
+	date, periods, err := ParseLog(args, now)
+	if err != nil {
+		return fmt.Sprintf("Could not log that: %s\n\nExample: log 9-17:30 today", err)
+	}
+	dayPeriods, err := d.Backend.Log(date, periods)
+	if err != nil {
+		return fmt.Sprintf("Failed logging: %s", err)
+	}
+	ranges := make([]string, len(periods))
+	for i, p := range periods {
+		ranges[i] = p.Start.Format("15:04") + "-" + p.End.Format("15:04")
+	}
+	return fmt.Sprintf("Logged %s on %s, with %s worked that day.",
+		strings.Join(ranges, ", "), date.Format(time.DateOnly), formatDuration(workedDuration(dayPeriods)))
+}
+
+func (d *Dispatcher) today(now time.Time) string {
+	worked, err := d.Backend.Worked(now)
+	if err != nil {
+		return fmt.Sprintf("Failed getting today's attendance: %s", err)
+	}
+	return fmt.Sprintf("You have worked %s today.", formatDuration(worked))
+}
+
+func (d *Dispatcher) vacation(now time.Time) string {
+	b, err := d.Backend.Vacation(now)
+	if err != nil {
+		return fmt.Sprintf("Failed getting your vacation balance: %s", err)
+	}
+	return fmt.Sprintf("You have %s vacation days left in %d (%s taken, %s planned, of %s).",
+		formatDays(b.RemainingDays), b.Year,
+		formatDays(b.TakenDays), formatDays(b.PlannedDays), formatDays(b.EntitlementDays))
+}
+
+var timeRangeRegex = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?-(\d{1,2})(?:[:.](\d{2}))?$`)
+
+// ParseLog parses the arguments of the "log" command, which are one or
+// more time ranges like "9-17:30", and optionally a date like "today",
+// "yesterday", "monday", or "2023-01-18". The date defaults to today.
+func ParseLog(args []string, now time.Time) (time.Time, []personio.Period, error) {
+	year, month, day := now.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	type timeRange struct{ start, end time.Duration }
+	var ranges []timeRange
+	var dateSet bool
+	for _, arg := range args {
+		if m := timeRangeRegex.FindStringSubmatch(arg); m != nil {
+			start, err := parseTimeOfDay(m[1], m[2])
+			if err != nil {
+				return time.Time{}, nil, err
+			}
+			end, err := parseTimeOfDay(m[3], m[4])
+			if err != nil {
+				return time.Time{}, nil, err
+			}
+			if end <= start {
+				return time.Time{}, nil, fmt.Errorf("%s ends before it starts", arg)
+			}
+			ranges = append(ranges, timeRange{start, end})
+			continue
+		}
+		if dateSet {
+			return time.Time{}, nil, fmt.Errorf("unexpected %q", arg)
+		}
+		parsed, err := parseDate(arg, date)
+		if err != nil {
+			return time.Time{}, nil, err
+		}
+		date = parsed
+		dateSet = true
+	}
+	if len(ranges) == 0 {
+		return time.Time{}, nil, errors.New("missing time range")
+	}
+	periods := make([]personio.Period, len(ranges))
+	for i, r := range ranges {
+		periods[i] = personio.Period{
+			ID:         uuid.New(),
+			PeriodType: personio.PeriodTypeWork,
+			Start:      date.Add(r.start),
+			End:        date.Add(r.end),
+		}
+	}
+	return date, periods, nil
+}
+
+func parseTimeOfDay(hour, minute string) (time.Duration, error) {
+	h, err := strconv.Atoi(hour)
+	if err != nil {
+		return 0, err
+	}
+	var m int
+	if minute != "" {
+		if m, err = strconv.Atoi(minute); err != nil {
+			return 0, err
+		}
+	}
+	if h > 24 || m > 59 || (h == 24 && m > 0) {
+		return 0, fmt.Errorf("invalid time %s:%02d", hour, m)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// parseDate parses a date relative to today, where weekday names refer to
+// the most recent such day, including today.
+func parseDate(s string, today time.Time) (time.Time, error) {
+	switch s {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) {
+			daysAgo := (int(today.Weekday()) - int(d) + 7) % 7
+			return today.AddDate(0, 0, -daysAgo), nil
+		}
+	}
+	date, err := time.ParseInLocation(time.DateOnly, s, today.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown date %q", s)
+	}
+	return date, nil
+}
+
+// containsAny returns true if any of the words, ignoring punctuation, is
+// one of the targets.
+func containsAny(words []string, targets ...string) bool {
+	for _, w := range words {
+		w = strings.TrimRight(w, "?!.,")
+		for _, t := range targets {
+			if w == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func workedDuration(periods []personio.Period) time.Duration {
+	var total time.Duration
+	for _, p := range periods {
+		if p.PeriodType == personio.PeriodTypeBreak {
+			continue
+		}
+		total += p.End.Sub(p.Start)
+	}
+	return total
+}
+
+func formatDuration(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
+
+func formatDays(days float64) string {
+	return strconv.FormatFloat(days, 'f', -1, 64)
+}
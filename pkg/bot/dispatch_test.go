@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/report"
+)
+
+type fakeBackend struct {
+	logged []personio.Period
+}
+
+func (b *fakeBackend) Log(date time.Time, periods []personio.Period) ([]personio.Period, error) {
+	b.logged = append(b.logged, periods...)
+	return b.logged, nil
+}
+
+func (b *fakeBackend) Worked(date time.Time) (time.Duration, error) {
+	return 90 * time.Minute, nil
+}
+
+func (b *fakeBackend) Vacation(now time.Time) (report.VacationBalance, error) {
+	return report.VacationBalance{Year: 2023, EntitlementDays: 30, TakenDays: 10, PlannedDays: 5.5, RemainingDays: 14.5}, nil
+}
+
+func TestDispatcherHandle(t *testing.T) {
+	now := time.Date(2023, 1, 18, 20, 0, 0, 0, time.UTC) // Wednesday
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "log 9-17:30 today", want: "Logged 09:00-17:30 on 2023-01-18, with 8:30 worked that day."},
+		{text: "/log@mybot 8-12 13.00-17 monday", want: "Logged 08:00-12:00, 13:00-17:00 on 2023-01-16"},
+		{text: "log 17-9", want: "Could not log that: 17-9 ends before it starts"},
+		{text: "log 9-17 someday", want: `Could not log that: unknown date "someday"`},
+		{text: "How many vacation days left?", want: "You have 14.5 vacation days left in 2023 (10 taken, 5.5 planned, of 30)."},
+		{text: "today", want: "You have worked 1:30 today."},
+		{text: "what's up", want: "Sorry, I didn't understand that."},
+	}
+	for _, tc := range tests {
+		t.Run(tc.text, func(t *testing.T) {
+			d := &Dispatcher{Backend: &fakeBackend{}}
+			got := d.Handle(tc.text, now)
+			if !strings.HasPrefix(got, tc.want) {
+				t.Errorf("want reply starting with %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Matrix is a [Transport] using the Matrix client-server API, listening in
+// a single room.
+type Matrix struct {
+	// Homeserver is the URL of the bot account's homeserver,
+	// e.g "https://matrix.org".
+	Homeserver  string
+	AccessToken string
+	RoomID      string
+	// HTTP defaults to [http.DefaultClient]. Its timeout must be longer
+	// than the 30 second long polling.
+	HTTP *http.Client
+
+	since string
+	txnID int
+}
+
+type matrixSync struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []struct {
+					Type    string `json:"type"`
+					Sender  string `json:"sender"`
+					Content struct {
+						MsgType string `json:"msgtype"`
+						Body    string `json:"body"`
+					} `json:"content"`
+				} `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// Receive long polls for new messages in the room. The sender is the
+// Matrix user ID. Messages sent before the bot started are skipped.
+func (m *Matrix) Receive(ctx context.Context) ([]Message, error) {
+	query := url.Values{}
+	filter, err := json.Marshal(map[string]any{
+		"room": map[string]any{
+			"rooms":    []string{m.RoomID},
+			"timeline": map[string]any{"types": []string{"m.room.message"}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	query.Set("filter", string(filter))
+	if m.since == "" {
+		// Initial sync, only to get the position to sync from
+		query.Set("timeout", "0")
+	} else {
+		query.Set("since", m.since)
+		query.Set("timeout", "30000")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.url("/sync")+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var sync matrixSync
+	if err := m.do(req, &sync); err != nil {
+		return nil, fmt.Errorf("sync: %w", err)
+	}
+	initial := m.since == ""
+	m.since = sync.NextBatch
+	if initial {
+		return nil, nil
+	}
+	var messages []Message
+	for _, e := range sync.Rooms.Join[m.RoomID].Timeline.Events {
+		if e.Type != "m.room.message" || e.Content.MsgType != "m.text" {
+			continue
+		}
+		messages = append(messages, Message{
+			Chat:   m.RoomID,
+			Sender: e.Sender,
+			Text:   e.Content.Body,
+		})
+	}
+	return messages, nil
+}
+
+// Send sends the text to the room.
+func (m *Matrix) Send(ctx context.Context, room, text string) error {
+	body, err := json.Marshal(map[string]string{"msgtype": "m.text", "body": text})
+	if err != nil {
+		return err
+	}
+	m.txnID++
+	txnID := strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + strconv.Itoa(m.txnID)
+	path := "/rooms/" + url.PathEscape(room) + "/send/m.room.message/" + txnID
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, m.url(path), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := m.do(req, nil); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+	return nil
+}
+
+func (m *Matrix) url(path string) string {
+	return strings.TrimSuffix(m.Homeserver, "/") + "/_matrix/client/v3" + path
+}
+
+func (m *Matrix) do(req *http.Request, result any) error {
+	req.Header.Set("Authorization", "Bearer "+m.AccessToken)
+	client := m.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var matrixErr struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&matrixErr)
+		return fmt.Errorf("%w: %s: %s %s", ErrAPI, resp.Status, matrixErr.ErrCode, matrixErr.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
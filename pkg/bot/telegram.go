@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ErrAPI is returned when the chat service's API responds with an error.
+var ErrAPI = errors.New("chat API error")
+
+// Telegram is a [Transport] using the Telegram Bot API.
+type Telegram struct {
+	// Token is the bot's token, as given by @BotFather.
+	Token string
+	// BaseURL defaults to "https://api.telegram.org".
+	BaseURL string
+	// HTTP defaults to [http.DefaultClient]. Its timeout must be longer
+	// than the 30 second long polling.
+	HTTP *http.Client
+
+	offset int64
+}
+
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		From *struct {
+			ID int64 `json:"id"`
+		} `json:"from"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// Receive long polls for new messages. The sender is the Telegram user ID.
+func (t *Telegram) Receive(ctx context.Context) ([]Message, error) {
+	query := url.Values{}
+	query.Set("timeout", "30")
+	query.Set("allowed_updates", `["message"]`)
+	if t.offset != 0 {
+		query.Set("offset", strconv.FormatInt(t.offset, 10))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url("getUpdates")+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var updates []telegramUpdate
+	if err := t.do(req, &updates); err != nil {
+		return nil, fmt.Errorf("get updates: %w", err)
+	}
+	var messages []Message
+	for _, u := range updates {
+		t.offset = u.UpdateID + 1
+		if u.Message == nil || u.Message.From == nil || u.Message.Text == "" {
+			continue
+		}
+		messages = append(messages, Message{
+			Chat:   strconv.FormatInt(u.Message.Chat.ID, 10),
+			Sender: strconv.FormatInt(u.Message.From.ID, 10),
+			Text:   u.Message.Text,
+		})
+	}
+	return messages, nil
+}
+
+// Send sends the text to the chat ID.
+func (t *Telegram) Send(ctx context.Context, chat, text string) error {
+	body, err := json.Marshal(map[string]string{"chat_id": chat, "text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url("sendMessage"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := t.do(req, nil); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+	return nil
+}
+
+func (t *Telegram) url(method string) string {
+	base := t.BaseURL
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	return base + "/bot" + t.Token + "/" + method
+}
+
+func (t *Telegram) do(req *http.Request, result any) error {
+	client := t.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL contains the token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	var tr telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+	if !tr.OK {
+		return fmt.Errorf("%w: %s", ErrAPI, tr.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(tr.Result, result)
+}
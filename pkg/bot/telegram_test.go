@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTelegram(t *testing.T) {
+	var offsets []string
+	var sent map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/botsecret/getUpdates":
+			offsets = append(offsets, r.URL.Query().Get("offset"))
+			fmt.Fprint(w, `{"ok":true,"result":[
+				{"update_id":41,"message":{"from":{"id":7},"chat":{"id":99},"text":"today"}},
+				{"update_id":42,"edited_message":{}}
+			]}`)
+		case "/botsecret/sendMessage":
+			json.NewDecoder(r.Body).Decode(&sent)
+			fmt.Fprint(w, `{"ok":true,"result":{}}`)
+		default:
+			fmt.Fprint(w, `{"ok":false,"description":"Not Found"}`)
+		}
+	}))
+	defer srv.Close()
+
+	tg := &Telegram{Token: "secret", BaseURL: srv.URL}
+	ctx := context.Background()
+	messages, err := tg.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0] != (Message{Chat: "99", Sender: "7", Text: "today"}) {
+		t.Errorf("unexpected messages: %+v", messages)
+	}
+	if _, err := tg.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(offsets) != 2 || offsets[0] != "" || offsets[1] != "43" {
+		t.Errorf("want offsets [\"\" 43], got %q", offsets)
+	}
+
+	if err := tg.Send(ctx, "99", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if sent["chat_id"] != "99" || sent["text"] != "hello" {
+		t.Errorf("unexpected sent message: %v", sent)
+	}
+}
@@ -0,0 +1,211 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package caldav contains a minimal CalDAV client, which writes events
+// into a calendar collection on a CalDAV server, such as Nextcloud,
+// Fastmail, iCloud, or Google Calendar, and a minimal read-only CalDAV
+// server, which calendar clients can subscribe to.
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/ical"
+)
+
+// ErrUnexpectedStatus is returned when the server responds with an
+// unexpected status code.
+var ErrUnexpectedStatus = errors.New("unexpected status code")
+
+// Client writes to a single calendar collection.
+type Client struct {
+	// URL is the URL of the calendar collection,
+	// e.g "https://cloud.example.com/remote.php/dav/calendars/me/vacation/"
+	URL string
+	// HTTP is the client used to send the requests.
+	// Defaults to [http.DefaultClient].
+	HTTP *http.Client
+	// Authorize is called on each request, to add authentication headers.
+	Authorize func(req *http.Request) error
+}
+
+// Put creates or replaces the event, stored as "<UID>.ics".
+func (c *Client) Put(event ical.Event, stamp time.Time) error {
+	var buf bytes.Buffer
+	if err := event.Write(&buf, stamp); err != nil {
+		return err
+	}
+	req, err := c.newRequest(http.MethodPut, resourceName(event.UID), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	resp, err := c.do(req, http.StatusCreated, http.StatusNoContent, http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("put event %s: %w", event.UID, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Delete removes the resource, where a missing resource is not an error.
+func (c *Client) Delete(name string) error {
+	req, err := c.newRequest(http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return fmt.Errorf("delete %s: %w", name, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List returns the names of all resources in the collection.
+func (c *Client) List() ([]string, error) {
+	body := strings.NewReader(`<?xml version="1.0" encoding="utf-8"?>` +
+		`<d:propfind xmlns:d="DAV:"><d:prop><d:getetag/></d:prop></d:propfind>`)
+	req, err := c.newRequest("PROPFIND", "", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := c.do(req, http.StatusMultiStatus)
+	if err != nil {
+		return nil, fmt.Errorf("list collection: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var ms struct {
+		Responses []struct {
+			Href string `xml:"href"`
+		} `xml:"response"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("parse collection listing: %w", err)
+	}
+	collection, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, r := range ms.Responses {
+		href, err := url.PathUnescape(r.Href)
+		if err != nil {
+			continue
+		}
+		if u, err := url.Parse(href); err == nil && u.Path != "" {
+			href = u.Path
+		}
+		if strings.TrimSuffix(href, "/") == strings.TrimSuffix(collection.Path, "/") {
+			// The collection itself
+			continue
+		}
+		names = append(names, path.Base(href))
+	}
+	return names, nil
+}
+
+// SyncResult is the outcome of [Client.Sync].
+type SyncResult struct {
+	Put     int `json:"put"`
+	Deleted int `json:"deleted"`
+}
+
+// Sync puts all events, and deletes the events previously created by the
+// [ical] package whose date is within the range but that are no longer
+// part of the events, such as cancelled absences. Other events in the
+// collection are left as-is.
+func (c *Client) Sync(events []ical.Event, start, end time.Time, stamp time.Time) (SyncResult, error) {
+	var result SyncResult
+	existing, err := c.List()
+	if err != nil {
+		return result, err
+	}
+	keep := make(map[string]bool, len(events))
+	for _, e := range events {
+		if err := c.Put(e, stamp); err != nil {
+			return result, err
+		}
+		keep[resourceName(e.UID)] = true
+		result.Put++
+	}
+	for _, name := range existing {
+		if keep[name] {
+			continue
+		}
+		date, ok := ical.EventDate(strings.TrimSuffix(name, ".ics"))
+		if !ok || date.Before(start) || date.After(end) {
+			continue
+		}
+		if err := c.Delete(name); err != nil {
+			return result, err
+		}
+		result.Deleted++
+	}
+	return result, nil
+}
+
+func resourceName(uid string) string {
+	return uid + ".ics"
+}
+
+func (c *Client) newRequest(method, name string, body io.Reader) (*http.Request, error) {
+	u := c.URL
+	if name != "" {
+		u = strings.TrimSuffix(u, "/") + "/" + url.PathEscape(name)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Authorize != nil {
+		if err := c.Authorize(req); err != nil {
+			return nil, fmt.Errorf("authorize: %w", err)
+		}
+	}
+	return req, nil
+}
+
+func (c *Client) do(req *http.Request, okStatuses ...int) (*http.Response, error) {
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range okStatuses {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+}
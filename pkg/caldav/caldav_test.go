@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/ical"
+)
+
+// fakeServer is an in-memory calendar collection at /cal/.
+type fakeServer struct {
+	mu        sync.Mutex
+	resources map[string]string
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := path.Base(r.URL.Path)
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		s.resources[name] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		delete(s.resources, name)
+		w.WriteHeader(http.StatusNoContent)
+	case "PROPFIND":
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">`)
+		fmt.Fprint(w, `<d:response><d:href>/cal/</d:href></d:response>`)
+		for name := range s.resources {
+			fmt.Fprintf(w, `<d:response><d:href>/cal/%s</d:href></d:response>`, name)
+		}
+		fmt.Fprint(w, `</d:multistatus>`)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *fakeServer) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestClientSync(t *testing.T) {
+	fake := &fakeServer{resources: map[string]string{
+		"personal-dentist.ics":                       "",
+		"rootless-personio-absence-2023-07-03-1.ics": "",
+		"rootless-personio-absence-2023-09-01-2.ics": "",
+		"rootless-personio-holiday-2022-12-26-3.ics": "",
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	var authorized int
+	c := &Client{
+		URL: srv.URL + "/cal/",
+		Authorize: func(req *http.Request) error {
+			authorized++
+			req.SetBasicAuth("me", "secret")
+			return nil
+		},
+	}
+	events := []ical.Event{{
+		UID:     "rootless-personio-absence-2023-07-10-4",
+		Summary: "Paid vacation",
+		Start:   time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC),
+		End:     time.Date(2023, 7, 11, 0, 0, 0, 0, time.UTC),
+		AllDay:  true,
+	}}
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	result, err := c.Sync(events, start, end, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Put != 1 || result.Deleted != 2 {
+		t.Errorf("want 1 put and 2 deleted, got %+v", result)
+	}
+	want := []string{
+		"personal-dentist.ics",
+		// Outside the range, so kept
+		"rootless-personio-holiday-2022-12-26-3.ics",
+		"rootless-personio-absence-2023-07-10-4.ics",
+	}
+	sort.Strings(want)
+	if got := fake.names(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("want resources %v, got %v", want, got)
+	}
+	if authorized == 0 {
+		t.Error("want requests to be authorized")
+	}
+}
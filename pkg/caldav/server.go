@@ -0,0 +1,342 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/ical"
+	"github.com/rs/zerolog/log"
+)
+
+// Handler serves the events as a read-only CalDAV calendar collection at
+// the root path, with each event as a "<UID>.ics" resource.
+//
+// A GET on the collection returns all events as a single iCalendar file,
+// for calendar clients that only support subscribing to a URL.
+type Handler struct {
+	// Name is the display name of the calendar.
+	Name string
+	// Events returns the events to serve, and is called on each request.
+	Events func() ([]ical.Event, error)
+	// Stamp is used as the DTSTAMP of all events. It is kept fixed, so the
+	// ETags only change when the events change.
+	Stamp time.Time
+	// Username and Password enables HTTP basic authentication, when set.
+	Username string
+	Password string
+	// BearerAuth enables bearer token authentication, when set, such as
+	// with OpenID Connect tokens. It returns an error if the token is
+	// rejected.
+	BearerAuth func(r *http.Request, token string) error
+}
+
+// resource is an event as served by the [Handler].
+type resource struct {
+	name string
+	etag string
+	data []byte
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		if h.Username != "" || h.Password != "" {
+			w.Header().Add("WWW-Authenticate", `Basic realm="rootless-personio"`)
+		}
+		if h.BearerAuth != nil {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="rootless-personio"`)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("DAV", "1, calendar-access")
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, REPORT")
+		w.WriteHeader(http.StatusOK)
+		return
+	case http.MethodGet, http.MethodHead, "PROPFIND", "REPORT":
+	default:
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, REPORT")
+		http.Error(w, "The calendar is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	events, err := h.Events()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed getting calendar events.")
+		http.Error(w, "Failed getting calendar events", http.StatusInternalServerError)
+		return
+	}
+	resources, err := h.resources(events)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed writing calendar events.")
+		http.Error(w, "Failed writing calendar events", http.StatusInternalServerError)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if name != "" {
+		res, ok := findResource(resources, name)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		resources = []resource{res}
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if name != "" {
+			w.Header().Set("ETag", resources[0].etag)
+			w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+			w.Write(resources[0].data)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		if err := (ical.Calendar{Name: h.Name, Events: events}).Write(w, h.Stamp); err != nil {
+			log.Warn().Err(err).Msg("Failed writing calendar.")
+		}
+	case "PROPFIND":
+		ms := multistatus{}
+		if name == "" {
+			ms.Responses = append(ms.Responses, h.collectionResponse(resources))
+			if r.Header.Get("Depth") == "0" {
+				resources = nil
+			}
+		}
+		for _, res := range resources {
+			ms.Responses = append(ms.Responses, res.response(false))
+		}
+		writeMultistatus(w, ms)
+	case "REPORT":
+		hrefs, err := reportHrefs(r.Body)
+		if err != nil {
+			http.Error(w, "Invalid REPORT body", http.StatusBadRequest)
+			return
+		}
+		ms := multistatus{}
+		if len(hrefs) > 0 {
+			// calendar-multiget
+			for _, href := range hrefs {
+				res, ok := findResource(resources, path.Base(href))
+				if !ok {
+					ms.Responses = append(ms.Responses, response{
+						Href:   href,
+						Status: "HTTP/1.1 404 Not Found",
+					})
+					continue
+				}
+				ms.Responses = append(ms.Responses, res.response(true))
+			}
+		} else {
+			// calendar-query, where all events match
+			for _, res := range resources {
+				ms.Responses = append(ms.Responses, res.response(true))
+			}
+		}
+		writeMultistatus(w, ms)
+	}
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	basic := h.Username != "" || h.Password != ""
+	if !basic && h.BearerAuth == nil {
+		return true
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && h.BearerAuth != nil {
+		if err := h.BearerAuth(r, token); err != nil {
+			log.Debug().Err(err).Msg("Rejected CalDAV bearer token.")
+			return false
+		}
+		return true
+	}
+	if !basic {
+		return false
+	}
+	username, password, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(h.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(h.Password)) == 1
+}
+
+func (h *Handler) resources(events []ical.Event) ([]resource, error) {
+	resources := make([]resource, 0, len(events))
+	for _, e := range events {
+		var buf bytes.Buffer
+		if err := e.Write(&buf, h.Stamp); err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource{
+			name: resourceName(e.UID),
+			etag: etag(buf.Bytes()),
+			data: buf.Bytes(),
+		})
+	}
+	return resources, nil
+}
+
+func (h *Handler) collectionResponse(resources []resource) response {
+	// The collection's tag changes whenever any event changes
+	hash := sha256.New()
+	for _, res := range resources {
+		io.WriteString(hash, res.name+res.etag)
+	}
+	return response{
+		Href: "/",
+		Propstat: &propstat{
+			Prop: prop{
+				ResourceType: &resourceType{Collection: &struct{}{}, Calendar: &struct{}{}},
+				DisplayName:  h.Name,
+				CTag:         `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`,
+				SupportedComponents: &supportedComponents{
+					Comps: []comp{{Name: "VEVENT"}},
+				},
+				Privileges: &privileges{Privilege: privilege{Read: &struct{}{}}},
+			},
+			Status: "HTTP/1.1 200 OK",
+		},
+	}
+}
+
+func (res resource) response(withData bool) response {
+	p := prop{
+		ResourceType: &resourceType{},
+		ETag:         res.etag,
+		ContentType:  "text/calendar; charset=utf-8; component=VEVENT",
+	}
+	if withData {
+		p.CalendarData = string(res.data)
+	}
+	return response{
+		Href: "/" + res.name,
+		Propstat: &propstat{
+			Prop:   p,
+			Status: "HTTP/1.1 200 OK",
+		},
+	}
+}
+
+func findResource(resources []resource, name string) (resource, bool) {
+	for _, res := range resources {
+		if res.name == name {
+			return res, true
+		}
+	}
+	return resource{}, false
+}
+
+func etag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// reportHrefs returns the hrefs of a calendar-multiget REPORT, or none for
+// other reports.
+func reportHrefs(body io.Reader) ([]string, error) {
+	var report struct {
+		XMLName xml.Name
+		Hrefs   []string `xml:"DAV: href"`
+	}
+	if err := xml.NewDecoder(body).Decode(&report); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if report.XMLName.Local != "calendar-multiget" {
+		return nil, nil
+	}
+	return report.Hrefs, nil
+}
+
+type multistatus struct {
+	XMLName   xml.Name   `xml:"d:multistatus"`
+	XMLNSD    string     `xml:"xmlns:d,attr"`
+	XMLNSC    string     `xml:"xmlns:c,attr"`
+	XMLNSCS   string     `xml:"xmlns:cs,attr"`
+	Responses []response `xml:"d:response"`
+}
+
+type response struct {
+	Href     string    `xml:"d:href"`
+	Propstat *propstat `xml:"d:propstat,omitempty"`
+	Status   string    `xml:"d:status,omitempty"`
+}
+
+type propstat struct {
+	Prop   prop   `xml:"d:prop"`
+	Status string `xml:"d:status"`
+}
+
+type prop struct {
+	ResourceType        *resourceType        `xml:"d:resourcetype,omitempty"`
+	DisplayName         string               `xml:"d:displayname,omitempty"`
+	CTag                string               `xml:"cs:getctag,omitempty"`
+	ETag                string               `xml:"d:getetag,omitempty"`
+	ContentType         string               `xml:"d:getcontenttype,omitempty"`
+	SupportedComponents *supportedComponents `xml:"c:supported-calendar-component-set,omitempty"`
+	Privileges          *privileges          `xml:"d:current-user-privilege-set,omitempty"`
+	CalendarData        string               `xml:"c:calendar-data,omitempty"`
+}
+
+type resourceType struct {
+	Collection *struct{} `xml:"d:collection,omitempty"`
+	Calendar   *struct{} `xml:"c:calendar,omitempty"`
+}
+
+type supportedComponents struct {
+	Comps []comp `xml:"c:comp"`
+}
+
+type comp struct {
+	Name string `xml:"name,attr"`
+}
+
+type privileges struct {
+	Privilege privilege `xml:"d:privilege"`
+}
+
+type privilege struct {
+	Read *struct{} `xml:"d:read,omitempty"`
+}
+
+func writeMultistatus(w http.ResponseWriter, ms multistatus) {
+	ms.XMLNSD = "DAV:"
+	ms.XMLNSC = "urn:ietf:params:xml:ns:caldav"
+	ms.XMLNSCS = "http://calendarserver.org/ns/"
+	body, err := xml.Marshal(ms)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed writing response: %s", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, xml.Header)
+	w.Write(body)
+}
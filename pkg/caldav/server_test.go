@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/ical"
+)
+
+func newTestHandler() *Handler {
+	return &Handler{
+		Name:  "Personio",
+		Stamp: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+		Events: func() ([]ical.Event, error) {
+			return []ical.Event{
+				{
+					UID:     "rootless-personio-absence-2023-07-03-1",
+					Summary: "Vacation",
+					Start:   time.Date(2023, 7, 3, 0, 0, 0, 0, time.UTC),
+					End:     time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC),
+					AllDay:  true,
+				},
+				{
+					UID:     "rootless-personio-period-2023-07-05-2",
+					Summary: "Work",
+					Start:   time.Date(2023, 7, 5, 8, 0, 0, 0, time.UTC),
+					End:     time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC),
+				},
+			}, nil
+		},
+	}
+}
+
+func TestHandlerList(t *testing.T) {
+	srv := httptest.NewServer(newTestHandler())
+	defer srv.Close()
+
+	client := &Client{URL: srv.URL + "/"}
+	names, err := client.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"rootless-personio-absence-2023-07-03-1.ics",
+		"rootless-personio-period-2023-07-05-2.ics",
+	}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("want %v, got %v", want, names)
+	}
+}
+
+func TestHandlerMultiget(t *testing.T) {
+	srv := httptest.NewServer(newTestHandler())
+	defer srv.Close()
+
+	body := `<?xml version="1.0"?>
+<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <d:href>/rootless-personio-period-2023-07-05-2.ics</d:href>
+  <d:href>/missing.ics</d:href>
+</c:calendar-multiget>`
+	req, err := http.NewRequest("REPORT", srv.URL+"/", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("want status %d, got %s", http.StatusMultiStatus, resp.Status)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{
+		"SUMMARY:Work",
+		"DTSTART:20230705T080000Z",
+		"HTTP/1.1 404 Not Found",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("want response to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(string(got), "SUMMARY:Vacation") {
+		t.Errorf("want only the requested events, got:\n%s", got)
+	}
+}
+
+func TestHandlerReadOnly(t *testing.T) {
+	srv := httptest.NewServer(newTestHandler())
+	defer srv.Close()
+
+	client := &Client{URL: srv.URL + "/"}
+	err := client.Put(ical.Event{UID: "new"}, time.Now())
+	if err == nil {
+		t.Fatal("want error when putting events, got nil")
+	}
+}
+
+func TestHandlerBasicAuth(t *testing.T) {
+	h := newTestHandler()
+	h.Username = "me"
+	h.Password = "secret"
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("want status %d without credentials, got %s", http.StatusUnauthorized, resp.Status)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/", nil)
+	req.SetBasicAuth("me", "secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(got), "X-WR-CALNAME:Personio") {
+		t.Errorf("want calendar with credentials, got %s:\n%s", resp.Status, got)
+	}
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package canary checks that logging in to Personio and reading from it
+// still works, for monitoring, and reports the result as Prometheus
+// metrics.
+package canary
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Step is a step of the check.
+type Step string
+
+// Steps of the check, in order.
+const (
+	StepConnect Step = "connect"
+	StepLogin   Step = "login"
+	StepRead    Step = "read"
+)
+
+// Steps are all steps of the check, in order.
+var Steps = []Step{StepConnect, StepLogin, StepRead}
+
+// Result is the outcome of a check.
+type Result struct {
+	Time time.Time
+	// Durations are how long each completed or failed step took.
+	Durations map[Step]time.Duration
+	// FailedStep is the step that failed, or empty on success.
+	FailedStep Step
+	Err        error
+}
+
+// Check runs the steps in order, stopping at the first failure.
+func Check(now time.Time, steps map[Step]func() error) Result {
+	r := Result{Time: now, Durations: make(map[Step]time.Duration)}
+	for _, step := range Steps {
+		f, ok := steps[step]
+		if !ok {
+			continue
+		}
+		start := time.Now()
+		err := f()
+		r.Durations[step] = time.Since(start)
+		if err != nil {
+			r.FailedStep = step
+			r.Err = err
+			break
+		}
+	}
+	return r
+}
+
+// OK returns true if all steps succeeded.
+func (r Result) OK() bool {
+	return r.FailedStep == ""
+}
+
+// String returns the result as a single line, e.g:
+//
+//	OK: connect 0.05s, login 1.20s, read 0.31s
+//	FAIL: login: invalid email or password
+func (r Result) String() string {
+	if !r.OK() {
+		return fmt.Sprintf("FAIL: %s: %s", r.FailedStep, r.Err)
+	}
+	var parts []string
+	for _, step := range Steps {
+		if d, ok := r.Durations[step]; ok {
+			parts = append(parts, fmt.Sprintf("%s %.2fs", step, d.Seconds()))
+		}
+	}
+	return "OK: " + strings.Join(parts, ", ")
+}
+
+// WriteMetrics writes the result in the Prometheus text format.
+func (r Result) WriteMetrics(w io.Writer) error {
+	var buf bytes.Buffer
+	success := 0
+	if r.OK() {
+		success = 1
+	}
+	fmt.Fprintln(&buf, "# HELP rootless_personio_canary_success Whether the last canary check succeeded.")
+	fmt.Fprintln(&buf, "# TYPE rootless_personio_canary_success gauge")
+	fmt.Fprintf(&buf, "rootless_personio_canary_success %d\n", success)
+	fmt.Fprintln(&buf, "# HELP rootless_personio_canary_step_success Whether each step of the last canary check succeeded, where skipped steps are 0.")
+	fmt.Fprintln(&buf, "# TYPE rootless_personio_canary_step_success gauge")
+	for _, step := range Steps {
+		stepSuccess := 0
+		if _, ok := r.Durations[step]; ok && step != r.FailedStep {
+			stepSuccess = 1
+		}
+		fmt.Fprintf(&buf, "rootless_personio_canary_step_success{step=%q} %d\n", step, stepSuccess)
+	}
+	fmt.Fprintln(&buf, "# HELP rootless_personio_canary_step_duration_seconds How long each step of the last canary check took.")
+	fmt.Fprintln(&buf, "# TYPE rootless_personio_canary_step_duration_seconds gauge")
+	for _, step := range Steps {
+		if d, ok := r.Durations[step]; ok {
+			fmt.Fprintf(&buf, "rootless_personio_canary_step_duration_seconds{step=%q} %g\n", step, d.Seconds())
+		}
+	}
+	fmt.Fprintln(&buf, "# HELP rootless_personio_canary_last_run_timestamp_seconds When the last canary check ran.")
+	fmt.Fprintln(&buf, "# TYPE rootless_personio_canary_last_run_timestamp_seconds gauge")
+	fmt.Fprintf(&buf, "rootless_personio_canary_last_run_timestamp_seconds %d\n", r.Time.Unix())
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Pushgateway pushes metrics to a Prometheus Pushgateway.
+type Pushgateway struct {
+	// URL is the base URL of the Pushgateway, e.g
+	// "http://pushgateway:9091".
+	URL string
+	// Job is the job label of the pushed metrics.
+	Job string
+	// Instance is the instance label of the pushed metrics, if set.
+	Instance string
+	// Client is used to push, or [http.DefaultClient] if nil.
+	Client *http.Client
+}
+
+// Push replaces the metrics of the job and instance with the result.
+func (p Pushgateway) Push(ctx context.Context, r Result) error {
+	var body bytes.Buffer
+	if err := r.WriteMetrics(&body); err != nil {
+		return err
+	}
+	u := strings.TrimSuffix(p.URL, "/") + "/metrics/job/" + url.PathEscape(p.Job)
+	if p.Instance != "" {
+		u += "/instance/" + url.PathEscape(p.Instance)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push metrics: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
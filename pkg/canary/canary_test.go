@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package canary
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	var read bool
+	r := Check(time.Unix(1700000000, 0), map[Step]func() error{
+		StepConnect: func() error { return nil },
+		StepLogin:   func() error { return errors.New("invalid email or password") },
+		StepRead:    func() error { read = true; return nil },
+	})
+	if r.OK() || r.FailedStep != StepLogin {
+		t.Fatalf("want login to fail, got %+v", r)
+	}
+	if read {
+		t.Error("want steps after the failure to be skipped")
+	}
+	if want := "FAIL: login: invalid email or password"; r.String() != want {
+		t.Errorf("want %q, got %q", want, r.String())
+	}
+}
+
+func TestPushgateway(t *testing.T) {
+	var gotPath, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+	}))
+	defer srv.Close()
+
+	r := Check(time.Unix(1700000000, 0), map[Step]func() error{
+		StepLogin: func() error { return nil },
+		StepRead:  func() error { return nil },
+	})
+	p := Pushgateway{URL: srv.URL + "/", Job: "canary", Instance: "ci"}
+	if err := p.Push(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/metrics/job/canary/instance/ci"; gotPath != want {
+		t.Errorf("want path %q, got %q", want, gotPath)
+	}
+	for _, want := range []string{
+		"rootless_personio_canary_success 1\n",
+		`rootless_personio_canary_step_success{step="connect"} 0` + "\n",
+		`rootless_personio_canary_step_success{step="read"} 1` + "\n",
+		"rootless_personio_canary_last_run_timestamp_seconds 1700000000\n",
+	} {
+		if !strings.Contains(gotBody, want) {
+			t.Errorf("want metrics to contain %q, got:\n%s", want, gotBody)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package chaos injects artificial latency, rate limiting, and session
+// expiries into HTTP requests, so the retry, re-login, and resume logic can
+// be tested during development without hammering a real Personio tenant.
+package chaos
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config is what to inject, and how often.
+type Config struct {
+	// Latency is added to every request.
+	Latency time.Duration
+	// Jitter is a random extra latency of up to this duration.
+	Jitter time.Duration
+	// RateLimit is the probability, from 0 to 1, of responding with
+	// HTTP 429 (Too Many Requests) instead of sending the request.
+	RateLimit float64
+	// RetryAfter is the Retry-After of the injected HTTP 429 responses.
+	RetryAfter time.Duration
+	// Expire is the probability, from 0 to 1, of the session expiring,
+	// after which all requests get the login page until logging in again.
+	Expire float64
+	// Seed makes the injected failures reproducible. Zero uses a random
+	// seed.
+	Seed int64
+}
+
+// ParseConfig parses comma-separated key=value pairs, e.g
+// "latency=200ms,jitter=100ms,429=0.1,expire=0.05,seed=1". The keys are:
+//
+//	latency      added latency of every request
+//	jitter       random extra latency of up to this duration
+//	429          probability of HTTP 429 (Too Many Requests)
+//	retry-after  Retry-After of the HTTP 429 responses, default 1s
+//	expire       probability of the session expiring
+//	seed         seed of the random failures, for reproducible runs
+func ParseConfig(s string) (Config, error) {
+	c := Config{RetryAfter: time.Second}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("%q: missing '=' between key and value", pair)
+		}
+		var err error
+		switch key {
+		case "latency":
+			c.Latency, err = time.ParseDuration(value)
+		case "jitter":
+			c.Jitter, err = time.ParseDuration(value)
+		case "429":
+			c.RateLimit, err = parseProbability(value)
+		case "retry-after":
+			c.RetryAfter, err = time.ParseDuration(value)
+		case "expire":
+			c.Expire, err = parseProbability(value)
+		case "seed":
+			c.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			err = fmt.Errorf("unknown key, must be one of: latency, jitter, 429, retry-after, expire, seed")
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return c, nil
+}
+
+func parseProbability(s string) (float64, error) {
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if p < 0 || p > 1 {
+		return 0, fmt.Errorf("probability must be between 0 and 1, got %g", p)
+	}
+	return p, nil
+}
+
+// Transport is an [http.RoundTripper] that injects the failures of its
+// config into the requests sent via its base transport.
+//
+// Requests to the login paths are never failed, as Personio locks the
+// account after repeated HTTP 429 responses on login, and logging in ends
+// an injected session expiry.
+type Transport struct {
+	Base   http.RoundTripper
+	Config Config
+	// LoginPaths are the suffixes of the paths of login requests.
+	LoginPaths []string
+	// Sleep waits for the latency, or [time.Sleep] if nil.
+	Sleep func(time.Duration)
+
+	mu      sync.Mutex
+	rand    *rand.Rand
+	expired bool
+}
+
+// NewTransport returns a transport that injects the failures of the config
+// into the requests sent via the base transport.
+func NewTransport(base http.RoundTripper, config Config, loginPaths ...string) *Transport {
+	return &Transport{Base: base, Config: config, LoginPaths: loginPaths}
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay, rateLimited, expired := t.roll(t.isLogin(req))
+	if delay > 0 {
+		sleep := t.Sleep
+		if sleep == nil {
+			sleep = time.Sleep
+		}
+		sleep(delay)
+	}
+	switch {
+	case expired:
+		return loginPageResponse(req), nil
+	case rateLimited:
+		return t.rateLimitResponse(req), nil
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+func (t *Transport) roll(isLogin bool) (delay time.Duration, rateLimited, expired bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rand == nil {
+		seed := t.Config.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		t.rand = rand.New(rand.NewSource(seed))
+	}
+	delay = t.Config.Latency
+	if t.Config.Jitter > 0 {
+		delay += time.Duration(t.rand.Int63n(int64(t.Config.Jitter)))
+	}
+	if isLogin {
+		t.expired = false
+		return delay, false, false
+	}
+	if !t.expired && t.rand.Float64() < t.Config.Expire {
+		t.expired = true
+	}
+	if t.expired {
+		return delay, false, true
+	}
+	return delay, t.rand.Float64() < t.Config.RateLimit, false
+}
+
+func (t *Transport) isLogin(req *http.Request) bool {
+	for _, path := range t.LoginPaths {
+		if strings.HasSuffix(req.URL.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Transport) rateLimitResponse(req *http.Request) *http.Response {
+	body := `{"success":false,"error":{"code":429,"message":"Too many requests (injected by chaos)"}}`
+	resp := newResponse(req, http.StatusTooManyRequests, "application/json", body)
+	resp.Header.Set("Retry-After", strconv.Itoa(int(t.Config.RetryAfter.Round(time.Second).Seconds())))
+	return resp
+}
+
+// loginPageResponse is what Personio responds with after the session has
+// expired.
+func loginPageResponse(req *http.Request) *http.Response {
+	body := `<!DOCTYPE html><html><head><title>Log in | Personio</title></head><body>Session expired (injected by chaos)</body></html>`
+	return newResponse(req, http.StatusOK, "text/html; charset=utf-8", body)
+}
+
+func newResponse(req *http.Request, status int, contentType, body string) *http.Response {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          io.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package chaos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	c, err := ParseConfig("latency=200ms, jitter=50ms,429=0.1,expire=0.05,seed=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{Latency: 200 * time.Millisecond, Jitter: 50 * time.Millisecond, RateLimit: 0.1, RetryAfter: time.Second, Expire: 0.05, Seed: 7}
+	if c != want {
+		t.Errorf("want %+v, got %+v", want, c)
+	}
+	for _, bad := range []string{"429=2", "latency", "unknown=1"} {
+		if _, err := ParseConfig(bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var slept time.Duration
+	get := func(tr *Transport, path string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	tr := NewTransport(nil, Config{Latency: time.Second, RateLimit: 1, RetryAfter: 2 * time.Second}, "/login/index")
+	tr.Sleep = func(d time.Duration) { slept += d }
+	resp := get(tr, "/api")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "2" {
+		t.Errorf("want HTTP 429 with Retry-After 2, got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if slept != time.Second {
+		t.Errorf("want latency of 1s, got %s", slept)
+	}
+	if resp := get(tr, "/login/index"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("want login to never fail, got %d", resp.StatusCode)
+	}
+
+	tr = NewTransport(nil, Config{Expire: 1}, "/login/index")
+	if resp := get(tr, "/api"); resp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("want login page after expiry, got %q", resp.Header.Get("Content-Type"))
+	}
+	tr.Config.Expire = 0
+	if resp := get(tr, "/api"); resp.StatusCode != http.StatusOK {
+		t.Errorf("want session to stay expired until logging in, got %d", resp.StatusCode)
+	}
+	get(tr, "/login/index")
+	if resp := get(tr, "/api"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("want session to work after logging in, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package client is the stable Go API of rootless-personio, intended for
+// third-party Go programs that read or write attendance in Personio as a
+// non-admin user.
+//
+// The [github.com/applejag/rootless-personio/pkg/personio] package mirrors
+// Personio's reverse-engineered internal API, so its types change whenever
+// Personio or this project's internals do. This package instead exposes a
+// curated set of interfaces, named types, and option functions that are
+// translated to and from the internal package.
+//
+// # Stability
+//
+// This package follows semantic versioning. Within a major version:
+//
+//   - Exported identifiers are not removed or renamed, and their signatures
+//     are not changed.
+//   - New methods are not added to the [Client] interface, as that would
+//     break other implementations of it. New functionality is added as new
+//     interfaces instead, which callers can type-assert for.
+//   - New fields may be added to structs, so use keyed struct literals.
+//   - New [Option] functions may be added.
+//
+// # Deprecation policy
+//
+// Identifiers that are to be removed are first marked with a "Deprecated:"
+// paragraph in their doc comment, naming the replacement. They keep working
+// for at least one minor release, and are only removed in the next major
+// version.
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// Client reads and writes attendance in Personio.
+//
+// The only implementation in this package is returned by [New], but the
+// interface allows callers to substitute their own, such as in tests.
+type Client interface {
+	// Login logs in with the given credentials. Must be called before the
+	// other methods.
+	Login(email, password string) error
+	// EmployeeID returns the ID of the logged in employee, or zero if not
+	// logged in.
+	EmployeeID() int
+	// Employee returns the employee with the given ID. Zero means the
+	// logged in employee.
+	Employee(id int) (Employee, error)
+	// Calendar returns the attendance, absences, and holidays between the
+	// start and end dates, inclusive.
+	Calendar(start, end time.Time) (Calendar, error)
+	// SetAttendance replaces the attendance of the date with the periods.
+	SetAttendance(date time.Time, periods []Period) error
+	// DeleteAttendance removes all attendance of the date.
+	DeleteAttendance(date time.Time) error
+	// RequestAbsence requests time off.
+	RequestAbsence(req AbsenceRequest) error
+}
+
+// ErrNotLoggedIn is returned when calling a [Client] method before
+// [Client.Login].
+var ErrNotLoggedIn = errors.New("not logged in")
+
+// Option configures the [Client] returned by [New].
+type Option func(*options)
+
+type options struct {
+	transport  http.RoundTripper
+	timeout    time.Duration
+	onBehalfOf int
+}
+
+// WithTransport sets the [http.RoundTripper] used for all HTTP requests,
+// such as to add logging or caching.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithTimeout sets the deadline of every request, including following its
+// redirects and reading its response body. Zero means no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithOnBehalfOf makes the attendance methods act on another employee,
+// which requires an account with admin rights to that employee's
+// attendance.
+func WithOnBehalfOf(employeeID int) Option {
+	return func(o *options) {
+		o.onBehalfOf = employeeID
+	}
+}
+
+// New returns a [Client] for the Personio instance at the base URL,
+// such as "https://example.personio.de".
+func New(baseURL string, opts ...Option) (Client, error) {
+	var o options
+	o.timeout = -1
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var personioOpts []personio.Option
+	if o.transport != nil {
+		personioOpts = append(personioOpts, personio.WithTransport(o.transport))
+	}
+	if o.timeout >= 0 {
+		personioOpts = append(personioOpts, personio.WithTimeouts(personio.Timeouts{Login: o.timeout, Read: o.timeout, Write: o.timeout}))
+	}
+	c, err := personio.New(baseURL, personioOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &client{c: c, onBehalfOf: o.onBehalfOf}, nil
+}
+
+type client struct {
+	c          *personio.Client
+	onBehalfOf int
+}
+
+func (c *client) Login(email, password string) error {
+	if err := c.c.Login(email, password); err != nil {
+		return err
+	}
+	if c.onBehalfOf != 0 {
+		c.c.ActOnBehalfOf(c.onBehalfOf)
+	}
+	return nil
+}
+
+func (c *client) EmployeeID() int {
+	return c.c.EmployeeID
+}
+
+func (c *client) Employee(id int) (Employee, error) {
+	if c.c.EmployeeID == 0 {
+		return Employee{}, ErrNotLoggedIn
+	}
+	if id == 0 {
+		id = c.c.EmployeeID
+	}
+	emp, err := c.c.GetEmployeeData(id)
+	if err != nil {
+		return Employee{}, err
+	}
+	return employeeFromPersonio(emp), nil
+}
+
+func (c *client) Calendar(start, end time.Time) (Calendar, error) {
+	if c.c.EmployeeID == 0 {
+		return Calendar{}, ErrNotLoggedIn
+	}
+	cal, err := c.c.GetAttendanceCalendarMerged(c.c.TargetEmployeeID(), start, end)
+	if err != nil {
+		return Calendar{}, err
+	}
+	return calendarFromPersonio(cal)
+}
+
+func (c *client) SetAttendance(date time.Time, periods []Period) error {
+	if c.c.EmployeeID == 0 {
+		return ErrNotLoggedIn
+	}
+	converted := make([]personio.Period, len(periods))
+	for i, p := range periods {
+		converted[i] = p.toPersonio()
+	}
+	if err := c.c.SetAttendance(date, converted); err != nil {
+		return fmt.Errorf("set attendance: %w", err)
+	}
+	return nil
+}
+
+func (c *client) DeleteAttendance(date time.Time) error {
+	if c.c.EmployeeID == 0 {
+		return ErrNotLoggedIn
+	}
+	if err := c.c.DeleteAttendance(date); err != nil {
+		return fmt.Errorf("delete attendance: %w", err)
+	}
+	return nil
+}
+
+func (c *client) RequestAbsence(req AbsenceRequest) error {
+	if c.c.EmployeeID == 0 {
+		return ErrNotLoggedIn
+	}
+	if err := c.c.RequestAbsence(personio.AbsenceRequest{
+		TimeOffTypeID: req.TypeID,
+		StartDate:     req.Start,
+		EndDate:       req.End,
+		HalfDayStart:  req.HalfDayStart,
+		HalfDayEnd:    req.HalfDayEnd,
+		Comment:       req.Comment,
+	}); err != nil {
+		return fmt.Errorf("request absence: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client_test
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/client"
+)
+
+func Example() {
+	c, err := client.New("https://example.personio.de", client.WithTimeout(time.Minute))
+	if err != nil {
+		log.Fatalln("Error creating client:", err)
+	}
+
+	email := os.Getenv("PERSONIO_EMAIL")
+	password := os.Getenv("PERSONIO_PASS")
+	if email == "" || password == "" {
+		log.Fatalln("Must set env var PERSONIO_EMAIL and PERSONIO_PASS")
+	}
+
+	if err := c.Login(email, password); err != nil {
+		log.Fatalln("Error logging in:", err)
+	}
+
+	today := time.Now()
+	if err := c.SetAttendance(today, []client.Period{
+		{Start: today.Add(-4 * time.Hour), End: today},
+	}); err != nil {
+		log.Fatalln("Error setting attendance:", err)
+	}
+
+	cal, err := c.Calendar(today.AddDate(0, 0, -7), today)
+	if err != nil {
+		log.Fatalln("Error fetching calendar:", err)
+	}
+	for _, day := range cal.Days {
+		log.Printf("%s: worked %s", day.Date.Format("2006-01-02"), day.Worked)
+	}
+}
@@ -0,0 +1,211 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// PeriodType is the kind of an attendance [Period].
+type PeriodType string
+
+const (
+	// PeriodWork is time spent working. It is the default for an empty
+	// [PeriodType].
+	PeriodWork PeriodType = "work"
+	// PeriodBreak is a break between work periods.
+	PeriodBreak PeriodType = "break"
+)
+
+// Period is a span of attendance within a day.
+type Period struct {
+	// ID identifies the period in Personio. Leave empty when creating new
+	// periods.
+	ID      string
+	Type    PeriodType
+	Start   time.Time
+	End     time.Time
+	Comment string
+	// ProjectID is the Personio project of the period, or zero for none.
+	ProjectID int
+}
+
+// Duration returns the length of the period.
+func (p Period) Duration() time.Duration {
+	return p.End.Sub(p.Start)
+}
+
+// Day is the attendance of a single date.
+type Day struct {
+	// Date is the date at midnight UTC.
+	Date time.Time
+	// Status is Personio's approval status of the day, such as "empty",
+	// "pending", or "confirmed".
+	Status  string
+	Worked  time.Duration
+	Break   time.Duration
+	Periods []Period
+}
+
+// Absence is time off, such as vacation or sick leave.
+type Absence struct {
+	ID string
+	// Name is the name of the absence type, such as "Paid vacation".
+	Name string
+	// Start and End are the first and last dates of the absence, at
+	// midnight UTC.
+	Start        time.Time
+	End          time.Time
+	HalfDayStart bool
+	HalfDayEnd   bool
+}
+
+// Holiday is a public holiday.
+type Holiday struct {
+	ID int
+	// Date is the date at midnight UTC.
+	Date    time.Time
+	Name    string
+	HalfDay bool
+	// Calendar is the name of the holiday calendar the holiday is from.
+	Calendar string
+}
+
+// Calendar is the attendance, absences, and holidays of a date range.
+type Calendar struct {
+	Days     []Day
+	Absences []Absence
+	Holidays []Holiday
+}
+
+// Employee is a person in Personio.
+type Employee struct {
+	ID         int
+	FirstName  string
+	LastName   string
+	Position   string
+	Department string
+	Office     string
+	Team       string
+}
+
+// AbsenceRequest is a request for time off.
+type AbsenceRequest struct {
+	// TypeID is the ID of the absence type in Personio.
+	TypeID int
+	// Start and End are the first and last dates of the absence.
+	Start        time.Time
+	End          time.Time
+	HalfDayStart bool
+	HalfDayEnd   bool
+	Comment      string
+}
+
+func (p Period) toPersonio() personio.Period {
+	var period personio.Period
+	if id, err := uuid.Parse(p.ID); err == nil {
+		period.ID = id
+	}
+	period.PeriodType = personio.PeriodType(p.Type)
+	period.Start = p.Start
+	period.End = p.End
+	if p.Comment != "" {
+		comment := p.Comment
+		period.Comment = &comment
+	}
+	if p.ProjectID != 0 {
+		projectID := p.ProjectID
+		period.ProjectID = &projectID
+	}
+	return period
+}
+
+func periodFromPersonio(p personio.Period) Period {
+	return Period{
+		ID:        p.ID.String(),
+		Type:      PeriodType(p.PeriodType),
+		Start:     p.Start,
+		End:       p.End,
+		Comment:   p.GetComment(),
+		ProjectID: p.GetProjectID(),
+	}
+}
+
+func employeeFromPersonio(e *personio.Employee) Employee {
+	return Employee{
+		ID:         e.ID,
+		FirstName:  e.FirstName,
+		LastName:   e.LastName,
+		Position:   e.Position,
+		Department: e.Department,
+		Office:     e.Office,
+		Team:       e.Team,
+	}
+}
+
+func calendarFromPersonio(cal *personio.AttendanceCalendar) (Calendar, error) {
+	periodsByDay := make(map[uuid.UUID][]Period)
+	for _, p := range cal.AttendancePeriods.Data {
+		period, err := p.Period()
+		if err != nil {
+			return Calendar{}, err
+		}
+		dayID := p.Attributes.AttendanceDayID
+		periodsByDay[dayID] = append(periodsByDay[dayID], periodFromPersonio(period))
+	}
+
+	var result Calendar
+	for _, d := range cal.AttendanceDays.Data {
+		periods := periodsByDay[d.ID]
+		sort.Slice(periods, func(i, j int) bool {
+			return periods[i].Start.Before(periods[j].Start)
+		})
+		result.Days = append(result.Days, Day{
+			Date:    d.Attributes.Day.Time,
+			Status:  d.Attributes.Status,
+			Worked:  d.Attributes.DurationMin.Duration(),
+			Break:   d.Attributes.BreakMin.Duration(),
+			Periods: periods,
+		})
+	}
+	for _, a := range cal.GetAbsencePeriods() {
+		result.Absences = append(result.Absences, Absence{
+			ID:           a.ID,
+			Name:         a.Name,
+			Start:        a.StartDate.Time,
+			End:          a.EndDate.Time,
+			HalfDayStart: a.HalfDayStart,
+			HalfDayEnd:   a.HalfDayEnd,
+		})
+	}
+	for _, h := range cal.GetHolidays() {
+		result.Holidays = append(result.Holidays, Holiday{
+			ID:       h.ID,
+			Date:     h.Date.Time,
+			Name:     h.Name,
+			HalfDay:  h.HalfDay,
+			Calendar: h.HolidayCalendarName,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestCalendarFromPersonio(t *testing.T) {
+	dayID := uuid.New()
+	comment := "standup"
+	cal := &personio.AttendanceCalendar{
+		AttendanceDays: personio.Data[[]personio.CalendarDay]{Data: []personio.CalendarDay{{
+			ID: dayID,
+			Attributes: personio.CalendarDayAttributes{
+				Day: personio.NewDate(2023, 1, 18), Status: "confirmed", DurationMin: 240, BreakMin: 30,
+			},
+		}}},
+		AttendancePeriods: personio.Data[[]personio.CalendarAttendancePeriod]{Data: []personio.CalendarAttendancePeriod{
+			{ID: uuid.New(), Attributes: personio.CalendarAttendancePeriodAttributes{
+				AttendanceDayID: dayID, PeriodType: "work",
+				Start: personio.NewFlexibleTime(time.Date(2023, 1, 18, 10, 0, 0, 0, time.UTC)), End: personio.NewFlexibleTime(time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)),
+			}},
+			{ID: uuid.New(), Attributes: personio.CalendarAttendancePeriodAttributes{
+				AttendanceDayID: dayID, PeriodType: "work", Comment: &comment,
+				Start: personio.NewFlexibleTime(time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)), End: personio.NewFlexibleTime(time.Date(2023, 1, 18, 10, 0, 0, 0, time.UTC)),
+			}},
+		}},
+		Holidays: &personio.Data[[]personio.CalendarHoliday]{Data: []personio.CalendarHoliday{
+			{ID: 1, Name: "New Year", Date: personio.NewDate(2023, 1, 1)},
+		}},
+	}
+
+	got, err := calendarFromPersonio(cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Days) != 1 {
+		t.Fatalf("want 1 day, got %d", len(got.Days))
+	}
+	day := got.Days[0]
+	if want := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC); !day.Date.Equal(want) {
+		t.Errorf("want date %s, got %s", want, day.Date)
+	}
+	if day.Worked != 4*time.Hour || day.Break != 30*time.Minute {
+		t.Errorf("want 4h worked and 30m break, got %s and %s", day.Worked, day.Break)
+	}
+	if len(day.Periods) != 2 {
+		t.Fatalf("want 2 periods, got %d", len(day.Periods))
+	}
+	if day.Periods[0].Comment != "standup" {
+		t.Errorf("want periods sorted by start, got first comment %q", day.Periods[0].Comment)
+	}
+	if len(got.Absences) != 0 {
+		t.Errorf("want no absences, got %d", len(got.Absences))
+	}
+	if len(got.Holidays) != 1 || got.Holidays[0].Name != "New Year" {
+		t.Errorf("want the New Year holiday, got %+v", got.Holidays)
+	}
+}
+
+func TestPeriodRoundTrip(t *testing.T) {
+	want := Period{
+		ID:        uuid.NewString(),
+		Type:      PeriodBreak,
+		Start:     time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC),
+		End:       time.Date(2023, 1, 18, 12, 30, 0, 0, time.UTC),
+		Comment:   "lunch",
+		ProjectID: 7,
+	}
+	if got := periodFromPersonio(want.toPersonio()); got != want {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,278 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package clock contains the local punch clock, which remembers when you
+// started working so the attendance period can be submitted when you stop.
+package clock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/filelock"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+var (
+	ErrAlreadyClockedIn = errors.New("already clocked in")
+	ErrNotClockedIn     = errors.New("not clocked in")
+)
+
+// State is the punch clock's state, as stored on disk.
+type State struct {
+	// ClockedInAt is when the current period started, or nil when
+	// not clocked in.
+	ClockedInAt *time.Time `json:"clockedInAt,omitempty"`
+	// Comment is the comment of the current period.
+	Comment string `json:"comment,omitempty"`
+	// ProjectID is the project of the current period.
+	ProjectID *int `json:"projectId,omitempty"`
+	// Pomodoro is the focus and break schedule of the current period,
+	// or nil when not using pomodoro mode.
+	Pomodoro *Pomodoro `json:"pomodoro,omitempty"`
+}
+
+// Options are the details of the period started with [In].
+type Options struct {
+	// Comment is the comment of the work periods.
+	Comment string
+	// ProjectID is the project of the work periods.
+	ProjectID *int
+	// Pomodoro enables pomodoro mode, where breaks are recorded
+	// automatically between focus blocks.
+	Pomodoro *Pomodoro
+}
+
+// Pomodoro is a schedule of alternating focus blocks and breaks.
+type Pomodoro struct {
+	// FocusMin is the length of each focus block, in minutes.
+	FocusMin int `json:"focusMin"`
+	// BreakMin is the length of each break, in minutes.
+	BreakMin int `json:"breakMin"`
+}
+
+// ParsePomodoro parses a pomodoro schedule in the format "focus/break",
+// in minutes, e.g "50/10" or "25/5".
+func ParsePomodoro(s string) (Pomodoro, error) {
+	focusStr, breakStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return Pomodoro{}, fmt.Errorf("invalid pomodoro %q, want format \"focus/break\" in minutes, e.g \"50/10\"", s)
+	}
+	focus, err := strconv.Atoi(focusStr)
+	if err != nil || focus <= 0 {
+		return Pomodoro{}, fmt.Errorf("invalid pomodoro focus minutes %q, want a positive number", focusStr)
+	}
+	brk, err := strconv.Atoi(breakStr)
+	if err != nil || brk < 0 {
+		return Pomodoro{}, fmt.Errorf("invalid pomodoro break minutes %q, want a non-negative number", breakStr)
+	}
+	return Pomodoro{FocusMin: focus, BreakMin: brk}, nil
+}
+
+// String returns the schedule in the format "focus/break", e.g "50/10".
+func (p Pomodoro) String() string {
+	return fmt.Sprintf("%d/%d", p.FocusMin, p.BreakMin)
+}
+
+// IsClockedIn returns true if a period has been started.
+func (s State) IsClockedIn() bool {
+	return s.ClockedInAt != nil
+}
+
+// Elapsed returns the duration since clocking in, or zero if not clocked in.
+func (s State) Elapsed(now time.Time) time.Duration {
+	if s.ClockedInAt == nil {
+		return 0
+	}
+	return now.Sub(*s.ClockedInAt)
+}
+
+// Worked returns the duration of work since clocking in, excluding
+// pomodoro breaks, or zero if not clocked in.
+func (s State) Worked(now time.Time, minBreak time.Duration) time.Duration {
+	if s.ClockedInAt == nil {
+		return 0
+	}
+	var total time.Duration
+	for _, p := range s.Periods(now, minBreak) {
+		if p.PeriodType == personio.PeriodTypeWork {
+			total += p.End.Sub(p.Start)
+		}
+	}
+	return total
+}
+
+// Periods returns the attendance periods from clocking in until the given
+// end time, or nil if not clocked in.
+//
+// In pomodoro mode, a break period is added after each focus block.
+// Breaks shorter than minBreak are merged into the surrounding work, and
+// a break that is still ongoing at the end time is left out.
+func (s State) Periods(end time.Time, minBreak time.Duration) []personio.Period {
+	if s.ClockedInAt == nil || !end.After(*s.ClockedInAt) {
+		return nil
+	}
+	start := *s.ClockedInAt
+	if s.Pomodoro == nil {
+		return []personio.Period{s.newPeriod(personio.PeriodTypeWork, start, end)}
+	}
+	var (
+		focus   = time.Duration(s.Pomodoro.FocusMin) * time.Minute
+		brk     = time.Duration(s.Pomodoro.BreakMin) * time.Minute
+		merge   = brk == 0 || brk < minBreak
+		periods []personio.Period
+	)
+	for t := start; t.Before(end); {
+		focusEnd := t.Add(focus)
+		if focusEnd.After(end) {
+			focusEnd = end
+		}
+		if last := len(periods) - 1; merge && last >= 0 {
+			periods[last].End = focusEnd
+		} else {
+			periods = append(periods, s.newPeriod(personio.PeriodTypeWork, t, focusEnd))
+		}
+		breakEnd := focusEnd.Add(brk)
+		if !breakEnd.Before(end) {
+			break
+		}
+		if !merge {
+			periods = append(periods, s.newPeriod(personio.PeriodTypeBreak, focusEnd, breakEnd))
+		}
+		t = breakEnd
+	}
+	return periods
+}
+
+func (s State) newPeriod(typ personio.PeriodType, start, end time.Time) personio.Period {
+	period := personio.Period{
+		PeriodType: typ,
+		Start:      start,
+		End:        end,
+	}
+	if typ == personio.PeriodTypeWork {
+		period.ProjectID = s.ProjectID
+		if s.Comment != "" {
+			comment := s.Comment
+			period.Comment = &comment
+		}
+	}
+	return period
+}
+
+// DefaultPath returns the default path of the punch clock's state file,
+// which is inside the user's config directory, e.g
+// ~/.config/rootless-personio/clock.json
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rootless-personio", "clock.json"), nil
+}
+
+// Load reads the punch clock's state. A missing file means not clocked in.
+func Load(path string) (State, error) {
+	var state State
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("read clock state: %w", err)
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return state, fmt.Errorf("parse clock state: %w", err)
+	}
+	return state, nil
+}
+
+// Save writes the punch clock's state.
+func Save(path string, state State) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := filelock.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("write clock state: %w", err)
+	}
+	return nil
+}
+
+// In starts a new period at the given time.
+//
+// Returns [ErrAlreadyClockedIn] if a period has already been started.
+func In(path string, now time.Time, opts Options) (State, error) {
+	lock, err := filelock.Acquire(path, 0)
+	if err != nil {
+		return State{}, err
+	}
+	defer lock.Release()
+	state, err := Load(path)
+	if err != nil {
+		return state, err
+	}
+	if state.IsClockedIn() {
+		return state, fmt.Errorf("%w since %s", ErrAlreadyClockedIn, state.ClockedInAt.Format(time.Kitchen))
+	}
+	start := now.Truncate(time.Minute)
+	state = State{
+		ClockedInAt: &start,
+		Comment:     opts.Comment,
+		ProjectID:   opts.ProjectID,
+		Pomodoro:    opts.Pomodoro,
+	}
+	return state, Save(path, state)
+}
+
+// Out ends the current period at the given time and returns its periods,
+// so they can be submitted. See [State.Periods] for how pomodoro breaks
+// and breaks shorter than minBreak are handled. The state is only cleared
+// after calling the submit function successfully, so a failed submit can
+// be retried. The state is locked until then, so concurrent runs can't
+// submit the same period twice.
+//
+// Returns [ErrNotClockedIn] if no period has been started.
+func Out(path string, now time.Time, minBreak time.Duration, submit func(periods []personio.Period) error) ([]personio.Period, error) {
+	lock, err := filelock.Acquire(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+	state, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if !state.IsClockedIn() {
+		return nil, ErrNotClockedIn
+	}
+	periods := state.Periods(now.Truncate(time.Minute), minBreak)
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("period is too short, started at %s", state.ClockedInAt.Format(time.Kitchen))
+	}
+	if err := submit(periods); err != nil {
+		return periods, err
+	}
+	return periods, Save(path, State{})
+}
@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package clock
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestInOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clock.json")
+	start := time.Date(2023, 1, 18, 8, 0, 30, 0, time.UTC)
+
+	if _, err := Out(path, start, 0, nil); !errors.Is(err, ErrNotClockedIn) {
+		t.Fatalf("want ErrNotClockedIn, got %v", err)
+	}
+	if _, err := In(path, start, Options{Comment: "coding"}); err != nil {
+		t.Fatalf("clock in: %s", err)
+	}
+	if _, err := In(path, start, Options{Comment: "again"}); !errors.Is(err, ErrAlreadyClockedIn) {
+		t.Fatalf("want ErrAlreadyClockedIn, got %v", err)
+	}
+
+	failing := errors.New("offline")
+	end := start.Add(4 * time.Hour)
+	if _, err := Out(path, end, 0, func([]personio.Period) error { return failing }); !errors.Is(err, failing) {
+		t.Fatalf("want submit error, got %v", err)
+	}
+	state, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if !state.IsClockedIn() {
+		t.Fatal("want still clocked in after failed submit")
+	}
+
+	var submitted personio.Period
+	if _, err := Out(path, end, 0, func(periods []personio.Period) error {
+		if len(periods) != 1 {
+			t.Fatalf("want 1 period, got %d", len(periods))
+		}
+		submitted = periods[0]
+		return nil
+	}); err != nil {
+		t.Fatalf("clock out: %s", err)
+	}
+	if want := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC); !submitted.Start.Equal(want) {
+		t.Errorf("want start %s, got %s", want, submitted.Start)
+	}
+	if submitted.GetComment() != "coding" {
+		t.Errorf("want comment %q, got %q", "coding", submitted.GetComment())
+	}
+	state, err = Load(path)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if state.IsClockedIn() {
+		t.Error("want clocked out after successful submit")
+	}
+}
+
+func TestStatePeriodsPomodoro(t *testing.T) {
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)
+	at := func(hour, min int) time.Time {
+		return time.Date(2023, 1, 18, hour, min, 0, 0, time.UTC)
+	}
+	type span struct {
+		typ        personio.PeriodType
+		start, end time.Time
+	}
+	var tests = []struct {
+		name     string
+		pomodoro Pomodoro
+		end      time.Time
+		minBreak time.Duration
+		want     []span
+	}{
+		{
+			name:     "focus and breaks",
+			pomodoro: Pomodoro{FocusMin: 50, BreakMin: 10},
+			end:      at(10, 30),
+			want: []span{
+				{personio.PeriodTypeWork, at(8, 0), at(8, 50)},
+				{personio.PeriodTypeBreak, at(8, 50), at(9, 0)},
+				{personio.PeriodTypeWork, at(9, 0), at(9, 50)},
+				{personio.PeriodTypeBreak, at(9, 50), at(10, 0)},
+				{personio.PeriodTypeWork, at(10, 0), at(10, 30)},
+			},
+		},
+		{
+			name:     "stopped during break",
+			pomodoro: Pomodoro{FocusMin: 50, BreakMin: 10},
+			end:      at(8, 55),
+			want: []span{
+				{personio.PeriodTypeWork, at(8, 0), at(8, 50)},
+			},
+		},
+		{
+			name:     "micro-breaks merged",
+			pomodoro: Pomodoro{FocusMin: 25, BreakMin: 3},
+			end:      at(9, 0),
+			minBreak: 5 * time.Minute,
+			want: []span{
+				{personio.PeriodTypeWork, at(8, 0), at(9, 0)},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			state := State{ClockedInAt: &start, Comment: "coding", Pomodoro: &tc.pomodoro}
+			periods := state.Periods(tc.end, tc.minBreak)
+			if len(periods) != len(tc.want) {
+				t.Fatalf("want %d periods, got %d: %v", len(tc.want), len(periods), periods)
+			}
+			for i, want := range tc.want {
+				got := periods[i]
+				if got.PeriodType != want.typ || !got.Start.Equal(want.start) || !got.End.Equal(want.end) {
+					t.Errorf("period %d: want %s %s-%s, got %s %s-%s", i,
+						want.typ, want.start.Format("15:04"), want.end.Format("15:04"),
+						got.PeriodType, got.Start.Format("15:04"), got.End.Format("15:04"))
+				}
+				if want.typ == personio.PeriodTypeBreak && got.Comment != nil {
+					t.Errorf("period %d: want no comment on break, got %q", i, *got.Comment)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePomodoro(t *testing.T) {
+	p, err := ParsePomodoro("50/10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != (Pomodoro{FocusMin: 50, BreakMin: 10}) {
+		t.Errorf("want 50/10, got %s", p)
+	}
+	for _, s := range []string{"50", "0/10", "50/-1", "a/b"} {
+		if _, err := ParsePomodoro(s); err == nil {
+			t.Errorf("want error for %q", s)
+		}
+	}
+}
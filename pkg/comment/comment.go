@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package comment renders attendance period comments from Go templates,
+// such as "ticket {{.Ticket}}", so comment conventions can be kept
+// consistent without any shell glue.
+package comment
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Built-in variable names.
+const (
+	// VarGitBranch is the current git branch name.
+	VarGitBranch = "GitBranch"
+	// VarTicket is the first ticket ID found in the current git branch
+	// name, such as "ABC-123" in "feature/ABC-123-login".
+	VarTicket = "Ticket"
+)
+
+var ticketRegex = regexp.MustCompile(`[A-Z][A-Z0-9]+-[0-9]+`)
+
+// Vars are the variables available in a comment template.
+//
+// Every key is also available with its first letter in upper case, so
+// e.g a "ticket" field can be used as both {{.ticket}} and {{.Ticket}}.
+type Vars map[string]any
+
+// ParseVars parses variables in the format "key=value".
+func ParseVars(pairs []string) (Vars, error) {
+	vars := make(Vars, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid variable %q, want format \"key=value\"", pair)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// IsTemplate returns true if the comment contains any template actions.
+func IsTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+// Renderer renders comment templates.
+//
+// The variables are, with later ones taking precedence:
+//  1. built-in variables, such as {{.GitBranch}} and {{.Ticket}}
+//  2. the entry's variables, such as the fields of an imported period
+//  3. the renderer's Vars, such as from command line flags
+type Renderer struct {
+	Vars Vars
+	// Dir is the directory used when looking up the git branch.
+	// Defaults to the current working directory.
+	Dir string
+
+	builtinsOnce sync.Once
+	builtins     Vars
+}
+
+// Render executes the comment template. Comments without any template
+// actions are returned as-is. Using an undefined variable is an error.
+func (r *Renderer) Render(text string, entry Vars) (string, error) {
+	if !IsTemplate(text) {
+		return text, nil
+	}
+	tmpl, err := template.New("comment").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse comment template: %w", err)
+	}
+	vars := make(Vars)
+	for _, v := range []Vars{r.loadBuiltins(), entry, r.Vars} {
+		for key, value := range v {
+			vars[key] = value
+			vars[upperFirst(key)] = value
+		}
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		return "", fmt.Errorf("render comment template: %w", err)
+	}
+	return sb.String(), nil
+}
+
+func (r *Renderer) loadBuiltins() Vars {
+	r.builtinsOnce.Do(func() {
+		r.builtins = make(Vars)
+		branch, err := GitBranch(r.Dir)
+		if err != nil {
+			log.Debug().Err(err).Msg("No git branch for comment templates.")
+			return
+		}
+		r.builtins[VarGitBranch] = branch
+		if ticket := TicketFromBranch(branch); ticket != "" {
+			r.builtins[VarTicket] = ticket
+		}
+	})
+	return r.builtins
+}
+
+// GitBranch returns the name of the current git branch in the directory.
+func GitBranch(dir string) (string, error) {
+	// Unlike "git rev-parse", this also works before the first commit,
+	// and fails on a detached HEAD
+	cmd := exec.Command("git", "symbolic-ref", "--short", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("get git branch: %w", err)
+	}
+	branch := strings.TrimSpace(string(out))
+	if branch == "" {
+		return "", errors.New("get git branch: empty branch name")
+	}
+	return branch, nil
+}
+
+// TicketFromBranch returns the first ticket ID in the branch name, such as
+// "ABC-123" in "feature/ABC-123-login", or an empty string if there is none.
+func TicketFromBranch(branch string) string {
+	return ticketRegex.FindString(branch)
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError || unicode.IsUpper(r) {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package comment
+
+import (
+	"testing"
+)
+
+func TestRendererRender(t *testing.T) {
+	r := &Renderer{Vars: Vars{"team": "platform"}}
+	// Skip the git lookup, as the tests may not run inside a git repo
+	r.builtinsOnce.Do(func() {
+		r.builtins = Vars{VarGitBranch: "feature/ABC-123-login", VarTicket: "ABC-123"}
+	})
+
+	var tests = []struct {
+		name    string
+		text    string
+		entry   Vars
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "plain comment",
+			text: "Work before lunch",
+			want: "Work before lunch",
+		},
+		{
+			name: "built-in",
+			text: "ticket {{.Ticket}} on {{.GitBranch}}",
+			want: "ticket ABC-123 on feature/ABC-123-login",
+		},
+		{
+			name:  "entry overrides built-in",
+			text:  "ticket {{.Ticket}}",
+			entry: Vars{"ticket": "XYZ-9"},
+			want:  "ticket XYZ-9",
+		},
+		{
+			name:  "flag var with upper case alias",
+			text:  "{{.Team}}/{{.team}}",
+			entry: Vars{"team": "ignored"},
+			want:  "platform/platform",
+		},
+		{
+			name:    "missing variable",
+			text:    "{{.Missing}}",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := r.Render(tc.text, tc.entry)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTicketFromBranch(t *testing.T) {
+	for branch, want := range map[string]string{
+		"feature/ABC-123-login": "ABC-123",
+		"PROJ2-7":               "PROJ2-7",
+		"main":                  "",
+	} {
+		if got := TicketFromBranch(branch); got != want {
+			t.Errorf("%q: want %q, got %q", branch, want, got)
+		}
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]string{"ticket=ABC-1", "empty=", "eq=a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["ticket"] != "ABC-1" || vars["empty"] != "" || vars["eq"] != "a=b" {
+		t.Errorf("unexpected vars: %v", vars)
+	}
+	if _, err := ParseVars([]string{"novalue"}); err == nil {
+		t.Error("want error for missing \"=\"")
+	}
+}
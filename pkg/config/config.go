@@ -22,10 +22,11 @@ package config
 
 import (
 	"reflect"
+	"strings"
 	"time"
 
-	"github.com/invopop/jsonschema"
 	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/invopop/jsonschema"
 )
 
 // Config is the full configuration file.
@@ -39,7 +40,19 @@ type Config struct {
 	//
 	// Any query parameters and fragments will get removed.
 	BaseURL string `yaml:"baseUrl" jsonschema:"oneof_type=string;null" jsonschema_extras:"format=uri"`
-	Auth    Auth
+	// Tenant is an alternative to setting the BaseURL, where the URL
+	// is instead built from its separate parts. Only used when BaseURL
+	// is unset.
+	Tenant Tenant
+	Auth   Auth
+	// Team points to a centrally managed config file that is shared
+	// within your team.
+	Team Team
+
+	// Employees lets you refer to other employees by email instead of by
+	// ID in the --employee flag, when acting on behalf of other employees
+	// as an admin.
+	Employees []Employee
 
 	// MinimumPeriodDuration is the duration for which attendance periods that
 	// are shorter than will get skipped when creating or updating attendance.
@@ -50,20 +63,167 @@ type Config struct {
 	// - 2h12m30s
 	MinimumPeriodDuration time.Duration `yaml:"minimumPeriodDuration" jsonschema:"type=string"`
 
+	// Cache contains configs for caching HTTP responses on disk.
+	Cache Cache
+	// Storage contains configs for where local state is stored, such as
+	// remembered cookies and the audit journal.
+	Storage Storage
+	// HTTP contains configs for the HTTP requests sent to Personio.
+	HTTP HTTP `yaml:"http"`
+	// Endpoints overrides the paths of Personio's endpoints, to work
+	// around Personio moving an endpoint before a new release is out.
+	Endpoints Endpoints
+	// Experimental enables features that use newly reverse-engineered
+	// endpoints, which have not yet been verified against many tenants.
+	Experimental Experimental
+
 	// Output is the format of the command line results.
 	// This controls the format of the single command line
 	// result output written to STDOUT.
 	Output OutFormat
-	Log    Log
+	// NonInteractive refuses to prompt for input, such as for passwords or
+	// confirmations, and fails instead, even when run from a terminal.
+	// Useful in containers, where prompting would hang waiting for STDIN.
+	NonInteractive bool `yaml:"nonInteractive"`
+	// Locale controls how dates, times, and durations are formatted
+	// when using the "pretty" output format.
+	Locale Locale
+	// DateRange controls which dates commands use when no range is given
+	// via their --start and --end flags.
+	DateRange DateRange `yaml:"dateRange"`
+	Log       Log
+	// Hooks are external commands that are executed around certain events.
+	Hooks Hooks
+	// Transform is a Starlark script that can modify attendance periods
+	// before they are submitted.
+	Transform Transform
+	// Tidy contains configs for merging adjacent and duplicate attendance
+	// periods, as done by the "attendance tidy" command.
+	Tidy Tidy
+	// Import contains configs for reading spreadsheets in the
+	// "attendance import" command.
+	Import Import
+	// Report contains configs for the "report" commands.
+	Report Report
+	// Lint contains configs for the "lint" command.
+	Lint Lint
+	// Mirror contains configs for the local SQLite mirror of your
+	// attendance data.
+	Mirror Mirror
+	// Queue contains configs for the queue of attendance changes made
+	// while offline.
+	Queue Queue
+	// Audit contains configs for the journal of attendance changes sent
+	// to Personio.
+	Audit Audit
+	// SyncOut contains configs for the "sync-out" commands, which copy your
+	// absences and holidays into external calendars.
+	SyncOut SyncOut `yaml:"syncOut"`
+	// Slack contains configs for setting your Slack status from your
+	// absences and work location.
+	Slack Slack
+	// Digest contains configs for the weekly summary email.
+	Digest Digest
+	// Bot contains configs for the chat bot, started via "bot matrix" or
+	// "bot telegram".
+	Bot Bot
+	// Clock contains configs for the punch clock used by the
+	// "attendance start" and "attendance stop" commands.
+	Clock Clock
+	// Daemon contains configs for the long-running daemon.
+	Daemon Daemon
+	// MCP contains configs for the "mcp" command, which lets AI
+	// assistants use this program via the Model Context Protocol.
+	MCP MCP `yaml:"mcp"`
+	// Serve contains configs for the "serve" command, which serves a REST
+	// API that can be shared by multiple employees.
+	Serve Serve
+	// Telemetry contains configs for the opt-in reporting of anonymized
+	// failures of requests to Personio.
+	Telemetry Telemetry
+	// Sandbox marks the Personio tenant as a test tenant, usually set in
+	// a profile's config file, such as personio.sandbox.yaml.
+	Sandbox Sandbox
+}
+
+// Employee maps an employee's email to their Personio employee ID, which is
+// found in the URL of their profile page.
+type Employee struct {
+	Email string `jsonschema:"format=email"`
+	ID    int    `yaml:"id"`
+}
+
+// Tenant contains configs for building the URL to your Personio instance.
+//
+// Use the "detect-tenant" command to find which domain your company's
+// Personio instance is hosted on.
+type Tenant struct {
+	// Slug is your company's subdomain, e.g "mycompany" in
+	// https://mycompany.personio.de.
+	Slug string `jsonschema:"oneof_type=string;null"`
+	// Domain is the Personio domain that your tenant is hosted on,
+	// which depends on the region your company's account was created in.
+	Domain string `jsonschema:"enum=personio.de,enum=app.personio.com"`
+	// Host overrides the full hostname, for when your company accesses
+	// Personio through a custom domain. Setting this ignores the Slug
+	// and Domain fields.
+	Host string `jsonschema:"oneof_type=string;null" jsonschema_extras:"format=hostname"`
+	// PathPrefix is prepended to all request paths, for when Personio is
+	// accessed through a reverse proxy, e.g "/personio".
+	PathPrefix string `yaml:"pathPrefix" jsonschema:"oneof_type=string;null"`
+}
+
+// Team contains configs for loading a centrally managed team config file,
+// so a whole team can standardize on one tested setup.
+//
+// The team config is merged in before your own config files, meaning
+// your own config files take precedence. The team config file must not
+// contain the "auth" or "team" fields.
+type Team struct {
+	// Source is a file path or HTTP(S) URL to the team config file.
+	Source string `jsonschema:"oneof_type=string;null"`
+	// SHA256 is the expected hex-encoded SHA-256 checksum of the team
+	// config file. Required when the Source is a URL.
+	SHA256 string `yaml:"sha256" jsonschema:"oneof_type=string;null" jsonschema_extras:"pattern=^[0-9a-fA-F]{64}$"`
 }
 
 // Auth contains configs for how the program should authenticate
 // with Personio.
 type Auth struct {
+	// Provider is how to log in to Personio. "password" logs in using the
+	// email and password. "cookie" reuses a logged in session from your web
+	// browser, via the Cookie field. "sso" opens Personio in your web
+	// browser to log in via your company's single sign-on, and then asks
+	// for the session's cookies. "headlessBrowser" runs the BrowserCommand
+	// to log in.
+	Provider string `jsonschema:"enum=password,enum=cookie,enum=sso,enum=headlessBrowser"`
+	// Cookie is the "Cookie" HTTP header value of a logged in session, as
+	// copied from your web browser's developer tools. Used by the "cookie"
+	// provider.
+	Cookie string `yaml:"cookie,omitempty" jsonschema:"oneof_type=string;null"`
+	// BrowserCommand is a command, such as a headless browser script, that
+	// logs in and prints the session's cookies to STDOUT as a "Cookie"
+	// HTTP header value. It gets the base URL, email, and password via the
+	// PERSONIO_BASEURL, PERSONIO_AUTH_EMAIL, and PERSONIO_AUTH_PASSWORD
+	// environment variables. Used by the "headlessBrowser" provider.
+	BrowserCommand []string `yaml:"browserCommand"`
 	// Email is your account's login email address.
 	Email string `jsonschema:"oneof_type=string;null" jsonschema_extras:"format=email"`
-	// Password is your account's login password.
+	// Password is your account's login password. When unset, the password
+	// is looked up in the OS keyring, or else prompted for interactively.
 	Password string `jsonschema:"oneof_type=string;null"`
+	// Keyring enables looking up the password in the OS keyring when the
+	// password is not set, and offering to store it there after having
+	// logged in with a prompted password.
+	Keyring bool
+	// LockoutCooldown is how long to refuse logging in after Personio
+	// responds with "too many login attempts", when it doesn't say for how
+	// long itself. The lockout is remembered across invocations.
+	LockoutCooldown time.Duration `yaml:"lockoutCooldown" jsonschema:"type=string"`
+	// RememberDevice enables persisting Personio's long-lived cookies
+	// between runs, in a file per tenant and email inside your user config
+	// directory, so each run doesn't look like a login from a new device.
+	RememberDevice bool `yaml:"rememberDevice"`
 
 	// CSRFToken is provided by this program when it fails to
 	// log in due to them detecting login via new device. You then need to
@@ -77,6 +237,683 @@ type Auth struct {
 	EmailToken string `yaml:"emailToken,omitempty" jsonschema:"oneof_type=string;null"`
 }
 
+// Locale contains configs for localizing the "pretty" output.
+type Locale struct {
+	// Language is used for the names of weekdays and months, as well
+	// as the decimal separator when showing durations as decimal hours.
+	Language string `jsonschema:"enum=en,enum=de"`
+	// DateFormat is how dates are written, where "iso" is 2006-01-02,
+	// "de" is 02.01.2006, and "us" is 01/02/2006.
+	DateFormat string `yaml:"dateFormat" jsonschema:"enum=iso,enum=de,enum=us"`
+	// TimeFormat is how times of day are written, where "24h" is 15:04,
+	// and "12h" is 3:04 PM.
+	TimeFormat string `yaml:"timeFormat" jsonschema:"enum=24h,enum=12h"`
+	// DurationFormat is how durations are written, where "clock" is 8:30,
+	// and "decimal" is 8.50 (or 8,50 when language is "de").
+	DurationFormat string `yaml:"durationFormat" jsonschema:"enum=clock,enum=decimal"`
+}
+
+// Hooks contains external commands that are executed around certain events,
+// allowing custom validation or alerting.
+//
+// Each hook is a command followed by its arguments, such as
+// ["notify-send", "Personio"]. The hook gets a JSON object with context
+// about the event written to its STDIN, and the hook name in the
+// PERSONIO_HOOK environment variable.
+type Hooks struct {
+	// PreSubmit is executed before attendance is submitted to Personio.
+	// If the command exits with a non-zero exit code, then the submission
+	// is aborted.
+	PreSubmit []string `yaml:"preSubmit"`
+	// PostSubmit is executed after attendance has been successfully
+	// submitted to Personio.
+	PostSubmit []string `yaml:"postSubmit"`
+	// OnLoginFailure is executed when logging in to Personio fails.
+	OnLoginFailure []string `yaml:"onLoginFailure"`
+}
+
+// Transform contains configs for a user-defined Starlark script, which
+// can modify attendance periods before they are submitted. Starlark is a
+// dialect of Python.
+//
+// The script can define a "transform(period)" function that is called once
+// per period, and a "transform_day(day, periods)" function that is called
+// once per day. See the documentation of the transform package for details.
+type Transform struct {
+	// File is the path to a Starlark script file.
+	File string `jsonschema:"oneof_type=string;null"`
+	// Script is inline Starlark source code. Only used when File is unset.
+	Script string `jsonschema:"oneof_type=string;null"`
+}
+
+// Import contains configs for reading spreadsheets in the
+// "attendance import" command.
+type Import struct {
+	// CSV contains how CSV files are read.
+	CSV ImportCSV `yaml:"csv"`
+	// XLSX contains how Excel (XLSX) files are read.
+	XLSX ImportXLSX `yaml:"xlsx"`
+}
+
+// ImportXLSX contains how Excel (XLSX) files are read.
+type ImportXLSX struct {
+	// Sheet is the name or 1-based number of the sheet to read.
+	// Defaults to the first sheet.
+	Sheet string `jsonschema:"oneof_type=string;null"`
+	// Mapping tells which columns contain which fields. Defaults to the
+	// CSV mapping when no start column is set. Cells formatted as dates
+	// or times are converted to text using the mapping's formats.
+	Mapping ImportMapping
+}
+
+// ImportCSV contains how CSV files are read.
+type ImportCSV struct {
+	// Delimiter is the character between the columns. Defaults to ",".
+	Delimiter string `jsonschema:"oneof_type=string;null" jsonschema_extras:"maxLength=1"`
+	// Mapping tells which columns contain which fields.
+	Mapping ImportMapping
+}
+
+// ImportMapping tells which spreadsheet columns contain which fields, by
+// header name (matched case-insensitively) or by 1-based column number.
+type ImportMapping struct {
+	// Date is the column of the day. When empty, the Start and End
+	// columns must contain both date and time.
+	Date string `jsonschema:"oneof_type=string;null"`
+	// Start and End are the columns of the start and end times.
+	Start string `jsonschema:"oneof_type=string;null"`
+	End   string `jsonschema:"oneof_type=string;null"`
+	// Duration is the column of the duration, used when End is empty.
+	// Values are either "1:30", decimal hours "1.5", or "1h30m".
+	Duration string `jsonschema:"oneof_type=string;null"`
+	// Type is the column of the period type, "work" or "break".
+	// Periods are work when empty.
+	Type    string `jsonschema:"oneof_type=string;null"`
+	Comment string `jsonschema:"oneof_type=string;null"`
+	// Source and SourceID are the columns of the time tracker and the
+	// entry's ID in it, used to not import the same entry twice.
+	Source   string `jsonschema:"oneof_type=string;null"`
+	SourceID string `yaml:"sourceId" jsonschema:"oneof_type=string;null"`
+	// DateFormat is the Go time layout of the Date column, such as
+	// "02.01.2006". Defaults to "2006-01-02".
+	DateFormat string `yaml:"dateFormat" jsonschema:"oneof_type=string;null"`
+	// TimeFormat is the Go time layout of the Start and End columns, such
+	// as "3:04 PM". Defaults to "15:04", or to RFC 3339 when Date is empty.
+	TimeFormat string `yaml:"timeFormat" jsonschema:"oneof_type=string;null"`
+	// TimeZone is the IANA time zone of the dates and times, such as
+	// "Europe/Berlin". Defaults to the local time zone.
+	TimeZone string `yaml:"timeZone" jsonschema:"oneof_type=string;null"`
+}
+
+// Tidy contains configs for merging adjacent, overlapping, and duplicate
+// attendance periods of the same type and project.
+type Tidy struct {
+	// Enabled makes "attendance set" tidy the periods before sending them,
+	// same as its --tidy flag.
+	Enabled bool
+	// MaxGap is the longest gap between two periods that still gets merged
+	// into a single period.
+	MaxGap time.Duration `yaml:"maxGap" jsonschema:"type=string"`
+}
+
+// Report contains configs for the "report" commands.
+type Report struct {
+	// WorkingHours is your target amount of work per weekday, used when
+	// calculating your flexitime balance. Holidays and absences are
+	// subtracted automatically.
+	WorkingHours WorkingHours `yaml:"workingHours"`
+	// HomeOffice contains the comment conventions used to tell office
+	// days apart from home office days.
+	HomeOffice HomeOffice `yaml:"homeOffice"`
+	// Surcharge contains the rules for which work is eligible for
+	// on-call, night, weekend, and holiday surcharges.
+	Surcharge Surcharge
+	// Vacation contains your vacation entitlement, used by the
+	// "absence balance" command.
+	Vacation Vacation
+}
+
+// Vacation contains your vacation entitlement, as Personio's balance is not
+// available via the endpoints used by this program.
+type Vacation struct {
+	// Days is your yearly vacation entitlement, in days.
+	Days float64
+	// Keywords are words in absence names that mark the absence as
+	// vacation, matched case-insensitively.
+	Keywords []string
+	// LeaveYearStart is the first day of the leave year, in the format
+	// "01-02" (month-day). Defaults to "01-01".
+	LeaveYearStart string `yaml:"leaveYearStart" jsonschema:"oneof_type=string;null" jsonschema_extras:"pattern=^[0-9]{2}-[0-9]{2}$"`
+	// Carryover is how unused days are carried over to the next leave
+	// year.
+	Carryover VacationCarryover
+	// EmploymentStart and EmploymentEnd are the first and last day of your
+	// employment, in the format "2006-01-02", used to pro-rate Days in
+	// partial years.
+	EmploymentStart string `yaml:"employmentStart" jsonschema:"oneof_type=string;null" jsonschema_extras:"format=date"`
+	EmploymentEnd   string `yaml:"employmentEnd" jsonschema:"oneof_type=string;null" jsonschema_extras:"format=date"`
+	// ProRate is how Days is pro-rated for partial-year employment.
+	ProRate string `yaml:"proRate" jsonschema:"enum=months,enum=days,enum=none"`
+}
+
+// VacationCarryover is how unused vacation days are carried over to the
+// next leave year.
+type VacationCarryover struct {
+	// MaxDays is the most days that are carried over, or zero to not
+	// carry over any days.
+	MaxDays float64 `yaml:"maxDays"`
+	// Expires is the last day that the carried over days can be taken,
+	// in the format "01-02" (month-day), such as "03-31". They never
+	// expire when empty.
+	Expires string `jsonschema:"oneof_type=string;null" jsonschema_extras:"pattern=^[0-9]{2}-[0-9]{2}$"`
+}
+
+// Surcharge contains the rules for which work is eligible for surcharges,
+// used when cross-checking your payroll.
+type Surcharge struct {
+	// NightStart is the time of day when night work starts, in the
+	// format "15:04".
+	NightStart string `yaml:"nightStart"`
+	// NightEnd is the time of day when night work ends, in the format
+	// "15:04". May be earlier than NightStart for nights that span
+	// midnight.
+	NightEnd string `yaml:"nightEnd"`
+	// OnCallKeywords are words in attendance period comments that mark
+	// the period as on-call work. Matched case-insensitively.
+	OnCallKeywords []string `yaml:"onCallKeywords"`
+}
+
+// HomeOffice contains the comment conventions used to tell office days
+// apart from home office days, as Personio doesn't track where you work.
+type HomeOffice struct {
+	// RemoteKeywords are words in attendance period comments that mark
+	// the period as worked from home. Matched case-insensitively.
+	RemoteKeywords []string `yaml:"remoteKeywords"`
+	// OfficeKeywords are words in attendance period comments that mark
+	// the period as worked from the office. Matched case-insensitively.
+	OfficeKeywords []string `yaml:"officeKeywords"`
+	// Default is the location of periods without any of the keywords.
+	Default string `jsonschema:"enum=office,enum=remote,enum=unknown"`
+}
+
+// WorkingHours is the target amount of work per weekday.
+//
+// The values are Go durations, which allows values like:
+// - 8h
+// - 7h30m
+// - 0s
+type WorkingHours struct {
+	Monday    time.Duration `jsonschema:"type=string"`
+	Tuesday   time.Duration `jsonschema:"type=string"`
+	Wednesday time.Duration `jsonschema:"type=string"`
+	Thursday  time.Duration `jsonschema:"type=string"`
+	Friday    time.Duration `jsonschema:"type=string"`
+	Saturday  time.Duration `jsonschema:"type=string"`
+	Sunday    time.Duration `jsonschema:"type=string"`
+}
+
+// Cache contains configs for caching HTTP responses on disk.
+//
+// Cached responses are always revalidated with Personio using their
+// ETag and Last-Modified headers, so they are never stale, but unchanged
+// responses do not need to be downloaded again.
+type Cache struct {
+	// Enabled turns the cache on or off.
+	Enabled bool
+	// Dir is the directory where cached responses are stored.
+	// Defaults to a "rootless-personio/http" directory inside your
+	// user cache directory, e.g ~/.cache/rootless-personio/http
+	Dir string
+	// MaxSizeMiB is the total size of all cached responses in mebibytes,
+	// before the least recently used responses are removed.
+	MaxSizeMiB int `yaml:"maxSizeMiB" jsonschema:"minimum=1"`
+}
+
+// HTTP contains configs for the HTTP requests sent to Personio.
+type HTTP struct {
+	// MaxResponseSizeMiB is the largest response body in mebibytes that is
+	// read, after decompression. Larger responses fail instead, which
+	// protects against huge HTML pages, such as when Personio redirects to
+	// the login page. Zero disables the limit.
+	MaxResponseSizeMiB int `yaml:"maxResponseSizeMiB" jsonschema:"minimum=0"`
+	// Timeouts are the deadlines of each request per class of request,
+	// including following redirects and reading the response.
+	Timeouts HTTPTimeouts
+	// PreflightTimeout is how long to wait for Personio to respond to a
+	// quick check before logging in, so that unreachable hosts, such as
+	// when a VPN is required, fail fast. Zero disables the check.
+	PreflightTimeout time.Duration `yaml:"preflightTimeout" jsonschema:"type=string"`
+}
+
+// HTTPTimeouts are the timeouts of requests per class of request.
+// Zero disables the timeout.
+type HTTPTimeouts struct {
+	// Login is the timeout of the login requests, where Personio's chain of
+	// redirects needs more headroom than other requests.
+	Login time.Duration `jsonschema:"type=string"`
+	// Read is the timeout of GET requests, such as fetching the calendar.
+	Read time.Duration `jsonschema:"type=string"`
+	// Write is the timeout of requests that change data, such as setting
+	// attendance.
+	Write time.Duration `jsonschema:"type=string"`
+}
+
+// Endpoints overrides the paths of Personio's endpoints, relative to the
+// base URL. Empty paths use the default path. Placeholders like
+// {employeeId} are replaced when sending the request.
+type Endpoints struct {
+	// Login defaults to "/login/index".
+	Login string
+	// TokenAuth defaults to "/login/token-auth".
+	TokenAuth string `yaml:"tokenAuth"`
+	// UserActivity defaults to "/user-activity/api/v1/pendo".
+	UserActivity string `yaml:"userActivity"`
+	// EmployeeHeader defaults to "/employee-header-bff/{employeeId}".
+	EmployeeHeader string `yaml:"employeeHeader"`
+	// AttendanceCalendar defaults to
+	// "/svc/attendance-bff/attendance-calendar/{employeeId}".
+	AttendanceCalendar string `yaml:"attendanceCalendar"`
+	// AttendanceDay defaults to "/api/v1/attendances/days/{dayId}".
+	AttendanceDay string `yaml:"attendanceDay"`
+	// AttendanceDayPeriods defaults to
+	// "/api/v1/attendances/days/{dayId}/periods".
+	AttendanceDayPeriods string `yaml:"attendanceDayPeriods"`
+	// AbsencePeriods defaults to "/api/v1/absence-periods".
+	AbsencePeriods string `yaml:"absencePeriods"`
+}
+
+// Experimental enables features that use newly reverse-engineered endpoints,
+// which have not yet been verified against many tenants. They are all
+// disabled by default, and may change or be removed in any release.
+type Experimental struct {
+	// AbsenceWrite enables the "absence request" command.
+	AbsenceWrite bool `yaml:"absenceWrite"`
+}
+
+// DateRange controls which dates commands use when no range is given via
+// their --start and --end flags.
+type DateRange struct {
+	// Default is the range used by all commands without an override in
+	// Commands.
+	Default string `jsonschema:"enum=thisWeek,enum=thisMonth,enum=last30Days"`
+	// Commands overrides the default range per command, keyed by the
+	// command without the program name, e.g "stats" or "report balance".
+	Commands map[string]string
+	// WeekStart is the first day of the week, used by the "thisWeek" range
+	// and the weeks of "attendance calendar".
+	WeekStart string `yaml:"weekStart" jsonschema:"enum=monday,enum=sunday"`
+}
+
+// Lint contains configs for the "lint" command, which flags suspicious
+// attendance entries.
+type Lint struct {
+	// MaxDay is the longest amount of work per day before the day
+	// is flagged as suspicious.
+	//
+	// The value is a Go duration, which allows values like:
+	// - 12h
+	// - 10h30m
+	MaxDay time.Duration `yaml:"maxDay" jsonschema:"type=string"`
+	// BaselineMonths is the number of months before the linted month
+	// that are used as a baseline of your usual days. Days that differ
+	// wildly from the baseline are flagged. Set to 0 to disable.
+	BaselineMonths int `yaml:"baselineMonths" jsonschema:"minimum=0"`
+}
+
+// Mirror contains configs for the local SQLite mirror of your attendance
+// data, which is updated via the "mirror sync" command.
+type Mirror struct {
+	// Path is the path of the SQLite database file.
+	// Defaults to a "rootless-personio/mirror.db" file inside your
+	// user cache directory, e.g ~/.cache/rootless-personio/mirror.db
+	Path string
+}
+
+// Queue contains configs for the queue of attendance changes made with the
+// --offline flag, which are replayed via the "flush" command.
+type Queue struct {
+	// Path is the path of the queue file.
+	// Defaults to a "rootless-personio/queue.json" file inside your
+	// user config directory, e.g ~/.config/rootless-personio/queue.json
+	Path string
+}
+
+// Storage contains configs for where local state is stored, such as
+// remembered cookies, day IDs, and the audit journal.
+type Storage struct {
+	// Backend is where the state is stored: "filesystem" stores it as
+	// files in a directory, while "sqlite" stores it in a single SQLite
+	// database file.
+	Backend string `jsonschema:"enum=filesystem,enum=sqlite"`
+	// Path is the directory of the "filesystem" backend, or the database
+	// file of the "sqlite" backend. Defaults to a "rootless-personio"
+	// directory inside your user config directory, e.g
+	// ~/.config/rootless-personio, or a "storage.db" file inside it.
+	Path string
+	// DayIDs remembers the IDs of attendance days between runs, which
+	// saves fetching the calendar before writing to a day seen before.
+	// Days deleted and recreated via Personio's web page get new IDs, which
+	// makes writes to those days fail until the remembered IDs are removed.
+	DayIDs bool `yaml:"dayIds"`
+}
+
+// Audit contains configs for the journal of attendance changes sent to
+// Personio, used by the "audit" commands.
+type Audit struct {
+	// Enabled makes attendance changes get recorded in the journal.
+	Enabled bool
+	// Path is the path of the journal file, which is then used regardless
+	// of the storage backend. Defaults to "audit.jsonl" in the storage,
+	// e.g ~/.config/rootless-personio/audit.jsonl
+	Path string
+}
+
+// SyncOut contains configs for the "sync-out" commands, which copy your
+// absences and public holidays from Personio into external calendars.
+type SyncOut struct {
+	// Holidays includes public holidays, and not only absences.
+	Holidays bool
+	// CalDAV is the calendar used by "sync-out caldav".
+	CalDAV SyncOutCalDAV `yaml:"caldav"`
+	// GCal is the Google calendar used by "sync-out gcal".
+	GCal SyncOutGCal `yaml:"gcal"`
+}
+
+// SyncOutCalDAV is a calendar collection on a CalDAV server.
+type SyncOutCalDAV struct {
+	// URL is the URL of the calendar collection, e.g
+	// "https://cloud.example.com/remote.php/dav/calendars/me/vacation/"
+	URL string `yaml:"url"`
+	// Username is used for HTTP basic authentication.
+	Username string
+	// Password is used for HTTP basic authentication. Prefer setting it via
+	// the PERSONIO_SYNCOUT_CALDAV_PASSWORD environment variable.
+	Password string
+}
+
+// SyncOutGCal is a calendar in Google Calendar, written to via Google's
+// CalDAV API.
+type SyncOutGCal struct {
+	// CalendarID is the ID of the calendar, found in the calendar's settings,
+	// such as "primary" or "abc123@group.calendar.google.com".
+	CalendarID string `yaml:"calendarId"`
+	// TokenCommand is a command that prints an OAuth 2.0 access token with
+	// the calendar scope, such as ["gcloud", "auth", "print-access-token"].
+	TokenCommand []string `yaml:"tokenCommand"`
+}
+
+// Slack contains configs for setting your Slack status from today's
+// absences and work location, via the "slack sync-status" command or
+// each morning by the daemon.
+type Slack struct {
+	// Token is a Slack user token ("xoxp-...") with the users.profile:read
+	// and users.profile:write scopes. Prefer setting it via the
+	// PERSONIO_SLACK_TOKEN environment variable.
+	Token string
+	// SyncAt is the time of day, in the format "15:04", when the daemon
+	// syncs your Slack status. Disabled when empty.
+	SyncAt string `yaml:"syncAt"`
+	// SickKeywords are words in absence names that mark the absence as
+	// sick leave, matched case-insensitively. Other absences are seen as
+	// vacation.
+	SickKeywords []string `yaml:"sickKeywords"`
+	// Vacation is the status while on vacation, or any other absence
+	// that is not sick leave.
+	Vacation SlackStatus
+	// Sick is the status while on sick leave.
+	Sick SlackStatus
+	// Remote is the status when working remotely, as told by the
+	// report.homeOffice keywords in today's attendance comments or in the
+	// comment of the running punch clock.
+	Remote SlackStatus
+}
+
+// SlackStatus is a Slack status. Leave both fields empty to not set any
+// status in that case.
+type SlackStatus struct {
+	Text  string
+	Emoji string
+}
+
+// Digest contains configs for the weekly summary email, sent via the
+// "digest" command or each week by the daemon.
+type Digest struct {
+	// Weekday is the day of the week when the daemon sends the digest.
+	Weekday string `jsonschema:"enum=monday,enum=tuesday,enum=wednesday,enum=thursday,enum=friday,enum=saturday,enum=sunday"`
+	// At is the time of day, in the format "15:04", when the daemon sends
+	// the digest. Disabled when empty.
+	At string
+	// UpcomingWeeks is how many weeks after the summarized week to list
+	// absences for.
+	UpcomingWeeks int `yaml:"upcomingWeeks"`
+	// From is the sender address, e.g "Personio <me@example.com>".
+	From string
+	// To are the recipient addresses.
+	To []string
+	// SMTP is the server used to send the email.
+	SMTP SMTP `yaml:"smtp"`
+}
+
+// SMTP contains configs for the server used to send emails.
+type SMTP struct {
+	Host string
+	// Port is typically 587 for STARTTLS, or 465 for implicit TLS.
+	Port     int
+	Username string
+	// Password is the SMTP password. Prefer setting it via the
+	// PERSONIO_DIGEST_SMTP_PASSWORD environment variable.
+	Password string
+}
+
+// Bot contains configs for the chat bot, which accepts commands such as
+// "log 9-17:30 today" from your own chat account.
+type Bot struct {
+	Telegram BotTelegram
+	Matrix   BotMatrix
+}
+
+// BotTelegram contains configs for the Telegram bot.
+type BotTelegram struct {
+	// Token is the bot's token, as given by @BotFather. Prefer setting it
+	// via the PERSONIO_BOT_TELEGRAM_TOKEN environment variable.
+	Token string
+	// UserID is your own Telegram user ID. Messages from other users are
+	// ignored.
+	UserID int64 `yaml:"userId"`
+}
+
+// BotMatrix contains configs for the Matrix bot.
+type BotMatrix struct {
+	// Homeserver is the URL of the bot account's homeserver,
+	// e.g "https://matrix.org".
+	Homeserver string
+	// AccessToken is the access token of the bot's own account. Prefer
+	// setting it via the PERSONIO_BOT_MATRIX_ACCESSTOKEN environment
+	// variable.
+	AccessToken string `yaml:"accessToken"`
+	// RoomID is the room to listen in, e.g "!abc123:matrix.org".
+	RoomID string `yaml:"roomId"`
+	// UserID is your own Matrix user ID, e.g "@me:matrix.org". Messages
+	// from other users are ignored.
+	UserID string `yaml:"userId"`
+}
+
+// Clock contains configs for the punch clock, which remembers when you ran
+// "attendance start" so the period can be submitted on "attendance stop".
+type Clock struct {
+	// Path is the path of the punch clock's state file.
+	// Defaults to a "rootless-personio/clock.json" file inside your
+	// user config directory, e.g ~/.config/rootless-personio/clock.json
+	Path string
+	// MinBreak is the shortest pomodoro break that is submitted as a break
+	// period. Shorter breaks are merged into the surrounding work.
+	MinBreak time.Duration `yaml:"minBreak" jsonschema:"type=string"`
+}
+
+// Daemon contains configs for the long-running daemon, started via the
+// "daemon run" command. Other invocations of the CLI send their requests
+// through the daemon when it is running, sharing its logged in session.
+type Daemon struct {
+	// Socket is the path of the daemon's unix socket.
+	// Defaults to $XDG_RUNTIME_DIR/rootless-personio.sock, or a
+	// "rootless-personio/daemon.sock" file inside your user cache
+	// directory if $XDG_RUNTIME_DIR is unset.
+	Socket string
+	// SyncInterval is how often the daemon syncs the current month into
+	// the local mirror. Set to 0s to disable.
+	//
+	// The value is a Go duration, which allows values like:
+	// - 15m
+	// - 1h
+	SyncInterval time.Duration `yaml:"syncInterval" jsonschema:"type=string"`
+	// MaintenanceBackoff is how long the daemon pauses its requests when
+	// Personio is under maintenance without announcing when it ends.
+	MaintenanceBackoff time.Duration `yaml:"maintenanceBackoff" jsonschema:"type=string"`
+	// NetworkWatchInterval is how often the daemon checks its network
+	// interfaces for changes, to pause requests while offline and resume
+	// them once Personio is reachable again. Set to 0s to disable.
+	NetworkWatchInterval time.Duration `yaml:"networkWatchInterval" jsonschema:"type=string"`
+	// RequestBudget is the ceiling of requests the daemon sends to
+	// Personio, shared by all its features, including the requests of other
+	// commands sent through the daemon.
+	RequestBudget DaemonRequestBudget `yaml:"requestBudget"`
+	// HealthListen is the TCP address to serve the "/healthz" and
+	// "/readyz" probes on, e.g ":8080". Disabled when empty.
+	HealthListen string `yaml:"healthListen"`
+	// ShutdownTimeout is how long the daemon may take to shut down when
+	// stopped, which includes sending the changes queued while offline.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" jsonschema:"type=string"`
+	// CalDAV is a read-only calendar of your attendance, absences, and
+	// holidays, served by the daemon.
+	CalDAV DaemonCalDAV `yaml:"caldav"`
+}
+
+// DaemonRequestBudget is a ceiling of requests per hour. Requests beyond
+// the budget wait for their turn instead of failing, which also holds up
+// other calls to the daemon, such as "daemon status".
+type DaemonRequestBudget struct {
+	// PerHour is the most requests sent in any hour. Set to 0 to disable.
+	PerHour int `yaml:"perHour" jsonschema:"minimum=0"`
+	// Burst is how many requests may be sent right away after being idle,
+	// which is taken from the hourly budget. Defaults to a tenth of
+	// PerHour when unset.
+	Burst int `jsonschema:"minimum=0"`
+}
+
+// DaemonCalDAV contains configs for the daemon's CalDAV calendar, which
+// calendar clients can subscribe to.
+type DaemonCalDAV struct {
+	// Listen is the TCP address to serve the calendar on,
+	// e.g "127.0.0.1:5232". The calendar is disabled when empty.
+	Listen string
+	// Months is how many months of events to serve, counting back from
+	// and including the current month.
+	Months int
+	// Holidays includes the public holidays in the calendar.
+	Holidays bool
+	// Username and Password enables HTTP basic authentication, which is
+	// recommended if other users can reach the listen address.
+	Username string
+	Password string
+	// OIDC enables authentication with OpenID Connect bearer tokens, in
+	// addition to basic authentication.
+	OIDC OIDC
+}
+
+// MCP contains configs for the "mcp" command, which lets AI assistants use
+// this program via the Model Context Protocol.
+type MCP struct {
+	// ReadOnly hides all tools that modify data, such as "set_attendance".
+	ReadOnly bool `yaml:"readOnly"`
+	// DryRun makes tools that modify data only report what they would
+	// have done, without sending anything to Personio.
+	DryRun bool `yaml:"dryRun"`
+}
+
+// Serve contains configs for the "serve" command's REST API.
+type Serve struct {
+	// Listen is the TCP address to serve the REST API on,
+	// e.g "127.0.0.1:8321".
+	Listen string
+	// Users are the employees that may use the REST API, each with their
+	// own API key and Personio credentials.
+	Users []ServeUser
+	// OIDC makes the REST API authenticate with OpenID Connect bearer
+	// tokens instead of API keys, matching the token's username claim
+	// against each user's auth.email.
+	OIDC OIDC
+	// ManagerGroups are the OIDC groups allowed to use the manager
+	// endpoints, which read the attendance of all users.
+	ManagerGroups []string `yaml:"managerGroups"`
+	// ShutdownTimeout is how long to wait for ongoing requests to finish
+	// when stopped.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" jsonschema:"type=string"`
+}
+
+// Sandbox contains configs for using a Personio test tenant, such as when
+// developing automation before aiming it at the production HR record.
+type Sandbox struct {
+	// Enabled shows a banner on STDERR for every command, to make it
+	// obvious that a test tenant is used.
+	Enabled bool
+	// Name is shown in the banner. Defaults to the profile's name.
+	Name string
+}
+
+// Telemetry contains configs for reporting which of Personio's endpoints
+// fail, so the maintainers notice when Personio changes something that
+// breaks this program. Reports only contain the endpoint's name, the
+// method, status code, content type, names of the headers sent, and the
+// kind of error. They never contain URLs, IDs, header values, request or
+// response bodies, or error messages.
+type Telemetry struct {
+	// Enabled sends a report when a command had failed requests.
+	// Disabled by default.
+	Enabled bool
+	// Endpoint is the URL that reports are sent to via HTTP POST, such as
+	// a self-hosted collector.
+	Endpoint string `jsonschema:"oneof_type=string;null" jsonschema_extras:"format=uri"`
+	// Timeout is how long to wait for the report to be sent, before giving
+	// up on it.
+	Timeout time.Duration `jsonschema:"type=string"`
+}
+
+// OIDC contains configs for protecting a server with OpenID Connect bearer
+// tokens, such as from a company Keycloak, for when it's exposed beyond
+// localhost.
+type OIDC struct {
+	// Issuer is the URL of the OpenID Connect issuer, e.g
+	// "https://keycloak.example.com/realms/company". OIDC is disabled when
+	// empty.
+	Issuer string
+	// Audience is the required "aud" claim of tokens, usually the client
+	// ID. Required when OIDC is enabled.
+	Audience string
+	// UsernameClaim is the token claim that identifies the user. When
+	// it's "email", which is the default, the token's "email_verified"
+	// claim must also be true.
+	UsernameClaim string `yaml:"usernameClaim"`
+	// GroupsClaim is the token claim that lists the user's groups. Nested
+	// claims are separated by dots, e.g "realm_access.roles".
+	GroupsClaim string `yaml:"groupsClaim"`
+	// AllowedGroups rejects tokens of users that are in none of the
+	// groups, when set.
+	AllowedGroups []string `yaml:"allowedGroups"`
+}
+
+// ServeUser is an employee that may use the REST API.
+type ServeUser struct {
+	// Name identifies the user in logs and responses.
+	Name string
+	// APIKey is the secret the user sends in the
+	// "Authorization: Bearer <key>" header. Must be unique per user.
+	APIKey string `yaml:"apiKey"`
+	// Auth is the user's Personio credentials, same as the top-level auth
+	// config. The "sso" provider and password prompts are not supported,
+	// as the server can't ask for input.
+	Auth Auth
+}
+
 // Log contains configs for the command line logging, which compared
 // to the command line output, loggin is written to STDERR and contains
 // small status reports, and is mostly used for debugging.
@@ -116,5 +953,48 @@ func Schema(sourceDir string) *jsonschema.Schema {
 	}
 	s := r.Reflect(&Config{})
 	s.ID = "https://github.com/applejag/rootless-personio/raw/main/personio.schema.json"
+	for _, d := range Deprecations {
+		markDeprecated(s, d)
+	}
 	return s
 }
+
+// Deprecation is a config field that still works, but should no longer
+// be used. Validating a config file that uses it results in a warning.
+type Deprecation struct {
+	// Path is the dot-separated path to the field, e.g "auth.emailToken".
+	Path string
+	// Message tells what to use instead.
+	Message string
+}
+
+// Deprecations are all deprecated config fields.
+var Deprecations []Deprecation
+
+func markDeprecated(root *jsonschema.Schema, d Deprecation) {
+	s := root
+	keys := strings.Split(d.Path, ".")
+	for i, key := range keys {
+		for s != nil && s.Ref != "" {
+			s = root.Definitions[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		}
+		if s == nil || s.Properties == nil {
+			return
+		}
+		name, ok := findKeyFold(s.Properties.Keys(), key)
+		if !ok {
+			return
+		}
+		value, _ := s.Properties.Get(name)
+		prop, ok := value.(*jsonschema.Schema)
+		if !ok {
+			return
+		}
+		if i == len(keys)-1 {
+			prop.Deprecated = true
+			prop.Description = strings.TrimSpace("Deprecated: " + d.Message + "\n\n" + prop.Description)
+			return
+		}
+		s = prop
+	}
+}
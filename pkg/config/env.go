@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+// StringToStructuredHookFunc returns a [mapstructure.DecodeHookFunc] that
+// parses strings as YAML (or JSON) when decoding them into lists, maps, or
+// structs, if they start with "[" or "{". This allows setting any config
+// via environment variables, such as:
+//
+//	PERSONIO_SERVE_USERS='[{"name": "alice", "auth": {"email": "alice@example.com"}}]'
+//
+// Other strings are left as-is, so comma-separated lists still work.
+func StringToStructuredHookFunc() mapstructure.DecodeHookFuncType {
+	return func(from, to reflect.Type, data any) (any, error) {
+		if from.Kind() != reflect.String {
+			return data, nil
+		}
+		switch to.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		default:
+			return data, nil
+		}
+		s := strings.TrimSpace(data.(string))
+		if !strings.HasPrefix(s, "[") && !strings.HasPrefix(s, "{") {
+			return data, nil
+		}
+		var v any
+		if err := yaml.Unmarshal([]byte(s), &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+func TestStringToStructuredHookFunc(t *testing.T) {
+	input := map[string]any{
+		"users":         `[{"name": "alice", "apiKey": "secret"}]`,
+		"managerGroups": "a,b",
+	}
+	var serve Serve
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			StringToStructuredHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		Result: &serve,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(input); err != nil {
+		t.Fatal(err)
+	}
+	if len(serve.Users) != 1 || serve.Users[0].Name != "alice" || serve.Users[0].APIKey != "secret" {
+		t.Errorf("want user alice with API key, got %+v", serve.Users)
+	}
+	if len(serve.ManagerGroups) != 2 {
+		t.Errorf("want comma-separated list of 2 groups, got %q", serve.ManagerGroups)
+	}
+}
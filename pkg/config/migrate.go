@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Migration upgrades an old config file layout to the current one.
+//
+// Migrations are applied in order on every config file when it's loaded,
+// so old config files keep working, and can be written back to the file
+// via the "config migrate" command.
+type Migration struct {
+	// Description is a short summary of what the migration changes.
+	Description string
+	// From and To are dot-separated key paths, e.g "auth.emailToken",
+	// for migrations that move a key. The config validation then tells
+	// the user to run "config migrate" when the From key is used.
+	From, To string
+	// Apply modifies a YAML config document in-place, and returns true if
+	// it changed anything. It must do nothing on already migrated files.
+	// Defaults to moving the From key to To.
+	Apply func(doc *yaml.Node) (bool, error)
+}
+
+// Migrations are all config migrations, in the order they are applied.
+var Migrations []Migration
+
+// Migrate applies all [Migrations] to a YAML config file, and returns the
+// descriptions of the migrations that changed anything. Comments are kept,
+// but the file is reformatted if any migration was applied.
+func Migrate(data []byte) ([]byte, []string, error) {
+	return migrateWith(Migrations, data)
+}
+
+func migrateWith(migrations []Migration, data []byte) ([]byte, []string, error) {
+	if len(migrations) == 0 {
+		return data, nil, nil
+	}
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse config: %w", err)
+		}
+		docs = append(docs, &doc)
+	}
+
+	var applied []string
+	for _, m := range migrations {
+		apply := m.Apply
+		if apply == nil {
+			apply = func(doc *yaml.Node) (bool, error) {
+				return moveKey(doc, m.From, m.To)
+			}
+		}
+		var changed bool
+		for _, doc := range docs {
+			docChanged, err := apply(doc)
+			if err != nil {
+				return nil, nil, fmt.Errorf("migrate config: %s: %w", m.Description, err)
+			}
+			changed = changed || docChanged
+		}
+		if changed {
+			applied = append(applied, m.Description)
+		}
+	}
+	if len(applied) == 0 {
+		return data, nil, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, nil, fmt.Errorf("encode migrated config: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, nil, fmt.Errorf("encode migrated config: %w", err)
+	}
+	return buf.Bytes(), applied, nil
+}
+
+// moveKey moves the value at the dot-separated path from to the path to,
+// creating any missing parent maps. Keys are matched case-insensitively.
+func moveKey(doc *yaml.Node, from, to string) (bool, error) {
+	root := documentRoot(doc)
+	if root == nil {
+		return false, nil
+	}
+	fromKeys := strings.Split(from, ".")
+	parent := lookupMap(root, fromKeys[:len(fromKeys)-1], false)
+	if parent == nil {
+		return false, nil
+	}
+	i := mapKeyIndex(parent, fromKeys[len(fromKeys)-1])
+	if i < 0 {
+		return false, nil
+	}
+	keyNode, valueNode := parent.Content[i], parent.Content[i+1]
+
+	toKeys := strings.Split(to, ".")
+	newParent := lookupMap(root, toKeys[:len(toKeys)-1], true)
+	if newParent == nil {
+		return false, fmt.Errorf("cannot move %q to %q, as a parent of %q is not a map", from, to, to)
+	}
+	if mapKeyIndex(newParent, toKeys[len(toKeys)-1]) >= 0 {
+		return false, fmt.Errorf("cannot move %q to %q, as both are set", from, to)
+	}
+	parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+	keyNode.Value = toKeys[len(toKeys)-1]
+	newParent.Content = append(newParent.Content, keyNode, valueNode)
+	return true, nil
+}
+
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc
+}
+
+// lookupMap returns the map at the path, optionally creating missing maps.
+// Returns nil if not found, or if a value along the path isn't a map.
+func lookupMap(node *yaml.Node, keys []string, create bool) *yaml.Node {
+	for _, key := range keys {
+		i := mapKeyIndex(node, key)
+		if i < 0 {
+			if !create {
+				return nil
+			}
+			child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+				child)
+			node = child
+			continue
+		}
+		child := node.Content[i+1]
+		if child.Kind == yaml.ScalarNode && child.Tag == "!!null" && create {
+			child.Kind, child.Tag, child.Value = yaml.MappingNode, "!!map", ""
+		}
+		if child.Kind != yaml.MappingNode {
+			return nil
+		}
+		node = child
+	}
+	return node
+}
+
+func mapKeyIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// movedKey returns the migration that moves the key at the path, if any.
+func movedKey(path string) (Migration, bool) {
+	for _, m := range Migrations {
+		if m.From != "" && strings.EqualFold(m.From, path) {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
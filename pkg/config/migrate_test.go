@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrate(t *testing.T) {
+	defer func(old []Migration) { Migrations = old }(Migrations)
+	Migrations = []Migration{
+		{Description: "Moved auth.token to auth.csrfToken", From: "auth.token", To: "auth.csrfToken"},
+		{Description: "Moved baseUrl to tenant.url", From: "baseUrl", To: "tenant.url"},
+		{
+			Description: "Never changes anything",
+			Apply:       func(doc *yaml.Node) (bool, error) { return false, nil },
+		},
+	}
+
+	data := []byte(`# my config
+auth:
+  email: me@example.com
+  Token: abc # keep me
+baseUrl: https://example.personio.de
+`)
+	got, applied, err := Migrate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# my config
+auth:
+  email: me@example.com
+  csrfToken: abc # keep me
+tenant:
+  url: https://example.personio.de
+`
+	if string(got) != want {
+		t.Errorf("wrong migrated config\nwant:\n%s\ngot:\n%s", want, got)
+	}
+	if len(applied) != 2 {
+		t.Errorf("want 2 applied migrations, got %d: %v", len(applied), applied)
+	}
+
+	again, applied, err := Migrate(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 || string(again) != string(got) {
+		t.Errorf("want migrations to not change an already migrated config, got %v:\n%s", applied, again)
+	}
+
+	if _, _, err := Migrate([]byte("auth:\n  token: a\n  csrfToken: b\n")); err == nil {
+		t.Error("want error when both the old and new key are set")
+	}
+}
+
+func TestValidateMovedKey(t *testing.T) {
+	defer func(old []Migration) { Migrations = old }(Migrations)
+	Migrations = []Migration{{Description: "Moved auth.token", From: "auth.token", To: "auth.csrfToken"}}
+
+	issues, err := Validate("personio.yaml", []byte("auth:\n  token: abc\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("want 1 issue, got %d: %v", len(issues), issues)
+	}
+	if issues[0].Severity != IssueWarning || !strings.Contains(issues[0].Message, "config migrate") {
+		t.Errorf("want warning to run config migrate, got: %s", issues[0])
+	}
+}
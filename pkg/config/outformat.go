@@ -38,6 +38,12 @@ const (
 	OutFormatPretty OutFormat = "pretty"
 	OutFormatJSON   OutFormat = "json"
 	OutFormatYAML   OutFormat = "yaml"
+	// OutFormatJSONL emits one compact JSON object per line. List commands
+	// print each item as soon as it's available, instead of all at once.
+	OutFormatJSONL OutFormat = "jsonl"
+	// OutFormatGitHub emits GitHub Actions workflow commands, such as
+	// "::error::", for commands that support it, and JSON for the rest.
+	OutFormatGitHub OutFormat = "github"
 )
 
 func _() {
@@ -66,8 +72,12 @@ func (f *OutFormat) Set(value string) error {
 		*f = OutFormatJSON
 	case OutFormatYAML:
 		*f = OutFormatYAML
+	case OutFormatJSONL:
+		*f = OutFormatJSONL
+	case OutFormatGitHub:
+		*f = OutFormatGitHub
 	default:
-		return fmt.Errorf("unknown output format: %q, must be one of: pretty, json, yaml", value)
+		return fmt.Errorf("unknown output format: %q, must be one of: pretty, json, yaml, jsonl, github", value)
 	}
 	return nil
 }
@@ -95,6 +105,8 @@ func (OutFormat) JSONSchema() *jsonschema.Schema {
 			OutFormatPretty,
 			OutFormatJSON,
 			OutFormatYAML,
+			OutFormatJSONL,
+			OutFormatGitHub,
 		},
 		Default: OutFormatDefault,
 	}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrTeamConfigChecksum is returned by [LoadTeamConfig] when the team config
+// file does not match its expected checksum.
+var ErrTeamConfigChecksum = errors.New("team config checksum mismatch")
+
+// teamConfigForbiddenKeys are top-level keys that are not allowed in a team
+// config file, as they are personal or would allow recursive loading. They
+// are matched case-insensitively, as Viper merges keys case-insensitively.
+var teamConfigForbiddenKeys = []string{"auth", "team"}
+
+// LoadTeamConfig reads the team config file from a local file path or an
+// HTTP(S) URL, and verifies its integrity against the expected checksum.
+//
+// The checksum is required when loading from a URL, as the team config can
+// contain hooks that execute arbitrary commands.
+func LoadTeamConfig(team Team) ([]byte, error) {
+	isURL := strings.HasPrefix(team.Source, "https://") || strings.HasPrefix(team.Source, "http://")
+	if isURL && team.SHA256 == "" {
+		return nil, errors.New("team.sha256 is required when team.source is a URL")
+	}
+
+	var data []byte
+	var err error
+	if isURL {
+		data, err = fetchTeamConfig(team.Source)
+	} else {
+		data, err = os.ReadFile(team.Source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if team.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		got := hex.EncodeToString(sum[:])
+		if !strings.EqualFold(got, team.SHA256) {
+			return nil, fmt.Errorf("%w: want sha256 %s, got %s", ErrTeamConfigChecksum, team.SHA256, got)
+		}
+	}
+
+	var keys map[string]any
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parse team config: %w", err)
+	}
+	for key := range keys {
+		for _, forbidden := range teamConfigForbiddenKeys {
+			if strings.EqualFold(key, forbidden) {
+				return nil, fmt.Errorf("team config must not contain the %q field", key)
+			}
+		}
+	}
+	return data, nil
+}
+
+func fetchTeamConfig(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch team config: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
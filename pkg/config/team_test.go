@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTeamConfigForbiddenKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{name: "allowed", yaml: "hooks:\n  preSubmit: [true]\n"},
+		{name: "lower-case auth", yaml: "auth:\n  email: a@example.com\n", wantErr: true},
+		{name: "title-case auth", yaml: "Auth:\n  email: a@example.com\n", wantErr: true},
+		{name: "upper-case team", yaml: "TEAM:\n  source: other.yaml\n", wantErr: true},
+		{name: "mixed-case team", yaml: "tEaM:\n  source: other.yaml\n", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "team.yaml")
+			if err := os.WriteFile(path, []byte(tc.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadTeamConfig(Team{Source: path})
+			if tc.wantErr && err == nil {
+				t.Error("want error, got nil")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("want no error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// IssueSeverity is how severe a config [Issue] is.
+type IssueSeverity string
+
+const (
+	// IssueError is a problem that makes the program misbehave, such as
+	// an unknown key that is silently ignored.
+	IssueError IssueSeverity = "error"
+	// IssueWarning is a problem that still works, such as using a
+	// deprecated field.
+	IssueWarning IssueSeverity = "warning"
+)
+
+// Issue is a problem found when validating a config file.
+type Issue struct {
+	File     string        `json:"file,omitempty"`
+	Line     int           `json:"line"`
+	Column   int           `json:"column"`
+	Path     string        `json:"path"`
+	Severity IssueSeverity `json:"severity"`
+	Message  string        `json:"message"`
+}
+
+// String returns the issue in the format "file:line:column: path: message".
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", i.File, i.Line, i.Column, i.Path, i.Message)
+}
+
+// HasErrors returns true if any of the issues has the [IssueError] severity.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == IssueError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks a YAML config file against the config's JSON schema, and
+// reports unknown keys, type mismatches, and deprecated fields.
+//
+// Keys are matched case-insensitively, the same way the config is loaded.
+// Null values are always allowed, as they leave the field unset.
+func Validate(file string, data []byte) ([]Issue, error) {
+	return validateAgainst(Schema(""), file, data)
+}
+
+func validateAgainst(schema *jsonschema.Schema, file string, data []byte) ([]Issue, error) {
+	v := validator{file: file, defs: schema.Definitions}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+		for _, node := range doc.Content {
+			v.check(node, schema, "")
+		}
+	}
+	sort.SliceStable(v.issues, func(i, j int) bool {
+		return v.issues[i].Line < v.issues[j].Line
+	})
+	return v.issues, nil
+}
+
+type validator struct {
+	file   string
+	defs   jsonschema.Definitions
+	issues []Issue
+}
+
+func (v *validator) report(node *yaml.Node, path string, severity IssueSeverity, format string, args ...any) {
+	if path == "" {
+		path = "(root)"
+	}
+	v.issues = append(v.issues, Issue{
+		File:     v.file,
+		Line:     node.Line,
+		Column:   node.Column,
+		Path:     path,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) resolve(s *jsonschema.Schema) *jsonschema.Schema {
+	for s != nil && s.Ref != "" {
+		def, ok := v.defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		if !ok {
+			return nil
+		}
+		s = def
+	}
+	return s
+}
+
+func (v *validator) check(node *yaml.Node, s *jsonschema.Schema, path string) {
+	s = v.resolve(s)
+	if s == nil {
+		return
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	if alts := append(s.OneOf, s.AnyOf...); len(alts) > 0 {
+		v.checkAlternatives(node, alts, path)
+		return
+	}
+	switch s.Type {
+	case "object":
+		v.checkObject(node, s, path)
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			v.report(node, path, IssueError, "expected a list, got %s", describeNode(node))
+			return
+		}
+		for i, item := range node.Content {
+			v.check(item, s.Items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "string", "integer", "number", "boolean":
+		if !scalarMatchesType(node, s.Type) {
+			v.report(node, path, IssueError, "expected %s, got %s", s.Type, describeNode(node))
+			return
+		}
+		v.checkEnum(node, s, path)
+	}
+}
+
+// checkAlternatives validates against each "oneOf"/"anyOf" schema, and
+// keeps the issues of the first one that matches without errors.
+func (v *validator) checkAlternatives(node *yaml.Node, alts []*jsonschema.Schema, path string) {
+	var types []string
+	for _, alt := range alts {
+		sub := validator{file: v.file, defs: v.defs}
+		sub.check(node, alt, path)
+		if !HasErrors(sub.issues) {
+			v.issues = append(v.issues, sub.issues...)
+			return
+		}
+		if resolved := v.resolve(alt); resolved != nil && resolved.Type != "" && resolved.Type != "null" {
+			types = append(types, resolved.Type)
+		}
+	}
+	v.report(node, path, IssueError, "expected %s, got %s", strings.Join(types, " or "), describeNode(node))
+}
+
+func (v *validator) checkObject(node *yaml.Node, s *jsonschema.Schema, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.report(node, path, IssueError, "expected a map, got %s", describeNode(node))
+		return
+	}
+	var keys []string
+	if s.Properties != nil {
+		keys = s.Properties.Keys()
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		keyPath := joinPath(path, keyNode.Value)
+		key, ok := findKeyFold(keys, keyNode.Value)
+		if !ok {
+			if m, moved := movedKey(keyPath); moved {
+				v.report(keyNode, keyPath, IssueWarning, "moved to %q, run \"config migrate\" to update the file", m.To)
+			} else if s.AdditionalProperties == jsonschema.FalseSchema {
+				v.report(keyNode, keyPath, IssueError, "unknown key %q%s", keyNode.Value, suggestKey(keyNode.Value, keys))
+			} else if s.AdditionalProperties != nil {
+				v.check(valueNode, s.AdditionalProperties, keyPath)
+			}
+			continue
+		}
+		value, _ := s.Properties.Get(key)
+		prop, _ := value.(*jsonschema.Schema)
+		if prop != nil && prop.Deprecated {
+			v.report(keyNode, keyPath, IssueWarning, "%s", firstLine(prop.Description))
+		}
+		v.check(valueNode, prop, keyPath)
+	}
+}
+
+func (v *validator) checkEnum(node *yaml.Node, s *jsonschema.Schema, path string) {
+	if len(s.Enum) == 0 {
+		return
+	}
+	values := make([]string, len(s.Enum))
+	for i, e := range s.Enum {
+		values[i] = fmt.Sprint(e)
+		if strings.EqualFold(values[i], node.Value) {
+			return
+		}
+	}
+	v.report(node, path, IssueError, "invalid value %q, must be one of: %s", node.Value, strings.Join(values, ", "))
+}
+
+func scalarMatchesType(node *yaml.Node, typ string) bool {
+	if node.Kind != yaml.ScalarNode {
+		return false
+	}
+	switch typ {
+	case "integer":
+		return node.Tag == "!!int"
+	case "number":
+		return node.Tag == "!!int" || node.Tag == "!!float"
+	case "boolean":
+		return node.Tag == "!!bool"
+	default:
+		// All scalars can be read as strings
+		return true
+	}
+}
+
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a map"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	switch node.Tag {
+	case "!!int":
+		return fmt.Sprintf("integer %s", node.Value)
+	case "!!float":
+		return fmt.Sprintf("number %s", node.Value)
+	case "!!bool":
+		return fmt.Sprintf("boolean %s", node.Value)
+	default:
+		return fmt.Sprintf("string %q", node.Value)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func findKeyFold(keys []string, key string) (string, bool) {
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// suggestKey returns a hint with the closest known key, or with all
+// known keys if none are close.
+func suggestKey(key string, keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	best, bestDist := "", 3
+	for _, k := range keys {
+		if d := levenshtein(strings.ToLower(key), strings.ToLower(k)); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf(", did you mean %q?", best)
+	}
+	return fmt.Sprintf(", valid keys are: %s", strings.Join(keys, ", "))
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(first int, rest ...int) int {
+	for _, v := range rest {
+		if v < first {
+			first = v
+		}
+	}
+	return first
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateDefaultConfig(t *testing.T) {
+	data, err := os.ReadFile("../../personio.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Validate("personio.yaml", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		t.Errorf("unexpected issue: %s", issue)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	data := []byte(`
+outformat: json
+output: xml
+auth:
+  emial: me@example.com
+  csrfToken: abc
+cache:
+  enabled: "yes"
+report:
+  workingHours: 8h
+hooks:
+  preSubmit: [./check.sh]
+`)
+	issues, err := Validate("personio.yaml", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`personio.yaml:2:1: outformat: unknown key "outformat", valid keys are:`,
+		`personio.yaml:3:9: output: invalid value "xml", must be one of:`,
+		`personio.yaml:5:3: auth.emial: unknown key "emial", did you mean "email"?`,
+		`personio.yaml:8:12: cache.enabled: expected boolean, got string "yes"`,
+		`personio.yaml:10:17: report.workingHours: expected a map, got string "8h"`,
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("want %d issues, got %d: %v", len(want), len(issues), issues)
+	}
+	for i, prefix := range want {
+		if got := issues[i].String(); !strings.HasPrefix(got, prefix) {
+			t.Errorf("issue %d:\nwant prefix %s\ngot         %s", i, prefix, got)
+		}
+	}
+}
+
+func TestValidateDeprecated(t *testing.T) {
+	defer func(old []Deprecation) { Deprecations = old }(Deprecations)
+	Deprecations = []Deprecation{{Path: "cache.dir", Message: "Use cache.path instead."}}
+
+	issues, err := Validate("personio.yaml", []byte("cache:\n  dir: /tmp\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("want 1 issue, got %d: %v", len(issues), issues)
+	}
+	want := "personio.yaml:2:3: cache.dir: Deprecated: Use cache.path instead."
+	if got := issues[0].String(); got != want || issues[0].Severity != IssueWarning {
+		t.Errorf("want warning %q, got %s %q", want, issues[0].Severity, got)
+	}
+}
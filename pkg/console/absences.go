@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+)
+
+// PrintAbsenceSummaries pretty-prints the absence days of each employee,
+// and per absence type when byType is set.
+func PrintAbsenceSummaries(summaries []report.AbsenceSummary, byType bool) {
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	multiple := len(summaries) > 1 || (len(summaries) == 1 && summaries[0].EmployeeID != 0)
+	var header []string
+	if multiple {
+		header = append(header, "Employee")
+	}
+	if byType {
+		header = append(header, "Type")
+	}
+	header = append(header, "Absences", "Days")
+	t.WriteColoredRow(tableHeaderColor, header...)
+	for _, s := range summaries {
+		name := s.Name
+		if name == "" {
+			name = fmt.Sprintf("employee %d", s.EmployeeID)
+		}
+		if byType {
+			for _, total := range s.Types {
+				if multiple {
+					t.WriteCell(name)
+				}
+				t.WriteCell(total.Type)
+				t.WriteCell(strconv.Itoa(total.Absences))
+				t.WriteCell(formatDays(total.Days))
+				t.CommitRow()
+			}
+		}
+		if multiple {
+			t.WriteCellColor(name, tableTotalColor)
+		}
+		if byType {
+			t.WriteCellColor("Total", tableTotalColor)
+		}
+		t.WriteCellColor(strconv.Itoa(s.Absences), tableTotalColor)
+		t.WriteCellColor(formatDays(s.TotalDays), tableTotalColor)
+		t.CommitRow()
+	}
+	t.Fprintln(stdout)
+}
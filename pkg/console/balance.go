@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/fatih/color"
+)
+
+var (
+	balanceTargetColor   = color.New(color.FgHiBlack)
+	balanceWorkColor     = color.New(color.FgYellow)
+	balanceAxisColor     = color.New(color.FgHiBlack)
+	balancePositiveColor = color.New(color.FgGreen)
+	balanceNegativeColor = color.New(color.FgRed)
+)
+
+const (
+	balanceChartHeight   = 12
+	balanceChartMaxWidth = 100
+)
+
+// PrintBalance pretty-prints the flexitime balance as a chart of the
+// cumulative target hours versus the cumulative logged hours.
+func PrintBalance(b report.Balance) {
+	if len(b.Daily) == 0 {
+		fmt.Fprintln(stdout, "  No days in range.")
+		return
+	}
+	days := sampleBalanceDays(b.Daily, balanceChartMaxWidth)
+
+	var maxMin int
+	for _, d := range days {
+		if d.CumulativeTargetMin > maxMin {
+			maxMin = d.CumulativeTargetMin
+		}
+		if d.CumulativeWorkMin > maxMin {
+			maxMin = d.CumulativeWorkMin
+		}
+	}
+	if maxMin == 0 {
+		maxMin = 1
+	}
+	level := func(m int) int {
+		return (m*balanceChartHeight + maxMin/2) / maxMin
+	}
+
+	maxLabel := fmt.Sprintf("%dh", maxMin/60)
+	labelWidth := len(maxLabel)
+	var sb strings.Builder
+	for row := balanceChartHeight; row >= 1; row-- {
+		label := ""
+		switch row {
+		case balanceChartHeight:
+			label = maxLabel
+		case balanceChartHeight / 2:
+			label = fmt.Sprintf("%dh", maxMin/2/60)
+		}
+		balanceAxisColor.Fprintf(&sb, "  %*s ┤", labelWidth, label)
+		for _, d := range days {
+			switch {
+			case level(d.CumulativeTargetMin) == row:
+				balanceTargetColor.Fprint(&sb, "•")
+			case level(d.CumulativeWorkMin) >= row:
+				balanceWorkColor.Fprint(&sb, "█")
+			default:
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	balanceAxisColor.Fprintf(&sb, "  %*s └%s\n", labelWidth, "0h", strings.Repeat("─", len(days)))
+	start, _ := time.Parse(time.DateOnly, b.Start)
+	end, _ := time.Parse(time.DateOnly, b.End)
+	startStr := locale.FormatDate(start)
+	endStr := locale.FormatDate(end)
+	padding := len(days) - len(startStr) - len(endStr)
+	if padding < 1 {
+		padding = 1
+	}
+	balanceAxisColor.Fprintf(&sb, "  %*s  %s%s%s\n", labelWidth, "", startStr, strings.Repeat(" ", padding), endStr)
+	fmt.Fprint(stdout, sb.String())
+
+	fmt.Fprintln(stdout)
+	balanceTargetColor.Fprint(stdout, "  • target")
+	fmt.Fprint(stdout, "  ")
+	balanceWorkColor.Fprintln(stdout, "█ logged")
+	fmt.Fprintln(stdout)
+
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	writeStatsRow(&t, "Target", locale.FormatDuration(minutes(b.TargetMin)))
+	writeStatsRow(&t, "Logged", locale.FormatDuration(minutes(b.WorkMin)))
+	t.WriteCellColor("Balance:", statsLabelColor)
+	balanceStr := locale.FormatDuration(minutes(b.BalanceMin))
+	if b.BalanceMin >= 0 {
+		t.WriteCellColor("+"+balanceStr, balancePositiveColor)
+	} else {
+		t.WriteCellColor(balanceStr, balanceNegativeColor)
+	}
+	t.CommitRow()
+	t.Fprintln(stdout)
+}
+
+// sampleBalanceDays picks evenly spaced days so the chart is at most
+// maxWidth columns wide, while always keeping the last day.
+func sampleBalanceDays(days []report.BalanceDay, maxWidth int) []report.BalanceDay {
+	if len(days) <= maxWidth {
+		return days
+	}
+	sampled := make([]report.BalanceDay, maxWidth)
+	for i := range sampled {
+		sampled[i] = days[(i+1)*len(days)/maxWidth-1]
+	}
+	return sampled
+}
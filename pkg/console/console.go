@@ -22,12 +22,14 @@ package console
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/fatih/color"
 	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/fatih/color"
 	"github.com/mattn/go-colorable"
 	"gopkg.in/typ.v4"
 )
@@ -48,48 +50,25 @@ var (
 	usageHelpColor   = color.New(color.FgHiBlack, color.Italic)
 )
 
-func PrintCalendarMonth(month time.Time, cal *personio.AttendanceCalendar) {
+// PrintCalendarMonth prints the month as a calendar, with a row per week
+// starting on the weekStart day, and the total attended time of each week.
+func PrintCalendarMonth(month time.Time, cal *personio.AttendanceCalendar, weekStart time.Weekday) {
+	writeCalendarMonth(stdout, month, cal, weekStart)
+}
+
+func writeCalendarMonth(w io.Writer, month time.Time, cal *personio.AttendanceCalendar, weekStart time.Weekday) {
 	t := Table{}
 
 	t.SetSpacing("  ")
 	t.SetPrefix("  ")
 
-	t.WriteColoredRow(calendarWeekdayColor,
-		"Monday",
-		"Tuesday",
-		"Wednesday",
-		"Thursday",
-		"Friday",
-		"Saturday",
-		"Sunday")
-	switch month.Weekday() {
-	case time.Monday:
-	case time.Tuesday:
-		t.WriteCell("")
-	case time.Wednesday:
-		t.WriteCell("")
-		t.WriteCell("")
-	case time.Thursday:
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-	case time.Friday:
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-	case time.Saturday:
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-	case time.Sunday:
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
+	weekdays := make([]string, 7)
+	for i := range weekdays {
+		weekdays[i] = locale.Weekday((weekStart + time.Weekday(i)) % 7)
+	}
+	t.WriteColoredRow(calendarWeekdayColor, weekdays...)
+	weekEnd := (weekStart + 6) % 7
+	for pad := (month.Weekday() - weekStart + 7) % 7; pad > 0; pad-- {
 		t.WriteCell("")
 	}
 
@@ -100,14 +79,14 @@ func PrintCalendarMonth(month time.Time, cal *personio.AttendanceCalendar) {
 		dayStr := strconv.Itoa(day.Day())
 		if calDay, ok := findCalendarDayAttendance(day, cal.AttendanceDays.Data); ok && calDay.Attributes.DurationMin > 0 {
 			dur := time.Minute * time.Duration(calDay.Attributes.DurationMin)
-			durStr := FormatDuration(dur)
+			durStr := locale.FormatDuration(dur)
 			t.WriteCellWidth(fmt.Sprintf(
 				"%s (%s)",
 				calendarAttendedColor.Sprint(dayStr),
 				calendarAttendedDurColor.Sprint(durStr),
 			), len(dayStr)+len(durStr)+3)
 			weekTime += dur
-		} else if absence, ok := findCalendarDayAbsence(day, cal.AbsencePeriods.Data); ok {
+		} else if absence, ok := findCalendarDayAbsence(day, cal.GetAbsencePeriods()); ok {
 			t.WriteCellWidth(fmt.Sprintf(
 				"%s (%s)",
 				calendarAttendedColor.Sprint(dayStr),
@@ -118,11 +97,11 @@ func PrintCalendarMonth(month time.Time, cal *personio.AttendanceCalendar) {
 			t.WriteCellColor(dayStr, calendarEmptyColor)
 		}
 		nextDay := day.AddDate(0, 0, 1)
-		if nextDay.Month() != m || day.Weekday() == time.Sunday {
+		if nextDay.Month() != m || day.Weekday() == weekEnd {
 			for len(t.pendingRow) < 7 {
 				t.WriteCell("")
 			}
-			t.WriteCellColor(fmt.Sprintf("∑ %s", FormatDuration(weekTime)), calendarWeekSumColor)
+			t.WriteCellColor(fmt.Sprintf("∑ %s", locale.FormatDuration(weekTime)), calendarWeekSumColor)
 			t.CommitRow()
 			weekTime = 0
 		}
@@ -132,10 +111,10 @@ func PrintCalendarMonth(month time.Time, cal *personio.AttendanceCalendar) {
 		day = nextDay
 	}
 
-	w := t.Width()
-	monthStr := month.Month().String()
-	calendarMonthColor.Printf("%s=== %s ===\n", strings.Repeat(" ", typ.Max(0, w/2-len(monthStr)/2-4)), monthStr)
-	t.Fprintln(stdout)
+	width := t.Width()
+	monthStr := locale.Month(month.Month())
+	calendarMonthColor.Fprintf(w, "%s=== %s ===\n", strings.Repeat(" ", typ.Max(0, width/2-utf8.RuneCountInString(monthStr)/2-4)), monthStr)
+	t.Fprintln(w)
 }
 
 // UsageTemplate returns a lightly colored usage template for Cobra.
@@ -184,9 +163,9 @@ func UsageTemplate() string {
 }
 
 func findCalendarDayAttendance(day time.Time, days []personio.CalendarDay) (personio.CalendarDay, bool) {
-	dayStr := day.Format(time.DateOnly)
+	date := personio.DateOf(day)
 	for _, calDay := range days {
-		if calDay.Attributes.Day == dayStr {
+		if calDay.Attributes.Day == date {
 			return calDay, true
 		}
 	}
@@ -195,19 +174,8 @@ func findCalendarDayAttendance(day time.Time, days []personio.CalendarDay) (pers
 
 func findCalendarDayAbsence(day time.Time, days []personio.CalendarAbsencePeriod) (personio.CalendarAbsencePeriod, bool) {
 	day = day.Add(time.Minute)
-	const layout = "2006-01-02 15:04:05"
 	for _, absence := range days {
-		start, err := time.Parse(layout, absence.StartTime)
-		if err != nil {
-			fmt.Println("err: start time:", err)
-			continue
-		}
-		end, err := time.Parse(layout, absence.EndTime)
-		if err != nil {
-			fmt.Println("err: end time:", err)
-			continue
-		}
-		if day.After(start) && day.Before(end) {
+		if day.After(absence.StartTime.Time) && day.Before(absence.EndTime.Time) {
 			return absence, true
 		}
 	}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/fatih/color"
+)
+
+func TestWriteCalendarMonthWeekStart(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+	// May 2024 starts on a Wednesday, and has Sunday the 5th
+	month := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	cal := &personio.AttendanceCalendar{}
+
+	tests := []struct {
+		name      string
+		weekStart time.Weekday
+		header    []string
+		firstWeek []string
+	}{
+		{
+			name:      "monday",
+			weekStart: time.Monday,
+			header:    []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"},
+			firstWeek: []string{"1", "2", "3", "4", "5", "∑"},
+		},
+		{
+			name:      "sunday",
+			weekStart: time.Sunday,
+			header:    []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+			firstWeek: []string{"1", "2", "3", "4", "∑"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			writeCalendarMonth(&sb, month, cal, tc.weekStart)
+			lines := strings.Split(sb.String(), "\n")
+			if len(lines) < 3 {
+				t.Fatalf("want month, header, and weeks, got:\n%s", sb.String())
+			}
+			if got := strings.Fields(lines[1]); strings.Join(got, " ") != strings.Join(tc.header, " ") {
+				t.Errorf("want header %v, got %v", tc.header, got)
+			}
+			if got := strings.Fields(lines[2]); len(got) < len(tc.firstWeek) || strings.Join(got[:len(tc.firstWeek)], " ") != strings.Join(tc.firstWeek, " ") {
+				t.Errorf("want first week %v, got %v", tc.firstWeek, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/fatih/color"
+)
+
+var dayAlertColor = color.New(color.FgRed)
+
+// PrintDayDetail pretty-prints the details of a single day.
+func PrintDayDetail(d report.DayDetail) {
+	date, _ := time.Parse(time.DateOnly, d.Date)
+
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	writeStatsRow(&t, "Date", fmt.Sprintf("%s %s", locale.Weekday(date.Weekday()), locale.FormatDate(date)))
+	if d.DayID != nil {
+		writeStatsRow(&t, "Day ID", d.DayID.String())
+	} else {
+		writeStatsRow(&t, "Day ID", "(none)")
+	}
+	if d.Status != "" {
+		writeStatsRow(&t, "Status", d.Status)
+	}
+	writeStatsRow(&t, "Worked", locale.FormatDuration(minutes(d.WorkMin)))
+	writeStatsRow(&t, "Breaks", locale.FormatDuration(minutes(d.BreakMin)))
+	if d.Holiday != nil {
+		holiday := d.Holiday.Name
+		if d.Holiday.HalfDay {
+			holiday += " (half day)"
+		}
+		writeStatsRow(&t, "Holiday", holiday)
+	}
+	if d.Absence != nil {
+		writeStatsRow(&t, "Absence", fmt.Sprintf("%s (%s – %s)",
+			d.Absence.Name, d.Absence.StartDate, d.Absence.EndDate))
+	}
+	t.Fprintln(stdout)
+
+	fmt.Fprintln(stdout)
+	if len(d.Periods) == 0 {
+		statsLabelColor.Fprintln(stdout, "  No attendance periods.")
+	} else {
+		t := Table{}
+		t.SetSpacing("  ")
+		t.SetPrefix("  ")
+		t.WriteColoredRow(tableHeaderColor, "Start", "End", "Duration", "Type", "Project", "Comment", "ID")
+		for _, p := range d.Periods {
+			t.WriteCell(locale.FormatTime(p.Start))
+			t.WriteCell(locale.FormatTime(p.End))
+			t.WriteCell(locale.FormatDuration(minutes(p.DurationMin)))
+			t.WriteCell(string(p.Type))
+			if p.ProjectID != 0 {
+				t.WriteCell(fmt.Sprint(p.ProjectID))
+			} else {
+				t.WriteCell("")
+			}
+			t.WriteCell(p.Comment)
+			t.WriteCellColor(p.ID.String(), statsLabelColor)
+			t.CommitRow()
+		}
+		t.Fprintln(stdout)
+	}
+
+	if len(d.Alerts) > 0 {
+		fmt.Fprintln(stdout)
+		statsLabelColor.Fprintln(stdout, "  Alerts:")
+		for _, alert := range d.Alerts {
+			dayAlertColor.Fprintf(stdout, "  %s\n", alert)
+		}
+	}
+}
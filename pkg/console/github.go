@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+)
+
+var githubSeverityCommands = map[report.Severity]string{
+	report.SeverityInfo:    "notice",
+	report.SeverityWarning: "warning",
+	report.SeverityError:   "error",
+}
+
+var githubSeverityEmojis = map[report.Severity]string{
+	report.SeverityInfo:    ":information_source:",
+	report.SeverityWarning: ":warning:",
+	report.SeverityError:   ":x:",
+}
+
+// PrintLintFindingsGitHub prints the suspicious attendance entries as
+// GitHub Actions workflow commands, which shows them as annotations on
+// the workflow run.
+func PrintLintFindingsGitHub(findings []report.Finding) {
+	for _, f := range findings {
+		fmt.Fprintf(stdout, "::%s title=%s::%s\n",
+			githubSeverityCommands[f.Severity],
+			githubEscapeProperty(f.Date+" "+f.Rule),
+			githubEscapeData(f.Message))
+	}
+}
+
+// WriteLintStepSummaryGitHub writes the suspicious attendance entries
+// as Markdown, meant to be appended to the file in the
+// $GITHUB_STEP_SUMMARY environment variable.
+func WriteLintStepSummaryGitHub(w io.Writer, month string, findings []report.Finding) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## Attendance lint for %s\n\n", month)
+	if len(findings) == 0 {
+		sb.WriteString(":white_check_mark: No findings.\n")
+	} else {
+		sb.WriteString("| | Date | Rule | Message |\n")
+		sb.WriteString("| --- | --- | --- | --- |\n")
+		for _, f := range findings {
+			fmt.Fprintf(&sb, "| %s | %s | `%s` | %s |\n",
+				githubSeverityEmojis[f.Severity], f.Date, f.Rule,
+				strings.ReplaceAll(f.Message, "|", `\|`))
+		}
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func githubEscapeData(s string) string {
+	return strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+	).Replace(s)
+}
+
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+		":", "%3A",
+		",", "%2C",
+	).Replace(s)
+}
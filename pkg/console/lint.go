@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/fatih/color"
+)
+
+var lintSeverityColors = map[report.Severity]*color.Color{
+	report.SeverityInfo:    color.New(color.FgCyan),
+	report.SeverityWarning: color.New(color.FgYellow),
+	report.SeverityError:   color.New(color.FgRed),
+}
+
+var lintNoFindingsColor = color.New(color.FgGreen)
+
+// PrintLintFindings pretty-prints the suspicious attendance entries.
+func PrintLintFindings(findings []report.Finding) {
+	if len(findings) == 0 {
+		lintNoFindingsColor.Fprintln(stdout, "  No findings.")
+		return
+	}
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	t.WriteColoredRow(tableHeaderColor, "Date", "Severity", "Rule", "Message")
+	for _, f := range findings {
+		dateStr := f.Date
+		if date, err := time.Parse(time.DateOnly, f.Date); err == nil {
+			dateStr = locale.FormatDate(date)
+		}
+		t.WriteCell(dateStr)
+		t.WriteCellColor(string(f.Severity), lintSeverityColors[f.Severity])
+		t.WriteCell(f.Rule)
+		t.WriteCell(f.Message)
+		t.CommitRow()
+	}
+	t.Fprintln(stdout)
+}
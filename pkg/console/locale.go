@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale controls how dates, times, and durations are formatted in
+// the pretty-printed output.
+type Locale struct {
+	Weekdays [7]string  // indexed by [time.Weekday], so Sunday first
+	Months   [12]string // indexed by [time.Month] - 1
+
+	DateLayout       string // ex: "2006-01-02"
+	TimeLayout       string // ex: "15:04"
+	DecimalDuration  bool   // show "8.5" instead of "8:30"
+	DecimalSeparator byte   // ex: '.'
+}
+
+// Available values for the [NewLocale] function.
+const (
+	LanguageEnglish = "en"
+	LanguageGerman  = "de"
+
+	DateFormatISO    = "iso"
+	DateFormatGerman = "de"
+	DateFormatUS     = "us"
+
+	TimeFormat24h = "24h"
+	TimeFormat12h = "12h"
+
+	DurationFormatClock   = "clock"
+	DurationFormatDecimal = "decimal"
+)
+
+var (
+	englishWeekdays = [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+	englishMonths   = [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	germanWeekdays  = [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"}
+	germanMonths    = [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"}
+)
+
+// DefaultLocale is the locale used unless [SetLocale] is called.
+var DefaultLocale = Locale{
+	Weekdays:         englishWeekdays,
+	Months:           englishMonths,
+	DateLayout:       time.DateOnly,
+	TimeLayout:       "15:04",
+	DecimalSeparator: '.',
+}
+
+var locale = DefaultLocale
+
+// SetLocale changes the locale used by all pretty-printing functions
+// in this package.
+func SetLocale(l Locale) {
+	locale = l
+}
+
+// NewLocale creates a new locale from the language, date format, time format,
+// and duration format names. Empty values fall back to the [DefaultLocale].
+func NewLocale(language, dateFormat, timeFormat, durationFormat string) (Locale, error) {
+	l := DefaultLocale
+
+	switch language {
+	case "", LanguageEnglish:
+	case LanguageGerman:
+		l.Weekdays = germanWeekdays
+		l.Months = germanMonths
+		l.DecimalSeparator = ','
+	default:
+		return Locale{}, fmt.Errorf("unknown language: %q, must be one of: en, de", language)
+	}
+
+	switch dateFormat {
+	case "", DateFormatISO:
+	case DateFormatGerman:
+		l.DateLayout = "02.01.2006"
+	case DateFormatUS:
+		l.DateLayout = "01/02/2006"
+	default:
+		return Locale{}, fmt.Errorf("unknown date format: %q, must be one of: iso, de, us", dateFormat)
+	}
+
+	switch timeFormat {
+	case "", TimeFormat24h:
+	case TimeFormat12h:
+		l.TimeLayout = "3:04 PM"
+	default:
+		return Locale{}, fmt.Errorf("unknown time format: %q, must be one of: 24h, 12h", timeFormat)
+	}
+
+	switch durationFormat {
+	case "", DurationFormatClock:
+	case DurationFormatDecimal:
+		l.DecimalDuration = true
+	default:
+		return Locale{}, fmt.Errorf("unknown duration format: %q, must be one of: clock, decimal", durationFormat)
+	}
+
+	return l, nil
+}
+
+// Weekday returns the translated name of the weekday.
+func (l Locale) Weekday(d time.Weekday) string {
+	return l.Weekdays[d]
+}
+
+// Month returns the translated name of the month.
+func (l Locale) Month(m time.Month) string {
+	return l.Months[m-1]
+}
+
+// FormatDate formats the date part of the time.
+func (l Locale) FormatDate(t time.Time) string {
+	return t.Format(l.DateLayout)
+}
+
+// FormatTime formats the time-of-day part of the time.
+func (l Locale) FormatTime(t time.Time) string {
+	return t.Format(l.TimeLayout)
+}
+
+// FormatDuration formats the duration either as h:mm (see [FormatDuration])
+// or as decimal hours with two decimals, e.g "8.50" or "8,50".
+func (l Locale) FormatDuration(d time.Duration) string {
+	if !l.DecimalDuration {
+		return FormatDuration(d)
+	}
+	s := strconv.FormatFloat(d.Hours(), 'f', 2, 64)
+	if l.DecimalSeparator != '.' {
+		s = strings.Replace(s, ".", string(l.DecimalSeparator), 1)
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocaleFormatDuration(t *testing.T) {
+	var tests = []struct {
+		name           string
+		language       string
+		durationFormat string
+		dur            time.Duration
+		want           string
+	}{
+		{
+			name: "default clock",
+			dur:  8*time.Hour + 30*time.Minute,
+			want: "8:30",
+		},
+		{
+			name:           "english decimal",
+			language:       LanguageEnglish,
+			durationFormat: DurationFormatDecimal,
+			dur:            8*time.Hour + 30*time.Minute,
+			want:           "8.50",
+		},
+		{
+			name:           "german decimal comma",
+			language:       LanguageGerman,
+			durationFormat: DurationFormatDecimal,
+			dur:            7*time.Hour + 45*time.Minute,
+			want:           "7,75",
+		},
+		{
+			name:           "negative decimal",
+			language:       LanguageGerman,
+			durationFormat: DurationFormatDecimal,
+			dur:            -90 * time.Minute,
+			want:           "-1,50",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := NewLocale(tc.language, "", "", tc.durationFormat)
+			if err != nil {
+				t.Fatalf("new locale: %s", err)
+			}
+			got := l.FormatDuration(tc.dur)
+			if got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLocaleGerman(t *testing.T) {
+	l, err := NewLocale(LanguageGerman, DateFormatGerman, TimeFormat24h, "")
+	if err != nil {
+		t.Fatalf("new locale: %s", err)
+	}
+	date := time.Date(2023, time.March, 6, 17, 5, 0, 0, time.UTC)
+	if got, want := l.FormatDate(date), "06.03.2023"; got != want {
+		t.Errorf("date: want %q, got %q", want, got)
+	}
+	if got, want := l.FormatTime(date), "17:05"; got != want {
+		t.Errorf("time: want %q, got %q", want, got)
+	}
+	if got, want := l.Weekday(date.Weekday()), "Montag"; got != want {
+		t.Errorf("weekday: want %q, got %q", want, got)
+	}
+	if got, want := l.Month(date.Month()), "März"; got != want {
+		t.Errorf("month: want %q, got %q", want, got)
+	}
+}
+
+func TestNewLocaleInvalid(t *testing.T) {
+	if _, err := NewLocale("sv", "", "", ""); err == nil {
+		t.Error("want error for unknown language, got nil")
+	}
+	if _, err := NewLocale("", "", "13h", ""); err == nil {
+		t.Error("want error for unknown time format, got nil")
+	}
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/fatih/color"
+)
+
+var (
+	tableHeaderColor = color.New(color.FgWhite, color.Underline)
+	tableTotalColor  = color.New(color.FgHiYellow)
+)
+
+// PrintLocationSummary pretty-prints the number of office and home office
+// days per month.
+func PrintLocationSummary(s report.LocationSummary) {
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	t.WriteColoredRow(tableHeaderColor, "Month", "Office", "Remote", "Unknown")
+	for _, m := range s.Months {
+		month, err := time.Parse("2006-01", m.Month)
+		monthStr := m.Month
+		if err == nil {
+			monthStr = fmt.Sprintf("%s %d", locale.Month(month.Month()), month.Year())
+		}
+		t.WriteCell(monthStr)
+		t.WriteCell(fmt.Sprint(m.OfficeDays))
+		t.WriteCell(fmt.Sprint(m.RemoteDays))
+		t.WriteCell(fmt.Sprint(m.UnknownDays))
+		t.CommitRow()
+	}
+	t.WriteCellColor("Total", tableTotalColor)
+	t.WriteCellColor(fmt.Sprint(s.OfficeDays), tableTotalColor)
+	t.WriteCellColor(fmt.Sprint(s.RemoteDays), tableTotalColor)
+	t.WriteCellColor(fmt.Sprint(s.UnknownDays), tableTotalColor)
+	t.CommitRow()
+	t.Fprintln(stdout)
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+
+	"github.com/applejag/rootless-personio/pkg/mirror"
+	"github.com/fatih/color"
+)
+
+var queryNullColor = color.New(color.FgHiBlack)
+
+// PrintQueryResult pretty-prints the result of an SQL query as a table.
+func PrintQueryResult(result *mirror.QueryResult) {
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	t.WriteColoredRow(tableHeaderColor, result.Columns...)
+	for _, row := range result.Rows {
+		for _, value := range row {
+			if value == nil {
+				t.WriteCellColor("NULL", queryNullColor)
+				continue
+			}
+			t.WriteCell(fmt.Sprint(value))
+		}
+		t.CommitRow()
+	}
+	t.Fprintln(stdout)
+	fmt.Fprintf(stdout, "  (%d rows)\n", len(result.Rows))
+}
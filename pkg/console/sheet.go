@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"strconv"
+
+	"github.com/applejag/rootless-personio/pkg/sheet"
+	"github.com/fatih/color"
+)
+
+var sheetErrorColor = color.New(color.FgRed)
+
+// PrintSheetPreview pretty-prints how spreadsheet rows were parsed into
+// attendance periods, with the errors of rows that failed to parse.
+func PrintSheetPreview(rows []sheet.Row) {
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	t.WriteColoredRow(tableHeaderColor, "Row", "Date", "Start", "End", "Type", "Comment")
+	for _, row := range rows {
+		t.WriteCell(strconv.Itoa(row.Line))
+		if row.Err != nil {
+			// Errors go in the last column, to not widen the others
+			for i := 0; i < 4; i++ {
+				t.WriteCell("")
+			}
+			t.WriteCellColor(row.Err.Error(), sheetErrorColor)
+			t.CommitRow()
+			continue
+		}
+		p := row.Period
+		t.WriteCell(p.Start.Format("2006-01-02"))
+		t.WriteCell(p.Start.Format("15:04"))
+		if p.End.YearDay() != p.Start.YearDay() {
+			t.WriteCell(p.End.Format("15:04") + " +1d")
+		} else {
+			t.WriteCell(p.End.Format("15:04"))
+		}
+		t.WriteCell(string(p.PeriodType))
+		t.WriteCell(p.GetComment())
+		t.CommitRow()
+	}
+	t.Fprintln(stdout)
+}
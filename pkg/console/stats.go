@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/fatih/color"
+)
+
+var (
+	statsLabelColor     = color.New(color.FgHiBlack)
+	statsValueColor     = color.New(color.FgHiYellow)
+	statsSparklineColor = color.New(color.FgYellow)
+	statsHistogramColor = color.New(color.FgYellow)
+)
+
+var sparklineRunes = []rune("▁▂▃▄▅▆▇█")
+
+// PrintStats pretty-prints the attendance statistics.
+func PrintStats(stats report.Stats) {
+	start, _ := time.Parse(time.DateOnly, stats.Start)
+	end, _ := time.Parse(time.DateOnly, stats.End)
+
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	writeStatsRow(&t, "Range", fmt.Sprintf("%s – %s", locale.FormatDate(start), locale.FormatDate(end)))
+	writeStatsRow(&t, "Days worked", fmt.Sprintf("%d of %d workdays", stats.DaysWorked, stats.Workdays))
+	writeStatsRow(&t, "Missing days", fmt.Sprint(stats.MissingDays))
+	writeStatsRow(&t, "Total work", locale.FormatDuration(minutes(stats.TotalWorkMin)))
+	writeStatsRow(&t, "Average work", locale.FormatDuration(minutes(stats.AverageWorkMin)))
+	writeStatsRow(&t, "Average start", formatTimeOfDay(stats.AverageStart))
+	writeStatsRow(&t, "Average end", formatTimeOfDay(stats.AverageEnd))
+	writeStatsRow(&t, "Earliest start", formatTimeOfDay(stats.EarliestStart))
+	writeStatsRow(&t, "Latest end", formatTimeOfDay(stats.LatestEnd))
+	if stats.LongestDay != nil {
+		date, _ := time.Parse(time.DateOnly, stats.LongestDay.Date)
+		writeStatsRow(&t, "Longest day", fmt.Sprintf("%s (%s)",
+			locale.FormatDuration(minutes(stats.LongestDay.WorkMin)),
+			locale.FormatDate(date)))
+	}
+	if stats.LongestStreak != nil {
+		streakStart, _ := time.Parse(time.DateOnly, stats.LongestStreak.Start)
+		streakEnd, _ := time.Parse(time.DateOnly, stats.LongestStreak.End)
+		writeStatsRow(&t, "Longest streak", fmt.Sprintf("%d days (%s – %s)",
+			stats.LongestStreak.Days,
+			locale.FormatDate(streakStart),
+			locale.FormatDate(streakEnd)))
+	}
+	writeStatsRow(&t, "Current streak", fmt.Sprintf("%d days", stats.CurrentStreak))
+	t.Fprintln(stdout)
+
+	fmt.Fprintln(stdout)
+	statsLabelColor.Fprintln(stdout, "  Daily hours:")
+	statsSparklineColor.Fprintf(stdout, "  %s\n", Sparkline(stats.Daily))
+
+	fmt.Fprintln(stdout)
+	statsLabelColor.Fprintln(stdout, "  Distribution of hours per day worked:")
+	printStatsHistogram(stats.Histogram)
+}
+
+func writeStatsRow(t *Table, label, value string) {
+	t.WriteCellColor(label+":", statsLabelColor)
+	t.WriteCellColor(value, statsValueColor)
+	t.CommitRow()
+}
+
+func printStatsHistogram(buckets []report.StatsHistogramBucket) {
+	var maxDays int
+	for _, b := range buckets {
+		if b.Days > maxDays {
+			maxDays = b.Days
+		}
+	}
+	const barWidth = 40
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	for _, b := range buckets {
+		if b.Days == 0 {
+			continue
+		}
+		label := fmt.Sprintf("%d–%dh", b.FromHours, b.ToHours)
+		if b.ToHours >= 24 {
+			label = fmt.Sprintf("%dh+", b.FromHours)
+		}
+		bar := strings.Repeat("█", b.Days*barWidth/maxDays)
+		if bar == "" {
+			bar = "▏"
+		}
+		t.WriteCellColor(label, statsLabelColor)
+		t.WriteCellWidth(statsHistogramColor.Sprint(bar)+fmt.Sprintf(" %d", b.Days),
+			utf8.RuneCountInString(bar)+1+uintWidth(uint(b.Days)))
+		t.CommitRow()
+	}
+	t.Fprintln(stdout)
+}
+
+// Sparkline renders the amount of work per day as a single line of block
+// characters, scaled to the day with the most work. Days without work are
+// rendered as spaces.
+func Sparkline(days []report.StatsDay) string {
+	var maxMin int
+	for _, d := range days {
+		if d.WorkMin > maxMin {
+			maxMin = d.WorkMin
+		}
+	}
+	var sb strings.Builder
+	for _, d := range days {
+		if d.WorkMin <= 0 {
+			sb.WriteByte(' ')
+			continue
+		}
+		i := d.WorkMin * (len(sparklineRunes) - 1) / maxMin
+		sb.WriteRune(sparklineRunes[i])
+	}
+	return sb.String()
+}
+
+func formatTimeOfDay(hhmm string) string {
+	if hhmm == "" {
+		return "-"
+	}
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return hhmm
+	}
+	return locale.FormatTime(t)
+}
+
+func minutes(m int) time.Duration {
+	return time.Duration(m) * time.Minute
+}
@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// StatusBarFormat is the output format of [WriteStatusBar].
+type StatusBarFormat string
+
+const (
+	StatusBarText     StatusBarFormat = "text"
+	StatusBarWaybar   StatusBarFormat = "waybar"
+	StatusBarI3blocks StatusBarFormat = "i3blocks"
+	StatusBarPolybar  StatusBarFormat = "polybar"
+)
+
+// StatusBarFormats are all the valid [StatusBarFormat] values.
+var StatusBarFormats = []StatusBarFormat{
+	StatusBarText,
+	StatusBarWaybar,
+	StatusBarI3blocks,
+	StatusBarPolybar,
+}
+
+// ParseStatusBarFormat returns the status bar format of the given name.
+func ParseStatusBarFormat(s string) (StatusBarFormat, error) {
+	for _, f := range StatusBarFormats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("invalid status bar format %q, must be one of: %s",
+		s, strings.Join(StatusBarFormatNames(), ", "))
+}
+
+// StatusBarFormatNames returns the names of all [StatusBarFormats].
+func StatusBarFormatNames() []string {
+	names := make([]string, len(StatusBarFormats))
+	for i, f := range StatusBarFormats {
+		names[i] = string(f)
+	}
+	return names
+}
+
+const statusBarColorClockedIn = "#50fa7b"
+
+// StatusBar is the current state shown in a status bar.
+type StatusBar struct {
+	// ClockedInAt is when the punch clock was started, or nil if
+	// not clocked in.
+	ClockedInAt *time.Time
+	// Worked is the amount of work today, including the time since
+	// clocking in.
+	Worked time.Duration
+	// Target is the target amount of work today.
+	Target time.Duration
+	// Synced is false when today's submitted attendance is unknown,
+	// meaning Worked only includes the time since clocking in.
+	Synced bool
+}
+
+// Remaining returns the amount of work left to reach today's target,
+// or zero if the target has been reached.
+func (s StatusBar) Remaining() time.Duration {
+	if s.Worked >= s.Target {
+		return 0
+	}
+	return s.Target - s.Worked
+}
+
+func (s StatusBar) state() string {
+	if s.ClockedInAt != nil {
+		return "in"
+	}
+	return "out"
+}
+
+func (s StatusBar) shortText() string {
+	return fmt.Sprintf("%s %s", s.state(), FormatDuration(s.Worked))
+}
+
+func (s StatusBar) progress() string {
+	switch {
+	case s.Target == 0:
+		return ""
+	case s.Remaining() == 0:
+		return " (done)"
+	default:
+		return fmt.Sprintf(" (%s left)", FormatDuration(s.Remaining()))
+	}
+}
+
+// Text returns the status as a single line, e.g "in 4:15 (3:45 left)".
+func (s StatusBar) Text() string {
+	return s.shortText() + s.progress()
+}
+
+func (s StatusBar) tooltip() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Worked %s of %s today", FormatDuration(s.Worked), FormatDuration(s.Target))
+	if s.ClockedInAt != nil {
+		fmt.Fprintf(&sb, "\nClocked in since %s", s.ClockedInAt.Local().Format("15:04"))
+	}
+	if !s.Synced {
+		sb.WriteString("\nMirror not synced, only counting the punch clock")
+	}
+	return sb.String()
+}
+
+func (s StatusBar) percentage() int {
+	if s.Worked >= s.Target {
+		return 100
+	}
+	return int(s.Worked * 100 / s.Target)
+}
+
+type waybarStatus struct {
+	Text       string `json:"text"`
+	Tooltip    string `json:"tooltip"`
+	Class      string `json:"class"`
+	Percentage int    `json:"percentage"`
+}
+
+type i3blocksStatus struct {
+	FullText  string `json:"full_text"`
+	ShortText string `json:"short_text"`
+	Color     string `json:"color,omitempty"`
+}
+
+// WriteStatusBar writes the status as a single line in the given format:
+//
+//   - text: plain text
+//   - waybar: JSON for waybar's "custom" module with "return-type": "json"
+//   - i3blocks: JSON for i3blocks' "format=json"
+//   - polybar: text with polybar's color format tags
+func WriteStatusBar(w io.Writer, format StatusBarFormat, s StatusBar) error {
+	switch format {
+	case StatusBarWaybar:
+		return json.NewEncoder(w).Encode(waybarStatus{
+			Text:       s.Text(),
+			Tooltip:    s.tooltip(),
+			Class:      "clocked-" + s.state(),
+			Percentage: s.percentage(),
+		})
+	case StatusBarI3blocks:
+		status := i3blocksStatus{
+			FullText:  s.Text(),
+			ShortText: s.shortText(),
+		}
+		if s.ClockedInAt != nil {
+			status.Color = statusBarColorClockedIn
+		}
+		return json.NewEncoder(w).Encode(status)
+	case StatusBarPolybar:
+		state := s.state()
+		if s.ClockedInAt != nil {
+			state = "%{F" + statusBarColorClockedIn + "}" + state + "%{F-}"
+		}
+		_, err := fmt.Fprintf(w, "%s %s%s\n", state, FormatDuration(s.Worked), s.progress())
+		return err
+	default:
+		_, err := fmt.Fprintln(w, s.Text())
+		return err
+	}
+}
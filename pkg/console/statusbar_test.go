@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteStatusBar(t *testing.T) {
+	clockedInAt := time.Date(2023, 1, 18, 9, 2, 0, 0, time.Local)
+	clockedIn := StatusBar{
+		ClockedInAt: &clockedInAt,
+		Worked:      4*time.Hour + 15*time.Minute,
+		Target:      8 * time.Hour,
+		Synced:      true,
+	}
+	done := StatusBar{
+		Worked: 8*time.Hour + 10*time.Minute,
+		Target: 8 * time.Hour,
+		Synced: true,
+	}
+	var tests = []struct {
+		name   string
+		format StatusBarFormat
+		status StatusBar
+		want   string
+	}{
+		{
+			name:   "text",
+			format: StatusBarText,
+			status: clockedIn,
+			want:   "in 4:15 (3:45 left)\n",
+		},
+		{
+			name:   "text done",
+			format: StatusBarText,
+			status: done,
+			want:   "out 8:10 (done)\n",
+		},
+		{
+			name:   "text no target",
+			format: StatusBarText,
+			status: StatusBar{Worked: time.Hour},
+			want:   "out 1:00\n",
+		},
+		{
+			name:   "waybar",
+			format: StatusBarWaybar,
+			status: done,
+			want:   `{"text":"out 8:10 (done)","tooltip":"Worked 8:10 of 8:00 today","class":"clocked-out","percentage":100}` + "\n",
+		},
+		{
+			name:   "i3blocks",
+			format: StatusBarI3blocks,
+			status: clockedIn,
+			want:   `{"full_text":"in 4:15 (3:45 left)","short_text":"in 4:15","color":"#50fa7b"}` + "\n",
+		},
+		{
+			name:   "polybar",
+			format: StatusBarPolybar,
+			status: clockedIn,
+			want:   "%{F#50fa7b}in%{F-} 4:15 (3:45 left)\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := WriteStatusBar(&sb, tc.format, tc.status); err != nil {
+				t.Fatal(err)
+			}
+			if got := sb.String(); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+)
+
+// PrintSurchargeSummary pretty-prints the surcharge-eligible work per
+// category and month.
+func PrintSurchargeSummary(s report.SurchargeSummary) {
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	t.WriteColoredRow(tableHeaderColor, "Month", "On-call", "Night", "Weekend", "Holiday")
+	for _, m := range s.Months {
+		month, err := time.Parse("2006-01", m.Month)
+		monthStr := m.Month
+		if err == nil {
+			monthStr = fmt.Sprintf("%s %d", locale.Month(month.Month()), month.Year())
+		}
+		t.WriteCell(monthStr)
+		t.WriteCell(locale.FormatDuration(minutes(m.OnCallMin)))
+		t.WriteCell(locale.FormatDuration(minutes(m.NightMin)))
+		t.WriteCell(locale.FormatDuration(minutes(m.WeekendMin)))
+		t.WriteCell(locale.FormatDuration(minutes(m.HolidayMin)))
+		t.CommitRow()
+	}
+	t.WriteCellColor("Total", tableTotalColor)
+	t.WriteCellColor(locale.FormatDuration(minutes(s.Total.OnCallMin)), tableTotalColor)
+	t.WriteCellColor(locale.FormatDuration(minutes(s.Total.NightMin)), tableTotalColor)
+	t.WriteCellColor(locale.FormatDuration(minutes(s.Total.WeekendMin)), tableTotalColor)
+	t.WriteCellColor(locale.FormatDuration(minutes(s.Total.HolidayMin)), tableTotalColor)
+	t.CommitRow()
+	t.Fprintln(stdout)
+}
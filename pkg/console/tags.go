@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+	"github.com/fatih/color"
+)
+
+var tagColor = color.New(color.FgHiCyan)
+
+// PrintTagSummary pretty-prints the amount of work per local-only tag.
+func PrintTagSummary(s report.TagSummary) {
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	t.WriteColoredRow(tableHeaderColor, "Tag", "Work", "Periods")
+	for _, tag := range s.Tags {
+		t.WriteCellColor("#"+tag.Tag, tagColor)
+		t.WriteCell(locale.FormatDuration(minutes(tag.WorkMin)))
+		t.WriteCell(fmt.Sprint(tag.Periods))
+		t.CommitRow()
+	}
+	t.WriteCellColor("Untagged", tableTotalColor)
+	t.WriteCellColor(locale.FormatDuration(minutes(s.UntaggedMin)), tableTotalColor)
+	t.CommitRow()
+	t.Fprintln(stdout)
+}
+
+// PrintPeriodTags pretty-prints all periods with their IDs and local-only
+// tags and notes, so the IDs can be used when tagging.
+func PrintPeriodTags(days []report.Day) {
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	t.WriteColoredRow(tableHeaderColor, "Date", "Time", "Type", "ID", "Tags", "Note / Comment")
+	for _, day := range days {
+		for _, p := range day.Periods {
+			t.WriteCell(locale.FormatDate(day.Date))
+			t.WriteCell(fmt.Sprintf("%s-%s", locale.FormatTime(p.Start), locale.FormatTime(p.End)))
+			t.WriteCell(string(p.Type))
+			t.WriteCell(p.ID.String())
+			tags := make([]string, len(p.Tags))
+			for i, tag := range p.Tags {
+				tags[i] = "#" + tag
+			}
+			t.WriteCellColor(strings.Join(tags, " "), tagColor)
+			if p.Note != "" {
+				t.WriteCell(p.Note)
+			} else {
+				t.WriteCellColor(p.Comment, queryNullColor)
+			}
+			t.CommitRow()
+		}
+	}
+	t.Fprintln(stdout)
+}
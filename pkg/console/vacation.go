@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"strconv"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+)
+
+// PrintVacationBalance pretty-prints the vacation days taken, planned, and
+// left in a year.
+func PrintVacationBalance(b report.VacationBalance) {
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+	if b.LeaveYearStart != "" {
+		writeStatsRow(&t, "Leave year", b.LeaveYearStart+" – "+b.LeaveYearEnd)
+	} else {
+		writeStatsRow(&t, "Year", strconv.Itoa(b.Year))
+	}
+	if b.EntitlementDays != b.YearlyEntitlementDays {
+		writeStatsRow(&t, "Entitlement", formatDays(b.EntitlementDays)+" (pro-rated from "+formatDays(b.YearlyEntitlementDays)+")")
+	} else {
+		writeStatsRow(&t, "Entitlement", formatDays(b.EntitlementDays))
+	}
+	if b.CarryoverDays > 0 {
+		writeStatsRow(&t, "Carried over", formatDays(b.CarryoverDays))
+		if b.CarryoverExpiredDays > 0 {
+			writeStatsRow(&t, "Expired", formatDays(b.CarryoverExpiredDays)+" (on "+b.CarryoverExpires+")")
+		} else if b.CarryoverExpiringDays > 0 {
+			writeStatsRow(&t, "Expiring", formatDays(b.CarryoverExpiringDays)+" (after "+b.CarryoverExpires+")")
+		}
+	}
+	writeStatsRow(&t, "Taken", formatDays(b.TakenDays))
+	writeStatsRow(&t, "Planned", formatDays(b.PlannedDays))
+	t.WriteCellColor("Remaining:", statsLabelColor)
+	if b.RemainingDays >= 0 {
+		t.WriteCellColor(formatDays(b.RemainingDays), balancePositiveColor)
+	} else {
+		t.WriteCellColor(formatDays(b.RemainingDays), balanceNegativeColor)
+	}
+	t.CommitRow()
+	t.Fprintln(stdout)
+}
+
+func formatDays(days float64) string {
+	s := strconv.FormatFloat(days, 'f', -1, 64)
+	if days == 1 {
+		return s + " day"
+	}
+	return s + " days"
+}
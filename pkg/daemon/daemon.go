@@ -0,0 +1,317 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package daemon contains the JSON-RPC API that the long-running daemon
+// exposes over a unix socket, so other invocations of the CLI can share
+// its logged in session and caches instead of logging in separately.
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/clock"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+)
+
+// serviceName is the name of the RPC service, used as prefix in the
+// JSON-RPC method names, e.g "Daemon.Status".
+const serviceName = "Daemon"
+
+// ErrNotRunning is returned when dialing a daemon that is not running.
+var ErrNotRunning = errors.New("daemon is not running")
+
+// DefaultSocketPath returns the default path of the daemon's unix socket,
+// which is inside $XDG_RUNTIME_DIR when set, and otherwise inside the
+// user's cache directory.
+func DefaultSocketPath() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "rootless-personio.sock"), nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rootless-personio", "daemon.sock"), nil
+}
+
+// Status is the daemon's current status.
+type Status struct {
+	BaseURL    string      `json:"baseUrl"`
+	EmployeeID int         `json:"employeeId"`
+	StartedAt  time.Time   `json:"startedAt"`
+	LastSync   *time.Time  `json:"lastSync,omitempty"`
+	Clock      clock.State `json:"clock"`
+	// MaintenanceUntil is set while requests are paused due to Personio's
+	// maintenance.
+	MaintenanceUntil *time.Time `json:"maintenanceUntil,omitempty"`
+	// NetworkDownSince is set while requests are paused due to Personio
+	// being unreachable, such as when offline or disconnected from a VPN.
+	NetworkDownSince *time.Time `json:"networkDownSince,omitempty"`
+	// RequestBudget is set when the daemon limits its requests per hour.
+	RequestBudget *RequestBudget `json:"requestBudget,omitempty"`
+	// Degraded are the capabilities that stopped working, such as after
+	// Personio changed one of its endpoints, while the rest keep working.
+	Degraded []personio.CapabilityStatus `json:"degraded,omitempty"`
+}
+
+// RequestBudget is the daemon's ceiling of requests per hour.
+type RequestBudget struct {
+	PerHour int `json:"perHour"`
+	// Available is how many requests can be sent right away.
+	Available int `json:"available"`
+}
+
+// SyncArgs is the date range to sync into the mirror.
+type SyncArgs struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// SyncReply is the result of a sync.
+type SyncReply struct {
+	Months int `json:"months"`
+}
+
+// ClockInArgs are the details of the period that is started.
+type ClockInArgs struct {
+	Comment   string          `json:"comment,omitempty"`
+	ProjectID *int            `json:"projectId,omitempty"`
+	Pomodoro  *clock.Pomodoro `json:"pomodoro,omitempty"`
+}
+
+// ProxyRequest is an HTTP request sent to Personio via the daemon's session.
+type ProxyRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"` // path and query, relative to the base URL
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// ProxyResponse is the HTTP response from Personio.
+type ProxyResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+// Backend implements the daemon's features, and is exposed over the
+// socket by [Serve].
+type Backend interface {
+	Status() (Status, error)
+	Sync(args SyncArgs) (SyncReply, error)
+	ClockIn(args ClockInArgs) (clock.State, error)
+	ClockOut() ([]personio.Period, error)
+	Proxy(req ProxyRequest) (ProxyResponse, error)
+}
+
+// service adapts the [Backend] to the method signatures required by
+// [net/rpc].
+type service struct {
+	backend Backend
+}
+
+// Empty is used for RPC methods without arguments.
+type Empty struct{}
+
+func (s *service) Status(_ Empty, reply *Status) (err error) {
+	*reply, err = s.backend.Status()
+	return err
+}
+
+func (s *service) Sync(args SyncArgs, reply *SyncReply) (err error) {
+	*reply, err = s.backend.Sync(args)
+	return err
+}
+
+func (s *service) ClockIn(args ClockInArgs, reply *clock.State) (err error) {
+	*reply, err = s.backend.ClockIn(args)
+	return err
+}
+
+func (s *service) ClockOut(_ Empty, reply *[]personio.Period) (err error) {
+	*reply, err = s.backend.ClockOut()
+	return err
+}
+
+func (s *service) Proxy(req ProxyRequest, reply *ProxyResponse) (err error) {
+	*reply, err = s.backend.Proxy(req)
+	return err
+}
+
+// Listen creates the unix socket, replacing any stale socket file left
+// behind by a daemon that did not shut down cleanly.
+//
+// Returns an error if another daemon is already listening on the socket.
+func Listen(socketPath string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another daemon is already listening on %s", socketPath)
+	}
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("remove stale socket: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0o700); err != nil {
+		return nil, fmt.Errorf("create socket directory: %w", err)
+	}
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	// Only the current user may use the daemon's session. Windows has no
+	// file modes, where the socket is instead protected by the ACLs of the
+	// user's profile directory that it's in.
+	if runtime.GOOS == "windows" {
+		return ln, nil
+	}
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("restrict socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// Serve accepts connections on the listener and serves the backend's
+// JSON-RPC API on them, until the listener is closed.
+func Serve(ln net.Listener, backend Backend) error {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(serviceName, &service{backend}); err != nil {
+		return err
+	}
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		log.Debug().Msg("Accepted daemon connection.")
+		go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// Client is a connection to a running daemon.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial connects to the daemon's socket.
+//
+// Returns [ErrNotRunning] if there is no daemon listening on the socket.
+func Dial(socketPath string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotRunning, err)
+	}
+	return &Client{rpc: jsonrpc.NewClient(conn)}, nil
+}
+
+// Close closes the connection to the daemon.
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// Status returns the daemon's current status.
+func (c *Client) Status() (Status, error) {
+	var reply Status
+	err := c.rpc.Call(serviceName+".Status", Empty{}, &reply)
+	return reply, err
+}
+
+// Sync triggers a sync of the date range into the mirror.
+func (c *Client) Sync(args SyncArgs) (SyncReply, error) {
+	var reply SyncReply
+	err := c.rpc.Call(serviceName+".Sync", args, &reply)
+	return reply, err
+}
+
+// ClockIn starts a new period on the punch clock.
+func (c *Client) ClockIn(args ClockInArgs) (clock.State, error) {
+	var reply clock.State
+	err := c.rpc.Call(serviceName+".ClockIn", args, &reply)
+	return reply, err
+}
+
+// ClockOut ends the current period on the punch clock and submits its
+// periods.
+func (c *Client) ClockOut() ([]personio.Period, error) {
+	var reply []personio.Period
+	err := c.rpc.Call(serviceName+".ClockOut", Empty{}, &reply)
+	return reply, err
+}
+
+// Transport returns an [http.RoundTripper] that sends all requests via the
+// daemon's logged in session. The base URL is stripped from the requests,
+// as the daemon uses its own base URL.
+func (c *Client) Transport(baseURL string) http.RoundTripper {
+	return &transport{client: c, baseURL: baseURL}
+}
+
+type transport struct {
+	client  *Client
+	baseURL string
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+	}
+	path, ok := strings.CutPrefix(req.URL.String(), t.baseURL)
+	if !ok {
+		return nil, fmt.Errorf("request URL %q is not on the base URL %q", req.URL, t.baseURL)
+	}
+	var reply ProxyResponse
+	if err := t.client.rpc.Call(serviceName+".Proxy", ProxyRequest{
+		Method: req.Method,
+		Path:   path,
+		Header: req.Header,
+		Body:   body,
+	}, &reply); err != nil {
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	return &http.Response{
+		Status:        reply.Status,
+		StatusCode:    reply.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        reply.Header,
+		Body:          io.NopCloser(bytes.NewReader(reply.Body)),
+		ContentLength: int64(len(reply.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/clock"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+type fakeBackend struct {
+	proxied ProxyRequest
+}
+
+func (b *fakeBackend) Status() (Status, error) {
+	return Status{EmployeeID: 123}, nil
+}
+
+func (b *fakeBackend) Sync(SyncArgs) (SyncReply, error) {
+	return SyncReply{}, errors.New("sync failed")
+}
+
+func (b *fakeBackend) ClockIn(ClockInArgs) (clock.State, error) {
+	return clock.State{}, nil
+}
+
+func (b *fakeBackend) ClockOut() ([]personio.Period, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) Proxy(req ProxyRequest) (ProxyResponse, error) {
+	b.proxied = req
+	return ProxyResponse{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"success":true}`),
+	}, nil
+}
+
+func TestServe(t *testing.T) {
+	// Unix socket paths have a short max length, so avoid the long t.TempDir
+	dir, err := os.MkdirTemp("", "personio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "d.sock")
+
+	if _, err := Dial(socketPath); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("want ErrNotRunning, got %v", err)
+	}
+
+	ln, err := Listen(socketPath)
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	defer ln.Close()
+	backend := &fakeBackend{}
+	go Serve(ln, backend)
+
+	if _, err := Listen(socketPath); err == nil {
+		t.Error("want error when listening on a socket that is in use")
+	}
+
+	client, err := Dial(socketPath)
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+	defer client.Close()
+
+	status, err := client.Status()
+	if err != nil {
+		t.Fatalf("status: %s", err)
+	}
+	if status.EmployeeID != 123 {
+		t.Errorf("want employee ID 123, got %d", status.EmployeeID)
+	}
+	if _, err := client.Sync(SyncArgs{}); err == nil || err.Error() != "sync failed" {
+		t.Errorf("want backend error, got %v", err)
+	}
+
+	httpClient := &http.Client{Transport: client.Transport("https://example.personio.de")}
+	resp, err := httpClient.Get("https://example.personio.de/api/v1/foo?bar=1")
+	if err != nil {
+		t.Fatalf("proxy: %s", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"success":true}` {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if backend.proxied.Path != "/api/v1/foo?bar=1" || backend.proxied.Method != http.MethodGet {
+		t.Errorf("unexpected proxied request: %+v", backend.proxied)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	dir, err := os.MkdirTemp("", "personio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := Notify("READY=1"); sent || err != nil {
+		t.Errorf("want no notification outside systemd, got sent=%t err=%v", sent, err)
+	}
+
+	t.Setenv("NOTIFY_SOCKET", socketPath)
+	if sent, err := Notify("READY=1"); !sent || err != nil {
+		t.Fatalf("want notification sent, got sent=%t err=%v", sent, err)
+	}
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "READY=1" {
+		t.Errorf("want %q, got %q", "READY=1", got)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_PID", "")
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	if got := WatchdogInterval(); got != 30*time.Second {
+		t.Errorf("want 30s, got %s", got)
+	}
+	t.Setenv("WATCHDOG_PID", "1")
+	if got := WatchdogInterval(); got != 0 {
+		t.Errorf("want 0 for another process, got %s", got)
+	}
+}
+
+func TestSystemdUnits(t *testing.T) {
+	files, err := SystemdUnits(UnitOptions{
+		Executable:   "/home/me/my bin/rootless-personio",
+		SyncInterval: 15 * time.Minute,
+		Watchdog:     time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("want 3 files, got %d", len(files))
+	}
+	service := files[0].Content
+	for _, want := range []string{
+		"Type=notify",
+		`ExecStart="/home/me/my bin/rootless-personio" daemon run`,
+		"WatchdogSec=60s",
+	} {
+		if !strings.Contains(service, want) {
+			t.Errorf("want service to contain %q, got:\n%s", want, service)
+		}
+	}
+	if timer := files[2].Content; !strings.Contains(timer, "OnUnitActiveSec=900s") {
+		t.Errorf("want timer to run every 900s, got:\n%s", timer)
+	}
+}
@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// UnitName is the name used for the generated service files.
+const UnitName = "rootless-personio"
+
+// LaunchdLabel is the label of the generated launchd agent on macOS.
+const LaunchdLabel = "com.github.applejag.rootless-personio"
+
+// UnitFile is a generated service file, with a path relative to the
+// service manager's user directory.
+type UnitFile struct {
+	Name    string
+	Content string
+}
+
+// UnitOptions are the settings of the generated service files.
+type UnitOptions struct {
+	// Executable is the absolute path of the rootless-personio binary.
+	Executable string
+	// SyncInterval is how often the timer syncs the mirror.
+	SyncInterval time.Duration
+	// Watchdog is how long systemd waits for a watchdog notification
+	// before restarting the daemon. Zero disables the watchdog.
+	Watchdog time.Duration
+}
+
+var systemdServiceTemplate = template.Must(template.New("service").Parse(`[Unit]
+Description=Rootless Personio daemon
+Documentation=https://github.com/applejag/rootless-personio
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=notify
+ExecStart={{ .Executable }} daemon run
+Restart=on-failure
+RestartSec=10s
+{{- if .Watchdog }}
+WatchdogSec={{ .Watchdog }}
+{{- end }}
+
+[Install]
+WantedBy=default.target
+`))
+
+var systemdSyncServiceTemplate = template.Must(template.New("sync-service").Parse(`[Unit]
+Description=Sync Rootless Personio mirror
+Documentation=https://github.com/applejag/rootless-personio
+
+[Service]
+Type=oneshot
+ExecStart={{ .Executable }} mirror sync
+`))
+
+var systemdSyncTimerTemplate = template.Must(template.New("sync-timer").Parse(`[Unit]
+Description=Periodically sync Rootless Personio mirror
+Documentation=https://github.com/applejag/rootless-personio
+
+[Timer]
+OnStartupSec=1min
+OnUnitActiveSec={{ .SyncInterval }}
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`))
+
+var launchdPlistTemplate = template.Must(template.New("plist").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{ .Label }}</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>{{ .Executable }}</string>
+		<string>daemon</string>
+		<string>run</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>StandardErrorPath</key>
+	<string>/tmp/{{ .Label }}.log</string>
+</dict>
+</plist>
+`))
+
+// SystemdUnits returns the systemd user units for the daemon, and for a
+// timer that periodically syncs the mirror for those who don't want the
+// daemon always running.
+func SystemdUnits(opts UnitOptions) ([]UnitFile, error) {
+	data := struct {
+		Executable   string
+		SyncInterval string
+		Watchdog     string
+	}{
+		Executable:   systemdEscape(opts.Executable),
+		SyncInterval: systemdDuration(opts.SyncInterval),
+	}
+	if opts.Watchdog > 0 {
+		data.Watchdog = systemdDuration(opts.Watchdog)
+	}
+	return renderUnits(data, []UnitFile{
+		{Name: UnitName + ".service"},
+		{Name: UnitName + "-sync.service"},
+		{Name: UnitName + "-sync.timer"},
+	}, systemdServiceTemplate, systemdSyncServiceTemplate, systemdSyncTimerTemplate)
+}
+
+// LaunchdPlist returns the launchd user agent for the daemon on macOS.
+func LaunchdPlist(opts UnitOptions) (UnitFile, error) {
+	data := struct {
+		Label      string
+		Executable string
+	}{
+		Label:      LaunchdLabel,
+		Executable: xmlEscape(opts.Executable),
+	}
+	files, err := renderUnits(data, []UnitFile{{Name: LaunchdLabel + ".plist"}}, launchdPlistTemplate)
+	if err != nil {
+		return UnitFile{}, err
+	}
+	return files[0], nil
+}
+
+func renderUnits(data any, files []UnitFile, templates ...*template.Template) ([]UnitFile, error) {
+	for i, tmpl := range templates {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return nil, fmt.Errorf("render %s: %w", files[i].Name, err)
+		}
+		files[i].Content = sb.String()
+	}
+	return files, nil
+}
+
+// systemdDuration formats the duration in whole seconds, which systemd
+// accepts in all of its time settings.
+func systemdDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// systemdEscape quotes the path if needed, so it can be used as the
+// executable in ExecStart=.
+func systemdEscape(path string) string {
+	// Percent signs are used for specifiers, such as %h for home directory
+	path = strings.ReplaceAll(path, "%", "%%")
+	if !strings.ContainsAny(path, " \t\"'\\") {
+		return path
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(path) + `"`
+}
+
+func xmlEscape(s string) string {
+	return strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+	).Replace(s)
+}
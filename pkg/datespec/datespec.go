@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package datespec contains helpers for resolving the date ranges that
+// commands operate on, such as when the user does not specify a range.
+package datespec
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/util"
+)
+
+// Range is an inclusive range of dates, where both dates are at midnight UTC.
+type Range struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ThisMonth returns the range of the full month of the given time.
+func ThisMonth(now time.Time) Range {
+	start, end := util.TimeFullMonth(now)
+	return Range{Start: start, End: end}
+}
+
+// ThisWeek returns the range of the full week of the given time, where
+// weeks start on the given weekday.
+func ThisWeek(now time.Time, weekStart time.Weekday) Range {
+	today := Date(now)
+	offset := (int(today.Weekday()) - int(weekStart) + 7) % 7
+	start := today.AddDate(0, 0, -offset)
+	return Range{Start: start, End: start.AddDate(0, 0, 6)}
+}
+
+// LastDays returns the range of the given number of days, ending today.
+func LastDays(now time.Time, days int) Range {
+	today := Date(now)
+	return Range{Start: today.AddDate(0, 0, 1-days), End: today}
+}
+
+// Preset is a named range that is relative to the current time, used by
+// commands when no range is given.
+type Preset string
+
+// Available [Preset] values.
+const (
+	PresetThisWeek   Preset = "thisWeek"
+	PresetThisMonth  Preset = "thisMonth"
+	PresetLast30Days Preset = "last30Days"
+)
+
+// Range returns the range of the preset relative to now, where weeks start
+// on the given weekday.
+func (p Preset) Range(now time.Time, weekStart time.Weekday) (Range, error) {
+	switch p {
+	case PresetThisWeek:
+		return ThisWeek(now, weekStart), nil
+	case PresetThisMonth:
+		return ThisMonth(now), nil
+	case PresetLast30Days:
+		return LastDays(now, 30), nil
+	default:
+		return Range{}, fmt.Errorf("unknown date range %q, must be one of: %s, %s, %s",
+			string(p), PresetThisWeek, PresetThisMonth, PresetLast30Days)
+	}
+}
+
+// ParseWeekStart parses the first day of the week, which is either
+// "monday" or "sunday". An empty string means Monday.
+func ParseWeekStart(s string) (time.Weekday, error) {
+	switch strings.ToLower(s) {
+	case "", "monday":
+		return time.Monday, nil
+	case "sunday":
+		return time.Sunday, nil
+	default:
+		return time.Monday, fmt.Errorf("unknown week start %q, must be monday or sunday", s)
+	}
+}
+
+// ParseWeekday parses the English name of a weekday, e.g "friday".
+func ParseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("unknown weekday %q", s)
+}
+
+// Resolve returns a range where the start and end dates fall back to the
+// default range when they are zero.
+func Resolve(start, end time.Time, def Range) Range {
+	r := def
+	if !start.IsZero() {
+		r.Start = start
+	}
+	if !end.IsZero() {
+		r.End = end
+	}
+	return r
+}
+
+// Days returns the number of days in the range, counting both the start
+// and end dates.
+func (r Range) Days() int {
+	if r.End.Before(r.Start) {
+		return 0
+	}
+	return int(r.End.Sub(r.Start).Hours()/24) + 1
+}
+
+// Each calls the function once for every date in the range, in order.
+func (r Range) Each(f func(date time.Time)) {
+	for date := r.Start; !date.After(r.End); date = date.AddDate(0, 0, 1) {
+		f(date)
+	}
+}
+
+// Date truncates the time to midnight UTC, while keeping the date as seen
+// in the time's own location.
+func Date(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package datespec
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPresetRange(t *testing.T) {
+	// Wednesday
+	now := time.Date(2023, 1, 18, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		preset    Preset
+		weekStart time.Weekday
+		want      string
+	}{
+		{PresetThisWeek, time.Monday, "2023-01-16..2023-01-22"},
+		{PresetThisWeek, time.Sunday, "2023-01-15..2023-01-21"},
+		{PresetThisMonth, time.Monday, "2023-01-01..2023-01-31"},
+		{PresetLast30Days, time.Monday, "2022-12-20..2023-01-18"},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.preset)+"/"+tc.weekStart.String(), func(t *testing.T) {
+			r, err := tc.preset.Range(now, tc.weekStart)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := r.Start.Format(time.DateOnly) + ".." + r.End.Format(time.DateOnly)
+			if got != tc.want {
+				t.Errorf("want %s, got %s", tc.want, got)
+			}
+		})
+	}
+	if _, err := Preset("nextYear").Range(now, time.Monday); err == nil {
+		t.Error("want error for unknown preset")
+	}
+}
@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package e2e is a smoke test of the client against a live Personio
+// tenant, which reports which capabilities still work, such as after
+// Personio changed something on their side.
+//
+// The suite only writes to a single scratch day, which must be empty, and
+// removes what it wrote afterwards. Run it via "make test-e2e", see the
+// README for the env vars it needs.
+package e2e
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// ErrScratchDayNotEmpty is returned when the scratch day already has
+// attendance, which the suite refuses to overwrite.
+var ErrScratchDayNotEmpty = errors.New("scratch day already has attendance")
+
+// DefaultScratchDay returns the day used for writes unless set, which is
+// a year from now, far enough to not collide with real attendance.
+func DefaultScratchDay(now time.Time) time.Time {
+	day := now.AddDate(1, 0, 0)
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// Suite is the steps to run against a live tenant.
+type Suite struct {
+	Client *personio.Client
+	// Login logs in the client.
+	Login func(client *personio.Client) error
+	// ScratchDay is the empty day that attendance is written to and then
+	// removed from.
+	ScratchDay time.Time
+}
+
+// Step is the result of a step of the suite.
+type Step struct {
+	Name       string              `json:"name"`
+	Capability personio.Capability `json:"capability"`
+	Skipped    bool                `json:"skipped,omitempty"`
+	Duration   time.Duration       `json:"duration"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// OK returns true if the step ran and succeeded.
+func (s Step) OK() bool {
+	return !s.Skipped && s.Error == ""
+}
+
+// Report is the result of all steps, meant to be attached to issues and
+// pull requests about compatibility with Personio.
+type Report struct {
+	BaseURL    string    `json:"baseUrl"`
+	ScratchDay string    `json:"scratchDay"`
+	StartedAt  time.Time `json:"startedAt"`
+	Steps      []Step    `json:"steps"`
+}
+
+// OK returns true if all steps succeeded.
+func (r Report) OK() bool {
+	for _, s := range r.Steps {
+		if !s.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// Write writes the report as a Markdown table.
+func (r Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintf(tw, "Compatibility report of %s, started %s, scratch day %s\n\n",
+		r.BaseURL, r.StartedAt.UTC().Format(time.RFC3339), r.ScratchDay)
+	fmt.Fprintln(tw, "| Step\t Capability\t Result\t Duration\t Error\t")
+	fmt.Fprintln(tw, "| ---\t ---\t ---\t ---\t ---\t")
+	for _, s := range r.Steps {
+		result := "ok"
+		switch {
+		case s.Skipped:
+			result = "skipped"
+		case s.Error != "":
+			result = "FAILED"
+		}
+		fmt.Fprintf(tw, "| %s\t %s\t %s\t %.2fs\t %s\t\n", s.Name, s.Capability, result, s.Duration.Seconds(), s.Error)
+	}
+	return tw.Flush()
+}
+
+// Run runs the steps in order. Steps that depend on a failed step are
+// skipped, while the cleanup always runs after writing.
+func (s Suite) Run() Report {
+	day := s.ScratchDay
+	r := Report{
+		BaseURL:    s.Client.BaseURL,
+		ScratchDay: day.Format(time.DateOnly),
+		StartedAt:  time.Now(),
+	}
+	failed := false
+	run := func(name string, capability personio.Capability, f func() error) bool {
+		step := Step{Name: name, Capability: capability}
+		if failed {
+			step.Skipped = true
+			r.Steps = append(r.Steps, step)
+			return false
+		}
+		start := time.Now()
+		err := f()
+		step.Duration = time.Since(start)
+		if err != nil {
+			step.Error = err.Error()
+			failed = true
+		}
+		r.Steps = append(r.Steps, step)
+		return err == nil
+	}
+
+	run("login", personio.CapabilityLogin, func() error {
+		return s.Login(s.Client)
+	})
+	run("read employee", personio.CapabilityEmployee, func() error {
+		_, err := s.Client.GetEmployeeData(s.Client.EmployeeID)
+		return err
+	})
+	run("read calendar", personio.CapabilityAttendanceRead, func() error {
+		periods, err := s.Client.GetAttendancePeriods(day)
+		if err != nil {
+			return err
+		}
+		if len(periods) > 0 {
+			return fmt.Errorf("%w: %d periods on %s", ErrScratchDayNotEmpty, len(periods), day.Format(time.DateOnly))
+		}
+		return nil
+	})
+
+	comment := "rootless-personio e2e test, safe to remove"
+	periods := []personio.Period{
+		{
+			PeriodType: personio.PeriodTypeWork,
+			Comment:    &comment,
+			Start:      day.Add(9 * time.Hour),
+			End:        day.Add(12 * time.Hour),
+		},
+		{
+			PeriodType: personio.PeriodTypeBreak,
+			Start:      day.Add(12 * time.Hour),
+			End:        day.Add(12*time.Hour + 30*time.Minute),
+		},
+	}
+	wrote := run("write scratch day", personio.CapabilityAttendanceWrite, func() error {
+		return s.Client.SetAttendance(day, periods)
+	})
+	run("verify scratch day", personio.CapabilityAttendanceRead, func() error {
+		_, err := s.Client.VerifyAttendance(day, periods)
+		return err
+	})
+
+	if wrote {
+		// Always clean up after writing, even if the verification failed
+		failed = false
+	}
+	run("clean up scratch day", personio.CapabilityAttendanceWrite, func() error {
+		if err := s.Client.DeleteAttendance(day); err != nil {
+			return err
+		}
+		remaining, err := s.Client.GetAttendancePeriods(day)
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 0 {
+			return fmt.Errorf("%d periods remain on %s", len(remaining), day.Format(time.DateOnly))
+		}
+		return nil
+	})
+	return r
+}
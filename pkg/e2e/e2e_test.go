@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// TestLiveTenant runs the suite against the tenant from the env vars:
+//
+//	PERSONIO_E2E_BASEURL      e.g https://mycompany.personio.de
+//	PERSONIO_E2E_EMAIL        email of a test account
+//	PERSONIO_E2E_PASSWORD     password of the test account
+//	PERSONIO_E2E_SCRATCH_DAY  empty day to write to, default a year from now
+//	PERSONIO_E2E_REPORT       file to write the report to, if set
+func TestLiveTenant(t *testing.T) {
+	baseURL := os.Getenv("PERSONIO_E2E_BASEURL")
+	email := os.Getenv("PERSONIO_E2E_EMAIL")
+	password := os.Getenv("PERSONIO_E2E_PASSWORD")
+	if baseURL == "" || email == "" || password == "" {
+		t.Skip("set PERSONIO_E2E_BASEURL, PERSONIO_E2E_EMAIL, and PERSONIO_E2E_PASSWORD to run against a live tenant")
+	}
+	scratchDay := DefaultScratchDay(time.Now())
+	if s := os.Getenv("PERSONIO_E2E_SCRATCH_DAY"); s != "" {
+		var err error
+		scratchDay, err = time.ParseInLocation(time.DateOnly, s, time.Local)
+		if err != nil {
+			t.Fatalf("parse PERSONIO_E2E_SCRATCH_DAY: %s", err)
+		}
+	}
+
+	client, err := personio.New(baseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite := Suite{
+		Client: client,
+		Login: func(client *personio.Client) error {
+			return client.Login(email, password)
+		},
+		ScratchDay: scratchDay,
+	}
+	report := suite.Run()
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + buf.String())
+	if path := os.Getenv("PERSONIO_E2E_REPORT"); path != "" {
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Errorf("write report: %s", err)
+		}
+	}
+	for _, step := range report.Steps {
+		switch {
+		case step.Skipped:
+			t.Errorf("%s: skipped after an earlier failure", step.Name)
+		case step.Error != "":
+			t.Errorf("%s: %s", step.Name, step.Error)
+		}
+	}
+}
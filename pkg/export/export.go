@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package export contains the records written by the "export" command,
+// and the state used to only export records that changed since the
+// previous export.
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/filelock"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/timesheet"
+	"github.com/google/uuid"
+)
+
+// Action is what happened to a record since the previous export.
+type Action string
+
+const (
+	ActionUpsert Action = "upsert"
+	ActionDelete Action = "delete"
+)
+
+// Record is a single exported attendance period. The fields are compatible
+// with the input of the "attendance set" command.
+type Record struct {
+	Action     Action              `json:"action"`
+	ID         uuid.UUID           `json:"id"`
+	Date       string              `json:"date"` // ex: "2023-01-18"
+	PeriodType personio.PeriodType `json:"period_type,omitempty"`
+	Start      *time.Time          `json:"start,omitempty"`
+	End        *time.Time          `json:"end,omitempty"`
+	Comment    *string             `json:"comment,omitempty"`
+	ProjectID  *int                `json:"project_id,omitempty"`
+}
+
+// Records converts all attendance periods in the calendar to records,
+// sorted by their start time.
+func Records(cal *personio.AttendanceCalendar) ([]Record, error) {
+	entries, err := timesheet.FromCalendar(cal)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0, len(entries))
+	for _, e := range entries {
+		id, err := uuid.Parse(e.SourceID)
+		if err != nil {
+			return nil, fmt.Errorf("parse period ID %q: %w", e.SourceID, err)
+		}
+		start, end := e.Start, e.End
+		records = append(records, Record{
+			Action:     ActionUpsert,
+			ID:         id,
+			Date:       e.Date,
+			PeriodType: e.Type,
+			Start:      &start,
+			End:        &end,
+			Comment:    e.Comment,
+			ProjectID:  e.ProjectID,
+		})
+	}
+	return records, nil
+}
+
+// State is what was exported in the previous export, used to only export
+// the records that changed since then.
+//
+// Personio doesn't tell when a period was last updated, so changes are
+// instead detected by comparing hashes of the records.
+type State struct {
+	// Watermark is when the previous export was made.
+	Watermark time.Time                 `json:"watermark"`
+	Periods   map[uuid.UUID]StatePeriod `json:"periods"`
+}
+
+// StatePeriod is the previously exported version of a period.
+type StatePeriod struct {
+	Date string `json:"date"`
+	Hash string `json:"hash"`
+}
+
+// LoadState reads the state file. A missing file results in an empty state,
+// meaning that all records will be exported.
+func LoadState(path string) (*State, error) {
+	state := &State{Periods: make(map[uuid.UUID]StatePeriod)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read export state: %w", err)
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("parse export state: %w", err)
+	}
+	if state.Periods == nil {
+		state.Periods = make(map[uuid.UUID]StatePeriod)
+	}
+	return state, nil
+}
+
+// Save writes the state file, replacing it atomically.
+func (s *State) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := filelock.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("write export state: %w", err)
+	}
+	return nil
+}
+
+// Delta returns the records that were added or changed since the previous
+// export, followed by deletions of periods inside the date range that no
+// longer exist, and updates the state accordingly.
+func (s *State) Delta(records []Record, r datespec.Range, now time.Time) ([]Record, error) {
+	var delta []Record
+	seen := make(map[uuid.UUID]bool, len(records))
+	for _, rec := range records {
+		seen[rec.ID] = true
+		hash, err := rec.hash()
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := s.Periods[rec.ID]; ok && prev.Hash == hash {
+			continue
+		}
+		s.Periods[rec.ID] = StatePeriod{Date: rec.Date, Hash: hash}
+		delta = append(delta, rec)
+	}
+
+	var deleted []Record
+	start := r.Start.Format(time.DateOnly)
+	end := r.End.Format(time.DateOnly)
+	for id, prev := range s.Periods {
+		if seen[id] || prev.Date < start || prev.Date > end {
+			continue
+		}
+		delete(s.Periods, id)
+		deleted = append(deleted, Record{Action: ActionDelete, ID: id, Date: prev.Date})
+	}
+	sort.Slice(deleted, func(i, j int) bool {
+		if deleted[i].Date != deleted[j].Date {
+			return deleted[i].Date < deleted[j].Date
+		}
+		return deleted[i].ID.String() < deleted[j].ID.String()
+	})
+
+	s.Watermark = now
+	return append(delta, deleted...), nil
+}
+
+func (rec Record) hash() (string, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package export
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/google/uuid"
+)
+
+func TestStateDelta(t *testing.T) {
+	r := datespec.Range{
+		Start: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC),
+	}
+	a := newRecord("2023-01-02", "morning")
+	b := newRecord("2023-01-03", "afternoon")
+	outside := newRecord("2023-02-01", "next month")
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("load empty state: %s", err)
+	}
+	delta, err := state.Delta([]Record{a, b}, r, time.Now())
+	if err != nil {
+		t.Fatalf("first delta: %s", err)
+	}
+	if len(delta) != 2 {
+		t.Fatalf("first delta: want 2 records, got %d", len(delta))
+	}
+	state.Periods[outside.ID] = StatePeriod{Date: outside.Date, Hash: "x"}
+	if err := state.Save(path); err != nil {
+		t.Fatalf("save: %s", err)
+	}
+
+	state, err = LoadState(path)
+	if err != nil {
+		t.Fatalf("load state: %s", err)
+	}
+	changedComment := "changed"
+	a.Comment = &changedComment
+	delta, err = state.Delta([]Record{a}, r, time.Now())
+	if err != nil {
+		t.Fatalf("second delta: %s", err)
+	}
+	if len(delta) != 2 {
+		t.Fatalf("second delta: want 2 records, got %d: %+v", len(delta), delta)
+	}
+	if delta[0].ID != a.ID || delta[0].Action != ActionUpsert {
+		t.Errorf("second delta: want upsert of changed record, got %+v", delta[0])
+	}
+	if delta[1].ID != b.ID || delta[1].Action != ActionDelete {
+		t.Errorf("second delta: want delete of removed record, got %+v", delta[1])
+	}
+	if _, ok := state.Periods[outside.ID]; !ok {
+		t.Error("second delta: want record outside of range to be kept in state")
+	}
+}
+
+func newRecord(date, comment string) Record {
+	start, _ := time.Parse(time.DateOnly, date)
+	end := start.Add(time.Hour)
+	return Record{
+		Action:  ActionUpsert,
+		ID:      uuid.New(),
+		Date:    date,
+		Start:   &start,
+		End:     &end,
+		Comment: &comment,
+	}
+}
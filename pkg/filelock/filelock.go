@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package filelock protects state files that are shared between concurrent
+// runs, such as a cron job racing a manual command, using advisory locks
+// and atomic writes.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultTimeout is how long [Acquire] waits for another run to release
+// its lock by default.
+const DefaultTimeout = 30 * time.Second
+
+// ErrTimeout is returned when the lock is still held by another run after
+// the timeout.
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+// pollInterval is how often a held lock is retried.
+const pollInterval = 25 * time.Millisecond
+
+// Lock is an exclusive lock on a file, held until released.
+type Lock struct {
+	f *os.File
+}
+
+// Acquire takes an exclusive lock on the file, waiting up to the timeout
+// for other processes to release it. The lock is taken on a separate
+// "<path>.lock" file, so the file itself can be replaced atomically while
+// locked. Zero timeout uses [DefaultTimeout].
+//
+// The lock is only advisory, and is only respected by other runs that also
+// use this package.
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create lock directory: %w", err)
+	}
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open lock: %w", err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("lock %s: %w", path, err)
+		}
+		if locked {
+			return &Lock{f: f}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("lock %s: %w", path, ErrTimeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Release unlocks the file. The lock file is kept, as removing it would
+// race with other runs that have it open.
+func (l *Lock) Release() error {
+	if err := unlock(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// WithLock calls f while holding the lock on the file.
+func WithLock(path string, f func() error) error {
+	lock, err := Acquire(path, 0)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	return f()
+}
+
+// WriteFile replaces the file atomically, by writing to a temporary file
+// in the same directory and then renaming it, so concurrent readers see
+// either the old or the new content but never a partial write, even if
+// the process is killed halfway through.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package flagtype
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// Month is a year and month, stored as the first day of the month
+// at midnight UTC.
+type Month time.Time
+
+// ensure it implements the interface
+var _ pflag.Value = &Month{}
+
+// Time is a helper function to return the [time.Time] representation.
+func (m Month) Time() time.Time {
+	return time.Time(m)
+}
+
+// IsZero returns true when this month is set to it's zero value: 0001-01
+func (m Month) IsZero() bool {
+	return time.Time(m) == time.Time{}
+}
+
+// String implements [fmt.Stringer] and [pflag.Value].
+//
+// Used by cobra when showing the default value of a flag.
+func (m Month) String() string {
+	if m.IsZero() {
+		return ""
+	}
+	return m.Time().Format("2006-01")
+}
+
+// Set implements [pflag.Value].
+//
+// Used by cobra when setting the new value for a flag.
+func (m *Month) Set(value string) error {
+	t, err := time.Parse("2006-01", value)
+	if err != nil {
+		return err
+	}
+	*m = Month(t.UTC())
+	return nil
+}
+
+// Type implements [pflag.Value].
+//
+// Used by cobra when rendering the list of flags and their types.
+func (m Month) Type() string {
+	return "month"
+}
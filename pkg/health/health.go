@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package health serves the "/healthz" and "/readyz" probes used by
+// service managers such as Kubernetes, to tell if the process is alive and
+// if it's ready to serve.
+package health
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrShuttingDown is reported by "/readyz" once shutdown has started.
+var ErrShuttingDown = errors.New("shutting down")
+
+// Handler serves "/healthz", which always succeeds while the process
+// responds, and "/readyz", which fails while not ready.
+type Handler struct {
+	// Ready returns an error while not ready to serve, such as while the
+	// upstream is unreachable. Always ready when nil.
+	Ready func() error
+
+	shuttingDown atomic.Bool
+}
+
+// Register adds the probes to the mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.Handle("/healthz", h)
+	mux.Handle("/readyz", h)
+}
+
+// ShuttingDown makes "/readyz" fail from now on, so no new traffic is
+// routed here while shutting down.
+func (h *Handler) ShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var err error
+	if r.URL.Path == "/readyz" {
+		err = h.ready()
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+func (h *Handler) ready() error {
+	if h.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+	if h.Ready == nil {
+		return nil
+	}
+	return h.Ready()
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var readyErr error
+	h := &Handler{Ready: func() error { return readyErr }}
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	check := func(step string, wantHealthz, wantReadyz int) {
+		t.Helper()
+		if got := get("/healthz"); got != wantHealthz {
+			t.Errorf("%s: want /healthz status %d, got %d", step, wantHealthz, got)
+		}
+		if got := get("/readyz"); got != wantReadyz {
+			t.Errorf("%s: want /readyz status %d, got %d", step, wantReadyz, got)
+		}
+	}
+
+	check("ready", http.StatusOK, http.StatusOK)
+	readyErr = errors.New("upstream unreachable")
+	check("not ready", http.StatusOK, http.StatusServiceUnavailable)
+	readyErr = nil
+	h.ShuttingDown()
+	check("shutting down", http.StatusOK, http.StatusServiceUnavailable)
+}
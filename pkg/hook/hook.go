@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package hook runs user-defined external commands around certain events,
+// such as before and after submitting attendance.
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Name is the name of a hook, and is passed to the hook command
+// as the PERSONIO_HOOK environment variable.
+type Name string
+
+// Available hook names.
+const (
+	PreSubmit      Name = "preSubmit"
+	PostSubmit     Name = "postSubmit"
+	OnLoginFailure Name = "onLoginFailure"
+)
+
+// ErrHookFailed is returned when the hook command exits with a non-zero
+// exit code.
+var ErrHookFailed = errors.New("hook failed")
+
+// Run executes the hook command, if any, with the JSON-encoded payload
+// written to its STDIN. The hook's STDOUT and STDERR are both forwarded to
+// this program's STDERR, as STDOUT is reserved for the command results.
+//
+// Does nothing if the command is empty.
+func Run(name Name, command []string, payload any) error {
+	if len(command) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s hook payload: %w", name, err)
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "PERSONIO_HOOK="+string(name))
+
+	log.Debug().
+		Str("hook", string(name)).
+		Str("command", strings.Join(command, " ")).
+		Msg("Running hook.")
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("%w: %s: exit code %d", ErrHookFailed, name, exitErr.ExitCode())
+		}
+		return fmt.Errorf("run %s hook: %w", name, err)
+	}
+	return nil
+}
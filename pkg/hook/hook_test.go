@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package hook
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		wantOut  string
+		wantErr  error
+		wantText string
+	}{
+		{
+			name:    "payload on stdin",
+			command: `cat > "$HOOK_OUT"`,
+			wantOut: `{"day":"2023-01-18"}`,
+		},
+		{
+			name:    "hook name in env",
+			command: `printf %s "$PERSONIO_HOOK" > "$HOOK_OUT"`,
+			wantOut: string(PreSubmit),
+		},
+		{
+			name:     "non-zero exit",
+			command:  `exit 3`,
+			wantErr:  ErrHookFailed,
+			wantText: "exit code 3",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			t.Setenv("HOOK_OUT", out)
+			err := Run(PreSubmit, []string{"sh", "-c", tc.command}, map[string]string{"day": "2023-01-18"})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) || !strings.Contains(err.Error(), tc.wantText) {
+					t.Fatalf("want %v with %q, got %v", tc.wantErr, tc.wantText, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.wantOut {
+				t.Errorf("want %q, got %q", tc.wantOut, got)
+			}
+		})
+	}
+}
+
+func TestRunMissingBinary(t *testing.T) {
+	err := Run(PostSubmit, []string{"rootless-personio-no-such-hook"}, nil)
+	if err == nil || errors.Is(err, ErrHookFailed) {
+		t.Fatalf("want error other than %v, got %v", ErrHookFailed, err)
+	}
+}
+
+func TestRunEmptyCommand(t *testing.T) {
+	if err := Run(PostSubmit, nil, make(chan int)); err != nil {
+		t.Fatalf("want no-op, got %v", err)
+	}
+}
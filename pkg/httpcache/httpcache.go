@@ -0,0 +1,276 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package httpcache contains an [http.RoundTripper] that caches GET
+// responses on disk, and revalidates them using the ETag and Last-Modified
+// headers, so unchanged responses do not need to be downloaded again.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/filelock"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultMaxSize is the default total size in bytes of all cached responses.
+const DefaultMaxSize = 50 << 20 // 50 MiB
+
+// Transport is an [http.RoundTripper] that caches GET responses on disk.
+//
+// Cached responses are never used without first revalidating them with the
+// server, by sending the If-None-Match and If-Modified-Since headers. When
+// the server responds with 304 Not Modified, then the cached response body
+// is used instead.
+type Transport struct {
+	// Base is the underlying transport. Defaults to [http.DefaultTransport].
+	Base http.RoundTripper
+	// Dir is the directory where cached responses are stored.
+	Dir string
+	// MaxSize is the total size in bytes of all cached responses, before
+	// the least recently used responses are removed. Defaults to
+	// [DefaultMaxSize].
+	MaxSize int64
+}
+
+// ensure it implements the interface
+var _ http.RoundTripper = &Transport{}
+
+// DefaultDir returns the default cache directory, which is inside the
+// user's cache directory, e.g ~/.cache/rootless-personio/http.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rootless-personio", "http"), nil
+}
+
+type entry struct {
+	URL          string      `json:"url"`
+	StatusCode   int         `json:"statusCode"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base().RoundTrip(req)
+	}
+
+	path := t.entryPath(req)
+	cached, err := t.load(path)
+	if err != nil {
+		log.Debug().Err(err).Str("url", req.URL.String()).Msg("Ignoring unreadable cache entry.")
+	}
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" && req.Header.Get("If-None-Match") == "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" && req.Header.Get("If-Modified-Since") == "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		log.Debug().Str("url", req.URL.String()).Msg("Using cached response.")
+		resp.Body.Close()
+		now := time.Now()
+		// Bump the modification time, used when pruning the cache
+		if err := os.Chtimes(path, now, now); err != nil {
+			log.Debug().Err(err).Msg("Failed touching cache entry.")
+		}
+		return cached.response(req, resp.Header), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	// Never replay cookies, as that would overwrite newer session cookies
+	header.Del("Set-Cookie")
+	newEntry := entry{
+		URL:          req.URL.String(),
+		StatusCode:   resp.StatusCode,
+		Header:       header,
+		Body:         body,
+		ETag:         etag,
+		LastModified: lastModified,
+	}
+	if err := t.store(path, newEntry); err != nil {
+		log.Debug().Err(err).Str("url", req.URL.String()).Msg("Failed storing response in cache.")
+	}
+	return resp, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) maxSize() int64 {
+	if t.MaxSize > 0 {
+		return t.MaxSize
+	}
+	return DefaultMaxSize
+}
+
+func (t *Transport) entryPath(req *http.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s", req.Method, req.URL.String(), req.Header.Get("Accept"))
+	return filepath.Join(t.Dir, hex.EncodeToString(h.Sum(nil))+".json")
+}
+
+func (t *Transport) load(path string) (*entry, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (t *Transport) store(path string, e entry) error {
+	if int64(len(e.Body)) > t.maxSize() {
+		return nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// Written atomically, so concurrent readers never see a partially
+	// written entry.
+	if err := filelock.WriteFile(path, b, 0o600); err != nil {
+		return err
+	}
+	return t.prune()
+}
+
+// prune removes the least recently used entries until the cache fits
+// within its max size.
+func (t *Transport) prune() error {
+	dirEntries, err := os.ReadDir(t.Dir)
+	if err != nil {
+		return err
+	}
+	var infos []fs.FileInfo
+	var total int64
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || filepath.Ext(dirEntry.Name()) != ".json" {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+		total += info.Size()
+	}
+	if total <= t.maxSize() {
+		return nil
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		if total <= t.maxSize() {
+			break
+		}
+		if err := os.Remove(filepath.Join(t.Dir, info.Name())); err != nil {
+			return err
+		}
+		total -= info.Size()
+	}
+	return nil
+}
+
+// Clear removes all cached responses.
+func (t *Transport) Clear() error {
+	err := os.RemoveAll(t.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (e *entry) response(req *http.Request, header http.Header) *http.Response {
+	merged := e.Header.Clone()
+	if merged == nil {
+		merged = http.Header{}
+	}
+	// The 304 response has the up-to-date headers, such as new cookies,
+	// except for the body related headers.
+	for key, values := range header {
+		if key == "Content-Length" {
+			continue
+		}
+		merged[key] = values
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        merged,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
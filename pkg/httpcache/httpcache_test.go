@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Set-Cookie", "session=abc")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"hello":"world"}`)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{Dir: t.TempDir()}}
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request #%d: %s", i, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("request #%d: read body: %s", i, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("request #%d: want status 200, got %d", i, resp.StatusCode)
+		}
+		if string(body) != `{"hello":"world"}` {
+			t.Errorf("request #%d: unexpected body: %q", i, body)
+		}
+		if got := resp.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("request #%d: want cached Content-Type, got %q", i, got)
+		}
+	}
+	if requests != 3 {
+		t.Errorf("want 3 requests, got %d", requests)
+	}
+	if notModified != 2 {
+		t.Errorf("want 2 revalidated requests, got %d", notModified)
+	}
+}
+
+func TestTransportSkipsNonGET(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("unexpected If-None-Match on %s", r.Method)
+		}
+		w.Header().Set("ETag", `"v1"`)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{Dir: t.TempDir()}}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(srv.URL, "text/plain", nil)
+		if err != nil {
+			t.Fatalf("request #%d: %s", i, err)
+		}
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package ical writes iCalendar (RFC 5545) files, used to export absences
+// and holidays to external calendars.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// ProdID identifies this program as the creator of the calendar.
+const ProdID = "-//applejag//rootless-personio//EN"
+
+// Event is a single calendar event.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	// Start is the first day of the event, or the start time if the event
+	// is not all-day.
+	Start time.Time
+	// End is exclusive, i.e the day after the last day of an all-day event.
+	End        time.Time
+	AllDay     bool
+	Categories []string
+	// Transparent events don't block time in the calendar, i.e they are
+	// shown as "free" instead of "busy".
+	Transparent bool
+}
+
+// Calendar is a collection of events.
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Write writes the calendar in iCalendar format. The stamp is used as the
+// DTSTAMP of all events.
+func (c Calendar) Write(w io.Writer, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:"+ProdID)
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	if c.Name != "" {
+		writeLine(bw, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+	for _, e := range c.Events {
+		e.write(bw, stamp)
+	}
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// Write writes a calendar containing only this event, as stored per
+// resource on a CalDAV server.
+func (e Event) Write(w io.Writer, stamp time.Time) error {
+	return Calendar{Events: []Event{e}}.Write(w, stamp)
+}
+
+func (e Event) write(w *bufio.Writer, stamp time.Time) {
+	writeLine(w, "BEGIN:VEVENT")
+	writeLine(w, "UID:"+e.UID)
+	writeLine(w, "DTSTAMP:"+formatDateTime(stamp))
+	if e.AllDay {
+		writeLine(w, "DTSTART;VALUE=DATE:"+formatDate(e.Start))
+		writeLine(w, "DTEND;VALUE=DATE:"+formatDate(e.End))
+	} else {
+		writeLine(w, "DTSTART:"+formatDateTime(e.Start))
+		writeLine(w, "DTEND:"+formatDateTime(e.End))
+	}
+	writeLine(w, "SUMMARY:"+escapeText(e.Summary))
+	if e.Description != "" {
+		writeLine(w, "DESCRIPTION:"+escapeText(e.Description))
+	}
+	if len(e.Categories) > 0 {
+		escaped := make([]string, len(e.Categories))
+		for i, c := range e.Categories {
+			escaped[i] = escapeText(c)
+		}
+		writeLine(w, "CATEGORIES:"+strings.Join(escaped, ","))
+	}
+	if e.Transparent {
+		writeLine(w, "TRANSP:TRANSPARENT")
+	} else {
+		writeLine(w, "TRANSP:OPAQUE")
+	}
+	writeLine(w, "END:VEVENT")
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("20060102")
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// writeLine writes the content line, folded into lines of at most 75
+// octets as required by RFC 5545, without splitting multi-byte characters.
+func writeLine(w *bufio.Writer, line string) {
+	const maxLen = 75
+	first := true
+	for len(line) > 0 {
+		limit := maxLen
+		if !first {
+			// The leading space counts towards the line length
+			limit--
+		}
+		n := len(line)
+		if n > limit {
+			n = limit
+			for n > 0 && !isRuneStart(line[n]) {
+				n--
+			}
+		}
+		if !first {
+			w.WriteByte(' ')
+		}
+		w.WriteString(line[:n])
+		w.WriteString("\r\n")
+		line = line[n:]
+		first = false
+	}
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+func TestCalendarWrite(t *testing.T) {
+	event := AbsenceEvent(personio.CalendarAbsencePeriod{
+		ID:         "123",
+		Name:       "Paid vacation, summer; part 1",
+		StartDate:  personio.NewDate(2023, 7, 3),
+		EndDate:    personio.NewDate(2023, 7, 14),
+		HalfDayEnd: true,
+	})
+	var buf bytes.Buffer
+	cal := Calendar{Name: "Vacation", Events: []Event{event}}
+	if err := cal.Write(&buf, time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:rootless-personio-absence-2023-07-03-123\r\n",
+		"DTSTAMP:20230601T120000Z\r\n",
+		"DTSTART;VALUE=DATE:20230703\r\n",
+		// End is exclusive
+		"DTEND;VALUE=DATE:20230715\r\n",
+		`SUMMARY:Paid vacation\, summer\; part 1 (half day at end)` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want output to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestWriteLineFolding(t *testing.T) {
+	var buf bytes.Buffer
+	event := Event{
+		UID:     "x",
+		Summary: strings.Repeat("å", 100),
+		AllDay:  true,
+	}
+	if err := event.Write(&buf, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("want at most 75 octets per line, got %d: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("want folding to not split characters, got %q", line)
+		}
+	}
+}
+
+func TestEventDate(t *testing.T) {
+	date, ok := EventDate("rootless-personio-holiday-2023-12-26-42")
+	if !ok || date.Format(time.DateOnly) != "2023-12-26" {
+		t.Errorf("want 2023-12-26, got %s %t", date, ok)
+	}
+	if _, ok := EventDate("some-other-event"); ok {
+		t.Error("want false for foreign UID")
+	}
+}
+
+func TestPeriodEvent(t *testing.T) {
+	comment := "lunch"
+	var p personio.CalendarAttendancePeriod
+	p.ID = uuid.MustParse("bc1edc0c-44ef-467f-89a0-10d0733efec5")
+	p.Attributes.PeriodType = "break"
+	p.Attributes.Comment = &comment
+	p.Attributes.Start = personio.NewFlexibleTime(time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC))
+	p.Attributes.End = personio.NewFlexibleTime(time.Date(2023, 1, 18, 12, 30, 0, 0, time.UTC))
+
+	event, err := PeriodEvent(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Summary != "Break" || event.Description != "lunch" || !event.Transparent {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if date, ok := EventDate(event.UID); !ok || date.Format(time.DateOnly) != "2023-01-18" {
+		t.Errorf("want UID dated 2023-01-18, got %q", event.UID)
+	}
+}
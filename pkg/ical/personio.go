@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// UIDPrefix is the prefix of the UIDs of all events created by this
+// package, used to tell them apart from other events in a shared calendar.
+const UIDPrefix = "rootless-personio-"
+
+// AbsenceEvent converts an absence to an all-day event.
+func AbsenceEvent(a personio.CalendarAbsencePeriod) Event {
+	summary := a.Name
+	switch {
+	case a.HalfDayStart && a.HalfDayEnd && a.StartDate == a.EndDate:
+		summary += " (half day)"
+	case a.HalfDayStart:
+		summary += " (half day at start)"
+	case a.HalfDayEnd:
+		summary += " (half day at end)"
+	}
+	return Event{
+		UID:        fmt.Sprintf("%sabsence-%s-%s", UIDPrefix, a.StartDate, a.ID),
+		Summary:    summary,
+		Start:      a.StartDate.Time,
+		End:        a.EndDate.AddDays(1).Time,
+		AllDay:     true,
+		Categories: []string{"Absence"},
+	}
+}
+
+// PeriodEvent converts an attendance period to a timed event. Breaks are
+// transparent, so they don't block time in the calendar.
+func PeriodEvent(p personio.CalendarAttendancePeriod) (Event, error) {
+	period, err := p.Period()
+	if err != nil {
+		return Event{}, err
+	}
+	periodType := p.Attributes.PeriodType
+	summary := periodType
+	if summary != "" {
+		summary = strings.ToUpper(summary[:1]) + summary[1:]
+	}
+	return Event{
+		UID:         fmt.Sprintf("%speriod-%s-%s", UIDPrefix, period.Start.Format(time.DateOnly), p.ID),
+		Summary:     summary,
+		Description: p.GetComment(),
+		Start:       period.Start,
+		End:         period.End,
+		Categories:  []string{"Attendance"},
+		Transparent: periodType == string(personio.PeriodTypeBreak),
+	}, nil
+}
+
+// HolidayEvent converts a public holiday to an all-day event.
+func HolidayEvent(h personio.CalendarHoliday) Event {
+	summary := h.Name
+	if h.HalfDay {
+		summary += " (half day)"
+	}
+	return Event{
+		UID:         fmt.Sprintf("%sholiday-%s-%d", UIDPrefix, h.Date, h.ID),
+		Summary:     summary,
+		Description: h.HolidayCalendarName,
+		Start:       h.Date.Time,
+		End:         h.Date.AddDays(1).Time,
+		AllDay:      true,
+		Categories:  []string{"Holiday"},
+		Transparent: h.HalfDay,
+	}
+}
+
+// EventDate returns the date that is part of the UID of events created by
+// this package, or false if the UID is not from this package.
+func EventDate(uid string) (time.Time, bool) {
+	// ex: "rootless-personio-absence-2023-01-18-123456"
+	if len(uid) < len(UIDPrefix) || uid[:len(UIDPrefix)] != UIDPrefix {
+		return time.Time{}, false
+	}
+	rest := uid[len(UIDPrefix):]
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != '-' {
+			continue
+		}
+		if len(rest) < i+1+len(time.DateOnly) {
+			return time.Time{}, false
+		}
+		date, err := time.Parse(time.DateOnly, rest[i+1:i+1+len(time.DateOnly)])
+		return date, err == nil
+	}
+	return time.Time{}, false
+}
+
+// EventOptions selects which parts of an attendance calendar to convert
+// with [CalendarEvents].
+type EventOptions struct {
+	Periods  bool
+	Absences bool
+	Holidays bool
+}
+
+// CalendarEvents converts the selected parts of the attendance calendar to
+// events, sorted with [SortEvents].
+func CalendarEvents(cal *personio.AttendanceCalendar, opts EventOptions) ([]Event, error) {
+	var events []Event
+	if opts.Periods {
+		for _, p := range cal.AttendancePeriods.Data {
+			event, err := PeriodEvent(p)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+		}
+	}
+	if opts.Absences {
+		for _, a := range cal.GetAbsencePeriods() {
+			events = append(events, AbsenceEvent(a))
+		}
+	}
+	if opts.Holidays {
+		for _, h := range cal.GetHolidays() {
+			events = append(events, HolidayEvent(h))
+		}
+	}
+	SortEvents(events)
+	return events, nil
+}
+
+// SortEvents sorts the events by their start, and then by their UID.
+func SortEvents(events []Event) {
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].Start.Equal(events[j].Start) {
+			return events[i].Start.Before(events[j].Start)
+		}
+		return events[i].UID < events[j].UID
+	})
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package keyring stores login passwords in the operating system's keyring,
+// such as the macOS Keychain, the Windows Credential Manager, or the
+// Secret Service (GNOME Keyring, KWallet) on Linux.
+package keyring
+
+import (
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Service is the name the passwords are stored under in the keyring.
+const Service = "rootless-personio"
+
+// ErrNotFound is returned when there's no password stored for the user.
+var ErrNotFound = keyring.ErrNotFound
+
+// Get returns the stored password for the email address.
+func Get(email string) (string, error) {
+	if email == "" {
+		return "", ErrNotFound
+	}
+	return keyring.Get(Service, email)
+}
+
+// Set stores the password for the email address, replacing any password
+// already stored for it.
+func Set(email, password string) error {
+	if email == "" {
+		return errors.New("missing email")
+	}
+	return keyring.Set(Service, email, password)
+}
+
+// Delete removes the stored password for the email address.
+func Delete(email string) error {
+	return keyring.Delete(Service, email)
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package lockout remembers when Personio has locked out logins due to too
+// many login attempts, so no further attempts are made until the cooldown
+// has passed. This is persisted on disk, as it needs to survive across
+// invocations, such as from a misconfigured cron job.
+package lockout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/filelock"
+)
+
+var ErrLockedOut = errors.New("login locked out")
+
+// State is the lockout state, as stored on disk.
+type State struct {
+	// Email is the account that was locked out.
+	Email string `json:"email"`
+	// Until is when login attempts are allowed again.
+	Until time.Time `json:"until"`
+	// Message is the error message from Personio, if any.
+	Message string `json:"message,omitempty"`
+}
+
+// Active returns true if login attempts for the email address are not
+// allowed at the given time.
+func (s State) Active(email string, now time.Time) bool {
+	return strings.EqualFold(s.Email, email) && now.Before(s.Until)
+}
+
+// DefaultPath returns the default path of the lockout state file, which is
+// inside the user's config directory, e.g
+// ~/.config/rootless-personio/lockout.json
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rootless-personio", "lockout.json"), nil
+}
+
+// Load reads the lockout state. A missing file means no lockout.
+func Load(path string) (State, error) {
+	var state State
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("read lockout state: %w", err)
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return state, fmt.Errorf("parse lockout state: %w", err)
+	}
+	return state, nil
+}
+
+// Save writes the lockout state.
+func Save(path string, state State) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := filelock.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("write lockout state: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the lockout state, if any.
+func Clear(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove lockout state: %w", err)
+	}
+	return nil
+}
+
+// Check returns an error wrapping [ErrLockedOut] if login attempts for the
+// email address are not allowed at the given time.
+func Check(path, email string, now time.Time) error {
+	state, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if !state.Active(email, now) {
+		return nil
+	}
+	return fmt.Errorf("%w until %s (in %s), to not get the account locked for longer; remove %s to try anyway",
+		ErrLockedOut, state.Until.Local().Format(time.DateTime),
+		state.Until.Sub(now).Round(time.Second), path)
+}
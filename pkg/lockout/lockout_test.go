@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lockout
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lockout.json")
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := Check(path, "me@example.com", now); err != nil {
+		t.Fatalf("want no lockout without state file, got: %s", err)
+	}
+
+	state := State{Email: "me@example.com", Until: now.Add(15 * time.Minute)}
+	if err := Save(path, state); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(path, "ME@example.com", now); !errors.Is(err, ErrLockedOut) {
+		t.Errorf("want ErrLockedOut, got: %v", err)
+	}
+	if err := Check(path, "other@example.com", now); err != nil {
+		t.Errorf("want no lockout for other account, got: %s", err)
+	}
+	if err := Check(path, "me@example.com", now.Add(15*time.Minute)); err != nil {
+		t.Errorf("want no lockout after cooldown, got: %s", err)
+	}
+
+	if err := Clear(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(path, "me@example.com", now); err != nil {
+		t.Errorf("want no lockout after clear, got: %s", err)
+	}
+}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := Message{
+		From:    "Personio <me@example.com>",
+		To:      []string{"me@example.com"},
+		Subject: "Wöchentliche Zusammenfassung",
+		Body:    "line 1\nline 2\n",
+		Date:    time.Date(2023, 1, 20, 17, 0, 0, 0, time.UTC),
+	}
+	b, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{
+		"From: \"Personio\" <me@example.com>\r\n",
+		"To: <me@example.com>\r\n",
+		"Subject: =?utf-8?q?W=C3=B6chentliche_Zusammenfassung?=\r\n",
+		"Date: Fri, 20 Jan 2023 17:00:00 +0000\r\n",
+		"\r\n\r\nline 1\r\nline 2\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want message to contain %q, got:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package mcp contains a minimal Model Context Protocol server, which
+// exposes tools to AI assistants over newline-delimited JSON-RPC 2.0 on
+// STDIN and STDOUT.
+//
+// See https://modelcontextprotocol.io/specification for the protocol.
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ProtocolVersion is the MCP protocol revision implemented by this package.
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC 2.0 error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Tool is a function that the AI assistant can call.
+type Tool struct {
+	Name        string
+	Description string
+	// InputSchema is the JSON Schema of the tool's arguments.
+	InputSchema map[string]any
+	// Handler is called with the tool's arguments, and its result is
+	// returned to the assistant as JSON. Errors are returned to the
+	// assistant as tool errors, so it can react to them.
+	Handler func(args json.RawMessage) (any, error)
+}
+
+// Server serves tools over MCP.
+type Server struct {
+	Name    string
+	Version string
+	Tools   []Tool
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *responseError  `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type toolInfo struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type toolCallParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type toolContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type toolCallResult struct {
+	Content []toolContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// Serve reads requests from r and writes responses to w, until r is closed.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	write := func(resp response) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := enc.Encode(resp); err != nil {
+			log.Error().Err(err).Msg("Failed writing MCP response.")
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			write(response{JSONRPC: "2.0", ID: json.RawMessage("null"),
+				Error: &responseError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+		// Requests without ID are notifications, which get no response
+		if len(req.ID) == 0 {
+			log.Debug().Str("method", req.Method).Msg("Received MCP notification.")
+			continue
+		}
+		result, rpcErr := s.handle(req)
+		resp := response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+		write(resp)
+	}
+	return scanner.Err()
+}
+
+func (s *Server) handle(req request) (any, *responseError) {
+	log.Debug().Str("method", req.Method).Msg("Received MCP request.")
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities": map[string]any{
+				"tools": map[string]any{},
+			},
+			"serverInfo": map[string]any{
+				"name":    s.Name,
+				"version": s.Version,
+			},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		tools := make([]toolInfo, len(s.Tools))
+		for i, t := range s.Tools {
+			tools[i] = toolInfo{Name: t.Name, Description: t.Description, InputSchema: t.InputSchema}
+		}
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var params toolCallParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		tool, ok := s.tool(params.Name)
+		if !ok {
+			return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %q", params.Name)}
+		}
+		return callTool(tool, params.Arguments), nil
+	case "":
+		return nil, &responseError{Code: codeInvalidRequest, Message: "missing method"}
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %q", req.Method)}
+	}
+}
+
+func (s *Server) tool(name string) (Tool, bool) {
+	for _, t := range s.Tools {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Tool{}, false
+}
+
+func callTool(tool Tool, args json.RawMessage) toolCallResult {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	result, err := tool.Handler(args)
+	if err != nil {
+		log.Warn().Err(err).Str("tool", tool.Name).Msg("MCP tool failed.")
+		return toolCallResult{
+			Content: []toolContent{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return toolCallResult{
+			Content: []toolContent{{Type: "text", Text: fmt.Sprintf("encode result: %s", err)}},
+			IsError: true,
+		}
+	}
+	return toolCallResult{Content: []toolContent{{Type: "text", Text: string(b)}}}
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	srv := Server{
+		Name:    "test",
+		Version: "1.0",
+		Tools: []Tool{
+			{
+				Name:        "echo",
+				InputSchema: map[string]any{"type": "object"},
+				Handler: func(args json.RawMessage) (any, error) {
+					return args, nil
+				},
+			},
+			{
+				Name:        "fail",
+				InputSchema: map[string]any{"type": "object"},
+				Handler: func(json.RawMessage) (any, error) {
+					return nil, errors.New("boom")
+				},
+			},
+		},
+	}
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"a":1}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"fail"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"nope"}`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := srv.Serve(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("serve: %s", err)
+	}
+
+	var responses []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp map[string]any
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("decode: %s", err)
+		}
+		responses = append(responses, resp)
+	}
+	if len(responses) != 5 {
+		t.Fatalf("want 5 responses (no response to notification), got %d", len(responses))
+	}
+	if v := responses[0]["result"].(map[string]any)["protocolVersion"]; v != ProtocolVersion {
+		t.Errorf("want protocol version %q, got %v", ProtocolVersion, v)
+	}
+	tools := responses[1]["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 2 {
+		t.Errorf("want 2 tools, got %d", len(tools))
+	}
+	echo := responses[2]["result"].(map[string]any)["content"].([]any)[0].(map[string]any)
+	if echo["text"] != `{"a":1}` {
+		t.Errorf("want echoed arguments, got %v", echo["text"])
+	}
+	if isErr := responses[3]["result"].(map[string]any)["isError"]; isErr != true {
+		t.Errorf("want tool error, got %v", responses[3])
+	}
+	if code := responses[4]["error"].(map[string]any)["code"]; code != float64(codeMethodNotFound) {
+		t.Errorf("want method not found, got %v", responses[4])
+	}
+}
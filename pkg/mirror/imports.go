@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mirror
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/timesheet"
+	"github.com/google/uuid"
+)
+
+// ImportedEntry records that an entry from an external time tracker was
+// sent to Personio, so importing it again can be skipped.
+//
+// It's stored separately from the synced data, so it's kept when the
+// entry's month is synced again.
+type ImportedEntry struct {
+	Source     timesheet.Source `json:"source"`
+	SourceID   string           `json:"sourceId"`
+	PeriodID   uuid.UUID        `json:"periodId"`
+	Date       string           `json:"date"`
+	Hash       string           `json:"hash"`
+	ImportedAt time.Time        `json:"importedAt"`
+}
+
+// ImportedEntries returns the previously imported entries of the source,
+// keyed by their source IDs.
+func (m *Mirror) ImportedEntries(source timesheet.Source) (map[string]ImportedEntry, error) {
+	imported := make(map[string]ImportedEntry)
+	if err := m.query(`SELECT source_id, period_id, date, hash, imported_at FROM imported_entries WHERE source = ?`,
+		[]any{string(source)}, func(rows *sql.Rows) error {
+			e := ImportedEntry{Source: source}
+			var importedAt string
+			if err := rows.Scan(&e.SourceID, &e.PeriodID, &e.Date, &e.Hash, &importedAt); err != nil {
+				return err
+			}
+			t, err := time.Parse(time.RFC3339, importedAt)
+			if err != nil {
+				return fmt.Errorf("parse imported_at: %w", err)
+			}
+			e.ImportedAt = t
+			imported[e.SourceID] = e
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("read imported entries: %w", err)
+	}
+	return imported, nil
+}
+
+// RecordImports stores that the entries were sent to Personio, replacing
+// any previous records of the same source IDs.
+func (m *Mirror) RecordImports(entries ...ImportedEntry) (err error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	for _, e := range entries {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO imported_entries VALUES (?, ?, ?, ?, ?, ?)`,
+			string(e.Source), e.SourceID, e.PeriodID.String(), e.Date, e.Hash,
+			e.ImportedAt.UTC().Format(time.RFC3339)); err != nil {
+			return fmt.Errorf("record imported entry: %w", err)
+		}
+	}
+	return tx.Commit()
+}
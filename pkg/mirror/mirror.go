@@ -0,0 +1,420 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package mirror contains a local SQLite database that mirrors your
+// attendance data, used for instant and offline analysis.
+package mirror
+
+import (
+	"database/sql"
+	"encoding"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+
+	// Registers the pure-Go "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+// ErrNotSynced is returned when reading a date range from the mirror that
+// has not been synced yet.
+var ErrNotSynced = errors.New("date range not synced to mirror")
+
+const schema = `
+CREATE TABLE IF NOT EXISTS days (
+	id           TEXT PRIMARY KEY,
+	date         TEXT NOT NULL UNIQUE,
+	status       TEXT NOT NULL,
+	duration_min INTEGER NOT NULL,
+	break_min    INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS periods (
+	id               TEXT PRIMARY KEY,
+	day_id           TEXT NOT NULL,
+	date             TEXT NOT NULL,
+	period_type      TEXT NOT NULL,
+	start            TEXT NOT NULL,
+	end              TEXT NOT NULL,
+	comment          TEXT,
+	project_id       INTEGER,
+	legacy_break_min INTEGER NOT NULL,
+	origin           TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS periods_date ON periods (date);
+CREATE TABLE IF NOT EXISTS absences (
+	id                 TEXT PRIMARY KEY,
+	name               TEXT NOT NULL,
+	tracks_overtime    INTEGER NOT NULL,
+	measurement_unit   TEXT NOT NULL,
+	start_date         TEXT NOT NULL,
+	start_time         TEXT NOT NULL,
+	end_date           TEXT NOT NULL,
+	end_time           TEXT NOT NULL,
+	effective_duration_min INTEGER,
+	half_day_start     INTEGER NOT NULL,
+	half_day_end       INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS holidays (
+	id            INTEGER NOT NULL,
+	date          TEXT NOT NULL,
+	name          TEXT NOT NULL,
+	half_day      INTEGER NOT NULL,
+	calendar_name TEXT NOT NULL,
+	PRIMARY KEY (id, date)
+);
+CREATE TABLE IF NOT EXISTS synced_months (
+	month     TEXT PRIMARY KEY,
+	synced_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS period_tags (
+	period_id TEXT NOT NULL,
+	tag       TEXT NOT NULL,
+	PRIMARY KEY (period_id, tag)
+);
+CREATE TABLE IF NOT EXISTS period_notes (
+	period_id TEXT PRIMARY KEY,
+	note      TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS imported_entries (
+	source      TEXT NOT NULL,
+	source_id   TEXT NOT NULL,
+	period_id   TEXT NOT NULL,
+	date        TEXT NOT NULL,
+	hash        TEXT NOT NULL,
+	imported_at TEXT NOT NULL,
+	PRIMARY KEY (source, source_id)
+);
+`
+
+// Mirror is a local SQLite database with attendance data.
+type Mirror struct {
+	db *sql.DB
+}
+
+// DefaultPath returns the default path of the mirror database, which is
+// inside the user's cache directory, e.g ~/.cache/rootless-personio/mirror.db
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rootless-personio", "mirror.db"), nil
+}
+
+// Open opens the mirror database, creating it if it doesn't exist.
+func Open(path string) (*Mirror, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create mirror directory: %w", err)
+	}
+	// Wait for concurrent runs, such as the daemon syncing, instead of
+	// failing right away with "database is locked"
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open mirror: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create mirror schema: %w", err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate mirror schema: %w", err)
+	}
+	return &Mirror{db: db}, nil
+}
+
+// migrate adds the columns that are missing in mirrors created by older
+// versions, as "CREATE TABLE IF NOT EXISTS" leaves existing tables as-is.
+func migrate(db *sql.DB) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('periods') WHERE name = 'origin'`).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		if _, err := db.Exec(`ALTER TABLE periods ADD COLUMN origin TEXT NOT NULL DEFAULT ''`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database.
+func (m *Mirror) Close() error {
+	return m.db.Close()
+}
+
+// Sync replaces all data within the date range with the data from the
+// calendar. The range must cover exactly one full month, as the mirror
+// keeps track of which months have been synced.
+func (m *Mirror) Sync(cal *personio.AttendanceCalendar, r datespec.Range, now time.Time) (err error) {
+	start := r.Start.Format(time.DateOnly)
+	end := r.End.Format(time.DateOnly)
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	stmts := []string{
+		`DELETE FROM days WHERE date BETWEEN ? AND ?`,
+		`DELETE FROM periods WHERE date BETWEEN ? AND ?`,
+	}
+	// Keep previously synced data of sections that Personio omitted
+	if cal.HasSection(personio.SectionHolidays) {
+		stmts = append(stmts, `DELETE FROM holidays WHERE date BETWEEN ? AND ?`)
+	}
+	if cal.HasSection(personio.SectionAbsencePeriods) {
+		stmts = append(stmts, `DELETE FROM absences WHERE start_date <= ?2 AND end_date >= ?1`)
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt, start, end); err != nil {
+			return fmt.Errorf("clear mirror: %w", err)
+		}
+	}
+
+	dayDates := make(map[uuid.UUID]string, len(cal.AttendanceDays.Data))
+	for _, d := range cal.AttendanceDays.Data {
+		dayDates[d.ID] = d.Attributes.Day.String()
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO days VALUES (?, ?, ?, ?, ?)`,
+			d.ID.String(), d.Attributes.Day.String(), d.Attributes.Status,
+			d.Attributes.DurationMin, d.Attributes.BreakMin); err != nil {
+			return fmt.Errorf("insert day: %w", err)
+		}
+	}
+	for _, p := range cal.AttendancePeriods.Data {
+		a := p.Attributes
+		date, ok := dayDates[a.AttendanceDayID]
+		if !ok && !a.Start.IsZero() {
+			date = a.Start.Format(time.DateOnly)
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO periods VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			p.ID.String(), a.AttendanceDayID.String(), date, a.PeriodType,
+			a.Start.String(), a.End.String(), a.Comment, a.ProjectID, a.LegacyBreakMin, a.Origin); err != nil {
+			return fmt.Errorf("insert period: %w", err)
+		}
+	}
+	for _, a := range cal.GetAbsencePeriods() {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO absences VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			a.ID, a.Name, a.TracksOvertime, a.MeasurementUnit,
+			a.StartDate.String(), a.StartTime.String(), a.EndDate.String(), a.EndTime.String(),
+			a.EffectiveDurationInMinutes, a.HalfDayStart, a.HalfDayEnd); err != nil {
+			return fmt.Errorf("insert absence: %w", err)
+		}
+	}
+	for _, h := range cal.GetHolidays() {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO holidays VALUES (?, ?, ?, ?, ?)`,
+			h.ID, h.Date.String(), h.Name, h.HalfDay, h.HolidayCalendarName); err != nil {
+			return fmt.Errorf("insert holiday: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO synced_months VALUES (?, ?)`,
+		r.Start.Format("2006-01"), now.UTC().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("mark month as synced: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetMyAttendanceCalendar reads the attendance calendar from the mirror,
+// in the same format as [personio.Client.GetMyAttendanceCalendar].
+//
+// Returns [ErrNotSynced] if any month in the range has not been synced.
+func (m *Mirror) GetMyAttendanceCalendar(startDate, endDate time.Time) (*personio.AttendanceCalendar, error) {
+	start := startDate.Format(time.DateOnly)
+	end := endDate.Format(time.DateOnly)
+	if err := m.assertSynced(startDate, endDate); err != nil {
+		return nil, err
+	}
+
+	cal := &personio.AttendanceCalendar{
+		AbsencePeriods: &personio.Data[[]personio.CalendarAbsencePeriod]{},
+		Holidays:       &personio.Data[[]personio.CalendarHoliday]{},
+	}
+	if err := m.query(`SELECT id, date, status, duration_min, break_min FROM days WHERE date BETWEEN ? AND ? ORDER BY date`,
+		[]any{start, end}, func(rows *sql.Rows) error {
+			var d personio.CalendarDay
+			if err := rows.Scan(&d.ID, textScanner{&d.Attributes.Day}, &d.Attributes.Status,
+				&d.Attributes.DurationMin, &d.Attributes.BreakMin); err != nil {
+				return err
+			}
+			cal.AttendanceDays.Data = append(cal.AttendanceDays.Data, d)
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("read days: %w", err)
+	}
+	if err := m.query(`SELECT id, day_id, period_type, start, end, comment, project_id, legacy_break_min, origin FROM periods WHERE date BETWEEN ? AND ? ORDER BY start`,
+		[]any{start, end}, func(rows *sql.Rows) error {
+			var p personio.CalendarAttendancePeriod
+			a := &p.Attributes
+			var projectID sql.NullInt64
+			if err := rows.Scan(&p.ID, &a.AttendanceDayID, &a.PeriodType, textScanner{&a.Start}, textScanner{&a.End},
+				&a.Comment, &projectID, &a.LegacyBreakMin, &a.Origin); err != nil {
+				return err
+			}
+			if projectID.Valid {
+				id := int(projectID.Int64)
+				a.ProjectID = &id
+			}
+			cal.AttendancePeriods.Data = append(cal.AttendancePeriods.Data, p)
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("read periods: %w", err)
+	}
+	if err := m.query(`SELECT id, name, tracks_overtime, measurement_unit, start_date, start_time, end_date, end_time, effective_duration_min, half_day_start, half_day_end FROM absences WHERE start_date <= ? AND end_date >= ? ORDER BY start_date`,
+		[]any{end, start}, func(rows *sql.Rows) error {
+			var a personio.CalendarAbsencePeriod
+			var effective sql.NullInt64
+			if err := rows.Scan(&a.ID, &a.Name, &a.TracksOvertime, &a.MeasurementUnit,
+				textScanner{&a.StartDate}, textScanner{&a.StartTime},
+				textScanner{&a.EndDate}, textScanner{&a.EndTime},
+				&effective, &a.HalfDayStart, &a.HalfDayEnd); err != nil {
+				return err
+			}
+			if effective.Valid {
+				min := personio.Minutes(effective.Int64)
+				a.EffectiveDurationInMinutes = &min
+			}
+			cal.AbsencePeriods.Data = append(cal.AbsencePeriods.Data, a)
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("read absences: %w", err)
+	}
+	if err := m.query(`SELECT id, date, name, half_day, calendar_name FROM holidays WHERE date BETWEEN ? AND ? ORDER BY date`,
+		[]any{start, end}, func(rows *sql.Rows) error {
+			var h personio.CalendarHoliday
+			if err := rows.Scan(&h.ID, textScanner{&h.Date}, &h.Name, &h.HalfDay, &h.HolidayCalendarName); err != nil {
+				return err
+			}
+			cal.Holidays.Data = append(cal.Holidays.Data, h)
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("read holidays: %w", err)
+	}
+	return cal, nil
+}
+
+func (m *Mirror) assertSynced(startDate, endDate time.Time) error {
+	year, month, _ := startDate.Date()
+	for date := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC); !date.After(endDate); date = date.AddDate(0, 1, 0) {
+		var count int
+		if err := m.db.QueryRow(`SELECT COUNT(*) FROM synced_months WHERE month = ?`,
+			date.Format("2006-01")).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: %s, run \"mirror sync\" first", ErrNotSynced, date.Format("2006-01"))
+		}
+	}
+	return nil
+}
+
+func (m *Mirror) query(query string, args []any, f func(rows *sql.Rows) error) error {
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := f(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// QueryResult is the result of a [Mirror.Query].
+type QueryResult struct {
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+}
+
+// Query runs an arbitrary SQL query against the mirror.
+func (m *Mirror) Query(query string) (*QueryResult, error) {
+	result := &QueryResult{Rows: [][]any{}}
+	err := m.QueryEach(query, func(columns []string, values []any) error {
+		result.Columns = columns
+		result.Rows = append(result.Rows, values)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryEach runs an arbitrary SQL query against the mirror, and calls the
+// function with each row as soon as it's read, to not keep large results
+// in memory.
+func (m *Mirror) QueryEach(query string, f func(columns []string, values []any) error) error {
+	rows, err := m.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if err := f(columns, values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// textScanner scans a text column into a value that's stored in its text
+// form, such as [personio.Date].
+type textScanner struct {
+	encoding.TextUnmarshaler
+}
+
+func (s textScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return s.UnmarshalText([]byte(v))
+	case []byte:
+		return s.UnmarshalText(v)
+	case nil:
+		return s.UnmarshalText(nil)
+	default:
+		return fmt.Errorf("scan %T as text", src)
+	}
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mirror
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/timesheet"
+	"github.com/google/uuid"
+)
+
+func TestMirrorSync(t *testing.T) {
+	m, err := Open(filepath.Join(t.TempDir(), "mirror.db"))
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+	defer m.Close()
+
+	dayID := uuid.New()
+	comment := "Work before lunch"
+	projectID := 42
+	cal := &personio.AttendanceCalendar{}
+	cal.AttendanceDays.Data = []personio.CalendarDay{{
+		ID: dayID,
+		Attributes: personio.CalendarDayAttributes{
+			Day: personio.NewDate(2023, 1, 18), Status: "confirmed", DurationMin: 240,
+		},
+	}}
+	cal.AttendancePeriods.Data = []personio.CalendarAttendancePeriod{{
+		ID: uuid.New(),
+		Attributes: personio.CalendarAttendancePeriodAttributes{
+			AttendanceDayID: dayID,
+			Comment:         &comment,
+			Start:           personio.NewFlexibleTime(time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)),
+			End:             personio.NewFlexibleTime(time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)),
+			PeriodType:      "work",
+			ProjectID:       &projectID,
+			Origin:          personio.PeriodOriginWeb,
+		},
+	}}
+	cal.Holidays = &personio.Data[[]personio.CalendarHoliday]{}
+	cal.Holidays.Data = []personio.CalendarHoliday{{
+		ID: 1, Date: personio.NewDate(2023, 1, 1), Name: "New Year",
+	}}
+
+	january := datespec.ThisMonth(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if _, err := m.GetMyAttendanceCalendar(january.Start, january.End); !errors.Is(err, ErrNotSynced) {
+		t.Fatalf("want ErrNotSynced before sync, got %v", err)
+	}
+	if err := m.Sync(cal, january, time.Now()); err != nil {
+		t.Fatalf("sync: %s", err)
+	}
+	// Syncing twice must replace, not duplicate, the data
+	if err := m.Sync(cal, january, time.Now()); err != nil {
+		t.Fatalf("sync again: %s", err)
+	}
+	// Sections omitted by Personio must keep their previously synced data
+	partial := *cal
+	partial.Holidays = nil
+	if err := m.Sync(&partial, january, time.Now()); err != nil {
+		t.Fatalf("sync partial: %s", err)
+	}
+
+	got, err := m.GetMyAttendanceCalendar(january.Start, january.End)
+	if err != nil {
+		t.Fatalf("get calendar: %s", err)
+	}
+	if !reflect.DeepEqual(got.AttendanceDays.Data, cal.AttendanceDays.Data) {
+		t.Errorf("days mismatch:\nwant %+v\ngot  %+v", cal.AttendanceDays.Data, got.AttendanceDays.Data)
+	}
+	if !reflect.DeepEqual(got.AttendancePeriods.Data, cal.AttendancePeriods.Data) {
+		t.Errorf("periods mismatch:\nwant %+v\ngot  %+v", cal.AttendancePeriods.Data, got.AttendancePeriods.Data)
+	}
+	if !reflect.DeepEqual(got.Holidays.Data, cal.Holidays.Data) {
+		t.Errorf("holidays mismatch:\nwant %+v\ngot  %+v", cal.Holidays.Data, got.Holidays.Data)
+	}
+
+	result, err := m.Query(`SELECT COUNT(*) AS n FROM periods`)
+	if err != nil {
+		t.Fatalf("query: %s", err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0][0] != int64(1) {
+		t.Errorf("want 1 period, got %+v", result.Rows)
+	}
+}
+
+func TestMirrorPeriodMeta(t *testing.T) {
+	m, err := Open(filepath.Join(t.TempDir(), "mirror.db"))
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+	defer m.Close()
+
+	periodID := uuid.New()
+	cal := &personio.AttendanceCalendar{}
+	cal.AttendancePeriods.Data = []personio.CalendarAttendancePeriod{{
+		ID: periodID,
+		Attributes: personio.CalendarAttendancePeriodAttributes{
+			Start:      personio.NewFlexibleTime(time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)),
+			End:        personio.NewFlexibleTime(time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)),
+			PeriodType: "work",
+		},
+	}}
+	january := datespec.ThisMonth(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	if err := m.AddTags(periodID, "client-a"); !errors.Is(err, ErrPeriodNotFound) {
+		t.Fatalf("want ErrPeriodNotFound before sync, got %v", err)
+	}
+	if err := m.Sync(cal, january, time.Now()); err != nil {
+		t.Fatalf("sync: %s", err)
+	}
+	if err := m.AddTags(periodID, "#client-a", "billable", "temp"); err != nil {
+		t.Fatalf("add tags: %s", err)
+	}
+	if err := m.RemoveTags(periodID, "temp"); err != nil {
+		t.Fatalf("remove tags: %s", err)
+	}
+	if err := m.SetNote(periodID, "Sprint planning"); err != nil {
+		t.Fatalf("set note: %s", err)
+	}
+	// Tags and notes must survive syncing the month again
+	if err := m.Sync(cal, january, time.Now()); err != nil {
+		t.Fatalf("sync again: %s", err)
+	}
+
+	metas, err := m.PeriodMeta(january.Start, january.End)
+	if err != nil {
+		t.Fatalf("period meta: %s", err)
+	}
+	want := map[uuid.UUID]PeriodMeta{
+		periodID: {Tags: []string{"billable", "client-a"}, Note: "Sprint planning"},
+	}
+	if !reflect.DeepEqual(metas, want) {
+		t.Errorf("want %+v, got %+v", want, metas)
+	}
+}
+
+func TestMirrorImportedEntries(t *testing.T) {
+	m, err := Open(filepath.Join(t.TempDir(), "mirror.db"))
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+	defer m.Close()
+
+	entry := ImportedEntry{
+		Source:     timesheet.SourceImport,
+		SourceID:   "timetrap-123",
+		PeriodID:   uuid.New(),
+		Date:       "2023-01-18",
+		Hash:       "abc",
+		ImportedAt: time.Date(2023, 1, 18, 18, 0, 0, 0, time.UTC),
+	}
+	other := entry
+	other.Source = "dinkur"
+	if err := m.RecordImports(entry, other); err != nil {
+		t.Fatalf("record imports: %s", err)
+	}
+	entry.Hash = "def"
+	if err := m.RecordImports(entry); err != nil {
+		t.Fatalf("record imports again: %s", err)
+	}
+
+	got, err := m.ImportedEntries(timesheet.SourceImport)
+	if err != nil {
+		t.Fatalf("imported entries: %s", err)
+	}
+	want := map[string]ImportedEntry{entry.SourceID: entry}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestMirrorMigratesOrigin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mirror.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open old mirror: %s", err)
+	}
+	// Periods table as created before the origin column was added
+	if _, err := db.Exec(`CREATE TABLE periods (
+	id TEXT PRIMARY KEY, day_id TEXT NOT NULL, date TEXT NOT NULL,
+	period_type TEXT NOT NULL, start TEXT NOT NULL, end TEXT NOT NULL,
+	comment TEXT, project_id INTEGER, legacy_break_min INTEGER NOT NULL)`); err != nil {
+		t.Fatalf("create old schema: %s", err)
+	}
+	if _, err := db.Exec(`INSERT INTO periods VALUES ('a', 'b', '2023-01-18', 'work', '2023-01-18T08:00:00Z', '2023-01-18T12:00:00Z', NULL, NULL, 0)`); err != nil {
+		t.Fatalf("insert old period: %s", err)
+	}
+	db.Close()
+
+	m, err := Open(path)
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+	defer m.Close()
+	result, err := m.Query(`SELECT origin FROM periods`)
+	if err != nil {
+		t.Fatalf("query: %s", err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0][0] != "" {
+		t.Errorf("want 1 period of unknown origin, got %+v", result.Rows)
+	}
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mirror
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrPeriodNotFound is returned when tagging a period that is not in the
+// mirror.
+var ErrPeriodNotFound = errors.New("period not found in mirror")
+
+// PeriodMeta is local-only metadata of an attendance period, which is
+// never sent to Personio.
+//
+// It's stored separately from the synced data, keyed by the period ID,
+// so it's kept when the period's month is synced again.
+type PeriodMeta struct {
+	Tags []string `json:"tags,omitempty"`
+	Note string   `json:"note,omitempty"`
+}
+
+// NormalizeTag trims whitespace and a leading "#" from the tag.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	if tag == "" {
+		return "", errors.New("tag must not be empty")
+	}
+	if strings.ContainsAny(tag, " \t\n") {
+		return "", fmt.Errorf("tag %q must not contain whitespace", tag)
+	}
+	return tag, nil
+}
+
+// AddTags adds tags to a period.
+//
+// Returns [ErrPeriodNotFound] if the period has not been synced.
+func (m *Mirror) AddTags(periodID uuid.UUID, tags ...string) error {
+	if err := m.assertPeriodExists(periodID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		if _, err := m.db.Exec(`INSERT OR IGNORE INTO period_tags VALUES (?, ?)`,
+			periodID.String(), tag); err != nil {
+			return fmt.Errorf("add tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// RemoveTags removes tags from a period. Tags that the period doesn't
+// have are ignored.
+func (m *Mirror) RemoveTags(periodID uuid.UUID, tags ...string) error {
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		if _, err := m.db.Exec(`DELETE FROM period_tags WHERE period_id = ? AND tag = ?`,
+			periodID.String(), tag); err != nil {
+			return fmt.Errorf("remove tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetNote sets the note of a period. An empty note removes it.
+//
+// Returns [ErrPeriodNotFound] if the period has not been synced.
+func (m *Mirror) SetNote(periodID uuid.UUID, note string) error {
+	if note == "" {
+		if _, err := m.db.Exec(`DELETE FROM period_notes WHERE period_id = ?`, periodID.String()); err != nil {
+			return fmt.Errorf("remove note: %w", err)
+		}
+		return nil
+	}
+	if err := m.assertPeriodExists(periodID); err != nil {
+		return err
+	}
+	if _, err := m.db.Exec(`INSERT OR REPLACE INTO period_notes VALUES (?, ?)`,
+		periodID.String(), note); err != nil {
+		return fmt.Errorf("set note: %w", err)
+	}
+	return nil
+}
+
+// PeriodMeta returns the metadata of all periods within the date range
+// that have any tags or a note.
+func (m *Mirror) PeriodMeta(startDate, endDate time.Time) (map[uuid.UUID]PeriodMeta, error) {
+	start := startDate.Format(time.DateOnly)
+	end := endDate.Format(time.DateOnly)
+	metas := make(map[uuid.UUID]PeriodMeta)
+	if err := m.query(`SELECT t.period_id, t.tag FROM period_tags t JOIN periods p ON p.id = t.period_id WHERE p.date BETWEEN ? AND ?`,
+		[]any{start, end}, func(rows *sql.Rows) error {
+			var id uuid.UUID
+			var tag string
+			if err := rows.Scan(&id, &tag); err != nil {
+				return err
+			}
+			meta := metas[id]
+			meta.Tags = append(meta.Tags, tag)
+			metas[id] = meta
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("read tags: %w", err)
+	}
+	if err := m.query(`SELECT n.period_id, n.note FROM period_notes n JOIN periods p ON p.id = n.period_id WHERE p.date BETWEEN ? AND ?`,
+		[]any{start, end}, func(rows *sql.Rows) error {
+			var id uuid.UUID
+			var note string
+			if err := rows.Scan(&id, &note); err != nil {
+				return err
+			}
+			meta := metas[id]
+			meta.Note = note
+			metas[id] = meta
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("read notes: %w", err)
+	}
+	for id, meta := range metas {
+		sort.Strings(meta.Tags)
+		metas[id] = meta
+	}
+	return metas, nil
+}
+
+func (m *Mirror) assertPeriodExists(periodID uuid.UUID) error {
+	var count int
+	if err := m.db.QueryRow(`SELECT COUNT(*) FROM periods WHERE id = ?`,
+		periodID.String()).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: %s, run \"mirror sync\" first", ErrPeriodNotFound, periodID)
+	}
+	return nil
+}
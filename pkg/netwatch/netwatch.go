@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package netwatch detects network changes, such as interfaces going up or
+// down when a laptop sleeps, switches Wi-Fi, or connects to a VPN, by
+// polling the network interfaces.
+package netwatch
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// State is a snapshot of the network interfaces.
+type State struct {
+	// Online is true when any interface other than loopback is up and has
+	// a routable address.
+	Online bool
+	// Fingerprint changes whenever an interface goes up or down, or its
+	// addresses change.
+	Fingerprint string
+}
+
+// Interface is the subset of [net.Interface] used to compute the [State].
+type Interface struct {
+	Name  string
+	Flags net.Flags
+	Addrs []net.Addr
+}
+
+// Snapshot returns the current state of the network interfaces.
+func Snapshot() (State, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return State{}, err
+	}
+	var snapshot []Interface
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return State{}, err
+		}
+		snapshot = append(snapshot, Interface{Name: iface.Name, Flags: iface.Flags, Addrs: addrs})
+	}
+	return StateOf(snapshot), nil
+}
+
+// StateOf computes the state of the interfaces.
+func StateOf(ifaces []Interface) State {
+	var state State
+	var parts []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		var addrs []string
+		for _, addr := range iface.Addrs {
+			addrs = append(addrs, addr.String())
+			if routable(addr) {
+				state.Online = true
+			}
+		}
+		sort.Strings(addrs)
+		parts = append(parts, iface.Name+"="+strings.Join(addrs, ","))
+	}
+	sort.Strings(parts)
+	state.Fingerprint = strings.Join(parts, ";")
+	return state
+}
+
+func routable(addr net.Addr) bool {
+	var ip net.IP
+	switch addr := addr.(type) {
+	case *net.IPNet:
+		ip = addr.IP
+	case *net.IPAddr:
+		ip = addr.IP
+	default:
+		return false
+	}
+	return ip.IsGlobalUnicast()
+}
+
+// Watch polls the network interfaces each interval until done is closed,
+// and calls f with the new state whenever it changes. Failed snapshots are
+// skipped, and f is not called for the initial state.
+func Watch(interval time.Duration, done <-chan struct{}, f func(State)) {
+	watch(interval, done, Snapshot, f)
+}
+
+func watch(interval time.Duration, done <-chan struct{}, snapshot func() (State, error), f func(State)) {
+	last, _ := snapshot()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		state, err := snapshot()
+		if err != nil || state == last {
+			continue
+		}
+		last = state
+		f(state)
+	}
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package netwatch
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStateOf(t *testing.T) {
+	loopback := Interface{
+		Name:  "lo",
+		Flags: net.FlagUp | net.FlagLoopback,
+		Addrs: []net.Addr{&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)}},
+	}
+	wifi := Interface{
+		Name:  "wlan0",
+		Flags: net.FlagUp,
+		Addrs: []net.Addr{&net.IPNet{IP: net.ParseIP("192.168.1.12"), Mask: net.CIDRMask(24, 32)}},
+	}
+	linkLocal := Interface{
+		Name:  "eth0",
+		Flags: net.FlagUp,
+		Addrs: []net.Addr{&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)}},
+	}
+	wifiDown := wifi
+	wifiDown.Flags = 0
+
+	tests := []struct {
+		name   string
+		ifaces []Interface
+		online bool
+	}{
+		{name: "only loopback", ifaces: []Interface{loopback}, online: false},
+		{name: "wifi up", ifaces: []Interface{loopback, wifi}, online: true},
+		{name: "wifi down", ifaces: []Interface{loopback, wifiDown}, online: false},
+		{name: "only link-local", ifaces: []Interface{loopback, linkLocal}, online: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := StateOf(tc.ifaces).Online; got != tc.online {
+				t.Errorf("want online=%t, got %t", tc.online, got)
+			}
+		})
+	}
+
+	if StateOf([]Interface{wifi, linkLocal}) != StateOf([]Interface{linkLocal, wifi}) {
+		t.Error("want same state regardless of interface order")
+	}
+	if StateOf([]Interface{wifi}) == StateOf([]Interface{wifiDown}) {
+		t.Error("want different fingerprint when interface goes down")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	states := make(chan State, 3)
+	states <- State{Online: true, Fingerprint: "a"}
+	states <- State{Online: true, Fingerprint: "a"}
+	states <- State{Online: false, Fingerprint: "b"}
+	snapshot := func() (State, error) {
+		select {
+		case s := <-states:
+			return s, nil
+		default:
+			return State{Online: false, Fingerprint: "b"}, nil
+		}
+	}
+
+	done := make(chan struct{})
+	changes := make(chan State, 10)
+	go watch(time.Millisecond, done, snapshot, func(s State) { changes <- s })
+	got := <-changes
+	close(done)
+	if got.Online || got.Fingerprint != "b" {
+		t.Errorf("want offline change, got %+v", got)
+	}
+	select {
+	case s := <-changes:
+		t.Errorf("want single change, got another: %+v", s)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
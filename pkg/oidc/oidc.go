@@ -0,0 +1,347 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package oidc verifies OpenID Connect bearer tokens, as issued by identity
+// providers such as Keycloak, for protecting the local servers when they
+// are exposed beyond localhost.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrInvalidToken is returned when a token is malformed, expired, not
+// issued for this server, or has an invalid signature.
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrForbidden is returned when a valid token's user isn't in any of the
+// allowed groups.
+var ErrForbidden = errors.New("user is not in any of the allowed groups")
+
+const (
+	// leeway is the allowed clock skew between us and the issuer.
+	leeway = time.Minute
+	// minRefresh is the least time between fetching the issuer's keys,
+	// so tokens with unknown key IDs can't make us flood the issuer.
+	minRefresh = time.Minute
+)
+
+// Verifier verifies tokens signed by an OpenID Connect issuer, whose
+// signing keys are discovered via the issuer's
+// "/.well-known/openid-configuration" document.
+type Verifier struct {
+	// Issuer is the URL of the issuer, which must equal the tokens' "iss"
+	// claim, e.g "https://keycloak.example.com/realms/company".
+	Issuer string
+	// Audience is the required "aud" claim of tokens, usually the client
+	// ID. All tokens are rejected when empty, as tokens issued to any other
+	// client of the issuer would otherwise be accepted.
+	Audience string
+	// UsernameClaim is the claim that identifies the user. Defaults to
+	// "email", in which case the "email_verified" claim must also be true.
+	UsernameClaim string
+	// GroupsClaim is the claim that lists the user's groups. Nested claims
+	// are separated by dots, e.g "realm_access.roles". Defaults to "groups".
+	GroupsClaim string
+	// AllowedGroups rejects tokens of users that are in none of the
+	// groups, when set.
+	AllowedGroups []string
+	// Client is used to fetch the issuer's keys. Defaults to a client with
+	// a 10 second timeout.
+	Client *http.Client
+
+	now func() time.Time
+
+	fetches   singleflight.Group
+	mu        sync.Mutex
+	jwksURI   string
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// Identity is the user of a verified token.
+type Identity struct {
+	Username string
+	Groups   []string
+	Claims   Claims
+}
+
+// InAnyGroup returns true if the user is in any of the groups.
+func (id *Identity) InAnyGroup(groups []string) bool {
+	for _, g := range groups {
+		for _, have := range id.Groups {
+			if g == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Verify verifies the token's signature and claims, and returns its user.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	id := &Identity{
+		Username: claims.String(orDefault(v.UsernameClaim, "email")),
+		Groups:   claims.Strings(orDefault(v.GroupsClaim, "groups")),
+		Claims:   claims,
+	}
+	if id.Username == "" {
+		return nil, fmt.Errorf("%w: missing %q claim", ErrInvalidToken, orDefault(v.UsernameClaim, "email"))
+	}
+	// Anyone can set an unverified email at most issuers, so it must not
+	// be trusted as the user's identity
+	if orDefault(v.UsernameClaim, "email") == "email" && !claims.bool("email_verified") {
+		return nil, fmt.Errorf("%w: email %q is not verified", ErrInvalidToken, id.Username)
+	}
+	if len(v.AllowedGroups) > 0 && !id.InAnyGroup(v.AllowedGroups) {
+		return nil, ErrForbidden
+	}
+	return id, nil
+}
+
+func (v *Verifier) validate(claims Claims) error {
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+	if iss := claims.String("iss"); iss != v.Issuer {
+		return fmt.Errorf("issued by %q, want %q", iss, v.Issuer)
+	}
+	if v.Audience == "" {
+		return errors.New("no audience configured")
+	}
+	if !claims.has("aud", v.Audience) {
+		return fmt.Errorf("not issued for audience %q", v.Audience)
+	}
+	exp, ok := claims.time("exp")
+	if !ok {
+		return errors.New("missing expiry")
+	}
+	if now.After(exp.Add(leeway)) {
+		return errors.New("token has expired")
+	}
+	if nbf, ok := claims.time("nbf"); ok && now.Add(leeway).Before(nbf) {
+		return errors.New("token is not valid yet")
+	}
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	var h crypto.Hash
+	switch {
+	case strings.HasSuffix(alg, "256"):
+		h = crypto.SHA256
+	case strings.HasSuffix(alg, "384"):
+		h = crypto.SHA384
+	case strings.HasSuffix(alg, "512"):
+		h = crypto.SHA512
+	}
+	switch {
+	case strings.HasPrefix(alg, "RS") && h != 0:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key is not an RSA key, but alg is %s", alg)
+		}
+		return rsa.VerifyPKCS1v15(pub, h, digest(h, signed), sig)
+	case strings.HasPrefix(alg, "PS") && h != 0:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key is not an RSA key, but alg is %s", alg)
+		}
+		return rsa.VerifyPSS(pub, h, digest(h, signed), sig, nil)
+	case strings.HasPrefix(alg, "ES") && h != 0:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key is not an EC key, but alg is %s", alg)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest(h, signed), r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case alg == "EdDSA":
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("key is not an Ed25519 key, but alg is %s", alg)
+		}
+		if !ed25519.Verify(pub, signed, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported alg %q", alg)
+	}
+}
+
+func digest(h crypto.Hash, data []byte) []byte {
+	var d hash.Hash
+	switch h {
+	case crypto.SHA384:
+		d = sha512.New384()
+	case crypto.SHA512:
+		d = sha512.New()
+	default:
+		d = sha256.New()
+	}
+	d.Write(data)
+	return d.Sum(nil)
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// Claims are the decoded claims of a token.
+type Claims map[string]any
+
+// String returns the string claim, or an empty string. Nested claims are
+// separated by dots, e.g "realm_access.roles".
+func (c Claims) String(name string) string {
+	s, _ := c.lookup(name).(string)
+	return s
+}
+
+// Strings returns the claim as a list of strings, which may either be a
+// JSON array of strings or a single string.
+func (c Claims) Strings(name string) []string {
+	switch v := c.lookup(name).(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+func (c Claims) has(name, want string) bool {
+	for _, s := range c.Strings(name) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// bool returns true if the claim is true. Some issuers send booleans as
+// strings, so "true" is accepted too.
+func (c Claims) bool(name string) bool {
+	switch v := c.lookup(name).(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
+
+func (c Claims) time(name string) (time.Time, bool) {
+	f, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// lookup returns the claim, trying the full name first, as claim names
+// may themselves contain dots, such as "https://example.com/groups".
+func (c Claims) lookup(name string) any {
+	if v, ok := c[name]; ok {
+		return v
+	}
+	var v any = map[string]any(c)
+	for _, key := range strings.Split(name, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
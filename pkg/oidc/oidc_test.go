@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": issuer + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	issuer = srv.URL
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	v := &Verifier{
+		Issuer:        issuer,
+		Audience:      "personio",
+		GroupsClaim:   "realm_access.roles",
+		AllowedGroups: []string{"staff"},
+		now:           func() time.Time { return now },
+	}
+	sign := func(kid string, claims map[string]any) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+		payload, _ := json.Marshal(claims)
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		sum := sha256.Sum256([]byte(signed))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	claims := func(override map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":            issuer,
+			"aud":            []string{"personio", "other"},
+			"exp":            now.Add(time.Hour).Unix(),
+			"email":          "alice@example.com",
+			"email_verified": true,
+			"realm_access":   map[string]any{"roles": []string{"staff", "managers"}},
+		}
+		for k, v := range override {
+			c[k] = v
+		}
+		return c
+	}
+
+	id, err := v.Verify(context.Background(), sign("k1", claims(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Username != "alice@example.com" {
+		t.Errorf("want username alice@example.com, got %q", id.Username)
+	}
+	if !id.InAnyGroup([]string{"managers"}) {
+		t.Errorf("want user in managers group, got %v", id.Groups)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "malformed", token: "not-a-jwt", wantErr: ErrInvalidToken},
+		{name: "expired", token: sign("k1", claims(map[string]any{"exp": now.Add(-time.Hour).Unix()})), wantErr: ErrInvalidToken},
+		{name: "wrong audience", token: sign("k1", claims(map[string]any{"aud": "other"})), wantErr: ErrInvalidToken},
+		{name: "wrong issuer", token: sign("k1", claims(map[string]any{"iss": "https://evil.example.com"})), wantErr: ErrInvalidToken},
+		{name: "unknown key", token: sign("k2", claims(nil)), wantErr: ErrInvalidToken},
+		{name: "tampered", token: tamper(sign("k1", claims(nil)), sign("k1", claims(map[string]any{"email": "mallory@example.com"}))), wantErr: ErrInvalidToken},
+		{name: "unverified email", token: sign("k1", claims(map[string]any{"email_verified": false})), wantErr: ErrInvalidToken},
+		{name: "missing email_verified", token: sign("k1", claims(map[string]any{"email_verified": nil})), wantErr: ErrInvalidToken},
+		{name: "not in group", token: sign("k1", claims(map[string]any{"realm_access": map[string]any{"roles": []string{"guests"}}})), wantErr: ErrForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := v.Verify(context.Background(), tc.token)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("want %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("no audience configured", func(t *testing.T) {
+		noAud := &Verifier{Issuer: issuer, now: v.now}
+		_, err := noAud.Verify(context.Background(), sign("k1", claims(nil)))
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("want %v, got %v", ErrInvalidToken, err)
+		}
+	})
+
+	t.Run("other username claim needs no verified email", func(t *testing.T) {
+		bySub := &Verifier{Issuer: issuer, Audience: "personio", UsernameClaim: "sub", now: v.now}
+		id, err := bySub.Verify(context.Background(), sign("k1", claims(map[string]any{"sub": "alice", "email_verified": nil})))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id.Username != "alice" {
+			t.Errorf("want username alice, got %q", id.Username)
+		}
+	})
+}
+
+func TestVerifierFetchesKeysWithoutLock(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issuer string
+	fetching := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var fetches int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": issuer + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if fetches > 1 {
+			fetching <- struct{}{}
+			<-release
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	defer close(release)
+	issuer = srv.URL
+
+	v := &Verifier{Issuer: issuer, Audience: "personio"}
+	if _, err := v.key(context.Background(), "k1"); err != nil {
+		t.Fatal(err)
+	}
+	v.mu.Lock()
+	v.fetchedAt = time.Now().Add(-2 * minRefresh)
+	v.mu.Unlock()
+
+	// An unknown key ID refetches the keys, which hangs until released
+	ctx, cancel := context.WithCancel(context.Background())
+	unknownErr := make(chan error, 1)
+	go func() {
+		_, err := v.key(ctx, "k2")
+		unknownErr <- err
+	}()
+	<-fetching
+
+	known := make(chan error, 1)
+	go func() {
+		_, err := v.key(context.Background(), "k1")
+		known <- err
+	}()
+	select {
+	case err := <-known:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("known key was blocked by the ongoing fetch")
+	}
+
+	cancel()
+	select {
+	case err := <-unknownErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled caller kept waiting for the fetch")
+	}
+}
+
+// tamper returns the token with the claims of another token.
+func tamper(token, other string) string {
+	parts := strings.Split(token, ".")
+	parts[1] = strings.Split(other, ".")[1]
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package pacer spreads a known number of requests over time, adapting to
+// the rate limit reported by the server, so big backfills neither trip
+// Personio's rate limiting nor run for longer than wanted.
+package pacer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// ErrMaxDurationReached is returned when the next request would start after
+// the pacer's deadline.
+var ErrMaxDurationReached = errors.New("max duration reached")
+
+// Pacer computes the delay before each of a fixed number of requests.
+type Pacer struct {
+	// Total is the number of requests to pace.
+	Total int
+	// Deadline is when the last request must have started, or zero for no
+	// deadline. With a deadline, the requests are spread evenly up to it.
+	Deadline time.Time
+
+	sent int
+}
+
+// New returns a pacer for the number of requests, where a non-zero
+// maxDuration spreads the requests across that window starting now.
+func New(total int, maxDuration time.Duration, now time.Time) *Pacer {
+	p := &Pacer{Total: total}
+	if maxDuration > 0 {
+		p.Deadline = now.Add(maxDuration)
+	}
+	return p
+}
+
+// Sent returns how many requests have been let through.
+func (p *Pacer) Sent() int {
+	return p.sent
+}
+
+// Delay returns how long to wait before the next request. The delay is the
+// longer of spreading the remaining requests evenly until the deadline,
+// and spreading the remaining rate limit quota until the limit is reset.
+// Returns [ErrMaxDurationReached] if the request would pass the deadline.
+func (p *Pacer) Delay(now time.Time, rl personio.RateLimit, hasRateLimit bool) (time.Duration, error) {
+	var delay time.Duration
+	if p.sent > 0 && !p.Deadline.IsZero() {
+		if remaining := p.Total - p.sent; remaining > 0 {
+			delay = p.Deadline.Sub(now) / time.Duration(remaining)
+		}
+	}
+	if hasRateLimit && rl.Active(now) {
+		untilReset := rl.Reset.Sub(now)
+		rateDelay := untilReset
+		if rl.Remaining > 0 {
+			rateDelay = untilReset / time.Duration(rl.Remaining)
+		}
+		if rateDelay > delay {
+			delay = rateDelay
+		}
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if !p.Deadline.IsZero() && now.Add(delay).After(p.Deadline) {
+		return 0, fmt.Errorf("%w: %d of %d requests sent", ErrMaxDurationReached, p.sent, p.Total)
+	}
+	return delay, nil
+}
+
+// Wait sleeps for the delay before the next request, and then counts the
+// request as sent.
+func (p *Pacer) Wait(ctx context.Context, rl personio.RateLimit, hasRateLimit bool) error {
+	delay, err := p.Delay(time.Now(), rl, hasRateLimit)
+	if err != nil {
+		return err
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	p.sent++
+	return nil
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pacer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestDelay(t *testing.T) {
+	now := time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		pacer   Pacer
+		rl      personio.RateLimit
+		hasRL   bool
+		want    time.Duration
+		wantErr error
+	}{
+		{
+			name:  "first request without limits",
+			pacer: Pacer{Total: 10},
+			want:  0,
+		},
+		{
+			name:  "spread until deadline",
+			pacer: Pacer{Total: 10, sent: 5, Deadline: now.Add(10 * time.Minute)},
+			want:  2 * time.Minute,
+		},
+		{
+			name:  "spread rate limit quota",
+			pacer: Pacer{Total: 100, sent: 1},
+			rl:    personio.RateLimit{Remaining: 10, Reset: now.Add(time.Minute)},
+			hasRL: true,
+			want:  6 * time.Second,
+		},
+		{
+			name:  "wait for reset when quota is used up",
+			pacer: Pacer{Total: 100, sent: 1},
+			rl:    personio.RateLimit{Remaining: 0, Reset: now.Add(30 * time.Second)},
+			hasRL: true,
+			want:  30 * time.Second,
+		},
+		{
+			name:  "ignore expired rate limit",
+			pacer: Pacer{Total: 100, sent: 1},
+			rl:    personio.RateLimit{Remaining: 0, Reset: now.Add(-time.Second)},
+			hasRL: true,
+			want:  0,
+		},
+		{
+			name:    "rate limit passes deadline",
+			pacer:   Pacer{Total: 100, sent: 99, Deadline: now.Add(time.Second)},
+			rl:      personio.RateLimit{Remaining: 0, Reset: now.Add(time.Minute)},
+			hasRL:   true,
+			wantErr: ErrMaxDurationReached,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.pacer.Delay(now, tc.rl, tc.hasRL)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("want %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
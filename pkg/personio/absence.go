@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// AbsenceRequest is a request for an absence, such as vacation.
+type AbsenceRequest struct {
+	// TimeOffTypeID is the ID of the absence type in Personio.
+	TimeOffTypeID int
+	StartDate     time.Time
+	EndDate       time.Time
+	HalfDayStart  bool
+	HalfDayEnd    bool
+	Comment       string
+}
+
+// RequestAbsence requests an absence for the logged in employee, or for the
+// employee set via [Client.ActOnBehalfOf].
+//
+// Experimental: the endpoint is newly reverse-engineered and has not been
+// verified against many tenants. Its path can be changed via
+// [Client.SetEndpoints] if it turns out to be wrong.
+func (c *Client) RequestAbsence(absence AbsenceRequest) error {
+	if err := c.assertLoggedIn(); err != nil {
+		return err
+	}
+	if absence.EndDate.Before(absence.StartDate) {
+		return fmt.Errorf("end date %s is before start date %s",
+			absence.EndDate.Format(time.DateOnly), absence.StartDate.Format(time.DateOnly))
+	}
+	body, err := json.Marshal(map[string]any{
+		"employee_id":      c.TargetEmployeeID(),
+		"time_off_type_id": absence.TimeOffTypeID,
+		"start_date":       absence.StartDate.Format(time.DateOnly),
+		"end_date":         absence.EndDate.Format(time.DateOnly),
+		"half_day_start":   absence.HalfDayStart,
+		"half_day_end":     absence.HalfDayEnd,
+		"comment":          absence.Comment,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.endpoints.AbsencePeriods, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := c.RawJSON(req)
+	if err != nil {
+		return err
+	}
+	_, err = ParseResponseJSON[any](resp)
+	return err
+}
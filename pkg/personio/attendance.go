@@ -24,11 +24,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/applejag/rootless-personio/pkg/util"
-	"github.com/rs/zerolog/log"
 )
 
 type AttendanceCalendar struct {
@@ -37,9 +37,211 @@ type AttendanceCalendar struct {
 	AttendanceDays           Data[[]CalendarDay]              `json:"attendance_days"`
 	AttendancePeriods        Data[[]CalendarAttendancePeriod] `json:"attendance_periods"`
 	OvertimeItems            struct{}                         `json:"overtime_items"`
-	AttendanceAlerts         struct{}                         `json:"attendance_alerts"`
-	AbsencePeriods           Data[[]CalendarAbsencePeriod]    `json:"absence_periods"`
-	Holidays                 Data[[]CalendarHoliday]          `json:"holidays"`
+	AttendanceAlerts         json.RawMessage                  `json:"attendance_alerts,omitempty"`
+
+	// AbsencePeriods and Holidays are nil when omitted by Personio, which
+	// some tenants do. Use [AttendanceCalendar.GetAbsencePeriods] and
+	// [AttendanceCalendar.GetHolidays] to read them safely.
+	AbsencePeriods *Data[[]CalendarAbsencePeriod] `json:"absence_periods,omitempty"`
+	Holidays       *Data[[]CalendarHoliday]       `json:"holidays,omitempty"`
+}
+
+// CalendarSection is an optional section of the attendance calendar.
+type CalendarSection string
+
+const (
+	SectionAbsencePeriods CalendarSection = "absence_periods"
+	SectionHolidays       CalendarSection = "holidays"
+)
+
+// HasSection returns true if the optional section was part of the calendar.
+func (cal *AttendanceCalendar) HasSection(section CalendarSection) bool {
+	if cal == nil {
+		return false
+	}
+	switch section {
+	case SectionAbsencePeriods:
+		return cal.AbsencePeriods != nil
+	case SectionHolidays:
+		return cal.Holidays != nil
+	default:
+		return false
+	}
+}
+
+// MissingSections returns the optional sections that were not part of the
+// calendar, so features depending on them will be incomplete.
+func (cal *AttendanceCalendar) MissingSections() []CalendarSection {
+	var missing []CalendarSection
+	for _, section := range []CalendarSection{SectionAbsencePeriods, SectionHolidays} {
+		if !cal.HasSection(section) {
+			missing = append(missing, section)
+		}
+	}
+	return missing
+}
+
+// GetAbsencePeriods returns the absences, or nil if the calendar has none
+// or if the section was omitted.
+func (cal *AttendanceCalendar) GetAbsencePeriods() []CalendarAbsencePeriod {
+	if cal == nil || cal.AbsencePeriods == nil {
+		return nil
+	}
+	return cal.AbsencePeriods.Data
+}
+
+// GetHolidays returns the public holidays, or nil if the calendar has none
+// or if the section was omitted.
+func (cal *AttendanceCalendar) GetHolidays() []CalendarHoliday {
+	if cal == nil || cal.Holidays == nil {
+		return nil
+	}
+	return cal.Holidays.Data
+}
+
+// PeriodsByDay returns the attendance periods grouped by their day, e.g
+// "2023-01-18".
+func (cal *AttendanceCalendar) PeriodsByDay() (map[string][]Period, error) {
+	days := make(map[uuid.UUID]string, len(cal.AttendanceDays.Data))
+	for _, day := range cal.AttendanceDays.Data {
+		days[day.ID] = day.Attributes.Day.String()
+	}
+	byDay := make(map[string][]Period)
+	for _, p := range cal.AttendancePeriods.Data {
+		day, ok := days[p.Attributes.AttendanceDayID]
+		if !ok {
+			continue
+		}
+		period, err := p.Period()
+		if err != nil {
+			return nil, err
+		}
+		byDay[day] = append(byDay[day], period)
+	}
+	return byDay, nil
+}
+
+// Merge adds the days, periods, absences, holidays, and alerts of another
+// calendar, such as of the next month. Absences that span both calendars
+// are only kept once. The optional sections are only kept if both
+// calendars have them, as they are otherwise incomplete.
+func (cal *AttendanceCalendar) Merge(other *AttendanceCalendar) {
+	if other == nil {
+		return
+	}
+	cal.AttendanceDays.Data = append(cal.AttendanceDays.Data, other.AttendanceDays.Data...)
+	cal.AttendancePeriods.Data = append(cal.AttendancePeriods.Data, other.AttendancePeriods.Data...)
+	if cal.AttendanceRights == nil {
+		cal.AttendanceRights = other.AttendanceRights
+	}
+	if cal.AbsencePeriods != nil && other.AbsencePeriods != nil {
+		seen := make(map[string]bool, len(cal.AbsencePeriods.Data))
+		for _, a := range cal.AbsencePeriods.Data {
+			seen[a.ID] = true
+		}
+		for _, a := range other.AbsencePeriods.Data {
+			if !seen[a.ID] {
+				seen[a.ID] = true
+				cal.AbsencePeriods.Data = append(cal.AbsencePeriods.Data, a)
+			}
+		}
+	} else {
+		cal.AbsencePeriods = nil
+	}
+	if cal.Holidays != nil && other.Holidays != nil {
+		cal.Holidays.Data = append(cal.Holidays.Data, other.Holidays.Data...)
+	} else {
+		cal.Holidays = nil
+	}
+	if alerts := append(cal.alerts(), other.alerts()...); len(alerts) > 0 {
+		if b, err := json.Marshal(alerts); err == nil {
+			cal.AttendanceAlerts = b
+		}
+	}
+}
+
+// alerts returns the attendance alerts, which are either wrapped in a
+// "data" field or not.
+func (cal *AttendanceCalendar) alerts() []json.RawMessage {
+	if cal == nil || len(cal.AttendanceAlerts) == 0 {
+		return nil
+	}
+	var data Data[[]json.RawMessage]
+	if err := json.Unmarshal(cal.AttendanceAlerts, &data); err == nil && data.Data != nil {
+		return data.Data
+	}
+	var alerts []json.RawMessage
+	if err := json.Unmarshal(cal.AttendanceAlerts, &alerts); err != nil {
+		return nil
+	}
+	return alerts
+}
+
+// AlertsOn returns the attendance alerts that mention the given date
+// (ex: "2023-01-20"). The alerts' format is undocumented, so they are
+// returned as-is, and are matched on whether they contain the date at all.
+func (cal *AttendanceCalendar) AlertsOn(day string) []json.RawMessage {
+	var matching []json.RawMessage
+	for _, alert := range cal.alerts() {
+		if bytes.Contains(alert, []byte(day)) {
+			matching = append(matching, alert)
+		}
+	}
+	return matching
+}
+
+// DayStatusEmpty is the status of days without any attendance tracked.
+const DayStatusEmpty = "empty"
+
+// WorkDays returns the days that have attendance tracked, i.e whose status
+// is not "empty".
+func (cal *AttendanceCalendar) WorkDays() []CalendarDay {
+	return cal.filterDays(func(day CalendarDay) bool {
+		return day.Attributes.Status != DayStatusEmpty
+	})
+}
+
+// EmptyDays returns the days without any attendance tracked.
+func (cal *AttendanceCalendar) EmptyDays() []CalendarDay {
+	return cal.filterDays(func(day CalendarDay) bool {
+		return day.Attributes.Status == DayStatusEmpty
+	})
+}
+
+// DaysWithAlerts returns the days mentioned by any attendance alert.
+// See [AttendanceCalendar.AlertsOn].
+func (cal *AttendanceCalendar) DaysWithAlerts() []CalendarDay {
+	if len(cal.alerts()) == 0 {
+		return nil
+	}
+	return cal.filterDays(func(day CalendarDay) bool {
+		return len(cal.AlertsOn(day.Attributes.Day.String())) > 0
+	})
+}
+
+// TotalWorkedMinutes returns the sum of the attendance duration of all days.
+func (cal *AttendanceCalendar) TotalWorkedMinutes() Minutes {
+	if cal == nil {
+		return 0
+	}
+	var total Minutes
+	for _, day := range cal.AttendanceDays.Data {
+		total += day.Attributes.DurationMin
+	}
+	return total
+}
+
+func (cal *AttendanceCalendar) filterDays(keep func(CalendarDay) bool) []CalendarDay {
+	if cal == nil {
+		return nil
+	}
+	var days []CalendarDay
+	for _, day := range cal.AttendanceDays.Data {
+		if keep(day) {
+			days = append(days, day)
+		}
+	}
+	return days
 }
 
 type CalendarDay struct {
@@ -48,10 +250,10 @@ type CalendarDay struct {
 }
 
 type CalendarDayAttributes struct {
-	BreakMin    int    `json:"break_min"`    // Duration of breaks in minutes
-	DurationMin int    `json:"duration_min"` // Duration of attendance in minutes
-	Status      string `json:"status"`       // ex: "empty"
-	Day         string `json:"day"`          // ex: "2023-01-20"
+	BreakMin    Minutes `json:"break_min"`    // Duration of breaks in minutes
+	DurationMin Minutes `json:"duration_min"` // Duration of attendance in minutes
+	Status      string  `json:"status"`       // ex: "empty"
+	Day         Date    `json:"day"`          // ex: "2023-01-20"
 }
 
 type CalendarAttendancePeriod struct {
@@ -60,27 +262,85 @@ type CalendarAttendancePeriod struct {
 }
 
 type CalendarAttendancePeriodAttributes struct {
-	AttendanceDayID uuid.UUID `json:"attendance_day_id"` // ex: "81954d73-0b0d-4053-a5dc-937bdd62f9f7"
-	Comment         *string   `json:"comment"`           // ex: ""
-	End             string    `json:"end"`               // ex: "2023-01-18T17:00:00Z"
-	LegacyBreakMin  int       `json:"legacy_break_min"`  // ex: 0
-	PeriodType      string    `json:"period_type"`       // ex: "work"
-	ProjectID       *int      `json:"project_id"`
-	Start           string    `json:"start"` // ex: "2023-01-18T13:00:00Z"
+	AttendanceDayID uuid.UUID    `json:"attendance_day_id"` // ex: "81954d73-0b0d-4053-a5dc-937bdd62f9f7"
+	Comment         *string      `json:"comment"`           // ex: ""
+	End             FlexibleTime `json:"end"`               // ex: "2023-01-18T17:00:00Z"
+	LegacyBreakMin  int          `json:"legacy_break_min"`  // ex: 0
+	PeriodType      string       `json:"period_type"`       // ex: "work"
+	ProjectID       *int         `json:"project_id"`
+	Start           FlexibleTime `json:"start"` // ex: "2023-01-18T13:00:00Z"
+	// Origin is how the period was created, or empty if Personio didn't say
+	Origin PeriodOrigin `json:"origin,omitempty"` // ex: "web"
+	// UpdatedAt and UpdatedBy are when and by whom the period was last
+	// changed, if Personio says. See [CalendarAttendancePeriod.Updated].
+	UpdatedAt *FlexibleTime   `json:"updated_at,omitempty"` // ex: "2023-01-19T09:12:00Z"
+	UpdatedBy json.RawMessage `json:"updated_by,omitempty"` // ex: 123456
+}
+
+// PeriodOrigin is how an attendance period was created in Personio, as
+// reported by Personio.
+type PeriodOrigin string
+
+const (
+	// PeriodOriginWeb is for periods entered in Personio's web interface,
+	// by the employee or by HR.
+	PeriodOriginWeb PeriodOrigin = "web"
+	// PeriodOriginAPI is for periods created via Personio's API.
+	PeriodOriginAPI PeriodOrigin = "api"
+	// PeriodOriginImport is for periods imported by Personio, such as from
+	// a file uploaded by HR.
+	PeriodOriginImport PeriodOrigin = "import"
+)
+
+// Period converts the calendar's attendance period to the format used when
+// setting attendance.
+func (p CalendarAttendancePeriod) Period() (Period, error) {
+	if p.Attributes.Start.IsZero() {
+		return Period{}, fmt.Errorf("period %s has no start", p.ID)
+	}
+	if p.Attributes.End.IsZero() {
+		return Period{}, fmt.Errorf("period %s has no end", p.ID)
+	}
+	return Period{
+		ID:             p.ID,
+		PeriodType:     PeriodType(p.Attributes.PeriodType),
+		Comment:        p.Attributes.Comment,
+		ProjectID:      p.Attributes.ProjectID,
+		Start:          p.Attributes.Start.Time,
+		End:            p.Attributes.End.Time,
+		LegacyBreakMin: p.Attributes.LegacyBreakMin,
+	}, nil
+}
+
+// GetComment returns the period's comment, or an empty string if it has
+// none.
+func (p CalendarAttendancePeriod) GetComment() string {
+	if p.Attributes.Comment == nil {
+		return ""
+	}
+	return *p.Attributes.Comment
+}
+
+// GetProjectID returns the period's project ID, or zero if it has none.
+func (p CalendarAttendancePeriod) GetProjectID() int {
+	if p.Attributes.ProjectID == nil {
+		return 0
+	}
+	return *p.Attributes.ProjectID
 }
 
 type CalendarAbsencePeriod struct {
-	ID                         string `json:"id"`   // ex: "123456789"
-	Name                       string `json:"name"` // ex: "Paid vacation"
-	TracksOvertime             bool   `json:"tracks_overtime"`
-	MeasurementUnit            string `json:"measurement_unit"` // ex: "day"
-	StartDate                  string `json:"start_date"`       // ex: "2022-12-22"
-	StartTime                  string `json:"start_time"`       // ex: "2022-12-22 00:00:00"
-	EndDate                    string `json:"end_date"`         // ex: "2022-12-28"
-	EndTime                    string `json:"end_time"`         // ex: "2022-12-29 00:00:00"
-	EffectiveDurationInMinutes *int   `json:"effective_duration_in_minutes"`
-	HalfDayStart               bool   `json:"half_day_start"`
-	HalfDayEnd                 bool   `json:"half_day_end"`
+	ID                         string       `json:"id"`   // ex: "123456789"
+	Name                       string       `json:"name"` // ex: "Paid vacation"
+	TracksOvertime             bool         `json:"tracks_overtime"`
+	MeasurementUnit            string       `json:"measurement_unit"` // ex: "day"
+	StartDate                  Date         `json:"start_date"`       // ex: "2022-12-22"
+	StartTime                  FlexibleTime `json:"start_time"`       // ex: "2022-12-22 00:00:00"
+	EndDate                    Date         `json:"end_date"`         // ex: "2022-12-28"
+	EndTime                    FlexibleTime `json:"end_time"`         // ex: "2022-12-29 00:00:00"
+	EffectiveDurationInMinutes *Minutes     `json:"effective_duration_in_minutes"`
+	HalfDayStart               bool         `json:"half_day_start"`
+	HalfDayEnd                 bool         `json:"half_day_end"`
 }
 
 type CalendarHoliday struct {
@@ -88,15 +348,13 @@ type CalendarHoliday struct {
 	HolidayCalendarName string `json:"holiday_calendar_name"` // ex: "DE (Hamburg) Feiertage CompanyName"
 	ID                  int    `json:"id"`                    // ex: 123456
 	Name                string `json:"name"`                  // ex: "2. Weihnachtstag"
-	Date                string `json:"date"`                  // ex: "2022-12-26"
-}
-
-type Data[M any] struct {
-	Data M `json:"data"`
+	Date                Date   `json:"date"`                  // ex: "2022-12-26"
 }
 
+// GetMyAttendanceCalendar returns the attendance calendar of the logged in
+// employee, or of the employee set via [Client.ActOnBehalfOf].
 func (c *Client) GetMyAttendanceCalendar(startDate, endDate time.Time) (*AttendanceCalendar, error) {
-	return c.GetAttendanceCalendar(c.EmployeeID, startDate, endDate)
+	return c.GetAttendanceCalendar(c.TargetEmployeeID(), startDate, endDate)
 }
 
 func (c *Client) GetAttendanceCalendar(employeeID int, startDate, endDate time.Time) (*AttendanceCalendar, error) {
@@ -108,19 +366,68 @@ func (c *Client) GetAttendanceCalendar(employeeID int, startDate, endDate time.T
 	queryParams.Set("start_date", startDate.Format(time.DateOnly))
 	queryParams.Set("end_date", endDate.Format(time.DateOnly))
 
-	req, err := http.NewRequest("GET", fmt.Sprintf(
-		"/svc/attendance-bff/attendance-calendar/%d?%s",
-		employeeID, queryParams.Encode()), nil)
+	req, err := http.NewRequest("GET", employeePath(c.endpoints.AttendanceCalendar, employeeID)+"?"+queryParams.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.RawJSON(req)
+	resp, err := c.RawJSONShared(req)
 	if err != nil {
 		return nil, err
 	}
 
-	return ParseResponseJSON[*AttendanceCalendar](resp)
+	cal, err := ParseResponseJSON[*AttendanceCalendar](resp)
+	c.noteCapability("AttendanceCalendar", nil, err)
+	if err != nil {
+		return nil, err
+	}
+	c.permissionsMu.Lock()
+	if c.permissions == nil {
+		c.permissions = make(map[int]*Permissions)
+	}
+	c.permissions[employeeID] = cal.Permissions(employeeID)
+	c.permissionsMu.Unlock()
+	if employeeID == c.TargetEmployeeID() {
+		c.cacheLockedMonths(cal.AttendanceDays.Data)
+	}
+	return cal, nil
+}
+
+// GetAttendanceCalendarRange fetches the attendance calendar one calendar
+// month at a time, and calls the function with each month's calendar and
+// first and last day. This keeps each request small, which matters for
+// ranges spanning multiple years, and lets the caller process the months as
+// they arrive instead of keeping them all in memory.
+func (c *Client) GetAttendanceCalendarRange(employeeID int, startDate, endDate time.Time, f func(cal *AttendanceCalendar, start, end time.Time) error) error {
+	return util.ForEachMonth(startDate, endDate, func(start, end time.Time) error {
+		cal, err := c.GetAttendanceCalendar(employeeID, start, end)
+		if err != nil {
+			return fmt.Errorf("get calendar %s to %s: %w", start.Format(time.DateOnly), end.Format(time.DateOnly), err)
+		}
+		return f(cal, start, end)
+	})
+}
+
+// GetAttendanceCalendarMerged fetches the attendance calendar one calendar
+// month at a time, like [Client.GetAttendanceCalendarRange], and merges
+// the months into a single calendar.
+func (c *Client) GetAttendanceCalendarMerged(employeeID int, startDate, endDate time.Time) (*AttendanceCalendar, error) {
+	var merged *AttendanceCalendar
+	err := c.GetAttendanceCalendarRange(employeeID, startDate, endDate, func(cal *AttendanceCalendar, _, _ time.Time) error {
+		if merged == nil {
+			merged = cal
+			return nil
+		}
+		merged.Merge(cal)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if merged == nil {
+		merged = &AttendanceCalendar{}
+	}
+	return merged, nil
 }
 
 type Period struct {
@@ -149,6 +456,36 @@ func (p Period) GetProjectID() int {
 	return *p.ProjectID
 }
 
+// ShiftDays returns a copy of the period moved the given number of days,
+// keeping the same local time of day even across daylight saving changes.
+// The copy gets a new ID, so it doesn't collide with the original period.
+func (p Period) ShiftDays(days int) Period {
+	p.ID = uuid.New()
+	p.Start = p.Start.Local().AddDate(0, 0, days)
+	p.End = p.End.Local().AddDate(0, 0, days)
+	return p
+}
+
+// ValidatePeriods returns an error if any period ends before it starts,
+// or if any periods overlap, which Personio would reject.
+func ValidatePeriods(periods []Period) error {
+	sorted := make([]Period, len(periods))
+	copy(sorted, periods)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	for i, p := range sorted {
+		if !p.End.After(p.Start) {
+			return fmt.Errorf("period %s: end %s is not after start %s", p.ID,
+				p.End.Local().Format(time.RFC3339), p.Start.Local().Format(time.RFC3339))
+		}
+		if i > 0 && p.Start.Before(sorted[i-1].End) {
+			return fmt.Errorf("period %s overlaps with period %s", p.ID, sorted[i-1].ID)
+		}
+	}
+	return nil
+}
+
 type PeriodType string
 
 const (
@@ -173,7 +510,7 @@ func (c *Client) SetAttendance(date time.Time, periods []Period) error {
 	}
 
 	body, err := json.Marshal(map[string]any{
-		"employee_id": c.EmployeeID,
+		"employee_id": c.TargetEmployeeID(),
 		"periods":     periods,
 	})
 	if err != nil {
@@ -185,20 +522,64 @@ func (c *Client) SetAttendance(date time.Time, periods []Period) error {
 	if err != nil {
 		return err
 	}
+	if err := c.checkCachedPermissions(c.TargetEmployeeID(), (*Permissions).CheckEdit); err != nil {
+		return err
+	}
+	if err := c.checkMonthLock(date); err != nil {
+		return err
+	}
 
-	req, err := http.NewRequest(http.MethodPut, "/api/v1/attendances/days/"+dayID.String(), bodyReader)
+	req, err := http.NewRequest(http.MethodPut, dayPath(c.endpoints.AttendanceDay, dayID.String()), bodyReader)
 	if err != nil {
 		return err
 	}
 
 	resp, err := c.RawJSON(req)
 	if err != nil {
-		return err
+		return c.wrapMonthLock(err, date)
 	}
 
 	// Currently don't care about the response
 	_, err = ParseResponseJSON[any](resp)
-	return err
+	return c.wrapMonthLock(err, date)
+}
+
+// GetAttendancePeriods returns the attendance periods that are already
+// stored for the given day.
+func (c *Client) GetAttendancePeriods(date time.Time) ([]Period, error) {
+	cal, err := c.GetMyAttendanceCalendar(date, date)
+	if err != nil {
+		return nil, err
+	}
+	c.cacheDayIDs(cal.AttendanceDays.Data, date, date)
+	dayIDs := make(map[uuid.UUID]bool)
+	for _, day := range cal.AttendanceDays.Data {
+		if day.Attributes.Day == DateOf(date) {
+			dayIDs[day.ID] = true
+		}
+	}
+	var periods []Period
+	for _, p := range cal.AttendancePeriods.Data {
+		if !dayIDs[p.Attributes.AttendanceDayID] {
+			continue
+		}
+		period, err := p.Period()
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, period)
+	}
+	return periods, nil
+}
+
+// AddAttendancePeriods adds periods to a day, while keeping the periods
+// that are already stored for that day.
+func (c *Client) AddAttendancePeriods(date time.Time, periods []Period) error {
+	existing, err := c.GetAttendancePeriods(date)
+	if err != nil {
+		return fmt.Errorf("get existing periods: %w", err)
+	}
+	return c.SetAttendance(date, append(existing, periods...))
 }
 
 func (c *Client) DeleteAttendance(date time.Time) error {
@@ -210,18 +591,26 @@ func (c *Client) DeleteAttendance(date time.Time) error {
 	if err != nil {
 		return err
 	}
+	if err := c.checkCachedPermissions(c.TargetEmployeeID(), (*Permissions).CheckDelete); err != nil {
+		return err
+	}
+	if err := c.checkMonthLock(date); err != nil {
+		return err
+	}
 
-	req, err := http.NewRequest(http.MethodDelete, "/api/v1/attendances/days/"+dayID.String()+"/periods", nil)
+	req, err := http.NewRequest(http.MethodDelete, dayPath(c.endpoints.AttendanceDayPeriods, dayID.String()), nil)
 	if err != nil {
 		return err
 	}
 
 	_, err = c.RawJSON(req)
-	return err
+	return c.wrapMonthLock(err, date)
 }
 
 // GetOrNewDayUUID will either lookup a day's ID (from cache or by querying
-// the API), or generate a new ID and store this new ID in cache.
+// the API), or generate a new ID and store this new ID in cache. The new ID
+// is only cached for this run, and not in the [DayIDStore], as Personio
+// hasn't seen it yet and the day may be created elsewhere with another ID.
 //
 // After the remote lookup to the API, the client caches which days in the same
 // month that has undefined IDs.
@@ -236,7 +625,7 @@ func (c *Client) GetOrNewDayUUID(date time.Time) (uuid.UUID, error) {
 	newID := uuid.New()
 	dateString := date.Format(time.DateOnly)
 	c.dayIDCache[dateString] = &newID
-	log.Debug().Str("day", dateString).Stringer("uuid", newID).
+	c.log().Debug().Str("day", dateString).Stringer("uuid", newID).
 		Msg("Randomized new UUID for day.")
 	return newID, nil
 }
@@ -255,6 +644,12 @@ func (c *Client) GetDayUUID(date time.Time) (*uuid.UUID, error) {
 	if id, ok := c.dayIDCache[dateString]; ok {
 		return id, nil
 	}
+	if c.dayIDStore != nil {
+		if id, ok := c.dayIDStore.DayID(c.TargetEmployeeID(), dateString); ok {
+			c.dayIDCache[dateString] = &id
+			return &id, nil
+		}
+	}
 	startDate, endDate := util.TimeFullMonth(date)
 	cal, err := c.GetMyAttendanceCalendar(startDate, endDate)
 	if err != nil {
@@ -269,19 +664,22 @@ func (c *Client) GetDayUUID(date time.Time) (*uuid.UUID, error) {
 }
 
 func (c *Client) cacheDayIDs(days []CalendarDay, startDate, endDate time.Time) {
-	log.Debug().
+	c.log().Debug().
 		Int("days", len(days)).
 		Time("start", startDate).
 		Time("end", endDate).
 		Msg("Caching UUIDs for days.")
 	// Cache known days
+	known := make(map[string]uuid.UUID, len(days))
 	for _, day := range days {
 		// must clone the var so we don't take ref of the for loop var
 		id := day.ID
-		c.dayIDCache[day.Attributes.Day] = &id
-		log.Debug().Str("day", day.Attributes.Day).Stringer("uuid", id).
+		c.dayIDCache[day.Attributes.Day.String()] = &id
+		known[day.Attributes.Day.String()] = id
+		c.log().Debug().Stringer("day", day.Attributes.Day).Stringer("uuid", id).
 			Msg("Cached existing UUID for day.")
 	}
+	c.storeDayIDs(known)
 
 	// Set unknown days
 	loopEnd := endDate.Add(24 * time.Hour)
@@ -289,8 +687,19 @@ func (c *Client) cacheDayIDs(days []CalendarDay, startDate, endDate time.Time) {
 		dateString := date.Format(time.DateOnly)
 		if _, ok := c.dayIDCache[dateString]; !ok {
 			c.dayIDCache[dateString] = nil
-			log.Debug().Str("day", dateString).Str("uuid", "nil").
+			c.log().Debug().Str("day", dateString).Str("uuid", "nil").
 				Msg("Cached undefined UUID for day.")
 		}
 	}
 }
+
+// storeDayIDs remembers the day IDs between runs, if enabled via
+// [Client.SetDayIDStore]. Failing is only logged, as it's only a cache.
+func (c *Client) storeDayIDs(ids map[string]uuid.UUID) {
+	if c.dayIDStore == nil || len(ids) == 0 {
+		return
+	}
+	if err := c.dayIDStore.SetDayIDs(c.TargetEmployeeID(), ids); err != nil {
+		c.log().Warn().Err(err).Msg("Failed storing day IDs.")
+	}
+}
@@ -19,9 +19,11 @@
 package personio
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
+	"github.com/applejag/rootless-personio/pkg/util"
 	"github.com/google/uuid"
 	"gopkg.in/typ.v4"
 )
@@ -30,19 +32,19 @@ func TestCacheDayIDs(t *testing.T) {
 	var knownDays = []CalendarDay{
 		{
 			ID:         uuid.MustParse("00000000-0000-0000-0000-000000000001"),
-			Attributes: CalendarDayAttributes{Day: "2023-01-01"},
+			Attributes: CalendarDayAttributes{Day: NewDate(2023, 1, 1)},
 		},
 		{
 			ID:         uuid.MustParse("00000000-0000-0000-0000-000000000005"),
-			Attributes: CalendarDayAttributes{Day: "2023-01-05"},
+			Attributes: CalendarDayAttributes{Day: NewDate(2023, 1, 5)},
 		},
 		{
 			ID:         uuid.MustParse("00000000-0000-0000-0000-000000000010"),
-			Attributes: CalendarDayAttributes{Day: "2023-01-10"},
+			Attributes: CalendarDayAttributes{Day: NewDate(2023, 1, 10)},
 		},
 		{
 			ID:         uuid.MustParse("00000000-0000-0000-0000-000000000011"),
-			Attributes: CalendarDayAttributes{Day: "2023-01-11"},
+			Attributes: CalendarDayAttributes{Day: NewDate(2023, 1, 11)},
 		},
 	}
 	startDate := mustParseTime(t, "2006-01-02", "2023-01-01")
@@ -129,3 +131,258 @@ func mustParseTime(t *testing.T, layout, value string) time.Time {
 	}
 	return tim
 }
+
+func TestAttendanceCalendarPartialSections(t *testing.T) {
+	var cal AttendanceCalendar
+	if err := json.Unmarshal([]byte(`{"attendance_periods":{"data":[]},"holidays":{"data":[{"date":"2023-01-01"}]}}`), &cal); err != nil {
+		t.Fatalf("unmarshal: %s", err)
+	}
+	if cal.HasSection(SectionAbsencePeriods) || !cal.HasSection(SectionHolidays) {
+		t.Errorf("want only holidays section, got missing %v", cal.MissingSections())
+	}
+	if got := cal.GetAbsencePeriods(); got != nil {
+		t.Errorf("want no absences, got %+v", got)
+	}
+	if got := cal.GetHolidays(); len(got) != 1 || got[0].Date != NewDate(2023, 1, 1) {
+		t.Errorf("want 1 holiday, got %+v", got)
+	}
+}
+
+func TestCalendarAttendancePeriodGetters(t *testing.T) {
+	comment := "standup"
+	projectID := 42
+	withAll := CalendarAttendancePeriod{Attributes: CalendarAttendancePeriodAttributes{Comment: &comment, ProjectID: &projectID}}
+	if got := withAll.GetComment(); got != comment {
+		t.Errorf("want comment %q, got %q", comment, got)
+	}
+	if got := withAll.GetProjectID(); got != projectID {
+		t.Errorf("want project %d, got %d", projectID, got)
+	}
+
+	var omitted CalendarAttendancePeriod
+	if got := omitted.GetComment(); got != "" {
+		t.Errorf("want empty comment when omitted, got %q", got)
+	}
+	if got := omitted.GetProjectID(); got != 0 {
+		t.Errorf("want zero project when omitted, got %d", got)
+	}
+}
+
+func TestPeriodShiftDays(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Skipf("load location: %s", err)
+	}
+	oldLocal := time.Local
+	time.Local = loc
+	defer func() { time.Local = oldLocal }()
+
+	// Daylight saving time starts on 2023-03-26 in Sweden
+	p := Period{
+		ID:    uuid.New(),
+		Start: time.Date(2023, 3, 24, 8, 0, 0, 0, loc).UTC(),
+		End:   time.Date(2023, 3, 24, 16, 0, 0, 0, loc).UTC(),
+	}
+	shifted := p.ShiftDays(3)
+
+	if shifted.ID == p.ID {
+		t.Error("want new ID, got same ID")
+	}
+	wantStart := time.Date(2023, 3, 27, 8, 0, 0, 0, loc)
+	if !shifted.Start.Equal(wantStart) {
+		t.Errorf("want start %s, got %s", wantStart, shifted.Start)
+	}
+	wantEnd := time.Date(2023, 3, 27, 16, 0, 0, 0, loc)
+	if !shifted.End.Equal(wantEnd) {
+		t.Errorf("want end %s, got %s", wantEnd, shifted.End)
+	}
+}
+
+func TestValidatePeriods(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2023, 1, 18, hour, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name    string
+		periods []Period
+		wantErr bool
+	}{
+		{
+			name: "adjacent",
+			periods: []Period{
+				{Start: at(13), End: at(17)},
+				{Start: at(8), End: at(12)},
+				{Start: at(12), End: at(13), PeriodType: PeriodTypeBreak},
+			},
+		},
+		{
+			name:    "empty",
+			periods: []Period{{Start: at(8), End: at(8)}},
+			wantErr: true,
+		},
+		{
+			name: "overlapping",
+			periods: []Period{
+				{Start: at(8), End: at(12)},
+				{Start: at(11), End: at(17)},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidatePeriods(tc.periods)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("want error %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func FuzzCacheDayIDs(f *testing.F) {
+	// Month edges and leap years, in timezones far from UTC
+	f.Add(int64(2024), uint8(2), uint8(29), uint8(23), int16(14*60), uint32(1))
+	f.Add(int64(2023), uint8(2), uint8(28), uint8(0), int16(-12*60), uint32(0))
+	f.Add(int64(2000), uint8(2), uint8(29), uint8(12), int16(0), uint32(0xffffffff))
+	f.Add(int64(1900), uint8(2), uint8(28), uint8(12), int16(60), uint32(0))
+	f.Add(int64(2023), uint8(12), uint8(31), uint8(23), int16(-60), uint32(1<<30))
+	f.Add(int64(2023), uint8(1), uint8(1), uint8(0), int16(60), uint32(1))
+	f.Fuzz(func(t *testing.T, year int64, month, day, hour uint8, offsetMin int16, known uint32) {
+		loc := time.FixedZone("", int(offsetMin%(15*60))*60)
+		date := time.Date(int(1900+year%300), time.Month(month%12+1), int(day%31+1), int(hour%24), 30, 0, 0, loc)
+		// Overflowing days, such as Feb 31, roll over into the next month
+		dateString := date.Format(time.DateOnly)
+
+		startDate, endDate := util.TimeFullMonth(date)
+		var days []CalendarDay
+		for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+			if known&(1<<(d.Day()-1)) != 0 {
+				days = append(days, CalendarDay{
+					ID:         uuid.New(),
+					Attributes: CalendarDayAttributes{Day: DateOf(d)},
+				})
+			}
+		}
+
+		client := &Client{dayIDCache: make(map[string]*uuid.UUID)}
+		client.cacheDayIDs(days, startDate, endDate)
+
+		if _, ok := client.dayIDCache[dateString]; !ok {
+			t.Fatalf("date %s: day %s missing from cache of %s-%s", date, dateString,
+				startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+		}
+		if want := endDate.Day(); len(client.dayIDCache) != want {
+			t.Errorf("date %s: want %d cached days, got %d", date, want, len(client.dayIDCache))
+		}
+		monthPrefix := startDate.Format("2006-01-")
+		for key := range client.dayIDCache {
+			if key[:len(monthPrefix)] != monthPrefix {
+				t.Errorf("date %s: cached day %s outside of month %s", date, key, monthPrefix)
+			}
+		}
+		for _, d := range days {
+			got := client.dayIDCache[d.Attributes.Day.String()]
+			if got == nil || *got != d.ID {
+				t.Errorf("date %s: want day %s to be %s, got %v", date, d.Attributes.Day, d.ID, got)
+			}
+		}
+	})
+}
+
+func FuzzValidatePeriods(f *testing.F) {
+	f.Add([]byte{0, 12, 12, 12})
+	f.Add([]byte{0, 12, 11, 12})
+	f.Add([]byte{5, 0})
+	f.Add([]byte{5, 0xff, 20, 3, 40, 3})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		base := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+		var periods []Period
+		for i := 0; i+1 < len(data) && len(periods) < 16; i += 2 {
+			start := base.Add(time.Duration(data[i]) * 5 * time.Minute)
+			periods = append(periods, Period{
+				ID:    uuid.New(),
+				Start: start,
+				End:   start.Add(time.Duration(int8(data[i+1])) * 5 * time.Minute),
+			})
+		}
+
+		wantValid := true
+		for i, a := range periods {
+			if !a.End.After(a.Start) {
+				wantValid = false
+			}
+			for _, b := range periods[i+1:] {
+				if a.Start.Before(b.End) && b.Start.Before(a.End) {
+					wantValid = false
+				}
+			}
+		}
+
+		err := ValidatePeriods(periods)
+		if wantValid && err != nil {
+			t.Errorf("want valid, got error: %s", err)
+		}
+		if !wantValid && err == nil {
+			t.Errorf("want error, got valid: %+v", periods)
+		}
+	})
+}
+
+func TestAttendanceCalendarMerge(t *testing.T) {
+	vacation := CalendarAbsencePeriod{ID: "1", Name: "Vacation", StartDate: NewDate(2023, 1, 30), EndDate: NewDate(2023, 2, 3)}
+	jan := &AttendanceCalendar{
+		AttendanceDays:   Data[[]CalendarDay]{Data: []CalendarDay{{Attributes: CalendarDayAttributes{Day: NewDate(2023, 1, 30)}}}},
+		AbsencePeriods:   &Data[[]CalendarAbsencePeriod]{Data: []CalendarAbsencePeriod{vacation}},
+		Holidays:         &Data[[]CalendarHoliday]{Data: []CalendarHoliday{{Date: NewDate(2023, 1, 1)}}},
+		AttendanceAlerts: []byte(`{"data":[{"day":"2023-01-30"}]}`),
+	}
+	feb := &AttendanceCalendar{
+		AttendanceDays: Data[[]CalendarDay]{Data: []CalendarDay{{Attributes: CalendarDayAttributes{Day: NewDate(2023, 2, 1)}}}},
+		AbsencePeriods: &Data[[]CalendarAbsencePeriod]{Data: []CalendarAbsencePeriod{vacation, {ID: "2", Name: "Sick"}}},
+		// Holidays omitted by Personio
+		AttendanceAlerts: []byte(`[{"day":"2023-02-01"}]`),
+	}
+	jan.Merge(feb)
+
+	if len(jan.AttendanceDays.Data) != 2 {
+		t.Errorf("want 2 days, got %d", len(jan.AttendanceDays.Data))
+	}
+	if got := jan.GetAbsencePeriods(); len(got) != 2 {
+		t.Errorf("want 2 absences, as the vacation spans both months, got %d", len(got))
+	}
+	if jan.HasSection(SectionHolidays) {
+		t.Error("want holidays section to be missing, as it's incomplete")
+	}
+	if len(jan.AlertsOn("2023-01-30")) != 1 || len(jan.AlertsOn("2023-02-01")) != 1 {
+		t.Errorf("want alerts of both months, got %s", jan.AttendanceAlerts)
+	}
+}
+
+func TestAttendanceCalendarDayFilters(t *testing.T) {
+	cal := &AttendanceCalendar{
+		AttendanceDays: Data[[]CalendarDay]{Data: []CalendarDay{
+			{Attributes: CalendarDayAttributes{Day: NewDate(2023, 1, 16), Status: "confirmed", DurationMin: 480}},
+			{Attributes: CalendarDayAttributes{Day: NewDate(2023, 1, 17), Status: DayStatusEmpty}},
+			{Attributes: CalendarDayAttributes{Day: NewDate(2023, 1, 18), Status: "pending", DurationMin: 240}},
+		}},
+		AttendanceAlerts: []byte(`{"data":[{"day":"2023-01-17"}]}`),
+	}
+
+	if got := cal.WorkDays(); len(got) != 2 {
+		t.Errorf("want 2 work days, got %d", len(got))
+	}
+	if got := cal.EmptyDays(); len(got) != 1 || got[0].Attributes.Day != NewDate(2023, 1, 17) {
+		t.Errorf("want 2023-01-17 as only empty day, got %v", got)
+	}
+	if got := cal.DaysWithAlerts(); len(got) != 1 || got[0].Attributes.Day != NewDate(2023, 1, 17) {
+		t.Errorf("want 2023-01-17 as only day with alerts, got %v", got)
+	}
+	if got := cal.TotalWorkedMinutes(); got != 720 {
+		t.Errorf("want 720 worked minutes, got %d", got)
+	}
+
+	var nilCal *AttendanceCalendar
+	if nilCal.WorkDays() != nil || nilCal.DaysWithAlerts() != nil || nilCal.TotalWorkedMinutes() != 0 {
+		t.Error("want nil calendar to have no days")
+	}
+}
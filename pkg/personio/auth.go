@@ -22,11 +22,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
+	"path"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var (
@@ -40,16 +41,31 @@ func (c *Client) UnlockAndLogin(email, pass, emailToken string) error {
 	return c.Login(email, pass)
 }
 
+// ParseUnlockToken returns the login token from the link in Personio's
+// "Confirm login in your account" email, or the token itself if it's not
+// a link.
+func ParseUnlockToken(codeOrLink string) string {
+	codeOrLink = strings.TrimSpace(codeOrLink)
+	u, err := url.Parse(codeOrLink)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return codeOrLink
+	}
+	if token := u.Query().Get("token"); token != "" {
+		return token
+	}
+	return path.Base(strings.TrimSuffix(u.Path, "/"))
+}
+
 func (c *Client) UnlockWithToken(emailToken string) error {
 	params := url.Values{}
 	params.Set("token", strings.TrimSpace(emailToken))
 
-	req, err := http.NewRequest(http.MethodPost, "/login/token-auth", strings.NewReader(params.Encode()))
+	req, err := http.NewRequest(http.MethodPost, c.endpoints.TokenAuth, strings.NewReader(params.Encode()))
 	if err != nil {
 		return err
 	}
 
-	resp, err := c.RawForm(req)
+	resp, err := c.RawForm(withRequestClass(req, RequestClassLogin))
 	if err != nil {
 		return err
 	}
@@ -57,10 +73,10 @@ func (c *Client) UnlockWithToken(emailToken string) error {
 	body, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("read token auth page: %w", err)
 	}
 
-	if strings.HasSuffix(resp.Request.URL.Path, "/login/token-auth") {
+	if strings.HasSuffix(resp.Request.URL.Path, c.endpoints.TokenAuth) {
 		errorMatch := csrfTokenErrorRegex.FindSubmatch(body)
 		if errorMatch != nil {
 			return fmt.Errorf("error from page: %s", errorMatch[1])
@@ -75,12 +91,15 @@ func (c *Client) Login(email, pass string) error {
 	params.Set("email", email)
 	params.Set("password", pass)
 
-	req, err := http.NewRequest(http.MethodPost, "/login/index", strings.NewReader(params.Encode()))
+	req, err := http.NewRequest(http.MethodPost, c.endpoints.Login, strings.NewReader(params.Encode()))
 	if err != nil {
 		return err
 	}
 
-	resp, err := c.RawForm(req)
+	resp, err := c.RawForm(withRequestClass(req, RequestClassLogin))
+	if lockout := lockoutFromResponse(resp, time.Now()); lockout != nil {
+		return lockout
+	}
 	if err != nil {
 		return err
 	}
@@ -94,10 +113,26 @@ func (c *Client) Login(email, pass string) error {
 		return fmt.Errorf("%w: want host %q, got %q", ErrUnexpectedRedirect, baseURL.Host, resp.Request.URL.Host)
 	}
 
-	if strings.HasSuffix(resp.Request.URL.Path, "/login/token-auth") {
+	if strings.HasSuffix(resp.Request.URL.Path, c.endpoints.TokenAuth) {
 		return ErrUnlockRequired
 	}
 
+	if strings.HasSuffix(resp.Request.URL.Path, c.endpoints.Login) {
+		// Personio sends you back to the login page on wrong credentials
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("%w: read login page: %s", ErrInvalidCredentials, err)
+		}
+		if errorMatch := csrfTokenErrorRegex.FindSubmatch(body); errorMatch != nil {
+			if lockout := lockoutFromMessage(string(errorMatch[1])); lockout != nil {
+				return lockout
+			}
+			return fmt.Errorf("%w: error from page: %s", ErrInvalidCredentials, errorMatch[1])
+		}
+		return ErrInvalidCredentials
+	}
+
 	if strings.TrimPrefix(resp.Request.URL.Path, "/") != "" {
 		return fmt.Errorf("%w: want path \"/\", got %q", ErrUnexpectedRedirect, resp.Request.URL.Path)
 	}
@@ -107,6 +142,37 @@ func (c *Client) Login(email, pass string) error {
 		return fmt.Errorf("%w: %s", ErrEmployeeIDNotFound, err)
 	}
 	c.EmployeeID = userActivity.Visitor.ID
+	if c.sessionStore != nil {
+		if err := c.sessionStore.Save(); err != nil {
+			c.log().Warn().Err(err).Msg("Failed saving session store.")
+		}
+	}
+	return nil
+}
+
+// SetCookies adds cookies for Personio's base URL, such as the session
+// cookies copied from a web browser.
+func (c *Client) SetCookies(cookies []*http.Cookie) error {
+	baseURL, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return fmt.Errorf("parse base URL: %w", err)
+	}
+	c.http.Jar.SetCookies(baseURL, cookies)
+	return nil
+}
+
+// ResumeSession checks that the client's cookies contain a logged in
+// session, such as after [Client.SetCookies], and then uses it the same as
+// after a [Client.Login].
+func (c *Client) ResumeSession() error {
+	userActivity, err := c.getUserActivity()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNotLoggedIn, err)
+	}
+	if userActivity.Visitor.ID == 0 {
+		return fmt.Errorf("%w: %s", ErrEmployeeIDNotFound, "no visitor ID in response")
+	}
+	c.EmployeeID = userActivity.Visitor.ID
 	return nil
 }
 
@@ -115,11 +181,12 @@ func (c *Client) Login(email, pass string) error {
 // Don't know for certain what this endpoint is, so keeping the function as
 // private in the meantime.
 func (c *Client) getUserActivity() (*userActivity, error) {
-	req, err := http.NewRequest(http.MethodGet, "/user-activity/api/v1/pendo", nil)
+	req, err := http.NewRequest(http.MethodGet, c.endpoints.UserActivity, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
-	resp, err := c.RawJSON(req)
+	// Part of logging in, so gets the same headroom
+	resp, err := c.RawJSON(withRequestClass(req, RequestClassLogin))
 	if err != nil {
 		return nil, err
 	}
@@ -133,35 +200,35 @@ type userActivity struct {
 }
 
 type userActivityVisitor struct {
-	EmploymentType      string `json:"employment_type"`        // ex: "internal"
-	HasReports          bool   `json:"has_reports"`            // ex: false
-	HasSupervisor       bool   `json:"has_supervisor"`         // ex: true
-	ID                  int    `json:"id"`                     // ex: 1234567
-	Role                string `json:"role"`                   // ex: "Employee"
-	Status              string `json:"status"`                 // ex: "active"
-	UserCreatedAt       string `json:"user_created_at"`        // ex: "2006-01-02 15:04:05"
-	UserIsAccountOwner  bool   `json:"user_is_account_owner"`  // ex: false
-	UserIsAdmin         bool   `json:"user_is_admin"`          // ex: false
-	UserIsContractOwner bool   `json:"user_is_contract_owner"` // ex: false
-	UserLang            string `json:"user_lang"`              // ex: "en"
+	EmploymentType      string       `json:"employment_type"`        // ex: "internal"
+	HasReports          bool         `json:"has_reports"`            // ex: false
+	HasSupervisor       bool         `json:"has_supervisor"`         // ex: true
+	ID                  int          `json:"id"`                     // ex: 1234567
+	Role                string       `json:"role"`                   // ex: "Employee"
+	Status              string       `json:"status"`                 // ex: "active"
+	UserCreatedAt       FlexibleTime `json:"user_created_at"`        // ex: "2006-01-02 15:04:05"
+	UserIsAccountOwner  bool         `json:"user_is_account_owner"`  // ex: false
+	UserIsAdmin         bool         `json:"user_is_admin"`          // ex: false
+	UserIsContractOwner bool         `json:"user_is_contract_owner"` // ex: false
+	UserLang            string       `json:"user_lang"`              // ex: "en"
 }
 
 type userActivityAccount struct {
-	AccountActiveEmployees     int    `json:"account_active_employees"`     // ex: 123
-	AccountCreatedAt           string `json:"account_created_at"`           // ex: "2006-01-02 15:04:05"
-	AccountExpiresOn           string `json:"account_expires_on"`           // ex: "2006-01-02"
-	AccountHostname            string `json:"account_hostname"`             // ex: "mycompany"
-	AccountIsTest              bool   `json:"account_is_test"`              // ex: false
-	AccountName                string `json:"account_name"`                 // ex: "My Company GmbH"
-	AccountPlanID              int    `json:"account_plan_id"`              // ex: 123
-	AccountSubcompaniesEnabled bool   `json:"account_subcompanies_enabled"` // ex: false
-	Addons                     string `json:"addons"`                       // ex: "productivity_plus,customization_plus,automation_plus"
-	BillingCountry             string `json:"billing_country"`              // ex: "DE"
-	EnabledPayrollIntegration  any    `json:"enabled_payroll_integration"`  // ex: null
-	ID                         int    `json:"id"`                           // ex: 1234
-	IsTrialAccount             bool   `json:"is_trial_account"`             // ex: false
-	PlanStatus                 string `json:"plan_status"`                  // ex: "active"
-	PlanType                   string `json:"plan_type"`                    // ex: "professional"
-	PlanVersion                int    `json:"plan_version"`                 // ex: 5
-	TrialConversionDate        any    `json:"trial_conversion_date"`        // ex: null
+	AccountActiveEmployees     int          `json:"account_active_employees"`     // ex: 123
+	AccountCreatedAt           FlexibleTime `json:"account_created_at"`           // ex: "2006-01-02 15:04:05"
+	AccountExpiresOn           FlexibleTime `json:"account_expires_on"`           // ex: "2006-01-02"
+	AccountHostname            string       `json:"account_hostname"`             // ex: "mycompany"
+	AccountIsTest              bool         `json:"account_is_test"`              // ex: false
+	AccountName                string       `json:"account_name"`                 // ex: "My Company GmbH"
+	AccountPlanID              int          `json:"account_plan_id"`              // ex: 123
+	AccountSubcompaniesEnabled bool         `json:"account_subcompanies_enabled"` // ex: false
+	Addons                     string       `json:"addons"`                       // ex: "productivity_plus,customization_plus,automation_plus"
+	BillingCountry             string       `json:"billing_country"`              // ex: "DE"
+	EnabledPayrollIntegration  any          `json:"enabled_payroll_integration"`  // ex: null
+	ID                         int          `json:"id"`                           // ex: 1234
+	IsTrialAccount             bool         `json:"is_trial_account"`             // ex: false
+	PlanStatus                 string       `json:"plan_status"`                  // ex: "active"
+	PlanType                   string       `json:"plan_type"`                    // ex: "professional"
+	PlanVersion                int          `json:"plan_version"`                 // ex: 5
+	TrialConversionDate        any          `json:"trial_conversion_date"`        // ex: null
 }
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxResponseSize is the default limit of decoded response bodies,
+// as set by [New].
+const DefaultMaxResponseSize = 16 << 20 // 16 MiB
+
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// ResponseTooLargeError is returned when reading a response body that is
+// larger than the client's max response size. It wraps [ErrResponseTooLarge].
+type ResponseTooLargeError struct {
+	// Limit is the max response size in bytes.
+	Limit int64
+	// MediaType is the media type of the response, e.g "text/html".
+	MediaType string
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	msg := fmt.Sprintf("%s: exceeded limit of %d bytes", ErrResponseTooLarge, e.Limit)
+	if e.MediaType == "text/html" {
+		msg += " (got an HTML page, possibly redirected to the login page)"
+	} else if e.MediaType != "" {
+		msg += fmt.Sprintf(" (got %q)", e.MediaType)
+	}
+	return msg
+}
+
+func (e *ResponseTooLargeError) Unwrap() error {
+	return ErrResponseTooLarge
+}
+
+// SetMaxResponseSize changes the limit of decoded response bodies, in bytes.
+// Zero or less disables the limit.
+func (c *Client) SetMaxResponseSize(size int64) {
+	c.maxResponseSize = size
+}
+
+// wrapResponseBody makes the response body transparently decode gzip and
+// deflate content encodings, and fail with a [ResponseTooLargeError] when
+// reading more than the limit.
+func wrapResponseBody(resp *http.Response, limit int64) error {
+	if resp == nil || resp.Body == nil {
+		return nil
+	}
+	body := resp.Body
+	var reader io.Reader = body
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return fmt.Errorf("decode gzip body: %w", err)
+		}
+		reader = gz
+	case "deflate":
+		reader = flate.NewReader(body)
+	default:
+		// Leave unknown encodings as-is, the JSON parsing will complain
+		return nil
+	}
+	if reader != io.Reader(body) {
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+	if limit > 0 {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		reader = &limitedReader{
+			r:         reader,
+			remaining: limit,
+			err:       &ResponseTooLargeError{Limit: limit, MediaType: mediaType},
+		}
+	}
+	resp.Body = readCloser{Reader: reader, Closer: body}
+	return nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedReader is like [io.LimitedReader], but returns an error instead of
+// [io.EOF] when the limit is exceeded.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	err       error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, l.err
+	}
+	// Read one byte past the limit, to tell a body of exactly the limit
+	// apart from a body that is too large
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), l.err
+	}
+	return n, err
+}
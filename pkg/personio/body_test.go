@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWrapResponseBody(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	io.WriteString(gz, `{"success":true}`)
+	gz.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		limit    int64
+		want     string
+		wantErr  error
+	}{
+		{
+			name: "plain",
+			body: []byte(`{"success":true}`),
+			want: `{"success":true}`,
+		},
+		{
+			name:     "gzip",
+			encoding: "gzip",
+			body:     gzipped.Bytes(),
+			want:     `{"success":true}`,
+		},
+		{
+			name:  "exactly the limit",
+			body:  []byte(`{"success":true}`),
+			limit: 16,
+			want:  `{"success":true}`,
+		},
+		{
+			name:    "too large",
+			body:    []byte(strings.Repeat("<html>", 100)),
+			limit:   16,
+			wantErr: ErrResponseTooLarge,
+		},
+		{
+			name:     "too large after decoding",
+			encoding: "gzip",
+			body:     gzipped.Bytes(),
+			limit:    8,
+			wantErr:  ErrResponseTooLarge,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header: http.Header{},
+				Body:   io.NopCloser(bytes.NewReader(tc.body)),
+			}
+			if tc.encoding != "" {
+				resp.Header.Set("Content-Encoding", tc.encoding)
+			}
+			if err := wrapResponseBody(resp, tc.limit); err != nil {
+				t.Fatalf("wrap body: %s", err)
+			}
+			got, err := io.ReadAll(resp.Body)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr == nil && string(got) != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+			if resp.Header.Get("Content-Encoding") != "" {
+				t.Errorf("want Content-Encoding header removed")
+			}
+		})
+	}
+}
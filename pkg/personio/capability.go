@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Capability is a feature that depends on some of Personio's endpoints.
+// When Personio changes one of them, only the features of that capability
+// break, while the rest keep working.
+type Capability string
+
+// Available [Capability] values.
+const (
+	CapabilityLogin           Capability = "login"
+	CapabilityEmployee        Capability = "employee"
+	CapabilityAttendanceRead  Capability = "attendance-read"
+	CapabilityAttendanceWrite Capability = "attendance-write"
+	CapabilityAbsenceWrite    Capability = "absence-write"
+)
+
+// Capabilities are all capabilities, in the order they are usually used.
+var Capabilities = []Capability{
+	CapabilityLogin,
+	CapabilityEmployee,
+	CapabilityAttendanceRead,
+	CapabilityAttendanceWrite,
+	CapabilityAbsenceWrite,
+}
+
+// endpointCapabilities maps the names of the fields in [Endpoints] to the
+// capability that depends on them.
+var endpointCapabilities = map[string]Capability{
+	"Login":                CapabilityLogin,
+	"TokenAuth":            CapabilityLogin,
+	"UserActivity":         CapabilityLogin,
+	"EmployeeHeader":       CapabilityEmployee,
+	"AttendanceCalendar":   CapabilityAttendanceRead,
+	"AttendanceDay":        CapabilityAttendanceWrite,
+	"AttendanceDayPeriods": CapabilityAttendanceWrite,
+	"AbsencePeriods":       CapabilityAbsenceWrite,
+}
+
+// CapabilityOf returns the capability that depends on the endpoint, by
+// its field name in [Endpoints], or an empty string when unknown.
+func CapabilityOf(endpoint string) Capability {
+	return endpointCapabilities[endpoint]
+}
+
+// DegradedRetryAfter is how long requests of a degraded capability fail
+// right away without being sent, before the endpoint is tried again.
+const DegradedRetryAfter = 5 * time.Minute
+
+var ErrDegraded = errors.New("capability degraded")
+
+// DegradedError is returned without sending the request, when the
+// capability's endpoint recently failed in a way that suggests that
+// Personio changed it. It wraps [ErrDegraded] and the original error.
+type DegradedError struct {
+	Capability Capability
+	// Endpoint is the field name in [Endpoints] of the failing endpoint.
+	Endpoint string
+	Since    time.Time
+	Err      error
+}
+
+func (e *DegradedError) Error() string {
+	return fmt.Sprintf("%s: %s unavailable since %s, as the %s endpoint failed: %s",
+		ErrDegraded, e.Capability, e.Since.Local().Format(time.RFC3339), e.Endpoint, e.Err)
+}
+
+func (e *DegradedError) Unwrap() []error {
+	return []error{ErrDegraded, e.Err}
+}
+
+// CapabilityStatus is whether a capability works, as seen by a client.
+type CapabilityStatus struct {
+	Capability Capability `json:"capability"`
+	Degraded   bool       `json:"degraded"`
+	// Endpoint is the field name in [Endpoints] of the failing endpoint,
+	// if degraded.
+	Endpoint string     `json:"endpoint,omitempty"`
+	Since    *time.Time `json:"since,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+type degradedCapability struct {
+	endpoint string
+	since    time.Time
+	lastTry  time.Time
+	err      error
+	// parsing is true when the response could not be parsed, so only a
+	// parsed response means it works again
+	parsing bool
+}
+
+type capabilityMap struct {
+	mu       sync.Mutex
+	degraded map[Capability]*degradedCapability
+}
+
+// check returns a [DegradedError] if the endpoint's capability is
+// degraded, unless it's time to try the endpoint again.
+func (m *capabilityMap) check(endpoint string, now time.Time) error {
+	capability := CapabilityOf(endpoint)
+	if capability == "" {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.degraded[capability]
+	if !ok {
+		return nil
+	}
+	if now.Sub(d.lastTry) >= DegradedRetryAfter {
+		d.lastTry = now
+		return nil
+	}
+	return &DegradedError{Capability: capability, Endpoint: d.endpoint, Since: d.since, Err: d.err}
+}
+
+// record marks the endpoint's capability as degraded if the error is a
+// breaking failure, or as working again if there was no error. The
+// response is nil for the result of parsing the response, instead of
+// sending the request. Returns true if the capability changed between
+// degraded and working.
+func (m *capabilityMap) record(endpoint string, resp *http.Response, err error, now time.Time) bool {
+	capability := CapabilityOf(endpoint)
+	if capability == "" {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, wasDegraded := m.degraded[capability]
+	if err == nil {
+		if !wasDegraded || (d.parsing && resp != nil) {
+			return false
+		}
+		delete(m.degraded, capability)
+		return true
+	}
+	if !isBreakingFailure(resp, err) {
+		return false
+	}
+	if m.degraded == nil {
+		m.degraded = make(map[Capability]*degradedCapability)
+	}
+	if !wasDegraded {
+		d = &degradedCapability{since: now}
+		m.degraded[capability] = d
+	}
+	d.endpoint = endpoint
+	d.lastTry = now
+	d.err = err
+	d.parsing = resp == nil
+	return !wasDegraded
+}
+
+func (m *capabilityMap) statuses() []CapabilityStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]CapabilityStatus, len(Capabilities))
+	for i, capability := range Capabilities {
+		statuses[i] = CapabilityStatus{Capability: capability}
+		if d, ok := m.degraded[capability]; ok {
+			since := d.since
+			statuses[i].Degraded = true
+			statuses[i].Endpoint = d.endpoint
+			statuses[i].Since = &since
+			statuses[i].Error = d.err.Error()
+		}
+	}
+	return statuses
+}
+
+// isBreakingFailure returns true if the error suggests that Personio
+// changed the endpoint, such as having moved or removed it, or changed
+// the shape of its response. Errors that affect all endpoints alike, such
+// as network errors, maintenance, or expired sessions, are not breaking.
+func isBreakingFailure(resp *http.Response, err error) bool {
+	if resp != nil {
+		switch resp.StatusCode {
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusGone, http.StatusNotImplemented:
+			return true
+		}
+	}
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	return errors.As(err, &typeErr) || errors.As(err, &syntaxErr)
+}
+
+// CapabilityStatuses returns whether each capability works, in the order
+// of [Capabilities], as seen by this client's requests so far.
+func (c *Client) CapabilityStatuses() []CapabilityStatus {
+	return c.capabilities.statuses()
+}
+
+// noteCapability marks the endpoint's capability as degraded or working,
+// based on the result of a request or of parsing its response, where the
+// response is nil when unknown.
+func (c *Client) noteCapability(endpoint string, resp *http.Response, err error) {
+	if !c.capabilities.record(endpoint, resp, err, time.Now()) {
+		return
+	}
+	if err != nil {
+		c.log().Warn().Err(err).
+			Str("capability", string(CapabilityOf(endpoint))).
+			Str("endpoint", endpoint).
+			Msg("Personio's endpoint seems to have changed. Marking its features as degraded, while the rest keep working.")
+	} else {
+		c.log().Info().
+			Str("capability", string(CapabilityOf(endpoint))).
+			Str("endpoint", endpoint).
+			Msg("Personio's endpoint works again.")
+	}
+}
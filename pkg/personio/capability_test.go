@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCapabilityDegraded(t *testing.T) {
+	var calendarRequests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "attendance-calendar") {
+			calendarRequests++
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{}}`))
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.EmployeeID = 42
+	day := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+
+	if _, err := client.GetMyAttendanceCalendar(day, day); err == nil {
+		t.Fatal("want error from moved endpoint")
+	}
+	_, err = client.GetMyAttendanceCalendar(day, day)
+	var degradedErr *DegradedError
+	if !errors.As(err, &degradedErr) || !errors.Is(err, ErrDegraded) {
+		t.Fatalf("want degraded error, got %v", err)
+	}
+	if degradedErr.Capability != CapabilityAttendanceRead || degradedErr.Endpoint != "AttendanceCalendar" {
+		t.Errorf("unexpected degraded error: %+v", degradedErr)
+	}
+	if calendarRequests != 1 {
+		t.Errorf("want degraded capability to not send requests, got %d requests", calendarRequests)
+	}
+
+	if _, err := client.GetEmployeeData(42); err != nil {
+		t.Errorf("want other capabilities to keep working, got %s", err)
+	}
+	for _, s := range client.CapabilityStatuses() {
+		if want := s.Capability == CapabilityAttendanceRead; s.Degraded != want {
+			t.Errorf("capability %s: want degraded %t, got %t", s.Capability, want, s.Degraded)
+		}
+	}
+}
+
+func TestCapabilityMapRecover(t *testing.T) {
+	var m capabilityMap
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	notFound := &http.Response{StatusCode: http.StatusNotFound}
+	if !m.record("AttendanceDay", notFound, errors.New("404"), now) {
+		t.Fatal("want capability to become degraded")
+	}
+	if err := m.check("AttendanceDayPeriods", now.Add(time.Minute)); !errors.Is(err, ErrDegraded) {
+		t.Errorf("want other endpoint of same capability to be degraded, got %v", err)
+	}
+	if err := m.check("AttendanceDay", now.Add(DegradedRetryAfter)); err != nil {
+		t.Errorf("want retry after %s, got %v", DegradedRetryAfter, err)
+	}
+	if !m.record("AttendanceDay", &http.Response{StatusCode: http.StatusOK}, nil, now.Add(DegradedRetryAfter)) {
+		t.Error("want capability to work again after a successful request")
+	}
+
+	var v any
+	parseErr := json.Unmarshal([]byte("{"), &v)
+	if !m.record("EmployeeHeader", nil, fmt.Errorf("parse body: %w", parseErr), now) {
+		t.Fatal("want capability to become degraded on unparsable response")
+	}
+	if m.record("EmployeeHeader", &http.Response{StatusCode: http.StatusOK}, nil, now) {
+		t.Error("want capability to stay degraded until a response is parsed")
+	}
+	if !m.record("EmployeeHeader", nil, nil, now) {
+		t.Error("want capability to work again after a parsed response")
+	}
+}
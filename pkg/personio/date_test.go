@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDateJSON(t *testing.T) {
+	var day CalendarDayAttributes
+	if err := json.Unmarshal([]byte(`{"day":"2023-01-20","duration_min":90}`), &day); err != nil {
+		t.Fatal(err)
+	}
+	if day.Day != NewDate(2023, 1, 20) {
+		t.Errorf("want 2023-01-20, got %s", day.Day)
+	}
+	if day.DurationMin.Duration() != 90*time.Minute {
+		t.Errorf("want 1h30m, got %s", day.DurationMin.Duration())
+	}
+	b, err := json.Marshal(day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"break_min":0,"duration_min":90,"status":"","day":"2023-01-20"}`; string(b) != want {
+		t.Errorf("want %s, got %s", want, b)
+	}
+
+	var zero struct{ A, B Date }
+	if err := json.Unmarshal([]byte(`{"A":"","B":null}`), &zero); err != nil {
+		t.Fatal(err)
+	}
+	if !zero.A.IsZero() || !zero.B.IsZero() {
+		t.Errorf("want zero dates, got %+v", zero)
+	}
+	if err := json.Unmarshal([]byte(`{"A":"2023-01-20T10:00:00Z"}`), &zero); err == nil {
+		t.Error("want error for date with time of day")
+	}
+}
+
+func TestDateOf(t *testing.T) {
+	cet := time.FixedZone("CET", 60*60)
+	got := DateOf(time.Date(2023, 1, 18, 23, 30, 0, 0, cet))
+	if got != NewDate(2023, 1, 18) {
+		t.Errorf("want 2023-01-18, got %s", got)
+	}
+	if want := time.Date(2023, 1, 19, 0, 0, 0, 0, cet); !got.AddDays(1).Midnight(cet).Equal(want) {
+		t.Errorf("want %s, got %s", want, got.AddDays(1).Midnight(cet))
+	}
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/applejag/rootless-personio/pkg/storage"
+	"github.com/google/uuid"
+)
+
+// DayIDStore persists the IDs of existing attendance days between runs,
+// which saves fetching the calendar before writing to a day seen before.
+type DayIDStore interface {
+	// DayID returns the ID of the day ("2006-01-02"), if known.
+	DayID(employeeID int, day string) (uuid.UUID, bool)
+	// SetDayIDs remembers the IDs of the days.
+	SetDayIDs(employeeID int, ids map[string]uuid.UUID) error
+}
+
+// SetDayIDStore makes the client remember day IDs between runs. Days known
+// to have no ID are still only cached per run, as another run may create
+// them. Nil disables it.
+func (c *Client) SetDayIDStore(store DayIDStore) {
+	c.dayIDStore = store
+}
+
+// StoredDayIDs is a [DayIDStore] in a [storage.Backend], with one key per
+// employee and month, e.g "dayids/example.personio.de/123/2023-01.json".
+type StoredDayIDs struct {
+	store  storage.Backend
+	prefix string
+	mu     sync.Mutex
+	months map[string]map[string]uuid.UUID
+}
+
+// NewStoredDayIDs returns a day ID store that stores its keys with the
+// prefix, which must be unique per Personio tenant, as the employee IDs
+// are only unique per tenant.
+func NewStoredDayIDs(store storage.Backend, prefix string) *StoredDayIDs {
+	return &StoredDayIDs{
+		store:  store,
+		prefix: prefix,
+		months: make(map[string]map[string]uuid.UUID),
+	}
+}
+
+func (s *StoredDayIDs) key(employeeID int, day string) string {
+	// The month is the "2006-01" prefix of the day
+	return fmt.Sprintf("%s/%d/%s.json", s.prefix, employeeID, day[:7])
+}
+
+// DayID implements [DayIDStore].
+func (s *StoredDayIDs) DayID(employeeID int, day string) (uuid.UUID, bool) {
+	if len(day) < len("2006-01") {
+		return uuid.Nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.key(employeeID, day)
+	ids, ok := s.months[key]
+	if !ok {
+		ids = make(map[string]uuid.UUID)
+		if b, err := s.store.Get(key); err == nil {
+			// Broken values are ignored, as it's only a cache
+			_ = json.Unmarshal(b, &ids)
+		}
+		s.months[key] = ids
+	}
+	id, ok := ids[day]
+	return id, ok
+}
+
+// SetDayIDs implements [DayIDStore].
+func (s *StoredDayIDs) SetDayIDs(employeeID int, ids map[string]uuid.UUID) error {
+	byKey := make(map[string]map[string]uuid.UUID)
+	for day, id := range ids {
+		if len(day) < len("2006-01") {
+			continue
+		}
+		key := s.key(employeeID, day)
+		if byKey[key] == nil {
+			byKey[key] = make(map[string]uuid.UUID)
+		}
+		byKey[key][day] = id
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for key, monthIDs := range byKey {
+		var merged map[string]uuid.UUID
+		err := s.store.Update(key, func(value []byte) ([]byte, error) {
+			merged = make(map[string]uuid.UUID)
+			if value != nil {
+				_ = json.Unmarshal(value, &merged)
+			}
+			for day, id := range monthIDs {
+				merged[day] = id
+			}
+			return json.Marshal(merged)
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.months[key] = merged
+	}
+	return errors.Join(errs...)
+}
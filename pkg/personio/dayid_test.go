@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/storage"
+	"github.com/google/uuid"
+)
+
+func TestStoredDayIDs(t *testing.T) {
+	store := storage.NewFS(t.TempDir())
+	id := uuid.New()
+	if err := NewStoredDayIDs(store, "dayids/example").SetDayIDs(123, map[string]uuid.UUID{"2023-01-18": id}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another run, using the same storage
+	client := &Client{
+		dayIDCache: make(map[string]*uuid.UUID),
+		dayIDStore: NewStoredDayIDs(store, "dayids/example"),
+		EmployeeID: 123,
+	}
+	got, err := client.GetDayUUID(time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || *got != id {
+		t.Errorf("want remembered ID %s, got %v", id, got)
+	}
+
+	if _, ok := client.dayIDStore.DayID(456, "2023-01-18"); ok {
+		t.Error("want day IDs separated per employee")
+	}
+}
+
+func TestGetOrNewDayUUIDDoesNotStoreNewID(t *testing.T) {
+	var days atomic.Value
+	days.Store(`[]`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"success":true,"data":{"attendance_days":{"data":%s}}}`, days.Load())
+	}))
+	defer srv.Close()
+	store := storage.NewFS(t.TempDir())
+	newClient := func() *Client {
+		client, err := New(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.EmployeeID = 123
+		client.SetDayIDStore(NewStoredDayIDs(store, "dayids/example"))
+		return client
+	}
+	date := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+
+	newID, err := newClient().GetOrNewDayUUID(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The day is then created elsewhere, such as in the web UI
+	serverID := uuid.New()
+	days.Store(fmt.Sprintf(`[{"id":%q,"attributes":{"day":"2023-01-18"}}]`, serverID))
+
+	got, err := newClient().GetDayUUID(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || *got != serverID {
+		t.Errorf("want server's ID %s, got %v (the randomized ID was %s)", serverID, got, newID)
+	}
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// sharedResponse is a response whose body has been read, so it can be
+// handed out to several callers.
+type sharedResponse struct {
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+// clone returns a copy of the response with its own headers and body, so
+// callers can read and modify it independently. A Personio [Error] is
+// copied too, so its response is the caller's copy.
+func (s sharedResponse) clone() (*http.Response, error) {
+	resp := *s.resp
+	resp.Header = s.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(s.body))
+	err := s.err
+	if personioErr, ok := err.(Error); ok {
+		personioErr.Response = &resp
+		err = personioErr
+	}
+	return &resp, err
+}
+
+// RawJSONShared is like [Client.RawJSON], but concurrent identical GET
+// requests from the same process share a single request to Personio,
+// such as when a client of the REST API ("serve") sends several requests
+// for the same calendar at once, as the server reads the calendar of each
+// user concurrently. Each caller gets its own copy of the
+// response. Other methods are not shared, as they are not safe to
+// deduplicate.
+//
+// The shared request is detached from the callers' contexts, so one
+// caller giving up doesn't fail the others. Each caller still stops
+// waiting when its own context is done.
+func (c *Client) RawJSONShared(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.RawJSON(req)
+	}
+	// The key must be taken before sending, as that rewrites the URL
+	key := req.URL.String()
+	shared := req.Clone(detachedContext{req.Context()})
+	ch := c.flights.DoChan(key, func() (any, error) {
+		resp, err := c.RawJSON(shared)
+		if resp == nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		body, readErr := io.ReadAll(resp.Body)
+		if readErr != nil && err == nil {
+			return nil, fmt.Errorf("read body: %w", readErr)
+		}
+		return sharedResponse{resp: resp, body: body, err: err}, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Shared {
+			c.log().Debug().Str("url", key).Msg("Shared response with concurrent identical request.")
+		}
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(sharedResponse).clone()
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+}
+
+// detachedContext keeps the values of its parent, but is never cancelled.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
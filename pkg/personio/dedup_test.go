@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRawJSONSharedDeduplicatesConcurrentGETs(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{"attendance_days":{"data":[{"attributes":{"day":"2024-05-02"}}]}}}`))
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.EmployeeID = 42
+	day := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+
+	const callers = 5
+	cals := make([]*AttendanceCalendar, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cals[i], errs[i] = client.GetMyAttendanceCalendar(day, day)
+		}(i)
+	}
+	// Give all callers time to join the in-flight request
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("want 1 upstream request, got %d", got)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("caller %d: %s", i, err)
+		}
+	}
+	cals[0].AttendanceDays.Data[0].Attributes.Status = "changed"
+	for i, cal := range cals[1:] {
+		if got := cal.AttendanceDays.Data[0].Attributes.Status; got != "" {
+			t.Errorf("caller %d: want own copy of calendar, got status %q", i+1, got)
+		}
+	}
+
+	if _, err := client.GetMyAttendanceCalendar(day, day); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("want later request to not be shared, got %d upstream requests", got)
+	}
+}
+
+func TestRawJSONSharedWaiterOutlivesCancelledLeader(t *testing.T) {
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		received <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/api/v1/shared", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client.RawJSONShared(req)
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := get(leaderCtx)
+		leaderErr <- err
+	}()
+	<-received
+
+	type result struct {
+		body []byte
+		err  error
+	}
+	waiter := make(chan result, 1)
+	go func() {
+		resp, err := get(context.Background())
+		if err != nil {
+			waiter <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		waiter <- result{body: body, err: err}
+	}()
+	// Give the waiter time to join the in-flight request
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader: want %v, got %v", context.Canceled, err)
+	}
+	close(release)
+	res := <-waiter
+	if res.err != nil {
+		t.Fatalf("waiter: %s", res.err)
+	}
+	if string(res.body) != `{"success":true}` {
+		t.Errorf("waiter: want body of shared response, got %q", res.body)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("want 1 upstream request, got %d", got)
+	}
+}
+
+func TestRawJSONSharedErrorHasOwnResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"success":false,"error":{"code":422,"message":"nope"}}`))
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/shared", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.RawJSONShared(req)
+	var personioErr Error
+	if !errors.As(err, &personioErr) {
+		t.Fatalf("want %T, got %v", personioErr, err)
+	}
+	if personioErr.Response != resp {
+		t.Error("want error's response to be the caller's copy of the response")
+	}
+}
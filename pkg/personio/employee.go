@@ -56,13 +56,15 @@ func (c *Client) GetMyEmployeeData() (*Employee, error) {
 }
 
 func (c *Client) GetEmployeeData(id int) (*Employee, error) {
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/employee-header-bff/%d", id), nil)
+	req, err := http.NewRequest(http.MethodGet, employeePath(c.endpoints.EmployeeHeader, id), nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
-	resp, err := c.RawJSON(req)
+	resp, err := c.RawJSONShared(req)
 	if err != nil {
 		return nil, err
 	}
-	return ParseResponseJSON[*Employee](resp)
+	employee, err := ParseResponseJSON[*Employee](resp)
+	c.noteCapability("EmployeeHeader", nil, err)
+	return employee, err
 }
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Paths of the Personio endpoints used by the client, relative to the base
+// URL. The version in the name is the API version in the path, where BFF
+// endpoints ("backend for frontend") are the unversioned endpoints used by
+// Personio's own web UI, which are the most likely to change.
+//
+// Placeholders like {employeeId} are replaced when sending the request.
+const (
+	PathLogin                  = "/login/index"
+	PathTokenAuth              = "/login/token-auth"
+	PathUserActivityV1         = "/user-activity/api/v1/pendo"
+	PathEmployeeHeaderBFF      = "/employee-header-bff/{employeeId}"
+	PathAttendanceCalendarBFF  = "/svc/attendance-bff/attendance-calendar/{employeeId}"
+	PathAttendanceDayV1        = "/api/v1/attendances/days/{dayId}"
+	PathAttendanceDayPeriodsV1 = "/api/v1/attendances/days/{dayId}/periods"
+	// PathAbsencePeriodsV1 is experimental and unverified.
+	PathAbsencePeriodsV1 = "/api/v1/absence-periods"
+)
+
+// Endpoints is the registry of endpoint paths used by the client. Change
+// them via [Client.SetEndpoints] to work around Personio moving an endpoint,
+// without waiting for a new release.
+type Endpoints struct {
+	Login                string
+	TokenAuth            string
+	UserActivity         string
+	EmployeeHeader       string
+	AttendanceCalendar   string
+	AttendanceDay        string
+	AttendanceDayPeriods string
+	AbsencePeriods       string
+}
+
+// DefaultEndpoints are the endpoint paths used by [New].
+var DefaultEndpoints = Endpoints{
+	Login:                PathLogin,
+	TokenAuth:            PathTokenAuth,
+	UserActivity:         PathUserActivityV1,
+	EmployeeHeader:       PathEmployeeHeaderBFF,
+	AttendanceCalendar:   PathAttendanceCalendarBFF,
+	AttendanceDay:        PathAttendanceDayV1,
+	AttendanceDayPeriods: PathAttendanceDayPeriodsV1,
+	AbsencePeriods:       PathAbsencePeriodsV1,
+}
+
+// WithDefaults returns a copy where all empty paths are replaced by the
+// paths from [DefaultEndpoints].
+func (e Endpoints) WithDefaults() Endpoints {
+	v := reflect.ValueOf(&e).Elem()
+	defaults := reflect.ValueOf(DefaultEndpoints)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).String() == "" {
+			v.Field(i).SetString(defaults.Field(i).String())
+		}
+	}
+	return e
+}
+
+// SetEndpoints overrides the endpoint paths, where empty paths keep their
+// default value.
+func (c *Client) SetEndpoints(endpoints Endpoints) {
+	c.endpoints = endpoints.WithDefaults()
+}
+
+// Endpoints returns the endpoint paths used by the client.
+func (c *Client) Endpoints() Endpoints {
+	return c.endpoints
+}
+
+// employeePath replaces the {employeeId} placeholder in the path.
+func employeePath(path string, employeeID int) string {
+	return strings.ReplaceAll(path, "{employeeId}", strconv.Itoa(employeeID))
+}
+
+// dayPath replaces the {dayId} placeholder in the path.
+func dayPath(path string, dayID string) string {
+	return strings.ReplaceAll(path, "{dayId}", url.PathEscape(dayID))
+}
+
+// Name returns the name of the field whose path matches the request path,
+// where placeholders match any single path segment, e.g "AttendanceDay"
+// for "/api/v1/attendances/days/0b0e...". Returns an empty string when no
+// path matches.
+func (e Endpoints) Name(path string) string {
+	v := reflect.ValueOf(e)
+	segments := strings.Split(path, "/")
+	for i := 0; i < v.NumField(); i++ {
+		if matchEndpointPath(v.Field(i).String(), segments) {
+			return v.Type().Field(i).Name
+		}
+	}
+	return ""
+}
+
+func matchEndpointPath(pattern string, segments []string) bool {
+	patternSegments := strings.Split(pattern, "/")
+	if pattern == "" || len(patternSegments) != len(segments) {
+		return false
+	}
+	for i, p := range patternSegments {
+		isPlaceholder := strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}")
+		if p != segments[i] && !(isPlaceholder && segments[i] != "") {
+			return false
+		}
+	}
+	return true
+}
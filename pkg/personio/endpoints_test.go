@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetEndpoints(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{}}`))
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetEndpoints(Endpoints{EmployeeHeader: "/v2/employees/{employeeId}/header"})
+	if got := client.Endpoints().Login; got != PathLogin {
+		t.Errorf("want default login path %q, got %q", PathLogin, got)
+	}
+	if _, err := client.GetEmployeeData(42); err != nil {
+		t.Fatalf("get employee: %s", err)
+	}
+	if want := "/v2/employees/42/header"; gotPath != want {
+		t.Errorf("want request to %q, got %q", want, gotPath)
+	}
+}
+
+func TestEndpointsName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/login/index", want: "Login"},
+		{path: "/svc/attendance-bff/attendance-calendar/42", want: "AttendanceCalendar"},
+		{path: "/api/v1/attendances/days/0b0e5e3c", want: "AttendanceDay"},
+		{path: "/api/v1/attendances/days/0b0e5e3c/periods", want: "AttendanceDayPeriods"},
+		{path: "/api/v1/attendances/days/", want: ""},
+		{path: "/unknown", want: ""},
+	}
+	for _, tc := range tests {
+		if got := DefaultEndpoints.Name(tc.path); got != tc.want {
+			t.Errorf("path %q: want %q, got %q", tc.path, tc.want, got)
+		}
+	}
+}
+
+func TestManifestMatchesDefaultEndpoints(t *testing.T) {
+	specs, err := Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make(map[string]string)
+	for _, spec := range specs {
+		if spec.Method == "" || spec.Status == "" {
+			t.Errorf("%s: missing method or status", spec.Name)
+		}
+		if spec.LastVerified != "" {
+			if _, err := time.Parse(time.DateOnly, spec.LastVerified); err != nil {
+				t.Errorf("%s: lastVerified: %s", spec.Name, err)
+			}
+		}
+		paths[spec.Name] = spec.Path
+	}
+	v := reflect.ValueOf(DefaultEndpoints)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		path, ok := paths[name]
+		if !ok {
+			t.Errorf("%s: missing in manifest.yaml", name)
+			continue
+		}
+		if want := v.Field(i).String(); path != want {
+			t.Errorf("%s: want path %q in manifest.yaml, got %q", name, want, path)
+		}
+		delete(paths, name)
+	}
+	for name := range paths {
+		t.Errorf("%s: in manifest.yaml, but not in Endpoints", name)
+	}
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import "net/http"
+
+// Data is the envelope of nested resources, such as the sections of the
+// attendance calendar: {"data": ...}
+type Data[M any] struct {
+	Data M `json:"data"`
+}
+
+// Envelope is the envelope of Personio's responses, which contains the data
+// together with either the success flag and error, or the pagination meta
+// and links, depending on the endpoint:
+//
+//	{"success": true, "data": ...}
+//	{"success": false, "error": {"code": 0, "message": "..."}}
+//	{"data": ..., "meta": {...}, "links": {...}}
+type Envelope[M any] struct {
+	// Success is nil for endpoints that don't report it.
+	Success *bool       `json:"success,omitempty"`
+	Error   ErrorDetail `json:"error"`
+	Data    M           `json:"data"`
+	// Meta and Links are nil for endpoints without pagination.
+	Meta  *Meta  `json:"meta,omitempty"`
+	Links *Links `json:"links,omitempty"`
+}
+
+// Err returns an [Error] if Personio reported that the request failed.
+func (e *Envelope[M]) Err(resp *http.Response) error {
+	if e.Success == nil || *e.Success {
+		return nil
+	}
+	return Error{
+		Code:      e.Error.Code,
+		Message:   e.Error.Message,
+		ErrorData: e.Error.ErrorData,
+		Response:  resp,
+	}
+}
+
+// ErrorDetail is the error of a failed request: {"code": 0, "message": "..."}
+type ErrorDetail struct {
+	Code      int                 `json:"code"`
+	Message   string              `json:"message"`
+	ErrorData map[string][]string `json:"error_data,omitempty"`
+}
+
+// Meta is the pagination info of paginated endpoints.
+type Meta struct {
+	CurrentPage   int `json:"current_page"`
+	TotalPages    int `json:"total_pages"`
+	TotalElements int `json:"total_elements"`
+	Limit         int `json:"limit,omitempty"`
+	Offset        int `json:"offset,omitempty"`
+}
+
+// HasNextPage returns true if there are pages after the current page.
+// Pages are numbered from zero.
+func (m *Meta) HasNextPage() bool {
+	return m != nil && m.CurrentPage+1 < m.TotalPages
+}
+
+// Links are the pagination links of paginated endpoints, which are empty
+// when there is no such page.
+type Links struct {
+	Self  string `json:"self,omitempty"`
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func newJSONResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+	}
+}
+
+func TestParseEnvelopeJSON(t *testing.T) {
+	resp := newJSONResponse(`{"data":[1,2],"meta":{"current_page":0,"total_pages":2,"total_elements":4},"links":{"next":"/api/v1/things?offset=2"}}`)
+	env, err := ParseEnvelopeJSON[[]int](resp)
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if len(env.Data) != 2 || env.Meta == nil || env.Meta.TotalElements != 4 {
+		t.Errorf("wrong envelope: %+v", env)
+	}
+	if !env.Meta.HasNextPage() || env.Links.Next == "" {
+		t.Errorf("want next page, got meta %+v and links %+v", env.Meta, env.Links)
+	}
+
+	resp = newJSONResponse(`{"success":false,"error":{"code":403,"message":"Forbidden"}}`)
+	_, err = ParseResponseJSON[any](resp)
+	var personioErr Error
+	if !errors.As(err, &personioErr) || personioErr.Code != 403 || personioErr.Message != "Forbidden" {
+		t.Errorf("want Personio error, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+)
+
+// Failure describes a failed request, without any of its values, such as
+// for reporting which endpoints break after a change on Personio's side.
+type Failure struct {
+	// Endpoint is the name of the field in [Endpoints] that the request
+	// was sent to, e.g "AttendanceCalendar", or empty when unknown.
+	Endpoint string
+	Method   string
+	// StatusCode is zero when no response was received.
+	StatusCode int
+	// ContentType is the media type of the response, without parameters.
+	ContentType string
+	// Headers are the canonical names of the headers sent, sorted.
+	Headers []string
+	Err     error
+}
+
+// WithFailureObserver calls the function for each failed request, as
+// described in [Client.SetFailureObserver].
+func WithFailureObserver(observer func(Failure)) Option {
+	return func(c *Client) {
+		c.failureObserver = observer
+	}
+}
+
+// SetFailureObserver makes the client call the function for each failed
+// request. The function may be called concurrently, and must not block.
+// A nil function disables it.
+func (c *Client) SetFailureObserver(observer func(Failure)) {
+	c.failureObserver = observer
+}
+
+func (c *Client) observeFailure(endpoint string, req *http.Request, resp *http.Response, err error) {
+	if err == nil || c.failureObserver == nil {
+		return
+	}
+	f := Failure{
+		Endpoint: endpoint,
+		Method:   req.Method,
+		Err:      err,
+	}
+	for key := range req.Header {
+		f.Headers = append(f.Headers, http.CanonicalHeaderKey(key))
+	}
+	sort.Strings(f.Headers)
+	if resp != nil {
+		f.StatusCode = resp.StatusCode
+		f.ContentType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	}
+	c.failureObserver(f)
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFailureObserver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html>login</html>"))
+	}))
+	defer srv.Close()
+
+	var failures []Failure
+	client, err := New(srv.URL, WithFailureObserver(func(f Failure) {
+		failures = append(failures, f)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetEmployeeData(42); !errors.Is(err, ErrUnexpectedHTML) {
+		t.Fatalf("want unexpected HTML error, got %v", err)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("want 1 failure, got %d", len(failures))
+	}
+	f := failures[0]
+	if f.Endpoint != "EmployeeHeader" || f.Method != http.MethodGet || f.StatusCode != http.StatusOK || f.ContentType != "text/html" {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if !errors.Is(f.Err, ErrUnexpectedHTML) {
+		t.Errorf("want unexpected HTML error, got %v", f.Err)
+	}
+}
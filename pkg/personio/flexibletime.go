@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// FlexibleTimeLayouts are the time formats that Personio has been observed
+// to use, sometimes for different fields in the same payload. Times without
+// a time zone are parsed as UTC.
+var FlexibleTimeLayouts = []string{
+	time.RFC3339,  // ex: "2023-01-18T13:00:00Z"
+	time.DateTime, // ex: "2022-12-22 00:00:00"
+	time.DateOnly, // ex: "2022-12-22"
+}
+
+// FlexibleTime is a timestamp that accepts any of the
+// [FlexibleTimeLayouts], so decoding doesn't break when Personio changes
+// the format of a field. It's encoded using the same layout it was decoded
+// from, or RFC3339 if it wasn't decoded, and as an empty string when zero.
+type FlexibleTime struct {
+	time.Time
+	layout string
+}
+
+// NewFlexibleTime returns the time as a [FlexibleTime] that's encoded as
+// RFC3339.
+func NewFlexibleTime(t time.Time) FlexibleTime {
+	return FlexibleTime{Time: t, layout: time.RFC3339}
+}
+
+// ParseFlexibleTime parses a time in any of the [FlexibleTimeLayouts].
+func ParseFlexibleTime(s string) (FlexibleTime, error) {
+	for _, layout := range FlexibleTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return FlexibleTime{Time: t, layout: layout}, nil
+		}
+	}
+	return FlexibleTime{}, fmt.Errorf("parse time %q: unknown format", s)
+}
+
+// Layout returns the layout that the time was parsed with, or RFC3339 if it
+// wasn't parsed.
+func (t FlexibleTime) Layout() string {
+	if t.layout == "" {
+		return time.RFC3339
+	}
+	return t.layout
+}
+
+// String returns the time formatted using its [FlexibleTime.Layout], or an
+// empty string if zero.
+func (t FlexibleTime) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(t.Layout())
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (t FlexibleTime) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (t *FlexibleTime) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*t = FlexibleTime{}
+		return nil
+	}
+	parsed, err := ParseFlexibleTime(string(text))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler]. It's needed as [time.Time]'s
+// own implementation would otherwise be used.
+func (t FlexibleTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (t *FlexibleTime) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*t = FlexibleTime{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("parse time: %w", err)
+	}
+	return t.UnmarshalText([]byte(s))
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFlexibleTimeJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+	}{
+		{
+			name:  "RFC3339",
+			value: `"2023-01-18T13:00:00Z"`,
+			want:  time.Date(2023, 1, 18, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "RFC3339 with offset",
+			value: `"2023-01-18T13:00:00+01:00"`,
+			want:  time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "date and time",
+			value: `"2022-12-22 08:30:00"`,
+			want:  time.Date(2022, 12, 22, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "date only",
+			value: `"2022-12-22"`,
+			want:  time.Date(2022, 12, 22, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "empty",
+			value: `""`,
+		},
+		{
+			name:  "null",
+			value: `null`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got FlexibleTime
+			if err := json.Unmarshal([]byte(tc.value), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("want %s, got %s", tc.want, got.Time)
+			}
+			if tc.value == "null" {
+				return
+			}
+			b, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.value {
+				t.Errorf("want round-trip to %s, got %s", tc.value, b)
+			}
+		})
+	}
+}
+
+func TestFlexibleTimeUnknownFormat(t *testing.T) {
+	var got FlexibleTime
+	if err := json.Unmarshal([]byte(`"18.01.2023"`), &got); err == nil {
+		t.Errorf("want error, got %s", got)
+	}
+}
+
+func TestCalendarAbsencePeriodMixedFormats(t *testing.T) {
+	var absence CalendarAbsencePeriod
+	err := json.Unmarshal([]byte(`{
+		"start_date": "2022-12-22",
+		"start_time": "2022-12-22 00:00:00",
+		"end_date": "2022-12-28",
+		"end_time": "2022-12-29T00:00:00Z"
+	}`), &absence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2022, 12, 29, 0, 0, 0, 0, time.UTC); !absence.EndTime.Equal(want) {
+		t.Errorf("want end time %s, got %s", want, absence.EndTime.Time)
+	}
+	if got := absence.StartTime.Layout(); got != time.DateTime {
+		t.Errorf("want start time layout %q, got %q", time.DateTime, got)
+	}
+}
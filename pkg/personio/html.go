@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var ErrUnexpectedHTML = errors.New("unexpected HTML response instead of JSON")
+
+var (
+	htmlTitleRegex  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	loginTitleRegex = regexp.MustCompile(`(?i)\b(log ?in|sign ?in|anmelden)\b`)
+)
+
+// UnexpectedHTMLError is returned when Personio responds with an HTML page
+// to a JSON request, which usually is the login page after the session has
+// expired. It wraps [ErrUnexpectedHTML].
+type UnexpectedHTMLError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// URL is the URL of the page, after following any redirects.
+	URL string
+	// Title is the page's <title>, or empty if it has none.
+	Title string
+}
+
+func (e *UnexpectedHTMLError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(ErrUnexpectedHTML.Error())
+	if e.Title != "" {
+		fmt.Fprintf(&sb, ": page %q", e.Title)
+	}
+	if e.StatusCode != 0 && e.StatusCode != http.StatusOK {
+		fmt.Fprintf(&sb, " (HTTP %d)", e.StatusCode)
+	}
+	fmt.Fprintf(&sb, ": %s", e.Remediation())
+	return sb.String()
+}
+
+func (e *UnexpectedHTMLError) Unwrap() error {
+	return ErrUnexpectedHTML
+}
+
+// IsLoginPage returns true if the page seems to be Personio's login page.
+func (e *UnexpectedHTMLError) IsLoginPage() bool {
+	return strings.Contains(e.URL, "/login") || loginTitleRegex.MatchString(e.Title)
+}
+
+// Remediation returns a suggestion on how to resolve the error.
+func (e *UnexpectedHTMLError) Remediation() string {
+	if e.IsLoginPage() {
+		return "got redirected to the login page, so the session has likely expired; log in again"
+	}
+	return "check that the base URL points to your company's Personio, e.g https://mycompany.personio.de"
+}
+
+// isHTMLResponse returns true if the response has an HTML content type.
+func isHTMLResponse(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/html"
+}
+
+// unexpectedHTML reads the page title from an HTML response and returns an
+// [UnexpectedHTMLError]. The body is kept, so it can be read again.
+func unexpectedHTML(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil && !errors.Is(err, ErrResponseTooLarge) {
+		return fmt.Errorf("%w: read body: %w", ErrUnexpectedHTML, err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return newUnexpectedHTMLError(resp, body)
+}
+
+// looksLikeHTML returns true if the body seems to be an HTML page, even if
+// the response claims to be JSON.
+func looksLikeHTML(body []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("<"))
+}
+
+func newUnexpectedHTMLError(resp *http.Response, body []byte) *UnexpectedHTMLError {
+	htmlErr := &UnexpectedHTMLError{StatusCode: resp.StatusCode}
+	if resp.Request != nil && resp.Request.URL != nil {
+		htmlErr.URL = resp.Request.URL.String()
+	}
+	if match := htmlTitleRegex.FindSubmatch(body); match != nil {
+		htmlErr.Title = strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " ")
+	}
+	return htmlErr
+}
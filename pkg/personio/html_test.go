@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRawJSONUnexpectedHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/login/index" {
+			http.Redirect(w, r, "/login/index", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<!DOCTYPE html><html><head><title>\n  Login &amp; Personio\n</title></head><body></body></html>")
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/attendances/periods", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.RawJSON(req)
+	if !errors.Is(err, ErrUnexpectedHTML) {
+		t.Fatalf("want ErrUnexpectedHTML, got %v", err)
+	}
+	var htmlErr *UnexpectedHTMLError
+	if !errors.As(err, &htmlErr) {
+		t.Fatalf("want UnexpectedHTMLError, got %T", err)
+	}
+	if htmlErr.Title != "Login & Personio" {
+		t.Errorf("want page title, got %q", htmlErr.Title)
+	}
+	if !htmlErr.IsLoginPage() || !strings.Contains(err.Error(), "log in again") {
+		t.Errorf("want login remediation, got %q", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), "<title>") {
+		t.Errorf("want body readable again, got %q", body)
+	}
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrTooManyAttempts = errors.New("too many login attempts")
+
+var (
+	lockoutMessageRegex  = regexp.MustCompile(`(?i)too many (?:\w+ )?(?:attempts|requests)|temporarily (?:locked|blocked)`)
+	lockoutDurationRegex = regexp.MustCompile(`(?i)(\d+)\s*(seconds?|secs?|minutes?|mins?|hours?|hrs?)\b`)
+)
+
+// LockoutError is returned when Personio refuses to log in due to too many
+// login attempts. It wraps [ErrTooManyAttempts].
+type LockoutError struct {
+	// RetryAfter is how long Personio asks to wait before trying again,
+	// or zero if it didn't say.
+	RetryAfter time.Duration
+	// Message is the error message from Personio, if any.
+	Message string
+}
+
+func (e *LockoutError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(ErrTooManyAttempts.Error())
+	if e.Message != "" {
+		fmt.Fprintf(&sb, ": error from page: %s", e.Message)
+	}
+	if e.RetryAfter > 0 {
+		fmt.Fprintf(&sb, " (retry after %s)", e.RetryAfter)
+	}
+	return sb.String()
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// lockoutFromResponse returns a [LockoutError] if the response is
+// Personio's HTTP 429 (Too Many Requests) response.
+func lockoutFromResponse(resp *http.Response, now time.Time) *LockoutError {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	return &LockoutError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), now)}
+}
+
+// lockoutFromMessage returns a [LockoutError] if the login page's error
+// message is about too many login attempts.
+func lockoutFromMessage(message string) *LockoutError {
+	if !lockoutMessageRegex.MatchString(message) {
+		return nil
+	}
+	lockout := &LockoutError{Message: message}
+	if match := lockoutDurationRegex.FindStringSubmatch(message); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		unit := time.Second
+		switch strings.ToLower(match[2])[0] {
+		case 'm':
+			unit = time.Minute
+		case 'h':
+			unit = time.Hour
+		}
+		lockout.RetryAfter = time.Duration(n) * unit
+	}
+	return lockout
+}
+
+// parseRetryAfter parses the Retry-After HTTP header, which is either in
+// seconds or an HTTP date. Returns zero if unset or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLockoutFromMessage(t *testing.T) {
+	var tests = []struct {
+		name    string
+		message string
+		want    *LockoutError
+	}{
+		{
+			name:    "wrong password",
+			message: "The email or password is incorrect.",
+			want:    nil,
+		},
+		{
+			name:    "minutes",
+			message: "Too many login attempts. Please try again in 15 minutes.",
+			want:    &LockoutError{RetryAfter: 15 * time.Minute},
+		},
+		{
+			name:    "no duration",
+			message: "Your account has been temporarily locked.",
+			want:    &LockoutError{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := lockoutFromMessage(tc.message)
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("want nil, got %v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("want lockout, got nil")
+			}
+			if got.RetryAfter != tc.want.RetryAfter {
+				t.Errorf("want retry after %s, got %s", tc.want.RetryAfter, got.RetryAfter)
+			}
+			if !errors.Is(got, ErrTooManyAttempts) {
+				t.Errorf("want error to wrap ErrTooManyAttempts")
+			}
+		})
+	}
+}
+
+func TestLockoutFromResponse(t *testing.T) {
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"Wed, 01 Mar 2023 12:30:00 GMT"}},
+	}
+	got := lockoutFromResponse(resp, now)
+	if got == nil || got.RetryAfter != 30*time.Minute {
+		t.Errorf("want retry after 30m, got %v", got)
+	}
+
+	resp.Header.Set("Retry-After", "120")
+	if got := lockoutFromResponse(resp, now); got == nil || got.RetryAfter != 2*time.Minute {
+		t.Errorf("want retry after 2m, got %v", got)
+	}
+
+	resp.StatusCode = http.StatusOK
+	if got := lockoutFromResponse(resp, now); got != nil {
+		t.Errorf("want nil, got %v", got)
+	}
+}
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ErrMaintenance = errors.New("Personio is under maintenance")
+
+var (
+	maintenanceRegex = regexp.MustCompile(`(?i)\bmaintenance\b|\bwartung|scheduled downtime`)
+	// Announced end times are expected in ISO 8601, e.g "2023-01-18T14:00:00Z"
+	maintenanceEndRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2})?(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})`)
+)
+
+// MaintenanceError is returned when Personio responds with its maintenance
+// page. It wraps [ErrMaintenance].
+type MaintenanceError struct {
+	// Until is the announced end of the maintenance, or zero if Personio
+	// didn't say.
+	Until time.Time
+	// Title is the maintenance page's <title>, if any.
+	Title string
+}
+
+func (e *MaintenanceError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(ErrMaintenance.Error())
+	if e.Title != "" {
+		fmt.Fprintf(&sb, ": page %q", e.Title)
+	}
+	if !e.Until.IsZero() {
+		fmt.Fprintf(&sb, " (until %s)", e.Until.Local().Format(time.RFC3339))
+	} else {
+		sb.WriteString(" (try again later)")
+	}
+	return sb.String()
+}
+
+func (e *MaintenanceError) Unwrap() error {
+	return ErrMaintenance
+}
+
+// maintenanceFromResponse returns a [MaintenanceError] if the response is
+// Personio's maintenance page, which is either an HTTP 503 (Service
+// Unavailable) response or an HTML page with maintenance in its title.
+// Only the title is checked, as regular pages may announce upcoming
+// maintenance in their content.
+// The body is kept, so it can be read again.
+func maintenanceFromResponse(resp *http.Response, now time.Time) *MaintenanceError {
+	if resp == nil || resp.Body == nil {
+		return nil
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable && !isHTMLResponse(resp) {
+		return nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil && !errors.Is(err, ErrResponseTooLarge) {
+		return nil
+	}
+	title := newUnexpectedHTMLError(resp, body).Title
+	if resp.StatusCode != http.StatusServiceUnavailable && !maintenanceRegex.MatchString(title) {
+		return nil
+	}
+	return &MaintenanceError{
+		Title: title,
+		Until: parseMaintenanceEnd(resp, body, now),
+	}
+}
+
+// parseMaintenanceEnd returns the announced end of the maintenance, from the
+// Retry-After header or the first future timestamp on the page.
+func parseMaintenanceEnd(resp *http.Response, body []byte, now time.Time) time.Time {
+	if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), now); retryAfter > 0 {
+		return now.Add(retryAfter)
+	}
+	for _, match := range maintenanceEndRegex.FindAll(body, -1) {
+		s := strings.Replace(string(match), " ", "T", 1)
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05Z0700", "2006-01-02T15:04Z0700"} {
+			if t, err := time.Parse(layout, s); err == nil && t.After(now) {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceFromResponse(t *testing.T) {
+	now := time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		status    int
+		header    http.Header
+		body      string
+		wantMaint bool
+		wantUntil time.Time
+	}{
+		{
+			name:   "regular page",
+			status: http.StatusOK,
+			header: http.Header{"Content-Type": {"text/html"}},
+			body:   "<title>Login</title><p>Scheduled maintenance on Saturday</p>",
+		},
+		{
+			name:      "maintenance page with end time",
+			status:    http.StatusOK,
+			header:    http.Header{"Content-Type": {"text/html"}},
+			body:      "<title>Personio - Maintenance</title><p>Back at 2023-01-18T14:00:00Z</p>",
+			wantMaint: true,
+			wantUntil: time.Date(2023, 1, 18, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "service unavailable with retry after",
+			status:    http.StatusServiceUnavailable,
+			header:    http.Header{"Retry-After": {"600"}},
+			wantMaint: true,
+			wantUntil: now.Add(10 * time.Minute),
+		},
+		{
+			name:   "json",
+			status: http.StatusOK,
+			header: http.Header{"Content-Type": {"application/json"}},
+			body:   `{"success":true}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tc.status,
+				Header:     tc.header,
+				Body:       io.NopCloser(bytes.NewReader([]byte(tc.body))),
+			}
+			got := maintenanceFromResponse(resp, now)
+			if (got != nil) != tc.wantMaint {
+				t.Fatalf("want maintenance=%t, got %v", tc.wantMaint, got)
+			}
+			if got != nil && !got.Until.Equal(tc.wantUntil) {
+				t.Errorf("want until %s, got %s", tc.wantUntil, got.Until)
+			}
+			if body, _ := io.ReadAll(resp.Body); string(body) != tc.body {
+				t.Errorf("want body kept, got %q", body)
+			}
+		})
+	}
+}
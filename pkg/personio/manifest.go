@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed manifest.yaml
+var manifestYAML []byte
+
+// EndpointSpec is what's known about one of Personio's reverse-engineered
+// endpoints, from the manifest embedded into the program.
+type EndpointSpec struct {
+	// Name is the endpoint's field in [Endpoints].
+	Name   string `yaml:"name" json:"name"`
+	Method string `yaml:"method" json:"method"`
+	// Path is the default path, the same as in [DefaultEndpoints].
+	Path string `yaml:"path" json:"path"`
+	// Status is "stable", or "experimental" for endpoints that have not
+	// been verified against many tenants.
+	Status string `yaml:"status" json:"status"`
+	// LastVerified is when the endpoint was last checked against a live
+	// tenant, as "2006-01-02", or empty if unknown.
+	LastVerified string       `yaml:"lastVerified" json:"lastVerified,omitempty"`
+	Headers      []HeaderSpec `yaml:"headers" json:"headers,omitempty"`
+	Query        []FieldSpec  `yaml:"query" json:"query,omitempty"`
+	Request      []FieldSpec  `yaml:"request" json:"request,omitempty"`
+	Response     []FieldSpec  `yaml:"response" json:"response,omitempty"`
+	Notes        string       `yaml:"notes" json:"notes,omitempty"`
+}
+
+// HeaderSpec is a request header required by an endpoint.
+type HeaderSpec struct {
+	Name  string `yaml:"name" json:"name"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// FieldSpec is a field of a query, request, or response, where nested
+// fields are separated by dots, e.g "data.visitor.id", and array items are
+// marked with brackets, e.g "periods[].start".
+type FieldSpec struct {
+	Name        string `yaml:"name" json:"name"`
+	Type        string `yaml:"type" json:"type"`
+	Description string `yaml:"description" json:"description,omitempty"`
+}
+
+var (
+	manifestOnce sync.Once
+	manifest     []EndpointSpec
+	manifestErr  error
+)
+
+// Manifest returns the specs of all endpoints used by the client, in the
+// order they are usually used.
+func Manifest() ([]EndpointSpec, error) {
+	manifestOnce.Do(func() {
+		if err := yaml.Unmarshal(manifestYAML, &manifest); err != nil {
+			manifestErr = fmt.Errorf("parse endpoint manifest: %w", err)
+		}
+	})
+	return manifest, manifestErr
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Updated returns when and by which employee the period was last changed.
+// The time is zero and the employee ID is zero when Personio didn't say.
+//
+// The format of "updated_by" is undocumented, so an employee ID given as
+// a number, as a string, or as an object with an "id" field is accepted.
+func (p CalendarAttendancePeriod) Updated() (time.Time, int) {
+	var updatedAt time.Time
+	if p.Attributes.UpdatedAt != nil {
+		updatedAt = p.Attributes.UpdatedAt.Time
+	}
+	return updatedAt, parseEmployeeRef(p.Attributes.UpdatedBy)
+}
+
+func parseEmployeeRef(raw json.RawMessage) int {
+	if len(raw) == 0 {
+		return 0
+	}
+	var id int
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		id, _ = strconv.Atoi(s)
+		return id
+	}
+	var obj struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil && len(obj.ID) > 0 {
+		return parseEmployeeRef(obj.ID)
+	}
+	return 0
+}
+
+// ForeignChange is a period that someone else changed, such as an HR
+// correction, which overwriting the period would silently undo.
+type ForeignChange struct {
+	PeriodID  uuid.UUID `json:"periodId"`
+	Day       string    `json:"day"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+	// UpdatedBy is the employee that changed the period, or zero if only
+	// the time of the change is known.
+	UpdatedBy int `json:"updatedBy,omitempty"`
+}
+
+// FindForeignChanges returns the periods in the calendar that were changed
+// by another employee than ownEmployeeID, or that were changed after the
+// time they were written by us according to writtenAt, keyed by period ID.
+// Periods where Personio doesn't report who or when are never returned.
+//
+// The result is sorted by day.
+func FindForeignChanges(cal *AttendanceCalendar, ownEmployeeID int, writtenAt map[uuid.UUID]time.Time) []ForeignChange {
+	dayDates := make(map[uuid.UUID]string, len(cal.AttendanceDays.Data))
+	for _, d := range cal.AttendanceDays.Data {
+		dayDates[d.ID] = d.Attributes.Day.String()
+	}
+	var changes []ForeignChange
+	for _, p := range cal.AttendancePeriods.Data {
+		updatedAt, updatedBy := p.Updated()
+		foreign := updatedBy != 0 && updatedBy != ownEmployeeID
+		if written, ok := writtenAt[p.ID]; ok && !updatedAt.IsZero() &&
+			updatedAt.After(written.Add(writeClockSkew)) && updatedBy != ownEmployeeID {
+			foreign = true
+		}
+		if !foreign {
+			continue
+		}
+		day, ok := dayDates[p.Attributes.AttendanceDayID]
+		if !ok && !p.Attributes.Start.IsZero() {
+			day = p.Attributes.Start.Format(time.DateOnly)
+		}
+		changes = append(changes, ForeignChange{
+			PeriodID:  p.ID,
+			Day:       day,
+			UpdatedAt: updatedAt,
+			UpdatedBy: updatedBy,
+		})
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Day < changes[j].Day
+	})
+	return changes
+}
+
+// writeClockSkew is how much later than our own record of a write that
+// Personio may timestamp the same write, due to clock differences.
+const writeClockSkew = time.Minute
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseEmployeeRef(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want int
+	}{
+		{raw: ``, want: 0},
+		{raw: `null`, want: 0},
+		{raw: `123`, want: 123},
+		{raw: `"123"`, want: 123},
+		{raw: `{"id": 123, "name": "HR"}`, want: 123},
+		{raw: `{"id": "123"}`, want: 123},
+		{raw: `"someone"`, want: 0},
+	}
+	for _, tc := range tests {
+		if got := parseEmployeeRef(json.RawMessage(tc.raw)); got != tc.want {
+			t.Errorf("%q: want %d, got %d", tc.raw, tc.want, got)
+		}
+	}
+}
+
+func TestFindForeignChanges(t *testing.T) {
+	const me, hr = 1, 2
+	dayID := uuid.New()
+	written := time.Date(2023, 1, 18, 18, 0, 0, 0, time.UTC)
+	period := func(updatedAt string, updatedBy int) CalendarAttendancePeriod {
+		p := CalendarAttendancePeriod{ID: uuid.New()}
+		p.Attributes.AttendanceDayID = dayID
+		p.Attributes.Start = NewFlexibleTime(time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC))
+		if updatedAt != "" {
+			at, _ := ParseFlexibleTime(updatedAt)
+			p.Attributes.UpdatedAt = &at
+		}
+		if updatedBy != 0 {
+			p.Attributes.UpdatedBy, _ = json.Marshal(updatedBy)
+		}
+		return p
+	}
+	byHR := period("2023-01-19T09:00:00Z", hr)
+	byMe := period("2023-01-19T09:00:00Z", me)
+	unknown := period("", 0)
+	laterUnknownAuthor := period("2023-01-19T09:00:00Z", 0)
+	sameWrite := period("2023-01-18T18:00:30Z", 0)
+
+	cal := &AttendanceCalendar{}
+	cal.AttendanceDays.Data = []CalendarDay{{ID: dayID, Attributes: CalendarDayAttributes{Day: NewDate(2023, 1, 18)}}}
+	cal.AttendancePeriods.Data = []CalendarAttendancePeriod{byHR, byMe, unknown, laterUnknownAuthor, sameWrite}
+
+	changes := FindForeignChanges(cal, me, map[uuid.UUID]time.Time{
+		laterUnknownAuthor.ID: written,
+		sameWrite.ID:          written,
+		byMe.ID:               written,
+	})
+	if len(changes) != 2 {
+		t.Fatalf("want 2 changes, got %+v", changes)
+	}
+	if changes[0].PeriodID != byHR.ID || changes[0].UpdatedBy != hr || changes[0].Day != "2023-01-18" {
+		t.Errorf("want change by HR first, got %+v", changes[0])
+	}
+	if changes[1].PeriodID != laterUnknownAuthor.ID {
+		t.Errorf("want change after our write second, got %+v", changes[1])
+	}
+}
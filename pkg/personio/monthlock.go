@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ErrMonthLocked = errors.New("month locked by payroll")
+
+// DayStatusLocked is the status of attendance days in months that have
+// been locked after a payroll run.
+const DayStatusLocked = "locked"
+
+var monthLockMessageRegex = regexp.MustCompile(`(?i)\b(?:locked|closed|finali[sz]ed)\b|payroll|abgeschlossen|gesperrt`)
+
+// MonthLockedError is returned when changing attendance in a month that
+// Personio has locked after a payroll run. It wraps [ErrMonthLocked].
+type MonthLockedError struct {
+	// Month is the first day of the locked month.
+	Month time.Time
+	// Message is the error message from Personio, if any.
+	Message string
+}
+
+func (e *MonthLockedError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %s cannot be changed anymore", ErrMonthLocked, e.Month.Format("2006-01"))
+	if e.Message != "" {
+		fmt.Fprintf(&sb, " (Personio responsed with: %s)", e.Message)
+	}
+	return sb.String()
+}
+
+func (e *MonthLockedError) Unwrap() error {
+	return ErrMonthLocked
+}
+
+// monthLockFromError returns a [MonthLockedError] if the error is
+// Personio's HTTP 422 (Unprocessable Entity) response about the month
+// being locked, or else returns the error as-is.
+func monthLockFromError(err error, date time.Time) error {
+	var personioErr Error
+	if !errors.As(err, &personioErr) {
+		return err
+	}
+	if personioErr.Code != http.StatusUnprocessableEntity &&
+		(personioErr.Response == nil || personioErr.Response.StatusCode != http.StatusUnprocessableEntity) {
+		return err
+	}
+	messages := []string{personioErr.Message}
+	for _, errs := range personioErr.ErrorData {
+		messages = append(messages, errs...)
+	}
+	for _, msg := range messages {
+		if monthLockMessageRegex.MatchString(msg) {
+			return &MonthLockedError{Month: monthStart(date), Message: msg}
+		}
+	}
+	return err
+}
+
+// IsMonthLocked returns true if the month of the date is known to be
+// locked, either from the status of its attendance days or from an
+// earlier rejected change. It does not send any request.
+func (c *Client) IsMonthLocked(date time.Time) bool {
+	c.lockedMonthsMu.Lock()
+	defer c.lockedMonthsMu.Unlock()
+	return c.lockedMonths[monthStart(date).Format("2006-01")]
+}
+
+// checkMonthLock fails early on changes in months known to be locked,
+// without sending any request.
+func (c *Client) checkMonthLock(date time.Time) error {
+	if c.IsMonthLocked(date) {
+		return &MonthLockedError{Month: monthStart(date)}
+	}
+	return nil
+}
+
+// wrapMonthLock converts the error to a [MonthLockedError] if it's about
+// the month being locked, and remembers the month as locked.
+func (c *Client) wrapMonthLock(err error, date time.Time) error {
+	err = monthLockFromError(err, date)
+	var lockErr *MonthLockedError
+	if errors.As(err, &lockErr) {
+		c.setMonthLocked(lockErr.Month)
+	}
+	return err
+}
+
+func (c *Client) cacheLockedMonths(days []CalendarDay) {
+	for _, day := range days {
+		if day.Attributes.Status != DayStatusLocked {
+			continue
+		}
+		if day.Attributes.Day.IsZero() {
+			continue
+		}
+		c.setMonthLocked(day.Attributes.Day.Time)
+	}
+}
+
+func (c *Client) setMonthLocked(date time.Time) {
+	month := monthStart(date).Format("2006-01")
+	c.lockedMonthsMu.Lock()
+	defer c.lockedMonthsMu.Unlock()
+	if c.lockedMonths == nil {
+		c.lockedMonths = map[string]bool{}
+	}
+	if !c.lockedMonths[month] {
+		c.log().Debug().Str("month", month).Msg("Cached month as locked by payroll.")
+	}
+	c.lockedMonths[month] = true
+}
+
+func monthStart(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+}
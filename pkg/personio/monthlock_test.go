@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMonthLockFromError(t *testing.T) {
+	date := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "locked message",
+			err:  Error{Code: 422, Message: "The attendance period is locked by payroll."},
+			want: true,
+		},
+		{
+			name: "locked error data",
+			err: Error{Code: 0, Message: "Validation failed.",
+				ErrorData: map[string][]string{"day": {"This month has been closed."}},
+				Response:  &http.Response{StatusCode: http.StatusUnprocessableEntity}},
+			want: true,
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("set attendance: %w", Error{Code: 422, Message: "Monat abgeschlossen"}),
+			want: true,
+		},
+		{
+			name: "other validation",
+			err:  Error{Code: 422, Message: "Periods must not overlap."},
+			want: false,
+		},
+		{
+			name: "other status",
+			err:  Error{Code: 403, Message: "Account locked."},
+			want: false,
+		},
+		{
+			name: "not a personio error",
+			err:  errors.New("locked"),
+			want: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := monthLockFromError(tc.err, date)
+			var lockErr *MonthLockedError
+			if errors.As(got, &lockErr) != tc.want {
+				t.Fatalf("want month locked %t, got %v", tc.want, got)
+			}
+			if !tc.want {
+				return
+			}
+			if !errors.Is(got, ErrMonthLocked) {
+				t.Errorf("want error to wrap ErrMonthLocked")
+			}
+			if want := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC); !lockErr.Month.Equal(want) {
+				t.Errorf("want month %s, got %s", want, lockErr.Month)
+			}
+		})
+	}
+}
+
+func TestCacheLockedMonths(t *testing.T) {
+	client := &Client{}
+	client.cacheLockedMonths([]CalendarDay{
+		{Attributes: CalendarDayAttributes{Day: NewDate(2023, 1, 31), Status: DayStatusLocked}},
+		{Attributes: CalendarDayAttributes{Day: NewDate(2023, 2, 1), Status: "confirmed"}},
+	})
+	if !client.IsMonthLocked(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Error("want January locked")
+	}
+	if client.IsMonthLocked(time.Date(2023, 2, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Error("want February not locked")
+	}
+	if err := client.checkMonthLock(time.Date(2023, 1, 20, 0, 0, 0, 0, time.UTC)); !errors.Is(err, ErrMonthLocked) {
+		t.Errorf("want ErrMonthLocked, got %v", err)
+	}
+}
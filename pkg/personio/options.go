@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Option configures a [Client] created by [New].
+//
+// Most options have a matching setter method, such as [WithTimeouts] and
+// [Client.SetTimeouts]. Prefer the options when creating a client, and use
+// the setters to reconfigure an existing client, such as to wrap its
+// transport in a cache after the config is loaded.
+type Option func(*Client)
+
+// WithHTTPClient sets the [http.Client] used for all requests. Its cookie
+// jar is replaced with a new one if nil, as the session relies on cookies,
+// and its transport is used as-is.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		if client == nil {
+			return
+		}
+		jar := c.http.Jar
+		clone := *client
+		if clone.Jar == nil {
+			clone.Jar = jar
+		}
+		c.http = &clone
+	}
+}
+
+// WithTransport sets the [http.RoundTripper] used for all HTTP requests,
+// as described in [Client.SetTransport].
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.SetTransport(transport)
+	}
+}
+
+// WithTimeouts sets the timeouts of requests, as described in
+// [Client.SetTimeouts].
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(c *Client) {
+		c.SetTimeouts(timeouts)
+	}
+}
+
+// WithEndpoints overrides the endpoint paths, as described in
+// [Client.SetEndpoints].
+func WithEndpoints(endpoints Endpoints) Option {
+	return func(c *Client) {
+		c.SetEndpoints(endpoints)
+	}
+}
+
+// WithMaxResponseSize sets the limit of decoded response bodies in bytes,
+// as described in [Client.SetMaxResponseSize].
+func WithMaxResponseSize(size int64) Option {
+	return func(c *Client) {
+		c.SetMaxResponseSize(size)
+	}
+}
+
+// WithJar sets the [http.CookieJar] used to store cookies, such as to
+// persist them between runs. See also [WithSessionStore].
+func WithJar(jar http.CookieJar) Option {
+	return func(c *Client) {
+		if jar != nil {
+			c.http.Jar = jar
+		}
+	}
+}
+
+// WithLogger sets the logger of the client's debug and trace logs, instead
+// of zerolog's global logger.
+func WithLogger(logger zerolog.Logger) Option {
+	return func(c *Client) {
+		c.logger = &logger
+	}
+}
+
+// WithHeaderProfile sets the headers added to every request, unless the
+// request already sets them.
+func WithHeaderProfile(profile HeaderProfile) Option {
+	return func(c *Client) {
+		c.headers = profile
+	}
+}
+
+// WithRateLimit limits the client to send at most the given number of
+// requests per duration, with bursts of up to that many requests.
+// Requests beyond the limit wait for their turn. Zero or negative values
+// disable the limit.
+func WithRateLimit(requests int, per time.Duration) Option {
+	return func(c *Client) {
+		c.limiter = newRequestLimiter(requests, per)
+	}
+}
+
+// WithRequestBudget limits the client to send at most the given number of
+// requests in any window of the duration, such as 600 per hour, with
+// bursts of up to burst requests. Unlike [WithRateLimit], the ceiling holds
+// even right after a burst, as the burst is taken from the budget. Zero or
+// negative burst, or a burst larger than the budget, uses a tenth of the
+// budget. Zero or negative requests disable the limit.
+func WithRequestBudget(requests int, per time.Duration, burst int) Option {
+	return func(c *Client) {
+		c.SetRequestBudget(requests, per, burst)
+	}
+}
+
+// SetRequestBudget changes the request budget of the client, as described
+// in [WithRequestBudget].
+func (c *Client) SetRequestBudget(requests int, per time.Duration, burst int) {
+	c.limiter = newBudgetLimiter(requests, per, burst)
+	c.budget = 0
+	if c.limiter != nil {
+		c.budget = requests
+	}
+}
+
+// RequestBudget returns how many requests can be sent right away, and the
+// budget set via [WithRequestBudget], or zeros when there is no budget.
+func (c *Client) RequestBudget() (available, budget int) {
+	if c.budget == 0 {
+		return 0, 0
+	}
+	return c.limiter.available(), c.budget
+}
+
+// WithSessionStore stores cookies in the session store, which is saved
+// after each successful [Client.Login], such as a session.Jar.
+func WithSessionStore(store SessionStore) Option {
+	return func(c *Client) {
+		if store != nil {
+			c.http.Jar = store
+			c.sessionStore = store
+		}
+	}
+}
+
+// SessionStore is a cookie jar that can persist its cookies.
+type SessionStore interface {
+	http.CookieJar
+	Save() error
+}
+
+// HeaderProfile is a set of headers added to every request.
+type HeaderProfile struct {
+	// Name identifies the profile in logs.
+	Name    string
+	Headers http.Header
+}
+
+// HeaderProfileFirefox makes requests look like they're from a desktop
+// Firefox web browser, for tenants that block unknown user agents.
+var HeaderProfileFirefox = HeaderProfile{
+	Name: "firefox",
+	Headers: http.Header{
+		"User-Agent":      {"Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0"},
+		"Accept-Language": {"en-US,en;q=0.5"},
+	},
+}
+
+func (c *Client) setProfileHeaders(headers http.Header) {
+	for key, values := range c.headers.Headers {
+		if len(values) > 0 {
+			setHeaderDefault(headers, key, values[0])
+		}
+	}
+}
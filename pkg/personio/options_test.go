@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	transport := &http.Transport{}
+	timeouts := Timeouts{Read: time.Second}
+	c, err := New("https://example.personio.de",
+		WithTransport(transport),
+		WithTimeouts(timeouts),
+		WithEndpoints(Endpoints{EmployeeHeader: "/v2/employees/{employeeId}/header"}),
+		WithMaxResponseSize(1024),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.http.Transport != transport {
+		t.Error("want transport from option")
+	}
+	if c.timeouts != timeouts {
+		t.Errorf("want timeouts %+v, got %+v", timeouts, c.timeouts)
+	}
+	if got := c.Endpoints(); got.EmployeeHeader != "/v2/employees/{employeeId}/header" || got.Login != DefaultEndpoints.Login {
+		t.Errorf("want overridden employee header and default login endpoint, got %+v", got)
+	}
+	if c.maxResponseSize != 1024 {
+		t.Errorf("want max response size 1024, got %d", c.maxResponseSize)
+	}
+}
@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Permissions are what the logged in account may do with an employee's
+// attendance, as reported by the "attendance_rights" in Personio's
+// attendance calendar.
+type Permissions struct {
+	// EmployeeID is the employee that the permissions apply to.
+	EmployeeID int
+	// Reported is true if Personio reported any of the known rights. When
+	// false, the other fields are unknown and the checks always pass, and
+	// Personio gets to decide instead.
+	Reported bool
+
+	CanView   bool
+	CanCreate bool
+	CanEdit   bool
+	CanDelete bool
+
+	// Raw are all rights as reported by Personio, including the ones that
+	// are not known by this client.
+	Raw map[string]bool
+}
+
+// Permissions returns the permissions from the calendar's attendance rights.
+//
+// The rights are matched on the verbs in their names, such as
+// "can_edit_attendance" or "edit", as Personio's naming is undocumented.
+func (cal *AttendanceCalendar) Permissions(employeeID int) *Permissions {
+	perms := &Permissions{
+		EmployeeID: employeeID,
+		Raw:        cal.AttendanceRights,
+	}
+	for name, allowed := range cal.AttendanceRights {
+		var field *bool
+		switch lower := strings.ToLower(name); {
+		case strings.Contains(lower, "view"), strings.Contains(lower, "read"):
+			field = &perms.CanView
+		case strings.Contains(lower, "create"), strings.Contains(lower, "add"):
+			field = &perms.CanCreate
+		case strings.Contains(lower, "edit"), strings.Contains(lower, "update"), strings.Contains(lower, "write"):
+			field = &perms.CanEdit
+		case strings.Contains(lower, "delete"), strings.Contains(lower, "remove"):
+			field = &perms.CanDelete
+		default:
+			continue
+		}
+		perms.Reported = true
+		*field = *field || allowed
+	}
+	return perms
+}
+
+// CheckView returns an error wrapping [ErrPermissionDenied] if the account
+// is known to not be allowed to view the employee's attendance.
+func (p *Permissions) CheckView(ownEmployeeID int) error {
+	if !p.Reported || p.CanView || p.CanEdit {
+		return nil
+	}
+	return p.denied(ownEmployeeID, "view")
+}
+
+// CheckEdit returns an error wrapping [ErrPermissionDenied] if the account
+// is known to not be allowed to create or edit the employee's attendance.
+func (p *Permissions) CheckEdit(ownEmployeeID int) error {
+	if !p.Reported || p.CanEdit || p.CanCreate {
+		return nil
+	}
+	return p.denied(ownEmployeeID, "edit")
+}
+
+// CheckDelete returns an error wrapping [ErrPermissionDenied] if the
+// account is known to not be allowed to delete the employee's attendance.
+func (p *Permissions) CheckDelete(ownEmployeeID int) error {
+	if !p.Reported || p.CanDelete || p.CanEdit {
+		return nil
+	}
+	return p.denied(ownEmployeeID, "delete")
+}
+
+func (p *Permissions) denied(ownEmployeeID int, action string) error {
+	if p.EmployeeID == ownEmployeeID {
+		return fmt.Errorf("%w: your account cannot %s your own attendance", ErrPermissionDenied, action)
+	}
+	return fmt.Errorf("%w: your account cannot %s attendance for other employees (employee %d)", ErrPermissionDenied, action, p.EmployeeID)
+}
+
+// GetPermissions returns what the logged in account may do with an
+// employee's attendance. The permissions are cached from any previous
+// attendance calendar request, or else fetched from today's calendar.
+func (c *Client) GetPermissions(employeeID int) (*Permissions, error) {
+	if perms, ok := c.cachedPermissions(employeeID); ok {
+		return perms, nil
+	}
+	today := time.Now()
+	cal, err := c.GetAttendanceCalendar(employeeID, today, today)
+	if err != nil {
+		return nil, err
+	}
+	return cal.Permissions(employeeID), nil
+}
+
+func (c *Client) cachedPermissions(employeeID int) (*Permissions, bool) {
+	c.permissionsMu.Lock()
+	defer c.permissionsMu.Unlock()
+	perms, ok := c.permissions[employeeID]
+	return perms, ok
+}
+
+// checkCachedPermissions runs the check on the permissions that are
+// already cached, without sending any request.
+func (c *Client) checkCachedPermissions(employeeID int, check func(p *Permissions, ownEmployeeID int) error) error {
+	perms, ok := c.cachedPermissions(employeeID)
+	if !ok {
+		return nil
+	}
+	return check(perms, c.EmployeeID)
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCalendarPermissions(t *testing.T) {
+	cal := &AttendanceCalendar{AttendanceRights: map[string]bool{
+		"can_view_attendance":   true,
+		"can_create_attendance": false,
+		"can_edit_attendance":   false,
+		"can_delete_attendance": false,
+		"can_approve":           true,
+	}}
+	perms := cal.Permissions(42)
+	if !perms.Reported || !perms.CanView || perms.CanCreate || perms.CanEdit || perms.CanDelete {
+		t.Fatalf("wrong permissions: %+v", perms)
+	}
+
+	err := perms.CheckEdit(1)
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("want ErrPermissionDenied, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "cannot edit attendance for other employees") {
+		t.Errorf("want message about other employees, got: %s", err)
+	}
+	if err := perms.CheckDelete(42); err == nil || !strings.Contains(err.Error(), "your own attendance") {
+		t.Errorf("want message about own attendance, got: %v", err)
+	}
+}
+
+func TestCalendarPermissionsNotReported(t *testing.T) {
+	perms := (&AttendanceCalendar{}).Permissions(42)
+	if perms.Reported {
+		t.Error("want not reported")
+	}
+	if err := perms.CheckEdit(1); err != nil {
+		t.Errorf("want no error when not reported, got: %s", err)
+	}
+}
@@ -32,10 +32,14 @@ import (
 	"net/http/cookiejar"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/applejag/rootless-personio/pkg/anonymize"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
 )
 
 var (
@@ -49,6 +53,7 @@ var (
 	ErrNotLoggedIn        = errors.New("not logged in")
 	ErrNon2xxStatusCode   = errors.New("non-2xx status code")
 	ErrUnlockRequired     = errors.New("unlock required")
+	ErrInvalidCredentials = errors.New("invalid email or password")
 )
 
 type Client struct {
@@ -56,9 +61,41 @@ type Client struct {
 	http       *http.Client
 	EmployeeID int
 	dayIDCache map[string]*uuid.UUID
+	dayIDStore DayIDStore
+	// permissions are cached from all attendance calendar responses,
+	// which may be fetched concurrently
+	permissions   map[int]*Permissions
+	permissionsMu sync.Mutex
+	// onBehalfOf is the employee that the attendance methods act on,
+	// or zero for the logged in employee
+	onBehalfOf int
+	// rateLimit is from the latest response with rate limit headers
+	rateLimit    RateLimit
+	hasRateLimit bool
+	rateLimitMu  sync.Mutex
+	// maxResponseSize is the limit of decoded response bodies in bytes
+	maxResponseSize int64
+	endpoints       Endpoints
+	timeouts        Timeouts
+	// logger is nil to use zerolog's global logger
+	logger       *zerolog.Logger
+	headers      HeaderProfile
+	limiter      *requestLimiter
+	budget       int // requests per window, set via WithRequestBudget
+	sessionStore SessionStore
+	// lockedMonths are the months ("2006-01") known to be locked by payroll
+	lockedMonths   map[string]bool
+	lockedMonthsMu sync.Mutex
+	// failureObserver is called for each failed request, if set
+	failureObserver func(Failure)
+	capabilities    capabilityMap
+	// flights deduplicates concurrent identical GET requests
+	flights singleflight.Group
 }
 
-func New(baseURL string) (*Client, error) {
+// New returns a client for the Personio instance at the base URL, such as
+// "https://example.personio.de", configured by the options.
+func New(baseURL string, opts ...Option) (*Client, error) {
 	normalURL, err := NormalizeBaseURL(baseURL)
 	if err != nil {
 		return nil, err
@@ -67,11 +104,66 @@ func New(baseURL string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Client{
-		http:       &http.Client{Jar: jar},
-		BaseURL:    normalURL,
-		dayIDCache: make(map[string]*uuid.UUID),
-	}, nil
+	c := &Client{
+		http:        &http.Client{Jar: jar, Transport: NewTransport()},
+		BaseURL:     normalURL,
+		dayIDCache:  make(map[string]*uuid.UUID),
+		permissions: make(map[int]*Permissions),
+
+		maxResponseSize: DefaultMaxResponseSize,
+		endpoints:       DefaultEndpoints,
+		timeouts:        DefaultTimeouts,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+func (c *Client) log() *zerolog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return &log.Logger
+}
+
+// ActOnBehalfOf makes the attendance methods read and write the attendance
+// of another employee, which requires an account with admin rights to that
+// employee's attendance. Zero means the logged in employee.
+func (c *Client) ActOnBehalfOf(employeeID int) {
+	if employeeID == c.EmployeeID {
+		employeeID = 0
+	}
+	if employeeID != c.onBehalfOf {
+		// Day IDs are per employee
+		c.dayIDCache = make(map[string]*uuid.UUID)
+	}
+	c.onBehalfOf = employeeID
+}
+
+// TargetEmployeeID returns the employee that the attendance methods act on,
+// which is the logged in employee unless changed via [Client.ActOnBehalfOf].
+func (c *Client) TargetEmployeeID() int {
+	if c.onBehalfOf != 0 {
+		return c.onBehalfOf
+	}
+	return c.EmployeeID
+}
+
+// SetTransport changes the [http.RoundTripper] used for all HTTP requests,
+// such as to add caching. A nil value uses a new transport from
+// [NewTransport].
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	if transport == nil {
+		transport = NewTransport()
+	}
+	c.http.Transport = transport
+}
+
+// SetCookieJar changes the [http.CookieJar] used to store cookies, such as
+// to persist them between runs.
+func (c *Client) SetCookieJar(jar http.CookieJar) {
+	c.http.Jar = jar
 }
 
 func (c *Client) csrfToken(u *url.URL) (string, bool) {
@@ -84,10 +176,27 @@ func (c *Client) csrfToken(u *url.URL) (string, bool) {
 	return "", false
 }
 
+// RawJSON sends a JSON request. Compressed responses are decoded
+// transparently, and reading a response body larger than the max response
+// size fails with a [ResponseTooLargeError].
+//
+// An HTML response, such as the login page after the session has expired,
+// results in an [UnexpectedHTMLError] together with the response.
 func (c *Client) RawJSON(req *http.Request) (*http.Response, error) {
 	setHeaderDefault(req.Header, "Content-Type", "application/json")
 	setHeaderDefault(req.Header, "Accept", "application/json")
-	return c.Raw(req)
+	setHeaderDefault(req.Header, "Accept-Encoding", "gzip, deflate")
+	endpoint := c.endpoints.Name(req.URL.Path)
+	resp, err := c.Raw(req)
+	if resp != nil && isHTMLResponse(resp) {
+		htmlErr := unexpectedHTML(resp)
+		if err == nil {
+			// Raw only observed the errors it returned
+			c.observeFailure(endpoint, req, resp, htmlErr)
+		}
+		return resp, htmlErr
+	}
+	return resp, err
 }
 
 func (c *Client) RawForm(req *http.Request) (*http.Response, error) {
@@ -96,6 +205,17 @@ func (c *Client) RawForm(req *http.Request) (*http.Response, error) {
 }
 
 func (c *Client) Raw(req *http.Request) (*http.Response, error) {
+	endpoint := c.endpoints.Name(req.URL.Path)
+	if err := c.capabilities.check(endpoint, time.Now()); err != nil {
+		return nil, err
+	}
+	resp, err := c.raw(req)
+	c.observeFailure(endpoint, req, resp, err)
+	c.noteCapability(endpoint, resp, err)
+	return resp, err
+}
+
+func (c *Client) raw(req *http.Request) (*http.Response, error) {
 	u, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("parse base URL: %w", err)
@@ -111,9 +231,18 @@ func (c *Client) Raw(req *http.Request) (*http.Response, error) {
 	if token, ok := c.csrfToken(req.URL); ok {
 		setHeaderDefault(req.Header, "X-CSRF-Token", token)
 	}
+	c.setProfileHeaders(req.Header)
 	setHeaderDefault(req.Header, "Accept", "application/json, text/plain, */*")
 
-	resp, err := DoRequest(c.http, req)
+	resp, err := c.doWithTimeout(req)
+	c.recordRateLimit(resp)
+	if wrapErr := wrapResponseBody(resp, c.maxResponseSize); wrapErr != nil {
+		resp.Body.Close()
+		return nil, wrapErr
+	}
+	if maintErr := maintenanceFromResponse(resp, time.Now()); maintErr != nil {
+		return resp, maintErr
+	}
 
 	if errors.Is(err, ErrNon2xxStatusCode) && resp != nil {
 		_, parsedErr := ParseResponseJSON[any](resp)
@@ -152,54 +281,64 @@ func NormalizeBaseURL(baseURL string) (string, error) {
 }
 
 func ParseResponseJSON[M any](resp *http.Response) (M, error) {
-	var zero M // only returned on fail
+	env, err := ParseEnvelopeJSON[M](resp)
+	if err != nil {
+		var zero M // only returned on fail
+		return zero, err
+	}
+	return env.Data, nil
+}
 
+// ParseEnvelopeJSON parses the full response envelope, for endpoints where
+// the pagination meta or links are needed in addition to the data.
+func ParseEnvelopeJSON[M any](resp *http.Response) (*Envelope[M], error) {
 	contentType := resp.Header.Get("Content-Type")
 	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		return zero, fmt.Errorf("parse Content-Type header: %w", err)
+		return nil, fmt.Errorf("parse Content-Type header: %w", err)
+	}
+	if mediaType == "text/html" {
+		return nil, unexpectedHTML(resp)
 	}
 	if mediaType != "application/json" {
-		return zero, fmt.Errorf("expected JSON response, but got %q", mediaType)
+		return nil, fmt.Errorf("expected JSON response, but got %q", mediaType)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return zero, fmt.Errorf("read body: %w", err)
+		return nil, fmt.Errorf("read body: %w", err)
 	}
 	// Redefine body, so it can be read again, if needed by caller
 	resp.Body = io.NopCloser(bytes.NewReader(body))
 
-	var typedBody struct {
-		Success *bool `json:"success"`
-		Error   struct {
-			Code      int                 `json:"code"`
-			Message   string              `json:"message"`
-			ErrorData map[string][]string `json:"error_data"`
-		} `json:"error"`
-		Data M `json:"data"`
-	}
-	if err := json.Unmarshal(body, &typedBody); err != nil {
-		return zero, fmt.Errorf("parse body: %w", err)
-	}
-
-	if typedBody.Success != nil && !*typedBody.Success {
-		return zero, Error{
-			Code:      typedBody.Error.Code,
-			Message:   typedBody.Error.Message,
-			ErrorData: typedBody.Error.ErrorData,
-			Response:  resp,
-		}
+	if looksLikeHTML(body) {
+		return nil, newUnexpectedHTMLError(resp, body)
 	}
 
-	return typedBody.Data, nil
+	var env Envelope[M]
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, fmt.Errorf("parse body: %w", err)
+	}
+	if err := env.Err(resp); err != nil {
+		return nil, err
+	}
+	return &env, nil
 }
 
 func DoRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	return doRequest(client, req, &log.Logger)
+}
+
+// doRequest sends the request, and logs it at trace level with the
+// logger from the request's context if any, or else the given logger.
+func doRequest(client *http.Client, req *http.Request, logger *zerolog.Logger) (*http.Response, error) {
 	setHeaderDefault(req.Header, "User-Agent", UserAgent)
+	if ctxLogger := zerolog.Ctx(req.Context()); ctxLogger.GetLevel() != zerolog.Disabled {
+		logger = ctxLogger
+	}
 
-	if log.Logger.GetLevel() <= zerolog.TraceLevel {
-		logRequest(req)
+	if logger.GetLevel() <= zerolog.TraceLevel {
+		logRequest(logger, req)
 	}
 
 	resp, err := client.Do(req)
@@ -207,8 +346,8 @@ func DoRequest(client *http.Client, req *http.Request) (*http.Response, error) {
 		return nil, fmt.Errorf("HTTP request: %w", err)
 	}
 
-	if log.Logger.GetLevel() <= zerolog.TraceLevel {
-		logRespone(resp)
+	if logger.GetLevel() <= zerolog.TraceLevel {
+		logRespone(logger, resp)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
@@ -217,29 +356,29 @@ func DoRequest(client *http.Client, req *http.Request) (*http.Response, error) {
 	return resp, nil
 }
 
-func logRequest(req *http.Request) {
+func logRequest(logger *zerolog.Logger, req *http.Request) {
 	var sb strings.Builder
 	sb.WriteString("Request:")
 	fmt.Fprintf(&sb, "\n\t> %s %s %s", req.Method, req.URL.RequestURI(), req.Proto)
 	fmt.Fprintf(&sb, "\n\t> Host: %s", req.URL.Hostname())
 	for key, values := range req.Header {
 		for _, value := range values {
-			fmt.Fprintf(&sb, "\n\t> %s: %s", key, value)
+			fmt.Fprintf(&sb, "\n\t> %s: %s", key, anonymize.Header(key, value))
 		}
 	}
-	log.Trace().Msg(sb.String())
+	logger.Trace().Msg(sb.String())
 }
 
-func logRespone(resp *http.Response) {
+func logRespone(logger *zerolog.Logger, resp *http.Response) {
 	var sb strings.Builder
 	sb.WriteString("Response:")
 	fmt.Fprintf(&sb, "\n\t< %s %s", resp.Proto, resp.Status)
 	for key, values := range resp.Header {
 		for _, value := range values {
-			fmt.Fprintf(&sb, "\n\t< %s: %s", key, value)
+			fmt.Fprintf(&sb, "\n\t< %s: %s", key, anonymize.Header(key, value))
 		}
 	}
-	log.Trace().Msg(sb.String())
+	logger.Trace().Msg(sb.String())
 }
 
 type Error struct {
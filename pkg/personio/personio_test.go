@@ -17,7 +17,17 @@
 
 package personio
 
-import "testing"
+import (
+	"bytes"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
 
 func TestNormalizeBaseURL(t *testing.T) {
 	var tests = []struct {
@@ -59,3 +69,160 @@ func TestNormalizeBaseURL(t *testing.T) {
 		})
 	}
 }
+
+func TestTenantBaseURL(t *testing.T) {
+	var tests = []struct {
+		name       string
+		host       string
+		pathPrefix string
+		want       string
+	}{
+		{
+			name: "only host",
+			host: "example.personio.de",
+			want: "https://example.personio.de",
+		},
+		{
+			name:       "with path prefix",
+			host:       "personio.example.com",
+			pathPrefix: "/some/reverse/proxy/",
+			want:       "https://personio.example.com/some/reverse/proxy",
+		},
+		{
+			name:       "path prefix without leading slash",
+			host:       "personio.example.com",
+			pathPrefix: "personio",
+			want:       "https://personio.example.com/personio",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := TenantBaseURL(tc.host, tc.pathPrefix)
+			if err != nil {
+				t.Fatalf("want %q, got error: %s", tc.want, err)
+			}
+			if got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateTenantSlug(t *testing.T) {
+	for _, slug := range []string{"example", "my-company", "company42"} {
+		if err := ValidateTenantSlug(slug); err != nil {
+			t.Errorf("want %q to be valid, got error: %s", slug, err)
+		}
+	}
+	for _, slug := range []string{"", "-example", "example-", "Example", "example.personio.de"} {
+		if err := ValidateTenantSlug(slug); err == nil {
+			t.Errorf("want %q to be invalid, got no error", slug)
+		}
+	}
+}
+
+func TestParseUnlockToken(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "code",
+			input: " abc123 \n",
+			want:  "abc123",
+		},
+		{
+			name:  "link with query",
+			input: "https://example.personio.de/login/token-auth?token=abc123",
+			want:  "abc123",
+		},
+		{
+			name:  "link with path",
+			input: "https://example.personio.de/login/token-auth/abc123/",
+			want:  "abc123",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ParseUnlockToken(tc.input); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header
+	}))
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(srv.URL,
+		WithHTTPClient(&http.Client{Timeout: time.Second}),
+		WithJar(jar),
+		WithHeaderProfile(HeaderProfileFirefox),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.http.Jar != jar {
+		t.Error("want the cookie jar from WithJar")
+	}
+	if c.http.Timeout != time.Second {
+		t.Error("want the HTTP client from WithHTTPClient")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Raw(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := gotHeader.Get("User-Agent"), HeaderProfileFirefox.Headers.Get("User-Agent"); got != want {
+		t.Errorf("want User-Agent %q, got %q", want, got)
+	}
+}
+
+func TestRawRedactsSecretsInLogs(t *testing.T) {
+	const secret = "s3cr3t-value"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: secret})
+		http.SetCookie(w, &http.Cookie{Name: "personio_session", Value: secret})
+	}))
+	defer srv.Close()
+
+	for _, level := range []zerolog.Level{zerolog.TraceLevel, zerolog.DebugLevel, zerolog.InfoLevel} {
+		var buf bytes.Buffer
+		c, err := New(srv.URL, WithLogger(zerolog.New(&buf).Level(level)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			// Second request sends the cookies and CSRF token
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+secret)
+			resp, err := c.Raw(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("level %s: secret in logs:\n%s", level, buf.String())
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultPreflightTimeout is the time a [Preflight] gets by default, which
+// is short enough to fail fast when a VPN is required but not connected.
+const DefaultPreflightTimeout = 3 * time.Second
+
+// ErrUnreachable is wrapped by [PreflightError] when the base URL could not
+// be reached.
+var ErrUnreachable = errors.New("cannot reach Personio")
+
+// PreflightError is returned by [Preflight.Check] when one of its steps
+// fails. It wraps [ErrUnreachable].
+type PreflightError struct {
+	Host string
+	// Step is the check that failed: "dns lookup", "tls handshake", or
+	// "head request".
+	Step string
+	Err  error
+}
+
+// Error implements [error].
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("cannot reach %s: check VPN: %s: %v", e.Host, e.Step, e.Err)
+}
+
+// Unwrap returns the error of the failed step.
+func (e *PreflightError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes [errors.Is] match [ErrUnreachable].
+func (e *PreflightError) Is(target error) bool {
+	return target == ErrUnreachable
+}
+
+// Preflight checks that a base URL is reachable by resolving its host,
+// doing a TLS handshake, and sending a HEAD request, all within a short
+// timeout. It lets long flows like logging in fail within seconds instead
+// of hanging until the request timeout, such as when a VPN is required.
+type Preflight struct {
+	// Timeout is the deadline of all steps together.
+	// Zero uses [DefaultPreflightTimeout].
+	Timeout time.Duration
+	// TLSConfig is used in the handshake and request. Nil uses the
+	// system defaults.
+	TLSConfig *tls.Config
+	// Resolver looks up the host. Nil uses [net.DefaultResolver].
+	Resolver *net.Resolver
+}
+
+// Check runs the preflight steps against the base URL. Any HTTP response,
+// regardless of its status, counts as reachable. When a proxy is configured
+// for the URL, only the HEAD request is sent, as the host may only resolve
+// from the proxy.
+func (p Preflight) Check(ctx context.Context, baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("parse base URL: %w", err)
+	}
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultPreflightTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	proxy, err := http.ProxyFromEnvironment(req)
+	if err != nil {
+		return fmt.Errorf("get proxy: %w", err)
+	}
+	if proxy == nil {
+		if err := p.dial(ctx, u); err != nil {
+			return err
+		}
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: p.TLSConfig,
+		},
+		// Redirects, such as to the login page, already prove that
+		// Personio is reachable
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &PreflightError{Host: host, Step: "head request", Err: unwrapURLError(err)}
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (p Preflight) dial(ctx context.Context, u *url.URL) error {
+	host := u.Hostname()
+	if net.ParseIP(host) == nil {
+		resolver := p.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		if _, err := resolver.LookupHost(ctx, host); err != nil {
+			return &PreflightError{Host: host, Step: "dns lookup", Err: err}
+		}
+	}
+	if u.Scheme != "https" {
+		return nil
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	config := p.TLSConfig.Clone()
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	dialer := &tls.Dialer{Config: config}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return &PreflightError{Host: host, Step: "tls handshake", Err: err}
+	}
+	return conn.Close()
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPreflightCheck(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("want HEAD request, got %s", r.Method)
+		}
+		http.Redirect(w, r, "/login/index", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	p := Preflight{TLSConfig: srv.Client().Transport.(*http.Transport).TLSClientConfig}
+	if err := p.Check(context.Background(), srv.URL); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+}
+
+func TestPreflightCheckTimeout(t *testing.T) {
+	// Accepts connections but never completes the TLS handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		<-done
+		conn.Close()
+	}()
+
+	p := Preflight{
+		Timeout:   100 * time.Millisecond,
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	start := time.Now()
+	err = p.Check(context.Background(), "https://"+ln.Addr().String())
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("want ErrUnreachable, got %v", err)
+	}
+	var preflightErr *PreflightError
+	if !errors.As(err, &preflightErr) || preflightErr.Step != "tls handshake" {
+		t.Errorf("want failed tls handshake, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("want fast failure, took %s", elapsed)
+	}
+}
@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is the request quota that Personio reported in its latest
+// response headers.
+type RateLimit struct {
+	// Limit is the number of requests allowed per window, or zero if
+	// Personio didn't say.
+	Limit int
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+	// Reset is when the current window ends and the quota is restored.
+	Reset time.Time
+}
+
+// Active returns true if the rate limit window has not yet been reset.
+func (r RateLimit) Active(now time.Time) bool {
+	return r.Reset.After(now)
+}
+
+// parseRateLimit reads the rate limit headers of a response. Both the
+// common X-RateLimit-* headers and the IETF draft RateLimit-* headers
+// are supported, as well as Retry-After on HTTP 429 responses.
+// Returns false if the response has no rate limit headers.
+func parseRateLimit(resp *http.Response, now time.Time) (RateLimit, bool) {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), now); retryAfter > 0 {
+			return RateLimit{Remaining: 0, Reset: now.Add(retryAfter)}, true
+		}
+	}
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		remaining, err := strconv.Atoi(resp.Header.Get(prefix + "Remaining"))
+		if err != nil {
+			continue
+		}
+		reset, ok := parseRateLimitReset(resp.Header.Get(prefix+"Reset"), now)
+		if !ok {
+			continue
+		}
+		limit, _ := strconv.Atoi(resp.Header.Get(prefix + "Limit"))
+		return RateLimit{Limit: limit, Remaining: remaining, Reset: reset}, true
+	}
+	return RateLimit{}, false
+}
+
+// parseRateLimitReset parses the reset header, which is either in seconds
+// until the reset or a Unix timestamp, depending on the server.
+func parseRateLimitReset(value string, now time.Time) (time.Time, bool) {
+	secs, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || secs < 0 {
+		return time.Time{}, false
+	}
+	// Deltas are never this large, so it must be a Unix timestamp
+	if secs > 1_000_000_000 {
+		return time.Unix(secs, 0), true
+	}
+	return now.Add(time.Duration(secs) * time.Second), true
+}
+
+// RateLimit returns the rate limit from the latest response that had rate
+// limit headers, or false if no such response has been received.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
+	return c.rateLimit, c.hasRateLimit
+}
+
+func (c *Client) recordRateLimit(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	rl, ok := parseRateLimit(resp, time.Now())
+	if !ok {
+		return
+	}
+	c.rateLimitMu.Lock()
+	c.rateLimit = rl
+	c.hasRateLimit = true
+	c.rateLimitMu.Unlock()
+}
+
+// requestLimiter is a token bucket that limits the rate of outgoing
+// requests, as set via [WithRateLimit]. A nil limiter allows all requests.
+type requestLimiter struct {
+	mu       sync.Mutex
+	burst    float64
+	interval time.Duration
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+func newRequestLimiter(requests int, per time.Duration) *requestLimiter {
+	return newBurstLimiter(requests, per, requests)
+}
+
+// newBurstLimiter refills the given number of requests per duration, and
+// holds at most burst unused requests.
+func newBurstLimiter(requests int, per time.Duration, burst int) *requestLimiter {
+	if requests <= 0 || per <= 0 || burst <= 0 {
+		return nil
+	}
+	return &requestLimiter{
+		burst:    float64(burst),
+		interval: per / time.Duration(requests),
+		tokens:   float64(burst),
+		now:      time.Now,
+	}
+}
+
+// newBudgetLimiter never lets more than the given number of requests
+// through in any window of the duration, while allowing bursts. In any
+// window, a token bucket lets through its burst plus its refill, so the
+// burst is subtracted from the refill rate.
+func newBudgetLimiter(requests int, per time.Duration, burst int) *requestLimiter {
+	if requests <= 0 || per <= 0 {
+		return nil
+	}
+	if requests == 1 {
+		// A single request can't be split into a burst and a refill, so
+		// each request after the first waits for the whole duration
+		return newBurstLimiter(1, per, 1)
+	}
+	if burst <= 0 || burst >= requests {
+		burst = requests / 10
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	// The burst is less than the budget, so the refill is at least 1
+	return newBurstLimiter(requests-burst, per, burst)
+}
+
+// available returns how many requests can be sent right away.
+func (l *requestLimiter) available() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tokens := l.tokens
+	if !l.last.IsZero() {
+		tokens += float64(l.now().Sub(l.last)) / float64(l.interval)
+		if tokens > l.burst {
+			tokens = l.burst
+		}
+	}
+	if tokens < 0 {
+		return 0
+	}
+	return int(tokens)
+}
+
+// reserve takes a token, and returns how long to wait before the request
+// may be sent.
+func (l *requestLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.interval))
+}
+
+func (l *requestLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		want   RateLimit
+		wantOK bool
+	}{
+		{
+			name:   "no headers",
+			status: http.StatusOK,
+			header: http.Header{},
+		},
+		{
+			name:   "delta seconds",
+			status: http.StatusOK,
+			header: http.Header{
+				"X-Ratelimit-Limit":     {"60"},
+				"X-Ratelimit-Remaining": {"12"},
+				"X-Ratelimit-Reset":     {"30"},
+			},
+			want:   RateLimit{Limit: 60, Remaining: 12, Reset: now.Add(30 * time.Second)},
+			wantOK: true,
+		},
+		{
+			name:   "unix timestamp",
+			status: http.StatusOK,
+			header: http.Header{
+				"Ratelimit-Remaining": {"0"},
+				"Ratelimit-Reset":     {"1674043260"},
+			},
+			want:   RateLimit{Remaining: 0, Reset: time.Unix(1674043260, 0)},
+			wantOK: true,
+		},
+		{
+			name:   "retry after",
+			status: http.StatusTooManyRequests,
+			header: http.Header{"Retry-After": {"120"}},
+			want:   RateLimit{Reset: now.Add(2 * time.Minute)},
+			wantOK: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseRateLimit(&http.Response{StatusCode: tc.status, Header: tc.header}, now)
+			if ok != tc.wantOK {
+				t.Fatalf("want ok=%t, got %t", tc.wantOK, ok)
+			}
+			if got.Limit != tc.want.Limit || got.Remaining != tc.want.Remaining || !got.Reset.Equal(tc.want.Reset) {
+				t.Errorf("want %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRequestLimiter(t *testing.T) {
+	now := time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)
+	l := newRequestLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i, want := range []time.Duration{0, 0, 30 * time.Second, time.Minute} {
+		if got := l.reserve(); got != want {
+			t.Errorf("request %d: want delay %s, got %s", i+1, want, got)
+		}
+	}
+
+	now = now.Add(2 * time.Minute)
+	if got := l.reserve(); got != 0 {
+		t.Errorf("after refill: want no delay, got %s", got)
+	}
+
+	if newRequestLimiter(0, time.Minute) != nil {
+		t.Error("want nil limiter for zero requests")
+	}
+}
+
+func TestBudgetLimiterNeverExceedsBudget(t *testing.T) {
+	tests := []struct {
+		name            string
+		requests, burst int
+	}{
+		{name: "with burst", requests: 60, burst: 10},
+		{name: "default burst", requests: 60},
+		{name: "burst of whole budget", requests: 5, burst: 5},
+		{name: "two requests", requests: 2},
+		{name: "single request", requests: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)
+			l := newBudgetLimiter(tc.requests, time.Hour, tc.burst)
+			l.now = func() time.Time { return now }
+
+			// Send each request as soon as the limiter allows it, for three hours
+			var sent []time.Time
+			end := now.Add(3 * time.Hour)
+			for now.Before(end) {
+				now = now.Add(l.reserve())
+				sent = append(sent, now)
+			}
+			for i, start := range sent {
+				var inWindow int
+				for _, at := range sent[i:] {
+					if at.Sub(start) < time.Hour {
+						inWindow++
+					}
+				}
+				if inWindow > tc.requests {
+					t.Fatalf("want at most %d requests per hour, got %d in the hour from %s", tc.requests, inWindow, start.Format(time.Kitchen))
+				}
+			}
+			if got := l.available(); got != 0 {
+				t.Errorf("want no requests available after using the budget, got %d", got)
+			}
+		})
+	}
+}
+
+func TestBudgetLimiterSingleRequest(t *testing.T) {
+	now := time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)
+	l := newBudgetLimiter(1, time.Hour, 0)
+	l.now = func() time.Time { return now }
+	if got := l.reserve(); got != 0 {
+		t.Errorf("first request: want no delay, got %s", got)
+	}
+	now = now.Add(59 * time.Minute)
+	if got := l.reserve(); got != time.Minute {
+		t.Errorf("second request: want to wait for the rest of the hour, got %s", got)
+	}
+}
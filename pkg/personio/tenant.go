@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TenantDomains are the known domains that Personio hosts its tenants on.
+// A tenant's host is its slug followed by one of these domains, e.g
+// "mycompany.personio.de".
+var TenantDomains = []string{
+	"personio.de",
+	"app.personio.com",
+}
+
+var (
+	ErrInvalidTenantSlug = errors.New("invalid tenant slug")
+	ErrTenantNotFound    = errors.New("tenant not found")
+)
+
+var tenantSlugRegex = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]*[a-z0-9])?$`)
+
+// ValidateTenantSlug returns an error if the slug is not a valid subdomain.
+func ValidateTenantSlug(slug string) error {
+	if !tenantSlugRegex.MatchString(slug) {
+		return fmt.Errorf("%w: %q, must only contain lowercase letters, digits, and dashes", ErrInvalidTenantSlug, slug)
+	}
+	return nil
+}
+
+// TenantBaseURL builds a base URL from its separate parts. The path prefix
+// is optional, and is used when Personio is accessed through a reverse proxy.
+func TenantBaseURL(host, pathPrefix string) (string, error) {
+	if host == "" {
+		return "", errors.New("missing host")
+	}
+	if strings.Contains(host, "/") {
+		return "", fmt.Errorf("host must not contain a scheme or path, got %q", host)
+	}
+	if pathPrefix != "" && !strings.HasPrefix(pathPrefix, "/") {
+		pathPrefix = "/" + pathPrefix
+	}
+	u := url.URL{
+		Scheme: "https",
+		Host:   host,
+		Path:   pathPrefix,
+	}
+	return NormalizeBaseURL(u.String())
+}
+
+// TenantCandidate is a possible base URL for a tenant, as reported by
+// [DetectTenant].
+type TenantCandidate struct {
+	Domain  string `json:"domain"`
+	BaseURL string `json:"baseUrl"`
+	Found   bool   `json:"found"`
+	Status  string `json:"status"`
+}
+
+// DetectTenant probes all the [TenantDomains] for the given company slug,
+// and reports which of them has a login page for the tenant.
+//
+// Returns [ErrTenantNotFound] if none of the domains were found.
+func DetectTenant(slug string) ([]TenantCandidate, error) {
+	if err := ValidateTenantSlug(slug); err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		// Unknown tenants redirect to Personio's marketing page,
+		// so we must not follow redirects to tell them apart.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	var candidates []TenantCandidate
+	var anyFound bool
+	for _, domain := range TenantDomains {
+		baseURL, err := TenantBaseURL(slug+"."+domain, "")
+		if err != nil {
+			return nil, err
+		}
+		candidate := probeTenant(client, baseURL)
+		candidate.Domain = domain
+		log.Debug().
+			Str("baseUrl", baseURL).
+			Bool("found", candidate.Found).
+			Str("status", candidate.Status).
+			Msg("Probed tenant domain.")
+		anyFound = anyFound || candidate.Found
+		candidates = append(candidates, candidate)
+	}
+	if !anyFound {
+		return candidates, fmt.Errorf("%w: %q", ErrTenantNotFound, slug)
+	}
+	return candidates, nil
+}
+
+func probeTenant(client *http.Client, baseURL string) TenantCandidate {
+	candidate := TenantCandidate{BaseURL: baseURL}
+	req, err := http.NewRequest(http.MethodGet, baseURL+PathLogin, nil)
+	if err != nil {
+		candidate.Status = err.Error()
+		return candidate
+	}
+	resp, err := DoRequest(client, req)
+	if resp == nil {
+		candidate.Status = err.Error()
+		return candidate
+	}
+	resp.Body.Close()
+	candidate.Status = resp.Status
+	candidate.Found = resp.StatusCode == http.StatusOK
+	return candidate
+}
@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RequestClass groups requests that need similar timeouts.
+type RequestClass string
+
+const (
+	// RequestClassLogin is for logging in, where Personio's redirect chain
+	// needs more time than other requests.
+	RequestClassLogin RequestClass = "login"
+	// RequestClassRead is for GET requests, such as fetching the calendar.
+	RequestClassRead RequestClass = "read"
+	// RequestClassWrite is for requests that change data, such as setting
+	// attendance.
+	RequestClassWrite RequestClass = "write"
+)
+
+// Timeouts are the deadlines of each request, including following its
+// redirects and reading its response body, per class of request.
+// Zero means no timeout.
+type Timeouts struct {
+	Login time.Duration
+	Read  time.Duration
+	Write time.Duration
+}
+
+// DefaultTimeouts are the timeouts used by a new [Client].
+var DefaultTimeouts = Timeouts{
+	Login: time.Minute,
+	Read:  30 * time.Second,
+	Write: 30 * time.Second,
+}
+
+// For returns the timeout of the class of requests.
+func (t Timeouts) For(class RequestClass) time.Duration {
+	switch class {
+	case RequestClassLogin:
+		return t.Login
+	case RequestClassWrite:
+		return t.Write
+	default:
+		return t.Read
+	}
+}
+
+// SetTimeouts changes the timeouts of requests. Zero values disable the
+// timeout of that class of requests.
+func (c *Client) SetTimeouts(timeouts Timeouts) {
+	c.timeouts = timeouts
+}
+
+type requestClassKey struct{}
+
+// withRequestClass marks the request as part of the given class, overriding
+// the class that is otherwise based on the request method.
+func withRequestClass(req *http.Request, class RequestClass) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), requestClassKey{}, class))
+}
+
+func requestClassOf(req *http.Request) RequestClass {
+	if class, ok := req.Context().Value(requestClassKey{}).(RequestClass); ok {
+		return class
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RequestClassRead
+	default:
+		return RequestClassWrite
+	}
+}
+
+// doWithTimeout sends the request with a deadline from the timeout of the
+// request's class. The deadline also covers reading the response body, and
+// is released when the body is closed.
+func (c *Client) doWithTimeout(req *http.Request) (*http.Response, error) {
+	if err := c.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	class := requestClassOf(req)
+	timeout := c.timeouts.For(class)
+	if timeout <= 0 {
+		return doRequest(c.http, req, c.log())
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := doRequest(c.http, req.WithContext(ctx), c.log())
+	if resp == nil || resp.Body == nil {
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%s request timed out after %s: %w", class, timeout, err)
+		}
+		return resp, err
+	}
+	resp.Body = &cancelCloser{ReadCloser: resp.Body, cancel: cancel}
+	return resp, err
+}
+
+type cancelCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelCloser) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestClassOf(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "/", nil)
+	put, _ := http.NewRequest(http.MethodPut, "/", nil)
+	login, _ := http.NewRequest(http.MethodPost, "/login", nil)
+	login = withRequestClass(login, RequestClassLogin)
+
+	tests := []struct {
+		req  *http.Request
+		want RequestClass
+	}{
+		{get, RequestClassRead},
+		{put, RequestClassWrite},
+		{login, RequestClassLogin},
+	}
+	for _, tc := range tests {
+		if got := requestClassOf(tc.req); got != tc.want {
+			t.Errorf("%s %s: want %q, got %q", tc.req.Method, tc.req.URL, tc.want, got)
+		}
+	}
+}
+
+func TestClientTimeouts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetTimeouts(Timeouts{Read: time.Second, Write: 50 * time.Millisecond})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	resp, err := c.RawJSON(req)
+	if err != nil {
+		t.Fatalf("GET: %s", err)
+	}
+	if _, err := ParseResponseJSON[any](resp); err != nil {
+		t.Errorf("GET: read body after request: %s", err)
+	}
+	resp.Body.Close()
+
+	req, _ = http.NewRequest(http.MethodPut, "/", nil)
+	_, err = c.RawJSON(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("PUT: want deadline exceeded, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "write request timed out") {
+		t.Errorf("PUT: want error to name the request class, got %q", err)
+	}
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// BenchmarkBulkRequests compares the default transport with [NewTransport]
+// when sending 50 concurrent requests, as done by bulk operations such as
+// "team export". The "conns/op" metric is the number of new connections.
+func BenchmarkBulkRequests(b *testing.B) {
+	const requests = 50
+
+	var conns atomic.Int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"success":true,"data":{}}`)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	tlsConfig := srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	transports := []struct {
+		name string
+		new  func() *http.Transport
+	}{
+		{"default", http.DefaultTransport.(*http.Transport).Clone},
+		{"tuned", NewTransport},
+	}
+	for _, proto := range []string{"http1", "http2"} {
+		for _, tc := range transports {
+			b.Run(tc.name+"-"+proto, func(b *testing.B) {
+				transport := tc.new()
+				transport.TLSClientConfig = tlsConfig.Clone()
+				if proto == "http1" {
+					transport.ForceAttemptHTTP2 = false
+					transport.TLSClientConfig.NextProtos = []string{"http/1.1"}
+				}
+				client := &http.Client{Transport: transport}
+				defer transport.CloseIdleConnections()
+				conns.Store(0)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := sendConcurrently(client, srv.URL, requests); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+			})
+		}
+	}
+}
+
+func sendConcurrently(client *http.Client, url string, n int) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(url)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer resp.Body.Close()
+			if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+				errs <- err
+				return
+			}
+			if resp.StatusCode != http.StatusOK {
+				errs <- fmt.Errorf("unexpected status: %s", resp.Status)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrVerifyMismatch is returned by [Client.VerifyAttendance] when the
+// periods stored by Personio differ from the submitted periods.
+var ErrVerifyMismatch = errors.New("stored attendance differs from submitted attendance")
+
+// PeriodMismatch is a difference between a submitted period and the period
+// that Personio stored.
+type PeriodMismatch struct {
+	PeriodID uuid.UUID `json:"periodId"`
+	// Field is the differing field, or "period" when the whole period is
+	// missing or unexpected.
+	Field     string `json:"field"`
+	Submitted string `json:"submitted"`
+	Stored    string `json:"stored"`
+}
+
+func (m PeriodMismatch) String() string {
+	return fmt.Sprintf("period %s: %s submitted as %q but stored as %q", m.PeriodID, m.Field, m.Submitted, m.Stored)
+}
+
+// ComparePeriods returns the differences between the submitted periods and
+// the periods stored by Personio, such as when Personio silently drops the
+// seconds or the comment of a period. Periods are matched by their IDs.
+func ComparePeriods(submitted, stored []Period) []PeriodMismatch {
+	storedByID := make(map[uuid.UUID]Period, len(stored))
+	for _, p := range stored {
+		storedByID[p.ID] = p
+	}
+	var mismatches []PeriodMismatch
+	seen := make(map[uuid.UUID]bool, len(submitted))
+	for _, sub := range submitted {
+		seen[sub.ID] = true
+		st, ok := storedByID[sub.ID]
+		if !ok {
+			mismatches = append(mismatches, PeriodMismatch{
+				PeriodID:  sub.ID,
+				Field:     "period",
+				Submitted: formatPeriod(sub),
+				Stored:    "",
+			})
+			continue
+		}
+		add := func(field, submitted, stored string) {
+			if submitted != stored {
+				mismatches = append(mismatches, PeriodMismatch{
+					PeriodID:  sub.ID,
+					Field:     field,
+					Submitted: submitted,
+					Stored:    stored,
+				})
+			}
+		}
+		add("start", formatPeriodTime(sub.Start), formatPeriodTime(st.Start))
+		add("end", formatPeriodTime(sub.End), formatPeriodTime(st.End))
+		add("period_type", string(periodTypeOrWork(sub.PeriodType)), string(periodTypeOrWork(st.PeriodType)))
+		add("comment", sub.GetComment(), st.GetComment())
+		add("project_id", fmt.Sprint(sub.GetProjectID()), fmt.Sprint(st.GetProjectID()))
+	}
+	for _, st := range stored {
+		if seen[st.ID] {
+			continue
+		}
+		mismatches = append(mismatches, PeriodMismatch{
+			PeriodID:  st.ID,
+			Field:     "period",
+			Submitted: "",
+			Stored:    formatPeriod(st),
+		})
+	}
+	sort.SliceStable(mismatches, func(i, j int) bool {
+		return mismatches[i].PeriodID.String() < mismatches[j].PeriodID.String()
+	})
+	return mismatches
+}
+
+// VerifyAttendance fetches the day's periods again and compares them with
+// the submitted periods, to catch changes that Personio made silently.
+// Returns an error wrapping [ErrVerifyMismatch] if they differ.
+func (c *Client) VerifyAttendance(date time.Time, submitted []Period) ([]PeriodMismatch, error) {
+	stored, err := c.GetAttendancePeriods(date)
+	if err != nil {
+		return nil, fmt.Errorf("get stored periods: %w", err)
+	}
+	mismatches := ComparePeriods(submitted, stored)
+	if len(mismatches) > 0 {
+		return mismatches, fmt.Errorf("%w: %d differences on %s", ErrVerifyMismatch, len(mismatches), date.Format(time.DateOnly))
+	}
+	return nil, nil
+}
+
+func periodTypeOrWork(t PeriodType) PeriodType {
+	if t == "" {
+		return PeriodTypeWork
+	}
+	return t
+}
+
+func formatPeriodTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatPeriod(p Period) string {
+	return fmt.Sprintf("%s %s-%s", periodTypeOrWork(p.PeriodType), formatPeriodTime(p.Start), formatPeriodTime(p.End))
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestComparePeriods(t *testing.T) {
+	comment := "Work before lunch"
+	idA := uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	idB := uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	idC := uuid.MustParse("00000000-0000-0000-0000-00000000000c")
+	submitted := []Period{
+		{
+			ID:      idA,
+			Start:   time.Date(2023, 1, 18, 8, 0, 30, 0, time.UTC),
+			End:     time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC),
+			Comment: &comment,
+		},
+		{
+			ID:         idB,
+			PeriodType: PeriodTypeBreak,
+			Start:      time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC),
+			End:        time.Date(2023, 1, 18, 13, 0, 0, 0, time.UTC),
+		},
+	}
+	stored := []Period{
+		{
+			// Seconds and comment dropped
+			ID:         idA,
+			PeriodType: PeriodTypeWork,
+			Start:      time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC),
+			End:        time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:    idC,
+			Start: time.Date(2023, 1, 18, 13, 0, 0, 0, time.UTC),
+			End:   time.Date(2023, 1, 18, 17, 0, 0, 0, time.UTC),
+		},
+	}
+
+	got := ComparePeriods(submitted, stored)
+
+	want := []PeriodMismatch{
+		{PeriodID: idA, Field: "start", Submitted: "2023-01-18T08:00:30Z", Stored: "2023-01-18T08:00:00Z"},
+		{PeriodID: idA, Field: "comment", Submitted: comment, Stored: ""},
+		{PeriodID: idB, Field: "period", Submitted: "break 2023-01-18T12:00:00Z-2023-01-18T13:00:00Z"},
+		{PeriodID: idC, Field: "period", Stored: "work 2023-01-18T13:00:00Z-2023-01-18T17:00:00Z"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d mismatches, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("mismatch %d:\nwant %v\ngot  %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestComparePeriodsEqual(t *testing.T) {
+	p := Period{
+		ID:    uuid.New(),
+		Start: time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC),
+		End:   time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC),
+	}
+	stored := p
+	stored.PeriodType = PeriodTypeWork
+	empty := ""
+	stored.Comment = &empty
+	if got := ComparePeriods([]Period{p}, []Period{stored}); len(got) != 0 {
+		t.Errorf("want no mismatches, got %v", got)
+	}
+}
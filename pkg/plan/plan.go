@@ -0,0 +1,302 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package plan compares the desired attendance periods with the ones in
+// Personio, and describes the changes needed, similar to "terraform plan",
+// so timesheets can be kept in files and reviewed before being applied.
+package plan
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// Action is what a [Change] does to a period.
+type Action string
+
+// Available [Action] values.
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// symbol returns the prefix of the action in the plan's text format.
+func (a Action) symbol() string {
+	switch a {
+	case ActionCreate:
+		return "+"
+	case ActionUpdate:
+		return "~"
+	case ActionDelete:
+		return "-"
+	default:
+		return "?"
+	}
+}
+
+// Change is a change of a single attendance period.
+type Change struct {
+	Action Action `json:"action"`
+	// Address identifies the period, e.g
+	// `attendance["2024-05-02"].work["08:00"]`, where the time is the start
+	// of the period, after the change or else before it.
+	Address string           `json:"address"`
+	Day     string           `json:"day"`
+	Cause   Cause            `json:"cause"`
+	Before  *personio.Period `json:"before,omitempty"`
+	After   *personio.Period `json:"after,omitempty"`
+	// Locked is true if the day is in a month locked by payroll, so
+	// Personio would reject the change.
+	Locked bool `json:"locked,omitempty"`
+}
+
+// Plan is the changes needed to make Personio match the desired periods.
+type Plan struct {
+	Changes []Change `json:"changes"`
+	// Days are the days that have changes, sorted.
+	Days []string `json:"days"`
+	// State is the last applied desired periods, used to tell the cause of
+	// each change. All changes are local when nil.
+	State *State `json:"-"`
+	// IsLocked returns true if the day is in a month locked by payroll.
+	// No days are locked when nil.
+	IsLocked func(day string) bool `json:"-"`
+}
+
+// Summary counts the changes per action.
+type Summary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Delete int `json:"delete"`
+}
+
+// String returns e.g "3 to create, 1 to update, 2 to delete".
+func (s Summary) String() string {
+	return fmt.Sprintf("%d to create, %d to update, %d to delete", s.Create, s.Update, s.Delete)
+}
+
+// Empty returns true if there are no changes.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// LockedDays returns the days of the plan that are locked by payroll,
+// sorted.
+func (p *Plan) LockedDays() []string {
+	var days []string
+	for _, c := range p.Changes {
+		if c.Locked && (len(days) == 0 || days[len(days)-1] != c.Day) {
+			days = append(days, c.Day)
+		}
+	}
+	return days
+}
+
+// Summary counts the changes per action.
+func (p *Plan) Summary() Summary {
+	return summarize(p.Changes)
+}
+
+func summarize(changes []Change) Summary {
+	var s Summary
+	for _, c := range changes {
+		switch c.Action {
+		case ActionCreate:
+			s.Create++
+		case ActionUpdate:
+			s.Update++
+		case ActionDelete:
+			s.Delete++
+		}
+	}
+	return s
+}
+
+// Add compares the current and desired periods of the day, and adds the
+// changes to the plan. Periods that are equal in both are left as-is.
+// Other desired periods update a current period of the same type that
+// starts at the same time or overlaps it, or are else created. The
+// remaining current periods are deleted.
+func (p *Plan) Add(day string, current, desired []personio.Period) {
+	current = sortedByStart(current)
+	desired = sortedByStart(desired)
+	matchedCurrent := make([]bool, len(current))
+	matchedDesired := make([]bool, len(desired))
+	for i, d := range desired {
+		for k, c := range current {
+			if !matchedCurrent[k] && Equal(c, d) {
+				matchedCurrent[k] = true
+				matchedDesired[i] = true
+				break
+			}
+		}
+	}
+
+	var changes []Change
+	for i, d := range desired {
+		if matchedDesired[i] {
+			continue
+		}
+		d := d
+		change := Change{Action: ActionCreate, Day: day, After: &d}
+		for k, c := range current {
+			if !matchedCurrent[k] && c.PeriodType == d.PeriodType && (c.Start.Equal(d.Start) || overlaps(c, d)) {
+				matchedCurrent[k] = true
+				c := c
+				change.Action = ActionUpdate
+				change.Before = &c
+				break
+			}
+		}
+		changes = append(changes, change)
+	}
+	for k, c := range current {
+		if matchedCurrent[k] {
+			continue
+		}
+		c := c
+		changes = append(changes, Change{Action: ActionDelete, Day: day, Before: &c})
+	}
+	if len(changes) == 0 {
+		return
+	}
+	cause := p.State.cause(day, current, desired)
+	locked := p.IsLocked != nil && p.IsLocked(day)
+	for i := range changes {
+		changes[i].Address = address(changes[i])
+		changes[i].Cause = cause
+		changes[i].Locked = locked
+	}
+	sort.SliceStable(changes, func(i, k int) bool {
+		return changeStart(changes[i]).Before(changeStart(changes[k]))
+	})
+	p.Changes = append(p.Changes, changes...)
+	p.Days = append(p.Days, day)
+	sort.Strings(p.Days)
+	sort.SliceStable(p.Changes, func(i, k int) bool {
+		return p.Changes[i].Day < p.Changes[k].Day
+	})
+}
+
+// Equal returns true if the periods have the same type, times, comment,
+// and project, ignoring their IDs.
+func Equal(a, b personio.Period) bool {
+	return a.PeriodType == b.PeriodType &&
+		a.Start.Truncate(time.Second).Equal(b.Start.Truncate(time.Second)) &&
+		a.End.Truncate(time.Second).Equal(b.End.Truncate(time.Second)) &&
+		a.GetComment() == b.GetComment() &&
+		a.GetProjectID() == b.GetProjectID()
+}
+
+func overlaps(a, b personio.Period) bool {
+	return a.Start.Before(b.End) && b.Start.Before(a.End)
+}
+
+func sortedByStart(periods []personio.Period) []personio.Period {
+	sorted := append([]personio.Period(nil), periods...)
+	sort.SliceStable(sorted, func(i, k int) bool {
+		return sorted[i].Start.Before(sorted[k].Start)
+	})
+	return sorted
+}
+
+func changeStart(c Change) time.Time {
+	if c.After != nil {
+		return c.After.Start
+	}
+	return c.Before.Start
+}
+
+func address(c Change) string {
+	p := c.After
+	if p == nil {
+		p = c.Before
+	}
+	return fmt.Sprintf("attendance[%q].%s[%q]", c.Day, p.PeriodType, p.Start.Local().Format("15:04"))
+}
+
+// Write writes the plan in a human readable text format, where days that
+// were changed in Personio since the last sync or that are locked by
+// payroll are marked, e.g:
+//
+//	attendance["2024-05-02"]: + create 1, ~ update 1, - delete 1
+//	  + attendance["2024-05-02"].work["08:00"]: 08:00-12:00 "Work before lunch"
+//	  ~ attendance["2024-05-02"].work["13:00"]: 13:00-17:00 -> 13:00-17:30
+//	  - attendance["2024-05-02"].break["12:00"]: 12:00-12:30
+//
+//	Plan: 1 to create, 1 to update, 1 to delete.
+func (p *Plan) Write(w io.Writer) error {
+	if p.Empty() {
+		_, err := fmt.Fprintln(w, "No changes. Personio matches the desired periods.")
+		return err
+	}
+	var sb strings.Builder
+	for _, day := range p.Days {
+		var dayChanges []Change
+		for _, c := range p.Changes {
+			if c.Day == day {
+				dayChanges = append(dayChanges, c)
+			}
+		}
+		s := summarize(dayChanges)
+		fmt.Fprintf(&sb, "attendance[%q]: + create %d, ~ update %d, - delete %d", day, s.Create, s.Update, s.Delete)
+		switch dayChanges[0].Cause {
+		case CauseDrift:
+			sb.WriteString(" (reverts changes made in Personio since the last sync)")
+		case CauseConflict:
+			sb.WriteString(" (conflict: changed both locally and in Personio since the last sync)")
+		}
+		if dayChanges[0].Locked {
+			sb.WriteString(" (locked by payroll, cannot be changed)")
+		}
+		sb.WriteByte('\n')
+		for _, c := range dayChanges {
+			fmt.Fprintf(&sb, "  %s %s: %s\n", c.Action.symbol(), c.Address, describe(c))
+		}
+	}
+	fmt.Fprintf(&sb, "\nPlan: %s.\n", p.Summary())
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func describe(c Change) string {
+	switch c.Action {
+	case ActionCreate:
+		return describePeriod(*c.After)
+	case ActionDelete:
+		return describePeriod(*c.Before)
+	default:
+		return describePeriod(*c.Before) + " -> " + describePeriod(*c.After)
+	}
+}
+
+func describePeriod(p personio.Period) string {
+	s := p.Start.Local().Format("15:04") + "-" + p.End.Local().Format("15:04")
+	if comment := p.GetComment(); comment != "" {
+		s += fmt.Sprintf(" %q", comment)
+	}
+	if project := p.GetProjectID(); project != 0 {
+		s += fmt.Sprintf(" (project %d)", project)
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func period(typ personio.PeriodType, start, end, comment string) personio.Period {
+	day := time.Date(2024, 5, 2, 0, 0, 0, 0, time.Local)
+	parse := func(clock string) time.Time {
+		t, err := time.Parse("15:04", clock)
+		if err != nil {
+			panic(err)
+		}
+		return day.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute)
+	}
+	p := personio.Period{PeriodType: typ, Start: parse(start), End: parse(end)}
+	if comment != "" {
+		p.Comment = &comment
+	}
+	return p
+}
+
+func TestPlanAdd(t *testing.T) {
+	current := []personio.Period{
+		period(personio.PeriodTypeWork, "08:00", "12:00", "Before lunch"),
+		period(personio.PeriodTypeBreak, "12:00", "12:30", ""),
+		period(personio.PeriodTypeWork, "13:00", "17:00", ""),
+	}
+	desired := []personio.Period{
+		period(personio.PeriodTypeWork, "08:00", "12:00", "Before lunch"),
+		period(personio.PeriodTypeWork, "13:00", "17:30", ""),
+		period(personio.PeriodTypeWork, "18:00", "19:00", "Evening"),
+	}
+	var p Plan
+	p.Add("2024-05-02", current, desired)
+	p.Add("2024-05-03", desired, desired)
+
+	want := Summary{Create: 1, Update: 1, Delete: 1}
+	if got := p.Summary(); got != want {
+		t.Errorf("want summary %v, got %v", want, got)
+	}
+	if len(p.Days) != 1 || p.Days[0] != "2024-05-02" {
+		t.Errorf("want only 2024-05-02 to change, got %v", p.Days)
+	}
+	var sb strings.Builder
+	if err := p.Write(&sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`attendance["2024-05-02"]: + create 1, ~ update 1, - delete 1`,
+		`  - attendance["2024-05-02"].break["12:00"]: 12:00-12:30`,
+		`  ~ attendance["2024-05-02"].work["13:00"]: 13:00-17:00 -> 13:00-17:30`,
+		`  + attendance["2024-05-02"].work["18:00"]: 18:00-19:00 "Evening"`,
+		`Plan: 1 to create, 1 to update, 1 to delete.`,
+	} {
+		if !strings.Contains(sb.String(), line+"\n") {
+			t.Errorf("want line %q in plan:\n%s", line, sb.String())
+		}
+	}
+}
+
+func TestPlanEmpty(t *testing.T) {
+	periods := []personio.Period{period(personio.PeriodTypeWork, "08:00", "12:00", "")}
+	var p Plan
+	p.Add("2024-05-02", periods, periods)
+	if !p.Empty() {
+		t.Errorf("want no changes, got %+v", p.Changes)
+	}
+}
+
+func TestPlanAddCause(t *testing.T) {
+	last := []personio.Period{period(personio.PeriodTypeWork, "08:00", "16:00", "")}
+	edited := []personio.Period{period(personio.PeriodTypeWork, "08:00", "15:00", "")}
+	longer := []personio.Period{period(personio.PeriodTypeWork, "08:00", "17:00", "")}
+
+	tests := []struct {
+		name             string
+		current, desired []personio.Period
+		want             Cause
+	}{
+		{name: "local", current: last, desired: longer, want: CauseLocal},
+		{name: "drift", current: edited, desired: last, want: CauseDrift},
+		{name: "conflict", current: edited, desired: longer, want: CauseConflict},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Plan{State: &State{Days: map[string]StateDay{"2024-05-02": {Periods: last}}}}
+			p.Add("2024-05-02", tc.current, tc.desired)
+			if len(p.Changes) == 0 {
+				t.Fatal("want changes, got none")
+			}
+			if got := p.Changes[0].Cause; got != tc.want {
+				t.Errorf("want cause %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	var p Plan
+	p.Add("2024-05-02", edited, longer)
+	if got := p.Changes[0].Cause; got != CauseLocal {
+		t.Errorf("without state: want cause %q, got %q", CauseLocal, got)
+	}
+}
+
+func TestPlanAddLocked(t *testing.T) {
+	current := []personio.Period{period(personio.PeriodTypeWork, "08:00", "16:00", "")}
+	desired := []personio.Period{period(personio.PeriodTypeWork, "08:00", "17:00", "")}
+	p := Plan{IsLocked: func(day string) bool { return day == "2024-05-02" }}
+	p.Add("2024-05-02", current, desired)
+	p.Add("2024-06-03", current, desired)
+
+	if got, want := p.LockedDays(), []string{"2024-05-02"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("want locked days %v, got %v", want, got)
+	}
+	var sb strings.Builder
+	if err := p.Write(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), `attendance["2024-05-02"]: + create 0, ~ update 1, - delete 0 (locked by payroll`) {
+		t.Errorf("want locked day marked, got:\n%s", sb.String())
+	}
+	if strings.Contains(sb.String(), `attendance["2024-06-03"]: + create 0, ~ update 1, - delete 0 (locked`) {
+		t.Errorf("want unlocked day not marked, got:\n%s", sb.String())
+	}
+}
@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/storage"
+	"github.com/applejag/rootless-personio/pkg/util"
+)
+
+// State is the last applied desired periods per day, so a plan can tell
+// changes made in Personio since the last sync ("drift") apart from new
+// changes to the desired periods.
+type State struct {
+	Days map[string]StateDay `json:"days"`
+}
+
+// StateDay is the last applied desired periods of a day.
+type StateDay struct {
+	AppliedAt time.Time         `json:"appliedAt"`
+	Periods   []personio.Period `json:"periods"`
+}
+
+// StateKey returns the storage key of the state of an employee, e.g
+// "sync/example.personio.de/12345.json".
+func StateKey(host string, employeeID int) string {
+	return "sync/" + util.SafeFileName(host) + "/" + strconv.Itoa(employeeID) + ".json"
+}
+
+// LoadState returns the state stored at the key. A missing key means an
+// empty state.
+func LoadState(store storage.Backend, key string) (*State, error) {
+	b, err := store.Get(key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return &State{Days: make(map[string]StateDay)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read sync state: %w", err)
+	}
+	return parseState(b)
+}
+
+func parseState(b []byte) (*State, error) {
+	state := &State{}
+	if b != nil {
+		if err := json.Unmarshal(b, state); err != nil {
+			return nil, fmt.Errorf("parse sync state: %w", err)
+		}
+	}
+	if state.Days == nil {
+		state.Days = make(map[string]StateDay)
+	}
+	return state, nil
+}
+
+// SaveDays stores the periods of the days in the state at the key, keeping
+// the other days as they are.
+func SaveDays(store storage.Backend, key string, days map[string][]personio.Period, now time.Time) error {
+	err := store.Update(key, func(value []byte) ([]byte, error) {
+		state, err := parseState(value)
+		if err != nil {
+			return nil, err
+		}
+		for day, periods := range days {
+			state.Days[day] = StateDay{AppliedAt: now, Periods: periods}
+		}
+		return json.MarshalIndent(state, "", "  ")
+	})
+	if err != nil {
+		return fmt.Errorf("write sync state: %w", err)
+	}
+	return nil
+}
+
+// SortedDays returns the days in the state, sorted.
+func (s *State) SortedDays() []string {
+	days := make([]string, 0, len(s.Days))
+	for day := range s.Days {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days
+}
+
+// Cause is why a [Change] is needed, based on the [State].
+type Cause string
+
+// Available [Cause] values.
+const (
+	// CauseLocal is when the desired periods changed since the last sync,
+	// or the day was never synced before.
+	CauseLocal Cause = "local"
+	// CauseDrift is when the periods in Personio changed since the last
+	// sync, such as edited in the web UI or corrected by HR, and the change
+	// reverts that.
+	CauseDrift Cause = "drift"
+	// CauseConflict is when both the desired periods and the periods in
+	// Personio changed since the last sync.
+	CauseConflict Cause = "conflict"
+)
+
+// cause returns why the day's changes are needed.
+func (s *State) cause(day string, current, desired []personio.Period) Cause {
+	if s == nil {
+		return CauseLocal
+	}
+	last, ok := s.Days[day]
+	if !ok {
+		return CauseLocal
+	}
+	drift := !EqualDays(current, last.Periods)
+	local := !EqualDays(desired, last.Periods)
+	switch {
+	case drift && local:
+		return CauseConflict
+	case drift:
+		return CauseDrift
+	default:
+		return CauseLocal
+	}
+}
+
+// EqualDays returns true if both days have the same periods, as compared
+// by [Equal], in any order.
+func EqualDays(a, b []personio.Period) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = sortedByStart(a)
+	b = sortedByStart(b)
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package plugin discovers and runs plugins, which are executables on the
+// PATH named "personio-plugin-<name>", similar to kubectl plugins. This
+// lets company-specific integrations live outside of this repository.
+//
+// A plugin is run as a subcommand, i.e "rootless-personio foo bar" runs
+// "personio-plugin-foo bar", with STDIN, STDOUT, and STDERR passed along.
+//
+// A plugin can also be an importer, in which case "personio-plugin-foo
+// import [args...]" must write attendance periods to STDOUT in the same
+// JSON format as read by "attendance set".
+//
+// Plugins get the [EnvBin] environment variable set to the path of this
+// program, so they can call back into it, e.g to read the config via
+// "$PERSONIO_PLUGIN_BIN config -o json".
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Prefix is the file name prefix of plugin executables.
+const Prefix = "personio-plugin-"
+
+// Environment variables set when running plugins.
+const (
+	// EnvBin is the path of this program's executable.
+	EnvBin = "PERSONIO_PLUGIN_BIN"
+	// EnvName is the name of the plugin being run.
+	EnvName = "PERSONIO_PLUGIN_NAME"
+)
+
+// ErrNotFound is returned when no plugin with the name is on the PATH.
+var ErrNotFound = errors.New("plugin not found")
+
+// Plugin is an executable plugin.
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// List returns the plugins found on the PATH, sorted by name. When multiple
+// plugins have the same name, the first one on the PATH is used.
+func List() ([]Plugin, error) {
+	seen := make(map[string]bool)
+	var plugins []Plugin
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read PATH directory: %w", err)
+		}
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok || seen[name] || entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, Plugin{Name: name, Path: path})
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+	return plugins, nil
+}
+
+// Find returns the plugin with the name, or [ErrNotFound].
+func Find(name string) (Plugin, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, "-") {
+		return Plugin{}, fmt.Errorf("%w: invalid name %q", ErrNotFound, name)
+	}
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return Plugin{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return Plugin{Name: name, Path: path}, nil
+}
+
+// Command returns the command that runs the plugin with the arguments, with
+// the plugin environment variables set.
+func (p Plugin) Command(args ...string) *exec.Cmd {
+	c := exec.Command(p.Path, args...)
+	c.Env = append(os.Environ(), EnvName+"="+p.Name)
+	if bin, err := os.Executable(); err == nil {
+		c.Env = append(c.Env, EnvBin+"="+bin)
+	}
+	return c
+}
+
+// Run runs the plugin as a subcommand, connected to this program's STDIN,
+// STDOUT, and STDERR.
+func (p Plugin) Run(args ...string) error {
+	c := p.Command(args...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
+
+func pluginName(fileName string) (string, bool) {
+	if runtime.GOOS == "windows" {
+		fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	}
+	name, ok := strings.CutPrefix(fileName, Prefix)
+	return name, ok && name != ""
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return info.Mode()&0o111 != 0
+}
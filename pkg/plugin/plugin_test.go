@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeScript(t *testing.T, dir, name, script string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListAndFind(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	writeScript(t, first, Prefix+"jira", "#!/bin/sh\necho first", 0o755)
+	writeScript(t, second, Prefix+"jira", "#!/bin/sh\necho second", 0o755)
+	writeScript(t, second, Prefix+"notes", "not executable", 0o644)
+	writeScript(t, second, Prefix+"tempo", "#!/bin/sh\necho \"$PERSONIO_PLUGIN_NAME $1\"", 0o755)
+	writeScript(t, second, "unrelated", "#!/bin/sh", 0o755)
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	plugins, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Plugin{
+		{Name: "jira", Path: filepath.Join(first, Prefix+"jira")},
+		{Name: "tempo", Path: filepath.Join(second, Prefix+"tempo")},
+	}
+	if len(plugins) != len(want) || plugins[0] != want[0] || plugins[1] != want[1] {
+		t.Errorf("want %v, got %v", want, plugins)
+	}
+
+	p, err := Find("tempo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.Command("import").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "tempo import" {
+		t.Errorf("want output %q, got %q", "tempo import", got)
+	}
+
+	if _, err := Find("missing"); err == nil {
+		t.Error("want error for missing plugin, got nil")
+	}
+	if _, err := Find("../jira"); err == nil {
+		t.Error("want error for plugin name with path, got nil")
+	}
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package queue contains a local queue of attendance mutations, used to
+// log time while Personio is unreachable and replay it later.
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/filelock"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+// Action is the kind of attendance mutation.
+type Action string
+
+const (
+	// ActionSet replaces all attendance periods on a day.
+	ActionSet Action = "set"
+	// ActionRemove deletes all attendance periods on a day.
+	ActionRemove Action = "remove"
+)
+
+// Operation is a single queued attendance mutation on a day.
+type Operation struct {
+	ID       uuid.UUID         `json:"id"`
+	QueuedAt time.Time         `json:"queuedAt"`
+	Action   Action            `json:"action"`
+	Day      string            `json:"day"` // ex: "2023-01-18"
+	Periods  []personio.Period `json:"periods,omitempty"`
+}
+
+// DefaultPath returns the default path of the queue file, which is inside
+// the user's config directory, e.g ~/.config/rootless-personio/queue.json
+//
+// The config directory is used over the cache directory, as the queue
+// contains data that is not yet stored anywhere else.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rootless-personio", "queue.json"), nil
+}
+
+// Load reads all queued operations, in the order they were queued.
+// A missing file results in an empty queue.
+func Load(path string) ([]Operation, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read queue: %w", err)
+	}
+	var ops []Operation
+	if err := json.Unmarshal(b, &ops); err != nil {
+		return nil, fmt.Errorf("parse queue: %w", err)
+	}
+	return ops, nil
+}
+
+// Save replaces the queue file atomically with the given operations.
+// The file is removed when there are no operations left.
+func Save(path string, ops []Operation) error {
+	if len(ops) == 0 {
+		err := os.Remove(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	b, err := json.MarshalIndent(ops, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := filelock.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("write queue: %w", err)
+	}
+	return nil
+}
+
+// Append adds operations to the end of the queue. The queue file is locked
+// while appending, so concurrent runs don't lose each other's operations.
+func Append(path string, ops ...Operation) error {
+	return filelock.WithLock(path, func() error {
+		queued, err := Load(path)
+		if err != nil {
+			return err
+		}
+		return Save(path, append(queued, ops...))
+	})
+}
+
+// Remove removes the operations with the given IDs from the queue, while
+// keeping any operations queued by concurrent runs in the meantime.
+func Remove(path string, ids ...uuid.UUID) error {
+	remove := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	return filelock.WithLock(path, func() error {
+		queued, err := Load(path)
+		if err != nil {
+			return err
+		}
+		var kept []Operation
+		for _, op := range queued {
+			if !remove[op.ID] {
+				kept = append(kept, op)
+			}
+		}
+		return Save(path, kept)
+	})
+}
+
+// NewOperation creates a new operation with a random ID.
+func NewOperation(action Action, day string, periods []personio.Period, now time.Time) Operation {
+	return Operation{
+		ID:       uuid.New(),
+		QueuedAt: now,
+		Action:   action,
+		Day:      day,
+		Periods:  periods,
+	}
+}
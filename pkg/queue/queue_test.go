@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	ops, err := Load(path)
+	if err != nil {
+		t.Fatalf("load missing queue: %s", err)
+	}
+	if len(ops) != 0 {
+		t.Fatalf("want empty queue, got %d operations", len(ops))
+	}
+
+	first := NewOperation(ActionSet, "2023-01-18", nil, time.Now())
+	second := NewOperation(ActionRemove, "2023-01-19", nil, time.Now())
+	if err := Append(path, first); err != nil {
+		t.Fatalf("append first: %s", err)
+	}
+	if err := Append(path, second); err != nil {
+		t.Fatalf("append second: %s", err)
+	}
+	ops, err = Load(path)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if len(ops) != 2 || ops[0].ID != first.ID || ops[1].ID != second.ID {
+		t.Fatalf("want operations in queued order, got %+v", ops)
+	}
+
+	if err := Save(path, nil); err != nil {
+		t.Fatalf("save empty: %s", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("want queue file removed when empty, got %v", err)
+	}
+}
+
+func TestRemoveKeepsNewOperations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	flushed := NewOperation(ActionSet, "2023-01-18", nil, time.Now())
+	if err := Append(path, flushed); err != nil {
+		t.Fatalf("append: %s", err)
+	}
+	// Queued by another run while flushing
+	queued := NewOperation(ActionSet, "2023-01-19", nil, time.Now())
+	if err := Append(path, queued); err != nil {
+		t.Fatalf("append: %s", err)
+	}
+
+	if err := Remove(path, flushed.ID); err != nil {
+		t.Fatalf("remove: %s", err)
+	}
+	ops, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if len(ops) != 1 || ops[0].ID != queued.ID {
+		t.Errorf("want only the newly queued operation, got %+v", ops)
+	}
+}
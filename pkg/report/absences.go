@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"sort"
+)
+
+// AbsenceSummary is the number of absence days in a range, in total and
+// per absence type.
+type AbsenceSummary struct {
+	// EmployeeID is only set when summarizing other employees.
+	EmployeeID int    `json:"employeeId,omitempty"`
+	Name       string `json:"name,omitempty"`
+	// Types are sorted by the number of days, most days first.
+	Types     []AbsenceTypeTotal `json:"types,omitempty"`
+	TotalDays float64            `json:"totalDays"`
+	Absences  int                `json:"absences"`
+}
+
+// AbsenceTypeTotal is the number of absence days of an absence type.
+type AbsenceTypeTotal struct {
+	Type     string  `json:"type"`
+	Days     float64 `json:"days"`
+	Absences int     `json:"absences"`
+}
+
+// SummarizeAbsences counts the absence days per absence type. Only
+// workdays in the schedule count, where half-day absences count as half a
+// day, and public holidays are not counted.
+func SummarizeAbsences(days []Day, schedule Schedule) AbsenceSummary {
+	var summary AbsenceSummary
+	totals := make(map[string]*AbsenceTypeTotal)
+	seen := make(map[string]bool)
+	for _, day := range days {
+		if day.Absence == nil {
+			continue
+		}
+		total, ok := totals[day.Absence.Name]
+		if !ok {
+			total = &AbsenceTypeTotal{Type: day.Absence.Name}
+			totals[day.Absence.Name] = total
+		}
+		if !seen[day.Absence.ID] {
+			seen[day.Absence.ID] = true
+			total.Absences++
+			summary.Absences++
+		}
+		value := absenceDayValue(day, schedule)
+		total.Days += value
+		summary.TotalDays += value
+	}
+	for _, total := range totals {
+		summary.Types = append(summary.Types, *total)
+	}
+	sort.Slice(summary.Types, func(i, j int) bool {
+		if summary.Types[i].Days != summary.Types[j].Days {
+			return summary.Types[i].Days > summary.Types[j].Days
+		}
+		return summary.Types[i].Type < summary.Types[j].Type
+	})
+	return summary
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestSummarizeAbsences(t *testing.T) {
+	start := time.Date(2023, 7, 3, 0, 0, 0, 0, time.Local) // Monday
+	sick := &personio.CalendarAbsencePeriod{ID: "1", Name: "Sick leave", StartDate: personio.NewDate(2023, 7, 3), EndDate: personio.NewDate(2023, 7, 4)}
+	vacation := &personio.CalendarAbsencePeriod{ID: "2", Name: "Vacation", StartDate: personio.NewDate(2023, 7, 5), EndDate: personio.NewDate(2023, 7, 9), HalfDayStart: true}
+	sickAgain := &personio.CalendarAbsencePeriod{ID: "3", Name: "Sick leave", StartDate: personio.NewDate(2023, 7, 10), EndDate: personio.NewDate(2023, 7, 10)}
+	absences := []*personio.CalendarAbsencePeriod{sick, sick, vacation, vacation, vacation, vacation, vacation, sickAgain}
+	var days []Day
+	for i, absence := range absences {
+		days = append(days, Day{Date: start.AddDate(0, 0, i), Absence: absence})
+	}
+	days = append(days, Day{Date: start.AddDate(0, 0, len(absences))})
+
+	var schedule Schedule
+	for d := time.Monday; d <= time.Friday; d++ {
+		schedule[d] = 8 * time.Hour
+	}
+
+	got := SummarizeAbsences(days, schedule)
+	// The vacation starts with a half day, and the weekend is not counted
+	want := []AbsenceTypeTotal{
+		{Type: "Sick leave", Days: 3, Absences: 2},
+		{Type: "Vacation", Days: 2.5, Absences: 1},
+	}
+	if len(got.Types) != len(want) {
+		t.Fatalf("want %+v, got %+v", want, got.Types)
+	}
+	for i := range want {
+		if got.Types[i] != want[i] {
+			t.Errorf("type %d: want %+v, got %+v", i, want[i], got.Types[i])
+		}
+	}
+	if got.TotalDays != 5.5 || got.Absences != 3 {
+		t.Errorf("want 5.5 days in 3 absences, got %v days in %d absences", got.TotalDays, got.Absences)
+	}
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// Schedule is the target amount of work per weekday,
+// indexed by [time.Weekday], so Sunday first.
+type Schedule [7]time.Duration
+
+// Target returns the target amount of work for the day, taking holidays
+// and absences into account.
+func (s Schedule) Target(day Day) time.Duration {
+	target := s[day.Date.Weekday()]
+	if day.Holiday != nil {
+		if !day.Holiday.HalfDay {
+			return 0
+		}
+		target /= 2
+	}
+	if day.Absence != nil {
+		date := personio.DateOf(day.Date)
+		switch {
+		case day.Absence.HalfDayStart && day.Absence.StartDate == date,
+			day.Absence.HalfDayEnd && day.Absence.EndDate == date:
+			target /= 2
+		default:
+			return 0
+		}
+	}
+	return target
+}
+
+// Balance is a comparison of the target amount of work versus the actual
+// amount of work over a date range, i.e a flexitime balance.
+type Balance struct {
+	Start      string       `json:"start"` // ex: "2023-01-01"
+	End        string       `json:"end"`   // ex: "2023-01-31"
+	TargetMin  int          `json:"targetMin"`
+	WorkMin    int          `json:"workMin"`
+	BalanceMin int          `json:"balanceMin"` // Positive means overtime
+	Daily      []BalanceDay `json:"daily"`
+}
+
+// BalanceDay is the target and actual amount of work on a single day,
+// as well as the cumulative amounts since the start of the range.
+type BalanceDay struct {
+	Date                string `json:"date"`
+	TargetMin           int    `json:"targetMin"`
+	WorkMin             int    `json:"workMin"`
+	CumulativeTargetMin int    `json:"cumulativeTargetMin"`
+	CumulativeWorkMin   int    `json:"cumulativeWorkMin"`
+	BalanceMin          int    `json:"balanceMin"`
+}
+
+// CalculateBalance calculates the flexitime balance over the days.
+// Days after the "now" time are skipped, as they have not happened yet.
+func CalculateBalance(days []Day, schedule Schedule, now time.Time) Balance {
+	today := datespec.Date(now)
+	var b Balance
+	for _, day := range days {
+		if day.Date.After(today) {
+			break
+		}
+		targetMin := int(schedule.Target(day).Minutes())
+		workMin := int(day.Work.Minutes())
+		b.TargetMin += targetMin
+		b.WorkMin += workMin
+		b.Daily = append(b.Daily, BalanceDay{
+			Date:                day.Date.Format(time.DateOnly),
+			TargetMin:           targetMin,
+			WorkMin:             workMin,
+			CumulativeTargetMin: b.TargetMin,
+			CumulativeWorkMin:   b.WorkMin,
+			BalanceMin:          b.WorkMin - b.TargetMin,
+		})
+	}
+	b.BalanceMin = b.WorkMin - b.TargetMin
+	if len(b.Daily) > 0 {
+		b.Start = b.Daily[0].Date
+		b.End = b.Daily[len(b.Daily)-1].Date
+	}
+	return b
+}
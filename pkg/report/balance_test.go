@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestCalculateBalance(t *testing.T) {
+	cal := &personio.AttendanceCalendar{}
+	addDay(cal, "2023-01-02", "08:00", "17:00") // Monday, +1h
+	addDay(cal, "2023-01-03", "08:00", "15:00") // -1h
+	// Wednesday is a half-day holiday, so -4h
+	cal.Holidays = &personio.Data[[]personio.CalendarHoliday]{}
+	cal.Holidays.Data = append(cal.Holidays.Data, personio.CalendarHoliday{
+		Date:    personio.NewDate(2023, 1, 4),
+		HalfDay: true,
+	})
+	// Thursday is a full-day absence
+	cal.AbsencePeriods = &personio.Data[[]personio.CalendarAbsencePeriod]{}
+	cal.AbsencePeriods.Data = append(cal.AbsencePeriods.Data, personio.CalendarAbsencePeriod{
+		StartDate: personio.NewDate(2023, 1, 5),
+		StartTime: personio.NewFlexibleTime(time.Date(2023, 1, 5, 0, 0, 0, 0, time.UTC)),
+		EndDate:   personio.NewDate(2023, 1, 5),
+		EndTime:   personio.NewFlexibleTime(time.Date(2023, 1, 6, 0, 0, 0, 0, time.UTC)),
+	})
+	addDay(cal, "2023-01-07", "10:00", "12:00") // Saturday, +2h
+
+	r := datespec.Range{
+		Start: mustParseDate(t, "2023-01-02"),
+		End:   mustParseDate(t, "2023-01-08"),
+	}
+	days, err := Days(cal, r)
+	if err != nil {
+		t.Fatalf("days: %s", err)
+	}
+	schedule := Schedule{
+		time.Monday:    8 * time.Hour,
+		time.Tuesday:   8 * time.Hour,
+		time.Wednesday: 8 * time.Hour,
+		time.Thursday:  8 * time.Hour,
+		time.Friday:    8 * time.Hour,
+	}
+	// Friday is "today", and is therefore included
+	b := CalculateBalance(days, schedule, mustParseDate(t, "2023-01-06").Add(10*time.Hour))
+
+	if len(b.Daily) != 5 {
+		t.Fatalf("want 5 days, got %d", len(b.Daily))
+	}
+	if want := (8 + 8 + 4 + 0 + 8) * 60; b.TargetMin != want {
+		t.Errorf("want target %d min, got %d", want, b.TargetMin)
+	}
+	if want := (9 + 7) * 60; b.WorkMin != want {
+		t.Errorf("want work %d min, got %d", want, b.WorkMin)
+	}
+	if want := -12 * 60; b.BalanceMin != want {
+		t.Errorf("want balance %d min, got %d", want, b.BalanceMin)
+	}
+	if got := b.Daily[1].BalanceMin; got != 0 {
+		t.Errorf("want balance 0 min after Tuesday, got %d", got)
+	}
+}
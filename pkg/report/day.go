@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+// DayDetail is everything known about a single day of attendance,
+// as shown by the "attendance show" command.
+type DayDetail struct {
+	Date string `json:"date"` // ex: "2023-01-20"
+	// DayID is the UUID of Personio's attendance day, which is nil when the
+	// day has never had any attendance.
+	DayID    *uuid.UUID                      `json:"dayId,omitempty"`
+	Status   string                          `json:"status,omitempty"` // ex: "empty"
+	WorkMin  int                             `json:"workMin"`
+	BreakMin int                             `json:"breakMin"`
+	Periods  []PeriodDetail                  `json:"periods"`
+	Absence  *personio.CalendarAbsencePeriod `json:"absence,omitempty"`
+	Holiday  *personio.CalendarHoliday       `json:"holiday,omitempty"`
+	Alerts   []json.RawMessage               `json:"alerts,omitempty"`
+}
+
+// PeriodDetail is a single attendance period of a [DayDetail].
+type PeriodDetail struct {
+	ID          uuid.UUID           `json:"id"`
+	Type        personio.PeriodType `json:"type"`
+	Start       time.Time           `json:"start"`
+	End         time.Time           `json:"end"`
+	DurationMin int                 `json:"durationMin"`
+	Comment     string              `json:"comment,omitempty"`
+	ProjectID   int                 `json:"projectId,omitempty"`
+}
+
+// NewDayDetail collects the details of a single date from the calendar.
+func NewDayDetail(cal *personio.AttendanceCalendar, date time.Time) (DayDetail, error) {
+	date = datespec.Date(date)
+	dayStr := date.Format(time.DateOnly)
+	days, err := Days(cal, datespec.Range{Start: date, End: date})
+	if err != nil {
+		return DayDetail{}, err
+	}
+	detail := DayDetail{
+		Date:    dayStr,
+		Periods: []PeriodDetail{},
+		Alerts:  cal.AlertsOn(dayStr),
+	}
+	if len(days) > 0 {
+		detail.Absence = days[0].Absence
+		detail.Holiday = days[0].Holiday
+	}
+
+	for _, day := range cal.AttendanceDays.Data {
+		if day.Attributes.Day.String() != dayStr {
+			continue
+		}
+		id := day.ID
+		detail.DayID = &id
+		detail.Status = day.Attributes.Status
+		break
+	}
+
+	projects := make(map[uuid.UUID]int, len(cal.AttendancePeriods.Data))
+	for _, p := range cal.AttendancePeriods.Data {
+		projects[p.ID] = p.GetProjectID()
+	}
+	if len(days) > 0 {
+		for _, p := range days[0].Periods {
+			detail.Periods = append(detail.Periods, PeriodDetail{
+				ID:          p.ID,
+				Type:        p.Type,
+				Start:       p.Start,
+				End:         p.End,
+				DurationMin: int(p.Duration().Minutes()),
+				Comment:     p.Comment,
+				ProjectID:   projects[p.ID],
+			})
+		}
+		detail.WorkMin = int(days[0].Work.Minutes())
+		detail.BreakMin = int(days[0].Break.Minutes())
+	}
+	return detail, nil
+}
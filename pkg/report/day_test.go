@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestNewDayDetail(t *testing.T) {
+	cal := &personio.AttendanceCalendar{}
+	addDay(cal, "2023-01-02", "08:00", "16:00")
+	addDay(cal, "2023-01-03", "09:00", "12:00")
+	addDay(cal, "2023-01-03", "13:00", "17:00")
+	cal.AttendanceAlerts = json.RawMessage(`{"data":[{"day":"2023-01-03","type":"missing_break"},{"day":"2023-01-02","type":"other"}]}`)
+
+	detail, err := NewDayDetail(cal, mustParseDate(t, "2023-01-03"))
+	if err != nil {
+		t.Fatalf("new day detail: %s", err)
+	}
+	if detail.DayID == nil {
+		t.Error("want day ID, got nil")
+	}
+	if len(detail.Periods) != 2 {
+		t.Fatalf("want 2 periods, got %d", len(detail.Periods))
+	}
+	if detail.Periods[0].DurationMin != 3*60 {
+		t.Errorf("want first period of %d minutes, got %d", 3*60, detail.Periods[0].DurationMin)
+	}
+	if detail.WorkMin != 7*60 {
+		t.Errorf("want %d work minutes, got %d", 7*60, detail.WorkMin)
+	}
+	if len(detail.Alerts) != 1 {
+		t.Errorf("want 1 alert, got %d: %s", len(detail.Alerts), detail.Alerts)
+	}
+}
+
+func TestNewDayDetail_noAttendance(t *testing.T) {
+	cal := &personio.AttendanceCalendar{}
+	addDay(cal, "2023-01-02", "08:00", "16:00")
+
+	detail, err := NewDayDetail(cal, mustParseDate(t, "2023-01-04"))
+	if err != nil {
+		t.Fatalf("new day detail: %s", err)
+	}
+	if detail.DayID != nil {
+		t.Errorf("want no day ID, got %s", detail.DayID)
+	}
+	if detail.Periods == nil || len(detail.Periods) != 0 {
+		t.Errorf("want empty non-nil periods, got %#v", detail.Periods)
+	}
+}
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// DayStatusPending is the status of days waiting for approval.
+const DayStatusPending = "pending"
+
+// Digest is a summary of a week of attendance, as sent in the weekly email.
+type Digest struct {
+	Start     string `json:"start"` // ex: "2023-01-16"
+	End       string `json:"end"`   // ex: "2023-01-22"
+	WorkMin   int    `json:"workMin"`
+	TargetMin int    `json:"targetMin"`
+	// Findings are the warnings and errors from [Lint], such as workdays
+	// without any work logged.
+	Findings []Finding `json:"findings,omitempty"`
+	// Pending are the dates waiting for approval.
+	Pending []string `json:"pending,omitempty"`
+	// Upcoming are the absences after the week.
+	Upcoming []personio.CalendarAbsencePeriod `json:"upcoming,omitempty"`
+}
+
+// NewDigest summarizes the week, and lists the absences in the upcoming
+// days.
+func NewDigest(week, upcoming []Day, schedule Schedule, now time.Time) Digest {
+	var digest Digest
+	if len(week) > 0 {
+		digest.Start = week[0].Date.Format(time.DateOnly)
+		digest.End = week[len(week)-1].Date.Format(time.DateOnly)
+	}
+	for _, day := range week {
+		digest.WorkMin += int(day.Work.Minutes())
+		digest.TargetMin += int(schedule.Target(day).Minutes())
+		if day.Status == DayStatusPending {
+			digest.Pending = append(digest.Pending, day.Date.Format(time.DateOnly))
+		}
+	}
+	for _, f := range Lint(week, nil, DefaultLintRules, now) {
+		if f.Severity.AtLeast(SeverityWarning) {
+			digest.Findings = append(digest.Findings, f)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, day := range upcoming {
+		if day.Absence == nil || seen[day.Absence.ID] {
+			continue
+		}
+		seen[day.Absence.ID] = true
+		digest.Upcoming = append(digest.Upcoming, *day.Absence)
+	}
+	sort.SliceStable(digest.Upcoming, func(i, j int) bool {
+		return digest.Upcoming[i].StartDate.Before(digest.Upcoming[j].StartDate.Time)
+	})
+	return digest
+}
+
+// WriteText writes the digest as plain text, as used in the email body.
+func (d Digest) WriteText(w io.Writer) error {
+	p := &errWriter{w: w}
+	p.printf("Week %s to %s\n\n", d.Start, d.End)
+	p.printf("Worked:  %s\n", formatLintDuration(time.Duration(d.WorkMin)*time.Minute))
+	p.printf("Target:  %s\n", formatLintDuration(time.Duration(d.TargetMin)*time.Minute))
+	p.printf("Balance: %s\n", formatSignedDuration(time.Duration(d.WorkMin-d.TargetMin)*time.Minute))
+
+	p.printf("\nGaps and issues:\n")
+	if len(d.Findings) == 0 {
+		p.printf("  none\n")
+	}
+	for _, f := range d.Findings {
+		p.printf("  %s  %s\n", f.Date, f.Message)
+	}
+
+	p.printf("\nWaiting for approval:\n")
+	if len(d.Pending) == 0 {
+		p.printf("  none\n")
+	}
+	for _, date := range d.Pending {
+		p.printf("  %s\n", date)
+	}
+
+	p.printf("\nUpcoming absences:\n")
+	if len(d.Upcoming) == 0 {
+		p.printf("  none\n")
+	}
+	for _, a := range d.Upcoming {
+		if a.StartDate == a.EndDate {
+			p.printf("  %s  %s\n", a.StartDate, a.Name)
+		} else {
+			p.printf("  %s to %s  %s\n", a.StartDate, a.EndDate, a.Name)
+		}
+	}
+	return p.err
+}
+
+func formatSignedDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + formatLintDuration(-d)
+	}
+	return "+" + formatLintDuration(d)
+}
+
+// errWriter keeps the first write error, to not check each write.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *errWriter) printf(format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestNewDigest(t *testing.T) {
+	monday := time.Date(2023, 1, 16, 0, 0, 0, 0, time.Local)
+	var week []Day
+	for i := 0; i < 7; i++ {
+		week = append(week, Day{Date: monday.AddDate(0, 0, i)})
+	}
+	week[0].Work = 8 * time.Hour
+	week[0].Status = DayStatusPending
+	week[1].Work = 7 * time.Hour
+	// Wednesday to Friday are missing
+	vacation := &personio.CalendarAbsencePeriod{ID: "1", Name: "Vacation", StartDate: personio.NewDate(2023, 1, 24), EndDate: personio.NewDate(2023, 1, 25)}
+	upcoming := []Day{
+		{Date: monday.AddDate(0, 0, 7)},
+		{Date: monday.AddDate(0, 0, 8), Absence: vacation},
+		{Date: monday.AddDate(0, 0, 9), Absence: vacation},
+	}
+	schedule := Schedule{time.Monday: 8 * time.Hour, time.Tuesday: 8 * time.Hour}
+
+	digest := NewDigest(week, upcoming, schedule, monday.AddDate(0, 0, 7))
+
+	if digest.Start != "2023-01-16" || digest.End != "2023-01-22" {
+		t.Errorf("want week 2023-01-16 to 2023-01-22, got %s to %s", digest.Start, digest.End)
+	}
+	if digest.WorkMin != 15*60 || digest.TargetMin != 16*60 {
+		t.Errorf("want 900 of 960 min, got %d of %d min", digest.WorkMin, digest.TargetMin)
+	}
+	if len(digest.Findings) != 3 {
+		t.Errorf("want 3 missing days, got %+v", digest.Findings)
+	}
+	if len(digest.Pending) != 1 || digest.Pending[0] != "2023-01-16" {
+		t.Errorf("want 2023-01-16 pending, got %v", digest.Pending)
+	}
+	if len(digest.Upcoming) != 1 {
+		t.Errorf("want 1 upcoming absence, got %+v", digest.Upcoming)
+	}
+
+	var buf bytes.Buffer
+	if err := digest.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Balance: -1h00m", "2023-01-24 to 2023-01-25  Vacation"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("want text to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/google/uuid"
+)
+
+// Severity is how serious a [Finding] is.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityInfo:    1,
+	SeverityWarning: 2,
+	SeverityError:   3,
+}
+
+// ParseSeverity returns the severity of the given name.
+func ParseSeverity(s string) (Severity, error) {
+	sev := Severity(strings.ToLower(s))
+	if _, ok := severityRanks[sev]; !ok {
+		return "", fmt.Errorf("invalid severity %q, must be one of: info, warning, error", s)
+	}
+	return sev, nil
+}
+
+// AtLeast returns true if the severity is equal to or more severe than
+// the other severity.
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRanks[s] >= severityRanks[other]
+}
+
+// Rules checked by [Lint].
+const (
+	LintRuleMissingDay        = "missing-day"
+	LintRuleDuplicatePeriod   = "duplicate-period"
+	LintRuleOverlappingPeriod = "overlapping-period"
+	LintRuleLongDay           = "long-day"
+	LintRuleHolidayWork       = "holiday-work"
+	LintRuleAbsenceWork       = "absence-work"
+	LintRuleUnusualDay        = "unusual-day"
+)
+
+// Finding is a suspicious attendance entry reported by [Lint].
+type Finding struct {
+	Date     string     `json:"date"`
+	Severity Severity   `json:"severity"`
+	Rule     string     `json:"rule"`
+	Message  string     `json:"message"`
+	PeriodID *uuid.UUID `json:"periodId,omitempty"`
+}
+
+// LintRules configures the checks done by [Lint].
+type LintRules struct {
+	// MaxDay is the longest amount of work per day that is not reported.
+	MaxDay time.Duration
+	// MinBaselineDays is the number of workdays that the baseline needs
+	// before days are compared against it.
+	MinBaselineDays int
+	// UnusualDeviation is the least deviation from the baseline for a day
+	// to be reported as unusual. Deviations must also be outside 3
+	// standard deviations from the baseline's mean.
+	UnusualDeviation time.Duration
+}
+
+// DefaultLintRules are the rules used when nothing else is configured.
+var DefaultLintRules = LintRules{
+	MaxDay:           12 * time.Hour,
+	MinBaselineDays:  10,
+	UnusualDeviation: 2 * time.Hour,
+}
+
+// Lint flags suspicious entries in the given days, such as missing days,
+// duplicate periods, long days, and work on holidays or during absences.
+// Workdays before today without any work are reported as missing.
+//
+// Days are also compared against the baseline, which is typically the
+// months before the linted days, and are reported when their start, end,
+// or amount of work differs wildly from the usual.
+func Lint(days, baseline []Day, rules LintRules, now time.Time) []Finding {
+	var findings []Finding
+	base := newLintBaseline(baseline, rules)
+	today := datespec.Date(now)
+	for _, day := range days {
+		isPast := day.Date.Before(today)
+		findings = append(findings, lintDay(day, isPast, base, rules)...)
+	}
+	return findings
+}
+
+func lintDay(day Day, isPast bool, base *lintBaseline, rules LintRules) []Finding {
+	var findings []Finding
+	dateStr := day.Date.Format(time.DateOnly)
+	add := func(sev Severity, rule string, periodID *uuid.UUID, format string, args ...any) {
+		findings = append(findings, Finding{
+			Date:     dateStr,
+			Severity: sev,
+			Rule:     rule,
+			Message:  fmt.Sprintf(format, args...),
+			PeriodID: periodID,
+		})
+	}
+
+	for i, p := range day.Periods {
+		for _, other := range day.Periods[:i] {
+			id := p.ID
+			switch {
+			case p.Type == other.Type && p.Start.Equal(other.Start) && p.End.Equal(other.End):
+				add(SeverityError, LintRuleDuplicatePeriod, &id,
+					"%s period %s-%s is a duplicate", p.Type, p.Start.Format("15:04"), p.End.Format("15:04"))
+			case p.Start.Before(other.End) && other.Start.Before(p.End):
+				add(SeverityError, LintRuleOverlappingPeriod, &id,
+					"%s period %s-%s overlaps with %s period %s-%s",
+					p.Type, p.Start.Format("15:04"), p.End.Format("15:04"),
+					other.Type, other.Start.Format("15:04"), other.End.Format("15:04"))
+			}
+		}
+	}
+
+	if !day.HasWork() {
+		if isPast && day.IsWorkday() {
+			add(SeverityWarning, LintRuleMissingDay, nil, "no work logged on a workday")
+		}
+		return findings
+	}
+	if rules.MaxDay > 0 && day.Work > rules.MaxDay {
+		add(SeverityWarning, LintRuleLongDay, nil,
+			"worked %s, which is more than %s", formatLintDuration(day.Work), formatLintDuration(rules.MaxDay))
+	}
+	if day.Holiday != nil && !day.Holiday.HalfDay {
+		add(SeverityWarning, LintRuleHolidayWork, nil,
+			"worked %s on public holiday %q", formatLintDuration(day.Work), day.Holiday.Name)
+	}
+	if day.Absence != nil {
+		add(SeverityWarning, LintRuleAbsenceWork, nil,
+			"worked %s during booked absence %q", formatLintDuration(day.Work), day.Absence.Name)
+	}
+	if base != nil {
+		if reasons := base.unusual(day); len(reasons) > 0 {
+			add(SeverityInfo, LintRuleUnusualDay, nil,
+				"differs from your usual days: %s", strings.Join(reasons, ", "))
+		}
+	}
+	return findings
+}
+
+type lintBaseline struct {
+	start, end, work lintMetric
+	minDeviation     float64
+}
+
+type lintMetric struct {
+	mean, stddev float64
+}
+
+func newLintBaseline(days []Day, rules LintRules) *lintBaseline {
+	var starts, ends, works []float64
+	for _, day := range days {
+		if !day.IsWorkday() || !day.HasWork() {
+			continue
+		}
+		starts = append(starts, float64(minuteOfDay(day.FirstStart())))
+		ends = append(ends, float64(minuteOfDay(day.LastEnd())))
+		works = append(works, day.Work.Minutes())
+	}
+	if len(works) == 0 || len(works) < rules.MinBaselineDays {
+		return nil
+	}
+	return &lintBaseline{
+		start:        newLintMetric(starts),
+		end:          newLintMetric(ends),
+		work:         newLintMetric(works),
+		minDeviation: rules.UnusualDeviation.Minutes(),
+	}
+}
+
+func newLintMetric(values []float64) lintMetric {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values))
+	return lintMetric{mean: mean, stddev: math.Sqrt(variance)}
+}
+
+func (m lintMetric) deviates(value, minDeviation float64) bool {
+	diff := math.Abs(value - m.mean)
+	return diff >= minDeviation && diff > 3*m.stddev
+}
+
+func (b *lintBaseline) unusual(day Day) []string {
+	var reasons []string
+	if start := minuteOfDay(day.FirstStart()); b.start.deviates(float64(start), b.minDeviation) {
+		reasons = append(reasons, fmt.Sprintf("started at %s instead of around %s",
+			day.FirstStart().Format("15:04"), formatMinuteOfDay(int(math.Round(b.start.mean)))))
+	}
+	if end := minuteOfDay(day.LastEnd()); b.end.deviates(float64(end), b.minDeviation) {
+		reasons = append(reasons, fmt.Sprintf("ended at %s instead of around %s",
+			day.LastEnd().Format("15:04"), formatMinuteOfDay(int(math.Round(b.end.mean)))))
+	}
+	if b.work.deviates(day.Work.Minutes(), b.minDeviation) {
+		reasons = append(reasons, fmt.Sprintf("worked %s instead of around %s",
+			formatLintDuration(day.Work), formatLintDuration(time.Duration(b.work.mean*float64(time.Minute)))))
+	}
+	return reasons
+}
+
+func formatLintDuration(d time.Duration) string {
+	minute := int(d.Round(time.Minute).Minutes())
+	return fmt.Sprintf("%dh%02dm", minute/60, minute%60)
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestLint(t *testing.T) {
+	cal := &personio.AttendanceCalendar{}
+	addDay(cal, "2023-03-01", "08:00", "12:00")
+	addDay(cal, "2023-03-01", "08:00", "12:00") // duplicate
+	addDay(cal, "2023-03-02", "08:00", "12:00")
+	addDay(cal, "2023-03-02", "11:00", "16:00") // overlapping
+	addDay(cal, "2023-03-03", "06:00", "19:00") // long day
+	addDay(cal, "2023-03-07", "08:00", "16:00") // holiday
+	addDay(cal, "2023-03-08", "13:00", "23:00") // unusual start and end
+	cal.Holidays = &personio.Data[[]personio.CalendarHoliday]{}
+	cal.Holidays.Data = append(cal.Holidays.Data, personio.CalendarHoliday{
+		Name: "Some holiday",
+		Date: personio.NewDate(2023, 3, 7),
+	})
+	days, err := Days(cal, datespec.Range{
+		Start: mustParseDate(t, "2023-03-01"),
+		End:   mustParseDate(t, "2023-03-08"),
+	})
+	if err != nil {
+		t.Fatalf("days: %s", err)
+	}
+
+	baseCal := &personio.AttendanceCalendar{}
+	for i := 1; i <= 28; i++ {
+		addDay(baseCal, fmt.Sprintf("2023-02-%02d", i), "08:00", "16:00")
+	}
+	baseline, err := Days(baseCal, datespec.Range{
+		Start: mustParseDate(t, "2023-02-01"),
+		End:   mustParseDate(t, "2023-02-28"),
+	})
+	if err != nil {
+		t.Fatalf("baseline days: %s", err)
+	}
+
+	findings := Lint(days, baseline, DefaultLintRules, mustParseDate(t, "2023-03-08"))
+
+	want := []struct {
+		date     string
+		rule     string
+		severity Severity
+	}{
+		{"2023-03-01", LintRuleDuplicatePeriod, SeverityError},
+		{"2023-03-01", LintRuleUnusualDay, SeverityInfo},
+		{"2023-03-02", LintRuleOverlappingPeriod, SeverityError},
+		{"2023-03-03", LintRuleLongDay, SeverityWarning},
+		{"2023-03-03", LintRuleUnusualDay, SeverityInfo},
+		{"2023-03-06", LintRuleMissingDay, SeverityWarning},
+		{"2023-03-07", LintRuleHolidayWork, SeverityWarning},
+		{"2023-03-08", LintRuleUnusualDay, SeverityInfo},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("want %d findings, got %d: %+v", len(want), len(findings), findings)
+	}
+	for i, w := range want {
+		f := findings[i]
+		if f.Date != w.date || f.Rule != w.rule || f.Severity != w.severity {
+			t.Errorf("finding #%d: want %s %s %s, got %s %s %s",
+				i, w.date, w.rule, w.severity, f.Date, f.Rule, f.Severity)
+		}
+	}
+}
+
+func TestSeverityAtLeast(t *testing.T) {
+	tests := []struct {
+		severity Severity
+		other    Severity
+		want     bool
+	}{
+		{SeverityError, SeverityWarning, true},
+		{SeverityWarning, SeverityWarning, true},
+		{SeverityInfo, SeverityWarning, false},
+	}
+	for _, tc := range tests {
+		if got := tc.severity.AtLeast(tc.other); got != tc.want {
+			t.Errorf("%s.AtLeast(%s): want %t, got %t", tc.severity, tc.other, tc.want, got)
+		}
+	}
+}
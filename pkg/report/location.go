@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// Location is where a day of work took place.
+type Location string
+
+// Available [Location] values.
+const (
+	LocationOffice  Location = "office"
+	LocationRemote  Location = "remote"
+	LocationUnknown Location = "unknown"
+)
+
+// LocationRules classifies periods by keywords in their comments.
+// Keywords are matched case-insensitively.
+type LocationRules struct {
+	RemoteKeywords []string
+	OfficeKeywords []string
+	Default        Location
+}
+
+// PeriodLocation returns the location of a single period.
+func (r LocationRules) PeriodLocation(p Period) Location {
+	comment := strings.ToLower(p.Comment)
+	for _, keyword := range r.RemoteKeywords {
+		if keyword != "" && strings.Contains(comment, strings.ToLower(keyword)) {
+			return LocationRemote
+		}
+	}
+	for _, keyword := range r.OfficeKeywords {
+		if keyword != "" && strings.Contains(comment, strings.ToLower(keyword)) {
+			return LocationOffice
+		}
+	}
+	if r.Default == "" {
+		return LocationUnknown
+	}
+	return r.Default
+}
+
+// DayLocation returns the location where most of the day's work took place,
+// or an empty string if the day has no work.
+func (r LocationRules) DayLocation(day Day) Location {
+	var durations = map[Location]time.Duration{}
+	for _, p := range day.Periods {
+		if p.Type == personio.PeriodTypeBreak {
+			continue
+		}
+		durations[r.PeriodLocation(p)] += p.Duration()
+	}
+	var best Location
+	var bestDur time.Duration
+	// Iterate in fixed order, so ties are resolved consistently
+	for _, loc := range []Location{LocationRemote, LocationOffice, LocationUnknown} {
+		if durations[loc] > bestDur {
+			best = loc
+			bestDur = durations[loc]
+		}
+	}
+	return best
+}
+
+// LocationSummary is the number of days worked per location, as used in
+// e.g German tax declarations (Homeoffice-Pauschale and Pendlerpauschale).
+type LocationSummary struct {
+	Start       string                 `json:"start"`
+	End         string                 `json:"end"`
+	OfficeDays  int                    `json:"officeDays"`
+	RemoteDays  int                    `json:"remoteDays"`
+	UnknownDays int                    `json:"unknownDays"`
+	Months      []LocationMonthSummary `json:"months"`
+}
+
+// LocationMonthSummary is the number of days worked per location in a month.
+type LocationMonthSummary struct {
+	Month       string `json:"month"` // ex: "2023-01"
+	OfficeDays  int    `json:"officeDays"`
+	RemoteDays  int    `json:"remoteDays"`
+	UnknownDays int    `json:"unknownDays"`
+}
+
+func (s *LocationMonthSummary) add(loc Location) {
+	switch loc {
+	case LocationOffice:
+		s.OfficeDays++
+	case LocationRemote:
+		s.RemoteDays++
+	case LocationUnknown:
+		s.UnknownDays++
+	}
+}
+
+// SummarizeLocations counts the days worked per location.
+func SummarizeLocations(days []Day, rules LocationRules) LocationSummary {
+	var summary LocationSummary
+	var month *LocationMonthSummary
+	for _, day := range days {
+		monthStr := day.Date.Format("2006-01")
+		if month == nil || month.Month != monthStr {
+			summary.Months = append(summary.Months, LocationMonthSummary{Month: monthStr})
+			month = &summary.Months[len(summary.Months)-1]
+		}
+		loc := rules.DayLocation(day)
+		month.add(loc)
+	}
+	for _, m := range summary.Months {
+		summary.OfficeDays += m.OfficeDays
+		summary.RemoteDays += m.RemoteDays
+		summary.UnknownDays += m.UnknownDays
+	}
+	if len(days) > 0 {
+		summary.Start = days[0].Date.Format(time.DateOnly)
+		summary.End = days[len(days)-1].Date.Format(time.DateOnly)
+	}
+	return summary
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"testing"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestSummarizeLocations(t *testing.T) {
+	cal := &personio.AttendanceCalendar{}
+	addDay(cal, "2023-01-30", "08:00", "16:00")
+	addDay(cal, "2023-01-31", "08:00", "16:00")
+	addDay(cal, "2023-02-01", "08:00", "16:00")
+	addDay(cal, "2023-02-02", "08:00", "09:00")
+	addDay(cal, "2023-02-02", "09:00", "16:00")
+	comments := []string{"#HomeOffice coding", "meetings #office", "", "#office standup", "#remote coding"}
+	for i := range cal.AttendancePeriods.Data {
+		cal.AttendancePeriods.Data[i].Attributes.Comment = &comments[i]
+	}
+
+	days, err := Days(cal, datespec.Range{
+		Start: mustParseDate(t, "2023-01-30"),
+		End:   mustParseDate(t, "2023-02-03"),
+	})
+	if err != nil {
+		t.Fatalf("days: %s", err)
+	}
+	summary := SummarizeLocations(days, LocationRules{
+		RemoteKeywords: []string{"#remote", "#homeoffice"},
+		OfficeKeywords: []string{"#office"},
+	})
+
+	if summary.RemoteDays != 2 {
+		t.Errorf("want 2 remote days, got %d", summary.RemoteDays)
+	}
+	if summary.OfficeDays != 1 {
+		t.Errorf("want 1 office day, got %d", summary.OfficeDays)
+	}
+	if summary.UnknownDays != 1 {
+		t.Errorf("want 1 unknown day, got %d", summary.UnknownDays)
+	}
+	if len(summary.Months) != 2 {
+		t.Fatalf("want 2 months, got %d", len(summary.Months))
+	}
+	if m := summary.Months[0]; m.Month != "2023-01" || m.RemoteDays != 1 || m.OfficeDays != 1 {
+		t.Errorf("want 2023-01 with 1 remote and 1 office day, got %+v", m)
+	}
+}
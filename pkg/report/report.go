@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package report contains calculations of statistics and summaries over
+// attendance data, such as used by the "stats" command.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/timesheet"
+	"github.com/google/uuid"
+)
+
+// Day is a single day of attendance, flattened from the different sections
+// of a [personio.AttendanceCalendar].
+type Day struct {
+	Date    time.Time
+	Periods []Period
+	Work    time.Duration
+	Break   time.Duration
+	Holiday *personio.CalendarHoliday
+	Absence *personio.CalendarAbsencePeriod
+	// Status is Personio's approval status of the day, e.g "empty" or
+	// "pending".
+	Status string
+}
+
+// Locked returns true if the day is in a month that Personio has locked
+// after a payroll run, so its attendance cannot be changed anymore.
+func (d Day) Locked() bool {
+	return d.Status == personio.DayStatusLocked
+}
+
+// Period is an attendance period with its times parsed and converted to
+// local time.
+type Period struct {
+	ID      uuid.UUID
+	Type    personio.PeriodType
+	Start   time.Time
+	End     time.Time
+	Comment string
+	// Origin is how the period was created in Personio, e.g "web", or
+	// empty if unknown.
+	Origin personio.PeriodOrigin
+	// Tags are local-only tags from the mirror, which Personio doesn't
+	// know about. Not set by [Days].
+	Tags []string
+	// Note is a local-only note from the mirror. Not set by [Days].
+	Note string
+}
+
+// Duration returns the time between the start and end of the period.
+func (p Period) Duration() time.Duration {
+	return p.End.Sub(p.Start)
+}
+
+// IsWorkday returns true if the day is a weekday that is neither a full-day
+// holiday nor covered by an absence, i.e a day where attendance is expected.
+func (d Day) IsWorkday() bool {
+	switch d.Date.Weekday() {
+	case time.Saturday, time.Sunday:
+		return false
+	}
+	if d.Holiday != nil && !d.Holiday.HalfDay {
+		return false
+	}
+	return d.Absence == nil
+}
+
+// HasWork returns true if the day has any work periods.
+func (d Day) HasWork() bool {
+	return d.Work > 0
+}
+
+// FirstStart returns the start time of the first work period,
+// or the zero time if there are no work periods.
+func (d Day) FirstStart() time.Time {
+	for _, p := range d.Periods {
+		if p.Type == personio.PeriodTypeWork {
+			return p.Start
+		}
+	}
+	return time.Time{}
+}
+
+// LastEnd returns the end time of the last work period,
+// or the zero time if there are no work periods.
+func (d Day) LastEnd() time.Time {
+	for i := len(d.Periods) - 1; i >= 0; i-- {
+		if d.Periods[i].Type == personio.PeriodTypeWork {
+			return d.Periods[i].End
+		}
+	}
+	return time.Time{}
+}
+
+// Days flattens the calendar into one [Day] per date in the range.
+func Days(cal *personio.AttendanceCalendar, r datespec.Range) ([]Day, error) {
+	entries, err := timesheet.FromCalendar(cal)
+	if err != nil {
+		return nil, err
+	}
+	periodsPerDay := make(map[string][]Period)
+	for _, e := range entries {
+		period, err := periodFromEntry(e)
+		if err != nil {
+			return nil, err
+		}
+		periodsPerDay[e.Date] = append(periodsPerDay[e.Date], period)
+	}
+
+	holidays := make(map[string]*personio.CalendarHoliday, len(cal.GetHolidays()))
+	for i, h := range cal.GetHolidays() {
+		holidays[h.Date.String()] = &cal.Holidays.Data[i]
+	}
+
+	statuses := make(map[string]string, len(cal.AttendanceDays.Data))
+	for _, d := range cal.AttendanceDays.Data {
+		statuses[d.Attributes.Day.String()] = d.Attributes.Status
+	}
+
+	days := make([]Day, 0, r.Days())
+	r.Each(func(date time.Time) {
+		dayStr := date.Format(time.DateOnly)
+		day := Day{
+			Date:    date,
+			Status:  statuses[dayStr],
+			Periods: periodsPerDay[dayStr],
+			Holiday: holidays[dayStr],
+		}
+		sortPeriods(day.Periods)
+		day.sumPeriods()
+		day.Absence = findAbsence(date, cal.GetAbsencePeriods())
+		days = append(days, day)
+	})
+	return days, nil
+}
+
+func periodFromEntry(e timesheet.Entry) (Period, error) {
+	id, err := uuid.Parse(e.SourceID)
+	if err != nil {
+		return Period{}, fmt.Errorf("parse period ID %q: %w", e.SourceID, err)
+	}
+	return Period{
+		ID:      id,
+		Type:    e.Type,
+		Start:   e.Start.Local(),
+		End:     e.End.Local(),
+		Comment: e.GetComment(),
+		Origin:  e.Origin,
+	}, nil
+}
+
+// sumPeriods sets the day's work and break durations from its periods.
+func (d *Day) sumPeriods() {
+	d.Work, d.Break = 0, 0
+	for _, p := range d.Periods {
+		switch p.Type {
+		case personio.PeriodTypeBreak:
+			d.Break += p.Duration()
+		default:
+			d.Work += p.Duration()
+		}
+	}
+}
+
+// FilterOrigins returns copies of the days with only the periods that were
+// created via one of the origins, and with their work and break durations
+// recalculated. The empty origin matches periods of unknown origin.
+func FilterOrigins(days []Day, origins []personio.PeriodOrigin) []Day {
+	keep := make(map[personio.PeriodOrigin]bool, len(origins))
+	for _, o := range origins {
+		keep[o] = true
+	}
+	filtered := make([]Day, len(days))
+	for i, d := range days {
+		periods := make([]Period, 0, len(d.Periods))
+		for _, p := range d.Periods {
+			if keep[p.Origin] {
+				periods = append(periods, p)
+			}
+		}
+		d.Periods = periods
+		d.sumPeriods()
+		filtered[i] = d
+	}
+	return filtered
+}
+
+func sortPeriods(periods []Period) {
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Start.Before(periods[j].Start)
+	})
+}
+
+func findAbsence(date time.Time, absences []personio.CalendarAbsencePeriod) *personio.CalendarAbsencePeriod {
+	// Absence start and end times are in local time, without time zone,
+	// where the end time is exclusive.
+	t := date.Add(time.Minute)
+	for i, absence := range absences {
+		if t.After(absence.StartTime.Time) && t.Before(absence.EndTime.Time) {
+			return &absences[i]
+		}
+	}
+	return nil
+}
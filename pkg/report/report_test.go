@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestFilterOrigins(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2023, 1, 18, hour, 0, 0, 0, time.UTC)
+	}
+	days := []Day{{
+		Date: at(0),
+		Periods: []Period{
+			{Type: personio.PeriodTypeWork, Start: at(8), End: at(12), Origin: personio.PeriodOriginAPI},
+			{Type: personio.PeriodTypeBreak, Start: at(12), End: at(13), Origin: personio.PeriodOriginWeb},
+			{Type: personio.PeriodTypeWork, Start: at(13), End: at(15), Origin: personio.PeriodOriginWeb},
+			{Type: personio.PeriodTypeWork, Start: at(15), End: at(16)},
+		},
+		Work:  7 * time.Hour,
+		Break: time.Hour,
+	}}
+
+	got := FilterOrigins(days, []personio.PeriodOrigin{personio.PeriodOriginWeb})
+	if len(got[0].Periods) != 2 {
+		t.Fatalf("want 2 web periods, got %d", len(got[0].Periods))
+	}
+	if got[0].Work != 2*time.Hour || got[0].Break != time.Hour {
+		t.Errorf("want 2h work and 1h break, got %s and %s", got[0].Work, got[0].Break)
+	}
+
+	got = FilterOrigins(days, []personio.PeriodOrigin{personio.PeriodOriginAPI, ""})
+	if got[0].Work != 5*time.Hour || got[0].Break != 0 {
+		t.Errorf("want 5h work of API and unknown origin, got %s and %s", got[0].Work, got[0].Break)
+	}
+
+	if len(days[0].Periods) != 4 || days[0].Work != 7*time.Hour {
+		t.Error("want the original days to be unchanged")
+	}
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+)
+
+// Stats contains statistics about attendance over a date range.
+type Stats struct {
+	Start string `json:"start"` // ex: "2023-01-01"
+	End   string `json:"end"`   // ex: "2023-01-31"
+
+	Workdays    int `json:"workdays"`    // Weekdays without holidays or absences
+	DaysWorked  int `json:"daysWorked"`  // Days with any work periods
+	MissingDays int `json:"missingDays"` // Past workdays without work periods
+
+	TotalWorkMin   int `json:"totalWorkMin"`
+	AverageWorkMin int `json:"averageWorkMin"` // Per day worked
+
+	AverageStart  string `json:"averageStart,omitempty"`  // ex: "08:45"
+	AverageEnd    string `json:"averageEnd,omitempty"`    // ex: "17:15"
+	EarliestStart string `json:"earliestStart,omitempty"` // ex: "07:30"
+	LatestEnd     string `json:"latestEnd,omitempty"`     // ex: "19:00"
+
+	LongestDay    *StatsDay    `json:"longestDay,omitempty"`
+	LongestStreak *StatsStreak `json:"longestStreak,omitempty"`
+	CurrentStreak int          `json:"currentStreak"`
+
+	Histogram []StatsHistogramBucket `json:"histogram"`
+	Daily     []StatsDay             `json:"daily"`
+}
+
+// StatsDay is the amount of work on a single day.
+type StatsDay struct {
+	Date    string `json:"date"`
+	WorkMin int    `json:"workMin"`
+}
+
+// StatsStreak is a range of consecutive workdays that all have work periods.
+// Weekends, holidays, and absences do not break a streak.
+type StatsStreak struct {
+	Days  int    `json:"days"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// StatsHistogramBucket is the number of days worked that had a total amount
+// of work between FromHours (inclusive) and ToHours (exclusive).
+type StatsHistogramBucket struct {
+	FromHours int `json:"fromHours"`
+	ToHours   int `json:"toHours"`
+	Days      int `json:"days"`
+}
+
+// statsHistogramMaxHours is the upper bound of the histogram, where the
+// last bucket contains all days with this amount of work or more.
+const statsHistogramMaxHours = 12
+
+// CalculateStats calculates statistics over the days. Days after the "now"
+// time are not counted as missing days, nor do they break streaks.
+func CalculateStats(days []Day, r datespec.Range, now time.Time) Stats {
+	stats := Stats{
+		Start: r.Start.Format(time.DateOnly),
+		End:   r.End.Format(time.DateOnly),
+	}
+	today := datespec.Date(now)
+
+	var (
+		sumStartMin, sumEndMin int
+		earliestStart          = -1
+		latestEnd              = -1
+		streak                 StatsStreak
+	)
+	stats.Histogram = make([]StatsHistogramBucket, statsHistogramMaxHours+1)
+	for i := range stats.Histogram {
+		stats.Histogram[i] = StatsHistogramBucket{FromHours: i, ToHours: i + 1}
+	}
+
+	for _, day := range days {
+		dateStr := day.Date.Format(time.DateOnly)
+		workMin := int(day.Work.Minutes())
+		stats.Daily = append(stats.Daily, StatsDay{Date: dateStr, WorkMin: workMin})
+
+		isPast := !day.Date.After(today)
+		if day.IsWorkday() {
+			stats.Workdays++
+			if isPast {
+				if day.HasWork() {
+					if streak.Days == 0 {
+						streak.Start = dateStr
+					}
+					streak.Days++
+					streak.End = dateStr
+					if stats.LongestStreak == nil || streak.Days > stats.LongestStreak.Days {
+						longest := streak
+						stats.LongestStreak = &longest
+					}
+				} else if !day.Date.Equal(today) {
+					// Today is not over yet, so it doesn't count as missing
+					stats.MissingDays++
+					streak = StatsStreak{}
+				}
+			}
+		}
+
+		if !day.HasWork() {
+			continue
+		}
+		stats.DaysWorked++
+		stats.TotalWorkMin += workMin
+		if stats.LongestDay == nil || workMin > stats.LongestDay.WorkMin {
+			stats.LongestDay = &StatsDay{Date: dateStr, WorkMin: workMin}
+		}
+		bucket := workMin / 60
+		if bucket > statsHistogramMaxHours {
+			bucket = statsHistogramMaxHours
+		}
+		stats.Histogram[bucket].Days++
+
+		startMin := minuteOfDay(day.FirstStart())
+		endMin := minuteOfDay(day.LastEnd())
+		if !datespec.Date(day.LastEnd()).Equal(datespec.Date(day.FirstStart())) {
+			// Work past midnight
+			endMin += 24 * 60
+		}
+		sumStartMin += startMin
+		sumEndMin += endMin
+		if earliestStart == -1 || startMin < earliestStart {
+			earliestStart = startMin
+		}
+		if latestEnd == -1 || endMin > latestEnd {
+			latestEnd = endMin
+		}
+	}
+	stats.Histogram[statsHistogramMaxHours].ToHours = 24
+
+	stats.CurrentStreak = streak.Days
+	if stats.DaysWorked > 0 {
+		stats.AverageWorkMin = stats.TotalWorkMin / stats.DaysWorked
+		stats.AverageStart = formatMinuteOfDay(sumStartMin / stats.DaysWorked)
+		stats.AverageEnd = formatMinuteOfDay(sumEndMin / stats.DaysWorked)
+		stats.EarliestStart = formatMinuteOfDay(earliestStart)
+		stats.LatestEnd = formatMinuteOfDay(latestEnd)
+	}
+	return stats
+}
+
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+func formatMinuteOfDay(m int) string {
+	m %= 24 * 60
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
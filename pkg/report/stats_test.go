@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+func TestCalculateStats(t *testing.T) {
+	cal := &personio.AttendanceCalendar{}
+	addDay(cal, "2023-01-02", "08:00", "16:00") // Monday
+	addDay(cal, "2023-01-03", "09:00", "19:00")
+	// Wednesday 2023-01-04 is missing, and breaks the streak
+	addDay(cal, "2023-01-05", "07:00", "15:00")
+	addDay(cal, "2023-01-06", "08:00", "12:00") // Friday, holiday
+	cal.Holidays = &personio.Data[[]personio.CalendarHoliday]{}
+	cal.Holidays.Data = append(cal.Holidays.Data, personio.CalendarHoliday{
+		Date: personio.NewDate(2023, 1, 6),
+		Name: "Heilige Drei Könige",
+	})
+	addDay(cal, "2023-01-09", "08:00", "17:00") // Monday
+	addDay(cal, "2023-01-10", "08:00", "16:00")
+
+	r := datespec.Range{
+		Start: mustParseDate(t, "2023-01-02"),
+		End:   mustParseDate(t, "2023-01-10"),
+	}
+	days, err := Days(cal, r)
+	if err != nil {
+		t.Fatalf("days: %s", err)
+	}
+	if len(days) != 9 {
+		t.Fatalf("want 9 days, got %d", len(days))
+	}
+
+	stats := CalculateStats(days, r, mustParseDate(t, "2023-01-31"))
+
+	if stats.Workdays != 6 {
+		t.Errorf("want 6 workdays, got %d", stats.Workdays)
+	}
+	if stats.DaysWorked != 6 {
+		t.Errorf("want 6 days worked, got %d", stats.DaysWorked)
+	}
+	if stats.MissingDays != 1 {
+		t.Errorf("want 1 missing day, got %d", stats.MissingDays)
+	}
+	if stats.TotalWorkMin != 47*60 {
+		t.Errorf("want %d total work minutes, got %d", 47*60, stats.TotalWorkMin)
+	}
+	if stats.LongestDay == nil || stats.LongestDay.Date != "2023-01-03" {
+		t.Errorf("want longest day 2023-01-03, got %+v", stats.LongestDay)
+	}
+	// The holiday on Friday does not break the streak
+	if stats.LongestStreak == nil || stats.LongestStreak.Days != 3 ||
+		stats.LongestStreak.Start != "2023-01-05" || stats.LongestStreak.End != "2023-01-10" {
+		t.Errorf("want longest streak of 3 days 2023-01-05 to 2023-01-10, got %+v", stats.LongestStreak)
+	}
+	if stats.EarliestStart != "07:00" {
+		t.Errorf("want earliest start 07:00, got %q", stats.EarliestStart)
+	}
+	if stats.LatestEnd != "19:00" {
+		t.Errorf("want latest end 19:00, got %q", stats.LatestEnd)
+	}
+	if got := stats.Histogram[8].Days; got != 3 {
+		t.Errorf("want 3 days in 8-9h bucket, got %d", got)
+	}
+}
+
+func addDay(cal *personio.AttendanceCalendar, day, start, end string) {
+	dayID := uuid.New()
+	date, _ := personio.ParseDate(day)
+	cal.AttendanceDays.Data = append(cal.AttendanceDays.Data, personio.CalendarDay{
+		ID:         dayID,
+		Attributes: personio.CalendarDayAttributes{Day: date},
+	})
+	// Use local time, as that's what the report package converts to
+	startTime, _ := time.ParseInLocation("2006-01-02 15:04", day+" "+start, time.Local)
+	endTime, _ := time.ParseInLocation("2006-01-02 15:04", day+" "+end, time.Local)
+	cal.AttendancePeriods.Data = append(cal.AttendancePeriods.Data, personio.CalendarAttendancePeriod{
+		ID: uuid.New(),
+		Attributes: personio.CalendarAttendancePeriodAttributes{
+			AttendanceDayID: dayID,
+			PeriodType:      string(personio.PeriodTypeWork),
+			Start:           personio.NewFlexibleTime(startTime.UTC()),
+			End:             personio.NewFlexibleTime(endTime.UTC()),
+		},
+	})
+}
+
+func mustParseDate(t *testing.T, value string) time.Time {
+	t.Helper()
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		t.Fatalf("parse date %q: %s", value, err)
+	}
+	return date
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// SurchargeRules defines which work is eligible for surcharges.
+type SurchargeRules struct {
+	// NightStart and NightEnd are the minute of the day when night work
+	// starts and ends. The night may span midnight, e.g 22:00 to 06:00.
+	NightStart int
+	NightEnd   int
+	// OnCallKeywords are words in period comments that mark the whole
+	// period as on-call work. Matched case-insensitively.
+	OnCallKeywords []string
+}
+
+// ParseTimeOfDay parses a time of day in the format "15:04" into the number
+// of minutes since midnight.
+func ParseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("parse time of day: %w", err)
+	}
+	return minuteOfDay(t), nil
+}
+
+// SurchargeSummary is the amount of surcharge-eligible work per category.
+// The categories overlap, so work on a Sunday night counts towards both
+// the night and weekend categories.
+type SurchargeSummary struct {
+	Start  string                  `json:"start"`
+	End    string                  `json:"end"`
+	Total  SurchargeTotals         `json:"total"`
+	Months []SurchargeMonthSummary `json:"months"`
+}
+
+// SurchargeTotals is the amount of work per surcharge category.
+type SurchargeTotals struct {
+	OnCallMin  int `json:"onCallMin"`
+	NightMin   int `json:"nightMin"`
+	WeekendMin int `json:"weekendMin"`
+	HolidayMin int `json:"holidayMin"`
+}
+
+func (t *SurchargeTotals) add(other SurchargeTotals) {
+	t.OnCallMin += other.OnCallMin
+	t.NightMin += other.NightMin
+	t.WeekendMin += other.WeekendMin
+	t.HolidayMin += other.HolidayMin
+}
+
+// SurchargeMonthSummary is the amount of work per surcharge category
+// in a month.
+type SurchargeMonthSummary struct {
+	Month string `json:"month"` // ex: "2023-01"
+	SurchargeTotals
+}
+
+// SummarizeSurcharges calculates the amount of surcharge-eligible work per
+// category and month. Work is attributed to the month of the day that the
+// period belongs to.
+func SummarizeSurcharges(days []Day, rules SurchargeRules) SurchargeSummary {
+	holidays := make(map[string]bool)
+	for _, day := range days {
+		if day.Holiday != nil {
+			holidays[day.Date.Format(time.DateOnly)] = true
+		}
+	}
+
+	var summary SurchargeSummary
+	var month *SurchargeMonthSummary
+	for _, day := range days {
+		monthStr := day.Date.Format("2006-01")
+		if month == nil || month.Month != monthStr {
+			summary.Months = append(summary.Months, SurchargeMonthSummary{Month: monthStr})
+			month = &summary.Months[len(summary.Months)-1]
+		}
+		for _, p := range day.Periods {
+			if p.Type == personio.PeriodTypeBreak {
+				continue
+			}
+			month.add(rules.periodTotals(p, holidays))
+		}
+	}
+	for _, m := range summary.Months {
+		summary.Total.add(m.SurchargeTotals)
+	}
+	if len(days) > 0 {
+		summary.Start = days[0].Date.Format(time.DateOnly)
+		summary.End = days[len(days)-1].Date.Format(time.DateOnly)
+	}
+	return summary
+}
+
+func (r SurchargeRules) periodTotals(p Period, holidays map[string]bool) SurchargeTotals {
+	var totals SurchargeTotals
+	if r.isOnCall(p) {
+		totals.OnCallMin = int(p.Duration().Minutes())
+	}
+
+	// Walk each local day that the period touches, starting from the day
+	// before, as the night may have started the evening before.
+	start := p.Start
+	year, month, dayOfMonth := start.Date()
+	day := time.Date(year, month, dayOfMonth-1, 0, 0, 0, 0, start.Location())
+	for day.Before(p.End) {
+		nextDay := day.AddDate(0, 0, 1)
+
+		switch day.Weekday() {
+		case time.Saturday, time.Sunday:
+			totals.WeekendMin += overlapMinutes(p.Start, p.End, day, nextDay)
+		}
+		if holidays[day.Format(time.DateOnly)] {
+			totals.HolidayMin += overlapMinutes(p.Start, p.End, day, nextDay)
+		}
+
+		nightStart := day.Add(time.Duration(r.NightStart) * time.Minute)
+		nightEnd := day.Add(time.Duration(r.NightEnd) * time.Minute)
+		if r.NightEnd <= r.NightStart {
+			nightEnd = nextDay.Add(time.Duration(r.NightEnd) * time.Minute)
+		}
+		totals.NightMin += overlapMinutes(p.Start, p.End, nightStart, nightEnd)
+
+		day = nextDay
+	}
+	return totals
+}
+
+func (r SurchargeRules) isOnCall(p Period) bool {
+	comment := strings.ToLower(p.Comment)
+	for _, keyword := range r.OnCallKeywords {
+		if keyword != "" && strings.Contains(comment, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapMinutes(aStart, aEnd, bStart, bEnd time.Time) int {
+	start := aStart
+	if bStart.After(start) {
+		start = bStart
+	}
+	end := aEnd
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return int(end.Sub(start).Minutes())
+}
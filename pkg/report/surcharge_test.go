@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"testing"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestSummarizeSurcharges(t *testing.T) {
+	cal := &personio.AttendanceCalendar{}
+	addDay(cal, "2023-02-03", "05:00", "13:00") // friday
+	addDay(cal, "2023-02-04", "20:00", "23:30") // saturday
+	comments := []string{"", "Incident #OnCall"}
+	for i := range cal.AttendancePeriods.Data {
+		cal.AttendancePeriods.Data[i].Attributes.Comment = &comments[i]
+	}
+
+	days, err := Days(cal, datespec.Range{
+		Start: mustParseDate(t, "2023-02-01"),
+		End:   mustParseDate(t, "2023-02-05"),
+	})
+	if err != nil {
+		t.Fatalf("days: %s", err)
+	}
+	summary := SummarizeSurcharges(days, SurchargeRules{
+		NightStart:     22 * 60,
+		NightEnd:       6 * 60,
+		OnCallKeywords: []string{"#oncall"},
+	})
+
+	want := SurchargeTotals{
+		OnCallMin:  210,
+		NightMin:   150,
+		WeekendMin: 210,
+	}
+	if summary.Total != want {
+		t.Errorf("want %+v, got %+v", want, summary.Total)
+	}
+	if len(summary.Months) != 1 {
+		t.Errorf("want 1 month, got %d", len(summary.Months))
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	got, err := ParseTimeOfDay("22:30")
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if got != 22*60+30 {
+		t.Errorf("want %d, got %d", 22*60+30, got)
+	}
+	if _, err := ParseTimeOfDay("25:00"); err == nil {
+		t.Error("want error for invalid time of day")
+	}
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// TagSummary is the amount of work per local-only tag.
+type TagSummary struct {
+	Start string     `json:"start"`
+	End   string     `json:"end"`
+	Tags  []TagTotal `json:"tags"`
+	// UntaggedMin is the work in periods without any tags.
+	UntaggedMin int `json:"untaggedMin"`
+}
+
+// TagTotal is the amount of work with a tag. Periods with multiple tags
+// count towards each of them.
+type TagTotal struct {
+	Tag     string `json:"tag"`
+	WorkMin int    `json:"workMin"`
+	Periods int    `json:"periods"`
+}
+
+// SummarizeTags sums up the work per tag, with the most worked tag first.
+// Break periods are not counted.
+func SummarizeTags(days []Day) TagSummary {
+	var summary TagSummary
+	totals := make(map[string]*TagTotal)
+	var untagged time.Duration
+	for _, day := range days {
+		for _, p := range day.Periods {
+			if p.Type == personio.PeriodTypeBreak {
+				continue
+			}
+			if len(p.Tags) == 0 {
+				untagged += p.Duration()
+				continue
+			}
+			for _, tag := range p.Tags {
+				total, ok := totals[tag]
+				if !ok {
+					total = &TagTotal{Tag: tag}
+					totals[tag] = total
+				}
+				total.WorkMin += int(p.Duration().Minutes())
+				total.Periods++
+			}
+		}
+	}
+	summary.Tags = make([]TagTotal, 0, len(totals))
+	for _, total := range totals {
+		summary.Tags = append(summary.Tags, *total)
+	}
+	sort.Slice(summary.Tags, func(i, j int) bool {
+		a, b := summary.Tags[i], summary.Tags[j]
+		if a.WorkMin != b.WorkMin {
+			return a.WorkMin > b.WorkMin
+		}
+		return a.Tag < b.Tag
+	})
+	summary.UntaggedMin = int(untagged.Minutes())
+	if len(days) > 0 {
+		summary.Start = days[0].Date.Format(time.DateOnly)
+		summary.End = days[len(days)-1].Date.Format(time.DateOnly)
+	}
+	return summary
+}
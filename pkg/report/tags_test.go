@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestSummarizeTags(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2023, 1, 18, hour, 0, 0, 0, time.UTC)
+	}
+	days := []Day{{
+		Date: time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC),
+		Periods: []Period{
+			{Type: personio.PeriodTypeWork, Start: at(8), End: at(10), Tags: []string{"billable", "client-a"}},
+			{Type: personio.PeriodTypeBreak, Start: at(10), End: at(11), Tags: []string{"billable"}},
+			{Type: personio.PeriodTypeWork, Start: at(11), End: at(12), Tags: []string{"client-b"}},
+			{Type: personio.PeriodTypeWork, Start: at(12), End: at(15)},
+		},
+	}}
+	got := SummarizeTags(days)
+	want := TagSummary{
+		Start: "2023-01-18",
+		End:   "2023-01-18",
+		Tags: []TagTotal{
+			{Tag: "billable", WorkMin: 120, Periods: 1},
+			{Tag: "client-a", WorkMin: 120, Periods: 1},
+			{Tag: "client-b", WorkMin: 60, Periods: 1},
+		},
+		UntaggedMin: 180,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
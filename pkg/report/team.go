@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// TeamMember is an employee included in a team report.
+type TeamMember struct {
+	ID    int
+	Name  string
+	Email string
+}
+
+// TeamMemberDays are the days of a single team member.
+type TeamMemberDays struct {
+	Member TeamMember
+	Days   []Day
+}
+
+// TeamTableHeader are the column names of the rows from [TeamTable].
+var TeamTableHeader = []string{
+	"Employee ID", "Name", "Email", "Date", "Weekday",
+	"Start", "End", "Work (h)", "Break (h)", "Absence", "Holiday",
+}
+
+// TeamTable returns one row per team member and day, sorted by the members'
+// names and then by date, with the columns from [TeamTableHeader].
+//
+// The start and end are the first and last work period's times of day in
+// the format "15:04", and the work and break are [time.Duration] values.
+// Cells without a value are nil.
+func TeamTable(members []TeamMemberDays) [][]any {
+	sorted := make([]TeamMemberDays, len(members))
+	copy(sorted, members)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := strings.ToLower(sorted[i].Member.Name), strings.ToLower(sorted[j].Member.Name)
+		if a != b {
+			return a < b
+		}
+		return sorted[i].Member.ID < sorted[j].Member.ID
+	})
+
+	var rows [][]any
+	for _, m := range sorted {
+		for _, d := range m.Days {
+			row := []any{
+				m.Member.ID,
+				m.Member.Name,
+				m.Member.Email,
+				d.Date.Format(time.DateOnly),
+				d.Date.Weekday().String(),
+				nil, nil,
+				d.Work,
+				d.Break,
+				nil, nil,
+			}
+			if d.HasWork() {
+				row[5] = d.FirstStart().Format("15:04")
+				row[6] = d.LastEnd().Format("15:04")
+			}
+			if d.Absence != nil {
+				row[9] = d.Absence.Name
+			}
+			if d.Holiday != nil {
+				row[10] = d.Holiday.Name
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestTeamTable(t *testing.T) {
+	day := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	members := []TeamMemberDays{
+		{
+			Member: TeamMember{ID: 2, Name: "Zoe"},
+			Days: []Day{{
+				Date:    day,
+				Absence: &personio.CalendarAbsencePeriod{Name: "Paid vacation"},
+			}},
+		},
+		{
+			Member: TeamMember{ID: 1, Name: "adam", Email: "adam@example.com"},
+			Days: []Day{{
+				Date: day,
+				Periods: []Period{
+					{Type: personio.PeriodTypeWork, Start: day.Add(8 * time.Hour), End: day.Add(12 * time.Hour)},
+					{Type: personio.PeriodTypeBreak, Start: day.Add(12 * time.Hour), End: day.Add(13 * time.Hour)},
+					{Type: personio.PeriodTypeWork, Start: day.Add(13 * time.Hour), End: day.Add(17 * time.Hour)},
+				},
+				Work:  8 * time.Hour,
+				Break: time.Hour,
+			}},
+		},
+	}
+	rows := TeamTable(members)
+	if len(rows) != 2 {
+		t.Fatalf("want 2 rows, got %d", len(rows))
+	}
+	if len(rows[0]) != len(TeamTableHeader) {
+		t.Fatalf("want %d columns, got %d", len(TeamTableHeader), len(rows[0]))
+	}
+	if rows[0][1] != "adam" || rows[0][5] != "08:00" || rows[0][6] != "17:00" || rows[0][7] != 8*time.Hour {
+		t.Errorf("wrong first row: %v", rows[0])
+	}
+	if rows[1][1] != "Zoe" || rows[1][5] != nil || rows[1][9] != "Paid vacation" {
+		t.Errorf("wrong second row: %v", rows[1])
+	}
+}
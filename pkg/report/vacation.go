@@ -0,0 +1,245 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// VacationRules configures how [CalculateVacation] counts vacation days.
+type VacationRules struct {
+	// Days is the yearly vacation entitlement, in days.
+	Days float64
+	// Keywords are words in absence names that mark the absence as
+	// vacation, matched case-insensitively. All absences are counted as
+	// vacation when empty.
+	Keywords []string
+	// LeaveYear is the range of the leave year, which the days are in.
+	// Leave years may span two calendar years. Days is not pro-rated
+	// when zero.
+	LeaveYear datespec.Range
+	// Employment is when you were employed. Days is pro-rated when the
+	// employment only covers parts of the leave year. A zero start or end
+	// means employed since before or until after the leave year.
+	Employment datespec.Range
+	// ProRate is how Days is pro-rated for partial-year employment.
+	ProRate ProRate
+	// CarryoverDays are the days carried over from the previous leave
+	// year.
+	CarryoverDays float64
+	// CarryoverExpires is the last day that the carried over days can be
+	// taken, or zero if they never expire. Vacation is taken from the
+	// carried over days first.
+	CarryoverExpires time.Time
+}
+
+// ProRate is how the yearly vacation entitlement is pro-rated for
+// partial-year employment. The result is rounded up to half days.
+type ProRate string
+
+const (
+	// ProRateMonths pro-rates by the calendar months employed, where a
+	// month counts if employed for at least half of it.
+	ProRateMonths ProRate = "months"
+	// ProRateDays pro-rates by the calendar days employed.
+	ProRateDays ProRate = "days"
+	// ProRateNone gives the full entitlement, regardless of employment.
+	ProRateNone ProRate = "none"
+)
+
+// IsVacation returns true if the absence with the given name is vacation.
+func (r VacationRules) IsVacation(name string) bool {
+	if len(r.Keywords) == 0 {
+		return true
+	}
+	name = strings.ToLower(name)
+	for _, keyword := range r.Keywords {
+		if keyword != "" && strings.Contains(name, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// VacationBalance is the number of vacation days taken, planned, and left
+// in a year.
+type VacationBalance struct {
+	Year int `json:"year"`
+	// LeaveYearStart and LeaveYearEnd are the first and last day of the
+	// leave year.
+	LeaveYearStart string `json:"leaveYearStart,omitempty"`
+	LeaveYearEnd   string `json:"leaveYearEnd,omitempty"`
+	// EntitlementDays is the yearly entitlement, pro-rated for
+	// partial-year employment.
+	EntitlementDays float64 `json:"entitlementDays"`
+	// YearlyEntitlementDays is the entitlement before pro-rating.
+	YearlyEntitlementDays float64 `json:"yearlyEntitlementDays"`
+	// CarryoverDays are the days carried over from the previous leave
+	// year.
+	CarryoverDays    float64 `json:"carryoverDays"`
+	CarryoverExpires string  `json:"carryoverExpires,omitempty"`
+	// CarryoverExpiredDays are the carried over days that were not taken
+	// before they expired.
+	CarryoverExpiredDays float64 `json:"carryoverExpiredDays"`
+	// CarryoverExpiringDays are the carried over days that are not yet
+	// taken or planned, and that will expire if not taken in time.
+	CarryoverExpiringDays float64 `json:"carryoverExpiringDays"`
+	// TakenDays are the vacation days up until and including today.
+	TakenDays float64 `json:"takenDays"`
+	// PlannedDays are the booked vacation days after today.
+	PlannedDays   float64 `json:"plannedDays"`
+	RemainingDays float64 `json:"remainingDays"`
+}
+
+// CalculateVacation counts the vacation days in the given days, which are
+// typically all days of a year. Only workdays in the schedule count, where
+// half-day absences count as half a day, and public holidays are not
+// counted.
+func CalculateVacation(days []Day, schedule Schedule, rules VacationRules, now time.Time) VacationBalance {
+	leaveYear := rules.LeaveYear
+	balance := VacationBalance{
+		YearlyEntitlementDays: rules.Days,
+		EntitlementDays:       proRateEntitlement(rules.Days, leaveYear, rules.Employment, rules.ProRate),
+		CarryoverDays:         rules.CarryoverDays,
+	}
+	if len(days) > 0 {
+		balance.Year = days[0].Date.Year()
+	}
+	if !leaveYear.Start.IsZero() {
+		balance.Year = leaveYear.Start.Year()
+		balance.LeaveYearStart = leaveYear.Start.Format(time.DateOnly)
+		balance.LeaveYearEnd = leaveYear.End.Format(time.DateOnly)
+	}
+	today := now.Format(time.DateOnly)
+	var expires string
+	if !rules.CarryoverExpires.IsZero() && rules.CarryoverDays > 0 {
+		expires = rules.CarryoverExpires.Format(time.DateOnly)
+		balance.CarryoverExpires = expires
+	}
+	// Vacation up until the expiry is taken from the carried over days
+	var beforeExpiry float64
+	for _, day := range days {
+		value := vacationDayValue(day, schedule, rules)
+		if value == 0 {
+			continue
+		}
+		date := day.Date.Format(time.DateOnly)
+		if date > today {
+			balance.PlannedDays += value
+		} else {
+			balance.TakenDays += value
+		}
+		if expires != "" && date <= expires {
+			beforeExpiry += value
+		}
+	}
+	if expires != "" {
+		unused := math.Max(rules.CarryoverDays-beforeExpiry, 0)
+		if today > expires {
+			balance.CarryoverExpiredDays = unused
+		} else {
+			balance.CarryoverExpiringDays = unused
+		}
+	}
+	balance.RemainingDays = balance.EntitlementDays + balance.CarryoverDays -
+		balance.CarryoverExpiredDays - balance.TakenDays - balance.PlannedDays
+	return balance
+}
+
+// proRateEntitlement pro-rates the yearly entitlement by the part of the
+// leave year that is covered by the employment, rounded up to half days.
+func proRateEntitlement(days float64, leaveYear, employment datespec.Range, method ProRate) float64 {
+	if method == ProRateNone || leaveYear.Start.IsZero() {
+		return days
+	}
+	start, end := dateOnly(leaveYear.Start), dateOnly(leaveYear.End)
+	if !employment.Start.IsZero() && dateOnly(employment.Start).After(start) {
+		start = dateOnly(employment.Start)
+	}
+	if !employment.End.IsZero() && dateOnly(employment.End).Before(end) {
+		end = dateOnly(employment.End)
+	}
+	if end.Before(start) {
+		return 0
+	}
+	if start.Equal(dateOnly(leaveYear.Start)) && end.Equal(dateOnly(leaveYear.End)) {
+		return days
+	}
+	var share float64
+	switch method {
+	case ProRateDays:
+		employed := end.Sub(start).Hours()/24 + 1
+		total := dateOnly(leaveYear.End).Sub(dateOnly(leaveYear.Start)).Hours()/24 + 1
+		share = employed / total
+	default:
+		var months int
+		for month := dateOnly(leaveYear.Start); !month.After(dateOnly(leaveYear.End)); month = month.AddDate(0, 1, 0) {
+			monthEnd := month.AddDate(0, 1, -1)
+			from, to := month, monthEnd
+			if start.After(from) {
+				from = start
+			}
+			if end.Before(to) {
+				to = end
+			}
+			employed := to.Sub(from).Hours()/24 + 1
+			if employed*2 >= monthEnd.Sub(month).Hours()/24+1 {
+				months++
+			}
+		}
+		share = float64(months) / 12
+	}
+	return math.Ceil(days*share*2) / 2
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func vacationDayValue(day Day, schedule Schedule, rules VacationRules) float64 {
+	if day.Absence == nil || !rules.IsVacation(day.Absence.Name) {
+		return 0
+	}
+	return absenceDayValue(day, schedule)
+}
+
+// absenceDayValue returns how much of a workday the day's absence covers,
+// where half-day absences count as half a day, and public holidays and
+// days off in the schedule are not counted.
+func absenceDayValue(day Day, schedule Schedule) float64 {
+	if day.Absence == nil {
+		return 0
+	}
+	if schedule[day.Date.Weekday()] == 0 {
+		return 0
+	}
+	if day.Holiday != nil && !day.Holiday.HalfDay {
+		return 0
+	}
+	date := personio.DateOf(day.Date)
+	if (day.Absence.HalfDayStart && day.Absence.StartDate == date) ||
+		(day.Absence.HalfDayEnd && day.Absence.EndDate == date) {
+		return 0.5
+	}
+	return 1
+}
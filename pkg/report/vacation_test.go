@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/datespec"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestCalculateVacation(t *testing.T) {
+	start := time.Date(2023, 7, 3, 0, 0, 0, 0, time.Local) // Monday
+	vacation := &personio.CalendarAbsencePeriod{
+		Name:       "Paid vacation",
+		StartDate:  personio.NewDate(2023, 7, 3),
+		EndDate:    personio.NewDate(2023, 7, 12),
+		HalfDayEnd: true,
+	}
+	sick := &personio.CalendarAbsencePeriod{Name: "Sick leave", StartDate: personio.NewDate(2023, 7, 13), EndDate: personio.NewDate(2023, 7, 13)}
+	var days []Day
+	for i := 0; i < 10; i++ {
+		days = append(days, Day{Date: start.AddDate(0, 0, i), Absence: vacation})
+	}
+	days = append(days, Day{Date: start.AddDate(0, 0, 10), Absence: sick})
+	days[2].Holiday = &personio.CalendarHoliday{Name: "Some holiday"}
+
+	var schedule Schedule
+	for d := time.Monday; d <= time.Friday; d++ {
+		schedule[d] = 8 * time.Hour
+	}
+	rules := VacationRules{Days: 30, Keywords: []string{"vacation"}}
+	now := start.AddDate(0, 0, 4).Add(12 * time.Hour) // Friday
+
+	got := CalculateVacation(days, schedule, rules, now)
+	// Mon-Fri minus the holiday is taken, while Mon-Tue and the half day
+	// on Wednesday are planned. The sick leave on Thursday is not counted.
+	want := VacationBalance{
+		Year:                  2023,
+		EntitlementDays:       30,
+		YearlyEntitlementDays: 30,
+		TakenDays:             4,
+		PlannedDays:           2.5,
+		RemainingDays:         23.5,
+	}
+	if got != want {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestCalculateVacationCarryover(t *testing.T) {
+	var schedule Schedule
+	for d := time.Monday; d <= time.Friday; d++ {
+		schedule[d] = 8 * time.Hour
+	}
+	vacation := &personio.CalendarAbsencePeriod{Name: "Vacation", StartDate: personio.NewDate(2023, 3, 27), EndDate: personio.NewDate(2023, 4, 4)}
+	var days []Day
+	start := time.Date(2023, 3, 27, 0, 0, 0, 0, time.UTC) // Monday
+	for i := 0; i < 9; i++ {
+		days = append(days, Day{Date: start.AddDate(0, 0, i), Absence: vacation})
+	}
+	rules := VacationRules{
+		Days:             30,
+		LeaveYear:        datespec.Range{Start: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+		CarryoverDays:    5,
+		CarryoverExpires: time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	// 5 workdays before the expiry use up all carried over days
+	got := CalculateVacation(days, schedule, rules, time.Date(2023, 4, 10, 0, 0, 0, 0, time.UTC))
+	if got.CarryoverExpiredDays != 0 || got.RemainingDays != 28 {
+		t.Errorf("want no expired days and 28 remaining, got %+v", got)
+	}
+
+	// Only 3 workdays before the expiry leaves 2 to expire
+	got = CalculateVacation(days[2:], schedule, rules, time.Date(2023, 4, 10, 0, 0, 0, 0, time.UTC))
+	if got.CarryoverExpiredDays != 2 || got.RemainingDays != 28 {
+		t.Errorf("want 2 expired days and 28 remaining, got %+v", got)
+	}
+	got = CalculateVacation(days[2:], schedule, rules, time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC))
+	if got.CarryoverExpiringDays != 2 || got.RemainingDays != 30 {
+		t.Errorf("want 2 expiring days and 30 remaining, got %+v", got)
+	}
+}
+
+func TestProRateEntitlement(t *testing.T) {
+	year := datespec.Range{Start: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)}
+	var tests = []struct {
+		name       string
+		employment datespec.Range
+		method     ProRate
+		want       float64
+	}{
+		{"full year", datespec.Range{}, ProRateMonths, 30},
+		{"joined mid-month", datespec.Range{Start: time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC)}, ProRateMonths, 15},
+		{"joined late in month", datespec.Range{Start: time.Date(2023, 7, 20, 0, 0, 0, 0, time.UTC)}, ProRateMonths, 12.5},
+		{"left", datespec.Range{End: time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC)}, ProRateMonths, 7.5},
+		{"days", datespec.Range{Start: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)}, ProRateDays, 15.5},
+		{"none", datespec.Range{Start: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)}, ProRateNone, 30},
+		{"outside", datespec.Range{Start: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, ProRateMonths, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := proRateEntitlement(30, year, tc.employment, tc.method)
+			if got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
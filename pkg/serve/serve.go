@@ -0,0 +1,506 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package serve is a local REST API for reading and writing attendance,
+// which can be shared by multiple employees that each authenticate with
+// their own API key, or with OpenID Connect, so a small team can run a
+// single sync service.
+package serve
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/oidc"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+)
+
+// User is an employee that may use the API.
+type User struct {
+	// Name identifies the user in logs and responses.
+	Name string
+	// APIKey is sent by the user in the "Authorization: Bearer <key>"
+	// header. Not used with OIDC.
+	APIKey string
+	// Identity is the user's username in OIDC tokens, such as their email.
+	// Only used with OIDC.
+	Identity string
+	// Login returns a new logged in client for the user. It's called on
+	// the user's first request, and again when the session has expired.
+	Login func() (*personio.Client, error)
+}
+
+// OIDC makes the server authenticate requests with OpenID Connect bearer
+// tokens instead of API keys.
+type OIDC struct {
+	// Verifier verifies the tokens.
+	Verifier TokenVerifier
+	// ManagerGroups are the groups allowed to use the manager endpoints,
+	// which read the attendance of all users.
+	ManagerGroups []string
+}
+
+// TokenVerifier verifies a bearer token and returns its user.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*oidc.Identity, error)
+}
+
+// Server is an [http.Handler] that serves the REST API.
+type Server struct {
+	users []*userSession
+	oidc  *OIDC
+}
+
+// caller is the authenticated caller of a request.
+type caller struct {
+	// user is the caller's own user, or nil if the caller has no Personio
+	// credentials configured, such as a manager that only reads the
+	// attendance of others.
+	user    *userSession
+	name    string
+	manager bool
+}
+
+// userSession is a user's logged in client. The client is not safe for
+// concurrent use in general, so the user's requests are serialized, except
+// for reads of the calendar, which may run concurrently so that identical
+// reads share a single request to Personio.
+type userSession struct {
+	User
+	keyHash [sha256.Size]byte
+	mu      sync.RWMutex
+	client  *personio.Client
+}
+
+// New returns a server for the users, which must have unique names and
+// API keys, or unique identities when authenticating with OIDC, when
+// oidcAuth is not nil.
+func New(users []User, oidcAuth *OIDC) (*Server, error) {
+	if len(users) == 0 {
+		return nil, errors.New("no users configured")
+	}
+	s := &Server{oidc: oidcAuth}
+	names := make(map[string]bool)
+	identities := make(map[string]bool)
+	keys := make(map[[sha256.Size]byte]bool)
+	for i, u := range users {
+		if u.Name == "" {
+			return nil, fmt.Errorf("user #%d: missing name", i+1)
+		}
+		if names[u.Name] {
+			return nil, fmt.Errorf("user %q: duplicate name", u.Name)
+		}
+		names[u.Name] = true
+		if oidcAuth != nil {
+			identity := strings.ToLower(u.Identity)
+			if identity == "" {
+				return nil, fmt.Errorf("user %q: missing identity", u.Name)
+			}
+			if identities[identity] {
+				return nil, fmt.Errorf("user %q: identity is used by another user", u.Name)
+			}
+			identities[identity] = true
+			s.users = append(s.users, &userSession{User: u})
+			continue
+		}
+		if u.APIKey == "" {
+			return nil, fmt.Errorf("user %q: missing API key", u.Name)
+		}
+		hash := sha256.Sum256([]byte(u.APIKey))
+		if keys[hash] {
+			return nil, fmt.Errorf("user %q: API key is used by another user", u.Name)
+		}
+		keys[hash] = true
+		s.users = append(s.users, &userSession{User: u, keyHash: hash})
+	}
+	return s, nil
+}
+
+// ServeHTTP implements [http.Handler].
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, err := s.authenticate(r)
+	if errors.Is(err, oidc.ErrForbidden) {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="rootless-personio"`)
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if path == "/api/v1/users" || strings.HasPrefix(path, "/api/v1/users/") {
+		if !c.manager {
+			writeError(w, http.StatusForbidden, errors.New("only managers may read the attendance of others"))
+			return
+		}
+		s.handleUsers(w, r, strings.TrimPrefix(strings.TrimPrefix(path, "/api/v1/users"), "/"))
+		return
+	}
+	if c.user == nil {
+		writeError(w, http.StatusForbidden, fmt.Errorf("no Personio credentials are configured for %q", c.name))
+		return
+	}
+	switch {
+	case path == "/api/v1/me":
+		s.handleMe(w, r, c.user)
+	case path == "/api/v1/calendar":
+		s.handleCalendar(w, r, c.user)
+	case strings.HasPrefix(path, "/api/v1/attendance/"):
+		s.handleAttendance(w, r, c.user, strings.TrimPrefix(path, "/api/v1/attendance/"))
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// authenticate returns the caller of the request, identified by its bearer
+// token.
+func (s *Server) authenticate(r *http.Request) (*caller, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, errors.New("missing bearer token")
+	}
+	if s.oidc != nil {
+		return s.authenticateOIDC(r.Context(), token)
+	}
+	// All keys are compared in constant time, to not leak which keys exist.
+	hash := sha256.Sum256([]byte(token))
+	var found *userSession
+	for _, u := range s.users {
+		if subtle.ConstantTimeCompare(hash[:], u.keyHash[:]) == 1 {
+			found = u
+		}
+	}
+	if found == nil {
+		return nil, errors.New("invalid API key")
+	}
+	return &caller{user: found, name: found.Name}, nil
+}
+
+func (s *Server) authenticateOIDC(ctx context.Context, token string) (*caller, error) {
+	id, err := s.oidc.Verifier.Verify(ctx, token)
+	if err != nil {
+		log.Debug().Err(err).Msg("Rejected OIDC token.")
+		if errors.Is(err, oidc.ErrForbidden) {
+			return nil, err
+		}
+		return nil, errors.New("invalid token")
+	}
+	c := &caller{
+		name:    id.Username,
+		manager: id.InAnyGroup(s.oidc.ManagerGroups),
+	}
+	for _, u := range s.users {
+		if strings.EqualFold(u.Identity, id.Username) {
+			c.user = u
+			c.name = u.Name
+			break
+		}
+	}
+	return c, nil
+}
+
+type userResponse struct {
+	Name string `json:"name"`
+}
+
+// handleUsers serves the manager endpoints, where path is relative to
+// "/api/v1/users".
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request, path string) {
+	if path == "" {
+		if !allowMethods(w, r, http.MethodGet) {
+			return
+		}
+		users := make([]userResponse, 0, len(s.users))
+		for _, u := range s.users {
+			users = append(users, userResponse{Name: u.Name})
+		}
+		writeJSON(w, http.StatusOK, users)
+		return
+	}
+	name, rest, _ := strings.Cut(path, "/")
+	if rest != "calendar" {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	for _, u := range s.users {
+		if u.Name == name {
+			s.handleCalendar(w, r, u)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("no such user: %q", name))
+}
+
+type meResponse struct {
+	User       string `json:"user"`
+	EmployeeID int    `json:"employeeId"`
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request, user *userSession) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	err := user.read(func(client *personio.Client) error {
+		writeJSON(w, http.StatusOK, meResponse{User: user.Name, EmployeeID: client.EmployeeID})
+		return nil
+	})
+	if err != nil {
+		writeClientError(w, user, err)
+	}
+}
+
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request, user *userSession) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	start, err := parseDate(r.URL.Query().Get("start"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("start: %w", err))
+		return
+	}
+	end, err := parseDate(r.URL.Query().Get("end"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("end: %w", err))
+		return
+	}
+	if end.Before(start) {
+		writeError(w, http.StatusBadRequest, errors.New("end is before start"))
+		return
+	}
+	var cal *personio.AttendanceCalendar
+	err = user.read(func(client *personio.Client) error {
+		var err error
+		cal, err = client.GetAttendanceCalendarMerged(client.EmployeeID, start, end)
+		return err
+	})
+	if err != nil {
+		writeClientError(w, user, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, cal)
+}
+
+func (s *Server) handleAttendance(w http.ResponseWriter, r *http.Request, user *userSession, day string) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPut, http.MethodDelete) {
+		return
+	}
+	date, err := parseDate(day)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		var periods []personio.Period
+		err = user.do(func(client *personio.Client) error {
+			var err error
+			periods, err = client.GetAttendancePeriods(date)
+			return err
+		})
+		if err == nil {
+			writeJSON(w, http.StatusOK, periods)
+		}
+	case http.MethodPut:
+		var periods []personio.Period
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&periods); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("parse periods: %w", err))
+			return
+		}
+		err = user.do(func(client *personio.Client) error {
+			return client.SetAttendance(date, periods)
+		})
+		if err == nil {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case http.MethodDelete:
+		err = user.do(func(client *personio.Client) error {
+			return client.DeleteAttendance(date)
+		})
+		if err == nil {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+	if err != nil {
+		writeClientError(w, user, err)
+	}
+}
+
+// do calls f with the user's logged in client, logging in first if
+// needed, and once more if the session has expired.
+func (u *userSession) do(f func(client *personio.Client) error) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.client == nil {
+		if err := u.login(); err != nil {
+			return err
+		}
+	}
+	err := f(u.client)
+	if !sessionExpired(err) {
+		return err
+	}
+	log.Info().Str("user", u.Name).Msg("Session expired, logging in again.")
+	if err := u.login(); err != nil {
+		return err
+	}
+	return f(u.client)
+}
+
+// read is like [userSession.do], but only for calls that are safe to run
+// concurrently with each other, such as fetching the calendar. Logging in
+// still takes the client to itself.
+func (u *userSession) read(f func(client *personio.Client) error) error {
+	client, err := u.readSession(nil, f)
+	if !sessionExpired(err) {
+		return err
+	}
+	_, err = u.readSession(client, f)
+	return err
+}
+
+// readSession calls f with the user's client while holding the read lock,
+// after logging in if there's no client yet or it's the expired client.
+// It returns the client that f was called with.
+func (u *userSession) readSession(expired *personio.Client, f func(client *personio.Client) error) (*personio.Client, error) {
+	u.mu.RLock()
+	if u.client == nil || u.client == expired {
+		u.mu.RUnlock()
+		if err := u.loginUnless(expired); err != nil {
+			return nil, err
+		}
+		u.mu.RLock()
+	}
+	defer u.mu.RUnlock()
+	client := u.client
+	if client == nil {
+		// Another request failed logging in again in between
+		return nil, &loginError{err: errors.New("logged out by a concurrent request")}
+	}
+	return client, f(client)
+}
+
+// loginUnless logs in, unless another request already did so since the
+// expired client was used.
+func (u *userSession) loginUnless(expired *personio.Client) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.client != nil && u.client != expired {
+		return nil
+	}
+	if expired != nil {
+		log.Info().Str("user", u.Name).Msg("Session expired, logging in again.")
+	}
+	return u.login()
+}
+
+func (u *userSession) login() error {
+	client, err := u.Login()
+	if err != nil {
+		u.client = nil
+		return &loginError{err: err}
+	}
+	log.Info().Str("user", u.Name).Int("employeeId", client.EmployeeID).Msg("Logged in user.")
+	u.client = client
+	return nil
+}
+
+// loginError is returned when the user's Personio login failed, as opposed
+// to the request itself.
+type loginError struct {
+	err error
+}
+
+func (e *loginError) Error() string {
+	return "log in to Personio: " + e.err.Error()
+}
+
+func (e *loginError) Unwrap() error {
+	return e.err
+}
+
+// sessionExpired returns true if Personio responded as if logged out,
+// such as by redirecting to its login page.
+func sessionExpired(err error) bool {
+	return errors.Is(err, personio.ErrNotLoggedIn) ||
+		errors.Is(err, personio.ErrUnexpectedHTML) ||
+		errors.Is(err, personio.ErrUnexpectedRedirect)
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("missing date")
+	}
+	date, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, must be YYYY-MM-DD", s)
+	}
+	return date, nil
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+func writeClientError(w http.ResponseWriter, user *userSession, err error) {
+	var loginErr *loginError
+	status := http.StatusBadGateway
+	switch {
+	case errors.As(err, &loginErr):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, personio.ErrPermissionDenied):
+		status = http.StatusForbidden
+	case errors.Is(err, personio.ErrMonthLocked):
+		status = http.StatusConflict
+	case errors.Is(err, personio.ErrMaintenance):
+		status = http.StatusServiceUnavailable
+	}
+	log.Warn().Err(err).Str("user", user.Name).Msg("Failed serving request.")
+	writeError(w, status, err)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug().Err(err).Msg("Failed writing response.")
+	}
+}
@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/oidc"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestServerAuthenticatesPerUser(t *testing.T) {
+	logins := map[string]int{}
+	newUser := func(name, key string, employeeID int) User {
+		return User{
+			Name:   name,
+			APIKey: key,
+			Login: func() (*personio.Client, error) {
+				logins[name]++
+				return &personio.Client{EmployeeID: employeeID}, nil
+			},
+		}
+	}
+	srv, err := New([]User{
+		newUser("alice", "alice-key", 1),
+		newUser("bob", "bob-key", 2),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantUser   string
+		wantID     int
+	}{
+		{name: "missing key", wantStatus: http.StatusUnauthorized},
+		{name: "wrong key", header: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "alice", header: "Bearer alice-key", wantStatus: http.StatusOK, wantUser: "alice", wantID: 1},
+		{name: "bob", header: "Bearer bob-key", wantStatus: http.StatusOK, wantUser: "bob", wantID: 2},
+		{name: "alice again", header: "Bearer alice-key", wantStatus: http.StatusOK, wantUser: "alice", wantID: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("want status %d, got %d: %s", tc.wantStatus, rec.Code, rec.Body)
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var me meResponse
+			if err := json.NewDecoder(rec.Body).Decode(&me); err != nil {
+				t.Fatal(err)
+			}
+			if me.User != tc.wantUser || me.EmployeeID != tc.wantID {
+				t.Errorf("want %s (%d), got %+v", tc.wantUser, tc.wantID, me)
+			}
+		})
+	}
+	if logins["alice"] != 1 || logins["bob"] != 1 {
+		t.Errorf("want each user logged in once, got %v", logins)
+	}
+}
+
+func TestNewRejectsSharedAPIKeys(t *testing.T) {
+	_, err := New([]User{
+		{Name: "alice", APIKey: "key"},
+		{Name: "bob", APIKey: "key"},
+	}, nil)
+	if err == nil {
+		t.Error("want error for API key used by two users")
+	}
+}
+
+// fakeVerifier accepts tokens named after its identities.
+type fakeVerifier map[string]*oidc.Identity
+
+func (v fakeVerifier) Verify(_ context.Context, token string) (*oidc.Identity, error) {
+	id, ok := v[token]
+	if !ok {
+		return nil, oidc.ErrInvalidToken
+	}
+	return id, nil
+}
+
+func TestServerOIDCManagerGroups(t *testing.T) {
+	newUser := func(name string, employeeID int) User {
+		return User{
+			Name:     name,
+			Identity: name + "@example.com",
+			Login: func() (*personio.Client, error) {
+				return &personio.Client{EmployeeID: employeeID}, nil
+			},
+		}
+	}
+	srv, err := New([]User{newUser("alice", 1), newUser("bob", 2)}, &OIDC{
+		Verifier: fakeVerifier{
+			"alice":   {Username: "Alice@example.com"},
+			"manager": {Username: "boss@example.com", Groups: []string{"managers"}},
+		},
+		ManagerGroups: []string{"managers"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		path       string
+		wantStatus int
+	}{
+		{name: "invalid token", token: "nope", path: "/api/v1/me", wantStatus: http.StatusUnauthorized},
+		{name: "own user", token: "alice", path: "/api/v1/me", wantStatus: http.StatusOK},
+		{name: "not manager", token: "alice", path: "/api/v1/users", wantStatus: http.StatusForbidden},
+		{name: "manager without own user", token: "manager", path: "/api/v1/me", wantStatus: http.StatusForbidden},
+		{name: "manager lists users", token: "manager", path: "/api/v1/users", wantStatus: http.StatusOK},
+		{name: "manager unknown user", token: "manager", path: "/api/v1/users/carol/calendar", wantStatus: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("want status %d, got %d: %s", tc.wantStatus, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestServerSharesConcurrentCalendarReads(t *testing.T) {
+	var requests atomic.Int32
+	received := make(chan struct{}, 2)
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		received <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{}}`))
+	}))
+	defer upstream.Close()
+
+	srv, err := New([]User{{
+		Name:   "alice",
+		APIKey: "alice-key",
+		Login: func() (*personio.Client, error) {
+			client, err := personio.New(upstream.URL)
+			if err != nil {
+				return nil, err
+			}
+			client.EmployeeID = 1
+			return client, nil
+		},
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const callers = 2
+	codes := make([]int, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/calendar?start=2024-05-01&end=2024-05-31", nil)
+			req.Header.Set("Authorization", "Bearer alice-key")
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			codes[i] = rec.Code
+		}(i)
+	}
+	<-received
+	// Give the other caller time to join the in-flight request
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("caller %d: want status %d, got %d", i, http.StatusOK, code)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("want 1 upstream request, got %d", got)
+	}
+}
@@ -0,0 +1,257 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package session persists the long-lived HTTP cookies that Personio sets,
+// such as the ones it uses to recognize known devices, so every run doesn't
+// look like a login from a brand-new device.
+//
+// Only cookies with an expiry are persisted, same as what a web browser
+// keeps when it's restarted. Session cookies are kept in-memory only.
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/storage"
+	"github.com/applejag/rootless-personio/pkg/util"
+)
+
+// Jar is a [http.CookieJar] that saves its persistent cookies to a
+// storage whenever they change.
+type Jar struct {
+	store   storage.Backend
+	key     string
+	inner   *cookiejar.Jar
+	mu      sync.Mutex
+	cookies map[cookieKey]storedCookie
+	// changed are the cookies set or deleted since the last save, which
+	// are merged into the file instead of overwriting cookies saved by
+	// concurrent runs
+	changed map[cookieKey]bool
+}
+
+type cookieKey struct {
+	host, domain, path, name string
+}
+
+type storedCookie struct {
+	// Host is the host that set the cookie.
+	Host     string        `json:"host"`
+	Name     string        `json:"name"`
+	Value    string        `json:"value"`
+	Domain   string        `json:"domain,omitempty"`
+	Path     string        `json:"path,omitempty"`
+	Expires  time.Time     `json:"expires"`
+	Secure   bool          `json:"secure,omitempty"`
+	HttpOnly bool          `json:"httpOnly,omitempty"`
+	SameSite http.SameSite `json:"sameSite,omitempty"`
+}
+
+func (c storedCookie) key() cookieKey {
+	host := c.Host
+	if c.Domain != "" {
+		// Domain cookies are shared between hosts
+		host = ""
+	}
+	return cookieKey{host: host, domain: strings.ToLower(c.Domain), path: c.Path, name: c.Name}
+}
+
+// Key returns the storage key of the cookies for an account, e.g
+// "sessions/example.personio.de/me@example.com.json", which in the
+// filesystem storage is inside the user's config directory, e.g
+// ~/.config/rootless-personio/sessions/example.personio.de/me@example.com.json
+// or %AppData%\rootless-personio\sessions\... on Windows.
+func Key(host, email string) string {
+	return "sessions/" + util.SafeFileName(host) + "/" + util.SafeFileName(email) + ".json"
+}
+
+// Load returns a new cookie jar with the cookies from the file, and which
+// saves changes to the file. A missing file means no cookies.
+func Load(path string) (*Jar, error) {
+	return LoadFrom(storage.NewFS(filepath.Dir(path)), filepath.Base(path))
+}
+
+// LoadFrom returns a new cookie jar with the cookies from the storage key,
+// and which saves changes to the storage. A missing key means no cookies.
+func LoadFrom(store storage.Backend, key string) (*Jar, error) {
+	inner, err := cookiejar.New(&cookiejar.Options{})
+	if err != nil {
+		return nil, err
+	}
+	jar := &Jar{
+		store:   store,
+		key:     key,
+		inner:   inner,
+		changed: make(map[cookieKey]bool),
+	}
+	b, err := store.Get(key)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("read cookies: %w", err)
+	}
+	jar.cookies, err = parseCookies(b, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range jar.cookies {
+		u := &url.URL{Scheme: "https", Host: c.Host, Path: c.Path}
+		inner.SetCookies(u, []*http.Cookie{{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		}})
+	}
+	return jar, nil
+}
+
+// parseCookies returns the unexpired cookies. Nil means no cookies.
+func parseCookies(b []byte, now time.Time) (map[cookieKey]storedCookie, error) {
+	cookies := make(map[cookieKey]storedCookie)
+	if b == nil {
+		return cookies, nil
+	}
+	var stored []storedCookie
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, fmt.Errorf("parse cookies: %w", err)
+	}
+	for _, c := range stored {
+		if c.Expires.After(now) {
+			cookies[c.key()] = c
+		}
+	}
+	return cookies, nil
+}
+
+// Cookies implements [http.CookieJar].
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	return j.inner.Cookies(u)
+}
+
+// SetCookies implements [http.CookieJar], and saves the cookies to the
+// file if any persistent cookies changed. Failing to save is ignored, as
+// it only means the cookies are not remembered until the next run.
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.inner.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	var changed bool
+	for _, c := range cookies {
+		stored := storedCookie{
+			Host:     u.Hostname(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   strings.TrimPrefix(c.Domain, "."),
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		}
+		if stored.Path == "" {
+			stored.Path = "/"
+		}
+		if c.MaxAge > 0 {
+			stored.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		key := stored.key()
+		old, exists := j.cookies[key]
+		switch {
+		case c.MaxAge < 0 || (!stored.Expires.IsZero() && !stored.Expires.After(now)):
+			// Deleted or expired
+			if exists {
+				delete(j.cookies, key)
+				j.changed[key] = true
+				changed = true
+			}
+		case stored.Expires.IsZero():
+			// Session cookie, which replaces any persistent cookie
+			if exists {
+				delete(j.cookies, key)
+				j.changed[key] = true
+				changed = true
+			}
+		case !exists || old != stored:
+			j.cookies[key] = stored
+			j.changed[key] = true
+			changed = true
+		}
+	}
+	if changed {
+		_ = j.save()
+	}
+}
+
+// Save writes the persistent cookies to the file.
+func (j *Jar) Save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.save()
+}
+
+func (j *Jar) save() error {
+	// Merge into the stored cookies, as concurrent runs may save their
+	// cookies at the same time
+	var merged map[cookieKey]storedCookie
+	err := j.store.Update(j.key, func(value []byte) ([]byte, error) {
+		var err error
+		merged, err = parseCookies(value, time.Now())
+		if err != nil {
+			// Overwrite broken cookies, as they are only an optimization
+			merged = make(map[cookieKey]storedCookie)
+		}
+		for key := range j.changed {
+			if c, ok := j.cookies[key]; ok {
+				merged[key] = c
+			} else {
+				delete(merged, key)
+			}
+		}
+		stored := make([]storedCookie, 0, len(merged))
+		for _, c := range merged {
+			stored = append(stored, c)
+		}
+		sort.Slice(stored, func(i, k int) bool {
+			if stored[i].Host != stored[k].Host {
+				return stored[i].Host < stored[k].Host
+			}
+			return stored[i].Name < stored[k].Name
+		})
+		return json.MarshalIndent(stored, "", "  ")
+	})
+	if err != nil {
+		return fmt.Errorf("write cookies: %w", err)
+	}
+	j.cookies = merged
+	j.changed = make(map[cookieKey]bool)
+	return nil
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package session
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJarPersistsCookies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	u := &url.URL{Scheme: "https", Host: "example.personio.de", Path: "/login/index"}
+
+	jar, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "device", Value: "abc", Path: "/", Expires: time.Now().Add(24 * time.Hour)},
+		{Name: "remember", Value: "def", Path: "/", MaxAge: 3600},
+		{Name: "session", Value: "ghi", Path: "/"},
+		{Name: "expired", Value: "jkl", Path: "/", MaxAge: -1},
+	})
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, c := range loaded.Cookies(u) {
+		got[c.Name] = c.Value
+	}
+	want := map[string]string{"device": "abc", "remember": "def"}
+	if len(got) != len(want) {
+		t.Fatalf("want cookies %v, got %v", want, got)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("cookie %q: want %q, got %q", name, value, got[name])
+		}
+	}
+
+	loaded.SetCookies(u, []*http.Cookie{{Name: "device", Path: "/", MaxAge: -1}})
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cookies := reloaded.Cookies(u); len(cookies) != 1 || cookies[0].Name != "remember" {
+		t.Errorf("want only the remember cookie after deleting device cookie, got %v", cookies)
+	}
+}
+
+func TestJarMergesConcurrentRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	u := &url.URL{Scheme: "https", Host: "example.personio.de", Path: "/"}
+	expires := time.Now().Add(24 * time.Hour)
+
+	first, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.SetCookies(u, []*http.Cookie{{Name: "device", Value: "abc", Path: "/", Expires: expires}})
+	// The second run has not seen the first run's cookie, and must not
+	// remove it when saving its own
+	second.SetCookies(u, []*http.Cookie{{Name: "remember", Value: "def", Path: "/", Expires: expires}})
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, c := range loaded.Cookies(u) {
+		got[c.Name] = c.Value
+	}
+	if got["device"] != "abc" || got["remember"] != "def" {
+		t.Errorf("want cookies from both runs, got %v", got)
+	}
+}
@@ -0,0 +1,321 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package sheet parses attendance periods from spreadsheet rows, such as
+// the rows of a CSV file, using a configurable column mapping, as
+// everyone's spreadsheet layout differs.
+package sheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// Mapping tells which columns contain which fields, by header name
+// (matched case-insensitively) or by 1-based column number.
+type Mapping struct {
+	// Date is the column of the day. When empty, Start and End must
+	// contain both date and time.
+	Date string
+	// Start and End are the columns of the start and end times.
+	Start string
+	End   string
+	// Duration is the column of the duration, used instead of End.
+	// Values are either "1:30", decimal hours "1.5", or "1h30m".
+	Duration string
+	// Type is the column of the period type, "work" or "break". Periods
+	// are work when empty.
+	Type    string
+	Comment string
+	// Source and SourceID are the columns of the time tracker and the
+	// entry's ID in it, used to not import the same entry twice.
+	Source   string
+	SourceID string
+
+	// DateFormat is the Go time layout of the Date column.
+	// Defaults to "2006-01-02".
+	DateFormat string
+	// TimeFormat is the Go time layout of the Start and End columns.
+	// Defaults to "15:04", or to RFC 3339 when Date is empty.
+	TimeFormat string
+	// Location is the time zone of the dates and times, unless the time
+	// layout contains a zone. Defaults to local time.
+	Location *time.Location
+}
+
+// Row is a parsed spreadsheet row.
+type Row struct {
+	// Line is the 1-based row number, where the header is row 1.
+	Line int `json:"line"`
+	// Values are the cells of the row, by header name.
+	Values   map[string]string `json:"values"`
+	Period   personio.Period   `json:"period"`
+	Source   string            `json:"source,omitempty"`
+	SourceID string            `json:"sourceId,omitempty"`
+	// Err is why the row could not be parsed.
+	Err error `json:"-"`
+}
+
+// MarshalJSON adds the error message to the JSON.
+func (r Row) MarshalJSON() ([]byte, error) {
+	type row Row
+	var errMsg string
+	if r.Err != nil {
+		errMsg = r.Err.Error()
+	}
+	return json.Marshal(struct {
+		row
+		Error string `json:"error,omitempty"`
+	}{row(r), errMsg})
+}
+
+// Object returns the row as a JSON object in the input format of
+// "attendance set", with the row's cells as extra fields that can be used
+// in comment templates.
+func (r Row) Object() (json.RawMessage, error) {
+	obj := make(map[string]any, len(r.Values)+6)
+	for header, value := range r.Values {
+		obj[header] = value
+	}
+	obj["start"] = r.Period.Start
+	obj["end"] = r.Period.End
+	obj["period_type"] = r.Period.PeriodType
+	if comment := r.Period.GetComment(); comment != "" {
+		obj["comment"] = comment
+	} else {
+		delete(obj, "comment")
+	}
+	if r.Source != "" {
+		obj["source"] = r.Source
+	}
+	if r.SourceID != "" {
+		obj["source_id"] = r.SourceID
+	}
+	return json.Marshal(obj)
+}
+
+// ReadCSV reads all records of a CSV file, skipping any UTF-8 byte order
+// mark, as added by Excel. A zero delimiter means comma.
+func ReadCSV(r io.Reader, delimiter rune) ([][]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	cr := csv.NewReader(bytes.NewReader(b))
+	if delimiter != 0 {
+		cr.Comma = delimiter
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV: %w", err)
+	}
+	return records, nil
+}
+
+// Parse parses the records, where the first record is the header. Empty
+// rows are skipped, and rows that fail to parse have their Err set.
+// Returns an error if the mapping doesn't match the header.
+func Parse(records [][]string, m Mapping) ([]Row, error) {
+	if len(records) == 0 {
+		return nil, errors.New("missing header row")
+	}
+	header := records[0]
+	cols, err := m.columns(header)
+	if err != nil {
+		return nil, err
+	}
+	var rows []Row
+	for i, record := range records[1:] {
+		if isEmpty(record) {
+			continue
+		}
+		row := Row{Line: i + 2, Values: make(map[string]string, len(header))}
+		for j, name := range header {
+			if j < len(record) && name != "" {
+				row.Values[name] = strings.TrimSpace(record[j])
+			}
+		}
+		row.Err = m.parseRow(&row, func(field string) string {
+			col, ok := cols[field]
+			if !ok || col >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[col])
+		})
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (m Mapping) columns(header []string) (map[string]int, error) {
+	fields := map[string]string{
+		"date":     m.Date,
+		"start":    m.Start,
+		"end":      m.End,
+		"duration": m.Duration,
+		"type":     m.Type,
+		"comment":  m.Comment,
+		"source":   m.Source,
+		"sourceId": m.SourceID,
+	}
+	if m.Start == "" {
+		return nil, errors.New("missing column mapping of the start time")
+	}
+	if m.End == "" && m.Duration == "" {
+		return nil, errors.New("missing column mapping of the end time or duration")
+	}
+	cols := make(map[string]int)
+	for field, column := range fields {
+		if column == "" {
+			continue
+		}
+		col, err := findColumn(header, column)
+		if err != nil {
+			return nil, fmt.Errorf("mapping of %s: %w", field, err)
+		}
+		cols[field] = col
+	}
+	return cols, nil
+}
+
+func findColumn(header []string, column string) (int, error) {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 || n > len(header) {
+			return 0, fmt.Errorf("column %d is out of range, the header has %d columns", n, len(header))
+		}
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("no column named %q in the header", column)
+}
+
+func (m Mapping) parseRow(row *Row, get func(field string) string) error {
+	loc := m.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	date := get("date")
+	layout := m.TimeFormat
+	switch {
+	case m.Date != "" && date == "":
+		return errors.New("missing date")
+	case m.Date != "":
+		dateFormat := m.DateFormat
+		if dateFormat == "" {
+			dateFormat = "2006-01-02"
+		}
+		if layout == "" {
+			layout = "15:04"
+		}
+		layout = dateFormat + " " + layout
+		date += " "
+	case layout == "":
+		layout = time.RFC3339
+	}
+
+	start, err := time.ParseInLocation(layout, date+get("start"), loc)
+	if err != nil {
+		return fmt.Errorf("parse start: %w", err)
+	}
+	var end time.Time
+	if m.End != "" && get("end") != "" {
+		end, err = time.ParseInLocation(layout, date+get("end"), loc)
+		if err != nil {
+			return fmt.Errorf("parse end: %w", err)
+		}
+		// Periods passing midnight only have the date of their start
+		if m.Date != "" && !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+	} else if m.Duration != "" && get("duration") != "" {
+		dur, err := ParseDuration(get("duration"))
+		if err != nil {
+			return fmt.Errorf("parse duration: %w", err)
+		}
+		end = start.Add(dur)
+	} else {
+		return errors.New("missing end time or duration")
+	}
+	periodType, err := parsePeriodType(get("type"))
+	if err != nil {
+		return err
+	}
+
+	row.Period = personio.Period{Start: start, End: end, PeriodType: periodType}
+	if comment := get("comment"); comment != "" {
+		row.Period.Comment = &comment
+	}
+	row.Source = get("source")
+	row.SourceID = get("sourceId")
+	return nil
+}
+
+func parsePeriodType(value string) (personio.PeriodType, error) {
+	switch strings.ToLower(value) {
+	case "", "work", "arbeit":
+		return personio.PeriodTypeWork, nil
+	case "break", "pause":
+		return personio.PeriodTypeBreak, nil
+	default:
+		return "", fmt.Errorf("unknown period type %q, must be \"work\" or \"break\"", value)
+	}
+}
+
+// ParseDuration parses a duration as written in spreadsheets, either as
+// "1:30", decimal hours "1.5" or "1,5", or a Go duration like "1h30m".
+func ParseDuration(value string) (time.Duration, error) {
+	if h, m, ok := strings.Cut(value, ":"); ok {
+		hours, err := strconv.Atoi(h)
+		if err != nil {
+			return 0, fmt.Errorf("invalid hours in %q", value)
+		}
+		minutes, err := strconv.Atoi(m)
+		if err != nil || minutes < 0 || minutes >= 60 {
+			return 0, fmt.Errorf("invalid minutes in %q", value)
+		}
+		return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+	}
+	if hours, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64); err == nil {
+		return time.Duration(hours * float64(time.Hour)).Round(time.Second), nil
+	}
+	return time.ParseDuration(value)
+}
+
+func isEmpty(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
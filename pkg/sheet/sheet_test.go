@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sheet
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestParse(t *testing.T) {
+	csv := "\xef\xbb\xbfDatum;Von;Bis;Art;Notiz\n" +
+		"18.01.2023;08:00;12:00;Arbeit;Before lunch\n" +
+		"18.01.2023;12:00;12:30;Pause;\n" +
+		";;;;\n" +
+		"18.01.2023;22:00;02:00;;Night shift\n" +
+		"19.01.2023;nope;12:00;;\n"
+	records, err := ReadCSV(strings.NewReader(csv), ';')
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := Parse(records, Mapping{
+		Date:       "datum",
+		Start:      "Von",
+		End:        "3",
+		Type:       "Art",
+		Comment:    "Notiz",
+		DateFormat: "02.01.2006",
+		Location:   time.UTC,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("want 4 rows, as the empty row is skipped, got %d", len(rows))
+	}
+
+	day := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+	if p := rows[0].Period; !p.Start.Equal(day.Add(8*time.Hour)) || !p.End.Equal(day.Add(12*time.Hour)) ||
+		p.PeriodType != personio.PeriodTypeWork || p.GetComment() != "Before lunch" {
+		t.Errorf("row 2: got %+v", p)
+	}
+	if p := rows[1].Period; p.PeriodType != personio.PeriodTypeBreak || p.Comment != nil {
+		t.Errorf("row 3: want break without comment, got %+v", p)
+	}
+	if p := rows[2].Period; rows[2].Line != 5 || !p.End.Equal(day.AddDate(0, 0, 1).Add(2*time.Hour)) {
+		t.Errorf("row 5: want end on the next day, got line %d and %+v", rows[2].Line, p)
+	}
+	if rows[3].Err == nil {
+		t.Error("row 6: want error on invalid start time")
+	}
+
+	obj, err := rows[0].Object()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p personio.Period
+	if err := json.Unmarshal(obj, &p); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Start.Equal(rows[0].Period.Start) || p.GetComment() != "Before lunch" {
+		t.Errorf("want object to round-trip as a period, got %s", obj)
+	}
+}
+
+func TestParseMappingErrors(t *testing.T) {
+	records := [][]string{{"Start", "End"}}
+	if _, err := Parse(records, Mapping{Start: "Start"}); err == nil {
+		t.Error("want error on missing end mapping")
+	}
+	if _, err := Parse(records, Mapping{Start: "Begin", End: "End"}); err == nil {
+		t.Error("want error on unknown column")
+	}
+	if _, err := Parse(records, Mapping{Start: "1", End: "3"}); err == nil {
+		t.Error("want error on out of range column")
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"1:30":  90 * time.Minute,
+		"1.5":   90 * time.Minute,
+		"0,25":  15 * time.Minute,
+		"2h15m": 135 * time.Minute,
+	} {
+		got, err := ParseDuration(value)
+		if err != nil {
+			t.Errorf("%q: %v", value, err)
+		} else if got != want {
+			t.Errorf("%q: want %s, got %s", value, want, got)
+		}
+	}
+	if _, err := ParseDuration("1:75"); err == nil {
+		t.Error("want error on invalid minutes")
+	}
+}
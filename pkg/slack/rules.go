@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package slack
+
+import (
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/report"
+)
+
+// Rules decide which status to set.
+type Rules struct {
+	Vacation Status
+	Sick     Status
+	Remote   Status
+	// SickKeywords are words in absence names that mark the absence as
+	// sick leave, matched case-insensitively. Other absences are seen as
+	// vacation.
+	SickKeywords []string
+}
+
+// StatusFor returns the status for the day, given where you work that day,
+// or the empty status if none of the rules apply.
+//
+// Absences expire after their last day, and remote work at the end of the
+// day. The day's date must be in local time.
+func (r Rules) StatusFor(day report.Day, location report.Location) Status {
+	if a := day.Absence; a != nil {
+		status := r.Vacation
+		name := strings.ToLower(a.Name)
+		for _, keyword := range r.SickKeywords {
+			if keyword != "" && strings.Contains(name, strings.ToLower(keyword)) {
+				status = r.Sick
+				break
+			}
+		}
+		status.Expiration = a.EndDate.AddDays(1).Midnight(day.Date.Location())
+		return status
+	}
+	if location == report.LocationRemote {
+		status := r.Remote
+		year, month, date := day.Date.Date()
+		status.Expiration = time.Date(year, month, date+1, 0, 0, 0, 0, day.Date.Location())
+		return status
+	}
+	return Status{}
+}
+
+// IsOwn returns true if the status was set by these rules, and may
+// therefore be cleared when the rules no longer apply. Statuses set by
+// other means are left as-is.
+func (r Rules) IsOwn(status Status) bool {
+	if status.IsZero() {
+		return false
+	}
+	for _, own := range []Status{r.Vacation, r.Sick, r.Remote} {
+		if !own.IsZero() && own.Text == status.Text && own.Emoji == status.Emoji {
+			return true
+		}
+	}
+	return false
+}
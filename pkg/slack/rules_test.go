@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package slack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/report"
+)
+
+var testRules = Rules{
+	Vacation:     Status{Text: "On vacation", Emoji: ":beach_with_umbrella:"},
+	Sick:         Status{Text: "Out sick", Emoji: ":face_with_thermometer:"},
+	Remote:       Status{Text: "Working remotely", Emoji: ":house_with_garden:"},
+	SickKeywords: []string{"sick", "krank"},
+}
+
+func TestRulesStatusFor(t *testing.T) {
+	date := time.Date(2023, 7, 5, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		absence  *personio.CalendarAbsencePeriod
+		location report.Location
+		want     Status
+	}{
+		{
+			name:    "vacation",
+			absence: &personio.CalendarAbsencePeriod{Name: "Paid vacation", EndDate: personio.NewDate(2023, 7, 14)},
+			want: Status{Text: "On vacation", Emoji: ":beach_with_umbrella:",
+				Expiration: time.Date(2023, 7, 15, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "sick overrides location",
+			absence:  &personio.CalendarAbsencePeriod{Name: "Krankheit", EndDate: personio.NewDate(2023, 7, 5)},
+			location: report.LocationRemote,
+			want: Status{Text: "Out sick", Emoji: ":face_with_thermometer:",
+				Expiration: time.Date(2023, 7, 6, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "remote",
+			location: report.LocationRemote,
+			want: Status{Text: "Working remotely", Emoji: ":house_with_garden:",
+				Expiration: time.Date(2023, 7, 6, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "office",
+			location: report.LocationOffice,
+			want:     Status{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := testRules.StatusFor(report.Day{Date: date, Absence: tc.absence}, tc.location)
+			if got.Text != tc.want.Text || got.Emoji != tc.want.Emoji || !got.Expiration.Equal(tc.want.Expiration) {
+				t.Errorf("want %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRulesIsOwn(t *testing.T) {
+	if !testRules.IsOwn(Status{Text: "Out sick", Emoji: ":face_with_thermometer:"}) {
+		t.Error("want own status to be detected")
+	}
+	if testRules.IsOwn(Status{Text: "In a meeting", Emoji: ":calendar:"}) {
+		t.Error("want other status to not be own")
+	}
+	if testRules.IsOwn(Status{}) {
+		t.Error("want empty status to not be own")
+	}
+}
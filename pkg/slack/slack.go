@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package slack sets your Slack status based on your absences and work
+// location in Personio.
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultBaseURL is the URL of Slack's Web API.
+const DefaultBaseURL = "https://slack.com/api"
+
+// ErrAPI is returned when Slack's Web API responds with an error.
+var ErrAPI = errors.New("slack API error")
+
+// Status is a Slack user status.
+type Status struct {
+	Text  string `json:"text"`
+	Emoji string `json:"emoji"`
+	// Expiration is when Slack clears the status, or zero to never
+	// clear it.
+	Expiration time.Time `json:"expiration,omitempty"`
+}
+
+// IsZero returns true for the empty status, i.e when no status is set.
+func (s Status) IsZero() bool {
+	return s.Text == "" && s.Emoji == ""
+}
+
+// Client calls Slack's Web API using a user token.
+type Client struct {
+	// Token is a user token ("xoxp-...") with the users.profile:read and
+	// users.profile:write scopes.
+	Token string
+	// BaseURL defaults to [DefaultBaseURL].
+	BaseURL string
+	// HTTP defaults to [http.DefaultClient].
+	HTTP *http.Client
+}
+
+type profile struct {
+	StatusText       string `json:"status_text"`
+	StatusEmoji      string `json:"status_emoji"`
+	StatusExpiration int64  `json:"status_expiration"`
+}
+
+type apiResponse struct {
+	OK      bool     `json:"ok"`
+	Error   string   `json:"error"`
+	Profile *profile `json:"profile"`
+}
+
+// GetStatus returns your current status.
+func (c *Client) GetStatus() (Status, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url("users.profile.get"), nil)
+	if err != nil {
+		return Status{}, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return Status{}, fmt.Errorf("get status: %w", err)
+	}
+	if resp.Profile == nil {
+		return Status{}, nil
+	}
+	status := Status{
+		Text:  resp.Profile.StatusText,
+		Emoji: resp.Profile.StatusEmoji,
+	}
+	if resp.Profile.StatusExpiration > 0 {
+		status.Expiration = time.Unix(resp.Profile.StatusExpiration, 0)
+	}
+	return status, nil
+}
+
+// SetStatus sets your status, where the empty status clears it.
+func (c *Client) SetStatus(status Status) error {
+	p := profile{
+		StatusText:  status.Text,
+		StatusEmoji: status.Emoji,
+	}
+	if !status.Expiration.IsZero() {
+		p.StatusExpiration = status.Expiration.Unix()
+	}
+	body, err := json.Marshal(struct {
+		Profile profile `json:"profile"`
+	}{p})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url("users.profile.set"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if _, err := c.do(req); err != nil {
+		return fmt.Errorf("set status: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) url(method string) string {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	return base + "/" + method
+}
+
+func (c *Client) do(req *http.Request) (apiResponse, error) {
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return apiResponse{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiResponse{}, fmt.Errorf("%w: %s", ErrAPI, resp.Status)
+	}
+	var apiResp apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return apiResponse{}, fmt.Errorf("parse response: %w", err)
+	}
+	if !apiResp.OK {
+		return apiResponse{}, fmt.Errorf("%w: %s", ErrAPI, apiResp.Error)
+	}
+	return apiResp, nil
+}
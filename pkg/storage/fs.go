@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/applejag/rootless-personio/pkg/filelock"
+)
+
+// FS is a [Backend] that stores each key as a file inside a directory,
+// where logs are stored with one record per line. Concurrent processes are
+// coordinated using file locks.
+type FS struct {
+	Dir string
+}
+
+// NewFS returns a filesystem backend in the directory, which is created
+// when the first value is written.
+func NewFS(dir string) *FS {
+	return &FS{Dir: dir}
+}
+
+func (s *FS) path(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// Get implements [Backend].
+func (s *FS) Get(key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+// Put implements [Backend].
+func (s *FS) Put(key string, value []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return filelock.WriteFile(path, value, 0o600)
+}
+
+// Update implements [Backend].
+func (s *FS) Update(key string, f func(value []byte) ([]byte, error)) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return filelock.WithLock(path, func() error {
+		old, err := s.Get(key)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		value, err := f(old)
+		if err != nil {
+			return err
+		}
+		return filelock.WriteFile(path, value, 0o600)
+	})
+}
+
+// Delete implements [Backend].
+func (s *FS) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Append implements [Backend].
+func (s *FS) Append(key string, records ...[]byte) error {
+	if err := validateRecords(records); err != nil {
+		return err
+	}
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, r := range records {
+		buf.Write(r)
+		buf.WriteByte('\n')
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	// A single write, so records of concurrent processes are not
+	// interleaved
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Records implements [Backend]. Blank lines are skipped.
+func (s *FS) Records(key string) ([][]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		records = append(records, bytes.Clone(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", key, err)
+	}
+	return records, nil
+}
+
+// Close implements [Backend].
+func (s *FS) Close() error {
+	return nil
+}
@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS storage_values (
+	key TEXT PRIMARY KEY,
+	value BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS storage_records (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	key TEXT NOT NULL,
+	value BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS storage_records_key ON storage_records (key, id);
+`
+
+// SQLite is a [Backend] that stores all keys in a single SQLite database,
+// which suits deployments where a single file is easier to back up than a
+// directory.
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the database, creating it if it doesn't exist.
+func OpenSQLite(path string) (*SQLite, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create storage directory: %w", err)
+	}
+	// Transactions take the write lock right away, so concurrent updates
+	// wait for each other instead of failing when upgrading their lock
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("open storage: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create storage schema: %w", err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+// Get implements [Backend].
+func (s *SQLite) Get(key string) ([]byte, error) {
+	return get(s.db, key)
+}
+
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func get(q queryer, key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	var value []byte
+	err := q.QueryRow(`SELECT value FROM storage_values WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+// Put implements [Backend].
+func (s *SQLite) Put(key string, value []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`INSERT INTO storage_values (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+// Update implements [Backend].
+func (s *SQLite) Update(key string, f func(value []byte) ([]byte, error)) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	old, err := get(tx, key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	value, err := f(old)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO storage_values (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, value); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete implements [Backend].
+func (s *SQLite) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM storage_values WHERE key = ?`, key)
+	return err
+}
+
+// Append implements [Backend].
+func (s *SQLite) Append(key string, records ...[]byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	if err := validateRecords(records); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, r := range records {
+		if _, err := tx.Exec(`INSERT INTO storage_records (key, value) VALUES (?, ?)`, key, r); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Records implements [Backend].
+func (s *SQLite) Records(key string) ([][]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT value FROM storage_records WHERE key = ? ORDER BY id`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records [][]byte
+	for rows.Next() {
+		var r []byte
+		if err := rows.Scan(&r); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Close implements [Backend].
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
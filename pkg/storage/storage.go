@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package storage abstracts where the local state is persisted, such as
+// remembered cookies and the audit journal, so features don't depend on
+// whether it's stored in files or in a database.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned by [Backend.Get] when the key has no value.
+var ErrNotFound = errors.New("not found in storage")
+
+// Backend stores values and append-only logs of records under
+// slash-separated keys, such as "sessions/example.personio.de/me.json".
+//
+// Implementations must be safe for concurrent use, including by other
+// processes using the same storage.
+type Backend interface {
+	// Get returns the value of the key, or [ErrNotFound] if it has none.
+	Get(key string) ([]byte, error)
+	// Put replaces the value of the key.
+	Put(key string, value []byte) error
+	// Update replaces the value of the key with the value returned by f,
+	// while preventing concurrent updates of the same key. The value
+	// passed to f is nil when the key has no value.
+	Update(key string, f func(value []byte) ([]byte, error)) error
+	// Delete removes the value of the key. Deleting a missing key is not an
+	// error.
+	Delete(key string) error
+	// Append adds records to the end of the log at the key. Records must
+	// not contain newlines, such as compact JSON.
+	Append(key string, records ...[]byte) error
+	// Records returns all records of the log at the key, in the order they
+	// were appended.
+	Records(key string) ([][]byte, error)
+	// Close releases the resources of the backend.
+	Close() error
+}
+
+// Kind is the type of a [Backend].
+type Kind string
+
+const (
+	// KindFilesystem stores each key as a file in a directory.
+	KindFilesystem Kind = "filesystem"
+	// KindSQLite stores all keys in a single SQLite database file.
+	KindSQLite Kind = "sqlite"
+)
+
+// Open returns a backend of the kind at the path, which is a directory for
+// [KindFilesystem] and a database file for [KindSQLite]. An empty kind
+// means [KindFilesystem].
+func Open(kind Kind, path string) (Backend, error) {
+	switch kind {
+	case "", KindFilesystem:
+		return NewFS(path), nil
+	case KindSQLite:
+		return OpenSQLite(path)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %q", kind)
+	}
+}
+
+// validateKey rejects keys that could escape the storage, such as
+// "../secrets" in the filesystem backend.
+func validateKey(key string) error {
+	if key == "" {
+		return errors.New("empty storage key")
+	}
+	if strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return fmt.Errorf("invalid storage key: %q", key)
+	}
+	return nil
+}
+
+func validateRecords(records [][]byte) error {
+	for _, r := range records {
+		if strings.ContainsAny(string(r), "\r\n") {
+			return errors.New("storage record must not contain newlines")
+		}
+	}
+	return nil
+}
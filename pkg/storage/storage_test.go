@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBackends(t *testing.T) {
+	backends := map[string]func(t *testing.T) Backend{
+		"filesystem": func(t *testing.T) Backend {
+			return NewFS(t.TempDir())
+		},
+		"sqlite": func(t *testing.T) Backend {
+			s, err := OpenSQLite(filepath.Join(t.TempDir(), "storage.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			defer s.Close()
+
+			if _, err := s.Get("sessions/host/me.json"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("get missing: want ErrNotFound, got %v", err)
+			}
+			if err := s.Put("sessions/host/me.json", []byte("first")); err != nil {
+				t.Fatalf("put: %s", err)
+			}
+			if got, err := s.Get("sessions/host/me.json"); err != nil || string(got) != "first" {
+				t.Errorf("get: want %q, got %q (err %v)", "first", got, err)
+			}
+
+			// Concurrent updates must not lose each other's changes
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := s.Update("counter", func(value []byte) ([]byte, error) {
+						return append(value, 'x'), nil
+					})
+					if err != nil {
+						t.Errorf("update: %s", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if got, _ := s.Get("counter"); string(got) != strings.Repeat("x", 10) {
+				t.Errorf("want 10 updates, got %q", got)
+			}
+
+			if err := s.Delete("counter"); err != nil {
+				t.Fatalf("delete: %s", err)
+			}
+			if err := s.Delete("counter"); err != nil {
+				t.Errorf("delete missing: %s", err)
+			}
+
+			if err := s.Append("audit.jsonl", []byte(`{"a":1}`), []byte(`{"a":2}`)); err != nil {
+				t.Fatalf("append: %s", err)
+			}
+			if err := s.Append("audit.jsonl", []byte(`{"a":3}`)); err != nil {
+				t.Fatalf("append: %s", err)
+			}
+			records, err := s.Records("audit.jsonl")
+			if err != nil {
+				t.Fatalf("records: %s", err)
+			}
+			if len(records) != 3 || string(records[0]) != `{"a":1}` || string(records[2]) != `{"a":3}` {
+				t.Errorf("want records in appended order, got %q", records)
+			}
+			if err := s.Append("audit.jsonl", []byte("a\nb")); err == nil {
+				t.Error("want error for record with newline")
+			}
+			if err := s.Put("../escape", nil); err == nil {
+				t.Error("want error for key outside the storage")
+			}
+		})
+	}
+}
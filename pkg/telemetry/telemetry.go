@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package telemetry reports anonymized failures of requests to Personio,
+// so changes on Personio's side that break this program are noticed
+// across all users within hours, instead of when the issues come in.
+//
+// Reports only contain which endpoint failed and how, such as the status
+// code, the names of the headers sent, and the kind of error. They never
+// contain URLs, IDs, header values, bodies, or error messages.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// MaxFailures is how many failures are kept per report. Later failures
+// are only counted.
+const MaxFailures = 50
+
+// Failure is an anonymized failed request.
+type Failure struct {
+	Command     string   `json:"command"`
+	Endpoint    string   `json:"endpoint"`
+	Method      string   `json:"method"`
+	StatusCode  int      `json:"statusCode,omitempty"`
+	ContentType string   `json:"contentType,omitempty"`
+	Headers     []string `json:"headers"`
+	// Kind is the kind of error, e.g "html" for an unexpected HTML
+	// response. See [Kind].
+	Kind string `json:"kind"`
+}
+
+// Report is the body sent to the telemetry endpoint.
+type Report struct {
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	// Date is the day of the report, in UTC, as finer times aren't needed.
+	Date     string    `json:"date"`
+	Failures []Failure `json:"failures"`
+	// Dropped is how many failures were left out, beyond [MaxFailures].
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// Collector collects failures, to send them all at once when done.
+type Collector struct {
+	// Endpoint is the URL that reports are sent to via HTTP POST.
+	Endpoint string
+	// Command is the command that failed, e.g "attendance set", which is
+	// added to all observed failures.
+	Command string
+	// Client is used to send the report, or [http.DefaultClient] if nil.
+	Client *http.Client
+
+	mu       sync.Mutex
+	failures []Failure
+	dropped  int
+}
+
+// Observe records the failure, and is meant to be passed to
+// [personio.Client.SetFailureObserver].
+func (c *Collector) Observe(f personio.Failure) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.failures) >= MaxFailures {
+		c.dropped++
+		return
+	}
+	c.failures = append(c.failures, Failure{
+		Command:     c.Command,
+		Endpoint:    f.Endpoint,
+		Method:      f.Method,
+		StatusCode:  f.StatusCode,
+		ContentType: f.ContentType,
+		Headers:     f.Headers,
+		Kind:        Kind(f.Err),
+	})
+}
+
+// Report returns the collected failures, or nil if there are none.
+func (c *Collector) Report(now time.Time) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.failures) == 0 {
+		return nil
+	}
+	return &Report{
+		Version:  version(),
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Date:     now.UTC().Format(time.DateOnly),
+		Failures: append([]Failure(nil), c.failures...),
+		Dropped:  c.dropped,
+	}
+}
+
+// Send sends the collected failures, if any, and then forgets them.
+func (c *Collector) Send(ctx context.Context) error {
+	report := c.Report(time.Now())
+	if report == nil {
+		return nil
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", personio.UserAgent)
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send telemetry: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("send telemetry: %s", resp.Status)
+	}
+	c.mu.Lock()
+	c.failures = nil
+	c.dropped = 0
+	c.mu.Unlock()
+	return nil
+}
+
+// Kind returns the kind of a request error, without any of its details.
+func Kind(err error) string {
+	var apiErr personio.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, personio.ErrUnexpectedHTML):
+		return "html"
+	case errors.Is(err, personio.ErrMaintenance):
+		return "maintenance"
+	case errors.Is(err, personio.ErrResponseTooLarge):
+		return "too_large"
+	case errors.Is(err, personio.ErrTooManyAttempts):
+		return "lockout"
+	case errors.Is(err, personio.ErrPermissionDenied):
+		return "permission"
+	case errors.Is(err, personio.ErrMonthLocked):
+		return "month_locked"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &netErr):
+		return "network"
+	case errors.As(err, &apiErr):
+		return "api_error"
+	case errors.Is(err, personio.ErrNon2xxStatusCode):
+		return "status"
+	default:
+		return "other"
+	}
+}
+
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "unknown"
+	}
+	return info.Main.Version
+}
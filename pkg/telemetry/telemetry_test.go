@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestCollectorSend(t *testing.T) {
+	var got Report
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read report: %s", err)
+		}
+		body = string(b)
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("decode report: %s", err)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Collector{Endpoint: srv.URL, Command: "attendance set"}
+	if err := c.Send(context.Background()); err != nil {
+		t.Fatalf("send without failures: %s", err)
+	}
+	if body != "" {
+		t.Fatal("want no report without failures")
+	}
+
+	c.Observe(personio.Failure{
+		Endpoint:    "AttendanceDay",
+		Method:      http.MethodPut,
+		StatusCode:  http.StatusOK,
+		ContentType: "text/html",
+		Headers:     []string{"Content-Type", "X-CSRF-Token"},
+		Err:         fmt.Errorf("PUT https://secret-company.personio.de/api/v1/attendances/days/8c1f: %w", personio.ErrUnexpectedHTML),
+	})
+	if err := c.Send(context.Background()); err != nil {
+		t.Fatalf("send: %s", err)
+	}
+	if len(got.Failures) != 1 {
+		t.Fatalf("want 1 failure, got %d", len(got.Failures))
+	}
+	f := got.Failures[0]
+	if f.Command != "attendance set" || f.Endpoint != "AttendanceDay" || f.Kind != "html" {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if strings.Contains(body, "secret-company") || strings.Contains(body, "8c1f") {
+		t.Errorf("report leaks error details: %s", body)
+	}
+	if c.Report(time.Now()) != nil {
+		t.Error("want failures to be forgotten after sending")
+	}
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package tidy cleans up attendance periods by merging adjacent, overlapping,
+// and duplicate periods.
+package tidy
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+// Periods returns the periods sorted by start time, where consecutive
+// periods of the same type and project are merged if the gap between them
+// is at most maxGap. Overlapping and duplicate periods are merged the same
+// way, which cleans up noisy output from time-tracking importers.
+//
+// Merged periods keep the ID of the first period, and the distinct
+// comments of all merged periods joined by "; ".
+func Periods(periods []personio.Period, maxGap time.Duration) []personio.Period {
+	if len(periods) == 0 {
+		return nil
+	}
+	sorted := make([]personio.Period, len(periods))
+	copy(sorted, periods)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	result := []personio.Period{sorted[0]}
+	for _, p := range sorted[1:] {
+		last := &result[len(result)-1]
+		if !canMerge(*last, p, maxGap) {
+			result = append(result, p)
+			continue
+		}
+		if p.End.After(last.End) {
+			last.End = p.End
+		}
+		last.Comment = mergeComments(last.Comment, p.Comment)
+	}
+	return result
+}
+
+func canMerge(a, b personio.Period, maxGap time.Duration) bool {
+	if periodType(a) != periodType(b) || a.GetProjectID() != b.GetProjectID() {
+		return false
+	}
+	return b.Start.Sub(a.End) <= maxGap
+}
+
+func periodType(p personio.Period) personio.PeriodType {
+	if p.PeriodType == "" {
+		return personio.PeriodTypeWork
+	}
+	return p.PeriodType
+}
+
+func mergeComments(a, b *string) *string {
+	if b == nil || *b == "" {
+		return a
+	}
+	if a == nil || *a == "" {
+		return b
+	}
+	for _, c := range strings.Split(*a, "; ") {
+		if c == *b {
+			return a
+		}
+	}
+	merged := *a + "; " + *b
+	return &merged
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tidy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestPeriods(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2023, 1, 18, hour, min, 0, 0, time.UTC)
+	}
+	comment := func(s string) *string {
+		return &s
+	}
+	periods := []personio.Period{
+		{Start: at(13, 0), End: at(17, 0), Comment: comment("review")},
+		{Start: at(8, 0), End: at(10, 0), Comment: comment("coding")},
+		{Start: at(10, 3), End: at(12, 0), Comment: comment("coding")},
+		// Duplicate from the importer
+		{Start: at(10, 3), End: at(12, 0), Comment: comment("coding")},
+		{Start: at(12, 0), End: at(13, 0), PeriodType: personio.PeriodTypeBreak},
+		{Start: at(16, 30), End: at(17, 30), Comment: comment("meeting")},
+	}
+
+	got := Periods(periods, 5*time.Minute)
+
+	want := []personio.Period{
+		{Start: at(8, 0), End: at(12, 0), Comment: comment("coding")},
+		{Start: at(12, 0), End: at(13, 0), PeriodType: personio.PeriodTypeBreak},
+		{Start: at(13, 0), End: at(17, 30), Comment: comment("review; meeting")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d periods, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) {
+			t.Errorf("period %d: want %s-%s, got %s-%s", i,
+				want[i].Start.Format("15:04"), want[i].End.Format("15:04"),
+				got[i].Start.Format("15:04"), got[i].End.Format("15:04"))
+		}
+		if got[i].GetComment() != want[i].GetComment() {
+			t.Errorf("period %d: want comment %q, got %q", i, want[i].GetComment(), got[i].GetComment())
+		}
+	}
+}
+
+func TestPeriodsKeepsGaps(t *testing.T) {
+	periods := []personio.Period{
+		{Start: time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC), End: time.Date(2023, 1, 18, 10, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2023, 1, 18, 10, 30, 0, 0, time.UTC), End: time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)},
+	}
+	if got := Periods(periods, 5*time.Minute); len(got) != 2 {
+		t.Errorf("want 2 periods, got %d", len(got))
+	}
+}
+
+func FuzzPeriods(f *testing.F) {
+	f.Add([]byte{0, 12, 0, 12, 12, 12, 0}, uint8(0))
+	f.Add([]byte{0, 12, 1, 13, 12, 0}, uint8(1))
+	f.Add([]byte{0, 48, 0, 4, 8, 1}, uint8(2))
+	f.Fuzz(func(t *testing.T, data []byte, maxGapSteps uint8) {
+		base := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+		maxGap := time.Duration(maxGapSteps%12) * 5 * time.Minute
+		var periods []personio.Period
+		for i := 0; i+2 < len(data) && len(periods) < 16; i += 3 {
+			start := base.Add(time.Duration(data[i]) * 5 * time.Minute)
+			p := personio.Period{
+				Start: start,
+				End:   start.Add(time.Duration(data[i+1]%48+1) * 5 * time.Minute),
+			}
+			if data[i+2]&1 != 0 {
+				p.PeriodType = personio.PeriodTypeBreak
+			}
+			periods = append(periods, p)
+		}
+
+		got := Periods(periods, maxGap)
+
+		if len(got) > len(periods) {
+			t.Fatalf("want at most %d periods, got %d", len(periods), len(got))
+		}
+		for i := 1; i < len(got); i++ {
+			if got[i].Start.Before(got[i-1].Start) {
+				t.Errorf("period %d starts before period %d", i, i-1)
+			}
+		}
+		for _, in := range periods {
+			var covered bool
+			for _, out := range got {
+				if periodType(out) == periodType(in) &&
+					!in.Start.Before(out.Start) && !in.End.After(out.End) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				t.Errorf("period %s-%s (%s) not covered by result", in.Start.Format("15:04"),
+					in.End.Format("15:04"), periodType(in))
+			}
+		}
+		for _, out := range got {
+			if !out.End.After(out.Start) {
+				t.Errorf("period %s-%s has no duration", out.Start.Format("15:04"), out.End.Format("15:04"))
+			}
+		}
+	})
+}
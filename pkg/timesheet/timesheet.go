@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package timesheet contains the canonical time-tracking entry, which
+// importers, exporters, and reports convert to and from, instead of
+// converting between each other's formats pairwise.
+package timesheet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+// Source is where an entry originates from.
+type Source string
+
+const (
+	SourcePersonio Source = "personio"
+	SourceClock    Source = "clock"
+	SourceImport   Source = "import"
+)
+
+// Entry is a single period of time tracking.
+type Entry struct {
+	// Date is the day that the entry belongs to, which for periods passing
+	// midnight is the day it started on. Ex: "2023-01-18"
+	Date      string              `json:"date"`
+	Start     time.Time           `json:"start"`
+	End       time.Time           `json:"end"`
+	Type      personio.PeriodType `json:"type"`
+	ProjectID *int                `json:"projectId,omitempty"`
+	Comment   *string             `json:"comment,omitempty"`
+	Source    Source              `json:"source,omitempty"`
+	// Origin is how the entry was created in Personio. Only set for
+	// entries from Personio, and not part of the [Entry.Hash].
+	Origin personio.PeriodOrigin `json:"origin,omitempty"`
+	// SourceID is the entry's ID in its source, such as the period's UUID
+	// in Personio.
+	SourceID string `json:"sourceId,omitempty"`
+}
+
+// Duration returns the time between the start and end of the entry.
+func (e Entry) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+// GetComment returns the comment, or an empty string if there is none.
+func (e Entry) GetComment() string {
+	if e.Comment == nil {
+		return ""
+	}
+	return *e.Comment
+}
+
+// Hash returns a hash of the entry's date, times, type, project, and
+// comment, used to detect changes to entries that were imported before.
+// The source and source ID are not part of the hash.
+func (e Entry) Hash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%d\n%s",
+		e.Date,
+		e.Start.UTC().Format(time.RFC3339),
+		e.End.UTC().Format(time.RFC3339),
+		e.Type,
+		e.Period().GetProjectID(),
+		e.GetComment())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Period converts the entry to a Personio period. The period's ID is the
+// source ID if it's a UUID, or otherwise nil so that a new ID is generated
+// when the period is submitted.
+func (e Entry) Period() personio.Period {
+	id, err := uuid.Parse(e.SourceID)
+	if err != nil {
+		id = uuid.Nil
+	}
+	typ := e.Type
+	if typ == "" {
+		typ = personio.PeriodTypeWork
+	}
+	return personio.Period{
+		ID:         id,
+		PeriodType: typ,
+		Comment:    e.Comment,
+		ProjectID:  e.ProjectID,
+		Start:      e.Start,
+		End:        e.End,
+	}
+}
+
+// FromPeriod converts a Personio period to an entry, dated on the local
+// date of the period's start.
+func FromPeriod(p personio.Period, source Source) Entry {
+	var sourceID string
+	if p.ID != uuid.Nil {
+		sourceID = p.ID.String()
+	}
+	typ := p.PeriodType
+	if typ == "" {
+		typ = personio.PeriodTypeWork
+	}
+	return Entry{
+		Date:      p.Start.Local().Format(time.DateOnly),
+		Start:     p.Start,
+		End:       p.End,
+		Type:      typ,
+		ProjectID: p.ProjectID,
+		Comment:   p.Comment,
+		Source:    source,
+		SourceID:  sourceID,
+	}
+}
+
+// FromCalendar converts all attendance periods in the calendar to entries,
+// sorted by their start time. Entries are dated on the attendance day they
+// belong to in Personio.
+func FromCalendar(cal *personio.AttendanceCalendar) ([]Entry, error) {
+	dayDates := make(map[uuid.UUID]string, len(cal.AttendanceDays.Data))
+	for _, day := range cal.AttendanceDays.Data {
+		dayDates[day.ID] = day.Attributes.Day.String()
+	}
+	entries := make([]Entry, 0, len(cal.AttendancePeriods.Data))
+	for _, p := range cal.AttendancePeriods.Data {
+		period, err := p.Period()
+		if err != nil {
+			return nil, err
+		}
+		date, ok := dayDates[p.Attributes.AttendanceDayID]
+		if !ok {
+			date = period.Start.Local().Format(time.DateOnly)
+		}
+		entries = append(entries, Entry{
+			Date:      date,
+			Start:     period.Start,
+			End:       period.End,
+			Type:      personio.PeriodType(p.Attributes.PeriodType),
+			ProjectID: p.Attributes.ProjectID,
+			Comment:   p.Attributes.Comment,
+			Source:    SourcePersonio,
+			SourceID:  p.ID.String(),
+			Origin:    p.Attributes.Origin,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Start.Before(entries[j].Start)
+	})
+	return entries, nil
+}
+
+// ByDate groups the entries by their date, keeping their order.
+func ByDate(entries []Entry) map[string][]Entry {
+	byDate := make(map[string][]Entry)
+	for _, e := range entries {
+		byDate[e.Date] = append(byDate[e.Date], e)
+	}
+	return byDate
+}
+
+// Periods converts the entries to Personio periods.
+func Periods(entries []Entry) []personio.Period {
+	periods := make([]personio.Period, len(entries))
+	for i, e := range entries {
+		periods[i] = e.Period()
+	}
+	return periods
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package timesheet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+func TestFromCalendar(t *testing.T) {
+	dayID := uuid.New()
+	periodID := uuid.New()
+	comment := "Night shift"
+	projectID := 42
+	cal := &personio.AttendanceCalendar{}
+	cal.AttendanceDays.Data = []personio.CalendarDay{
+		{ID: dayID, Attributes: personio.CalendarDayAttributes{Day: personio.NewDate(2023, 1, 18)}},
+	}
+	cal.AttendancePeriods.Data = []personio.CalendarAttendancePeriod{
+		{
+			ID: periodID,
+			Attributes: personio.CalendarAttendancePeriodAttributes{
+				AttendanceDayID: dayID,
+				Comment:         &comment,
+				PeriodType:      string(personio.PeriodTypeWork),
+				ProjectID:       &projectID,
+				Origin:          personio.PeriodOriginImport,
+				// Passes midnight, but belongs to the day it started on
+				Start: personio.NewFlexibleTime(time.Date(2023, 1, 18, 22, 0, 0, 0, time.UTC)),
+				End:   personio.NewFlexibleTime(time.Date(2023, 1, 19, 6, 0, 0, 0, time.UTC)),
+			},
+		},
+	}
+
+	entries, err := FromCalendar(cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("want 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Date != "2023-01-18" {
+		t.Errorf("want date 2023-01-18, got %s", e.Date)
+	}
+	if e.Duration() != 8*time.Hour {
+		t.Errorf("want 8h duration, got %s", e.Duration())
+	}
+	if e.Source != SourcePersonio || e.SourceID != periodID.String() {
+		t.Errorf("want source personio %s, got %s %s", periodID, e.Source, e.SourceID)
+	}
+	if e.Origin != personio.PeriodOriginImport {
+		t.Errorf("want origin import, got %q", e.Origin)
+	}
+
+	p := e.Period()
+	if p.ID != periodID {
+		t.Errorf("want period ID %s, got %s", periodID, p.ID)
+	}
+	if p.GetComment() != comment || p.GetProjectID() != projectID {
+		t.Errorf("want comment %q and project %d, got %q and %d", comment, projectID, p.GetComment(), p.GetProjectID())
+	}
+	if back := FromPeriod(p, SourcePersonio); back.SourceID != e.SourceID || !back.Start.Equal(e.Start) {
+		t.Errorf("want round-trip to keep ID and start, got %+v", back)
+	}
+}
+
+func TestEntryPeriodWithoutUUID(t *testing.T) {
+	e := Entry{
+		Start:    time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC),
+		End:      time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC),
+		Source:   SourceImport,
+		SourceID: "timetrap-123",
+	}
+	p := e.Period()
+	if p.ID != uuid.Nil {
+		t.Errorf("want nil ID for non-UUID source ID, got %s", p.ID)
+	}
+	if p.PeriodType != personio.PeriodTypeWork {
+		t.Errorf("want default period type work, got %q", p.PeriodType)
+	}
+}
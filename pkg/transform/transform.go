@@ -0,0 +1,307 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package transform applies user-defined Starlark scripts to attendance
+// periods before they are submitted to Personio.
+//
+// Starlark is a dialect of Python, see https://github.com/bazelbuild/starlark.
+// A script can define one or both of these functions:
+//
+//	def transform(period):
+//	    # Called once per period. Return the (modified) period,
+//	    # None to drop it, or a list of periods to split it.
+//	    return period
+//
+//	def transform_day(day, periods):
+//	    # Called once per day, after transform(), with the day as a
+//	    # "2006-01-02" string. Must return a list of periods.
+//	    return periods
+//
+// Periods are dicts with the keys "id", "period_type", "comment",
+// "project_id", "start", and "end", where "start" and "end" are values
+// from the predeclared "time" module.
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+	starlarktime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+)
+
+// Names of the functions that a script may define.
+const (
+	FuncTransform    = "transform"
+	FuncTransformDay = "transform_day"
+)
+
+// ErrNoFunctions is returned by [Compile] when the script does not define
+// any of the [FuncTransform] or [FuncTransformDay] functions.
+var ErrNoFunctions = errors.New("script defines neither transform() nor transform_day()")
+
+// Script is a compiled Starlark transformation script.
+type Script struct {
+	filename     string
+	transform    *starlark.Function
+	transformDay *starlark.Function
+}
+
+// Compile executes the Starlark source code and looks up its transformation
+// functions. The filename is only used in error messages.
+func Compile(filename string, src any) (*Script, error) {
+	thread := newThread(filename)
+	predeclared := starlark.StringDict{
+		"time": starlarktime.Module,
+	}
+	globals, err := starlark.ExecFile(thread, filename, src, predeclared)
+	if err != nil {
+		return nil, err
+	}
+	s := &Script{filename: filename}
+	if s.transform, err = lookupFunc(globals, FuncTransform); err != nil {
+		return nil, err
+	}
+	if s.transformDay, err = lookupFunc(globals, FuncTransformDay); err != nil {
+		return nil, err
+	}
+	if s.transform == nil && s.transformDay == nil {
+		return nil, fmt.Errorf("%s: %w", filename, ErrNoFunctions)
+	}
+	return s, nil
+}
+
+func lookupFunc(globals starlark.StringDict, name string) (*starlark.Function, error) {
+	v, ok := globals[name]
+	if !ok {
+		return nil, nil
+	}
+	fn, ok := v.(*starlark.Function)
+	if !ok {
+		return nil, fmt.Errorf("%s: want function, got %s", name, v.Type())
+	}
+	return fn, nil
+}
+
+func newThread(filename string) *starlark.Thread {
+	return &starlark.Thread{Name: filename}
+}
+
+// Apply runs the script's functions on the periods, and returns the
+// resulting periods sorted by their start time.
+func (s *Script) Apply(periods []personio.Period) ([]personio.Period, error) {
+	thread := newThread(s.filename)
+
+	if s.transform != nil {
+		var result []personio.Period
+		for _, p := range periods {
+			v, err := starlark.Call(thread, s.transform, starlark.Tuple{periodToDict(p)}, nil)
+			if err != nil {
+				return nil, err
+			}
+			transformed, err := valueToPeriods(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s() result: %w", FuncTransform, err)
+			}
+			result = append(result, transformed...)
+		}
+		periods = result
+	}
+
+	if s.transformDay != nil {
+		days := groupByDay(periods)
+		var result []personio.Period
+		for _, day := range days {
+			list := starlark.NewList(nil)
+			for _, p := range day.periods {
+				list.Append(periodToDict(p))
+			}
+			v, err := starlark.Call(thread, s.transformDay, starlark.Tuple{starlark.String(day.day), list}, nil)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := v.(*starlark.List); !ok {
+				return nil, fmt.Errorf("%s() result: want list, got %s", FuncTransformDay, v.Type())
+			}
+			transformed, err := valueToPeriods(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s() result: %w", FuncTransformDay, err)
+			}
+			result = append(result, transformed...)
+		}
+		periods = result
+	}
+
+	sort.SliceStable(periods, func(i, j int) bool {
+		return periods[i].Start.Before(periods[j].Start)
+	})
+	return periods, nil
+}
+
+type dayPeriods struct {
+	day     string
+	periods []personio.Period
+}
+
+func groupByDay(periods []personio.Period) []dayPeriods {
+	var days []dayPeriods
+	index := map[string]int{}
+	for _, p := range periods {
+		day := p.Start.Format(time.DateOnly)
+		i, ok := index[day]
+		if !ok {
+			i = len(days)
+			index[day] = i
+			days = append(days, dayPeriods{day: day})
+		}
+		days[i].periods = append(days[i].periods, p)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].day < days[j].day
+	})
+	return days
+}
+
+func periodToDict(p personio.Period) *starlark.Dict {
+	d := starlark.NewDict(6)
+	id := starlark.Value(starlark.None)
+	if p.ID != uuid.Nil {
+		id = starlark.String(p.ID.String())
+	}
+	// Missing and empty values are both None, so scripts can check for
+	// either with "if p["comment"]"
+	comment := starlark.Value(starlark.None)
+	if s := p.GetComment(); s != "" {
+		comment = starlark.String(s)
+	}
+	projectID := starlark.Value(starlark.None)
+	if id := p.GetProjectID(); id != 0 {
+		projectID = starlark.MakeInt(id)
+	}
+	d.SetKey(starlark.String("id"), id)
+	d.SetKey(starlark.String("period_type"), starlark.String(p.PeriodType))
+	d.SetKey(starlark.String("comment"), comment)
+	d.SetKey(starlark.String("project_id"), projectID)
+	d.SetKey(starlark.String("start"), starlarktime.Time(p.Start))
+	d.SetKey(starlark.String("end"), starlarktime.Time(p.End))
+	return d
+}
+
+func valueToPeriods(v starlark.Value) ([]personio.Period, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case *starlark.Dict:
+		p, err := dictToPeriod(v)
+		if err != nil {
+			return nil, err
+		}
+		return []personio.Period{p}, nil
+	case *starlark.List:
+		periods := make([]personio.Period, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			d, ok := v.Index(i).(*starlark.Dict)
+			if !ok {
+				return nil, fmt.Errorf("index %d: want dict, got %s", i, v.Index(i).Type())
+			}
+			p, err := dictToPeriod(d)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			periods = append(periods, p)
+		}
+		return periods, nil
+	default:
+		return nil, fmt.Errorf("want dict, list, or None, got %s", v.Type())
+	}
+}
+
+func dictToPeriod(d *starlark.Dict) (personio.Period, error) {
+	var p personio.Period
+	for _, item := range d.Items() {
+		key, ok := starlark.AsString(item[0])
+		if !ok {
+			return p, fmt.Errorf("want string keys, got %s", item[0].Type())
+		}
+		if err := setPeriodField(&p, key, item[1]); err != nil {
+			return p, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return p, nil
+}
+
+func setPeriodField(p *personio.Period, key string, v starlark.Value) error {
+	switch key {
+	case "id":
+		if v == starlark.None {
+			p.ID = uuid.Nil
+			return nil
+		}
+		s, ok := starlark.AsString(v)
+		if !ok {
+			return fmt.Errorf("want string or None, got %s", v.Type())
+		}
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return err
+		}
+		p.ID = id
+	case "period_type":
+		s, ok := starlark.AsString(v)
+		if !ok {
+			return fmt.Errorf("want string, got %s", v.Type())
+		}
+		p.PeriodType = personio.PeriodType(s)
+	case "comment":
+		if v == starlark.None {
+			p.Comment = nil
+			return nil
+		}
+		s, ok := starlark.AsString(v)
+		if !ok {
+			return fmt.Errorf("want string or None, got %s", v.Type())
+		}
+		p.Comment = &s
+	case "project_id":
+		if v == starlark.None {
+			p.ProjectID = nil
+			return nil
+		}
+		var i int
+		if err := starlark.AsInt(v, &i); err != nil {
+			return err
+		}
+		p.ProjectID = &i
+	case "start", "end":
+		t, ok := v.(starlarktime.Time)
+		if !ok {
+			return fmt.Errorf("want time.time, got %s", v.Type())
+		}
+		if key == "start" {
+			p.Start = time.Time(t)
+		} else {
+			p.End = time.Time(t)
+		}
+	default:
+		return errors.New("unknown key")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"gopkg.in/typ.v4"
+)
+
+const supportScript = `
+def transform(p):
+    if "#support" in (p["comment"] or ""):
+        p["project_id"] = 42
+    if p["period_type"] == "break":
+        return None
+    return p
+
+def transform_day(day, periods):
+    budget = time.parse_duration("2h")
+    result = []
+    for p in periods:
+        if p["project_id"] != 42:
+            result.append(p)
+            continue
+        if budget <= time.parse_duration("0s"):
+            continue
+        if p["end"] - p["start"] > budget:
+            p["end"] = p["start"] + budget
+        budget -= p["end"] - p["start"]
+        result.append(p)
+    return result
+`
+
+func TestScriptApply(t *testing.T) {
+	script, err := Compile("test.star", supportScript)
+	if err != nil {
+		t.Fatalf("compile: %s", err)
+	}
+
+	periods := []personio.Period{
+		newPeriod(t, "08:00", "09:30", personio.PeriodTypeWork, "#support tickets"),
+		newPeriod(t, "09:30", "10:00", personio.PeriodTypeBreak, ""),
+		newPeriod(t, "10:00", "12:00", personio.PeriodTypeWork, "#support more tickets"),
+		newPeriod(t, "13:00", "17:00", personio.PeriodTypeWork, "coding"),
+	}
+
+	got, err := script.Apply(periods)
+	if err != nil {
+		t.Fatalf("apply: %s", err)
+	}
+
+	want := []personio.Period{
+		newPeriod(t, "08:00", "09:30", personio.PeriodTypeWork, "#support tickets"),
+		newPeriod(t, "10:00", "10:30", personio.PeriodTypeWork, "#support more tickets"),
+		newPeriod(t, "13:00", "17:00", personio.PeriodTypeWork, "coding"),
+	}
+	want[0].ProjectID = typ.Ref(42)
+	want[1].ProjectID = typ.Ref(42)
+
+	if len(got) != len(want) {
+		t.Fatalf("want %d periods, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) {
+			t.Errorf("index %d: want %s-%s, got %s-%s", i,
+				want[i].Start, want[i].End, got[i].Start, got[i].End)
+		}
+		if got[i].GetProjectID() != want[i].GetProjectID() {
+			t.Errorf("index %d: want project %d, got %d", i,
+				want[i].GetProjectID(), got[i].GetProjectID())
+		}
+		if got[i].GetComment() != want[i].GetComment() {
+			t.Errorf("index %d: want comment %q, got %q", i,
+				want[i].GetComment(), got[i].GetComment())
+		}
+	}
+}
+
+func TestCompileNoFunctions(t *testing.T) {
+	_, err := Compile("empty.star", "x = 1")
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+}
+
+func TestApplyInvalidResult(t *testing.T) {
+	script, err := Compile("bad.star", `def transform(p): return 123`)
+	if err != nil {
+		t.Fatalf("compile: %s", err)
+	}
+	_, err = script.Apply([]personio.Period{newPeriod(t, "08:00", "09:00", personio.PeriodTypeWork, "")})
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+}
+
+func newPeriod(t *testing.T, start, end string, periodType personio.PeriodType, comment string) personio.Period {
+	t.Helper()
+	p := personio.Period{
+		PeriodType: periodType,
+		Start:      mustParseTime(t, "2023-01-18T"+start+":00Z"),
+		End:        mustParseTime(t, "2023-01-18T"+end+":00Z"),
+	}
+	if comment != "" {
+		p.Comment = &comment
+	}
+	return p
+}
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	tim, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("parse time %q: %s", value, err)
+	}
+	return tim
+}
@@ -21,6 +21,7 @@ package util
 import (
 	"bytes"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -45,6 +46,9 @@ var camelCaseReplacer = strings.NewReplacer(
 	"PEM", "Pem",
 	"DER", "Pem",
 	"RSA", "Rsa",
+	"MCP", "Mcp",
+	"CSRF", "Csrf",
+	"SHA", "Sha",
 )
 
 // ToCamelCase is a very stupid implementation for converting
@@ -80,6 +84,34 @@ func PrettyPath(s string) string {
 	return path
 }
 
+// windowsReservedNames are file names that Windows reserves for devices,
+// regardless of file extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SafeFileName escapes the string so it can be used as a file name on all
+// operating systems, including Windows, which disallows more characters
+// than other systems. Characters are escaped the same way as
+// [url.PathEscape], so names are unchanged from before this function
+// existed, except that colons are also escaped.
+func SafeFileName(s string) string {
+	escaped := strings.ReplaceAll(url.PathEscape(s), ":", "%3A")
+	// Windows silently strips trailing dots and spaces
+	if strings.HasSuffix(escaped, ".") {
+		escaped = strings.TrimSuffix(escaped, ".") + "%2E"
+	}
+	base, _, _ := strings.Cut(escaped, ".")
+	if windowsReservedNames[strings.ToUpper(base)] {
+		escaped = "_" + escaped
+	}
+	return escaped
+}
+
 func ColorizeJSON(data []byte) ([]byte, error) {
 	args := []string{"."}
 	if isatty.IsTerminal(os.Stdout.Fd()) {
@@ -115,3 +147,20 @@ func TimeFullMonth(date time.Time) (time.Time, time.Time) {
 	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC),
 		time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
 }
+
+// ForEachMonth splits the date range into calendar months, and calls the
+// function with the first and last day of each month that are inside the
+// range. Ranges spanning multiple years are split the same way.
+func ForEachMonth(start, end time.Time, f func(start, end time.Time) error) error {
+	for start := start; !start.After(end); {
+		_, monthEnd := TimeFullMonth(start)
+		if monthEnd.After(end) {
+			monthEnd = end
+		}
+		if err := f(start, monthEnd); err != nil {
+			return err
+		}
+		start = monthEnd.AddDate(0, 0, 1)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func FuzzTimeFullMonth(f *testing.F) {
+	for _, date := range []time.Time{
+		time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC),
+		time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 12, 31, 23, 30, 0, 0, time.FixedZone("", 14*3600)),
+		time.Date(2023, 1, 1, 0, 30, 0, 0, time.FixedZone("", -12*3600)),
+		time.Date(2000, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1900, 2, 15, 0, 0, 0, 0, time.UTC),
+	} {
+		_, offset := date.Zone()
+		f.Add(date.Unix(), offset/60)
+	}
+	f.Fuzz(func(t *testing.T, unix int64, offsetMin int) {
+		// Keep within years 1-9999 and real timezone offsets
+		unix %= 250_000_000_000
+		offsetMin %= 15 * 60
+		date := time.Unix(unix, 0).In(time.FixedZone("", offsetMin*60))
+
+		start, end := TimeFullMonth(date)
+
+		if start.Location() != time.UTC || end.Location() != time.UTC {
+			t.Fatalf("want UTC, got %s and %s", start.Location(), end.Location())
+		}
+		year, month, day := date.Date()
+		if start.Year() != year || start.Month() != month || start.Day() != 1 {
+			t.Errorf("date %s: want start on first of the month, got %s", date, start)
+		}
+		if end.Year() != year || end.Month() != month {
+			t.Errorf("date %s: want end in the same month, got %s", date, end)
+		}
+		if next := end.AddDate(0, 0, 1); next.Day() != 1 {
+			t.Errorf("date %s: want end on last of the month, got %s", date, end)
+		}
+		if day < start.Day() || day > end.Day() {
+			t.Errorf("date %s: day %d not within %s-%s", date, day, start, end)
+		}
+		if start.Hour() != 0 || end.Hour() != 0 || start.Minute() != 0 || end.Minute() != 0 {
+			t.Errorf("date %s: want midnight, got %s and %s", date, start, end)
+		}
+	})
+}
+
+func TestSafeFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "example.personio.de", want: "example.personio.de"},
+		{name: "me@example.com", want: "me@example.com"},
+		{name: "localhost:8080", want: "localhost%3A8080"},
+		{name: `a/b\c*d?e"f<g>h|i`, want: "a%2Fb%5Cc%2Ad%3Fe%22f%3Cg%3Eh%7Ci"},
+		{name: "trailing.", want: "trailing%2E"},
+		{name: "con", want: "_con"},
+		{name: "NUL.json", want: "_NUL.json"},
+		{name: "console", want: "console"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SafeFileName(tc.name); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestForEachMonth(t *testing.T) {
+	start := time.Date(2022, 11, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)
+	var months []string
+	err := ForEachMonth(start, end, func(start, end time.Time) error {
+		months = append(months, start.Format(time.DateOnly)+".."+end.Format(time.DateOnly))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(months) != 16 {
+		t.Fatalf("want 16 months, got %d: %v", len(months), months)
+	}
+	for i, want := range map[int]string{
+		0:  "2022-11-15..2022-11-30",
+		1:  "2022-12-01..2022-12-31",
+		2:  "2023-01-01..2023-01-31",
+		14: "2024-01-01..2024-01-31",
+		15: "2024-02-01..2024-02-10",
+	} {
+		if months[i] != want {
+			t.Errorf("month %d: want %s, got %s", i, want, months[i])
+		}
+	}
+}
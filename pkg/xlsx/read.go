@@ -0,0 +1,368 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Workbook is a workbook opened for reading, with only enough support to
+// read the cell values of its sheets as text.
+type Workbook struct {
+	// Sheets are the names of the sheets, in order.
+	Sheets []string
+
+	zip           *zip.Reader
+	sheetPaths    []string
+	sharedStrings []string
+	// dateStyles are the cell style indexes with a date or time format
+	dateStyles map[int]bool
+	date1904   bool
+}
+
+// ReadOptions configures how [Workbook.Rows] turns cells into text.
+type ReadOptions struct {
+	// DateFormat is the Go time layout of date cells.
+	// Defaults to "2006-01-02".
+	DateFormat string
+	// TimeFormat is the Go time layout of time cells.
+	// Defaults to "15:04".
+	TimeFormat string
+	// DateTimeFormat is the Go time layout of cells with both date and
+	// time. Defaults to DateFormat and TimeFormat separated by a space.
+	DateTimeFormat string
+}
+
+// Open opens a workbook for reading.
+func Open(r io.ReaderAt, size int64) (*Workbook, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("open workbook: %w", err)
+	}
+	wb := &Workbook{zip: zr, dateStyles: make(map[int]bool)}
+
+	var workbook struct {
+		Pr struct {
+			Date1904 bool `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := wb.decode("xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	wb.date1904 = workbook.Pr.Date1904
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := wb.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+	for _, sheet := range workbook.Sheets {
+		wb.Sheets = append(wb.Sheets, sheet.Name)
+		wb.sheetPaths = append(wb.sheetPaths, targets[sheet.RID])
+	}
+
+	if err := wb.readSharedStrings(); err != nil {
+		return nil, err
+	}
+	if err := wb.readStyles(); err != nil {
+		return nil, err
+	}
+	return wb, nil
+}
+
+// Rows returns the cell values of a sheet as text, by sheet name or by
+// 1-based sheet number, or of the first sheet when empty. Leading empty
+// rows are skipped.
+func (wb *Workbook) Rows(sheet string, opts ReadOptions) ([][]string, error) {
+	index, err := wb.sheetIndex(sheet)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DateFormat == "" {
+		opts.DateFormat = "2006-01-02"
+	}
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = "15:04"
+	}
+	if opts.DateTimeFormat == "" {
+		opts.DateTimeFormat = opts.DateFormat + " " + opts.TimeFormat
+	}
+
+	var data struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R      string   `xml:"r,attr"`
+				T      string   `xml:"t,attr"`
+				S      int      `xml:"s,attr"`
+				V      string   `xml:"v"`
+				Inline richText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := wb.decode(wb.sheetPaths[index], &data); err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	for i, row := range data.Rows {
+		rowNum := row.R
+		if rowNum == 0 {
+			rowNum = i + 1
+		}
+		// Rows without any cells may be left out of the file
+		for len(rows) < rowNum-1 {
+			rows = append(rows, nil)
+		}
+		var values []string
+		for j, cell := range row.Cells {
+			col := j
+			if cell.R != "" {
+				col = columnIndex(cell.R)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			switch cell.T {
+			case "s":
+				n, err := strconv.Atoi(cell.V)
+				if err != nil || n < 0 || n >= len(wb.sharedStrings) {
+					return nil, fmt.Errorf("cell %s: invalid shared string %q", cell.R, cell.V)
+				}
+				values[col] = wb.sharedStrings[n]
+			case "inlineStr":
+				values[col] = cell.Inline.String()
+			case "b":
+				values[col] = map[string]string{"0": "FALSE", "1": "TRUE"}[cell.V]
+			case "", "n":
+				values[col] = wb.formatNumber(cell.V, cell.S, opts)
+			default:
+				values[col] = cell.V
+			}
+		}
+		rows = append(rows, values)
+	}
+	for len(rows) > 0 && isEmptyRow(rows[0]) {
+		rows = rows[1:]
+	}
+	return rows, nil
+}
+
+func (wb *Workbook) sheetIndex(sheet string) (int, error) {
+	if len(wb.Sheets) == 0 {
+		return 0, errors.New("workbook has no sheets")
+	}
+	if sheet == "" {
+		return 0, nil
+	}
+	for i, name := range wb.Sheets {
+		if strings.EqualFold(name, sheet) {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(sheet); err == nil && n >= 1 && n <= len(wb.Sheets) {
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("no sheet named %q, the workbook has: %s", sheet, strings.Join(wb.Sheets, ", "))
+}
+
+// formatNumber formats a number cell, where cells with a date or time
+// format are stored as the number of days since 1900 (or 1904).
+func (wb *Workbook) formatNumber(value string, style int, opts ReadOptions) string {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || !wb.dateStyles[style] {
+		return value
+	}
+	t := wb.serialTime(f)
+	days, frac := math.Modf(f)
+	switch {
+	case frac == 0:
+		return t.Format(opts.DateFormat)
+	case days == 0:
+		return t.Format(opts.TimeFormat)
+	default:
+		return t.Format(opts.DateTimeFormat)
+	}
+}
+
+func (wb *Workbook) serialTime(serial float64) time.Time {
+	// Excel counts the non-existent 1900-02-29, so day 60 and onwards are
+	// offset by a day, which the 1899-12-30 epoch accounts for
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if wb.date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	secs := math.Round(serial * 24 * 60 * 60)
+	return epoch.Add(time.Duration(secs) * time.Second)
+}
+
+func (wb *Workbook) readSharedStrings() error {
+	var sst struct {
+		Items []richText `xml:"si"`
+	}
+	if err := wb.decode("xl/sharedStrings.xml", &sst); err != nil {
+		if errors.Is(err, errMissingFile) {
+			return nil
+		}
+		return err
+	}
+	for _, item := range sst.Items {
+		wb.sharedStrings = append(wb.sharedStrings, item.String())
+	}
+	return nil
+}
+
+func (wb *Workbook) readStyles() error {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := wb.decode("xl/styles.xml", &styles); err != nil {
+		if errors.Is(err, errMissingFile) {
+			return nil
+		}
+		return err
+	}
+	customDates := make(map[int]bool)
+	for _, f := range styles.NumFmts {
+		customDates[f.ID] = isDateFormatCode(f.Code)
+	}
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) || customDates[id] {
+			wb.dateStyles[i] = true
+		}
+	}
+	return nil
+}
+
+// isDateFormatCode returns true if the number format code formats dates or
+// times, ignoring quoted text, escaped characters, and [colors].
+func isDateFormatCode(code string) bool {
+	var inQuote, inBracket bool
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case inQuote:
+			inQuote = c != '"'
+		case inBracket:
+			// Elapsed time, like [h]:mm, is still a time
+			if c == 'h' || c == 'H' || c == 'm' || c == 'M' || c == 's' || c == 'S' {
+				return true
+			}
+			inBracket = c != ']'
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == '\\' || c == '_' || c == '*':
+			i++
+		case strings.IndexByte("dmyhsDMYHS", c) >= 0:
+			return true
+		}
+	}
+	return false
+}
+
+var errMissingFile = errors.New("missing file in workbook")
+
+func (wb *Workbook) decode(name string, v any) error {
+	f, err := wb.zip.Open(name)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errMissingFile, name)
+	}
+	defer f.Close()
+	if err := xml.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// richText is a string that is either a single <t> element, or rich text
+// runs of <r><t> elements.
+type richText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt richText) String() string {
+	if len(rt.Runs) == 0 {
+		return rt.T
+	}
+	var sb strings.Builder
+	sb.WriteString(rt.T)
+	for _, r := range rt.Runs {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+// columnIndex returns the zero-based column index of a cell reference,
+// e.g 0 for "A1" and 27 for "AB3".
+func columnIndex(ref string) int {
+	var index int
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		index = index*26 + int(c-'A'+1)
+	}
+	return index - 1
+}
+
+func isEmptyRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
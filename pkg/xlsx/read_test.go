@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadWritten(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf,
+		Sheet{Name: "Summary", Rows: [][]any{{"ignored"}}},
+		Sheet{Name: "Periods", Rows: [][]any{
+			{"Date", "Hours", "Comment"},
+			{"2023-01-18", 7.5, nil},
+			{"2023-01-19", 8 * time.Hour, "<done>"},
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wb, err := Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Summary", "Periods"}; !reflect.DeepEqual(wb.Sheets, want) {
+		t.Errorf("want sheets %v, got %v", want, wb.Sheets)
+	}
+	for _, sheet := range []string{"periods", "2"} {
+		rows, err := wb.Rows(sheet, ReadOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := [][]string{
+			{"Date", "Hours", "Comment"},
+			{"2023-01-18", "7.5"},
+			{"2023-01-19", "8", "<done>"},
+		}
+		if !reflect.DeepEqual(rows, want) {
+			t.Errorf("sheet %q: want %q, got %q", sheet, want, rows)
+		}
+	}
+	if _, err := wb.Rows("Missing", ReadOptions{}); err == nil {
+		t.Error("want error on unknown sheet")
+	}
+}
+
+func TestReadSharedStringsAndDates(t *testing.T) {
+	files := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Zeiten" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="worksheet" Target="/xl/worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>Datum</t></si><si><t>Von</t></si><si><r><t>Not</t></r><r><t>iz</t></r></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<numFmts><numFmt numFmtId="164" formatCode="dd/mm/yyyy"/><numFmt numFmtId="165" formatCode="&quot;h&quot;0.00"/></numFmts>` +
+			`<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="20"/><xf numFmtId="165"/></cellXfs></styleSheet>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="2"><c r="A2" t="s"><v>0</v></c><c r="B2" t="s"><v>1</v></c><c r="D2" t="s"><v>2</v></c></row>` +
+			`<row r="3"><c r="A3" s="1"><v>44944</v></c><c r="B3" s="2"><v>0.34375</v></c><c r="C3" s="3"><v>1.5</v></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wb, err := Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := wb.Rows("", ReadOptions{DateFormat: "02.01.2006"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The leading empty row is skipped
+	want := [][]string{
+		{"Datum", "Von", "", "Notiz"},
+		{"18.01.2023", "08:15", "1.5"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("want %q, got %q", want, rows)
+	}
+}